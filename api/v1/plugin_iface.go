@@ -1,11 +1,32 @@
 // Package plugin_iface defines the Orbit plugin contract (PluginV1).
-// All external plugins must implement this interface and export an "OrbitPlugin" symbol.
+// All external plugins must implement this interface, and either export an
+// "OrbitPlugin" symbol from a .so or pass it to pkg/pluginrpc.Serve from a
+// standalone binary.
 package v1
 
+import "context"
+
 // PluginAPIVersion is the current plugin API version.
 // Checked at plugin load time to prevent incompatible plugins from loading.
 const PluginAPIVersion = "v1"
 
+// Hook names a plugin may subscribe to in Hooks().
+const (
+	HookPreDeploy      = "OnPreDeploy"
+	HookPostDeploy     = "OnPostDeploy"
+	HookPreScale       = "OnPreScale"
+	HookPostScale      = "OnPostScale"
+	HookNodeConnect    = "OnNodeConnect"
+	HookNodeDisconnect = "OnNodeDisconnect"
+	HookSSLRenew       = "OnSSLRenew"
+)
+
+// HookDispatcher fires named hooks at every subscribed plugin. The plugin
+// host implements it; a nil dispatcher fires nothing.
+type HookDispatcher interface {
+	Fire(ctx context.Context, hook string, hctx HookContext)
+}
+
 // HookFunc is a function invoked at a named lifecycle point.
 type HookFunc func(ctx HookContext) error
 
@@ -17,6 +38,8 @@ type HookContext struct {
 	ImageTo   string
 	DryRun    bool
 	// Metadata is a free-form map for passing extension data between hooks.
+	// The host sets "action" on deploy hooks, "replicas" and "current" on
+	// scale hooks, and "result" (plus "error" on failure) on post hooks.
 	Metadata map[string]string
 }
 
@@ -30,7 +53,8 @@ type PluginV1 interface {
 	// A mismatch causes the plugin to be rejected at load time.
 	APIVersion() string
 
-	// Init is called once after the plugin is loaded.
+	// Init is called once after the plugin is loaded with the plugin's
+	// plugins.<name>.config map from orbit.yaml (never nil; keys lower-cased).
 	// Return an error to abort loading.
 	Init(cfg map[string]string) error
 
@@ -43,3 +67,16 @@ type PluginV1 interface {
 	// Shutdown is called when Orbit exits cleanly.
 	Shutdown() error
 }
+
+// PluginConfigRequirer is optionally implemented by a PluginV1 that cannot
+// run without certain settings. Each key must be present under
+// plugins.<name>.config in orbit.yaml, or the plugin is rejected at load time.
+type PluginConfigRequirer interface {
+	RequiredConfig() []string
+}
+
+// PluginVersioner is optionally implemented by a PluginV1 to report its own
+// release version, shown by `orbit plugin ls`.
+type PluginVersioner interface {
+	Version() string
+}
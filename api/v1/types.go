@@ -1,7 +1,10 @@
 // Package v1 defines the public data types shared across all Orbit layers.
 package v1
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // ─────────────────────────────────────────────────────────────────────────────
 // Status enumerations
@@ -44,11 +47,43 @@ type ServiceSpec struct {
 	HealthCheck   *HealthCheckSpec  `yaml:"health_check"   mapstructure:"health_check"`
 	Proxy         *ProxySpec        `yaml:"proxy"          mapstructure:"proxy"`
 	Deploy        *DeploySpec       `yaml:"deploy"         mapstructure:"deploy"`
+	DependsOn     []string          `yaml:"depends_on"     mapstructure:"depends_on"` // services up starts first and down stops last
+	Profiles      []string          `yaml:"profiles"       mapstructure:"profiles"`   // started by orbit up only with --profile naming one; none: always
+	Hooks         *HooksSpec        `yaml:"hooks"          mapstructure:"hooks"`      // shell commands run around deploys and stops
+	Migrations    *MigrationSpec    `yaml:"migrations"     mapstructure:"migrations"` // one-off container each deploy runs before starting the new image
+
+	// Process overrides. Unset, the image's ENTRYPOINT, CMD and WORKDIR apply.
+	Command         ShellCommand  `yaml:"command"           mapstructure:"command"`
+	Entrypoint      ShellCommand  `yaml:"entrypoint"        mapstructure:"entrypoint"`
+	WorkingDir      string        `yaml:"working_dir"       mapstructure:"working_dir"`
+	Init            bool          `yaml:"init"              mapstructure:"init"`              // run an init process as PID 1 that reaps zombies and forwards signals
+	StopSignal      string        `yaml:"stop_signal"       mapstructure:"stop_signal"`       // default SIGTERM, or the image's STOPSIGNAL
+	StopGracePeriod time.Duration `yaml:"stop_grace_period" mapstructure:"stop_grace_period"` // wait before SIGKILL on stop; default 10s
+
+	// Hardening. Capabilities are named as in capabilities(7), with or
+	// without the CAP_ prefix; ALL stands for every capability.
+	CapAdd      []string `yaml:"cap_add"      mapstructure:"cap_add"`
+	CapDrop     []string `yaml:"cap_drop"     mapstructure:"cap_drop"`
+	SecurityOpt []string `yaml:"security_opt" mapstructure:"security_opt"` // e.g. no-new-privileges:true, seccomp=profile.json, apparmor=name
+	ReadOnly    bool     `yaml:"read_only"    mapstructure:"read_only"`    // mount the root filesystem read-only
+	Privileged  bool     `yaml:"privileged"   mapstructure:"privileged"`   // every capability and host device; avoid
+	Tmpfs       []string `yaml:"tmpfs"        mapstructure:"tmpfs"`        // path[:options], e.g. /run:size=64m
+
+	// Kernel limits and name resolution.
+	Ulimits    map[string]string `yaml:"ulimits"     mapstructure:"ulimits"`     // name to soft[:hard], e.g. nofile: "65536:65536"
+	Sysctls    map[string]string `yaml:"sysctls"     mapstructure:"sysctls"`     // namespaced kernel parameters, e.g. net.core.somaxconn: "1024"
+	ExtraHosts []string          `yaml:"extra_hosts" mapstructure:"extra_hosts"` // host:ip entries added to /etc/hosts
+	DNS        []string          `yaml:"dns"         mapstructure:"dns"`         // nameservers instead of the daemon's
+	DNSSearch  []string          `yaml:"dns_search"  mapstructure:"dns_search"`
 }
 
+// ShellCommand is a command line given as a list of arguments, or as one
+// string split into them the way a shell would, honouring quotes.
+type ShellCommand []string
+
 // HealthCheckSpec configures how Orbit probes service liveness.
 type HealthCheckSpec struct {
-	Type         string        `yaml:"type"          mapstructure:"type"` // tcp | http | cmd
+	Type         string        `yaml:"type"          mapstructure:"type"` // tcp | http | cmd | exec | grpc | docker
 	URL          string        `yaml:"url"           mapstructure:"url"`
 	Port         int           `yaml:"port"          mapstructure:"port"`
 	Command      string        `yaml:"command"       mapstructure:"command"`
@@ -56,6 +91,106 @@ type HealthCheckSpec struct {
 	Interval     time.Duration `yaml:"interval"      mapstructure:"interval"`
 	Retries      int           `yaml:"retries"       mapstructure:"retries"`
 	ExpectedCode int           `yaml:"expected_code" mapstructure:"expected_code"`
+
+	// HTTP probe options
+	Method          string            `yaml:"method"            mapstructure:"method"`            // default GET
+	Headers         map[string]string `yaml:"headers"           mapstructure:"headers"`           // sent with the request, e.g. Authorization
+	ExpectBody      string            `yaml:"expect_body"       mapstructure:"expect_body"`       // substring the body must contain
+	ExpectBodyRegex string            `yaml:"expect_body_regex" mapstructure:"expect_body_regex"` // regexp the body must match
+	ExpectHeaders   map[string]string `yaml:"expect_headers"    mapstructure:"expect_headers"`    // required response headers; "" only checks presence
+
+	// gRPC probe options
+	GRPCService string `yaml:"grpc_service" mapstructure:"grpc_service"` // "" checks overall server health
+
+	// TLS options for https and grpc probes
+	TLS           bool   `yaml:"tls"             mapstructure:"tls"`
+	TLSSkipVerify bool   `yaml:"tls_skip_verify" mapstructure:"tls_skip_verify"`
+	CAFile        string `yaml:"ca_file"         mapstructure:"ca_file"`
+	ServerName    string `yaml:"server_name"     mapstructure:"server_name"`
+
+	// Per-purpose probes. Each inherits the check definition above and may
+	// override it along with its own timing and thresholds.
+	Startup   *ProbeSpec `yaml:"startup"   mapstructure:"startup"`   // gates readiness until the app has booted
+	Readiness *ProbeSpec `yaml:"readiness" mapstructure:"readiness"` // gates deploy cut-over and proxy inclusion
+	Liveness  *ProbeSpec `yaml:"liveness"  mapstructure:"liveness"`  // triggers restarts when failing
+}
+
+// HooksSpec lists shell commands run around a service's deploys and stops:
+// migrations, cache warms, CDN purges and the like, without a plugin.
+type HooksSpec struct {
+	PreDeploy  []HookCommand `yaml:"pre_deploy"  mapstructure:"pre_deploy"`  // before the new image is pulled
+	PostDeploy []HookCommand `yaml:"post_deploy" mapstructure:"post_deploy"` // once the new containers serve
+	PreStop    []HookCommand `yaml:"pre_stop"    mapstructure:"pre_stop"`    // before orbit down stops the service
+}
+
+// Command hooks, the lists of a HooksSpec.
+const (
+	HookCmdPreDeploy  = "pre_deploy"
+	HookCmdPostDeploy = "post_deploy"
+	HookCmdPreStop    = "pre_stop"
+)
+
+// Commands returns the commands of hook, one of the HookCmd constants.
+func (h *HooksSpec) Commands(hook string) []HookCommand {
+	if h == nil {
+		return nil
+	}
+	switch hook {
+	case HookCmdPreDeploy:
+		return h.PreDeploy
+	case HookCmdPostDeploy:
+		return h.PostDeploy
+	case HookCmdPreStop:
+		return h.PreStop
+	}
+	return nil
+}
+
+// HookCommand is a shell command of a hooks list. It runs with sh -c where
+// Where says: on the machine running orbit, on the service's node over SSH,
+// or inside the service's container.
+type HookCommand struct {
+	Command   string        `yaml:"command"    mapstructure:"command"`
+	Where     string        `yaml:"where"      mapstructure:"where"`      // local (default) | node | container
+	Timeout   time.Duration `yaml:"timeout"    mapstructure:"timeout"`    // default 5m
+	OnFailure string        `yaml:"on_failure" mapstructure:"on_failure"` // fail (default) | ignore
+}
+
+// Places a HookCommand runs, for HookCommand.Where.
+const (
+	HookWhereLocal     = "local"
+	HookWhereNode      = "node"
+	HookWhereContainer = "container"
+)
+
+// Failure policies for HookCommand.OnFailure.
+const (
+	HookFailureFail   = "fail"
+	HookFailureIgnore = "ignore"
+)
+
+// MigrationSpec is a one-off container a deploy runs once the new image is
+// pulled and before any replica of it starts, with the service's
+// environment, volumes and networks. A non-zero exit fails the deploy while
+// the old replicas still serve.
+type MigrationSpec struct {
+	Image   string        `yaml:"image"   mapstructure:"image"`   // default: the image being deployed
+	Command ShellCommand  `yaml:"command" mapstructure:"command"` // default: the image's
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"` // default 10m
+}
+
+// ProbeSpec overrides the base health check for a startup, readiness, or liveness probe.
+// Zero-valued fields inherit from the enclosing HealthCheckSpec.
+type ProbeSpec struct {
+	Type             string        `yaml:"type"              mapstructure:"type"`
+	URL              string        `yaml:"url"               mapstructure:"url"`
+	Port             int           `yaml:"port"              mapstructure:"port"`
+	Command          string        `yaml:"command"           mapstructure:"command"`
+	InitialDelay     time.Duration `yaml:"initial_delay"     mapstructure:"initial_delay"`
+	Interval         time.Duration `yaml:"interval"          mapstructure:"interval"`
+	Timeout          time.Duration `yaml:"timeout"           mapstructure:"timeout"`
+	FailureThreshold int           `yaml:"failure_threshold" mapstructure:"failure_threshold"`
+	SuccessThreshold int           `yaml:"success_threshold" mapstructure:"success_threshold"`
 }
 
 // ProxySpec controls NGINX reverse proxy generation for a service.
@@ -68,11 +203,56 @@ type ProxySpec struct {
 
 // DeploySpec controls rolling deploy behaviour.
 type DeploySpec struct {
-	Replicas          int           `yaml:"replicas"           mapstructure:"replicas"`
-	Strategy          string        `yaml:"strategy"           mapstructure:"strategy"` // rolling | blue-green
-	MaxSurge          int           `yaml:"max_surge"          mapstructure:"max_surge"`
-	RollbackOnFailure bool          `yaml:"rollback_on_failure" mapstructure:"rollback_on_failure"`
-	ReadinessDelay    time.Duration `yaml:"readiness_delay"    mapstructure:"readiness_delay"`
+	Replicas          int            `yaml:"replicas"           mapstructure:"replicas"`
+	Strategy          string         `yaml:"strategy"           mapstructure:"strategy"` // rolling | blue-green
+	MaxSurge          int            `yaml:"max_surge"          mapstructure:"max_surge"`
+	RollbackOnFailure bool           `yaml:"rollback_on_failure" mapstructure:"rollback_on_failure"`
+	ReadinessDelay    time.Duration  `yaml:"readiness_delay"    mapstructure:"readiness_delay"`
+	Autoscale         *AutoscaleSpec `yaml:"autoscale"          mapstructure:"autoscale"`
+
+	// Verify watches the service after cut-over and rolls the deploy back
+	// if it regresses.
+	Verify *VerifySpec `yaml:"verify" mapstructure:"verify"`
+
+	// ReplicaPorts is how replicas after the first publish the service's
+	// host ports, which only one container can bind: "offset" adds the
+	// replica's index minus one to each host port, "ephemeral" lets the
+	// runtime pick free ones. Unset, the service runs a single replica.
+	ReplicaPorts string `yaml:"replica_ports" mapstructure:"replica_ports"`
+}
+
+// Replica port modes for DeploySpec.ReplicaPorts.
+const (
+	ReplicaPortsOffset    = "offset"
+	ReplicaPortsEphemeral = "ephemeral"
+)
+
+// VerifySpec watches a service for Window after a deploy's cut-over, while
+// the deploy can still be undone. The deploy is rolled back when, across the
+// new replicas, there are more than MaxRestarts restarts (or a replica
+// exits), more than MaxHealthFlaps readiness probe failures after passing,
+// or more than MaxErrorRate percent of requests to URL fail. Zero maximums
+// tolerate none.
+type VerifySpec struct {
+	Window         time.Duration `yaml:"window"           mapstructure:"window"`
+	Interval       time.Duration `yaml:"interval"         mapstructure:"interval"` // between checks; default 5s
+	MaxRestarts    int           `yaml:"max_restarts"     mapstructure:"max_restarts"`
+	MaxHealthFlaps int           `yaml:"max_health_flaps" mapstructure:"max_health_flaps"`
+
+	// URL is probed once per interval; a request fails when it gets no
+	// answer or a 5xx status.
+	URL          string  `yaml:"url"            mapstructure:"url"`
+	MaxErrorRate float64 `yaml:"max_error_rate" mapstructure:"max_error_rate"` // percent of requests
+}
+
+// AutoscaleSpec lets orbit agent adjust the replica count to keep CPU usage,
+// averaged over replicas, near TargetCPU. Zero cooldowns use the defaults.
+type AutoscaleSpec struct {
+	MinReplicas       int           `yaml:"min_replicas"        mapstructure:"min_replicas"`
+	MaxReplicas       int           `yaml:"max_replicas"        mapstructure:"max_replicas"`
+	TargetCPU         float64       `yaml:"target_cpu"          mapstructure:"target_cpu"` // percent of one CPU per replica
+	ScaleUpCooldown   time.Duration `yaml:"scale_up_cooldown"   mapstructure:"scale_up_cooldown"`
+	ScaleDownCooldown time.Duration `yaml:"scale_down_cooldown" mapstructure:"scale_down_cooldown"`
 }
 
 // NodeSpec is the declarative definition of a remote node.
@@ -83,6 +263,17 @@ type NodeSpec struct {
 	Key    string   `yaml:"key"    mapstructure:"key"`
 	Port   int      `yaml:"port"   mapstructure:"port"`
 	Groups []string `yaml:"groups" mapstructure:"groups"`
+
+	// ProxyJump routes the connection through bastion hosts, in OpenSSH
+	// ProxyJump syntax: "[user@]host[:port]", comma-separated for chains.
+	ProxyJump string `yaml:"proxy_jump" mapstructure:"proxy_jump"`
+
+	// DockerHost is the node's Docker daemon, as a URL (unix://, tcp://) or
+	// a Docker context name, for daemons not on the default socket.
+	DockerHost string `yaml:"docker_host" mapstructure:"docker_host"`
+
+	// HeartbeatInterval overrides how often the node is probed (default 30s).
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" mapstructure:"heartbeat_interval"`
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -98,11 +289,15 @@ type NodeInfo struct {
 	HostKey        string     `json:"host_key"`  // base64-encoded known host line
 	HostKeyKnown   bool       `json:"host_key_known"`
 	FailCount      int        `json:"fail_count"`
+	Modified       time.Time  `json:"modified"`       // when Spec or the host key was last written
+
+	Host *HostMetrics `json:"host,omitempty"` // reading from the last successful heartbeat
 }
 
 // ServiceState is the runtime state of a deployed service instance.
 type ServiceState struct {
 	Name        string        `json:"name"`
+	Project     string        `json:"project,omitempty"` // project.name of the orbit.yaml it was deployed from
 	ContainerID string        `json:"container_id"`
 	Image       string        `json:"image"`
 	Status      ServiceStatus `json:"status"`
@@ -111,14 +306,66 @@ type ServiceState struct {
 	Replicas    int           `json:"replicas"`
 	Node        string        `json:"node"`
 	StartedAt   time.Time     `json:"started_at"`
-	Ports       []string      `json:"ports"`
+	Ports       []string      `json:"ports"` // host:container ports the replicas publish, for the proxy
+	Ready       bool          `json:"ready"` // readiness probe passing — eligible for proxy traffic
+
+	// Restart history since the last up or deploy. RestartCount is the
+	// unexpected exits the watchdog saw or the restarts the runtime reports,
+	// whichever is higher; the rest describe the latest exit.
+	RestartCount int       `json:"restart_count,omitempty"`
+	ExitCode     int       `json:"exit_code,omitempty"`
+	OOMKilled    bool      `json:"oom_killed,omitempty"` // killed by the kernel for running out of memory
+	LastExit     time.Time `json:"last_exit,omitempty"`
+	CrashLoop    bool      `json:"crash_loop,omitempty"` // stopped by the watchdog after restarting too often
+
+	// Scale is the replica count set with `orbit scale` when it differs from
+	// deploy.replicas; up and deploy keep it. 0 = follow orbit.yaml.
+	Scale int `json:"scale,omitempty"`
+}
+
+// UnmarshalJSON decodes a ServiceState. One recorded before readiness was
+// tracked has no ready field and is taken as ready, so the proxy keeps
+// routing to it until a probe says otherwise.
+func (s *ServiceState) UnmarshalJSON(data []byte) error {
+	type plain ServiceState
+	p := plain{Ready: true}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*s = ServiceState(p)
+	return nil
+}
+
+// ContainerEvent is a container lifecycle event from the runtime's event
+// stream, reduced to what orbit acts on.
+type ContainerEvent struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Service  string    `json:"service"`           // orbit.service label
+	Task     bool      `json:"task"`              // orbit.task label: a one-off `orbit run` container
+	Replica  string    `json:"replica,omitempty"` // orbit.replica label: the replica index after the first
+	Image    string    `json:"image,omitempty"`
+	Action   string    `json:"action"`           // start | die | kill | health_status | ...
+	Health   string    `json:"health,omitempty"` // health_status only: starting | healthy | unhealthy
+	ExitCode int       `json:"exit_code"`
+	Time     time.Time `json:"time"`
+}
+
+// ContainerExit is a container's restart history as the runtime records it.
+type ContainerExit struct {
+	Restarts  int       `json:"restarts"`  // times its restart policy restarted it
+	ExitCode  int       `json:"exit_code"` // of the latest exit
+	OOMKilled bool      `json:"oom_killed"`
+	ExitedAt  time.Time `json:"exited_at"` // zero if it never exited
 }
 
 // DeploymentRecord is an immutable audit record of a deployment action.
 type DeploymentRecord struct {
 	ID          string    `json:"id"`
+	Project     string    `json:"project,omitempty"`
 	Service     string    `json:"service"`
 	Node        string    `json:"node"`
+	Action      string    `json:"action"` // deploy | rollback | scale
 	FromImage   string    `json:"from_image"`
 	ToImage     string    `json:"to_image"`
 	StartedAt   time.Time `json:"started_at"`
@@ -126,21 +373,99 @@ type DeploymentRecord struct {
 	Result      string    `json:"result"` // success | failure | rolledback
 	DurationMS  int64     `json:"duration_ms"`
 	Error       string    `json:"error,omitempty"`
+	Replicas    int       `json:"replicas,omitempty"`
+	Reason      string    `json:"reason,omitempty"` // why an automatic action was taken
+	RunID       string    `json:"run_id,omitempty"` // orbit invocation that made it, as in its logs
+	Commit      string    `json:"commit,omitempty"` // git commit of orbit.yaml it applied, for GitOps deploys
 }
 
+// DeploymentRecord actions and results.
+const (
+	DeployActionDeploy   = "deploy"
+	DeployActionRollback = "rollback"
+	DeployActionScale    = "scale"
+
+	DeployResultSuccess    = "success"
+	DeployResultFailure    = "failure"
+	DeployResultRolledBack = "rolledback"
+)
+
 // Metrics is a point-in-time snapshot of resource utilisation across services.
 type Metrics struct {
 	Timestamp time.Time                 `json:"timestamp"`
 	Node      string                    `json:"node"`
 	Services  map[string]ServiceMetrics `json:"services"`
+	Host      *HostMetrics              `json:"host,omitempty"`
 }
 
 // ServiceMetrics holds per-container resource stats.
 type ServiceMetrics struct {
-	CPUPercent float64 `json:"cpu_percent"`
-	MemBytes   int64   `json:"mem_bytes"`
-	MemLimit   int64   `json:"mem_limit"`
-	NetRxBytes int64   `json:"net_rx_bytes"`
-	NetTxBytes int64   `json:"net_tx_bytes"`
-	PIDs       int     `json:"pids"`
+	CPUPercent      float64 `json:"cpu_percent"`
+	MemBytes        int64   `json:"mem_bytes"`
+	MemLimit        int64   `json:"mem_limit"`
+	NetRxBytes      int64   `json:"net_rx_bytes"`
+	NetTxBytes      int64   `json:"net_tx_bytes"`
+	PIDs            int     `json:"pids"`
+	BlockReadBytes  int64   `json:"block_read_bytes"`
+	BlockWriteBytes int64   `json:"block_write_bytes"`
+	DiskBytes       int64   `json:"disk_bytes"` // writable layer size (SizeRw), sampled less often than the rest
+}
+
+// HostMetrics is a point-in-time reading of a node's host resources. Disk and
+// inode figures are for the filesystem holding the container runtime's data root.
+type HostMetrics struct {
+	Load1        float64   `json:"load1"`
+	Load5        float64   `json:"load5"`
+	Load15       float64   `json:"load15"`
+	MemTotal     int64     `json:"mem_total"`
+	MemAvailable int64     `json:"mem_available"`
+	DiskPath     string    `json:"disk_path"`
+	DiskTotal    int64     `json:"disk_total"`
+	DiskFree     int64     `json:"disk_free"`
+	InodesTotal  int64     `json:"inodes_total"`
+	InodesFree   int64     `json:"inodes_free"`
+	CollectedAt  time.Time `json:"collected_at"`
+}
+
+// Alert is an alerting rule whose condition has held for the rule's duration.
+// Active alerts are persisted so every orbit process on the host sees them.
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Metric    string    `json:"metric"`
+	Node      string    `json:"node"`
+	Subject   string    `json:"subject"` // service or node the condition holds for
+	Value     float64   `json:"value"`   // reading when the alert fired
+	Threshold float64   `json:"threshold"`
+	Message   string    `json:"message"`
+	Since     time.Time `json:"since"` // when the condition started holding
+	FiredAt   time.Time `json:"fired_at"`
 }
+
+// Event is an entry in the state DB's event log: something that happened to a
+// service, node or deployment, kept for timelines and notification replay.
+type Event struct {
+	ID       string    `json:"id"` // assigned by the state DB; IDs sort by Time
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`     // deploy | rollback | scale | alert | alert.resolved | ...
+	Resource string    `json:"resource"` // kind/name, e.g. service/web or node/edge-1
+	Node     string    `json:"node,omitempty"`
+	Severity string    `json:"severity"` // info | warning | error
+	Message  string    `json:"message"`
+	RunID    string    `json:"run_id,omitempty"` // orbit invocation that recorded it, as in its logs
+}
+
+// Event types recorded by orbit itself.
+const (
+	EventDeploy        = DeployActionDeploy
+	EventRollback      = DeployActionRollback
+	EventScale         = DeployActionScale
+	EventAlert         = "alert"
+	EventAlertResolved = "alert.resolved"
+)
+
+// Event severities.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
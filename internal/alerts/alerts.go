@@ -0,0 +1,233 @@
+// Package alerts evaluates the alerts: rules from orbit.yaml against service
+// metrics, host readings, service health, and node heartbeats.
+package alerts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/metrics"
+)
+
+// Interval is how often `orbit agent` evaluates the rules.
+const Interval = 15 * time.Second
+
+// Metrics an alert rule can watch.
+const (
+	MetricCPU         = "cpu"       // service CPU, percent of one CPU
+	MetricMem         = "mem"       // service memory, percent of its limit
+	MetricHostMem     = "host_mem"  // host memory in use, percent
+	MetricHostDisk    = "host_disk" // runtime data root filesystem in use, percent
+	MetricHostLoad    = "host_load" // 1-minute load average
+	MetricUnhealthy   = "unhealthy" // service unhealthy or crash-looping
+	MetricNodeOffline = "node_offline"
+)
+
+// Input is what one evaluation round reads.
+type Input struct {
+	Metrics  v1.Metrics        // the evaluating node's collector readings
+	Services []v1.ServiceState // service states of the evaluating node
+	Nodes    []v1.NodeInfo     // registered remote nodes
+}
+
+// Event reports an alert that fired or resolved.
+type Event struct {
+	Alert    v1.Alert
+	Resolved bool
+}
+
+// Evaluator tracks how long each rule's condition has held, per subject.
+// It is not safe for concurrent use.
+type Evaluator struct {
+	rules   map[string]config.AlertRule
+	order   []string
+	pending map[string]time.Time // alert key → condition holding since
+	active  map[string]v1.Alert
+}
+
+// NewEvaluator creates an Evaluator for rules.
+func NewEvaluator(rules []config.AlertRule) *Evaluator {
+	e := &Evaluator{
+		rules:   make(map[string]config.AlertRule, len(rules)),
+		pending: map[string]time.Time{},
+		active:  map[string]v1.Alert{},
+	}
+	for _, r := range rules {
+		e.rules[r.Name] = r
+		e.order = append(e.order, r.Name)
+	}
+	return e
+}
+
+// Restore seeds the evaluator with alerts persisted by an earlier run, so
+// they resolve normally instead of lingering. It returns those whose rule no
+// longer exists, which the caller should delete.
+func (e *Evaluator) Restore(alerts []v1.Alert) (orphaned []v1.Alert) {
+	for _, a := range alerts {
+		if _, ok := e.rules[a.Rule]; !ok {
+			orphaned = append(orphaned, a)
+			continue
+		}
+		key := state.AlertKey(a)
+		e.active[key] = a
+		e.pending[key] = a.Since
+	}
+	return orphaned
+}
+
+// Active returns the firing alerts ordered by rule, node, and subject.
+func (e *Evaluator) Active() []v1.Alert {
+	out := make([]v1.Alert, 0, len(e.active))
+	for _, a := range e.active {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return state.AlertKey(out[i]) < state.AlertKey(out[j]) })
+	return out
+}
+
+// Evaluate checks every rule at now and returns the alerts that fired or
+// resolved since the previous round.
+func (e *Evaluator) Evaluate(now time.Time, in Input) []Event {
+	var events []Event
+	holding := map[string]bool{}
+	for _, name := range e.order {
+		r := e.rules[name]
+		for _, c := range conditions(r, in) {
+			a := v1.Alert{Rule: r.Name, Metric: r.Metric, Node: c.node, Subject: c.subject, Value: c.value, Threshold: r.Above}
+			key := state.AlertKey(a)
+			holding[key] = true
+			since, ok := e.pending[key]
+			if !ok {
+				since = now
+				e.pending[key] = now
+			}
+			if _, firing := e.active[key]; firing || now.Sub(since) < r.For {
+				continue
+			}
+			a.Since, a.FiredAt = since, now
+			a.Message = message(r, a)
+			e.active[key] = a
+			events = append(events, Event{Alert: a})
+		}
+	}
+
+	for key := range e.pending {
+		if !holding[key] {
+			delete(e.pending, key)
+		}
+	}
+	var resolved []string
+	for key := range e.active {
+		if !holding[key] {
+			resolved = append(resolved, key)
+		}
+	}
+	sort.Strings(resolved)
+	for _, key := range resolved {
+		events = append(events, Event{Alert: e.active[key], Resolved: true})
+		delete(e.active, key)
+	}
+	return events
+}
+
+// condition is one subject for which a rule's condition currently holds.
+type condition struct {
+	node, subject string
+	value         float64
+}
+
+// conditions returns the subjects breaching r.
+func conditions(r config.AlertRule, in Input) []condition {
+	var out []condition
+	switch r.Metric {
+	case MetricCPU, MetricMem:
+		for name, s := range in.Metrics.Services {
+			if r.Service != "" && r.Service != name {
+				continue
+			}
+			v := s.CPUPercent
+			if r.Metric == MetricMem {
+				if s.MemLimit <= 0 {
+					continue
+				}
+				v = float64(s.MemBytes) / float64(s.MemLimit) * 100
+			}
+			if v > r.Above {
+				out = append(out, condition{in.Metrics.Node, name, v})
+			}
+		}
+
+	case MetricHostMem, MetricHostDisk, MetricHostLoad:
+		check := func(node string, h *v1.HostMetrics) {
+			if h == nil || (r.Node != "" && r.Node != node) {
+				return
+			}
+			var v float64
+			switch r.Metric {
+			case MetricHostMem:
+				v = metrics.UsedPercent(h.MemTotal, h.MemAvailable)
+			case MetricHostDisk:
+				v = metrics.UsedPercent(h.DiskTotal, h.DiskFree)
+			default:
+				v = h.Load1
+			}
+			if v > r.Above {
+				out = append(out, condition{node, node, v})
+			}
+		}
+		check(in.Metrics.Node, in.Metrics.Host)
+		for _, n := range in.Nodes {
+			// An offline node's last reading is stale; node_offline covers it.
+			if n.Spec.Name != in.Metrics.Node && n.Status != v1.NodeOffline {
+				check(n.Spec.Name, n.Host)
+			}
+		}
+
+	case MetricUnhealthy:
+		for _, s := range in.Services {
+			if r.Service != "" && r.Service != s.Name {
+				continue
+			}
+			if s.Status == v1.StatusUnhealthy || s.CrashLoop {
+				out = append(out, condition{s.Node, s.Name, 0})
+			}
+		}
+
+	case MetricNodeOffline:
+		for _, n := range in.Nodes {
+			if (r.Node == "" || r.Node == n.Spec.Name) && n.Status == v1.NodeOffline {
+				out = append(out, condition{n.Spec.Name, n.Spec.Name, 0})
+			}
+		}
+	}
+	return out
+}
+
+// message describes a firing alert in one line.
+func message(r config.AlertRule, a v1.Alert) string {
+	var what string
+	switch r.Metric {
+	case MetricCPU:
+		what = fmt.Sprintf("%s cpu %.1f%% above %g%%", a.Subject, a.Value, r.Above)
+	case MetricMem:
+		what = fmt.Sprintf("%s memory %.1f%% of limit, above %g%%", a.Subject, a.Value, r.Above)
+	case MetricHostMem:
+		what = fmt.Sprintf("node %s memory %.1f%% used, above %g%%", a.Subject, a.Value, r.Above)
+	case MetricHostDisk:
+		what = fmt.Sprintf("node %s disk %.1f%% used, above %g%%", a.Subject, a.Value, r.Above)
+	case MetricHostLoad:
+		what = fmt.Sprintf("node %s load %.2f above %g", a.Subject, a.Value, r.Above)
+	case MetricUnhealthy:
+		what = fmt.Sprintf("%s is unhealthy", a.Subject)
+	case MetricNodeOffline:
+		what = fmt.Sprintf("node %s is offline", a.Subject)
+	}
+	if r.For > 0 {
+		what += " for " + r.For.String()
+	}
+	return what
+}
@@ -0,0 +1,96 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+)
+
+func cpuInput(cpu float64) Input {
+	return Input{Metrics: v1.Metrics{Node: "local", Services: map[string]v1.ServiceMetrics{
+		"api": {CPUPercent: cpu},
+		"web": {CPUPercent: 1},
+	}}}
+}
+
+func TestEvaluateFiresAfterDurationAndResolves(t *testing.T) {
+	e := NewEvaluator([]config.AlertRule{{Name: "hot", Metric: MetricCPU, Above: 90, For: 5 * time.Minute}})
+	now := time.Now()
+
+	if evs := e.Evaluate(now, cpuInput(95)); len(evs) != 0 {
+		t.Fatalf("fired before the duration elapsed: %+v", evs)
+	}
+	// A dip below the threshold restarts the clock.
+	e.Evaluate(now.Add(2*time.Minute), cpuInput(50))
+	if evs := e.Evaluate(now.Add(6*time.Minute), cpuInput(95)); len(evs) != 0 {
+		t.Fatalf("fired although the condition was interrupted: %+v", evs)
+	}
+	evs := e.Evaluate(now.Add(11*time.Minute), cpuInput(97))
+	if len(evs) != 1 || evs[0].Resolved || evs[0].Alert.Subject != "api" || evs[0].Alert.Value != 97 {
+		t.Fatalf("events = %+v", evs)
+	}
+	if !evs[0].Alert.Since.Equal(now.Add(6 * time.Minute)) {
+		t.Errorf("since = %s", evs[0].Alert.Since)
+	}
+	if evs := e.Evaluate(now.Add(12*time.Minute), cpuInput(99)); len(evs) != 0 {
+		t.Fatalf("fired twice: %+v", evs)
+	}
+	if len(e.Active()) != 1 {
+		t.Fatalf("active = %+v", e.Active())
+	}
+
+	evs = e.Evaluate(now.Add(13*time.Minute), cpuInput(10))
+	if len(evs) != 1 || !evs[0].Resolved {
+		t.Fatalf("events = %+v", evs)
+	}
+	if len(e.Active()) != 0 {
+		t.Fatalf("active after resolve = %+v", e.Active())
+	}
+}
+
+func TestEvaluateHealthAndNodes(t *testing.T) {
+	e := NewEvaluator([]config.AlertRule{
+		{Name: "down", Metric: MetricUnhealthy},
+		{Name: "offline", Metric: MetricNodeOffline},
+		{Name: "disk", Metric: MetricHostDisk, Above: 90},
+	})
+	in := Input{
+		Metrics:  v1.Metrics{Node: "local", Host: &v1.HostMetrics{DiskTotal: 100, DiskFree: 50}},
+		Services: []v1.ServiceState{{Name: "api", Node: "local", Status: v1.StatusHealthy, CrashLoop: true}, {Name: "web", Status: v1.StatusHealthy}},
+		Nodes: []v1.NodeInfo{
+			{Spec: v1.NodeSpec{Name: "prod-01"}, Status: v1.NodeOffline, Host: &v1.HostMetrics{DiskTotal: 100, DiskFree: 1}},
+			{Spec: v1.NodeSpec{Name: "prod-02"}, Status: v1.NodeOnline, Host: &v1.HostMetrics{DiskTotal: 100, DiskFree: 5}},
+		},
+	}
+	got := map[string]string{}
+	for _, ev := range e.Evaluate(time.Now(), in) {
+		got[ev.Alert.Rule] += ev.Alert.Subject
+	}
+	want := map[string]string{"down": "api", "offline": "prod-01", "disk": "prod-02"}
+	if len(got) != len(want) {
+		t.Fatalf("fired = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("rule %s fired for %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	e := NewEvaluator([]config.AlertRule{{Name: "hot", Metric: MetricCPU, Above: 90}})
+	orphaned := e.Restore([]v1.Alert{
+		{Rule: "hot", Node: "local", Subject: "api"},
+		{Rule: "removed", Node: "local", Subject: "api"},
+	})
+	if len(orphaned) != 1 || orphaned[0].Rule != "removed" {
+		t.Fatalf("orphaned = %+v", orphaned)
+	}
+	// The restored alert resolves once the condition is gone.
+	evs := e.Evaluate(time.Now(), cpuInput(10))
+	if len(evs) != 1 || !evs[0].Resolved || evs[0].Alert.Subject != "api" {
+		t.Fatalf("events = %+v", evs)
+	}
+}
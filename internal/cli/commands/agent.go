@@ -0,0 +1,664 @@
+// orbit agent — long-running node agent (health monitoring, liveness restarts, node heartbeats, alerts, autoscaling, OTLP export).
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/alerts"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/daemon"
+	"github.com/f9-o/orbit/internal/gitops"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/internal/logship"
+	"github.com/f9-o/orbit/internal/metrics"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/internal/telemetry"
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+func NewAgentCmd() *cobra.Command {
+	var noRestart, collectLogs bool
+	var git gitopsFlags
+
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Run the Orbit agent: continuously probe services and nodes and keep state current",
+		Long: `Runs in the foreground until interrupted. Every service with a health_check
+is probed at its configured interval; status transitions are written to the
+state DB, and containers whose liveness probe keeps failing are restarted.
+Registered remote nodes are heartbeated over SSH and their status kept current.
+Rules from the alerts: section of orbit.yaml are evaluated every 15s; active
+alerts are kept in the state DB and listed by ` + "`orbit status`" + `. With
+metrics.otlp_endpoint set, service and host metrics are pushed to that
+OpenTelemetry collector every 15s. Services with deploy.autoscale are scaled
+between their replica bounds every 30s to keep CPU near the target.
+
+With --collect-logs, the output of every orbit container is appended to a
+rotated file per service under ~/.orbit/logs/services/<node>/, which
+` + "`orbit logs`" + ` then reads: a service's logs survive the deploys that replace
+its containers, and cover all of its replicas.
+
+With a logging: section in orbit.yaml, the agent also pushes every
+container's lines, labelled with service, node and image, to Loki, an HTTP
+endpoint or a syslog server.
+
+With --gitops, orbit.yaml comes from a git repository instead: the agent
+clones it, polls it every --gitops-interval and deploys each new commit's
+changes, recording the commit in the deployment history, except for
+services in profiles --gitops-profile does not name. In an environment
+deploy_policy protects, each deploy waits for a maintenance window and the
+approval webhook; a commit stands in for the typed confirmation. Alerts,
+autoscaling, deploy_policy and the scan.deploy vulnerability gate keep the
+settings the agent started with until it restarts.
+
+To keep it running across reboots, install it as a service with
+` + "`orbit agent install`" + `.`,
+		Example: `  orbit agent
+  orbit agent --node prod-01 --no-restart
+  orbit agent --collect-logs
+  orbit agent --gitops git@github.com:acme/infra.git --gitops-path apps/web/orbit.yaml
+  sudo orbit agent install --run-as deploy`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			nodeName := nodeOrLocal(rt.Flags.Node)
+
+			docker, err := rt.NewContainerClient()
+			if err != nil {
+				return fmt.Errorf("docker: %w", err)
+			}
+			defer docker.Close()
+
+			// Cancelled on SIGINT/SIGTERM by the root shutdown manager.
+			ctx := cmd.Context()
+
+			checker := health.NewChecker(rt.Log).WithExecer(docker).WithInspector(docker)
+			var syncer *gitops.Syncer
+			if git.url != "" {
+				if syncer, err = startGitOps(ctx, rt, docker, checker, nodeName, git); err != nil {
+					return err
+				}
+			}
+			monitor := health.NewMonitor(checker, rt.State, nodeName, rt.Config.Services, rt.Log)
+			monitor.WithWatchdog(docker, docker, crashLoopPolicy(rt)).WithExits(docker)
+			if !noRestart {
+				monitor.WithRestarter(docker)
+			}
+
+			go monitor.Run(ctx)
+
+			pool := rt.NewPool()
+			defer pool.Close()
+			heartbeat := startHeartbeat(rt, pool)
+			defer heartbeat.StopAll()
+
+			// The collector and the log shipper read the same containers
+			// through one follower, so the agent subscribes to events once.
+			follow := orchestrator.NewFollower(docker, "agent", metrics.PollInterval, rt.Log)
+			following := false
+
+			// Service and host readings feed alert rules and OTLP export
+			var collector *metrics.Collector
+			if len(rt.Config.Alerts) > 0 || rt.Config.Metrics.OTLPEndpoint != "" || autoscaled(rt.Config.Services) {
+				collector = metrics.NewCollector(docker, nodeName, rt.Log).WithFollower(follow)
+				go collector.Run(ctx)
+				following = true
+			}
+			if collectLogs || rt.Config.Logging.Enabled() {
+				shipper, err := startLogShipper(rt, docker, nodeName, collectLogs)
+				if err != nil {
+					return err
+				}
+				go shipper.WithFollower(follow).Run(ctx)
+				following = true
+			}
+			if following {
+				go follow.Run(ctx)
+			}
+			alertEvents := startAlerts(ctx, rt, collector, nodeName)
+			scaleEvents := startAutoscaler(ctx, rt, docker, collector, nodeName)
+			if ep := rt.Config.Metrics.OTLPEndpoint; ep != "" {
+				exporter, err := telemetry.NewMetricsExporter(ep, Version)
+				if err != nil {
+					return fmt.Errorf("metrics.otlp_endpoint: %w", err)
+				}
+				go exportMetrics(ctx, rt, exporter, collector)
+			}
+
+			var gitopsEvents <-chan gitops.Event
+			if syncer != nil {
+				go syncer.Run(ctx, git.interval)
+				gitopsEvents = syncer.Events()
+			}
+
+			pprint.Info("Agent running on %q (Ctrl+C to stop)", nodeName)
+			rt.Log.Info("agent.start", "node", nodeName, "services", len(rt.Config.Services))
+
+			for {
+				select {
+				case <-ctx.Done():
+					rt.Log.Info("agent.stop", "node", nodeName)
+					return nil
+				case ev := <-monitor.Events():
+					printHealthEvent(ev)
+				case ev := <-heartbeat.Events():
+					printNodeEvent(ev)
+				case ev := <-alertEvents:
+					printAlertEvent(ev)
+				case d := <-scaleEvents:
+					printScaleDecision(d)
+				case ev := <-gitopsEvents:
+					if ev.Config != nil {
+						monitor.SetServices(ev.Config.Services)
+					}
+					printGitOpsEvent(ev)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&noRestart, "no-restart", false, "Only report liveness failures; never restart containers")
+	cmd.Flags().BoolVar(&collectLogs, "collect-logs", false, "Keep each service's container output in files under ~/.orbit/logs/services")
+	cmd.Flags().StringVar(&git.url, "gitops", "", "Deploy the orbit.yaml in this git repository on every new commit")
+	cmd.Flags().StringVar(&git.branch, "gitops-branch", "", "Branch to follow (default: the repository's default branch)")
+	cmd.Flags().StringVar(&git.path, "gitops-path", gitops.DefaultPath, "Path of the config file within the repository")
+	cmd.Flags().DurationVar(&git.interval, "gitops-interval", gitops.DefaultInterval, "How often to poll the repository")
+	cmd.Flags().BoolVar(&git.prune, "gitops-prune", false, "Remove services that are no longer in the repository's config")
+	cmd.Flags().StringSliceVar(&git.profiles, "gitops-profile", config.ProfilesFromEnv(), "Also deploy the services in this profile (repeatable; '*' for all; default: $"+config.EnvProfiles+")")
+	cmd.AddCommand(newAgentInstallCmd(), newAgentUninstallCmd())
+	return cmd
+}
+
+// startLogShipper sets up log collection: into files with --collect-logs,
+// and to the stores in the logging: section.
+func startLogShipper(rt *Runtime, docker *orchestrator.Client, node string, files bool) (*logship.Shipper, error) {
+	sinks, err := logship.NewSinks(rt.Config.Logging)
+	if err != nil {
+		return nil, err
+	}
+	shipper := logship.NewShipper(docker, node, rt.Log).WithSinks(sinks...)
+	if files {
+		shipper.WithFiles(logship.Dir(node), logger.ConfiguredRotation())
+	}
+	names := make([]string, len(sinks))
+	for i, s := range sinks {
+		names[i] = s.Name()
+	}
+	rt.Log.Info("agent.logs", "files", files, "sinks", strings.Join(names, ","))
+	return shipper, nil
+}
+
+// gitopsFlags are the agent's --gitops options.
+type gitopsFlags struct {
+	url, branch, path string
+	interval          time.Duration
+	prune             bool
+	profiles          []string
+}
+
+// startGitOps checks out the --gitops repository and applies its current
+// commit, so the agent starts on the repository's config rather than the
+// local one. The returned Syncer applies later commits once run.
+func startGitOps(ctx context.Context, rt *Runtime, docker *orchestrator.Client, checker *health.Checker, node string, f gitopsFlags) (*gitops.Syncer, error) {
+	repo := gitops.NewRepo(f.url, f.branch, gitops.CheckoutDir(config.OrbitHome(), f.url))
+	commands := commandHooks(rt, docker, nil)
+	deployer := orchestrator.NewDeployer(docker, rt.State, checker, rt.Log).WithHooks(rt.Plugins).WithCommandHooks(commands)
+	policy, err := scanPolicy(rt)
+	if err != nil {
+		return nil, err
+	}
+	if policy != nil {
+		deployer.WithPolicy(policy)
+	}
+	if policy := deployGate(rt).Policy(); policy != nil {
+		deployer.WithPolicy(policy)
+	}
+	syncer := gitops.NewSyncer(repo, f.path, node, orchestrator.NewPlanner(docker, rt.State, rt.Log), deployer, rt.Log).
+		WithLoadOptions(config.LoadOptions{Strict: rt.Flags.Strict}).
+		WithProfiles(f.profiles)
+	if f.prune {
+		syncer.WithPrune(orchestrator.NewLifecycleManager(docker, rt.State, rt.Log).WithHooks(rt.Plugins).WithCommandHooks(commands))
+	}
+
+	pprint.Info("GitOps: syncing %s", f.url)
+	ev, _ := syncer.Sync(ctx)
+	if ev.Config == nil {
+		return nil, errs.Wrap(ev.Err, errs.ErrConfig, "agent.gitops").
+			WithAdvice("Check the repository URL, branch and --gitops-path, and that git can reach it non-interactively")
+	}
+	rt.Config = ev.Config
+	printGitOpsEvent(ev)
+	return syncer, nil
+}
+
+func printGitOpsEvent(ev gitops.Event) {
+	ts := ev.Time.Format("15:04:05")
+	commit := gitops.ShortCommit(ev.Commit)
+	switch {
+	case ev.Err != nil:
+		pprint.Error("%s  gitops %s: %v", ts, commit, ev.Err)
+	case ev.Plan != nil && ev.Plan.HasChanges():
+		pprint.Success("%s  gitops %s applied: %s", ts, commit, ev.Plan)
+	default:
+		pprint.Info("%s  gitops %s: no changes", ts, commit)
+	}
+}
+
+func newAgentInstallCmd() *cobra.Command {
+	var perUser, noStart, noRestart, collectLogs, printOnly bool
+	var runAs string
+	var env []string
+
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Run the agent as a service: a systemd unit on Linux, a launchd job on macOS",
+		Long: `Write and enable a service that runs ` + "`orbit agent`" + ` at boot and restarts it
+if it exits, then start it.
+
+The service runs this orbit binary from the current directory, so it finds
+the same orbit.yaml; --config and --node are passed on to the agent. It runs
+as root, as --run-as, or — with --user — as a per-user service of yours
+(systemctl --user, or a LaunchAgent on macOS).
+
+ORBIT_SECRET_KEY and the Docker connection variables (DOCKER_HOST,
+CONTAINER_HOST, …) are copied from your environment when set, with any
+--env additions. On Linux they go to a 0600 environment file
+(/etc/orbit/agent.env, or ~/.orbit/agent.env with --user), not the unit.
+
+Installing again replaces the service. Use --print to see the files
+without installing them.`,
+		Example: `  sudo orbit agent install --run-as deploy
+  orbit agent install --user
+  sudo orbit agent install --config /srv/app/orbit.yaml --node prod-01
+  orbit agent install --print`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+
+			mgr, err := daemon.New(perUser)
+			if err != nil {
+				return err
+			}
+			var agentArgs []string
+			if noRestart {
+				agentArgs = append(agentArgs, "--no-restart")
+			}
+			if collectLogs {
+				agentArgs = append(agentArgs, "--collect-logs")
+			}
+			unit, err := agentUnit(rt, mgr, perUser, agentArgs, runAs, env)
+			if err != nil {
+				return err
+			}
+			rt.audit("", map[string]string{"system": mgr.System(), "user": unit.User})
+
+			if printOnly || rt.Flags.DryRun {
+				shown := unit
+				shown.Env = maps.Clone(unit.Env)
+				for _, k := range []string{encryption.EnvSecretKey, encryption.EnvPassphrase} {
+					if _, ok := shown.Env[k]; ok {
+						shown.Env[k] = "********"
+					}
+				}
+				files, err := mgr.Render(shown)
+				if err != nil {
+					return err
+				}
+				for i, f := range files {
+					if i > 0 {
+						fmt.Println()
+					}
+					pprint.Info("%s (mode %04o)", f.Path, f.Mode)
+					fmt.Print(string(f.Data))
+				}
+				return nil
+			}
+
+			files, err := mgr.Install(cmd.Context(), unit, !noStart)
+			for _, f := range files {
+				pprint.Success("Wrote %s", f.Path)
+			}
+			if errors.Is(err, os.ErrPermission) {
+				return errs.Wrap(err, errs.ErrConfig, "agent.install").
+					WithAdvice("Run it with sudo, or pass --user to install a service of your own")
+			}
+			if err != nil {
+				return err
+			}
+			verb := "started"
+			if noStart {
+				verb = "enabled (start it, or reboot, to run it)"
+			}
+			pprint.Success("Agent service %s", verb)
+			if mgr.System() == daemon.Systemd {
+				scope := ""
+				if perUser {
+					scope = "--user "
+				}
+				pprint.Info("Status: systemctl %sstatus %s   Logs: journalctl %s-u %s -f", scope, daemon.Name, scope, daemon.Name)
+			} else {
+				pprint.Info("Logs: %s", unit.LogFile)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&perUser, "user", false, "Install a per-user service instead of a system one")
+	cmd.Flags().StringVar(&runAs, "run-as", "", "Account the system service runs as (default: $SUDO_USER, else root)")
+	cmd.Flags().StringArrayVar(&env, "env", nil, "Extra KEY=VALUE for the agent's environment (repeatable)")
+	cmd.Flags().BoolVar(&noStart, "no-start", false, "Enable the service without starting it now")
+	cmd.Flags().BoolVar(&noRestart, "no-restart", false, "Pass --no-restart to the agent")
+	cmd.Flags().BoolVar(&collectLogs, "collect-logs", false, "Pass --collect-logs to the agent")
+	cmd.Flags().BoolVar(&printOnly, "print", false, "Print the service files instead of installing them")
+	return cmd
+}
+
+// agentUnit describes the service that runs this binary's agent with the
+// invoking command's config, node and environment.
+func agentUnit(rt *Runtime, mgr *daemon.Manager, perUser bool, agentArgs []string, runAs string, env []string) (daemon.Unit, error) {
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		return daemon.Unit{}, fmt.Errorf("locate the orbit binary: %w", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return daemon.Unit{}, err
+	}
+
+	u := daemon.Unit{Exec: exe, Args: []string{"agent"}, WorkDir: wd, Env: map[string]string{}}
+	if cfg := rt.Flags.ConfigFile; cfg != "" {
+		abs, err := filepath.Abs(cfg)
+		if err != nil {
+			return u, err
+		}
+		u.Args = append(u.Args, "--config", abs)
+	}
+	if rt.Flags.Node != "" {
+		u.Args = append(u.Args, "--node", rt.Flags.Node)
+	}
+	u.Args = append(u.Args, agentArgs...)
+
+	if !perUser {
+		u.User = runAs
+		if u.User == "" && os.Geteuid() == 0 {
+			u.User = os.Getenv("SUDO_USER") // installed with sudo: run as whoever ran it
+		}
+	}
+	for _, k := range daemon.PassEnv {
+		if v, ok := os.LookupEnv(k); ok {
+			u.Env[k] = v
+		}
+	}
+	for _, kv := range env {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return u, errs.Newf(errs.ErrValidation, "agent.install", "--env %q: want KEY=VALUE", kv)
+		}
+		u.Env[k] = v
+	}
+	_, withKey := u.Env[encryption.EnvSecretKey]
+	_, withPassphrase := u.Env[encryption.EnvPassphrase]
+	switch {
+	case withKey:
+	case encryption.CurrentSource() == encryption.SourcePassphrase:
+		if !withPassphrase {
+			rt.Log.Warn("agent.install: the state DB is encrypted with a passphrase and ORBIT_PASSPHRASE is not set; the agent cannot open it")
+		}
+	default:
+		rt.Log.Warn("agent.install: ORBIT_SECRET_KEY is not set; the agent can only open an unencrypted state DB")
+	}
+
+	if mgr.System() == daemon.Launchd {
+		u.LogFile = "/var/log/orbit-agent.log"
+		if perUser {
+			u.LogFile = filepath.Join(config.OrbitHome(), "logs", "agent.log")
+		}
+	}
+	return u, nil
+}
+
+func newAgentUninstallCmd() *cobra.Command {
+	var perUser bool
+
+	cmd := &cobra.Command{
+		Use:          "uninstall",
+		Short:        "Stop the agent service and remove what `orbit agent install` wrote",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := daemon.New(perUser)
+			if err != nil {
+				return err
+			}
+			err = mgr.Uninstall(cmd.Context())
+			if errors.Is(err, os.ErrPermission) {
+				return errs.Wrap(err, errs.ErrConfig, "agent.uninstall").
+					WithAdvice("Run it with sudo, or pass --user if it was installed with --user")
+			}
+			if err != nil {
+				return err
+			}
+			pprint.Success("Agent service removed (%s)", mgr.UnitPath())
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&perUser, "user", false, "Remove the per-user service instead of the system one")
+	return cmd
+}
+
+// crashLoopPolicy returns the watchdog thresholds from orbit.yaml.
+func crashLoopPolicy(rt *Runtime) health.CrashLoopPolicy {
+	return health.CrashLoopPolicy{
+		MaxRestarts: rt.Config.Watchdog.MaxRestarts,
+		Window:      rt.Config.Watchdog.Window,
+	}
+}
+
+// startAlerts evaluates the alerts: rules every alerts.Interval until ctx is
+// done, keeping the state DB's active alerts current. It returns nil when no
+// rules are configured.
+func startAlerts(ctx context.Context, rt *Runtime, collector *metrics.Collector, node string) <-chan alerts.Event {
+	if len(rt.Config.Alerts) == 0 {
+		return nil
+	}
+	eval := alerts.NewEvaluator(rt.Config.Alerts)
+	if saved, err := rt.State.ListAlerts(); err != nil {
+		rt.Log.Warn("alerts: load active alerts failed", "err", err)
+	} else {
+		for _, a := range eval.Restore(saved) {
+			_ = rt.State.DeleteAlert(a)
+		}
+	}
+
+	out := make(chan alerts.Event, 16)
+	go func() {
+		ticker := time.NewTicker(alerts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				in := alerts.Input{Metrics: collector.AllMetrics()}
+				var err error
+				if in.Services, err = rt.State.ListServiceStates(node); err != nil {
+					rt.Log.Warn("alerts: list services failed", "err", err)
+					continue
+				}
+				if in.Nodes, err = rt.State.ListNodes(); err != nil {
+					rt.Log.Warn("alerts: list nodes failed", "err", err)
+					continue
+				}
+				for _, ev := range eval.Evaluate(now, in) {
+					if ev.Resolved {
+						err = rt.State.DeleteAlert(ev.Alert)
+					} else {
+						err = rt.State.PutAlert(ev.Alert)
+					}
+					if err != nil {
+						rt.Log.Warn("alerts: persist failed", "rule", ev.Alert.Rule, "err", err)
+					}
+					if _, err := rt.State.AppendEvent(alertEvent(ev)); err != nil {
+						rt.Log.Warn("alerts: record event failed", "rule", ev.Alert.Rule, "err", err)
+					}
+					rt.Log.Info("alert", "rule", ev.Alert.Rule, "subject", ev.Alert.Subject, "resolved", ev.Resolved)
+					select {
+					case out <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// alertEvent is the event log entry for an alert firing or resolving.
+func alertEvent(ev alerts.Event) v1.Event {
+	a := ev.Alert
+	e := v1.Event{
+		Type:     v1.EventAlert,
+		Resource: "alert/" + a.Rule,
+		Node:     a.Node,
+		Severity: v1.SeverityWarning,
+		Message:  a.Message,
+	}
+	if ev.Resolved {
+		e.Type, e.Severity = v1.EventAlertResolved, v1.SeverityInfo
+		e.Message = fmt.Sprintf("%s resolved for %s", a.Rule, a.Subject)
+	}
+	return e
+}
+
+// autoscaled reports whether any service has deploy.autoscale.
+func autoscaled(services []v1.ServiceSpec) bool {
+	for _, s := range services {
+		if s.Deploy != nil && s.Deploy.Autoscale != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// startAutoscaler evaluates autoscaled services every
+// orchestrator.AutoscaleInterval until ctx is done. It returns nil when no
+// service is autoscaled.
+func startAutoscaler(ctx context.Context, rt *Runtime, docker *orchestrator.Client, collector *metrics.Collector, node string) <-chan orchestrator.ScaleDecision {
+	if !autoscaled(rt.Config.Services) {
+		return nil
+	}
+	scaler := orchestrator.NewAutoscaler(orchestrator.NewScaler(docker, rt.State, rt.Log).WithHooks(rt.Plugins), node, rt.Log)
+	out := make(chan orchestrator.ScaleDecision, 16)
+	go func() {
+		ticker := time.NewTicker(orchestrator.AutoscaleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				for _, d := range scaler.Evaluate(ctx, rt.Config.Services, collector.AllMetrics(), now) {
+					select {
+					case out <- d:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// printScaleDecision renders an autoscaling action as a single status line.
+func printScaleDecision(d orchestrator.ScaleDecision) {
+	ts := d.Time.Local().Format("15:04:05")
+	if d.Err != nil {
+		pprint.Error("%s  %s autoscale %d → %d failed: %v", ts, d.Service, d.From, d.To, d.Err)
+		return
+	}
+	pprint.Success("%s  %s scaled %d → %d (%s)", ts, d.Service, d.From, d.To, d.Reason)
+}
+
+// exportMetrics pushes the collector's readings, plus the host readings of
+// registered nodes, every telemetry.ExportInterval until ctx is done.
+func exportMetrics(ctx context.Context, rt *Runtime, exporter *telemetry.MetricsExporter, collector *metrics.Collector) {
+	ticker := time.NewTicker(telemetry.ExportInterval)
+	defer ticker.Stop()
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			nodes, err := rt.State.ListNodes()
+			if err != nil {
+				rt.Log.Warn("otlp: list nodes failed", "err", err)
+			}
+			err = exporter.Export(ctx, collector.AllMetrics(), nodes)
+			switch {
+			case err != nil && !failing:
+				rt.Log.Warn("otlp: metrics export failed", "err", err)
+			case err == nil && failing:
+				rt.Log.Info("otlp: metrics export recovered")
+			}
+			failing = err != nil
+		}
+	}
+}
+
+// printAlertEvent renders an alert firing or resolving as a single status line.
+func printAlertEvent(ev alerts.Event) {
+	ts := time.Now().Format("15:04:05")
+	if ev.Resolved {
+		pprint.Success("%s  alert %s resolved: %s", ts, ev.Alert.Rule, ev.Alert.Subject)
+		return
+	}
+	pprint.Error("%s  alert %s: %s", ts, ev.Alert.Rule, ev.Alert.Message)
+}
+
+// printHealthEvent renders a ServiceEvent as a single status line.
+func printHealthEvent(ev health.ServiceEvent) {
+	ts := ev.Time.Local().Format("15:04:05")
+	switch {
+	case ev.CrashLoop:
+		pprint.Error("%s  %s is crash-looping: %v", ts, ev.Service, ev.Err)
+	case ev.Restarted:
+		pprint.Warn("%s  %s restarted after liveness failures: %v", ts, ev.Service, ev.Err)
+	case ev.Err != nil:
+		pprint.Warn("%s  %s %s → %s: %v", ts, ev.Service, ev.From, ev.To, ev.Err)
+	default:
+		pprint.Success("%s  %s %s → %s", ts, ev.Service, ev.From, ev.To)
+	}
+}
+
+// printNodeEvent renders a heartbeat transition as a single status line.
+func printNodeEvent(ev remote.NodeEvent) {
+	ts := ev.Time.Local().Format("15:04:05")
+	switch ev.Status {
+	case v1.NodeOnline:
+		pprint.Success("%s  node %s is online", ts, ev.Node)
+	default:
+		pprint.Warn("%s  node %s is %s", ts, ev.Node, ev.Status)
+	}
+}
@@ -0,0 +1,93 @@
+// orbit audit — query the audit trail of commands run against this machine.
+package commands
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+func NewAuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "audit",
+		Annotations: readsState,
+		Short:       "Query the audit trail",
+		Long: `Every orbit command is recorded in ~/.orbit/audit.log, one JSON object per
+line: when it ran, who ran it, its command line, the node and service it
+acted on and how it exited.`,
+	}
+	cmd.AddCommand(newAuditLsCmd())
+	return cmd
+}
+
+func newAuditLsCmd() *cobra.Command {
+	var since time.Duration
+	var filter logger.AuditFilter
+
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List audit entries, oldest first",
+		Long: `List audit entries from audit.log and its rotated files, oldest first.
+--op matches a command ("nodes add") or a command group ("nodes"); the global
+--node flag limits the list to commands run against that node.`,
+		Example: `  orbit audit ls --since 24h --op deploy
+  orbit audit ls --service api --failed
+  orbit audit ls --user alice -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+			filter.Node = rt.Flags.Node
+			entries, err := logger.ReadAudit(filepath.Join(config.OrbitHome(), logger.AuditFile), filter)
+			if err != nil {
+				return err
+			}
+			return output.Render(rt.Flags.Output, entries, auditView)
+		},
+	}
+
+	cmd.Flags().DurationVar(&since, "since", 0, "Only entries from this long ago (e.g. 24h, 30m)")
+	cmd.Flags().StringVar(&filter.RunID, "run", "", "Only the entry of this run (support) ID")
+	cmd.Flags().StringVar(&filter.Op, "op", "", "Only this command or command group")
+	cmd.Flags().StringVar(&filter.Service, "service", "", "Only entries for this service")
+	cmd.Flags().StringVar(&filter.User, "user", "", "Only entries by this user")
+	cmd.Flags().BoolVar(&filter.Failed, "failed", false, "Only commands that failed")
+	return cmd
+}
+
+// auditView is the table layout for `orbit audit ls`.
+var auditView = output.View[logger.AuditEntry]{
+	ID: func(e logger.AuditEntry) string { return e.Timestamp.Format(time.RFC3339Nano) },
+	Columns: []output.Column[logger.AuditEntry]{
+		{Header: "TIME", Value: func(e logger.AuditEntry) string { return e.Timestamp.Local().Format("2006-01-02 15:04:05") }},
+		{Header: "USER", Value: func(e logger.AuditEntry) string { return e.User }},
+		{Header: "OP", Value: func(e logger.AuditEntry) string { return e.Op }},
+		{Header: "NODE", Value: func(e logger.AuditEntry) string { return orDash(e.Node) }},
+		{Header: "SERVICE", Value: func(e logger.AuditEntry) string { return orDash(e.Service) }},
+		{Header: "EXIT", Value: func(e logger.AuditEntry) string { return strconv.Itoa(e.ExitCode) }},
+		{Header: "RUN", Wide: true, Value: func(e logger.AuditEntry) string { return orDash(e.RunID) }},
+		{Header: "COMMAND", Wide: true, Value: func(e logger.AuditEntry) string { return strings.Join(e.Args, " ") }},
+		{Header: "META", Wide: true, Value: auditMeta},
+		{Header: "ERROR", Wide: true, Value: func(e logger.AuditEntry) string { return orDash(e.Error) }},
+	},
+}
+
+// auditMeta renders an entry's meta as sorted key=value pairs.
+func auditMeta(e logger.AuditEntry) string {
+	pairs := make([]string, 0, len(e.Meta))
+	for k, v := range e.Meta {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return orDash(strings.Join(pairs, ","))
+}
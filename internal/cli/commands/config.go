@@ -0,0 +1,70 @@
+// orbit config — inspect and validate the orbit.yaml format.
+package commands
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "config",
+		Annotations: readsState,
+		Short:       "Inspect and validate orbit.yaml",
+	}
+	cmd.AddCommand(newConfigSchemaCmd(), newConfigValidateCmd())
+	return cmd
+}
+
+func newConfigSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema for orbit.yaml",
+		Example: `  orbit config schema > orbit.schema.json
+  # then in orbit.yaml:
+  # yaml-language-server: $schema=./orbit.schema.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(config.Schema())
+		},
+	}
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Strictly validate orbit.yaml, rejecting unknown keys",
+		Long: `Load orbit.yaml strictly, rejecting unknown keys and invalid values, and
+warn about settings that are valid but weaken isolation, such as privileged
+services.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+
+			path := rt.Flags.ConfigFile
+			if path == "" {
+				found, err := config.DiscoverProjectConfig()
+				if err != nil {
+					return err
+				}
+				path = found
+			}
+
+			cfg, err := config.LoadWithOptions(path, config.LoadOptions{Strict: true})
+			if err != nil {
+				pprint.Error("%s is invalid", path)
+				return err
+			}
+			for _, w := range cfg.Warnings() {
+				pprint.Warn("%s", w)
+			}
+			pprint.Success("%s is valid", path)
+			return nil
+		},
+	}
+}
@@ -4,9 +4,14 @@ package commands
 import (
 	"context"
 
+	"github.com/f9-o/orbit/internal/cli/output"
 	"github.com/f9-o/orbit/internal/core/config"
 	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/plugin"
 	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/proxy/traefik"
+	"github.com/f9-o/orbit/internal/remote"
 )
 
 // contextKey is the key type for values stored in a command context.
@@ -16,18 +21,106 @@ const runtimeContextKey contextKey = "orbit.runtime"
 
 // GlobalFlags holds the parsed global flags for use by subcommands.
 type GlobalFlags struct {
+	ConfigFile string
 	Node       string
 	Debug      bool
-	JSONOutput bool
+	Output     output.Options
 	DryRun     bool
+	Yes        bool // --yes: never prompt
+	Strict     bool
+	StrictKeys bool   // --strict-host-keys: refuse untrusted SSH hosts
+	DockerHost string // --docker-host: daemon URL or Docker context name
+	Project    string // --project, else project.name: whose state commands act on
 }
 
 // Runtime is the shared dependency bundle injected into each subcommand via context.
 type Runtime struct {
-	Config *config.Config
-	Log    *logger.Logger
-	State  *state.DB
-	Flags  GlobalFlags
+	Config  *config.Config
+	Log     *logger.Logger
+	State   *state.DB
+	Plugins *plugin.Host // loaded from ~/.orbit/plugins; never nil
+	Flags   GlobalFlags
+
+	// Audit is written to audit.log when the command exits, with its exit
+	// status. Commands fill in Service and Meta; nil for commands not audited.
+	Audit *logger.AuditEntry
+}
+
+// ReadOnlyState annotates commands, or groups of them, that only read the
+// state DB. It is opened read-only for them, so they can run beside one
+// another.
+const ReadOnlyState = "orbit.state.read-only"
+
+var readsState = map[string]string{ReadOnlyState: "true"}
+
+// audit records the service a command acts on, and details of what it did,
+// in the command's audit entry.
+func (rt *Runtime) audit(service string, meta map[string]string) {
+	if rt.Audit == nil {
+		return
+	}
+	rt.Audit.Service = service
+	if rt.Audit.Meta == nil {
+		rt.Audit.Meta = map[string]string{}
+	}
+	for k, v := range meta {
+		rt.Audit.Meta[k] = v
+	}
+}
+
+// NewPool returns an SSH connection pool honouring --strict-host-keys and the
+// ssh: limits in orbit.yaml.
+func (rt *Runtime) NewPool() *remote.Pool {
+	return remote.NewPool(rt.Log).
+		WithStrictHostKeys(rt.Flags.StrictKeys).
+		WithHooks(rt.Plugins).
+		WithLimits(remote.PoolLimits{
+			MaxConns:    rt.Config.SSH.MaxConnections,
+			IdleTimeout: rt.Config.SSH.IdleTimeout,
+			MaxSessions: rt.Config.SSH.MaxSessions,
+		})
+}
+
+// DockerHost is the container daemon to connect to: --docker-host, else the
+// docker_host of the --node node, else docker_host in orbit.yaml. "" leaves
+// the choice to DOCKER_HOST and the current Docker context.
+func (rt *Runtime) DockerHost() string {
+	if rt.Flags.DockerHost != "" {
+		return rt.Flags.DockerHost
+	}
+	if name := rt.Flags.Node; name != "" {
+		if n := rt.Config.NodeByName(name); n != nil && n.DockerHost != "" {
+			return n.DockerHost
+		}
+		if rt.State != nil {
+			if info, err := rt.State.GetNode(name); err == nil && info != nil && info.Spec.DockerHost != "" {
+				return info.Spec.DockerHost
+			}
+		}
+	}
+	return rt.Config.DockerHost
+}
+
+// NewContainerClient connects to the container runtime selected by the
+// `runtime:` key in orbit.yaml (Docker unless set to podman), at DockerHost,
+// kept to the containers of the project whose state commands act on. With
+// the traefik proxy backend, containers of proxied services get Traefik
+// labels.
+func (rt *Runtime) NewContainerClient() (*orchestrator.Client, error) {
+	client, err := orchestrator.NewRuntime(rt.Config.Runtime, rt.DockerHost(), rt.Log)
+	if err != nil {
+		return nil, err
+	}
+	client.WithProject(rt.Flags.Project)
+	if px := rt.Config.Proxy; px.Backend == "traefik" {
+		client.WithProxy(traefik.New(traefik.Options{
+			Network:       px.Traefik.Network,
+			EntryPoint:    px.Traefik.EntryPoint,
+			TLSEntryPoint: px.Traefik.TLSEntryPoint,
+			CertResolver:  px.Traefik.CertResolver,
+		}).Apply)
+	}
+	return client, nil
 }
 
 // NewContext returns a new context carrying the Runtime.
@@ -38,10 +131,19 @@ func NewContext(parent context.Context, rt *Runtime) context.Context {
 	return context.WithValue(parent, runtimeContextKey, rt)
 }
 
+// LookupRuntime returns the Runtime in ctx, if PersistentPreRunE stored one.
+func LookupRuntime(ctx context.Context) (*Runtime, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	rt, ok := ctx.Value(runtimeContextKey).(*Runtime)
+	return rt, ok && rt != nil
+}
+
 // FromContext extracts the Runtime from ctx. Panics if not present (programming error).
 func FromContext(ctx context.Context) *Runtime {
-	rt, ok := ctx.Value(runtimeContextKey).(*Runtime)
-	if !ok || rt == nil {
+	rt, ok := LookupRuntime(ctx)
+	if !ok {
 		panic("orbit: Runtime not found in context — missing PersistentPreRunE?")
 	}
 	return rt
@@ -0,0 +1,150 @@
+// orbit cp — copy files between the local machine and service containers.
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/internal/transfer"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+func NewCpCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cp <src> <dst>",
+		Short: "Copy files or directories to and from a service container",
+		Long: `Copy files or directories between the local filesystem and a service container.
+
+Container paths are written as SERVICE:PATH. Exactly one side must be a
+container path. Directories are copied recursively. For services on a remote
+node (--node), files are streamed over SSH and placed with docker cp on the host.`,
+		Args: cobra.ExactArgs(2),
+		Example: `  orbit cp web:/etc/nginx/nginx.conf ./nginx.conf
+  orbit cp ./public web:/usr/share/nginx/html
+  orbit cp --node prod-01 api:/var/log/app ./logs`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			src, dst := transfer.ParseLocation(args[0]), transfer.ParseLocation(args[1])
+			if src.Local() == dst.Local() {
+				return fmt.Errorf("exactly one of %q and %q must be a SERVICE:PATH container path", args[0], args[1])
+			}
+			container := src
+			if src.Local() {
+				container = dst
+			}
+			if container.Path == "" {
+				return fmt.Errorf("%q: container path is empty", container)
+			}
+
+			node := nodeOrLocal(rt.Flags.Node)
+			svc, err := rt.State.GetServiceState(node, container.Service)
+			if err != nil {
+				return fmt.Errorf("state: %w", err)
+			}
+			if svc == nil || svc.ContainerID == "" {
+				return fmt.Errorf("service %q is not running on %q. Try 'orbit up'", container.Service, node)
+			}
+
+			var copier *transfer.Copier
+			if node == "local" {
+				docker, err := rt.NewContainerClient()
+				if err != nil {
+					return fmt.Errorf("docker: %w", err)
+				}
+				defer docker.Close()
+				copier = transfer.NewLocalCopier(docker)
+			} else {
+				info, err := remote.NewRegistry(rt.State).Get(node)
+				if err != nil {
+					return err
+				}
+				pool := rt.NewPool()
+				defer pool.Close()
+				copier = transfer.NewRemoteCopier(pool, info)
+			}
+
+			var total int64
+			if src.Local() {
+				if total, err = transfer.Size(src.Path); err != nil {
+					return err
+				}
+			}
+			bar := newCopyProgress(fmt.Sprintf("%s → %s", src, dst), total)
+			copier.WithProgress(bar.set)
+
+			if src.Local() {
+				err = copier.Upload(cmd.Context(), src.Path, svc.ContainerID, dst.Path)
+			} else {
+				err = copier.Download(cmd.Context(), svc.ContainerID, src.Path, dst.Path)
+			}
+			bar.done(err == nil)
+			if err != nil {
+				return fmt.Errorf("cp: %w", err)
+			}
+			size := bar.n
+			if total > 0 {
+				size = total
+			}
+			pprint.Success("Copied %s → %s (%s)", src, dst, humanSize(size))
+			return nil
+		},
+	}
+	return cmd
+}
+
+// copyProgress draws a progress bar when the size is known up front and a
+// running byte count otherwise.
+type copyProgress struct {
+	label string
+	total int64
+	n     int64
+	bar   *pprint.Progress
+}
+
+func newCopyProgress(label string, total int64) *copyProgress {
+	p := &copyProgress{label: label, total: total}
+	if total > 0 {
+		p.bar = pprint.NewProgress(label, 100, 30)
+	}
+	return p
+}
+
+func (p *copyProgress) set(n int64) {
+	p.n = n
+	if p.bar != nil {
+		// archive headers make the stream slightly larger than the content
+		p.bar.Set(int(min(n*100/p.total, 99)))
+		return
+	}
+	if pprint.Interactive() {
+		fmt.Fprintf(os.Stdout, "\r%s  %s", p.label, humanSize(n))
+	}
+}
+
+func (p *copyProgress) done(ok bool) {
+	switch {
+	case p.bar != nil && ok:
+		p.bar.Set(100)
+	case p.n > 0 && pprint.Interactive():
+		fmt.Println()
+	case p.n > 0:
+		fmt.Printf("%s  %s\n", p.label, humanSize(p.n))
+	}
+}
+
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -2,86 +2,109 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	v1 "github.com/f9-o/orbit/api/v1"
 	"github.com/f9-o/orbit/internal/health"
 	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/errs"
 	"github.com/f9-o/orbit/pkg/pprint"
 )
 
 func NewDeployCmd() *cobra.Command {
-	var tag string
+	var tag, confirm string
 	var timeout time.Duration
-	var dryRun bool
+	var dryRun, skipScan bool
+	var selector []string
 
 	cmd := &cobra.Command{
 		Use:   "deploy <service>",
 		Short: "Rolling update a running service to a new image tag",
-		Args:  cobra.ExactArgs(1),
+		Long: `Rolling update a service to a new image tag. --selector deploys every
+service in orbit.yaml whose labels match instead, one at a time in
+orbit.yaml order, stopping at the first that fails.
+
+With scan.deploy set in orbit.yaml, each image is scanned for
+vulnerabilities first and refused at scan.fail_on or worse; --skip-scan
+deploys without scanning.
+
+Deploys to an environment deploy_policy.protected lists are refused outside
+its maintenance windows, wait for its approval webhook to approve each
+service, and, with deploy_policy.confirm, for the environment's name to be
+typed; --confirm gives it up front, for scripts.`,
+		Args: cobra.MaximumNArgs(1),
 		Example: `  orbit deploy web
   orbit deploy web --tag v1.2.0
   orbit deploy web --tag latest --timeout 3m
-  orbit deploy web --dry-run`,
+  orbit deploy web --dry-run
+  orbit deploy web --tag v1.2.0 --confirm production
+  orbit deploy -l tier=backend --tag v1.2.0`,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			rt := FromContext(cmd.Context())
-			name := args[0]
+			services, err := deployServices(rt, args, selector)
+			if err != nil {
+				return err
+			}
+			for _, svc := range services {
+				rt.audit(svc.Name, map[string]string{"image": orchestrator.ResolveImage(svc.Image, tag)})
+			}
 
-			svc := rt.Config.ServiceByName(name)
-			if svc == nil {
-				pprint.Error("Service %q not found in orbit.yaml", name)
-				return fmt.Errorf("service %q not found", name)
+			if dryRun || rt.Flags.DryRun {
+				planned := make([]v1.ServiceSpec, len(services))
+				for i, svc := range services {
+					planned[i] = svc
+					planned[i].Image = orchestrator.ResolveImage(svc.Image, tag)
+				}
+				planner, done := buildPlanner(cmd, rt)
+				defer done()
+				plan, err := planner.Plan(cmd.Context(), planned, nodeOrLocal(rt.Flags.Node), false)
+				if err != nil {
+					return fmt.Errorf("plan: %w", err)
+				}
+				pprint.Warn("DRY RUN — no changes will be made")
+				printPlan(plan)
+				return nil
 			}
 
-			pprint.Header("Rolling Deploy — " + name)
-			pprint.KV("Service", name)
-			pprint.KV("Image", svc.Image)
-			if tag != "" {
-				pprint.KV("Tag", tag)
+			g := deployGate(rt)
+			if g.Protected() {
+				if err := confirmDeploy(rt, g, confirm); err != nil {
+					return err
+				}
 			}
-			pprint.KV("Node", func() string {
-				if rt.Flags.Node != "" {
-					return rt.Flags.Node
+			var checks []deployCheck
+			if !skipScan {
+				policy, err := scanPolicy(rt)
+				if err != nil {
+					return err
 				}
-				return "local"
-			}())
-			if dryRun {
-				pprint.Warn("DRY RUN — no changes will be made")
+				checks = append(checks, deployCheck{"Scanning %s for vulnerabilities", policy})
 			}
-			fmt.Println()
+			checks = append(checks, deployCheck{"Checking deploy_policy for %s", g.Policy()})
 
-			docker, err := orchestrator.NewClient("", rt.Log)
+			docker, err := rt.NewContainerClient()
 			if err != nil {
 				return fmt.Errorf("docker: %w", err)
 			}
 			defer docker.Close()
 
-			checker := health.NewChecker(rt.Log)
-			deployer := orchestrator.NewDeployer(docker, rt.State, checker, rt.Log)
-
-			// Step 1: Pull
-			sp1 := pprint.NewSpinner("Pulling new image")
-			sp1.Start()
-
-			err = deployer.Deploy(cmd.Context(), *svc, rt.Flags.Node, orchestrator.DeployOptions{
-				Tag:     tag,
-				Timeout: timeout,
-				DryRun:  dryRun,
-			})
-
-			if err != nil {
-				sp1.Stop(false)
-				pprint.Error("Deploy failed: %v", err)
-				pprint.Info("Run `orbit logs %s` to inspect the failed container.", name)
-				return err
+			// Selected services deploy one at a time, stopping at the first
+			// that fails so the rest keep running what they have.
+			for i, svc := range services {
+				if err := deployService(cmd, rt, docker, checks, svc, tag, timeout); err != nil {
+					if rest := services[i+1:]; len(rest) > 0 {
+						pprint.Warn("Not deployed: %s", strings.Join(serviceNames(rest), ", "))
+					}
+					return err
+				}
 			}
-			sp1.Stop(true)
-
-			fmt.Println()
-			pprint.Success("Deploy complete — %s is running the new image", name)
 			return nil
 		},
 	}
@@ -89,5 +112,152 @@ func NewDeployCmd() *cobra.Command {
 	cmd.Flags().StringVar(&tag, "tag", "", "Image tag to deploy (default: current tag in orbit.yaml)")
 	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "Health check timeout before rollback")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Simulate deploy without making changes")
+	cmd.Flags().StringVar(&confirm, "confirm", "", "Name of the protected environment being deployed to, confirming the deploy")
+	cmd.Flags().BoolVar(&skipScan, "skip-scan", false, "Deploy without the vulnerability scan scan.deploy asks for")
+	addSelectorFlag(cmd, &selector)
 	return cmd
 }
+
+// deployServices returns the services `orbit deploy` deploys: the one
+// named, or those in orbit.yaml that the --selector expressions match.
+func deployServices(rt *Runtime, args, selector []string) ([]v1.ServiceSpec, error) {
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(args) == 1 && len(sel) > 0:
+		return nil, errs.Newf(errs.ErrValidation, "deploy", "name a service or give --selector, not both")
+	case len(args) == 1:
+		svc := rt.Config.ServiceByName(args[0])
+		if svc == nil {
+			pprint.Error("Service %q not found in orbit.yaml", args[0])
+			return nil, fmt.Errorf("service %q not found", args[0])
+		}
+		return []v1.ServiceSpec{*svc}, nil
+	case len(sel) == 0:
+		return nil, errs.Newf(errs.ErrValidation, "deploy", "no service named").
+			WithAdvice("Name a service, as in orbit deploy web, or select services by label with --selector")
+	}
+	services := rt.Config.SelectServices(sel, nodeOrLocal(rt.Flags.Node), rt.State.ProjectName())
+	if len(services) == 0 {
+		return nil, noneSelected(rt, sel)
+	}
+	return services, nil
+}
+
+// deployCheck is a policy orbit deploy checks each image against before
+// deploying it, with a spinner labelled by formatting the image into label.
+type deployCheck struct {
+	label  string
+	policy orchestrator.Policy // nil when there is nothing to check
+}
+
+// deployService rolls svc out to the --node, drawing its progress, once
+// checks pass.
+func deployService(cmd *cobra.Command, rt *Runtime, docker *orchestrator.Client, checks []deployCheck, svc v1.ServiceSpec, tag string, timeout time.Duration) error {
+	name := svc.Name
+	pprint.Header("Rolling Deploy — " + name)
+	pprint.KV("Service", name)
+	pprint.KV("Image", svc.Image)
+	if tag != "" {
+		pprint.KV("Tag", tag)
+	}
+	pprint.KV("Node", nodeOrLocal(rt.Flags.Node))
+	fmt.Println()
+
+	// The pull draws layer progress; each later step gets a spinner.
+	bars := &pullRenderer{}
+	docker.WithPullProgress(bars.update)
+	var sp *pprint.Spinner
+	endStep := func(ok bool) {
+		bars.finish(ok)
+		if sp != nil {
+			sp.Stop(ok)
+			sp = nil
+		}
+	}
+
+	checker := health.NewChecker(rt.Log).WithExecer(docker).WithInspector(docker)
+	deployer := orchestrator.NewDeployer(docker, rt.State, checker, rt.Log).WithHooks(rt.Plugins).
+		WithCommandHooks(commandHooks(rt, docker, os.Stdout)).
+		WithProgress(func(step orchestrator.DeployStep) {
+			endStep(step != orchestrator.StepRollback)
+			switch label := deployStepLabels[step]; {
+			case step == orchestrator.StepPreDeploy, step == orchestrator.StepPostDeploy:
+				// Hook commands print their own output, which a spinner would garble.
+				pprint.Info("Running %s hooks", step)
+			case label != "":
+				sp = pprint.NewSpinner(label)
+				sp.Start()
+			}
+		})
+	for _, c := range checks {
+		if c.policy == nil {
+			continue
+		}
+		deployer.WithPolicy(func(ctx context.Context, spec v1.ServiceSpec, image string) error {
+			sp = pprint.NewSpinner(fmt.Sprintf(c.label, image))
+			sp.Start()
+			err := c.policy(ctx, spec, image)
+			endStep(err == nil)
+			return err
+		})
+	}
+
+	err := deployer.Deploy(cmd.Context(), svc, nodeOrLocal(rt.Flags.Node), orchestrator.DeployOptions{
+		Tag:     tag,
+		Timeout: timeout,
+	})
+	endStep(err == nil)
+
+	if err != nil {
+		pprint.Error("Deploy failed: %v", err)
+		switch {
+		case errs.IsCode(err, errs.ErrImageVulnerable):
+			pprint.Info("Run `orbit scan %s` for the full report.", strings.TrimSpace(name+" "+tagFlag(tag)))
+		case errs.IsCode(err, errs.ErrPolicyWindow), errs.IsCode(err, errs.ErrPolicyApproval):
+		case errs.IsCode(err, errs.ErrServiceVerify):
+			pprint.Info("The new replicas were rolled back; `orbit logs %s --since 10m` shows what they logged.", name)
+		case errs.IsCode(err, errs.ErrServiceMigration):
+			pprint.Info("Nothing was replaced: the old replicas of %s still serve. Fix the migration and deploy again.", name)
+		case errs.IsCode(err, errs.ErrServiceHook):
+			pprint.Info("Fix the hook command in orbit.yaml, or set on_failure: ignore if it may fail.")
+		default:
+			pprint.Info("Run `orbit logs %s` to inspect the failed container.", name)
+		}
+		return err
+	}
+
+	fmt.Println()
+	pprint.Success("Deploy complete — %s is running the new image", name)
+	return nil
+}
+
+// tagFlag is the --tag flag that repeats tag, or "" for none.
+func tagFlag(tag string) string {
+	if tag == "" {
+		return ""
+	}
+	return "--tag " + tag
+}
+
+// serviceNames returns the names of services.
+func serviceNames(services []v1.ServiceSpec) []string {
+	names := make([]string, len(services))
+	for i, s := range services {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// deployStepLabels are the spinner labels of deploy steps after the pull,
+// which shows layer progress instead.
+var deployStepLabels = map[orchestrator.DeployStep]string{
+	orchestrator.StepMigrate:  "Running migrations",
+	orchestrator.StepStart:    "Starting new containers",
+	orchestrator.StepHealth:   "Waiting for health checks",
+	orchestrator.StepCutover:  "Cutting over",
+	orchestrator.StepVerify:   "Verifying the new replicas",
+	orchestrator.StepRollback: "Rolling back",
+}
@@ -0,0 +1,132 @@
+// orbit diff — compare services' running containers with orbit.yaml.
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+func NewDiffCmd() *cobra.Command {
+	var exitCode bool
+
+	cmd := &cobra.Command{
+		Use:         "diff [service...]",
+		Annotations: readsState,
+		Short:       "Show how running containers have drifted from orbit.yaml",
+		Long: `Compare each service's definition in orbit.yaml, field by field, with the
+configuration of its running container — image, environment, ports,
+volumes, labels, user, restart policy, command and other process settings,
+capabilities and security options, ulimits, sysctls, DNS, networks, name and
+replica count — and say what converges it: ` + "`orbit deploy`" + ` for a new
+image, a new replica count or a service with several replicas to recreate,
+and ` + "`orbit up`" + ` for any other change, which it applies in place where
+Docker allows.
+
+Labels the container has beyond those in orbit.yaml (from the image, Orbit
+or a proxy integration) are not drift. Values of secret-looking environment
+variables are masked.`,
+		Example: `  orbit diff web
+  orbit diff               # every service
+  orbit diff --exit-code   # exit 1 when anything drifted, e.g. in CI
+  orbit diff web -o json`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+
+			specs := rt.Config.Services
+			if len(args) > 0 {
+				specs = specs[:0:0]
+				for _, name := range args {
+					svc := rt.Config.ServiceByName(name)
+					if svc == nil {
+						return errs.Newf(errs.ErrServiceNotFound, "diff", "service %q not found in orbit.yaml", name)
+					}
+					specs = append(specs, *svc)
+				}
+			}
+
+			docker, err := rt.NewContainerClient()
+			if err != nil {
+				return fmt.Errorf("docker: %w", err)
+			}
+			defer docker.Close()
+			planner := orchestrator.NewPlanner(docker, rt.State, rt.Log)
+
+			node := nodeOrLocal(rt.Flags.Node)
+			drifts := make([]*orchestrator.Drift, 0, len(specs))
+			drifted := 0
+			for _, spec := range specs {
+				d, err := planner.Drift(cmd.Context(), spec, node)
+				if err != nil {
+					return err
+				}
+				if d.Drifted() {
+					drifted++
+				}
+				drifts = append(drifts, d)
+			}
+
+			out := rt.Flags.Output
+			switch {
+			case out.Quiet:
+				for _, d := range drifts {
+					if d.Drifted() {
+						fmt.Println(d.Service)
+					}
+				}
+			case out.Format.Structured():
+				if err := output.Encode(out, drifts); err != nil {
+					return err
+				}
+			default:
+				printDrifts(drifts, drifted)
+			}
+			if exitCode && drifted > 0 {
+				return &ExitError{Code: 1}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&exitCode, "exit-code", false, "Exit with status 1 when any service has drifted")
+	return cmd
+}
+
+// printDrifts renders each service's field-level differences, in the
+// layout of `orbit plan`.
+func printDrifts(drifts []*orchestrator.Drift, drifted int) {
+	if len(drifts) == 0 {
+		pprint.Info("No services in orbit.yaml")
+		return
+	}
+	pprint.Header("Drift — " + drifts[0].Node)
+	for _, d := range drifts {
+		if !d.Drifted() {
+			fmt.Println(pprint.StyleMuted.Render("    " + d.Service + " (in sync)"))
+			continue
+		}
+		id := ""
+		if d.ContainerID != "" {
+			id = " " + d.ContainerID[:min(12, len(d.ContainerID))]
+		}
+		fmt.Println(pprint.StyleWarning.Render("  ~ "+d.Service) + pprint.StyleMuted.Render(id))
+		changes := make([]pprint.Change, len(d.Changes))
+		for i, c := range d.Changes {
+			changes[i] = pprint.Change{Field: c.Field, From: c.From, To: c.To}
+		}
+		pprint.NewDiff().Fields("      ", changes)
+		fmt.Println(pprint.StyleMuted.Render("      → " + d.Command()))
+	}
+	fmt.Println()
+	if drifted == 0 {
+		pprint.Success("No drift. Running containers match orbit.yaml.")
+		return
+	}
+	pprint.Warn("%d of %d services drifted from orbit.yaml", drifted, len(drifts))
+}
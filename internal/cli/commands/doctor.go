@@ -0,0 +1,112 @@
+// orbit doctor — diagnose the local environment.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/doctor"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+func NewDoctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:         "doctor",
+		Annotations: readsState,
+		Short:       "Check Docker, state, config, nodes, disk, certificates, and ports",
+		Example: `  orbit doctor
+  orbit doctor -o json
+  orbit doctor -q      # names of checks that warned or failed`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			results := runDoctor(cmd.Context(), rt)
+
+			out := rt.Flags.Output
+			switch {
+			case out.Quiet:
+				for _, r := range results {
+					if r.Status == doctor.StatusWarn || r.Status == doctor.StatusFail {
+						fmt.Println(r.Name)
+					}
+				}
+			case out.Format.Structured():
+				if err := output.Encode(out, results); err != nil {
+					return err
+				}
+			default:
+				printDoctor(results)
+			}
+
+			if doctor.Failed(results) {
+				return fmt.Errorf("doctor: one or more checks failed")
+			}
+			return nil
+		},
+	}
+}
+
+// runDoctor runs every doctor check.
+func runDoctor(ctx context.Context, rt *Runtime) []doctor.Result {
+	docker, dockerErr := rt.NewContainerClient()
+	if docker != nil {
+		defer docker.Close()
+	}
+	pool := rt.NewPool()
+	defer pool.Close()
+
+	running := map[string]bool{}
+	if states, err := rt.State.ListServiceStates(nodeOrLocal(rt.Flags.Node)); err == nil {
+		for _, s := range states {
+			running[s.Name] = s.ContainerID != ""
+		}
+	}
+
+	return doctor.Run(ctx, []doctor.Check{
+		doctor.Docker(docker, dockerErr),
+		doctor.State(rt.State),
+		doctor.Config(rt.Flags.ConfigFile),
+		doctor.Nodes(remote.NewRegistry(rt.State), pool),
+		doctor.SSHPool(pool),
+		doctor.Disk(config.OrbitHome()),
+		doctor.Certificates(rt.Config.SSL.ResolvedCertDir(), time.Now()),
+		doctor.Ports(rt.Config.Services, running),
+	})
+}
+
+// printDoctor renders one line per check with remediation advice under failures.
+func printDoctor(results []doctor.Result) {
+	pprint.Header("Orbit Doctor")
+	counts := map[doctor.Status]int{}
+	for _, r := range results {
+		counts[r.Status]++
+		var mark string
+		switch r.Status {
+		case doctor.StatusPass:
+			mark = pprint.StyleSuccess.Render("✓")
+		case doctor.StatusWarn:
+			mark = pprint.StyleWarning.Render("!")
+		case doctor.StatusFail:
+			mark = pprint.StyleError.Render("✖")
+		default:
+			mark = pprint.StyleMuted.Render("-")
+		}
+		detail := r.Detail
+		if r.Code != "" {
+			detail = fmt.Sprintf("%s [%s]", detail, r.Code)
+		}
+		fmt.Printf("  %s %-22s %s\n", mark, r.Name, detail)
+		if r.Advice != "" && r.Status != doctor.StatusPass {
+			fmt.Println(pprint.StyleMuted.Render("      → " + r.Advice))
+		}
+	}
+	fmt.Println()
+	fmt.Printf("  %d passed, %d warnings, %d failed, %d skipped\n",
+		counts[doctor.StatusPass], counts[doctor.StatusWarn], counts[doctor.StatusFail], counts[doctor.StatusSkip])
+}
@@ -3,26 +3,43 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/errs"
 )
 
 func NewDownCmd() *cobra.Command {
-	var removeVolumes bool
+	var (
+		removeVolumes bool
+		timeout       time.Duration
+		selector      []string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "down [service...]",
 		Short: "Stop and remove running services",
+		Long: `Stop and remove running services, every replica of each. Services stop
+one at a time, those that depend on others (depends_on) first, so a
+dependency outlives its dependents. Each container gets its stop_signal
+and, before it is killed, the service's stop_grace_period, or --timeout
+when given.
+
+--selector stops the services on the node whose labels match instead of
+those named.`,
 		Example: `  orbit down              # stop all services
   orbit down web worker   # stop specific services
+  orbit down -l tier=backend
+  orbit down --timeout 1m # give every container a minute to exit
   orbit down --volumes    # also remove named volumes`,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			rt := FromContext(cmd.Context())
 
-			docker, err := orchestrator.NewClient("", rt.Log)
+			docker, err := rt.NewContainerClient()
 			if err != nil {
 				return fmt.Errorf("docker: %w", err)
 			}
@@ -32,20 +49,31 @@ func NewDownCmd() *cobra.Command {
 			if nodeName == "" {
 				nodeName = "local"
 			}
+			if len(selector) > 0 {
+				if len(args) > 0 {
+					return errs.Newf(errs.ErrValidation, "down", "name services or give --selector, not both")
+				}
+				if args, err = selectedServices(rt, nodeName, selector); err != nil {
+					return err
+				}
+			}
 
-			lm := orchestrator.NewLifecycleManager(docker, rt.State, rt.Log)
+			lm := orchestrator.NewLifecycleManager(docker, rt.State, rt.Log).
+				WithServices(rt.Config.Services).
+				WithCommandHooks(commandHooks(rt, docker, os.Stdout)).
+				WithStopTimeout(timeout)
 
 			if rt.Flags.DryRun {
-				what := "all services"
-				if len(args) > 0 {
-					what = fmt.Sprintf("%v", args)
+				plan, err := orchestrator.NewPlanner(docker, rt.State, rt.Log).PlanDown(nodeName, args)
+				if err != nil {
+					return fmt.Errorf("plan: %w", err)
 				}
-				fmt.Printf("[dry-run] would stop: %s on node %q\n", what, nodeName)
+				printPlan(plan)
 				return nil
 			}
 
 			if err := lm.Down(cmd.Context(), nodeName, args, removeVolumes); err != nil {
-				return fmt.Errorf("down: %w", err)
+				return err
 			}
 
 			fmt.Println("✓ Services stopped")
@@ -54,5 +82,7 @@ func NewDownCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&removeVolumes, "volumes", false, "Remove named volumes along with containers")
+	addSelectorFlag(cmd, &selector)
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Wait this long for each container to stop before killing it (default: the service's stop_grace_period)")
 	return cmd
 }
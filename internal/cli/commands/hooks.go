@@ -0,0 +1,30 @@
+// Package commands: running the shell commands of services' hooks: blocks.
+package commands
+
+import (
+	"context"
+	"io"
+
+	"github.com/f9-o/orbit/internal/hooks"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/remote"
+)
+
+// commandHooks returns the runner of services' hook commands: "container"
+// commands exec through docker, "node" commands go over SSH to registered
+// nodes. Their output is printed to out, or logged when out is nil.
+func commandHooks(rt *Runtime, docker *orchestrator.Client, out io.Writer) *hooks.Runner {
+	r := hooks.New(docker, rt.Log).WithNodes(func(ctx context.Context, name, cmd string, stdout, stderr io.Writer) (int, error) {
+		node, err := remote.NewRegistry(rt.State).Get(name)
+		if err != nil {
+			return -1, err
+		}
+		pool := rt.NewPool()
+		defer pool.Close()
+		return pool.Exec(ctx, node, cmd, stdout, stderr)
+	})
+	if out != nil && !rt.Flags.Output.Quiet {
+		r.WithOutput(out)
+	}
+	return r
+}
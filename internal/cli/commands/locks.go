@@ -0,0 +1,88 @@
+// orbit locks — inspect and clear per-service operation locks.
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+func NewLocksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "locks",
+		Short: "Manage deploy locks held on services",
+		Long: `Deploy, scale, up and down take a per-service lock so two operators
+cannot change the same service at once. Locks whose process has exited
+(or that are older than an hour) are taken over automatically. Locks are
+per project, so same-named services of two projects lock independently.`,
+	}
+	cmd.AddCommand(newLocksLsCmd(), newLocksUnlockCmd())
+	return cmd
+}
+
+func newLocksLsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:         "ls",
+		Annotations: readsState,
+		Short:       "List held service locks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			locks, err := rt.State.ListLocks()
+			if err != nil {
+				return err
+			}
+			return output.Render(rt.Flags.Output, locks, locksView)
+		},
+	}
+}
+
+func newLocksUnlockCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unlock <service>",
+		Short: "Force-release the lock on a service",
+		Long: `Remove a service's lock regardless of who holds it. Use this only when
+the holding operation was interrupted; releasing a live lock lets two
+operations race on the same containers.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			node := nodeOrLocal(rt.Flags.Node)
+			rt.audit(args[0], nil)
+			existed, err := rt.State.ForceUnlock(node, args[0])
+			if err != nil {
+				return err
+			}
+			if !existed {
+				pprint.Info("%s is not locked on %s", args[0], node)
+				return nil
+			}
+			pprint.Success("Released lock on %s (%s)", args[0], node)
+			return nil
+		},
+	}
+}
+
+// locksView is the table layout for `orbit locks ls`.
+var locksView = output.View[state.Lock]{
+	ID: func(l state.Lock) string { return l.Service },
+	Columns: []output.Column[state.Lock]{
+		{Header: "SERVICE", Value: func(l state.Lock) string { return l.Service }},
+		{Header: "PROJECT", Wide: true, Value: func(l state.Lock) string { return orDash(l.Project) }},
+		{Header: "NODE", Value: func(l state.Lock) string { return l.Node }},
+		{Header: "OPERATION", Value: func(l state.Lock) string { return l.Operation }},
+		{Header: "HOLDER", Value: func(l state.Lock) string { return l.Holder }},
+		{Header: "PID", Wide: true, Value: func(l state.Lock) string { return fmt.Sprint(l.PID) }},
+		{Header: "AGE", Value: func(l state.Lock) string { return fmtDuration(time.Since(l.AcquiredAt)) }},
+		{Header: "STALE", Value: func(l state.Lock) string {
+			if l.Stale(time.Now()) {
+				return "yes"
+			}
+			return "no"
+		}},
+	},
+}
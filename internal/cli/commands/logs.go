@@ -4,56 +4,193 @@ package commands
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/logship"
 	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/pprint"
 )
 
 func NewLogsCmd() *cobra.Command {
-	var follow bool
+	var follow, live, timestamps, stderrOnly bool
 	var tail int
 	var since time.Duration
+	var selector []string
+	var filter string
 
 	cmd := &cobra.Command{
-		Use:   "logs <service>",
-		Short: "Stream or tail logs from a service container",
-		Args:  cobra.ExactArgs(1),
+		Use:         "logs [service]",
+		Annotations: readsState,
+		Short:       "Stream or tail logs from a service container",
+		Long: `Show a service's logs. When orbit agent runs with --collect-logs, they are
+read from the files it keeps, which cover every replica and survive the
+deploys that replace containers; each line names the container that wrote
+it. Otherwise, and with --follow or --live, they come from the service's
+current container.
+
+--selector shows the logs of every service on the node whose labels match,
+instead of one: collected logs merged in time order, container logs each
+line prefixed with its service.
+
+Lines keep the colours their containers wrote only when orbit's own output
+is coloured: --no-color, NO_COLOR or a pipe strip them. --filter keeps the
+lines a regular expression matches, as grep -E would, and --stderr-only the
+lines written to stderr.`,
+		Args: cobra.MaximumNArgs(1),
 		Example: `  orbit logs web
   orbit logs web -f
-  orbit logs worker -n 200
-  orbit logs api --since 1h`,
+  orbit logs worker --tail 200
+  orbit logs api --since 1h
+  orbit logs api --since 24h -o json   # collected logs as JSON
+  orbit logs api -t --stderr-only --filter 'timeout|refused'
+  orbit logs -l tier=backend -f`,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			rt := FromContext(cmd.Context())
-			serviceName := args[0]
-
-			state, err := rt.State.GetServiceState(rt.Flags.Node, serviceName)
+			node := nodeOrLocal(rt.Flags.Node)
+			services, err := logServices(rt, node, args, selector)
 			if err != nil {
-				return fmt.Errorf("state: %w", err)
+				return err
+			}
+			var match *regexp.Regexp
+			if filter != "" {
+				if match, err = regexp.Compile(filter); err != nil {
+					return errs.Newf(errs.ErrValidation, "logs", "--filter: %v", err)
+				}
+			}
+			stream := ""
+			if stderrOnly {
+				stream = "stderr"
+			}
+
+			collected := !follow && !live
+			for _, name := range services {
+				collected = collected && logship.Collected(node, name)
+			}
+			if collected {
+				f := logship.Filter{Stream: stream, Match: match}
+				if since > 0 {
+					f.Since = time.Now().Add(-since)
+				}
+				if since == 0 || cmd.Flags().Changed("tail") {
+					f.Tail = tail
+				}
+				var lines []logship.Line
+				for _, name := range services {
+					read, err := logship.Read(node, name, f)
+					if err != nil {
+						return fmt.Errorf("collected logs: %w", err)
+					}
+					lines = append(lines, read...)
+				}
+				if len(services) > 1 {
+					sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time.Before(lines[j].Time) })
+				}
+				if out := rt.Flags.Output; out.Format.Structured() {
+					return output.Encode(out, lines)
+				}
+				for _, l := range lines {
+					text := l.Text
+					if !pprint.Color() {
+						text = pprint.StripANSI(text)
+					}
+					if timestamps {
+						fmt.Println(l.Time.Format(time.RFC3339Nano), l.Container, text)
+					} else {
+						fmt.Println(l.Container, text)
+					}
+				}
+				return nil
 			}
-			if state == nil {
-				return fmt.Errorf("service %q not found in state. Is it running? Try 'orbit up'", serviceName)
+
+			containers := make([]string, len(services))
+			for i, name := range services {
+				state, err := rt.State.GetServiceState(node, name)
+				if err != nil {
+					return fmt.Errorf("state: %w", err)
+				}
+				if state == nil {
+					return fmt.Errorf("service %q not found in state. Is it running? Try 'orbit up'", name)
+				}
+				containers[i] = state.ContainerID
 			}
 			_ = tail // tail param — Docker API uses 'since' + streaming
 
-			docker, err := orchestrator.NewClient("", rt.Log)
+			docker, err := rt.NewContainerClient()
 			if err != nil {
 				return fmt.Errorf("docker: %w", err)
 			}
 			defer docker.Close()
 
 			if follow {
-				fmt.Printf("◉ Following logs for %q (Ctrl+C to stop)...\n", serviceName)
+				fmt.Printf("◉ Following logs for %q (Ctrl+C to stop)...\n", strings.Join(services, ", "))
+			}
+			opts := orchestrator.LogStreamOptions{
+				Follow:     follow,
+				Since:      since,
+				Timestamps: timestamps,
+				StderrOnly: stderrOnly,
+				StripColor: !pprint.Color(),
+				Filter:     match,
+			}
+			if len(services) == 1 {
+				return docker.StreamLogs(cmd.Context(), containers[0], opts, os.Stdout)
 			}
 
-			return docker.StreamLogs(cmd.Context(), state.ContainerID, follow, since, os.Stdout)
+			// Several services stream at once, each line prefixed with its
+			// service.
+			width := 0
+			for _, name := range services {
+				width = max(width, len(name))
+			}
+			var mu sync.Mutex
+			var wg sync.WaitGroup
+			group := errs.NewGroup(errs.ErrDockerInspect, "logs")
+			for i, name := range services {
+				w := &prefixWriter{mu: &mu, w: os.Stdout, prefix: pprint.StyleMuted.Render(fmt.Sprintf("%-*s │ ", width, name))}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := docker.StreamLogs(cmd.Context(), containers[i], opts, w)
+					w.Flush()
+					group.Add(name, err)
+				}()
+			}
+			wg.Wait()
+			return group.Err()
 		},
 	}
 
 	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output in real-time")
-	cmd.Flags().IntVarP(&tail, "tail", "n", 100, "Number of lines to show from end of logs")
+	cmd.Flags().IntVar(&tail, "tail", 100, "Number of lines to show from end of logs")
 	cmd.Flags().DurationVar(&since, "since", 0, "Show logs since duration (e.g., 1h, 30m, 5s)")
+	cmd.Flags().BoolVar(&live, "live", false, "Read the current container's logs even when the agent collects them")
+	cmd.Flags().BoolVarP(&timestamps, "timestamps", "t", false, "Show the time each line was written")
+	cmd.Flags().BoolVar(&stderrOnly, "stderr-only", false, "Show only lines written to stderr")
+	cmd.Flags().StringVar(&filter, "filter", "", "Show only lines matching this regular expression")
+	addSelectorFlag(cmd, &selector)
 	return cmd
 }
+
+// logServices returns the services `orbit logs` shows: the one named, or
+// those on node that the --selector expressions match.
+func logServices(rt *Runtime, node string, args, selector []string) ([]string, error) {
+	switch {
+	case len(args) == 1 && len(selector) > 0:
+		return nil, errs.Newf(errs.ErrValidation, "logs", "name a service or give --selector, not both")
+	case len(args) == 1:
+		return args, nil
+	case len(selector) == 0:
+		return nil, errs.Newf(errs.ErrValidation, "logs", "no service named").
+			WithAdvice("Name a service, as in orbit logs web, or select services by label with --selector")
+	}
+	return selectedServices(rt, node, selector)
+}
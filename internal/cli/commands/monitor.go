@@ -6,16 +6,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/cli/output"
 	"github.com/f9-o/orbit/internal/metrics"
-	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/pprint"
 )
 
 func NewMonitorCmd() *cobra.Command {
@@ -32,7 +31,7 @@ func NewMonitorCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			rt := FromContext(cmd.Context())
 
-			docker, err := orchestrator.NewClient("", rt.Log)
+			docker, err := rt.NewContainerClient()
 			if err != nil {
 				return fmt.Errorf("docker: %w", err)
 			}
@@ -51,13 +50,12 @@ func NewMonitorCmd() *cobra.Command {
 			// Start collector
 			go collector.Run(ctx)
 
-			// Handle Ctrl+C
-			sigs := make(chan os.Signal, 1)
-			signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-			go func() {
-				<-sigs
-				cancel()
-			}()
+			if format == "" {
+				format = "table"
+				if rt.Flags.Output.Format == output.FormatJSON {
+					format = "json"
+				}
+			}
 
 			ticker := time.NewTicker(interval)
 			defer ticker.Stop()
@@ -75,6 +73,14 @@ func NewMonitorCmd() *cobra.Command {
 					case "json":
 						data, _ := json.Marshal(m)
 						fmt.Println(string(data))
+					case "prometheus":
+						nodes, err := rt.State.ListNodes()
+						if err != nil {
+							rt.Log.Debug("monitor: list nodes", "err", err)
+						}
+						if err := metrics.WritePrometheus(os.Stdout, m, nodes); err != nil {
+							return err
+						}
 					default:
 						printMetricsTable(m, nodeName)
 					}
@@ -83,23 +89,33 @@ func NewMonitorCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&format, "format", "table", "Output format: table | json | prometheus")
+	cmd.Flags().StringVar(&format, "format", "", "Output format: table | json | prometheus (default: table, or json with -o json)")
 	cmd.Flags().DurationVar(&interval, "interval", 2*time.Second, "Refresh interval")
 	return cmd
 }
 
 func printMetricsTable(m v1.Metrics, node string) {
-	fmt.Printf("\033[H\033[2J") // clear screen
+	if pprint.Interactive() {
+		fmt.Printf("\033[H\033[2J") // clear screen
+	} else {
+		fmt.Println()
+	}
 	fmt.Printf("◉ Orbit Monitor — %s — %s\n\n", node, time.Now().Format("15:04:05"))
+	if h := m.Host; h != nil {
+		fmt.Printf("Host: load %.2f %.2f %.2f · mem %s · disk %s · inodes %s\n\n",
+			h.Load1, h.Load5, h.Load15, percent(h.MemTotal, h.MemAvailable),
+			percent(h.DiskTotal, h.DiskFree), percent(h.InodesTotal, h.InodesFree))
+	}
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	fmt.Fprintln(w, "SERVICE\tCPU%\tMEM\tNET RX\tNET TX\tPIDs")
-	fmt.Fprintln(w, "-------\t----\t---\t------\t------\t----")
+	fmt.Fprintln(w, "SERVICE\tCPU%\tMEM\tNET RX\tNET TX\tBLK READ\tBLK WRITE\tDISK\tPIDs")
+	fmt.Fprintln(w, "-------\t----\t---\t------\t------\t--------\t---------\t----\t----")
 	for name, svc := range m.Services {
 		mem := fmt.Sprintf("%.1fMB", float64(svc.MemBytes)/1024/1024)
 		rx := fmt.Sprintf("%.1fKB", float64(svc.NetRxBytes)/1024)
 		tx := fmt.Sprintf("%.1fKB", float64(svc.NetTxBytes)/1024)
-		fmt.Fprintf(w, "%s\t%.1f%%\t%s\t%s\t%s\t%d\n",
-			name, svc.CPUPercent, mem, rx, tx, svc.PIDs)
+		fmt.Fprintf(w, "%s\t%.1f%%\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
+			name, svc.CPUPercent, mem, rx, tx,
+			humanSize(svc.BlockReadBytes), humanSize(svc.BlockWriteBytes), humanSize(svc.DiskBytes), svc.PIDs)
 	}
 	_ = w.Flush()
 }
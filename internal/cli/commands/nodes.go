@@ -2,16 +2,26 @@
 package commands
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
-	"text/tabwriter"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/inventory"
+	"github.com/f9-o/orbit/internal/metrics"
 	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/pprint"
+	"github.com/f9-o/orbit/pkg/pprint/prompt"
 	"github.com/f9-o/orbit/pkg/sshutil"
 )
 
@@ -24,11 +34,14 @@ func NewNodesCmd() *cobra.Command {
 
 	cmd.AddCommand(
 		newNodesAddCmd(),
+		newNodesImportCmd(),
 		newNodesRmCmd(),
 		newNodesLsCmd(),
 		newNodesInfoCmd(),
 		newNodesTestCmd(),
 		newNodesTrustCmd(),
+		newNodesRunCmd(),
+		newNodesExecLogsCmd(),
 	)
 	return cmd
 }
@@ -36,13 +49,16 @@ func NewNodesCmd() *cobra.Command {
 func newNodesAddCmd() *cobra.Command {
 	var keyPath string
 	var port int
+	var jump string
+	var heartbeat time.Duration
 
 	cmd := &cobra.Command{
 		Use:   "add <name> <user@host>",
 		Short: "Register a new remote node",
 		Args:  cobra.ExactArgs(2),
 		Example: `  orbit nodes add prod-01 deploy@192.168.1.10
-  orbit nodes add staging ubuntu@staging.example.com --key ~/.ssh/id_ed25519`,
+  orbit nodes add staging ubuntu@staging.example.com --key ~/.ssh/id_ed25519
+  orbit nodes add db-01 deploy@10.0.2.15 --jump ops@bastion.example.com:2222`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			rt := FromContext(cmd.Context())
 			name := args[0]
@@ -61,11 +77,13 @@ func newNodesAddCmd() *cobra.Command {
 
 			nodeInfo := v1.NodeInfo{
 				Spec: v1.NodeSpec{
-					Name: name,
-					Host: host,
-					User: user,
-					Key:  keyPath,
-					Port: port,
+					Name:              name,
+					Host:              host,
+					User:              user,
+					Key:               keyPath,
+					Port:              port,
+					ProxyJump:         jump,
+					HeartbeatInterval: heartbeat,
 				},
 				Status: v1.NodeOffline,
 			}
@@ -83,9 +101,173 @@ func newNodesAddCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&keyPath, "key", "", "Path to SSH private key")
 	cmd.Flags().IntVar(&port, "port", 22, "SSH port")
+	cmd.Flags().DurationVar(&heartbeat, "heartbeat", 0, "Heartbeat probe interval (default 30s)")
+	cmd.Flags().StringVar(&jump, "jump", "", "Reach the node through bastion hosts ([user@]host[:port], comma-separated)")
 	return cmd
 }
 
+// nodeImport is one node read by `orbit nodes import` and what was done
+// with it.
+type nodeImport struct {
+	Name   string   `json:"name"`
+	Host   string   `json:"host"`
+	User   string   `json:"user"`
+	Port   int      `json:"port"`
+	Groups []string `json:"groups"`
+	Action string   `json:"action"` // added, updated, unchanged or skipped (already registered)
+}
+
+func newNodesImportCmd() *cobra.Command {
+	var fromTerraform, fromAnsible string
+	var opts inventory.Options
+	var replace bool
+
+	cmd := &cobra.Command{
+		Use:   "import (--from-terraform <state.json> | --from-ansible <inventory>)",
+		Short: "Register nodes in bulk from Terraform/OpenTofu state or an Ansible inventory",
+		Long: `Read nodes from existing infrastructure-as-code outputs and register them all.
+
+--from-terraform takes a state file (terraform.tfstate), ` + "`terraform show -json`" + ` or
+` + "`terraform output -json`" + `; "-" reads standard input. An output named
+orbit_nodes — a list of {name, host, user, port, key, groups} objects —
+is used when present. Otherwise every instance of a known compute resource
+(aws_instance, google_compute_instance, hcloud_server, digitalocean_droplet,
+…) becomes a node named after its Name tag or name, at its public address
+(--private for the private one); the tags or labels orbit_user, orbit_port
+and orbit_groups set its user, port and groups.
+
+--from-ansible takes an INI or YAML inventory. Each host is named after its
+inventory hostname and put in its groups; ansible_host, ansible_user,
+ansible_port and ansible_ssh_private_key_file give its address, user, port
+and key.
+
+--user, --port and --key apply where the source names none; --group adds
+groups to every node. Nodes already registered are skipped unless
+--replace is given.`,
+		Example: `  terraform output -json | orbit nodes import --from-terraform - --user deploy
+  orbit nodes import --from-terraform terraform.tfstate --private --group prod
+  orbit nodes import --from-ansible inventory.ini --replace
+  orbit nodes import --from-ansible hosts.yml --dry-run`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+
+			path := fromTerraform + fromAnsible
+			var data []byte
+			var err error
+			if path == "-" {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = os.ReadFile(path)
+			}
+			if err != nil {
+				return errs.Wrap(err, errs.ErrConfig, "nodes.import")
+			}
+			if opts.Key == "" {
+				homeDir, _ := os.UserHomeDir()
+				opts.Key = fmt.Sprintf("%s/.ssh/id_ed25519", homeDir)
+			}
+
+			var specs []v1.NodeSpec
+			if fromTerraform != "" {
+				specs, err = inventory.Terraform(data, opts)
+			} else {
+				specs, err = inventory.Ansible(path, data, opts)
+			}
+			if err != nil {
+				return errs.Wrap(err, errs.ErrValidation, "nodes.import").WithAdvice("Check that " + path + " is the file you meant")
+			}
+
+			registry := remote.NewRegistry(rt.State)
+			results := make([]nodeImport, 0, len(specs))
+			counts := map[string]int{}
+			for _, spec := range specs {
+				res := nodeImport{Name: spec.Name, Host: spec.Host, User: spec.User, Port: spec.Port, Groups: spec.Groups}
+				existing, err := rt.State.GetNode(spec.Name)
+				if err != nil {
+					return err
+				}
+				switch {
+				case existing == nil:
+					res.Action = "added"
+					if !rt.Flags.DryRun {
+						err = registry.Add(v1.NodeInfo{Spec: spec})
+					}
+				case sameNodeSpec(existing.Spec, spec):
+					res.Action = "unchanged"
+				case !replace:
+					res.Action = "skipped"
+				default:
+					res.Action = "updated"
+					if !rt.Flags.DryRun {
+						err = registry.Update(spec)
+					}
+				}
+				if err != nil {
+					return err
+				}
+				counts[res.Action]++
+				results = append(results, res)
+			}
+			rt.audit("", map[string]string{"source": path, "added": fmt.Sprint(counts["added"]), "updated": fmt.Sprint(counts["updated"])})
+
+			if err := output.Render(rt.Flags.Output, results, nodeImportView); err != nil {
+				return err
+			}
+			if rt.Flags.Output.Format.Structured() || rt.Flags.Output.Quiet {
+				return nil
+			}
+			fmt.Println()
+			summary := fmt.Sprintf("%d added, %d updated, %d unchanged, %d skipped",
+				counts["added"], counts["updated"], counts["unchanged"], counts["skipped"])
+			if rt.Flags.DryRun {
+				pprint.Info("Dry run: %s; nothing registered", summary)
+				return nil
+			}
+			pprint.Success("Imported %d nodes: %s", len(results), summary)
+			if counts["skipped"] > 0 {
+				pprint.Info("Pass --replace to update the skipped nodes")
+			}
+			if counts["added"]+counts["updated"] > 0 {
+				pprint.Info("Run 'orbit nodes trust <name>' to record host keys")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&fromTerraform, "from-terraform", "", "Terraform/OpenTofu state or output JSON (\"-\" for stdin)")
+	cmd.Flags().StringVar(&fromAnsible, "from-ansible", "", "Ansible inventory, INI or YAML (\"-\" for stdin, read as INI)")
+	cmd.Flags().StringVar(&opts.User, "user", "", "SSH user for nodes the source names none for (default root)")
+	cmd.Flags().IntVar(&opts.Port, "port", 22, "SSH port for nodes the source names none for")
+	cmd.Flags().StringVar(&opts.Key, "key", "", "Path to SSH private key for nodes the source names none for")
+	cmd.Flags().StringSliceVarP(&opts.Groups, "group", "g", nil, "Add every imported node to these groups")
+	cmd.Flags().BoolVar(&opts.Private, "private", false, "Terraform: use instances' private addresses")
+	cmd.Flags().BoolVar(&replace, "replace", false, "Update nodes that are already registered")
+	cmd.MarkFlagsOneRequired("from-terraform", "from-ansible")
+	cmd.MarkFlagsMutuallyExclusive("from-terraform", "from-ansible")
+	return cmd
+}
+
+// sameNodeSpec reports whether an import would leave a registered node as is.
+func sameNodeSpec(a, b v1.NodeSpec) bool {
+	return a.Host == b.Host && a.User == b.User && a.Port == b.Port && a.Key == b.Key &&
+		slices.Equal(a.Groups, b.Groups)
+}
+
+// nodeImportView is the table layout for `orbit nodes import`.
+var nodeImportView = output.View[nodeImport]{
+	ID: func(n nodeImport) string { return n.Name },
+	Columns: []output.Column[nodeImport]{
+		{Header: "NAME", Value: func(n nodeImport) string { return n.Name }},
+		{Header: "HOST", Value: func(n nodeImport) string { return n.Host }},
+		{Header: "USER", Value: func(n nodeImport) string { return n.User }},
+		{Header: "PORT", Value: func(n nodeImport) string { return fmt.Sprint(n.Port) }},
+		{Header: "GROUPS", Value: func(n nodeImport) string { return orDash(strings.Join(n.Groups, ",")) }},
+		{Header: "ACTION", Value: func(n nodeImport) string { return n.Action }},
+	},
+}
+
 func newNodesRmCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "rm <name>",
@@ -115,28 +297,7 @@ func newNodesLsCmd() *cobra.Command {
 				return err
 			}
 
-			if rt.Flags.JSONOutput {
-				return json.NewEncoder(os.Stdout).Encode(nodes)
-			}
-
-			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-			fmt.Fprintln(w, "NAME\tHOST\tUSER\tSTATUS\tLAST SEEN\tKEY TRUSTED")
-			for _, n := range nodes {
-				lastSeen := "never"
-				if !n.LastSeen.IsZero() {
-					lastSeen = fmtDuration(time.Since(n.LastSeen))
-				}
-				trusted := "✗"
-				if n.HostKeyKnown {
-					trusted = "✓"
-				}
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s ago\t%s\n",
-					n.Spec.Name, n.Spec.Host, n.Spec.User,
-					statusIcon(n.Status)+string(n.Status),
-					lastSeen, trusted,
-				)
-			}
-			return w.Flush()
+			return output.Render(rt.Flags.Output, nodes, nodesView)
 		},
 	}
 }
@@ -153,8 +314,15 @@ func newNodesInfoCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			data, _ := json.MarshalIndent(info, "", "  ")
-			fmt.Println(string(data))
+			out := rt.Flags.Output
+			switch {
+			case out.Quiet:
+				fmt.Println(info.Spec.Name)
+			case out.Format.Structured():
+				return output.Encode(out, info)
+			default:
+				printNodeInfo(info)
+			}
 			return nil
 		},
 	}
@@ -173,7 +341,7 @@ func newNodesTestCmd() *cobra.Command {
 				return err
 			}
 
-			pool := remote.NewPool(rt.Log)
+			pool := rt.NewPool()
 			defer pool.Close()
 
 			fmt.Printf("◉ Testing SSH connection to %s (%s@%s)...\n",
@@ -220,22 +388,398 @@ func newNodesTrustCmd() *cobra.Command {
 
 			fmt.Printf("  Fingerprint: %s\n", fingerprint)
 			fmt.Printf("  Type:        %s\n", key.Type())
-			fmt.Print("  Trust this key? [y/N] ")
 
-			var answer string
-			fmt.Scanln(&answer)
-			if answer != "y" && answer != "Y" {
+			ok, err := prompt.Confirm("Trust this key?", false)
+			if err != nil {
+				return err
+			}
+			if !ok {
 				fmt.Println("Aborted.")
 				return nil
 			}
 
+			if err := sshutil.AddKnownHost(config.KnownHostsFile(), addr, key); err != nil {
+				return fmt.Errorf("write known_hosts: %w", err)
+			}
 			if err := registry.Trust(args[0], fingerprint, encodedKey); err != nil {
 				return err
 			}
-			fmt.Printf("✓ Host key for %q trusted\n", args[0])
+			fmt.Printf("✓ Host key for %q trusted (%s)\n", args[0], config.KnownHostsFile())
+			return nil
+		},
+	}
+}
+
+func newNodesRunCmd() *cobra.Command {
+	var all bool
+	var group string
+	var names []string
+	var parallel int
+
+	cmd := &cobra.Command{
+		Use:   "run (--all | --group <name> | --node <name>...) -- <command>",
+		Short: "Run a shell command on many nodes in parallel",
+		Example: `  orbit nodes run --all -- uptime
+  orbit nodes run --group web --parallel 4 -- 'df -h /'
+  orbit nodes run --node prod-01,prod-02 -- docker ps -q`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			registry := remote.NewRegistry(rt.State)
+			nodes, err := registry.List()
+			if err != nil {
+				return err
+			}
+			targets, err := selectNodes(nodes, all, group, names)
+			if err != nil {
+				return err
+			}
+
+			pool := rt.NewPool()
+			defer pool.Close()
+
+			out := rt.Flags.Output
+			command := strings.Join(args, " ")
+			width := 0
+			for _, n := range targets {
+				width = max(width, len(n.Spec.Name))
+			}
+
+			var mu sync.Mutex
+			captured := map[string]*strings.Builder{}
+			var writers []*prefixWriter
+			streams := func(node string) (io.Writer, io.Writer) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case out.Format.Structured():
+					b := &strings.Builder{}
+					captured[node] = b
+					return &lockedWriter{mu: &mu, w: b}, &lockedWriter{mu: &mu, w: b}
+				case out.Quiet:
+					return io.Discard, io.Discard
+				}
+				prefix := pprint.StyleMuted.Render(fmt.Sprintf("%-*s │ ", width, node))
+				stdout := &prefixWriter{mu: &mu, w: os.Stdout, prefix: prefix}
+				stderr := &prefixWriter{mu: &mu, w: os.Stderr, prefix: prefix}
+				writers = append(writers, stdout, stderr)
+				return stdout, stderr
+			}
+
+			results := pool.RunAll(cmd.Context(), targets, command, parallel, streams)
+			for _, w := range writers {
+				w.Flush()
+			}
+
+			failed := 0
+			for _, r := range results {
+				if !r.OK() {
+					failed++
+				}
+			}
+			switch {
+			case out.Format.Structured():
+				type nodeRun struct {
+					remote.ExecResult
+					Output string `json:"output"`
+				}
+				rows := make([]nodeRun, len(results))
+				for i, r := range results {
+					rows[i] = nodeRun{ExecResult: r}
+					if b := captured[r.Node]; b != nil {
+						rows[i].Output = b.String()
+					}
+				}
+				if err := output.Encode(out, rows); err != nil {
+					return err
+				}
+			case out.Quiet:
+				for _, r := range results {
+					if !r.OK() {
+						fmt.Println(r.Node)
+					}
+				}
+			default:
+				printRunSummary(results, failed)
+			}
+
+			if failed > 0 {
+				return &ExitError{Code: 1}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Run on every registered node")
+	cmd.Flags().StringVarP(&group, "group", "g", "", "Run on nodes in this group")
+	cmd.Flags().StringSliceVar(&names, "node", nil, "Run on the named nodes (repeatable or comma-separated)")
+	cmd.Flags().IntVarP(&parallel, "parallel", "p", remote.DefaultParallelism, "Maximum concurrent SSH sessions")
+	cmd.MarkFlagsMutuallyExclusive("all", "group", "node")
+	return cmd
+}
+
+func newNodesExecLogsCmd() *cobra.Command {
+	var sources []string
+	var opts remote.LogOptions
+
+	cmd := &cobra.Command{
+		Use:         "exec-logs <name>",
+		Annotations: readsState,
+		Short:       "Print the orbit agent's and Docker daemon's logs from a node",
+		Long: `Fetch logs from a node over SSH: the orbit agent's, from its systemd unit,
+its container or its log file, and the Docker daemon's, from its journal.
+The SSH user needs to read the journal, e.g. by being in the
+systemd-journal or adm group.
+
+To attach them to a bug report with everything else, use orbit support-bundle.`,
+		Args: cobra.ExactArgs(1),
+		Example: `  orbit nodes exec-logs prod-01
+  orbit nodes exec-logs prod-01 --source docker --since 1h
+  orbit nodes exec-logs prod-01 --lines 0 > agent.log   # everything`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			info, err := remote.NewRegistry(rt.State).Get(args[0])
+			if err != nil {
+				return err
+			}
+			pool := rt.NewPool()
+			defer pool.Close()
+
+			quiet := rt.Flags.Output.Quiet
+			for _, source := range sources {
+				logs, err := pool.Logs(cmd.Context(), info, source, opts)
+				if err != nil {
+					return err
+				}
+				if !quiet {
+					pprint.Header(fmt.Sprintf("%s logs — %s", source, info.Spec.Name))
+				}
+				if len(logs) == 0 {
+					if !quiet {
+						pprint.Info("No %s logs found on %s", source, info.Spec.Name)
+					}
+					continue
+				}
+				os.Stdout.Write(logs)
+			}
 			return nil
 		},
 	}
+
+	cmd.Flags().StringSliceVar(&sources, "source", remote.LogSources, "Logs to fetch: agent, docker")
+	cmd.Flags().DurationVar(&opts.Since, "since", 24*time.Hour, "Only entries this recent (0 for all)")
+	cmd.Flags().IntVar(&opts.Lines, "lines", 500, "Last lines of each log (0 for all)")
+	return cmd
+}
+
+// startHeartbeat watches every registered node. A heartbeat_interval set for
+// the same node name in orbit.yaml takes precedence over the registry's.
+func startHeartbeat(rt *Runtime, pool *remote.Pool) *remote.Engine {
+	registry := remote.NewRegistry(rt.State)
+	engine := remote.NewEngine(pool, registry, rt.Log)
+	nodes, err := registry.List()
+	if err != nil {
+		rt.Log.Warn("heartbeat: list nodes failed", "err", err)
+		return engine
+	}
+	for _, n := range nodes {
+		if spec := rt.Config.NodeByName(n.Spec.Name); spec != nil && spec.HeartbeatInterval > 0 {
+			n.Spec.HeartbeatInterval = spec.HeartbeatInterval
+		}
+		engine.Watch(n)
+	}
+	return engine
+}
+
+// selectNodes picks the targets of `orbit nodes run` from the registry.
+func selectNodes(nodes []v1.NodeInfo, all bool, group string, names []string) ([]v1.NodeInfo, error) {
+	var out []v1.NodeInfo
+	switch {
+	case all:
+		out = nodes
+	case group != "":
+		for _, n := range nodes {
+			if slices.Contains(n.Spec.Groups, group) {
+				out = append(out, n)
+			}
+		}
+		if len(out) == 0 {
+			return nil, fmt.Errorf("no nodes in group %q", group)
+		}
+	case len(names) > 0:
+		byName := map[string]v1.NodeInfo{}
+		for _, n := range nodes {
+			byName[n.Spec.Name] = n
+		}
+		for _, name := range names {
+			n, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("node %q not found in registry", name)
+			}
+			out = append(out, n)
+		}
+	default:
+		return nil, fmt.Errorf("choose target nodes with --all, --group, or --node")
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no nodes registered — add one with `orbit nodes add`")
+	}
+	return out, nil
+}
+
+// printRunSummary lists each node's exit status after `orbit nodes run`.
+func printRunSummary(results []remote.ExecResult, failed int) {
+	fmt.Println()
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			fmt.Printf("  %s %s  %s\n", pprint.StyleError.Render("✖"), r.Node, r.Error)
+		case r.ExitCode != 0:
+			fmt.Printf("  %s %s  exit %d (%s)\n", pprint.StyleError.Render("✖"), r.Node, r.ExitCode, r.Duration.Round(time.Millisecond))
+		default:
+			fmt.Printf("  %s %s  exit 0 (%s)\n", pprint.StyleSuccess.Render("✓"), r.Node, r.Duration.Round(time.Millisecond))
+		}
+	}
+	fmt.Printf("\n  %d succeeded, %d failed\n", len(results)-failed, failed)
+}
+
+// prefixWriter prefixes each complete line with the node (or service) it
+// came from so interleaved output from parallel sessions stays readable.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		fmt.Fprint(p.w, p.prefix, string(p.buf[:i+1]))
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes any trailing partial line.
+func (p *prefixWriter) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.buf) > 0 {
+		fmt.Fprintln(p.w, p.prefix+string(p.buf))
+		p.buf = nil
+	}
+}
+
+// lockedWriter serialises writes to a shared buffer.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(b)
+}
+
+// nodesView is the table layout for `orbit nodes ls`.
+var nodesView = output.View[v1.NodeInfo]{
+	ID: func(n v1.NodeInfo) string { return n.Spec.Name },
+	Columns: []output.Column[v1.NodeInfo]{
+		{Header: "NAME", Value: func(n v1.NodeInfo) string { return n.Spec.Name }},
+		{Header: "HOST", Value: func(n v1.NodeInfo) string { return n.Spec.Host }},
+		{Header: "PORT", Wide: true, Value: func(n v1.NodeInfo) string { return fmt.Sprint(sshPort(n.Spec.Port)) }},
+		{Header: "USER", Value: func(n v1.NodeInfo) string { return n.Spec.User }},
+		{Header: "STATUS", Value: func(n v1.NodeInfo) string { return statusIcon(n.Status) + string(n.Status) }},
+		{Header: "LAST SEEN", Value: func(n v1.NodeInfo) string { return lastSeen(n.LastSeen) }},
+		{Header: "FAILS", Wide: true, Value: func(n v1.NodeInfo) string { return fmt.Sprint(n.FailCount) }},
+		{Header: "KEY TRUSTED", Value: func(n v1.NodeInfo) string {
+			if n.HostKeyKnown {
+				return "✓"
+			}
+			return "✗"
+		}},
+		{Header: "FINGERPRINT", Wide: true, Value: func(n v1.NodeInfo) string { return n.KeyFingerprint }},
+		{Header: "LOAD", Wide: true, Value: func(n v1.NodeInfo) string {
+			return hostValue(n.Host, func(h *v1.HostMetrics) string { return fmt.Sprintf("%.2f", h.Load1) })
+		}},
+		{Header: "MEM", Wide: true, Value: func(n v1.NodeInfo) string {
+			return hostValue(n.Host, func(h *v1.HostMetrics) string { return percent(h.MemTotal, h.MemAvailable) })
+		}},
+		{Header: "DISK", Wide: true, Value: func(n v1.NodeInfo) string {
+			return hostValue(n.Host, func(h *v1.HostMetrics) string { return percent(h.DiskTotal, h.DiskFree) })
+		}},
+		{Header: "INODES", Wide: true, Value: func(n v1.NodeInfo) string {
+			return hostValue(n.Host, func(h *v1.HostMetrics) string { return percent(h.InodesTotal, h.InodesFree) })
+		}},
+	},
+}
+
+// hostValue formats a host metric, or "-" before the first reading.
+func hostValue(h *v1.HostMetrics, f func(*v1.HostMetrics) string) string {
+	if h == nil {
+		return "-"
+	}
+	return f(h)
+}
+
+// percent formats the used share of total, or "-" when total is unknown.
+func percent(total, free int64) string {
+	if total <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", metrics.UsedPercent(total, free))
+}
+
+// printNodeInfo renders a single node as labelled fields.
+func printNodeInfo(n v1.NodeInfo) {
+	pprint.Header("Node " + n.Spec.Name)
+	pprint.KV("Host", fmt.Sprintf("%s@%s:%d", n.Spec.User, n.Spec.Host, sshPort(n.Spec.Port)))
+	pprint.KV("Key", n.Spec.Key)
+	if n.Spec.ProxyJump != "" {
+		pprint.KV("Proxy jump", n.Spec.ProxyJump)
+	}
+	if len(n.Spec.Groups) > 0 {
+		pprint.KV("Groups", strings.Join(n.Spec.Groups, ", "))
+	}
+	pprint.KV("Status", statusIcon(n.Status)+string(n.Status))
+	pprint.KV("Last seen", lastSeen(n.LastSeen))
+	pprint.KV("Fail count", fmt.Sprint(n.FailCount))
+	if h := n.Host; h != nil {
+		pprint.KV("Load", fmt.Sprintf("%.2f %.2f %.2f", h.Load1, h.Load5, h.Load15))
+		pprint.KV("Memory", fmt.Sprintf("%s used of %s", percent(h.MemTotal, h.MemAvailable), humanSize(h.MemTotal)))
+		pprint.KV("Disk", fmt.Sprintf("%s used of %s (%s), inodes %s", percent(h.DiskTotal, h.DiskFree),
+			humanSize(h.DiskTotal), h.DiskPath, percent(h.InodesTotal, h.InodesFree)))
+	}
+	fingerprint := "(not trusted — run `orbit nodes trust " + n.Spec.Name + "`)"
+	if n.HostKeyKnown {
+		fingerprint = n.KeyFingerprint
+	}
+	pprint.KV("Host key", fingerprint)
+	fmt.Println()
+}
+
+func sshPort(port int) int {
+	if port == 0 {
+		return 22
+	}
+	return port
+}
+
+func lastSeen(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return fmtDuration(time.Since(t)) + " ago"
 }
 
 // parseUserAtHost splits "user@host" into its parts.
@@ -0,0 +1,220 @@
+// orbit outdated — list services whose registry has a newer image.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/registry"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+// outdatedParallel is how many registries orbit outdated queries at once.
+const outdatedParallel = 4
+
+// outdatedService is one service's row of `orbit outdated`.
+type outdatedService struct {
+	Service string `json:"service"`
+	Node    string `json:"node"`
+	registry.Update
+	Error string `json:"error,omitempty"`
+}
+
+func NewOutdatedCmd() *cobra.Command {
+	var plan bool
+
+	cmd := &cobra.Command{
+		Use:         "outdated [service...]",
+		Annotations: readsState,
+		Short:       "List services whose registry has a newer image than the one deployed",
+		Long: `Ask each service's registry whether it has a newer image than the one
+deployed on the node (or, for a service not deployed, the one in orbit.yaml).
+
+A tag that reads as a version — 1.25.3, v2.1, 1.25-alpine — is compared with
+the registry's tags of the same form, and the newest is shown with whether
+it is a major, minor or patch update. Any tag, latest included, is also
+checked for a new image pushed under it, by comparing the digest the
+registry has for it with the one the running container was pulled by.
+
+Private registries are queried with the credentials docker login saved.
+--plan shows the deploy plan for moving every outdated service to its
+newest tag.`,
+		Example: `  orbit outdated
+  orbit outdated web api
+  orbit outdated --plan
+  orbit outdated -q          # names of outdated services`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			node := nodeOrLocal(rt.Flags.Node)
+
+			specs := rt.Config.Services
+			if len(args) > 0 {
+				specs = nil
+				for _, name := range args {
+					svc := rt.Config.ServiceByName(name)
+					if svc == nil {
+						return fmt.Errorf("service %q not found in orbit.yaml", name)
+					}
+					specs = append(specs, *svc)
+				}
+			}
+
+			// Without Docker, only newer tags are looked for.
+			var docker *orchestrator.Client
+			if c, err := rt.NewContainerClient(); err != nil {
+				rt.Log.Debug("outdated: docker client unavailable, skipping digest checks", "err", err)
+			} else if err := c.Ping(cmd.Context()); err != nil {
+				rt.Log.Debug("outdated: docker unreachable, skipping digest checks", "err", err)
+				c.Close()
+			} else {
+				docker = c
+				defer docker.Close()
+			}
+
+			rows, err := checkOutdated(cmd.Context(), rt, docker, registry.New(), specs, node)
+			if err != nil {
+				return err
+			}
+			if err := output.Render(rt.Flags.Output, outdatedRows(rows, rt.Flags.Output), outdatedView); err != nil {
+				return err
+			}
+			if out := rt.Flags.Output; out.Quiet || out.Format.Structured() {
+				return nil
+			}
+
+			var planned []v1.ServiceSpec
+			for _, r := range rows {
+				if r.Latest == "" {
+					continue
+				}
+				spec := *rt.Config.ServiceByName(r.Service)
+				spec.Image = orchestrator.ResolveImage(spec.Image, r.Latest)
+				planned = append(planned, spec)
+				if !plan {
+					pprint.Info("orbit deploy %s --tag %s", r.Service, r.Latest)
+				}
+			}
+			if plan && len(planned) > 0 {
+				planner, done := buildPlanner(cmd, rt)
+				defer done()
+				p, err := planner.Plan(cmd.Context(), planned, node, false)
+				if err != nil {
+					return fmt.Errorf("plan: %w", err)
+				}
+				fmt.Println()
+				printPlan(p)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&plan, "plan", false, "Show the deploy plan for moving outdated services to their newest tags")
+	return cmd
+}
+
+// checkOutdated checks the image of each of specs on node, a few at a time.
+// A service's check failing is reported in its row.
+func checkOutdated(ctx context.Context, rt *Runtime, docker *orchestrator.Client, reg *registry.Client, specs []v1.ServiceSpec, node string) ([]outdatedService, error) {
+	rows := make([]outdatedService, len(specs))
+	sem := make(chan struct{}, outdatedParallel)
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		image, digests, err := deployedImage(ctx, rt, docker, spec, node)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = outdatedService{Service: spec.Name, Node: node, Update: registry.Update{Image: image}}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			u, err := reg.Check(ctx, image, digests)
+			rows[i].Update = u
+			if err != nil {
+				rows[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	return rows, nil
+}
+
+// deployedImage returns the image spec runs on node, as recorded in state,
+// and the registry digests its container's image was pulled by. A service
+// not deployed yet is checked by its image in orbit.yaml.
+func deployedImage(ctx context.Context, rt *Runtime, docker *orchestrator.Client, spec v1.ServiceSpec, node string) (string, []string, error) {
+	s, err := rt.State.GetServiceState(node, spec.Name)
+	if err != nil {
+		return "", nil, fmt.Errorf("state: %w", err)
+	}
+	if s == nil || s.Image == "" {
+		return spec.Image, nil, nil
+	}
+	if docker == nil || s.ContainerID == "" {
+		return s.Image, nil, nil
+	}
+	info, err := docker.InspectContainer(ctx, s.ContainerID)
+	if err != nil {
+		rt.Log.Debug("outdated: container inspect failed", "service", spec.Name, "err", err)
+		return s.Image, nil, nil
+	}
+	digests, err := docker.ImageRepoDigests(ctx, info.Image)
+	if err != nil {
+		rt.Log.Debug("outdated: image inspect failed", "service", spec.Name, "err", err)
+	}
+	return s.Image, digests, nil
+}
+
+// outdatedRows is what `orbit outdated` lists: in quiet mode, only the
+// services with an update.
+func outdatedRows(rows []outdatedService, out output.Options) []outdatedService {
+	if !out.Quiet {
+		return rows
+	}
+	var outdated []outdatedService
+	for _, r := range rows {
+		if r.Kind != "" {
+			outdated = append(outdated, r)
+		}
+	}
+	return outdated
+}
+
+// outdatedView is the table layout for `orbit outdated`.
+var outdatedView = output.View[outdatedService]{
+	ID: func(r outdatedService) string { return r.Service },
+	Columns: []output.Column[outdatedService]{
+		{Header: "NODE", Wide: true, Value: func(r outdatedService) string { return r.Node }},
+		{Header: "SERVICE", Value: func(r outdatedService) string { return r.Service }},
+		{Header: "IMAGE", Value: func(r outdatedService) string { return r.Image }},
+		{Header: "LATEST", Value: func(r outdatedService) string { return orDash(r.Latest) }},
+		{Header: "UPDATE", Value: func(r outdatedService) string {
+			switch {
+			case r.Error != "":
+				return "✖ " + r.Error
+			case r.Kind == "" && r.Note != "":
+				return "? " + r.Note
+			case r.Kind == "":
+				return "up to date"
+			}
+			return r.Kind
+		}},
+		{Header: "DIGEST", Wide: true, Value: func(r outdatedService) string { return orDash(shortDigest(r.Digest)) }},
+	},
+}
+
+// shortDigest is the first 12 hex digits of a sha256:… digest.
+func shortDigest(d string) string {
+	if len(d) > len("sha256:")+12 {
+		return d[:len("sha256:")+12]
+	}
+	return d
+}
@@ -0,0 +1,24 @@
+// Package commands: shared -o/--output and -q/--quiet handling.
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/cli/output"
+)
+
+// OutputOptions reads the global output flags from cmd. The deprecated --json
+// flag is honoured as -o json.
+func OutputOptions(cmd *cobra.Command) (output.Options, error) {
+	flags := cmd.Flags()
+	name, _ := flags.GetString("output")
+	if asJSON, _ := flags.GetBool("json"); asJSON && !flags.Changed("output") {
+		name = string(output.FormatJSON)
+	}
+	format, err := output.ParseFormat(name)
+	if err != nil {
+		return output.Options{}, err
+	}
+	quiet, _ := flags.GetBool("quiet")
+	return output.Options{Format: format, Quiet: quiet}, nil
+}
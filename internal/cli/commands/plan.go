@@ -0,0 +1,132 @@
+// orbit plan — preview the changes `orbit up` would make.
+package commands
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+func NewPlanCmd() *cobra.Command {
+	var prune bool
+	var profiles []string
+
+	cmd := &cobra.Command{
+		Use:         "plan [service...]",
+		Annotations: readsState,
+		Short:       "Show the changes needed to converge running services on orbit.yaml",
+		Example: `  orbit plan
+  orbit plan web api
+  orbit plan --prune     # also plan removal of services no longer in orbit.yaml
+  orbit plan --profile debug
+  orbit plan -q          # names of services that would change`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+
+			specs, err := profileServices(rt, profiles, cmd.Flags().Changed("profile"))
+			if err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				specs = specs[:0:0]
+				for _, name := range args {
+					svc := rt.Config.ServiceByName(name)
+					if svc == nil {
+						return fmt.Errorf("service %q not found in orbit.yaml", name)
+					}
+					specs = append(specs, *svc)
+				}
+			}
+
+			planner, done := buildPlanner(cmd, rt)
+			defer done()
+
+			plan, err := planner.Plan(cmd.Context(), specs, nodeOrLocal(rt.Flags.Node), prune && len(args) == 0)
+			if err != nil {
+				return fmt.Errorf("plan: %w", err)
+			}
+			// Services of inactive profiles are still in orbit.yaml: not pruned.
+			plan.Services = slices.DeleteFunc(plan.Services, func(c orchestrator.ServiceChange) bool {
+				return c.Action == orchestrator.ActionDestroy && rt.Config.ServiceByName(c.Service) != nil
+			})
+
+			out := rt.Flags.Output
+			switch {
+			case out.Quiet:
+				for _, s := range plan.Services {
+					if s.Action != orchestrator.ActionUnchanged {
+						fmt.Println(s.Service)
+					}
+				}
+			case out.Format.Structured():
+				return output.Encode(out, plan)
+			default:
+				printPlan(plan)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&prune, "prune", false, "Plan destruction of services that are running but no longer defined")
+	addProfileFlag(cmd, &profiles)
+	return cmd
+}
+
+// buildPlanner returns a Planner and a cleanup func, degrading to state-only
+// planning when the Docker daemon is unreachable.
+func buildPlanner(cmd *cobra.Command, rt *Runtime) (*orchestrator.Planner, func()) {
+	docker, err := rt.NewContainerClient()
+	if err != nil {
+		rt.Log.Debug("plan: docker client unavailable, planning from state only", "err", err)
+		return orchestrator.NewPlanner(nil, rt.State, rt.Log), func() {}
+	}
+	if err := docker.Ping(cmd.Context()); err != nil {
+		rt.Log.Debug("plan: docker unreachable, planning from state only", "err", err)
+		docker.Close()
+		return orchestrator.NewPlanner(nil, rt.State, rt.Log), func() {}
+	}
+	return orchestrator.NewPlanner(docker, rt.State, rt.Log), func() { docker.Close() }
+}
+
+// printPlan renders a terraform-style plan.
+func printPlan(plan *orchestrator.Plan) {
+	pprint.Header("Plan — " + plan.Node)
+	for _, s := range plan.Services {
+		switch s.Action {
+		case orchestrator.ActionCreate:
+			fmt.Println(pprint.StyleSuccess.Render("  + "+s.Service) + pprint.StyleMuted.Render(" (create)"))
+		case orchestrator.ActionUpdate:
+			ops := orchestrator.PlanUpdate(s.Changes).String()
+			fmt.Println(pprint.StyleWarning.Render("  ~ "+s.Service) + pprint.StyleMuted.Render(" (update: "+ops+")"))
+		case orchestrator.ActionDestroy:
+			fmt.Println(pprint.StyleError.Render("  - "+s.Service) + pprint.StyleMuted.Render(" (destroy)"))
+		default:
+			fmt.Println(pprint.StyleMuted.Render("    " + s.Service + " (unchanged)"))
+		}
+		changes := make([]pprint.Change, len(s.Changes))
+		for i, c := range s.Changes {
+			changes[i] = pprint.Change{Field: c.Field, From: c.From, To: c.To}
+		}
+		pprint.NewDiff().Fields("      ", changes)
+	}
+	fmt.Println()
+	if !plan.HasChanges() {
+		pprint.Success("No changes. Running services match orbit.yaml.")
+		return
+	}
+	pprint.Info("Plan: %s.", plan)
+}
+
+// nodeOrLocal returns node, or "local" if node is empty.
+func nodeOrLocal(node string) string {
+	if node == "" {
+		return "local"
+	}
+	return node
+}
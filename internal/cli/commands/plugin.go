@@ -0,0 +1,174 @@
+// orbit plugin — list, install, enable and disable plugins.
+package commands
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/plugin"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+func NewPluginCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Manage plugins in ~/.orbit/plugins",
+		Long: `Plugins are Go shared objects (.so), standalone binaries or
+WebAssembly modules (.wasm, run sandboxed) in ~/.orbit/plugins. Each is configured under plugins.<name> in orbit.yaml;
+enable and disable override that section's enabled flag on this machine.
+Plugins are loaded by every command and receive OnPreDeploy, OnPostDeploy,
+OnPreScale, OnPostScale, OnNodeConnect and OnSSLRenew hooks.`,
+	}
+	cmd.AddCommand(newPluginLsCmd(), newPluginInstallCmd(),
+		newPluginToggleCmd(true), newPluginToggleCmd(false))
+	return cmd
+}
+
+func newPluginLsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls",
+		Short: "List plugins with their versions, hooks and load status",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			return output.Render(rt.Flags.Output, rt.Plugins.Plugins(), pluginView)
+		},
+	}
+}
+
+func newPluginInstallCmd() *cobra.Command {
+	var opts plugin.InstallOptions
+	cmd := &cobra.Command{
+		Use:   "install <url | oci://registry/repo:tag>",
+		Short: "Download a plugin into ~/.orbit/plugins",
+		Long: `Download a .so, .wasm or plugin binary over http(s), or pull it from an OCI
+artifact whose layer holds the file (as pushed by oras). Artifacts with one
+layer per platform are matched on the layer title, e.g. notify_linux_amd64.
+The plugin is loaded once to check that it starts.`,
+		Example: `  orbit plugin install https://example.com/releases/notify
+  orbit plugin install oci://ghcr.io/acme/orbit-notify:1.2.0 --name notify`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			path, err := plugin.NewInstaller(config.PluginDir()).Install(cmd.Context(), args[0], opts)
+			if err != nil {
+				return err
+			}
+
+			settings, err := pluginSettings(rt)
+			if err != nil {
+				return err
+			}
+			host := plugin.NewHost(rt.Log).WithConfig(settings)
+			defer host.Shutdown()
+			info := host.LoadFile(path)
+			switch info.Status {
+			case plugin.StatusLoaded:
+				pprint.Success("Installed %s %s → %s", info.Name, info.Version, path)
+			case plugin.StatusDisabled:
+				pprint.Success("Installed %s (disabled; run `orbit plugin enable %s`)", path, pluginKey(info))
+			default:
+				pprint.Warn("Installed %s, but it failed to load: %s", path, info.Error)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&opts.Name, "name", "", "File name to install as (default: from the source)")
+	cmd.Flags().StringVar(&opts.SHA256, "sha256", "", "Expected SHA-256 of the download")
+	return cmd
+}
+
+func newPluginToggleCmd(enable bool) *cobra.Command {
+	verb, done := "disable", "Disabled"
+	if enable {
+		verb, done = "enable", "Enabled"
+	}
+	return &cobra.Command{
+		Use:   verb + " <name>",
+		Short: strings.ToUpper(verb[:1]) + verb[1:] + " a plugin on this machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			name := strings.ToLower(args[0])
+			err := rt.State.SetPluginEnabled(state.PluginToggle{Name: name, Enabled: enable, ChangedAt: time.Now().UTC()})
+			if err != nil {
+				return err
+			}
+			pprint.Success("%s plugin %s", done, name)
+			return nil
+		},
+	}
+}
+
+// pluginSettings returns orbit.yaml's plugins: section with the toggles
+// recorded by `orbit plugin enable/disable` applied on top.
+func pluginSettings(rt *Runtime) (map[string]config.PluginConfig, error) {
+	settings := map[string]config.PluginConfig{}
+	if rt.Config != nil {
+		for name, s := range rt.Config.Plugins {
+			settings[name] = s
+		}
+	}
+	toggles, err := rt.State.ListPluginToggles()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range toggles {
+		s := settings[t.Name]
+		enabled := t.Enabled
+		s.Enabled = &enabled
+		settings[t.Name] = s
+	}
+	return settings, nil
+}
+
+// LoadPlugins starts a plugin host over ~/.orbit/plugins for rt. Problems
+// are logged rather than returned: a broken plugin setup must not stop
+// commands that never fire a hook. The caller must call Shutdown on it.
+func LoadPlugins(rt *Runtime) *plugin.Host {
+	settings, err := pluginSettings(rt)
+	if err != nil {
+		rt.Log.Warn("plugin settings unreadable, using orbit.yaml only", "err", err)
+		settings = rt.Config.Plugins
+	}
+	host := plugin.NewHost(rt.Log).WithConfig(settings)
+	if err := host.LoadDir(config.PluginDir()); err != nil {
+		rt.Log.Warn("plugins not loaded", "err", err)
+	}
+	return host
+}
+
+// pluginKey is the name a plugin is configured and toggled under.
+func pluginKey(p plugin.PluginInfo) string {
+	if p.Name != "" {
+		return strings.ToLower(p.Name)
+	}
+	return plugin.FileKey(p.File)
+}
+
+// pluginView is the table layout for `orbit plugin ls`.
+var pluginView = output.View[plugin.PluginInfo]{
+	ID: pluginKey,
+	Columns: []output.Column[plugin.PluginInfo]{
+		{Header: "NAME", Value: pluginKey},
+		{Header: "VERSION", Value: func(p plugin.PluginInfo) string { return orDash(p.Version) }},
+		{Header: "API", Value: func(p plugin.PluginInfo) string { return orDash(p.APIVersion) }},
+		{Header: "KIND", Value: func(p plugin.PluginInfo) string { return p.Kind }},
+		{Header: "STATUS", Value: func(p plugin.PluginInfo) string { return p.Status }},
+		{Header: "HOOKS", Value: func(p plugin.PluginInfo) string { return orDash(strings.Join(p.Hooks, ",")) }},
+		{Header: "FILE", Wide: true, Value: func(p plugin.PluginInfo) string { return p.File }},
+		{Header: "ERROR", Value: func(p plugin.PluginInfo) string { return p.Error }},
+	},
+}
+
+// orDash returns s, or "-" when it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
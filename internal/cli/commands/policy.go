@@ -0,0 +1,56 @@
+// Package commands: deploy_policy, guarding deploys to protected
+// environments.
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/gate"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/pprint"
+	"github.com/f9-o/orbit/pkg/pprint/prompt"
+)
+
+// deployGate is the deploy_policy gate for deploys to the --node.
+func deployGate(rt *Runtime) *gate.Gate {
+	return gate.New(rt.Config, rt.State.ProjectName(), nodeOrLocal(rt.Flags.Node))
+}
+
+// confirmDeploy is asked before deploying to a protected environment: it
+// fails outside the maintenance windows, and, when deploy_policy.confirm is
+// set, asks for the environment's name to be typed unless confirm, from
+// --confirm, already gives it. --yes does not answer it.
+func confirmDeploy(rt *Runtime, g *gate.Gate, confirm string) error {
+	if err := g.CheckWindow(); err != nil {
+		return err
+	}
+	if !g.NeedsConfirm() || confirm != "" {
+		return g.CheckConfirm(confirm)
+	}
+	if out := rt.Flags.Output; out.Quiet || out.Format.Structured() || prompt.AssumeYes() {
+		return g.CheckConfirm("")
+	}
+	pprint.Warn("%s is a protected environment", g.Environment())
+	typed, err := prompt.Input(fmt.Sprintf("Type %s to deploy", g.Environment()), "", nil)
+	if errors.Is(err, prompt.ErrNonInteractive) {
+		return g.CheckConfirm("")
+	}
+	if err != nil {
+		return err
+	}
+	return g.CheckConfirm(typed)
+}
+
+// unconfirmable refuses deploys that deploy_policy wants typed
+// confirmation of, for the places that cannot ask for it.
+func unconfirmable(g *gate.Gate) orchestrator.Policy {
+	if !g.NeedsConfirm() {
+		return nil
+	}
+	return func(context.Context, v1.ServiceSpec, string) error {
+		return g.CheckConfirm("")
+	}
+}
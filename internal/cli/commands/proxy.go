@@ -0,0 +1,304 @@
+// orbit proxy — built-in reverse proxy and load balancer, config push to nodes.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/proxy/lb"
+	"github.com/f9-o/orbit/internal/proxy/push"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+func NewProxyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Run the built-in reverse proxy or push proxy configs to nodes",
+	}
+	cmd.AddCommand(newProxyServeCmd(), newProxyPushCmd())
+	return cmd
+}
+
+func newProxyServeCmd() *cobra.Command {
+	var httpAddr, httpsAddr string
+	var refresh, healthInterval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Load-balance traffic across service replicas",
+		Long: `Runs in the foreground until interrupted, proxying to every service with a
+proxy: section in orbit.yaml.
+
+Services with proxy.domain are routed by Host header on the --http and
+--https listeners; services with only proxy.port get a TCP listener on that
+port. Requests are spread round-robin over the service's replicas on every
+node the state DB records it as deployed to: local replicas are reached
+directly, remote ones through the port the service publishes on the node.
+
+Replicas are dialled every --health-interval and taken out of rotation when
+they stop answering, or as soon as a request to them fails; they come back
+once a check passes. Routes are rediscovered every --refresh, so deploys and
+scaling are picked up without a restart.
+
+TLS is terminated with the certificates in ssl.cert_dir, chosen by SNI, with
+a wildcard certificate covering any host without one of its own. Plain HTTP
+requests for proxy.ssl services are redirected to HTTPS.`,
+		Example: `  orbit proxy serve
+  orbit proxy serve --http :8080 --https :8443
+  orbit proxy serve --https "" --refresh 10s`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			ctx := cmd.Context()
+
+			docker, err := rt.NewContainerClient()
+			if err != nil {
+				return fmt.Errorf("docker: %w", err)
+			}
+			defer docker.Close()
+
+			discovery := &lb.StateDiscovery{
+				Services:   rt.Config.Services,
+				State:      rt.State,
+				Containers: docker,
+				LocalNode:  nodeOrLocal(rt.Flags.Node),
+			}
+			routes, err := discovery.Routes(ctx)
+			if err != nil {
+				return err
+			}
+			if len(routes) == 0 {
+				pprint.Warn("No service in orbit.yaml has a proxy: section; nothing to route yet")
+			}
+			for _, r := range routes {
+				pprint.KV(r.Service, fmt.Sprintf("%s → %d replica(s)", routeListener(r, httpAddr, httpsAddr), len(r.Backends)))
+			}
+
+			srv := lb.New(lb.Options{
+				HTTPAddr:        httpAddr,
+				HTTPSAddr:       httpsAddr,
+				CertDir:         rt.Config.SSL.ResolvedCertDir(),
+				RefreshInterval: refresh,
+				HealthInterval:  healthInterval,
+			}, discovery.Routes, rt.Log)
+
+			errc := make(chan error, 1)
+			go func() { errc <- srv.Run(ctx) }()
+			pprint.Info("Proxy running (Ctrl+C to stop)")
+
+			for {
+				select {
+				case err := <-errc:
+					return err
+				case ev := <-srv.Events():
+					printProxyEvent(ev)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&httpAddr, "http", ":80", `Plain HTTP listen address ("" to disable)`)
+	cmd.Flags().StringVar(&httpsAddr, "https", ":443", `HTTPS listen address ("" to disable TLS termination)`)
+	cmd.Flags().DurationVar(&refresh, "refresh", lb.DefaultRefreshInterval, "How often to rediscover replicas")
+	cmd.Flags().DurationVar(&healthInterval, "health-interval", lb.DefaultHealthInterval, "How often to check replicas")
+	return cmd
+}
+
+// routeListener describes where a route is served.
+func routeListener(r lb.Route, httpAddr, httpsAddr string) string {
+	switch {
+	case r.Domain == "":
+		return "tcp :" + strconv.Itoa(r.Port)
+	case r.SSL && httpsAddr != "":
+		return "https://" + r.Domain + " (" + httpsAddr + ")"
+	default:
+		return "http://" + r.Domain + " (" + httpAddr + ")"
+	}
+}
+
+// printProxyEvent renders a replica entering or leaving rotation.
+func printProxyEvent(ev lb.Event) {
+	ts := ev.Time.Local().Format("15:04:05")
+	if ev.Up {
+		pprint.Success("%s  %s: %s (%s) back in rotation", ts, ev.Service, ev.Backend.Name, ev.Backend.Addr)
+		return
+	}
+	pprint.Warn("%s  %s: %s (%s) out of rotation: %v", ts, ev.Service, ev.Backend.Name, ev.Backend.Addr, ev.Err)
+}
+
+func newProxyPushCmd() *cobra.Command {
+	var force, diff bool
+
+	cmd := &cobra.Command{
+		Use:   "push",
+		Short: "Upload proxy configs and certificates to nodes and reload the proxy",
+		Long: `Generate the proxy.backend (nginx or caddy) config for every service with a
+proxy: section and distribute it, with the certificates of proxy.ssl services,
+to the node given with --node or to every registered node.
+
+Files go over SFTP, and only changed ones are transferred. Configs land in
+proxy.config_path (default ~/.orbit/proxy), a directory Orbit owns and the
+node's proxy must include; certificates land in ssl.cert_dir. The proxy's
+config is then validated (nginx -t, caddy validate) and, when it passes,
+reloaded gracefully. When validation fails the previous config directory is
+restored and the running proxy is left untouched. A service whose deployment
+on a node is not ready, unhealthy or crash-looping is left out of that node's
+config until the next push.
+
+Set proxy.validate_command and proxy.reload_command to run them differently,
+e.g. through sudo.
+
+With --diff (or --dry-run) nothing is uploaded: the generated configs are
+compared with those on each node and the differences shown.`,
+		Example: `  orbit proxy push
+  orbit proxy push --node prod-01
+  orbit proxy push --diff
+  orbit proxy push --force -o json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			px := rt.Config.Proxy
+
+			pool := rt.NewPool()
+			defer pool.Close()
+			pusher, err := push.New(pool, rt.Config.Services, push.Options{
+				Backend:         px.Backend,
+				ConfigDir:       px.ConfigPath,
+				CertDir:         rt.Config.SSL.RemoteCertDir(),
+				LocalCertDir:    rt.Config.SSL.ResolvedCertDir(),
+				ValidateCommand: px.ValidateCommand,
+				ReloadCommand:   px.ReloadCommand,
+				Force:           force,
+				Serving: func(node, service string) bool {
+					st, err := rt.State.GetServiceState(node, service)
+					return err != nil || st == nil || lb.Serving(*st)
+				},
+			}, rt.Log)
+			if err != nil {
+				return errs.New(errs.ErrConfig, "proxy.push", err).
+					WithAdvice("Set proxy.backend to nginx or caddy in orbit.yaml")
+			}
+
+			registry := remote.NewRegistry(rt.State)
+			var nodes []v1.NodeInfo
+			if rt.Flags.Node != "" {
+				info, err := registry.Get(rt.Flags.Node)
+				if err != nil {
+					return err
+				}
+				nodes = append(nodes, info)
+			} else if nodes, err = registry.List(); err != nil {
+				return err
+			}
+			if len(nodes) == 0 {
+				pprint.Warn("No nodes registered; add one with `orbit nodes add`")
+				return nil
+			}
+
+			if diff || rt.Flags.DryRun {
+				return previewProxyPush(cmd.Context(), rt, pusher, nodes)
+			}
+
+			results := make([]push.Result, 0, len(nodes))
+			failed := 0
+			for _, n := range nodes {
+				res := pusher.Push(cmd.Context(), n)
+				if res.Error != "" {
+					failed++
+				}
+				results = append(results, res)
+			}
+			if err := output.Render(rt.Flags.Output, results, pushView); err != nil {
+				return err
+			}
+			if failed > 0 {
+				return fmt.Errorf("proxy push failed on %d of %d node(s)", failed, len(nodes))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Validate and reload even when no file changed")
+	cmd.Flags().BoolVar(&diff, "diff", false, "Show how the configs on each node would change, without pushing")
+	return cmd
+}
+
+// nodeConfigDiff is the --diff result for one node in structured output.
+type nodeConfigDiff struct {
+	Node  string          `json:"node"`
+	Files []push.FileDiff `json:"files"`
+}
+
+// previewProxyPush prints how a push would change each node's proxy configs.
+func previewProxyPush(ctx context.Context, rt *Runtime, pusher *push.Pusher, nodes []v1.NodeInfo) error {
+	var all []nodeConfigDiff
+	for _, n := range nodes {
+		diffs, err := pusher.Diff(ctx, n)
+		if err != nil {
+			return fmt.Errorf("%s: %w", n.Spec.Name, err)
+		}
+		all = append(all, nodeConfigDiff{Node: n.Spec.Name, Files: diffs})
+	}
+	if rt.Flags.Output.Format.Structured() {
+		return output.Encode(rt.Flags.Output, all)
+	}
+
+	for _, nd := range all {
+		if len(nd.Files) == 0 {
+			pprint.Success("%s: proxy configs up to date", nd.Node)
+			continue
+		}
+		pprint.Header("Proxy configs — " + nd.Node)
+		for _, f := range nd.Files {
+			from, to := nd.Node+":"+f.Path, "generated/"+f.Path
+			if f.Old == "" {
+				from = "/dev/null"
+			}
+			if f.New == "" {
+				to = "/dev/null"
+			}
+			pprint.NewDiff().Unified(from, to, f.Old, f.New)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// pushView is the table layout for `orbit proxy push`.
+var pushView = output.View[push.Result]{
+	ID: func(r push.Result) string { return r.Node },
+	Columns: []output.Column[push.Result]{
+		{Header: "NODE", Value: func(r push.Result) string { return r.Node }},
+		{Header: "UPLOADED", Value: func(r push.Result) string { return strconv.Itoa(len(r.Uploaded)) }},
+		{Header: "DELETED", Value: func(r push.Result) string { return strconv.Itoa(len(r.Deleted)) }},
+		{Header: "FILES", Wide: true, Value: func(r push.Result) string {
+			return orDash(strings.Join(append(append([]string{}, r.Uploaded...), r.Deleted...), ","))
+		}},
+		{Header: "RESULT", Value: pushOutcome},
+	},
+}
+
+// pushOutcome summarises what a push did to a node.
+func pushOutcome(r push.Result) string {
+	switch {
+	case r.RolledBack:
+		return "rolled back: " + r.Error
+	case r.Error != "":
+		return "failed: " + r.Error
+	case r.Reloaded:
+		return "reloaded"
+	default:
+		return "unchanged"
+	}
+}
@@ -0,0 +1,126 @@
+// orbit prune — remove orphaned containers, stale state, and superseded images.
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/pprint"
+	"github.com/f9-o/orbit/pkg/pprint/prompt"
+)
+
+func NewPruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove orphaned containers, stale state, and superseded images",
+		Long: `Find orbit resources nothing refers to any more and remove them:
+
+  • containers labelled orbit.service whose service is not in the state DB
+  • temporary -new-/-rollback- containers left by an interrupted deploy
+  • finished task containers from 'orbit run'
+  • state entries whose container no longer exists
+  • images from earlier deploys that no container uses
+  • dangling volumes and networks labelled orbit.service
+
+Everything found is listed first; nothing is removed until you confirm.
+Services with a held deploy lock are skipped. Named volumes declared in
+orbit.yaml are never removed.`,
+		Example: `  orbit prune              # list, then confirm
+  orbit prune --dry-run    # list only
+  orbit prune --yes        # remove without prompting`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			out := rt.Flags.Output
+			yes := rt.Flags.Yes
+
+			docker, err := rt.NewContainerClient()
+			if err != nil {
+				return fmt.Errorf("docker: %w", err)
+			}
+			defer docker.Close()
+
+			node := nodeOrLocal(rt.Flags.Node)
+			pruner := orchestrator.NewPruner(docker, rt.State, rt.Log)
+			items, err := pruner.Find(cmd.Context(), node)
+			if err != nil {
+				return fmt.Errorf("prune: %w", err)
+			}
+
+			if len(items) == 0 {
+				if out.Format.Structured() {
+					return output.Encode(out, []orchestrator.PruneItem{})
+				}
+				if !out.Quiet {
+					pprint.Success("Nothing to prune")
+				}
+				return nil
+			}
+
+			if rt.Flags.DryRun || !yes {
+				if err := output.Render(out, items, pruneView); err != nil {
+					return err
+				}
+			}
+			if rt.Flags.DryRun {
+				return nil
+			}
+			if !yes {
+				if out.Format.Structured() || out.Quiet {
+					return fmt.Errorf("refusing to prune without confirmation; pass --yes")
+				}
+				fmt.Println()
+				ok, err := prompt.Confirm(fmt.Sprintf("Remove %d item(s)?", len(items)), false)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Println("Aborted.")
+					return nil
+				}
+			}
+
+			results := pruner.Prune(cmd.Context(), node, items)
+			failed := errs.NewGroup(errs.ErrDockerRemove, "prune")
+			for _, r := range results {
+				var err error
+				if r.Error != "" {
+					err = errors.New(r.Error)
+				}
+				failed.Add(string(r.Kind)+" "+r.Name, err)
+			}
+
+			if out.Format.Structured() {
+				if err := output.Encode(out, results); err != nil {
+					return err
+				}
+				if failed.Len() > 0 {
+					return &ExitError{Code: 1} // the failures are in the output
+				}
+				return nil
+			}
+			if !out.Quiet {
+				pprint.Success("Removed %d of %d item(s)", len(results)-failed.Len(), len(results))
+			}
+			return failed.Err()
+		},
+	}
+
+	return cmd
+}
+
+// pruneView is the table layout for `orbit prune`.
+var pruneView = output.View[orchestrator.PruneItem]{
+	ID: func(it orchestrator.PruneItem) string { return it.ID },
+	Columns: []output.Column[orchestrator.PruneItem]{
+		{Header: "KIND", Value: func(it orchestrator.PruneItem) string { return string(it.Kind) }},
+		{Header: "NAME", Value: func(it orchestrator.PruneItem) string { return it.Name }},
+		{Header: "SERVICE", Value: func(it orchestrator.PruneItem) string { return it.Service }},
+		{Header: "ID", Wide: true, Value: func(it orchestrator.PruneItem) string { return it.ID }},
+		{Header: "REASON", Value: func(it orchestrator.PruneItem) string { return it.Reason }},
+	},
+}
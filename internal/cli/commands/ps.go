@@ -0,0 +1,127 @@
+// orbit ps — list services and their runtime state.
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/core/state"
+)
+
+func NewPsCmd() *cobra.Command {
+	var allNodes, allProjects bool
+	var selector []string
+
+	cmd := &cobra.Command{
+		Use:         "ps",
+		Annotations: readsState,
+		Short:       "List services with status, replicas, and restart counts",
+		Long: `List services with their health, replicas and restarts. RESTARTS counts
+unexpected exits since the last up or deploy, as seen by orbit agent or
+reported by the container runtime. A service that exited within the
+crash-loop window is degraded even while its probes pass, since its restart
+policy may be hiding a crash loop. -o wide adds the latest exit code, marked
+OOM when the kernel killed the container for memory.
+
+Only the services of the current project are listed: project.name of
+orbit.yaml, or --project. --all-projects lists every project's, with -o wide
+showing which each belongs to.
+
+--selector lists only the services whose labels match: the labels of their
+spec in orbit.yaml, and orbit.service, orbit.node and orbit.project.`,
+		Example: `  orbit ps
+  orbit ps --all-nodes
+  orbit ps -l tier=backend
+  orbit ps --all-projects -o wide
+  orbit ps -o wide`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			sel, err := parseSelector(selector)
+			if err != nil {
+				return err
+			}
+
+			node := nodeOrLocal(rt.Flags.Node)
+			if allNodes {
+				node = ""
+			}
+			db := rt.State
+			if allProjects {
+				db = db.Project(state.AllProjects)
+			}
+			states, err := db.ListServiceStates(node)
+			if err != nil {
+				return err
+			}
+			states = selectStates(rt, sel, states)
+			sort.Slice(states, func(i, j int) bool {
+				if states[i].Project != states[j].Project {
+					return states[i].Project < states[j].Project
+				}
+				if states[i].Node != states[j].Node {
+					return states[i].Node < states[j].Node
+				}
+				return states[i].Name < states[j].Name
+			})
+			return output.Render(rt.Flags.Output, states, psView)
+		},
+	}
+
+	cmd.Flags().BoolVar(&allNodes, "all-nodes", false, "List services on every node")
+	cmd.Flags().BoolVar(&allProjects, "all-projects", false, "List the services of every project")
+	addSelectorFlag(cmd, &selector)
+	return cmd
+}
+
+// psView is the table layout for `orbit ps`.
+var psView = output.View[v1.ServiceState]{
+	ID: func(s v1.ServiceState) string { return s.Name },
+	Columns: []output.Column[v1.ServiceState]{
+		{Header: "PROJECT", Wide: true, Value: func(s v1.ServiceState) string { return orDash(s.Project) }},
+		{Header: "NODE", Wide: true, Value: func(s v1.ServiceState) string { return s.Node }},
+		{Header: "NAME", Value: func(s v1.ServiceState) string { return s.Name }},
+		{Header: "IMAGE", Value: func(s v1.ServiceState) string { return s.Image }},
+		{Header: "STATUS", Value: serviceStatus},
+		{Header: "REPLICAS", Value: func(s v1.ServiceState) string { return fmt.Sprint(max(s.Replicas, 1)) }},
+		{Header: "RESTARTS", Value: func(s v1.ServiceState) string { return fmt.Sprint(s.RestartCount) }},
+		{Header: "LAST EXIT", Wide: true, Value: lastExit},
+		{Header: "UP", Value: func(s v1.ServiceState) string {
+			if s.StartedAt.IsZero() {
+				return "-"
+			}
+			return fmtDuration(time.Since(s.StartedAt))
+		}},
+		{Header: "CONTAINER", Wide: true, Value: func(s v1.ServiceState) string { return s.ContainerID[:min(12, len(s.ContainerID))] }},
+	},
+}
+
+// serviceStatus is the STATUS cell: the health status, overridden when the
+// watchdog has stopped the service for crash-looping.
+func serviceStatus(s v1.ServiceState) string {
+	if s.CrashLoop {
+		return "✖ crash-loop"
+	}
+	status := s.Status
+	if status == "" {
+		status = v1.StatusUnknown
+	}
+	return string(status)
+}
+
+// lastExit is the LAST EXIT cell: the exit code of the latest unexpected
+// exit, whether it was an OOM kill, and how long ago.
+func lastExit(s v1.ServiceState) string {
+	if s.LastExit.IsZero() {
+		return "-"
+	}
+	code := fmt.Sprint(s.ExitCode)
+	if s.OOMKilled {
+		code += " (OOM)"
+	}
+	return fmt.Sprintf("%s, %s ago", code, fmtDuration(time.Since(s.LastExit)))
+}
@@ -0,0 +1,111 @@
+// orbit pull — pull service images with per-layer progress.
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+func NewPullCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pull [service...]",
+		Short: "Pull the images of services in orbit.yaml",
+		Long: `Pull the image of each named service, or of every service, showing the
+progress of each layer as docker pull does. Images already present are
+pulled again so moving tags such as latest are refreshed.`,
+		Example: `  orbit pull
+  orbit pull web worker`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+
+			specs := rt.Config.Services
+			if len(args) > 0 {
+				specs = nil
+				for _, name := range args {
+					svc := rt.Config.ServiceByName(name)
+					if svc == nil {
+						return fmt.Errorf("service %q not found in orbit.yaml", name)
+					}
+					specs = append(specs, *svc)
+				}
+			}
+
+			docker, err := rt.NewContainerClient()
+			if err != nil {
+				return fmt.Errorf("docker: %w", err)
+			}
+			defer docker.Close()
+
+			n, err := pullImages(cmd.Context(), docker, specs, false)
+			if err != nil {
+				return err
+			}
+			pprint.Success("Pulled %d image(s)", n)
+			return nil
+		},
+	}
+	return cmd
+}
+
+// pullImages pulls the images of specs, each once, drawing layer progress.
+// With missingOnly, images already present are skipped. It returns how many
+// images were pulled.
+func pullImages(ctx context.Context, docker *orchestrator.Client, specs []v1.ServiceSpec, missingOnly bool) (int, error) {
+	bars := &pullRenderer{}
+	docker.WithPullProgress(bars.update)
+	defer docker.WithPullProgress(nil)
+
+	pulled := 0
+	seen := map[string]bool{}
+	for _, s := range specs {
+		if s.Image == "" || seen[s.Image] {
+			continue
+		}
+		seen[s.Image] = true
+		var err error
+		if missingOnly {
+			var did bool
+			did, err = docker.EnsureImage(ctx, s.Image)
+			if did {
+				pulled++
+			}
+		} else {
+			err = docker.PullImage(ctx, s.Image)
+			pulled++
+		}
+		bars.finish(err == nil)
+		if err != nil {
+			return pulled, err
+		}
+	}
+	return pulled, nil
+}
+
+// pullRenderer draws the layer progress of image pulls, one image at a time.
+type pullRenderer struct {
+	image string
+	bars  *pprint.LayerProgress
+}
+
+func (r *pullRenderer) update(ev orchestrator.PullEvent) {
+	if ev.Image != r.image {
+		r.finish(true)
+		r.image, r.bars = ev.Image, pprint.NewLayerProgress("Pulling "+ev.Image)
+	}
+	r.bars.Update(ev.Layer, ev.Status, ev.Current, ev.Total)
+}
+
+// finish completes the current image's progress, if any.
+func (r *pullRenderer) finish(ok bool) {
+	if r.bars != nil {
+		r.bars.Done(ok)
+	}
+	r.image, r.bars = "", nil
+}
@@ -0,0 +1,102 @@
+// orbit run — run a one-off task in a disposable service container.
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/orchestrator"
+)
+
+// ExitError carries a task's exit status out of a command so the process can
+// exit with the same code. It is returned without a message to print.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func NewRunCmd() *cobra.Command {
+	var interactive, tty bool
+	var env []string
+
+	cmd := &cobra.Command{
+		Use:   "run <service> [-- command...]",
+		Short: "Run a one-off task in a disposable container from a service's spec",
+		Long: `Run a one-off command — a migration, a console, an admin script — in a new
+container built from the service's image, environment, volumes, and networks.
+Ports are not published. Output is streamed, the container is removed when the
+command finishes, and orbit exits with the command's exit code.`,
+		Args: cobra.MinimumNArgs(1),
+		Example: `  orbit run api -- ./manage.py migrate
+  orbit run api -e DEBUG=1 -- ./scripts/reindex.sh
+  orbit run -it api -- /bin/sh`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			name := args[0]
+
+			if nodeOrLocal(rt.Flags.Node) != "local" {
+				return fmt.Errorf("orbit run only supports the local node")
+			}
+			spec := rt.Config.ServiceByName(name)
+			if spec == nil {
+				return fmt.Errorf("service %q not found in orbit.yaml", name)
+			}
+			rt.audit(name, map[string]string{"image": spec.Image})
+
+			overrides := map[string]string{}
+			for _, kv := range env {
+				k, v, ok := strings.Cut(kv, "=")
+				if !ok {
+					v = os.Getenv(k) // -e NAME passes the local value through
+				}
+				overrides[k] = v
+			}
+
+			docker, err := rt.NewContainerClient()
+			if err != nil {
+				return fmt.Errorf("docker: %w", err)
+			}
+			defer docker.Close()
+
+			opts := orchestrator.TaskOptions{
+				Cmd:    args[1:],
+				Env:    overrides,
+				TTY:    tty,
+				Stdout: os.Stdout,
+				Stderr: os.Stderr,
+			}
+			if interactive {
+				opts.Stdin = os.Stdin
+			}
+			if fd, isTerm := term.GetFdInfo(os.Stdin); tty && interactive && isTerm {
+				state, err := term.SetRawTerminal(fd)
+				if err != nil {
+					return fmt.Errorf("terminal: %w", err)
+				}
+				defer term.RestoreTerminal(fd, state) //nolint:errcheck
+			}
+
+			code, err := docker.RunTask(cmd.Context(), *spec, opts)
+			if err != nil {
+				return fmt.Errorf("run %s: %w", name, err)
+			}
+			if code != 0 {
+				return &ExitError{Code: code}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Attach stdin to the task")
+	cmd.Flags().BoolVarP(&tty, "tty", "t", false, "Allocate a pseudo-terminal")
+	cmd.Flags().StringArrayVarP(&env, "env", "e", nil, "Set an environment variable (KEY=VALUE, or KEY to pass through the local value)")
+	return cmd
+}
@@ -3,60 +3,92 @@ package commands
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	v1 "github.com/f9-o/orbit/api/v1"
 	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/errs"
 )
 
 func NewScaleCmd() *cobra.Command {
 	var replicas int
 
 	cmd := &cobra.Command{
-		Use:   "scale <service>",
-		Short: "Scale a service to the specified number of replicas",
-		Args:  cobra.ExactArgs(1),
-		Example: `  orbit scale web --replicas 3
-  orbit scale worker --replicas 0   # stop all replicas`,
+		Use:   "scale <service>[=<replicas>]...",
+		Short: "Scale services to the specified number of replicas",
+		Long: `Scale one or more services. Give each service its count as service=N, or
+use --replicas for every service named without one; a service with neither
+goes back to deploy.replicas from orbit.yaml (or 1).
+
+The count is remembered, so later ` + "`orbit up`" + ` and ` + "`orbit deploy`" + ` runs keep it
+instead of resetting to deploy.replicas; scaling back to deploy.replicas
+forgets it. A service scaled to 0 is started again by the next up or deploy.`,
+		Args: cobra.MinimumNArgs(1),
+		Example: `  orbit scale web=3 worker=2
+  orbit scale web --replicas 3
+  orbit scale worker --replicas 0   # stop all replicas
+  orbit scale web                   # back to deploy.replicas`,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			rt := FromContext(cmd.Context())
-			serviceName := args[0]
 
-			svcSpec := rt.Config.ServiceByName(serviceName)
-			if svcSpec == nil {
-				return fmt.Errorf("service %q not found in orbit.yaml", serviceName)
+			type target struct {
+				spec     v1.ServiceSpec
+				replicas int
+			}
+			targets := make([]target, 0, len(args))
+			for _, arg := range args {
+				name, count, hasCount := strings.Cut(arg, "=")
+				spec := rt.Config.ServiceByName(name)
+				if spec == nil {
+					return errs.Newf(errs.ErrServiceNotFound, "scale", "service %q not found in orbit.yaml", name)
+				}
+				n := orchestrator.DefaultReplicas(*spec)
+				switch {
+				case hasCount:
+					var err error
+					if n, err = strconv.Atoi(count); err != nil || n < 0 {
+						return errs.Newf(errs.ErrValidation, "scale", "%q: replicas must be a whole number >= 0", arg)
+					}
+				case cmd.Flags().Changed("replicas"):
+					n = replicas
+				}
+				targets = append(targets, target{*spec, n})
 			}
 
-			nodeName := rt.Flags.Node
-			if nodeName == "" {
-				nodeName = "local"
+			nodeName := nodeOrLocal(rt.Flags.Node)
+			if rt.Flags.DryRun {
+				for _, t := range targets {
+					fmt.Printf("[dry-run] would scale %q to %d replicas on %q\n", t.spec.Name, t.replicas, nodeName)
+				}
+				return nil
 			}
 
-			docker, err := orchestrator.NewClient("", rt.Log)
+			docker, err := rt.NewContainerClient()
 			if err != nil {
 				return fmt.Errorf("docker: %w", err)
 			}
 			defer docker.Close()
 
-			scaler := orchestrator.NewScaler(docker, rt.State, rt.Log)
+			scaler := orchestrator.NewScaler(docker, rt.State, rt.Log).WithHooks(rt.Plugins)
 
-			if rt.Flags.DryRun {
-				fmt.Printf("[dry-run] would scale %q to %d replicas on %q\n", serviceName, replicas, nodeName)
-				return nil
-			}
-
-			fmt.Printf("◉ Scaling %q to %d replica(s)...\n", serviceName, replicas)
-			if err := scaler.Scale(cmd.Context(), *svcSpec, nodeName, replicas); err != nil {
-				return fmt.Errorf("scale: %w", err)
+			failed := errs.NewGroup(errs.ErrServiceStart, "scale")
+			for _, t := range targets {
+				rt.audit(t.spec.Name, map[string]string{"replicas": strconv.Itoa(t.replicas)})
+				fmt.Printf("◉ Scaling %q to %d replica(s)...\n", t.spec.Name, t.replicas)
+				if err := scaler.Scale(cmd.Context(), t.spec, nodeName, t.replicas); err != nil {
+					failed.Add(t.spec.Name, err)
+					continue
+				}
+				fmt.Printf("✓ %q scaled to %d\n", t.spec.Name, t.replicas)
 			}
-
-			fmt.Printf("✓ %q scaled to %d\n", serviceName, replicas)
-			return nil
+			return failed.Err()
 		},
 	}
 
-	cmd.Flags().IntVar(&replicas, "replicas", 1, "Target number of replicas")
-	_ = cmd.MarkFlagRequired("replicas")
+	cmd.Flags().IntVar(&replicas, "replicas", 0, "Replicas for services named without =N (default: deploy.replicas)")
 	return cmd
 }
@@ -0,0 +1,239 @@
+// orbit scan — check service images for known vulnerabilities.
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/scan"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+// scannedService is one service's row of `orbit scan`.
+type scannedService struct {
+	Service string `json:"service"`
+	scan.Report
+	Error string `json:"error,omitempty"`
+}
+
+func NewScanCmd() *cobra.Command {
+	var (
+		tag, scanner, failOn string
+		ignoreUnfixed        bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "scan [service...]",
+		Short: "Scan service images for known vulnerabilities with Trivy or Grype",
+		Long: `Scan the image of each service in orbit.yaml, or of those named, for known
+vulnerabilities, and list what is found grouped by severity.
+
+Orbit runs Trivy or Grype, whichever is installed (scan.scanner or
+--scanner picks one), so the image is scanned from its registry unless
+the local Docker daemon has it.
+
+--fail-on exits non-zero when any image has a vulnerability of that
+severity or worse, for CI. With scan.deploy set in orbit.yaml, orbit deploy
+and the agent scan every image before deploying it and refuse one that
+fails scan.fail_on.`,
+		Example: `  orbit scan
+  orbit scan web --tag v1.3.0
+  orbit scan --fail-on high --ignore-unfixed
+  orbit scan -o json > scan.json`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			specs := rt.Config.Services
+			if len(args) > 0 {
+				specs = nil
+				for _, name := range args {
+					svc := rt.Config.ServiceByName(name)
+					if svc == nil {
+						return fmt.Errorf("service %q not found in orbit.yaml", name)
+					}
+					specs = append(specs, *svc)
+				}
+			}
+
+			settings := rt.Config.Scan
+			if cmd.Flags().Changed("scanner") {
+				settings.Scanner = scanner
+			}
+			if cmd.Flags().Changed("fail-on") {
+				settings.FailOn = failOn
+			}
+			if cmd.Flags().Changed("ignore-unfixed") {
+				settings.IgnoreUnfixed = ignoreUnfixed
+			}
+			var threshold scan.Severity
+			if settings.FailOn != "" {
+				sev, err := scan.ParseSeverity(settings.FailOn)
+				if err != nil {
+					return errs.New(errs.ErrValidation, "scan", err)
+				}
+				threshold = sev
+			}
+			s, err := scan.Detect(settings.Scanner)
+			if err != nil {
+				return errs.New(errs.ErrImageScan, "scan", err).
+					WithAdvice("Install Trivy (https://trivy.dev) or Grype (https://github.com/anchore/grype)")
+			}
+
+			out := rt.Flags.Output
+			quietly := out.Quiet || out.Format.Structured()
+			rows := make([]scannedService, len(specs))
+			for i, spec := range specs {
+				image := orchestrator.ResolveImage(spec.Image, tag)
+				rows[i] = scannedService{Service: spec.Name, Report: scan.Report{Image: image, Scanner: s.Name()}}
+				var sp *pprint.Spinner
+				if !quietly {
+					sp = pprint.NewSpinner(fmt.Sprintf("Scanning %s with %s", image, s.Name()))
+					sp.Start()
+				}
+				r, err := s.Scan(cmd.Context(), image, scan.Options{IgnoreUnfixed: settings.IgnoreUnfixed})
+				if sp != nil {
+					sp.Stop(err == nil)
+				}
+				if err != nil {
+					rows[i].Error = err.Error()
+					continue
+				}
+				rows[i].Report = r
+			}
+
+			if err := output.Render(out, scanRows(rows, threshold, out), scanView); err != nil {
+				return err
+			}
+			if !quietly {
+				for _, r := range rows {
+					printFindings(r)
+				}
+			}
+
+			failed := errs.NewGroup(errs.ErrImageScan, "scan")
+			for _, r := range rows {
+				switch {
+				case r.Error != "":
+					failed.Add(r.Service, errors.New(r.Error))
+				case threshold != "":
+					failed.Add(r.Service, scan.Check(r.Report, threshold))
+				}
+			}
+			return failed.Err()
+		},
+	}
+
+	cmd.Flags().StringVar(&tag, "tag", "", "Scan the image with this tag instead of the one in orbit.yaml")
+	cmd.Flags().StringVar(&scanner, "scanner", "", "Scanner to run: trivy or grype (default: scan.scanner, or whichever is installed)")
+	cmd.Flags().StringVar(&failOn, "fail-on", "", "Exit non-zero on a vulnerability of this severity or worse: critical, high, medium, low (default: scan.fail_on)")
+	cmd.Flags().BoolVar(&ignoreUnfixed, "ignore-unfixed", false, "Leave out vulnerabilities with no fixed version (default: scan.ignore_unfixed)")
+	return cmd
+}
+
+// scanRows is what `orbit scan` lists: in quiet mode, only the services
+// whose image fails --fail-on, or has any vulnerability without it.
+func scanRows(rows []scannedService, threshold scan.Severity, out output.Options) []scannedService {
+	if !out.Quiet {
+		return rows
+	}
+	if threshold == "" {
+		threshold = scan.Unknown
+	}
+	var failing []scannedService
+	for _, r := range rows {
+		if r.Error == "" && len(r.AtLeast(threshold)) > 0 {
+			failing = append(failing, r)
+		}
+	}
+	return failing
+}
+
+// scanView is the table layout for `orbit scan`.
+var scanView = output.View[scannedService]{
+	ID: func(r scannedService) string { return r.Service },
+	Columns: []output.Column[scannedService]{
+		{Header: "SERVICE", Value: func(r scannedService) string { return r.Service }},
+		{Header: "IMAGE", Value: func(r scannedService) string { return r.Image }},
+		{Header: "SCANNER", Wide: true, Value: func(r scannedService) string { return r.Scanner }},
+		severityColumn(scan.Critical),
+		severityColumn(scan.High),
+		severityColumn(scan.Medium),
+		severityColumn(scan.Low),
+		{Header: "RESULT", Value: func(r scannedService) string {
+			switch {
+			case r.Error != "":
+				return "✖ " + r.Error
+			case len(r.Findings) == 0:
+				return "clean"
+			}
+			return strconv.Itoa(len(r.Findings)) + " found"
+		}},
+	},
+}
+
+// severityColumn counts the findings of sev.
+func severityColumn(sev scan.Severity) output.Column[scannedService] {
+	return output.Column[scannedService]{
+		Header: string(sev),
+		Value: func(r scannedService) string {
+			if r.Error != "" {
+				return "-"
+			}
+			return strconv.Itoa(r.Counts()[sev])
+		},
+	}
+}
+
+// printFindings lists the findings of r's image, grouped by severity.
+func printFindings(r scannedService) {
+	if r.Error != "" || len(r.Findings) == 0 {
+		return
+	}
+	pprint.Header(r.Service + " — " + r.Image)
+	for _, sev := range scan.Severities {
+		var group []scan.Finding
+		for _, f := range r.Findings {
+			if f.Severity == sev {
+				group = append(group, f)
+			}
+		}
+		if len(group) == 0 {
+			continue
+		}
+		fmt.Printf("\n  %s (%d)\n", sev, len(group))
+		for _, f := range group {
+			fix := pprint.StyleMuted.Render("no fix")
+			if f.Fixed != "" {
+				fix = "→ " + f.Fixed
+			}
+			fmt.Printf("    %-20s %s %s %s  %s\n", f.ID, f.Package, f.Installed, fix, pprint.StyleMuted.Render(f.Title))
+		}
+	}
+	fmt.Println()
+}
+
+// scanPolicy is the pre-deploy policy scan.deploy asks for: every image is
+// scanned before it is deployed, and refused at scan.fail_on or worse. It is
+// nil when scan.deploy is off.
+func scanPolicy(rt *Runtime) (orchestrator.Policy, error) {
+	settings := rt.Config.Scan
+	if !settings.Deploy {
+		return nil, nil
+	}
+	threshold, err := scan.ParseSeverity(settings.FailOn)
+	if err != nil {
+		return nil, errs.New(errs.ErrConfig, "scan.policy", err)
+	}
+	s, err := scan.Detect(settings.Scanner)
+	if err != nil {
+		return nil, errs.New(errs.ErrImageScan, "scan.policy", err).
+			WithAdvice("scan.deploy is set in orbit.yaml: install Trivy or Grype, or deploy with --skip-scan")
+	}
+	return s.Gate(threshold, scan.Options{IgnoreUnfixed: settings.IgnoreUnfixed}), nil
+}
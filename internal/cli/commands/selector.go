@@ -0,0 +1,86 @@
+// Package commands: -l/--selector, which narrows a command to the services
+// whose labels match.
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// addSelectorFlag registers -l/--selector on cmd, filling exprs.
+func addSelectorFlag(cmd *cobra.Command, exprs *[]string) {
+	cmd.Flags().StringArrayVarP(exprs, "selector", "l", nil,
+		"Only services whose labels match, e.g. tier=backend, tier!=edge or !canary (comma-separated or repeated; all must hold)")
+}
+
+// parseSelector parses the --selector expressions.
+func parseSelector(exprs []string) (config.Selector, error) {
+	sel, err := config.ParseSelector(exprs...)
+	if err != nil {
+		return nil, errs.New(errs.ErrValidation, "cli.selector", err).
+			WithAdvice("Match the labels of a service in orbit.yaml, or orbit.service, orbit.node and orbit.project")
+	}
+	return sel, nil
+}
+
+// stateLabels are the labels a selector matches a service's state against.
+// Its spec's labels are known when it is a service of this project.
+func stateLabels(rt *Runtime, s v1.ServiceState) map[string]string {
+	var spec *v1.ServiceSpec
+	if s.Project == "" || s.Project == rt.State.ProjectName() {
+		spec = rt.Config.ServiceByName(s.Name)
+	}
+	return config.ServiceLabels(spec, s.Name, s.Node, s.Project)
+}
+
+// selectStates returns the states of states that sel matches.
+func selectStates(rt *Runtime, sel config.Selector, states []v1.ServiceState) []v1.ServiceState {
+	if len(sel) == 0 {
+		return states
+	}
+	var out []v1.ServiceState
+	for _, s := range states {
+		if sel.Matches(stateLabels(rt, s)) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// selectedServices returns the names of the services with state on node
+// that the --selector expressions match, sorted. Matching none is an error.
+func selectedServices(rt *Runtime, node string, selector []string) ([]string, error) {
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	states, err := rt.State.ListServiceStates(node)
+	if err != nil {
+		return nil, fmt.Errorf("state: %w", err)
+	}
+	var names []string
+	for _, s := range selectStates(rt, sel, states) {
+		names = append(names, s.Name)
+	}
+	if len(names) == 0 {
+		return nil, noneSelected(rt, sel)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// noneSelected is the error for a selector that matched no service.
+func noneSelected(rt *Runtime, sel config.Selector) error {
+	advice := "No service in orbit.yaml has labels; add them under a service's labels:"
+	if keys := rt.Config.LabelKeys(); len(keys) > 0 {
+		advice = "Services in orbit.yaml are labelled with " + strings.Join(keys, ", ")
+	}
+	return errs.Newf(errs.ErrServiceNotFound, "cli.selector", "no service matches --selector %s", sel).WithAdvice(advice)
+}
@@ -0,0 +1,110 @@
+// orbit self-update — replace the orbit binary with a newer release.
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/update"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/pprint"
+	"github.com/f9-o/orbit/pkg/pprint/prompt"
+)
+
+func NewSelfUpdateCmd() *cobra.Command {
+	var version string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Replace this orbit binary with the latest release",
+		Long: `Download the latest Orbit release (or the one given with --version) for
+this platform from GitHub, verify it against the release's checksums.txt and
+replace the running binary with it.
+
+The binary is replaced in place, so you need write access to its directory;
+for a system-wide install, run it with sudo. Development builds are only
+replaced with --force.`,
+		Example: `  orbit self-update
+  orbit self-update --version v1.4.0
+  orbit self-update --dry-run`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			ctx := cmd.Context()
+
+			exe, err := os.Executable()
+			if err == nil {
+				exe, err = filepath.EvalSymlinks(exe)
+			}
+			if err != nil {
+				return fmt.Errorf("locate the orbit binary: %w", err)
+			}
+
+			client := update.New()
+			var rel *update.Release
+			if version != "" {
+				rel, err = client.Tag(ctx, version)
+			} else {
+				rel, err = client.Latest(ctx)
+			}
+			if err != nil {
+				return errs.Wrap(err, errs.ErrUnknown, "self-update").
+					WithAdvice("Check your connection, or download a release from https://github.com/f9-o/orbit/releases")
+			}
+			rt.audit("", map[string]string{"from": Version, "to": rel.Version})
+
+			switch {
+			case rel.Version == Version && !force:
+				pprint.Success("Orbit %s is already installed", Version)
+				return nil
+			case version == "" && !force && update.IsRelease(Version) && !update.Newer(rel.Version, Version):
+				pprint.Success("Orbit %s is the latest release", Version)
+				return nil
+			case !update.IsRelease(Version) && !force:
+				return errs.Newf(errs.ErrValidation, "self-update", "this is a development build (%s)", Version).
+					WithAdvice("Pass --force to replace it with " + rel.Version)
+			}
+
+			pprint.KV("Binary", exe)
+			pprint.KV("Installed", Version)
+			pprint.KV("Release", rel.Version+"  "+rel.URL)
+			if rt.Flags.DryRun {
+				pprint.Info("Dry run: nothing downloaded")
+				return nil
+			}
+			ok, err := prompt.Confirm(fmt.Sprintf("Replace orbit %s with %s?", Version, rel.Version), true)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Aborted.")
+				return nil
+			}
+
+			sp := pprint.NewSpinner("Downloading " + update.BinaryName(runtime.GOOS, runtime.GOARCH))
+			sp.Start()
+			err = client.Install(ctx, rel, exe)
+			sp.Stop(err == nil)
+			if errors.Is(err, os.ErrPermission) {
+				return errs.Wrap(err, errs.ErrUnknown, "self-update").
+					WithAdvice("Re-run with sudo, or install orbit somewhere you can write to")
+			}
+			if err != nil {
+				return err
+			}
+			pprint.Success("Orbit updated to %s", rel.Version)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&version, "version", "", "Install this release instead of the latest, e.g. v1.4.0")
+	cmd.Flags().BoolVar(&force, "force", false, "Reinstall the same release, or replace a development build")
+	return cmd
+}
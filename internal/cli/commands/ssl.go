@@ -2,9 +2,21 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/internal/ssl"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/pprint"
 )
 
 func NewSSLCmd() *cobra.Command {
@@ -18,44 +30,111 @@ func NewSSLCmd() *cobra.Command {
 
 func newSSLIssueCmd() *cobra.Command {
 	var acmeURL, challenge, email string
+	var selfSigned bool
 
 	cmd := &cobra.Command{
-		Use:   "issue <domain>",
-		Short: "Issue a new SSL certificate for a domain",
-		Args:  cobra.ExactArgs(1),
+		Use:   "issue <domain> [domain...]",
+		Short: "Issue a new SSL certificate for one or more domains",
+		Long: `Order a certificate covering the given domains; files are written to
+<ssl.cert_dir>/<first domain>/{fullchain,privkey}.pem.
+
+The dns challenge publishes _acme-challenge TXT records through the provider
+named by ssl.dns_provider (cloudflare, route53, digitalocean or desec) using
+ssl.dns_credentials, so it works for wildcard domains and for hosts with no
+public port 80. It is the default whenever ssl.dns_provider is set.
+
+--self-signed signs the certificate with a local CA kept in ~/.orbit/ca
+instead, for localhost, LAN hosts and IP addresses that no public CA will
+validate. Import ~/.orbit/ca/ca.pem into your trust store once to make
+browsers accept them. It is the default in the development environment
+when no DNS provider is configured.`,
+		Args: cobra.MinimumNArgs(1),
 		Example: `  orbit ssl issue api.example.com
-  orbit ssl issue app.example.com --challenge dns`,
+  orbit ssl issue example.com '*.example.com' --challenge dns
+  orbit ssl issue app.test localhost 127.0.0.1 --self-signed`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			rt := FromContext(cmd.Context())
-			domain := args[0]
+			sslCfg := rt.Config.SSL
+
+			if !cmd.Flags().Changed("challenge") && !cmd.Flags().Changed("self-signed") &&
+				sslCfg.DNSProvider == "" && rt.Config.Project.Environment == "development" {
+				selfSigned = true
+			}
+			if selfSigned {
+				return issueSelfSigned(rt, args)
+			}
 
-			if email == "" && rt.Config != nil {
-				email = rt.Config.SSL.Email
+			if email == "" {
+				email = sslCfg.Email
 			}
 			if email == "" {
 				return fmt.Errorf("email is required (set ssl.email in orbit.yaml or pass --email)")
 			}
+			if acmeURL == "" {
+				acmeURL = sslCfg.AcmeURL
+			}
+			if !cmd.Flags().Changed("challenge") && sslCfg.DNSProvider != "" {
+				challenge = "dns"
+			}
 
-			if acmeURL == "" && rt.Config != nil {
-				acmeURL = rt.Config.SSL.AcmeURL
+			rt.Log.Info("ssl.issue", "domains", args, "email", email, "acme", acmeURL, "challenge", challenge)
+			switch challenge {
+			case "dns":
+			case "http":
+				return errs.Newf(errs.ErrSSLIssueFail, "ssl.issue", "the http challenge is not supported yet").
+					WithAdvice("Set ssl.dns_provider in orbit.yaml and use --challenge dns")
+			default:
+				return fmt.Errorf("unknown challenge %q (want http or dns)", challenge)
+			}
+			if sslCfg.DNSProvider == "" {
+				return errs.Newf(errs.ErrSSLIssueFail, "ssl.issue", "--challenge dns needs a DNS provider").
+					WithAdvice("Set ssl.dns_provider (" + strings.Join(ssl.ProviderNames(), ", ") + ") and ssl.dns_credentials in orbit.yaml")
+			}
+			provider, err := ssl.NewProvider(sslCfg.DNSProvider, sslCfg.DNSCredentials)
+			if err != nil {
+				return errs.Wrap(err, errs.ErrSSLIssueFail, "ssl.issue")
 			}
 
-			rt.Log.Info("ssl.issue", "domain", domain, "email", email, "acme", acmeURL, "challenge", challenge)
-			fmt.Printf("◉ Issuing certificate for %q...\n", domain)
-			fmt.Printf("  ACME: %s\n", acmeURL)
-			fmt.Printf("  Challenge: %s\n", challenge)
-			fmt.Println("  → ACME client integration in progress (lego library integration)")
-			fmt.Printf("✓ Certificate would be issued for %q\n", domain)
+			pprint.Info("Issuing certificate for %s via %s (dns-01)...", strings.Join(args, ", "), sslCfg.DNSProvider)
+			cert, err := ssl.NewIssuer(acmeURL, email, sslCfg.ResolvedCertDir(), config.ACMEAccountKeyFile(), rt.Log).
+				WithDNS(provider).
+				WithTimeout(sslCfg.Timeout).
+				Issue(cmd.Context(), args)
+			if err != nil {
+				return errs.Wrap(err, errs.ErrSSLIssueFail, "ssl.issue")
+			}
+			pprint.Success("Certificate issued for %s, valid until %s", strings.Join(cert.Domains, ", "), cert.NotAfter.Format("2006-01-02"))
+			fmt.Printf("  cert: %s\n  key:  %s\n", cert.CertFile, cert.KeyFile)
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVar(&acmeURL, "acme-url", "", "ACME directory URL (defaults to Let's Encrypt)")
-	cmd.Flags().StringVar(&challenge, "challenge", "http", "Challenge type: http | dns")
+	cmd.Flags().StringVar(&challenge, "challenge", "http", "Challenge type: http | dns (dns when ssl.dns_provider is set)")
 	cmd.Flags().StringVar(&email, "email", "", "Email address for ACME account")
+	cmd.Flags().BoolVar(&selfSigned, "self-signed", false, "Sign with the local development CA instead of ACME")
+	cmd.MarkFlagsMutuallyExclusive("self-signed", "challenge")
 	return cmd
 }
 
+// issueSelfSigned signs a certificate for domains with the local CA,
+// creating the CA on first use.
+func issueSelfSigned(rt *Runtime, domains []string) error {
+	ca, err := ssl.LoadOrCreateCA(config.LocalCADir())
+	if err != nil {
+		return errs.Wrap(err, errs.ErrSSLIssueFail, "ssl.issue")
+	}
+	rt.Log.Info("ssl.issue", "domains", domains, "ca", ca.CertFile())
+	cert, err := ca.Issue(domains, rt.Config.SSL.ResolvedCertDir())
+	if err != nil {
+		return errs.Wrap(err, errs.ErrSSLIssueFail, "ssl.issue")
+	}
+	pprint.Success("Development certificate issued for %s, valid until %s", strings.Join(cert.Domains, ", "), cert.NotAfter.Format("2006-01-02"))
+	fmt.Printf("  cert: %s\n  key:  %s\n", cert.CertFile, cert.KeyFile)
+	pprint.Info("Trust it by importing %s into your system or browser trust store", ca.CertFile())
+	return nil
+}
+
 func newSSLRenewCmd() *cobra.Command {
 	var force bool
 	return &cobra.Command{
@@ -74,26 +153,145 @@ func newSSLRenewCmd() *cobra.Command {
 				fmt.Println("◉ Renewing all certificates...")
 			}
 			fmt.Println("✓ Certificate renewal triggered")
+
+			node := &v1.NodeSpec{Name: nodeOrLocal(rt.Flags.Node)}
+			for _, svc := range rt.Config.Services {
+				if svc.Proxy == nil || !svc.Proxy.SSL || (domain != "" && svc.Proxy.Domain != domain) {
+					continue
+				}
+				svc := svc
+				rt.Plugins.Fire(cmd.Context(), v1.HookSSLRenew, v1.HookContext{
+					Service: &svc, Node: node,
+					Metadata: map[string]string{"domain": svc.Proxy.Domain, "force": strconv.FormatBool(force)},
+				})
+			}
 			return nil
 		},
 	}
 }
 
 func newSSLStatusCmd() *cobra.Command {
-	return &cobra.Command{
+	var allNodes bool
+	cmd := &cobra.Command{
 		Use:   "status [domain]",
-		Short: "Show SSL certificate status",
+		Short: "Show issuer, SANs, expiry and chain validity of certificates",
+		Long: `Read the certificates under ssl.cert_dir — locally, on the node given with
+--node, or everywhere with --all-nodes — and report their issuer, SANs,
+expiry and whether their chain verifies. Certificates inside the renewal
+window (ssl.renew_days, default 30) are flagged for renewal.`,
+		Args: cobra.MaximumNArgs(1),
+		Example: `  orbit ssl status
+  orbit ssl status api.example.com -o json
+  orbit ssl status --all-nodes -o wide`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			domain := ""
+			rt := FromContext(cmd.Context())
+			certs, err := collectCerts(cmd.Context(), rt, allNodes)
+			if err != nil {
+				return err
+			}
 			if len(args) > 0 {
-				domain = args[0]
+				certs = slices.DeleteFunc(certs, func(c ssl.CertInfo) bool {
+					return c.Domain != args[0] && !slices.Contains(c.SANs, args[0])
+				})
 			}
-			if domain != "" {
-				fmt.Printf("Checking certificate status for %q...\n", domain)
-			} else {
-				fmt.Println("Checking all certificate statuses...")
+			if len(certs) == 0 && !rt.Flags.Output.Format.Structured() && !rt.Flags.Output.Quiet {
+				pprint.Info("No certificates found")
+				return nil
 			}
-			return nil
+			return output.Render(rt.Flags.Output, certs, certView)
 		},
 	}
+	cmd.Flags().BoolVar(&allNodes, "all-nodes", false, "Also read certificates on every registered node")
+	return cmd
+}
+
+// collectCerts inspects the certificates under ssl.cert_dir on the --node
+// target (local by default), or locally and on every registered node.
+// Unreachable nodes are warned about and skipped.
+func collectCerts(ctx context.Context, rt *Runtime, allNodes bool) ([]ssl.CertInfo, error) {
+	inspector := ssl.NewInspector(rt.Config.SSL.RenewDays).WithLocalCA(config.LocalCADir())
+	registry := remote.NewRegistry(rt.State)
+
+	var nodes []v1.NodeInfo
+	var certs []ssl.CertInfo
+	switch node := nodeOrLocal(rt.Flags.Node); {
+	case node != "local" && !allNodes:
+		info, err := registry.Get(node)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, info)
+	default:
+		local, err := inspector.InspectDir(rt.Config.SSL.ResolvedCertDir())
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, withNode(local, "local")...)
+		if allNodes {
+			if nodes, err = registry.List(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return certs, nil
+	}
+
+	dir := rt.Config.SSL.RemoteCertDir()
+	pool := rt.NewPool()
+	defer pool.Close()
+	for _, n := range nodes {
+		out, code, err := pool.Run(ctx, n, ssl.ListCommand(dir))
+		if err == nil && code != 0 {
+			err = fmt.Errorf("listing %s exited %d", dir, code)
+		}
+		if err != nil {
+			pprint.Warn("%s: %v", n.Spec.Name, err)
+			continue
+		}
+		certs = append(certs, withNode(inspector.InspectFiles(ssl.ParseListing(out)), n.Spec.Name)...)
+	}
+	return certs, nil
+}
+
+func withNode(certs []ssl.CertInfo, node string) []ssl.CertInfo {
+	for i := range certs {
+		certs[i].Node = node
+	}
+	return certs
+}
+
+// certView is the table layout for `orbit ssl status`. STATUS is last so its
+// colour codes do not upset the column alignment.
+var certView = output.View[ssl.CertInfo]{
+	ID: func(c ssl.CertInfo) string { return c.Domain },
+	Columns: []output.Column[ssl.CertInfo]{
+		{Header: "DOMAIN", Value: func(c ssl.CertInfo) string { return c.Domain }},
+		{Header: "NODE", Value: func(c ssl.CertInfo) string { return c.Node }},
+		{Header: "ISSUER", Value: func(c ssl.CertInfo) string { return orDash(c.Issuer) }},
+		{Header: "SANS", Wide: true, Value: func(c ssl.CertInfo) string { return orDash(strings.Join(c.SANs, ",")) }},
+		{Header: "FILE", Wide: true, Value: func(c ssl.CertInfo) string { return c.File }},
+		{Header: "NOT AFTER", Value: func(c ssl.CertInfo) string { return c.NotAfter.Local().Format("2006-01-02") }},
+		{Header: "DAYS", Value: func(c ssl.CertInfo) string { return strconv.Itoa(c.DaysLeft) }},
+		{Header: "CHAIN", Value: func(c ssl.CertInfo) string {
+			if c.ChainValid {
+				return "ok"
+			}
+			return "invalid"
+		}},
+		{Header: "STATUS", Value: func(c ssl.CertInfo) string { return colorCertStatus(c.Status) }},
+	},
+}
+
+// colorCertStatus colours a certificate status: green when valid, amber
+// inside the renewal window, red when expired or unusable.
+func colorCertStatus(status string) string {
+	switch status {
+	case ssl.CertValid:
+		return pprint.StyleSuccess.Render(status)
+	case ssl.CertRenew:
+		return pprint.StyleWarning.Render(status)
+	default:
+		return pprint.StyleError.Render(status)
+	}
 }
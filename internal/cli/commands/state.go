@@ -0,0 +1,358 @@
+// orbit state — export, import, rekey, convert and sync the state DB.
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/internal/statesync"
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/pprint"
+	"github.com/f9-o/orbit/pkg/pprint/prompt"
+)
+
+func NewStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Export, import, rekey, convert and sync the state DB",
+		Long: `The state DB (~/.orbit/state.db) records nodes, service state,
+deployment history, active alerts, plugin toggles and the event log, encrypted
+with ORBIT_SECRET_KEY or the master key in ~/.orbit/.master.key. Export it to
+review it, to move ~/.orbit to another machine, or to keep a copy to recover
+from. Rekey it to keep the master key off the disk. Convert it to SQLite
+(~/.orbit/state.sqlite) for readers that never wait on a writer. Sync it to
+share the node registry and deployment history with teammates.`,
+	}
+	cmd.AddCommand(newStateExportCmd(), newStateImportCmd(), newStateRekeyCmd(), newStateConvertCmd(), newStateSyncCmd())
+	return cmd
+}
+
+func newStateExportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:         "export",
+		Annotations: readsState,
+		Short:       "Print the state DB as YAML, or JSON with -o json",
+		Long: `Print every record of the state DB, decrypted, as YAML (or JSON with
+-o json). Locks are left out. The dump is plain text: node names and host
+keys, images and deployment history are readable by anyone who can read
+the file.`,
+		Example: `  orbit state export > state.yaml
+  orbit state export -o json | jq '.deployments[] | select(.service == "web")'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			dump, err := rt.State.Export()
+			if err != nil {
+				return err
+			}
+			opts := rt.Flags.Output
+			if !opts.Format.Structured() {
+				opts.Format = output.FormatYAML
+			}
+			return output.Encode(opts, dump)
+		},
+	}
+}
+
+func newStateImportCmd() *cobra.Command {
+	var replace bool
+
+	cmd := &cobra.Command{
+		Use:   "import <file | ->",
+		Short: "Load a dump written by orbit state export",
+		Long: `Load a YAML or JSON dump written by orbit state export into the state DB,
+in one transaction. Records with the same key — a node's name, a service's
+node and name, a deployment's ID — are overwritten and others kept; with
+--replace the DB's records are deleted first, leaving only the dump's.
+Held locks are never touched. With --dry-run, only report what the dump
+holds.`,
+		Example: `  orbit state import state.yaml
+  ssh old-host orbit state export | orbit state import --replace -`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+
+			var data []byte
+			var err error
+			if args[0] == "-" {
+				data, err = io.ReadAll(os.Stdin)
+			} else {
+				data, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+			dump, err := state.ParseDump(data)
+			if err != nil {
+				return err
+			}
+
+			summary := fmt.Sprintf("%d node(s), %d service(s), %d deployment(s), %d alert(s), %d plugin toggle(s) and %d event(s)",
+				len(dump.Nodes), len(dump.Services), len(dump.Deployments), len(dump.Alerts), len(dump.Plugins), len(dump.Events))
+			if rt.Flags.DryRun {
+				pprint.Info("Would import %s from %s (exported %s)", summary, args[0], dump.ExportedAt.Local().Format("2006-01-02 15:04"))
+				return nil
+			}
+			rt.audit("", map[string]string{"source": args[0], "replace": fmt.Sprint(replace)})
+			if err := rt.State.Import(dump, replace); err != nil {
+				return err
+			}
+			pprint.Success("Imported %s", summary)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&replace, "replace", false, "Delete the DB's records before importing, instead of merging")
+	return cmd
+}
+
+func newStateRekeyCmd() *cobra.Command {
+	var to string
+
+	cmd := &cobra.Command{
+		Use:   "rekey --to file|passphrase|keychain",
+		Short: "Re-encrypt the state DB under a new master key",
+		Long: `Re-encrypt every record of the state DB under a new master key, kept in:
+
+  file        ~/.orbit/.master.key (the default), readable by anyone who can
+              read state.db
+  passphrase  derived from a passphrase with scrypt, asked for whenever the
+              DB is opened, or read from ORBIT_PASSPHRASE
+  keychain    the macOS Keychain, or the Secret Service (secret-tool) on Linux
+
+The old key material is deleted. Rekeying to passphrase again changes the
+passphrase. A DB encrypted with ORBIT_SECRET_KEY is not rekeyed: that key is
+managed outside orbit. orbit agent, running unattended, needs ORBIT_PASSPHRASE
+in its environment to open a passphrase-encrypted DB.`,
+		Example: `  orbit state export > state.yaml && orbit state rekey --to passphrase
+  orbit state rekey --to keychain`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			src := encryption.KeySource(to)
+			if !slices.Contains(encryption.KeySources, src) {
+				return errs.Newf(errs.ErrValidation, "state.rekey", "--to %q: want file, passphrase or keychain", to)
+			}
+			from := encryption.CurrentSource()
+			if from == encryption.SourceEnv {
+				return errs.Newf(errs.ErrValidation, "state.rekey", "the state DB is encrypted with %s", encryption.EnvSecretKey).
+					WithAdvice("Unset it to rekey the DB orbit keeps the key of, or manage the key yourself")
+			}
+			if from == src && src != encryption.SourcePassphrase {
+				pprint.Info("The master key is already kept in %s", src)
+				return nil
+			}
+			rt.audit("", map[string]string{"from": string(from), "to": string(src)})
+			if rt.Flags.DryRun {
+				pprint.Info("Would re-encrypt the state DB under a new master key kept in %s, and delete the %s key", src, from)
+				return nil
+			}
+
+			var passphrase string
+			if src == encryption.SourcePassphrase {
+				var err error
+				if passphrase, err = newPassphrase(); err != nil {
+					return err
+				}
+			}
+			key, err := encryption.NewKey(src, passphrase)
+			if err != nil {
+				return err
+			}
+			engine, err := key.Engine()
+			if err != nil {
+				return err
+			}
+			if err := rt.State.Rekey(engine, key.Save); err != nil {
+				return err
+			}
+			pprint.Success("Re-encrypted the state DB; the master key is now kept in %s", src)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "Where to keep the new master key: file | passphrase | keychain")
+	_ = cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func newStateConvertCmd() *cobra.Command {
+	var to string
+
+	cmd := &cobra.Command{
+		Use:   "convert --to sqlite|bolt",
+		Short: "Move the state DB to another storage backend",
+		Long: `Copy every record of the state DB into a new DB kept in another backend,
+and keep the old file beside it with a .bak suffix:
+
+  bolt    ~/.orbit/state.db, a BoltDB file (the default). One orbit process
+          writes it at a time, and only while no other is reading it.
+  sqlite  ~/.orbit/state.sqlite. Readers carry on while a process writes,
+          and deployment history is indexed by service, node and time.
+
+orbit uses state.sqlite whenever it exists. Locks are not copied, so convert
+when no deploy is in progress.`,
+		Example: `  orbit state convert --to sqlite
+  orbit state convert --to bolt`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			if to != state.BackendBolt && to != state.BackendSQLite {
+				return errs.Newf(errs.ErrValidation, "state.convert", "--to %q: want sqlite or bolt", to)
+			}
+			from := rt.State.Backend()
+			if from == to {
+				pprint.Info("The state DB is already kept in %s", to)
+				return nil
+			}
+			locks, err := rt.State.Project(state.AllProjects).ListLocks()
+			if err != nil {
+				return err
+			}
+			if len(locks) > 0 {
+				return errs.Newf(errs.ErrStateLocked, "state.convert", "%d lock(s) held, e.g. on %s by %s", len(locks), locks[0].Service, locks[0].Holder).
+					WithAdvice("Wait for the operations to finish, or clear interrupted ones with orbit locks unlock")
+			}
+
+			src := rt.State.Path()
+			dst := filepath.Join(filepath.Dir(src), state.Filename(to))
+			rt.audit("", map[string]string{"from": from, "to": to})
+			if rt.Flags.DryRun {
+				pprint.Info("Would copy the state DB from %s to %s, and keep %s.bak", src, dst, src)
+				return nil
+			}
+			// A DB left from converting the other way is kept, not merged into.
+			if _, err := os.Stat(dst); err == nil {
+				if err := os.Rename(dst, dst+".bak"); err != nil {
+					return err
+				}
+			}
+			if err := rt.State.CopyTo(dst, state.OpenOptions{Backend: to, Holder: cmd.CommandPath()}); err != nil {
+				return err
+			}
+			if err := rt.State.Close(); err != nil {
+				return err
+			}
+			if err := os.Rename(src, src+".bak"); err != nil {
+				return err
+			}
+			pprint.Success("Converted the state DB to %s in %s; the %s DB is kept as %s.bak", to, dst, from, src)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "Backend to keep the state DB in: sqlite | bolt")
+	_ = cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func newStateSyncCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync",
+		Short: "Share the node registry and deployment history with other machines",
+		Long: `Merge the node registry and deployment history, of every project, with the
+shared state at state.sync.url in orbit.yaml:
+
+  s3://<bucket>/<key>            an object in an S3-compatible bucket, with
+                                 credentials from AWS_ACCESS_KEY_ID and
+                                 AWS_SECRET_ACCESS_KEY; state.sync.endpoint
+                                 and state.sync.region pick the store
+  ssh://<node>/<absolute path>   a file on a registered node
+
+A record changed on one side since the last sync is copied to the other.
+One changed on both is a conflict: the later change wins, and the conflict
+is reported. A node's address and trusted host key are shared, not what
+this machine has seen of it. The shared state is plain JSON; keep it where
+only the team can read it. With --dry-run, only report what would change.`,
+		Example: `  orbit state sync
+  orbit state sync --dry-run -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			pool := rt.NewPool()
+			defer pool.Close()
+			r, err := statesync.NewRemote(rt.Config.State.Sync, remote.NewRegistry(rt.State).Get, pool)
+			if err != nil {
+				return err
+			}
+			if !rt.Flags.DryRun {
+				rt.audit("", map[string]string{"remote": r.String()})
+			}
+			report, err := statesync.Sync(cmd.Context(), rt.State, r, statesync.Options{DryRun: rt.Flags.DryRun})
+			if err != nil {
+				return err
+			}
+			if out := rt.Flags.Output; out.Format.Structured() {
+				return output.Encode(out, report)
+			}
+			printSync(report)
+			return nil
+		},
+	}
+}
+
+func printSync(r *statesync.Report) {
+	pulled, pushed := "Pulled", "Pushed"
+	if r.DryRun {
+		pulled, pushed = "Would pull", "Would push"
+	}
+	for _, c := range r.Pulled {
+		pprint.Info("%s %s", pulled, syncChange(c))
+	}
+	for _, c := range r.Pushed {
+		pprint.Info("%s %s", pushed, syncChange(c))
+	}
+	for _, c := range r.Conflicts {
+		if c.Kept == "local" {
+			pprint.Warn("Conflict on %s: kept this machine's change (%s) over %s's (%s)", c.Key,
+				c.LocalTime.Local().Format(time.Stamp), c.RemoteBy, c.RemoteTime.Local().Format(time.Stamp))
+		} else {
+			pprint.Warn("Conflict on %s: kept %s's change (%s) over this machine's (%s)", c.Key,
+				c.RemoteBy, c.RemoteTime.Local().Format(time.Stamp), c.LocalTime.Local().Format(time.Stamp))
+		}
+	}
+	summary := fmt.Sprintf("%d pulled, %d pushed, %d conflict(s)", len(r.Pulled), len(r.Pushed), len(r.Conflicts))
+	if r.DryRun {
+		pprint.Info("Would sync with %s: %s", r.Remote, summary)
+		return
+	}
+	pprint.Success("Synced with %s: %s", r.Remote, summary)
+}
+
+func syncChange(c statesync.Change) string {
+	if c.Deleted {
+		return c.Key + " (deleted)"
+	}
+	return c.Key
+}
+
+// newPassphrase asks for a new passphrase twice.
+func newPassphrase() (string, error) {
+	for {
+		p, err := prompt.Password(fmt.Sprintf("New state passphrase (at least %d characters)", encryption.MinPassphrase))
+		if err != nil {
+			return "", err
+		}
+		if len(p) < encryption.MinPassphrase {
+			pprint.Warn("Too short")
+			continue
+		}
+		again, err := prompt.Password("Repeat it")
+		if err != nil {
+			return "", err
+		}
+		if again == p {
+			return p, nil
+		}
+		pprint.Warn("The passphrases differ")
+	}
+}
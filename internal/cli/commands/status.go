@@ -0,0 +1,142 @@
+// orbit status — one-screen summary of service health, node connectivity, certificates, and active alerts.
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/ssl"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+// statusReport is the structured form of `orbit status`.
+type statusReport struct {
+	Services     map[v1.ServiceStatus]int `json:"services"`
+	Nodes        map[v1.NodeStatus]int    `json:"nodes"`
+	Certificates []ssl.CertInfo           `json:"certificates"`
+	Alerts       []v1.Alert               `json:"alerts"`
+}
+
+func NewStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:         "status",
+		Annotations: readsState,
+		Short:       "Summarize service health, node connectivity, and active alerts",
+		Long: `Counts services by health status, registered nodes by connectivity and local
+certificates by expiry (see ` + "`orbit ssl status`" + `), then lists active alerts. Alerts are raised by ` + "`orbit agent`" + ` from the alerts: rules
+in orbit.yaml and cleared once their condition no longer holds.`,
+		Example: `  orbit status
+  orbit status -o json
+  orbit status -q   # active alert keys only`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			services, err := rt.State.ListServiceStates("")
+			if err != nil {
+				return err
+			}
+			nodes, err := rt.State.ListNodes()
+			if err != nil {
+				return err
+			}
+			active, err := rt.State.ListAlerts()
+			if err != nil {
+				return err
+			}
+			sort.Slice(active, func(i, j int) bool { return active[i].FiredAt.Before(active[j].FiredAt) })
+			certs, err := ssl.NewInspector(rt.Config.SSL.RenewDays).WithLocalCA(config.LocalCADir()).InspectDir(rt.Config.SSL.ResolvedCertDir())
+			if err != nil {
+				return err
+			}
+
+			report := statusReport{
+				Services:     map[v1.ServiceStatus]int{},
+				Nodes:        map[v1.NodeStatus]int{},
+				Certificates: certs,
+				Alerts:       active,
+			}
+			for _, s := range services {
+				status := s.Status
+				if status == "" {
+					status = v1.StatusUnknown
+				}
+				report.Services[status]++
+			}
+			for _, n := range nodes {
+				report.Nodes[n.Status]++
+			}
+
+			out := rt.Flags.Output
+			switch {
+			case out.Quiet:
+				return output.Render(out, active, alertView)
+			case out.Format.Structured():
+				if report.Alerts == nil {
+					report.Alerts = []v1.Alert{}
+				}
+				if report.Certificates == nil {
+					report.Certificates = []ssl.CertInfo{}
+				}
+				return output.Encode(out, report)
+			}
+
+			pprint.Header("Status")
+			pprint.KV("Services", countSummary(len(services), report.Services))
+			pprint.KV("Nodes", countSummary(len(nodes), report.Nodes))
+			certCounts := map[string]int{}
+			for _, c := range certs {
+				certCounts[c.Status]++
+			}
+			pprint.KV("Certificates", countSummary(len(certs), certCounts))
+			fmt.Println()
+			for _, c := range certs {
+				if c.NeedsAttention() {
+					pprint.Warn("Certificate %s is %s (expires %s)", c.Domain, c.Status, c.NotAfter.Local().Format("2006-01-02"))
+				}
+			}
+			if len(active) == 0 {
+				pprint.Success("No active alerts")
+				return nil
+			}
+			pprint.Warn("%d active alert(s)", len(active))
+			return output.Render(out, active, alertView)
+		},
+	}
+}
+
+// alertView is the table layout for active alerts.
+var alertView = output.View[v1.Alert]{
+	ID: state.AlertKey,
+	Columns: []output.Column[v1.Alert]{
+		{Header: "RULE", Value: func(a v1.Alert) string { return a.Rule }},
+		{Header: "NODE", Wide: true, Value: func(a v1.Alert) string { return a.Node }},
+		{Header: "SUBJECT", Value: func(a v1.Alert) string { return a.Subject }},
+		{Header: "MESSAGE", Value: func(a v1.Alert) string { return a.Message }},
+		{Header: "FIRING", Value: func(a v1.Alert) string { return fmtDuration(time.Since(a.FiredAt)) }},
+		{Header: "SINCE", Wide: true, Value: func(a v1.Alert) string { return a.Since.Local().Format(time.DateTime) }},
+	},
+}
+
+// countSummary renders "total (n status, …)" with statuses in name order.
+func countSummary[K ~string](total int, counts map[K]int) string {
+	if total == 0 {
+		return "0"
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%d %s", counts[K(k)], k)
+	}
+	return fmt.Sprintf("%d (%s)", total, strings.Join(parts, ", "))
+}
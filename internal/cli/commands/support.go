@@ -0,0 +1,309 @@
+// orbit support-bundle — collect diagnostics for a bug report.
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/internal/support"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+// supportLogBytes is how much of the end of each local log a bundle keeps.
+const supportLogBytes = 5 << 20
+
+func NewSupportBundleCmd() *cobra.Command {
+	var file string
+	var noNodes bool
+	var opts remote.LogOptions
+
+	cmd := &cobra.Command{
+		Use:         "support-bundle",
+		Annotations: readsState,
+		Short:       "Collect config, logs, state and doctor results into an archive for a bug report",
+		Long: `Write a tar.gz with what a bug report needs:
+
+  orbit.yaml               the project's config
+  versions.json            orbit's build and the local Docker daemon's version
+  docker-info.json         the local Docker daemon's docker info
+  doctor.json              orbit doctor's results
+  state.yaml               orbit state export
+  logs/                    the end of each log in ~/.orbit/logs
+  nodes/<node>/system.txt  each registered node's kernel, docker version,
+                           docker info and orbit version
+  nodes/<node>/agent.log   the orbit agent's logs on the node
+  nodes/<node>/docker.log  its Docker daemon's logs
+
+Secrets are masked in every file: values of keys and variables named like
+password, token, secret or key (and DATABASE_URL and the like), whole
+secrets: blocks, URL passwords and Authorization headers. Values that only
+reference a variable, such as ${DB_PASSWORD}, are kept.
+
+Node files are fetched over SSH as orbit nodes exec-logs does; a node that
+cannot be reached is noted in nodes/<node>/error.txt and the rest carry on.
+Look the archive over before sharing it: it holds node addresses, images
+and deployment history.`,
+		Example: `  orbit support-bundle
+  orbit support-bundle --file /tmp/orbit-support.tar.gz --since 2h
+  orbit support-bundle --no-nodes`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt := FromContext(cmd.Context())
+			root := "orbit-support-" + time.Now().UTC().Format("20060102-150405")
+			if file == "" {
+				file = root + ".tar.gz"
+			}
+
+			var w io.Writer = os.Stdout
+			if file != "-" {
+				f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+				if err != nil {
+					return fmt.Errorf("support bundle: %w", err)
+				}
+				defer f.Close()
+				w = f
+			}
+			b := support.NewBundle(w, root)
+			quiet := rt.Flags.Output.Quiet || file == "-"
+			step := func(label string, fn func() error) error {
+				var sp *pprint.Spinner
+				if !quiet {
+					sp = pprint.NewSpinner(label)
+					sp.Start()
+				}
+				err := fn()
+				if sp != nil {
+					sp.Stop(err == nil)
+				}
+				return err
+			}
+
+			if err := step("Collecting config and versions", func() error {
+				if err := addConfig(b, rt); err != nil {
+					return err
+				}
+				return addVersions(cmd.Context(), b, rt)
+			}); err != nil {
+				return err
+			}
+			if err := step("Running doctor checks", func() error {
+				return addJSON(b, "doctor.json", runDoctor(cmd.Context(), rt))
+			}); err != nil {
+				return err
+			}
+			if err := step("Exporting state", func() error {
+				dump, err := rt.State.Export()
+				if err != nil {
+					return err
+				}
+				var buf bytes.Buffer
+				if err := output.Encode(output.Options{Format: output.FormatYAML, Out: &buf}, dump); err != nil {
+					return err
+				}
+				redacted, err := support.RedactYAML(buf.Bytes())
+				if err != nil {
+					return err
+				}
+				return b.Add("state.yaml", redacted)
+			}); err != nil {
+				return err
+			}
+			if err := step("Collecting local logs", func() error {
+				return addLocalLogs(b)
+			}); err != nil {
+				return err
+			}
+
+			if !noNodes {
+				nodes, err := remote.NewRegistry(rt.State).List()
+				if err != nil {
+					return err
+				}
+				if len(nodes) > 0 {
+					pool := rt.NewPool()
+					defer pool.Close()
+					var failed []string
+					_ = step(fmt.Sprintf("Fetching system info and logs from %d node(s)", len(nodes)), func() error {
+						failed = addNodes(cmd.Context(), b, pool, nodes, opts)
+						if len(failed) > 0 {
+							return fmt.Errorf("%d failed", len(failed))
+						}
+						return nil
+					})
+					for _, msg := range failed {
+						pprint.Warn("%s (see error.txt in the bundle)", msg)
+					}
+				}
+			}
+
+			if err := b.Close(); err != nil {
+				return fmt.Errorf("support bundle: %w", err)
+			}
+			if !quiet {
+				pprint.Success("Wrote %s (%d files)", file, len(b.Files()))
+				pprint.Info("Look it over before attaching it to an issue: it holds node addresses and deployment history.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Where to write the archive; - for stdout (default: orbit-support-<time>.tar.gz)")
+	cmd.Flags().BoolVar(&noNodes, "no-nodes", false, "Leave out registered nodes' system info and logs")
+	cmd.Flags().DurationVar(&opts.Since, "since", 24*time.Hour, "Node log entries this recent (0 for all)")
+	cmd.Flags().IntVar(&opts.Lines, "lines", 2000, "Last lines of each node log (0 for all)")
+	return cmd
+}
+
+// addJSON adds v to b as indented JSON.
+func addJSON(b *support.Bundle, name string, v any) error {
+	var buf bytes.Buffer
+	if err := output.Encode(output.Options{Format: output.FormatJSON, Out: &buf}, v); err != nil {
+		return err
+	}
+	return b.Add(name, buf.Bytes())
+}
+
+// addConfig adds the project's orbit.yaml, if there is one, with secrets
+// masked.
+func addConfig(b *support.Bundle, rt *Runtime) error {
+	path := rt.Flags.ConfigFile
+	if path == "" {
+		found, err := config.DiscoverProjectConfig()
+		if err != nil {
+			return nil
+		}
+		path = found
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	// Add still masks what it can in a file that is not valid YAML.
+	if redacted, err := support.RedactYAML(data); err == nil {
+		data = redacted
+	}
+	return b.Add("orbit.yaml", data)
+}
+
+// addVersions adds orbit's build and the local Docker daemon's version and
+// docker info. A daemon that cannot be reached is noted in versions.json.
+func addVersions(ctx context.Context, b *support.Bundle, rt *Runtime) error {
+	versions := map[string]any{"orbit": versionInfo()}
+	var info any
+	docker, err := rt.NewContainerClient()
+	if err == nil {
+		defer docker.Close()
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		var v any
+		if v, err = docker.ServerVersion(ctx); err == nil {
+			versions["docker"] = v
+			info, err = docker.Info(ctx)
+		}
+	}
+	if err != nil {
+		versions["docker"] = map[string]string{"error": err.Error()}
+	}
+	if err := addJSON(b, "versions.json", versions); err != nil {
+		return err
+	}
+	if info == nil {
+		return nil
+	}
+	return addJSON(b, "docker-info.json", info)
+}
+
+// addLocalLogs adds the end of each log file in ~/.orbit/logs; rotated and
+// compressed ones are left out.
+func addLocalLogs(b *support.Bundle) error {
+	paths, err := filepath.Glob(filepath.Join(config.OrbitHome(), "logs", "*.log"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if err := b.AddTail("logs/"+filepath.Base(p), p, supportLogBytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodeFile is a file of each node's directory in a bundle, and how it is
+// fetched.
+type nodeFile struct {
+	name  string
+	fetch func(ctx context.Context, node v1.NodeInfo) ([]byte, error)
+}
+
+// addNodes fetches the system info and every log source of nodes, a few
+// nodes at a time, and adds them to b in node order. It returns what could
+// not be fetched, a line for each node.
+func addNodes(ctx context.Context, b *support.Bundle, pool *remote.Pool, nodes []v1.NodeInfo, opts remote.LogOptions) []string {
+	files := []nodeFile{{name: "system.txt", fetch: pool.System}}
+	for _, source := range remote.LogSources {
+		files = append(files, nodeFile{name: source + ".log", fetch: func(ctx context.Context, node v1.NodeInfo) ([]byte, error) {
+			return pool.Logs(ctx, node, source, opts)
+		}})
+	}
+
+	type fetched struct {
+		data [][]byte
+		errs []string
+	}
+	results := make([]fetched, len(nodes))
+	sem := make(chan struct{}, remote.DefaultParallelism)
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i].data = make([][]byte, len(files))
+			for j, f := range files {
+				data, err := f.fetch(ctx, node)
+				if err != nil {
+					results[i].errs = append(results[i].errs, fmt.Sprintf("%s: %v", f.name, err))
+				}
+				results[i].data[j] = data
+			}
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, node := range nodes {
+		dir := "nodes/" + node.Spec.Name + "/"
+		for j, f := range files {
+			if data := results[i].data[j]; len(data) > 0 {
+				if err := b.Add(dir+f.name, data); err != nil {
+					failed = append(failed, err.Error())
+				}
+			}
+		}
+		if errs := results[i].errs; len(errs) > 0 {
+			_ = b.Add(dir+"error.txt", []byte(strings.Join(errs, "\n")+"\n"))
+			msg := node.Spec.Name + ": " + errs[0]
+			if len(errs) > 1 {
+				msg += fmt.Sprintf(" (and %d more)", len(errs)-1)
+			}
+			failed = append(failed, msg)
+		}
+	}
+	return failed
+}
@@ -2,26 +2,62 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 
+	"github.com/f9-o/orbit/internal/health"
 	"github.com/f9-o/orbit/internal/orchestrator"
 	"github.com/f9-o/orbit/internal/tui"
 )
 
 func NewUICmd() *cobra.Command {
+	var theme string
+	var selector []string
+
 	cmd := &cobra.Command{
 		Use:   "ui",
 		Short: "Launch the interactive TUI dashboard",
 		Example: `  orbit ui
-  orbit ui --node prod-01`,
+  orbit ui --node prod-01
+  orbit ui --theme orbit-light
+  orbit ui -l tier=backend   # list only the backend services`,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			rt := FromContext(cmd.Context())
+			sel, err := parseSelector(selector)
+			if err != nil {
+				return err
+			}
+
+			if theme == "" {
+				theme = rt.Config.TUI.Theme
+			}
+			palette, err := tui.LoadTheme(theme)
+			if err != nil {
+				return fmt.Errorf("theme: %w", err)
+			}
+			keymap, err := tui.NewKeymap(rt.Config.TUI.Keys)
+			if err != nil {
+				return fmt.Errorf("tui.keys: %w", err)
+			}
+			// Deploys started from the dashboard are checked as orbit deploy
+			// checks them, but cannot be confirmed by typing.
+			g := deployGate(rt)
+			scanCheck, err := scanPolicy(rt)
+			if err != nil {
+				return err
+			}
+			var policies []orchestrator.Policy
+			for _, policy := range []orchestrator.Policy{unconfirmable(g), scanCheck, g.Policy()} {
+				if policy != nil {
+					policies = append(policies, policy)
+				}
+			}
 
-			docker, err := orchestrator.NewClient("", rt.Log)
+			docker, err := rt.NewContainerClient()
 			if err != nil {
 				return fmt.Errorf("docker: %w", err)
 			}
@@ -31,6 +67,22 @@ func NewUICmd() *cobra.Command {
 				nodeName = "local"
 			}
 
+			// Keep service health current while the dashboard is open
+			checker := health.NewChecker(rt.Log).WithExecer(docker).WithInspector(docker)
+			monitor := health.NewMonitor(checker, rt.State, nodeName, rt.Config.Services, rt.Log).
+				WithWatchdog(docker, docker, crashLoopPolicy(rt)).
+				WithExits(docker)
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			go monitor.Run(ctx)
+
+			// Heartbeat registered remote nodes so the sidebar and event
+			// timeline show connectivity changes
+			pool := rt.NewPool()
+			defer pool.Close()
+			heartbeat := startHeartbeat(rt, pool)
+			defer heartbeat.StopAll()
+
 			// Build initial app model
 			app := tui.New(tui.Config{
 				Node:         nodeName,
@@ -38,6 +90,14 @@ func NewUICmd() *cobra.Command {
 				State:        rt.State,
 				Log:          rt.Log,
 				OrbitConfig:  rt.Config,
+				Health:       monitor,
+				Heartbeat:    heartbeat,
+				Palette:      &palette,
+				Keymap:       &keymap,
+				Hooks:        rt.Plugins,
+				Selector:     sel,
+				Policies:     policies,
+				CommandHooks: commandHooks(rt, docker, nil),
 			})
 
 			p := tea.NewProgram(app,
@@ -51,5 +111,7 @@ func NewUICmd() *cobra.Command {
 			return nil
 		},
 	}
+	cmd.Flags().StringVar(&theme, "theme", "", "Color theme: orbit-dark | orbit-light | high-contrast | a name from ~/.orbit/themes")
+	addSelectorFlag(cmd, &selector)
 	return cmd
 }
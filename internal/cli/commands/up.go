@@ -3,32 +3,93 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/health"
 	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/errs"
 	"github.com/f9-o/orbit/pkg/pprint"
 )
 
 func NewUpCmd() *cobra.Command {
-	var forceRecreate bool
+	var forceRecreate, skipScan bool
+	var profiles []string
+	var confirm string
 
 	cmd := &cobra.Command{
 		Use:   "up",
 		Short: "Start all services defined in orbit.yaml",
+		Long: `Start all services defined in orbit.yaml.
+
+Services already running are compared with orbit.yaml. Changes Docker can
+make to a running container — restart policy, extra networks, a lost name —
+are applied in place; other changes recreate the container from the image
+already on the node. Unchanged services are left alone. --force recreates
+every service. Replica counts are applied by orbit deploy and orbit scale.
+Each container started is waited on until its health_check (or the image's
+HEALTHCHECK) passes, so the proxy routes to it; one that does not fails up
+but is left running.
+
+Up is held to the same checks as orbit deploy: in a protected environment of
+deploy_policy it fails outside the maintenance windows and asks for the
+environment's name to be typed (or --confirm), and each service it starts or
+changes must pass the approval webhook and, with scan.deploy, the
+vulnerability scan (--skip-scan skips it).
+
+Services with profiles: in orbit.yaml — debug tools, admin panels — start
+only when --profile (or ORBIT_PROFILES) names one of them, along with the
+services they depend on. --profile '*' starts every service.`,
 		Example: `  orbit up
   orbit up --force
-  orbit up --node prod-01`,
+  orbit up --profile debug
+  orbit up --node prod-01
+  orbit up --node prod-01 --confirm production`,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			rt := FromContext(cmd.Context())
+			services, err := profileServices(rt, profiles, cmd.Flags().Changed("profile"))
+			if err != nil {
+				return err
+			}
+
+			if rt.Flags.DryRun {
+				planner, done := buildPlanner(cmd, rt)
+				defer done()
+				plan, err := planner.Plan(cmd.Context(), services, nodeOrLocal(rt.Flags.Node), false)
+				if err != nil {
+					return fmt.Errorf("plan: %w", err)
+				}
+				printPlan(plan)
+				return nil
+			}
+
+			g := deployGate(rt)
+			if g.Protected() {
+				if err := confirmDeploy(rt, g, confirm); err != nil {
+					return err
+				}
+			}
+			policies := []orchestrator.Policy{g.Policy()}
+			if !skipScan {
+				policy, err := scanPolicy(rt)
+				if err != nil {
+					return err
+				}
+				policies = append(policies, policy)
+			}
 
 			pprint.Header("Starting Services")
 
 			spinner := pprint.NewSpinner("Connecting to Docker")
 			spinner.Start()
 
-			docker, err := orchestrator.NewClient("", rt.Log)
+			docker, err := rt.NewContainerClient()
 			if err != nil {
 				spinner.Stop(false)
 				return fmt.Errorf("docker: %w", err)
@@ -43,20 +104,30 @@ func NewUpCmd() *cobra.Command {
 			}
 			spinner.Stop(true)
 
-			lm := orchestrator.NewLifecycleManager(docker, rt.State, rt.Log)
+			if _, err := pullImages(cmd.Context(), docker, services, true); err != nil {
+				return err
+			}
+
+			lm := orchestrator.NewLifecycleManager(docker, rt.State, rt.Log).WithHooks(rt.Plugins).
+				WithCommandHooks(commandHooks(rt, docker, os.Stdout)).
+				WithChecker(health.NewChecker(rt.Log).WithExecer(docker).WithInspector(docker))
+			for _, policy := range policies {
+				if policy != nil {
+					lm.WithPolicy(policy)
+				}
+			}
 
-			total := len(rt.Config.Services)
-			for i, svc := range rt.Config.Services {
+			total := len(services)
+			for i, svc := range services {
 				pprint.Step(i+1, total, "Starting %s", svc.Name)
 			}
 
 			sp := pprint.NewSpinner("Bringing up all services")
 			sp.Start()
-			err = lm.Up(cmd.Context(), rt.Config.Services, rt.Flags.Node, forceRecreate)
+			err = lm.Up(cmd.Context(), services, nodeOrLocal(rt.Flags.Node), forceRecreate)
 			if err != nil {
 				sp.Stop(false)
-				pprint.Error("Failed: %v", err)
-				return err
+				return err // every service that failed, reported by Execute
 			}
 			sp.Stop(true)
 
@@ -67,6 +138,38 @@ func NewUpCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().BoolVar(&forceRecreate, "force", false, "Force-recreate containers even if already running")
+	cmd.Flags().BoolVar(&forceRecreate, "force", false, "Recreate containers even when running and unchanged")
+	cmd.Flags().StringVar(&confirm, "confirm", "", "Name of the protected environment being started in, confirming it")
+	cmd.Flags().BoolVar(&skipScan, "skip-scan", false, "Start services without the vulnerability scan scan.deploy asks for")
+	addProfileFlag(cmd, &profiles)
 	return cmd
 }
+
+// addProfileFlag registers --profile on cmd, filling profiles.
+func addProfileFlag(cmd *cobra.Command, profiles *[]string) {
+	cmd.Flags().StringSliceVar(profiles, "profile", nil, "Also start the services in this profile (repeatable; '*' for all; default: $"+config.EnvProfiles+")")
+}
+
+// profileServices returns the services orbit up starts with the --profile
+// profiles active, or those of ORBIT_PROFILES when the flag is not given,
+// noting the services left out.
+func profileServices(rt *Runtime, profiles []string, given bool) ([]v1.ServiceSpec, error) {
+	if !given {
+		profiles = config.ProfilesFromEnv()
+	}
+	if err := rt.Config.CheckProfiles(profiles); err != nil {
+		return nil, errs.New(errs.ErrValidation, "cli.profiles", err).
+			WithAdvice("Name a profile listed under a service's profiles: in orbit.yaml")
+	}
+	services := rt.Config.ActiveServices(profiles)
+	var skipped []string
+	for _, svc := range rt.Config.Services {
+		if !slices.ContainsFunc(services, func(s v1.ServiceSpec) bool { return s.Name == svc.Name }) {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", svc.Name, strings.Join(svc.Profiles, ", ")))
+		}
+	}
+	if out := rt.Flags.Output; len(skipped) > 0 && !out.Quiet && !out.Format.Structured() {
+		pprint.Info("Not starting services of inactive profiles: %s", strings.Join(skipped, ", "))
+	}
+	return services, nil
+}
@@ -2,13 +2,18 @@
 package commands
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/f9-o/orbit/internal/cli/output"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/update"
 	"github.com/f9-o/orbit/pkg/pprint"
 )
 
@@ -20,22 +25,49 @@ var (
 )
 
 func NewVersionCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:          "version",
-		Short:        "Print Orbit version information",
+	var check bool
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print Orbit version information",
+		Long: `Print the version, commit and build details of this binary.
+
+With --check, also ask GitHub for the latest release and say how to upgrade
+when it is newer. Set updates.check: true in ~/.orbit/config.yaml to be told
+about new releases after any command, at most once per updates.interval.`,
+		Example: `  orbit version
+  orbit version --check
+  orbit version -o json`,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			info := map[string]string{
-				"version":    Version,
-				"commit":     Commit,
-				"build_date": BuildDate,
-				"go_version": runtime.Version(),
-				"os_arch":    runtime.GOOS + "/" + runtime.GOARCH,
+			info := versionInfo()
+
+			var latest *update.Release
+			if check {
+				ctx, cancel := context.WithTimeout(cmd.Context(), 15*time.Second)
+				defer cancel()
+				rel, err := update.New().Latest(ctx)
+				if err != nil {
+					return fmt.Errorf("check for updates: %w", err)
+				}
+				latest = rel
+				info["latest"] = rel.Version
+				info["update_available"] = strconv.FormatBool(update.Newer(rel.Version, Version))
+				_ = update.SaveCheck(filepath.Join(config.OrbitHome(), update.CheckFile),
+					update.CheckState{CheckedAt: time.Now().UTC(), Latest: rel.Version, URL: rel.URL})
 			}
 
-			jsonFlag, _ := cmd.Root().PersistentFlags().GetBool("json")
-			if jsonFlag {
-				return json.NewEncoder(os.Stdout).Encode(info)
+			// version skips runtime setup, so read the output flags directly
+			out, err := OutputOptions(cmd)
+			if err != nil {
+				return err
+			}
+			switch {
+			case out.Quiet:
+				fmt.Println(Version)
+				return nil
+			case out.Format.Structured():
+				return output.Encode(out, info)
 			}
 
 			pprint.PrintBanner(Version, BuildDate)
@@ -46,7 +78,38 @@ func NewVersionCmd() *cobra.Command {
 			pprint.KV("Go       ", runtime.Version())
 			pprint.KV("Platform ", fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH))
 			fmt.Println()
+			if latest != nil {
+				printUpdateStatus(latest.Version, latest.URL)
+			}
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&check, "check", false, "Check GitHub for a newer release")
+	return cmd
+}
+
+// printUpdateStatus tells the user whether release latest, published at
+// url, is newer than this binary and how to upgrade.
+func printUpdateStatus(latest, url string) {
+	switch {
+	case !update.IsRelease(Version):
+		pprint.Info("This is a development build; the latest release is %s (%s)", latest, url)
+	case update.Newer(latest, Version):
+		pprint.Warn("Orbit %s is available (installed: %s)", latest, Version)
+		pprint.Info("Upgrade with `orbit self-update`, or download it from %s", url)
+	default:
+		pprint.Success("Orbit %s is the latest release", Version)
+	}
+}
+
+// versionInfo describes this binary's build.
+func versionInfo() map[string]string {
+	return map[string]string{
+		"version":    Version,
+		"commit":     Commit,
+		"build_date": BuildDate,
+		"go_version": runtime.Version(),
+		"os_arch":    runtime.GOOS + "/" + runtime.GOARCH,
+	}
 }
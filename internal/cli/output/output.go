@@ -0,0 +1,153 @@
+// Package output renders command results as table, wide, json, or yaml.
+//
+// List commands describe their rows once as a View (columns plus an ID) and
+// hand the items to Render; the selected format then decides whether they are
+// printed as an aligned table, encoded, or reduced to IDs for scripting.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format selected with -o/--output.
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatWide  Format = "wide"
+	FormatJSON  Format = "json"
+	FormatYAML  Format = "yaml"
+)
+
+// Formats lists the accepted --output values in help order.
+var Formats = []Format{FormatTable, FormatWide, FormatJSON, FormatYAML}
+
+// ParseFormat validates an --output value. "" selects FormatTable.
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return FormatTable, nil
+	}
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown output format %q (valid: %s)", s, strings.Join(names, ", "))
+}
+
+// Structured reports whether f is a machine-readable encoding.
+func (f Format) Structured() bool {
+	return f == FormatJSON || f == FormatYAML
+}
+
+// Options carries the global output flags.
+type Options struct {
+	Format Format
+	Quiet  bool // print only IDs, one per line
+	Out    io.Writer
+}
+
+func (o Options) writer() io.Writer {
+	if o.Out == nil {
+		return os.Stdout
+	}
+	return o.Out
+}
+
+// Column is one table column. Wide columns only appear with -o wide.
+type Column[T any] struct {
+	Header string
+	Wide   bool
+	Value  func(T) string
+}
+
+// View describes how a list of T is shown in table form and in quiet mode.
+type View[T any] struct {
+	Columns []Column[T]
+	ID      func(T) string
+}
+
+// Render writes items in the selected format. Structured formats encode items
+// as-is, so field order follows the struct definition.
+func Render[T any](o Options, items []T, view View[T]) error {
+	w := o.writer()
+	if o.Quiet {
+		for _, it := range items {
+			fmt.Fprintln(w, view.ID(it))
+		}
+		return nil
+	}
+	if o.Format.Structured() {
+		if items == nil {
+			items = []T{}
+		}
+		return Encode(o, items)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	var headers []string
+	for _, c := range view.Columns {
+		if c.Wide && o.Format != FormatWide {
+			continue
+		}
+		headers = append(headers, c.Header)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, it := range items {
+		var cells []string
+		for _, c := range view.Columns {
+			if c.Wide && o.Format != FormatWide {
+				continue
+			}
+			cells = append(cells, c.Value(it))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Encode writes v as JSON or YAML. YAML keys use the JSON field names and
+// order so both encodings describe the same document.
+func Encode(o Options, v any) error {
+	w := o.writer()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if o.Format != FormatYAML {
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle clears the flow and quoting styles yaml.v3 records when parsing
+// JSON; the encoder still quotes strings that would otherwise change type.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
@@ -0,0 +1,80 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type row struct {
+	Name   string `json:"name"`
+	Image  string `json:"image"`
+	Labels int    `json:"labels"`
+}
+
+var rowView = View[row]{
+	ID: func(r row) string { return r.Name },
+	Columns: []Column[row]{
+		{Header: "NAME", Value: func(r row) string { return r.Name }},
+		{Header: "IMAGE", Wide: true, Value: func(r row) string { return r.Image }},
+	},
+}
+
+var rows = []row{{Name: "web", Image: "nginx:1.25", Labels: 2}, {Name: "api", Image: "api:v3"}}
+
+func render(t *testing.T, o Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	o.Out = &buf
+	if err := Render(o, rows, rowView); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestRenderFormats(t *testing.T) {
+	table := render(t, Options{Format: FormatTable})
+	if !strings.HasPrefix(table, "NAME\n") || strings.Contains(table, "nginx") {
+		t.Errorf("table output should hide wide columns:\n%s", table)
+	}
+
+	wide := render(t, Options{Format: FormatWide})
+	if !strings.Contains(wide, "IMAGE") || !strings.Contains(wide, "nginx:1.25") {
+		t.Errorf("wide output missing wide column:\n%s", wide)
+	}
+
+	if got := render(t, Options{Format: FormatJSON, Quiet: true}); got != "web\napi\n" {
+		t.Errorf("quiet output = %q", got)
+	}
+
+	js := render(t, Options{Format: FormatJSON})
+	if !strings.Contains(js, `"name": "web"`) || strings.Index(js, `"name"`) > strings.Index(js, `"image"`) {
+		t.Errorf("json output not in field order:\n%s", js)
+	}
+
+	want := "- name: web\n  image: nginx:1.25\n  labels: 2\n- name: api\n  image: api:v3\n  labels: 0\n"
+	if got := render(t, Options{Format: FormatYAML}); got != want {
+		t.Errorf("yaml output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEncodeEmptyListIsArray(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(Options{Format: FormatJSON, Out: &buf}, []row(nil), rowView); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("got %q, want []", buf.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": FormatTable, "JSON": FormatJSON, "wide": FormatWide, "yaml": FormatYAML} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil || !strings.Contains(err.Error(), "table, wide, json, yaml") {
+		t.Errorf("ParseFormat(xml) err = %v", err)
+	}
+}
@@ -2,17 +2,27 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/f9-o/orbit/internal/cli/commands"
 	"github.com/f9-o/orbit/internal/core/config"
 	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/shutdown"
 	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/telemetry"
+	"github.com/f9-o/orbit/internal/update"
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
 	"github.com/f9-o/orbit/pkg/pprint"
+	"github.com/f9-o/orbit/pkg/pprint/prompt"
 )
 
 // globalFlags holds values bound to persistent global flags.
@@ -21,7 +31,17 @@ var globalFlags struct {
 	node       string
 	debug      bool
 	jsonOutput bool
+	output     string
+	quiet      bool
+	noColor    bool
 	dryRun     bool
+	yes        bool
+	strict     bool
+	strictKeys bool
+	dockerHost string
+	project    string
+	lockWait   time.Duration
+	vars       map[string]string
 }
 
 // rootCmd is the base command for orbit.
@@ -36,6 +56,11 @@ var rootCmd = &cobra.Command{
 		return cmd.Help()
 	},
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if globalFlags.noColor {
+			pprint.SetColor(false)
+		}
+		prompt.SetAssumeYes(globalFlags.yes)
+		encryption.SetPassphrasePrompt(prompt.Password)
 		if cmd.Name() == "version" || cmd.Name() == "completion" {
 			return nil
 		}
@@ -48,13 +73,132 @@ func Execute() {
 	// Show banner before every help screen
 	origHelp := rootCmd.HelpFunc()
 	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if globalFlags.noColor {
+			pprint.SetColor(false)
+		}
 		pprint.PrintBanner(commands.Version, commands.BuildDate)
 		origHelp(cmd, args)
 	})
 
-	if err := rootCmd.Execute(); err != nil {
-		pprint.Error("%s", err)
-		os.Exit(1)
+	// One run ID per invocation ties its log records, errors, audit entry and
+	// deployment records together.
+	runID := logger.NewRunID()
+	logger.SetRunID(runID)
+	errs.SetRunID(runID)
+
+	// SIGINT/SIGTERM cancel the command's context; whatever it registered
+	// for cleanup (temporary containers, locks, the state DB) is undone here.
+	shut := shutdown.New()
+	ctx := shut.Start(context.Background(),
+		func() { pprint.Warn("Interrupted — stopping (Ctrl+C again to force)") },
+		func(reports []shutdown.Report) {
+			printCleanup(reports)
+			os.Exit(exitInterrupted)
+		})
+
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	flushSpans()
+	printUpdateNotice()
+	code := exitCode(err, shut.Interrupted())
+	recordAudit(cmd, code, err)
+	if shut.Interrupted() {
+		printCleanup(shut.Cleanup())
+		if err != nil {
+			os.Exit(code)
+		}
+	}
+	if err != nil {
+		var exit *commands.ExitError
+		var multi *errs.MultiError
+		if !errors.As(err, &exit) { // otherwise the command's own output already explains it
+			if errors.As(err, &multi) {
+				pprint.Error("%s", multi.Summary())
+			} else {
+				pprint.Error("%s", err)
+			}
+			pprint.Info("Support ID: %s (run_id in ~/.orbit/logs/orbit.log and `orbit audit ls --run`)", runID)
+		}
+		os.Exit(code)
+	}
+}
+
+// exitCode is the status orbit exits with after a command returned err.
+func exitCode(err error, interrupted bool) int {
+	var exit *commands.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case interrupted:
+		return exitInterrupted
+	case errors.As(err, &exit):
+		return exit.Code
+	default:
+		return 1
+	}
+}
+
+// recordAudit writes the audit entry of the command that ran, if it has one.
+func recordAudit(cmd *cobra.Command, code int, err error) {
+	if cmd == nil {
+		return
+	}
+	rt, ok := commands.LookupRuntime(cmd.Context())
+	if !ok || rt.Audit == nil {
+		return
+	}
+	entry := *rt.Audit
+	entry.ExitCode = code
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	rt.Log.Audit(entry)
+}
+
+// flushTelemetry sends spans still buffered for the OTLP collector; nil when
+// metrics.otlp_endpoint is unset.
+var flushTelemetry func(context.Context) error
+
+// flushSpans runs flushTelemetry with a bounded wait, so an unreachable
+// collector does not hold up exit.
+func flushSpans() {
+	if flushTelemetry == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = flushTelemetry(ctx)
+}
+
+// updateCheck delivers the latest release when updates.check is on.
+var updateCheck <-chan update.CheckState
+
+// printUpdateNotice says when a newer release than this binary is out.
+func printUpdateNotice() {
+	if updateCheck == nil {
+		return
+	}
+	s := <-updateCheck // bounded by the check's own timeout
+	if update.Newer(s.Latest, commands.Version) {
+		fmt.Println()
+		pprint.Info("Orbit %s is available (installed: %s) — run `orbit self-update`", s.Latest, commands.Version)
+	}
+}
+
+// exitInterrupted is the conventional exit status after SIGINT (128+2).
+const exitInterrupted = 130
+
+// printCleanup lists the cleanup steps run after an interrupt.
+func printCleanup(reports []shutdown.Report) {
+	if len(reports) == 0 {
+		return
+	}
+	pprint.Info("Cleaned up after interrupt:")
+	for _, r := range reports {
+		if r.Err != nil {
+			pprint.Warn("%s: %v", r.Name, r.Err)
+			continue
+		}
+		pprint.Success("%s", r.Name)
 	}
 }
 
@@ -62,30 +206,69 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&globalFlags.configFile, "config", "c", "", "Path to orbit.yaml (defaults to auto-discovery)")
 	rootCmd.PersistentFlags().StringVarP(&globalFlags.node, "node", "n", "", "Target node name (overrides config)")
 	rootCmd.PersistentFlags().BoolVar(&globalFlags.debug, "debug", false, "Enable debug-level logging")
+	rootCmd.PersistentFlags().StringVarP(&globalFlags.output, "output", "o", "table", "Output format: table | wide | json | yaml")
+	rootCmd.PersistentFlags().BoolVarP(&globalFlags.quiet, "quiet", "q", false, "Print only names/IDs, one per line")
+	rootCmd.PersistentFlags().BoolVar(&globalFlags.noColor, "no-color", false, "Disable colored output (also NO_COLOR, or when stdout is not a terminal)")
 	rootCmd.PersistentFlags().BoolVar(&globalFlags.jsonOutput, "json", false, "Output in machine-readable JSON")
+	_ = rootCmd.PersistentFlags().MarkDeprecated("json", "use --output json")
 	rootCmd.PersistentFlags().BoolVar(&globalFlags.dryRun, "dry-run", false, "Print planned actions without executing")
+	rootCmd.PersistentFlags().BoolVarP(&globalFlags.yes, "yes", "y", false, "Answer yes to confirmations and take defaults instead of prompting")
+	rootCmd.PersistentFlags().BoolVar(&globalFlags.strict, "strict", false, "Reject unknown keys in orbit.yaml")
+	rootCmd.PersistentFlags().BoolVar(&globalFlags.strictKeys, "strict-host-keys", false, "Refuse SSH hosts whose key is not in ~/.orbit/known_hosts")
+	rootCmd.PersistentFlags().StringVar(&globalFlags.dockerHost, "docker-host", "", "Docker daemon URL or Docker context name (overrides docker_host)")
+	rootCmd.PersistentFlags().StringVar(&globalFlags.project, "project", "", "Project whose state to act on (overrides project.name; \"*\" lists every project)")
+	rootCmd.PersistentFlags().DurationVar(&globalFlags.lockWait, "lock-wait", state.DefaultLockWait, "How long to wait for another orbit process to release the state DB (negative waits indefinitely)")
+	rootCmd.PersistentFlags().StringToStringVar(&globalFlags.vars, "var", nil, "Override an orbit.yaml template variable (key=value, repeatable)")
 
 	// Register all subcommands
 	rootCmd.AddCommand(
 		commands.NewInitCmd(),
 		commands.NewUpCmd(),
 		commands.NewDownCmd(),
+		commands.NewPsCmd(),
+		commands.NewStatusCmd(),
 		commands.NewDeployCmd(),
+		commands.NewPullCmd(),
+		commands.NewOutdatedCmd(),
+		commands.NewScanCmd(),
 		commands.NewLogsCmd(),
+		commands.NewCpCmd(),
+		commands.NewRunCmd(),
 		commands.NewNodesCmd(),
+		commands.NewLocksCmd(),
+		commands.NewStateCmd(),
+		commands.NewAuditCmd(),
+		commands.NewPluginCmd(),
 		commands.NewScaleCmd(),
+		commands.NewPruneCmd(),
 		commands.NewSSLCmd(),
+		commands.NewProxyCmd(),
 		commands.NewMonitorCmd(),
 		commands.NewUICmd(),
+		commands.NewAgentCmd(),
+		commands.NewConfigCmd(),
+		commands.NewPlanCmd(),
+		commands.NewDiffCmd(),
+		commands.NewDoctorCmd(),
+		commands.NewSupportBundleCmd(),
 		commands.NewVersionCmd(),
+		commands.NewSelfUpdateCmd(),
 	)
 }
 
 // initRuntime loads config, logger, and state before each command runs.
 func initRuntime(cmd *cobra.Command) error {
+	out, err := commands.OutputOptions(cmd)
+	if err != nil {
+		return err
+	}
+
 	// Load config
-	cfg, err := config.Load(globalFlags.configFile)
-	if err != nil && globalFlags.configFile != "" {
+	cfg, err := config.LoadWithOptions(globalFlags.configFile, config.LoadOptions{
+		Strict: globalFlags.strict,
+		Vars:   globalFlags.vars,
+	})
+	if err != nil && (globalFlags.configFile != "" || globalFlags.strict) {
 		return fmt.Errorf("config: %w", err)
 	}
 	if cfg == nil {
@@ -106,33 +289,102 @@ func initRuntime(cmd *cobra.Command) error {
 		logFormat = cfg.Log.Format
 	}
 
+	logger.SetRotation(logger.Rotation{
+		MaxSizeMB:  cfg.Log.MaxSizeMB,
+		MaxBackups: cfg.Log.MaxBackups,
+		MaxAge:     cfg.Log.MaxAge,
+		Compress:   cfg.Log.Compress,
+	})
 	log, err := logger.Init(logLevel, logFormat, logFile, orbitHome, globalFlags.debug)
 	if err != nil {
 		return fmt.Errorf("logger init: %w", err)
 	}
 
+	// Export spans when an OpenTelemetry collector is configured
+	if ep := cfg.Metrics.OTLPEndpoint; ep != "" {
+		flush, err := telemetry.Setup(cmd.Context(), ep, commands.Version)
+		if err != nil {
+			log.Warn("telemetry: setup failed", "err", err)
+		} else {
+			flushTelemetry = flush
+		}
+	}
+
 	// Open state DB
-	dbPath := filepath.Join(orbitHome, "state.db")
+	dbPath, backend := state.Locate(orbitHome)
 	if err := os.MkdirAll(orbitHome, 0750); err != nil {
 		return fmt.Errorf("create orbit home: %w", err)
 	}
-	db, err := state.Open(dbPath)
+	db, err := state.OpenWithOptions(dbPath, state.OpenOptions{
+		Backend:  backend,
+		ReadOnly: readsState(cmd),
+		Wait:     globalFlags.lockWait,
+		Holder:   cmd.CommandPath(),
+	})
 	if err != nil {
 		return fmt.Errorf("state db: %w", err)
 	}
+	shutdown.Register(cmd.Context(), "close state database", func(context.Context) error {
+		return db.Close()
+	})
+	// Service state, history and locks are kept per project
+	project := cfg.Project.Name
+	if globalFlags.project != "" {
+		project = globalFlags.project
+	}
 
-	// Store in command context
-	cmd.SetContext(commands.NewContext(cmd.Context(), &commands.Runtime{
+	rt := &commands.Runtime{
 		Config: cfg,
 		Log:    log,
-		State:  db,
+		State:  db.Project(project),
 		Flags: commands.GlobalFlags{
+			ConfigFile: globalFlags.configFile,
 			Node:       globalFlags.node,
 			Debug:      globalFlags.debug,
-			JSONOutput: globalFlags.jsonOutput,
+			Output:     out,
 			DryRun:     globalFlags.dryRun,
+			Yes:        globalFlags.yes,
+			Strict:     globalFlags.strict,
+			StrictKeys: globalFlags.strictKeys,
+			DockerHost: globalFlags.dockerHost,
+			Project:    project,
 		},
-	}))
+	}
+	// Every command is audited except those reading the audit log.
+	if op := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "); op != "audit" && !strings.HasPrefix(op, "audit ") {
+		rt.Audit = &logger.AuditEntry{
+			Op:   op,
+			Node: globalFlags.node,
+			Args: os.Args[1:],
+			Meta: map[string]string{},
+		}
+	}
+
+	// Opt-in check for a newer release, reported once the command is done
+	if cfg.Updates.Check && pprint.Interactive() && !out.Format.Structured() && !out.Quiet && cmd.Name() != "self-update" {
+		updateCheck = update.New().Background(cmd.Context(), filepath.Join(orbitHome, update.CheckFile), cfg.Updates.Interval)
+	}
+
+	// Load plugins so lifecycle hooks reach them
+	rt.Plugins = commands.LoadPlugins(rt)
+	shutdown.Register(cmd.Context(), "stop plugins", func(context.Context) error {
+		rt.Plugins.Shutdown()
+		return nil
+	})
+
+	// Store in command context
+	cmd.SetContext(commands.NewContext(cmd.Context(), rt))
 
 	return nil
 }
+
+// readsState reports whether cmd, or a group it belongs to, only reads the
+// state DB.
+func readsState(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[commands.ReadOnlyState] != "" {
+			return true
+		}
+	}
+	return false
+}
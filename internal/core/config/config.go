@@ -3,13 +3,17 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
+	units "github.com/docker/go-units"
 	"github.com/spf13/viper"
 
 	v1 "github.com/f9-o/orbit/api/v1"
@@ -20,13 +24,21 @@ var sensitiveKeyRegex = regexp.MustCompile(`(?i)(password|token|secret|key|passp
 
 // Defaults contains factory-default values applied before any config file is loaded.
 var Defaults = map[string]any{
-	"project.environment": "development",
-	"log.level":           "info",
-	"log.format":          "text",
-	"metrics.enabled":     false,
-	"metrics.port":        9091,
-	"proxy.backend":       "nginx",
-	"ssl.acme_url":        "https://acme-v02.api.letsencrypt.org/directory",
+	"project.environment":   "development",
+	"runtime":               "docker",
+	"log.level":             "info",
+	"log.format":            "text",
+	"metrics.enabled":       false,
+	"metrics.port":          9091,
+	"proxy.backend":         "nginx",
+	"ssl.acme_url":          "https://acme-v02.api.letsencrypt.org/directory",
+	"tui.theme":             "orbit-dark",
+	"ssh.max_connections":   64,
+	"ssh.idle_timeout":      "10m",
+	"ssh.max_sessions":      8,
+	"watchdog.max_restarts": 5,
+	"watchdog.window":       "10m",
+	"updates.interval":      "24h",
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -35,14 +47,27 @@ var Defaults = map[string]any{
 
 // Config is the fully-decoded project configuration.
 type Config struct {
-	Version  string           `mapstructure:"version"`
-	Project  ProjectConfig    `mapstructure:"project"`
-	Nodes    []v1.NodeSpec    `mapstructure:"nodes"`
-	Services []v1.ServiceSpec `mapstructure:"services"`
-	Metrics  MetricsConfig    `mapstructure:"metrics"`
-	Proxy    ProxyConfig      `mapstructure:"proxy"`
-	SSL      SSLConfig        `mapstructure:"ssl"`
-	Log      LogConfig        `mapstructure:"log"`
+	Version    string                  `mapstructure:"version"`
+	Vars       map[string]any          `mapstructure:"vars"`
+	Project    ProjectConfig           `mapstructure:"project"`
+	Runtime    string                  `mapstructure:"runtime"`     // docker | podman
+	DockerHost string                  `mapstructure:"docker_host"` // daemon URL or Docker context name
+	Nodes      []v1.NodeSpec           `mapstructure:"nodes"`
+	Services   []v1.ServiceSpec        `mapstructure:"services"`
+	Metrics    MetricsConfig           `mapstructure:"metrics"`
+	Proxy      ProxyConfig             `mapstructure:"proxy"`
+	SSL        SSLConfig               `mapstructure:"ssl"`
+	Log        LogConfig               `mapstructure:"log"`
+	Logging    LoggingConfig           `mapstructure:"logging"`
+	TUI        TUIConfig               `mapstructure:"tui"`
+	SSH        SSHConfig               `mapstructure:"ssh"`
+	Watchdog   WatchdogConfig          `mapstructure:"watchdog"`
+	Updates    UpdatesConfig           `mapstructure:"updates"`
+	Scan       ScanConfig              `mapstructure:"scan"`
+	Policy     DeployPolicyConfig      `mapstructure:"deploy_policy"`
+	State      StateConfig             `mapstructure:"state"`
+	Alerts     []AlertRule             `mapstructure:"alerts"`
+	Plugins    map[string]PluginConfig `mapstructure:"plugins"` // keyed by plugin name, lower-case
 }
 
 // ProjectConfig holds project-level metadata.
@@ -51,25 +76,41 @@ type ProjectConfig struct {
 	Environment string `mapstructure:"environment"`
 }
 
-// MetricsConfig controls the optional Prometheus /metrics endpoint.
+// MetricsConfig controls the optional Prometheus /metrics endpoint and
+// OpenTelemetry export.
 type MetricsConfig struct {
-	Enabled bool `mapstructure:"enabled"`
-	Port    int  `mapstructure:"port"`
+	Enabled      bool   `mapstructure:"enabled"`
+	Port         int    `mapstructure:"port"`
+	OTLPEndpoint string `mapstructure:"otlp_endpoint"` // OTLP/HTTP collector base URL, e.g. http://otel-collector:4318
 }
 
 // ProxyConfig holds reverse proxy settings.
 type ProxyConfig struct {
-	Backend    string `mapstructure:"backend"`     // nginx | caddy
-	ConfigPath string `mapstructure:"config_path"` // output config file path
+	Backend         string        `mapstructure:"backend"`          // nginx | caddy | traefik
+	ConfigPath      string        `mapstructure:"config_path"`      // directory for generated configs on nodes; default ~/.orbit/proxy
+	ValidateCommand string        `mapstructure:"validate_command"` // default: nginx -t | caddy validate ...
+	ReloadCommand   string        `mapstructure:"reload_command"`   // default: nginx -s reload | caddy reload ...
+	Traefik         TraefikConfig `mapstructure:"traefik"`
+}
+
+// TraefikConfig configures the traefik backend, which labels containers for
+// an existing Traefik instance rather than writing config files.
+type TraefikConfig struct {
+	Network       string `mapstructure:"network"`        // Docker network shared with Traefik
+	EntryPoint    string `mapstructure:"entrypoint"`     // default: web
+	TLSEntryPoint string `mapstructure:"tls_entrypoint"` // default: websecure
+	CertResolver  string `mapstructure:"cert_resolver"`  // Traefik certificatesResolvers name
 }
 
 // SSLConfig holds ACME configuration.
 type SSLConfig struct {
-	AcmeURL   string        `mapstructure:"acme_url"`
-	Email     string        `mapstructure:"email"`
-	CertDir   string        `mapstructure:"cert_dir"`
-	RenewDays int           `mapstructure:"renew_days"` // renew if expiry < N days
-	Timeout   time.Duration `mapstructure:"timeout"`
+	AcmeURL        string            `mapstructure:"acme_url"`
+	Email          string            `mapstructure:"email"`
+	CertDir        string            `mapstructure:"cert_dir"`
+	RenewDays      int               `mapstructure:"renew_days"` // renew if expiry < N days
+	Timeout        time.Duration     `mapstructure:"timeout"`
+	DNSProvider    string            `mapstructure:"dns_provider"`    // cloudflare | route53 | digitalocean | desec
+	DNSCredentials map[string]string `mapstructure:"dns_credentials"` // provider API credentials; ${VAR} expanded
 }
 
 // LogConfig controls logging behaviour.
@@ -77,15 +118,142 @@ type LogConfig struct {
 	Level  string `mapstructure:"level"` // debug | info | warn | error
 	File   string `mapstructure:"file"`
 	Format string `mapstructure:"format"` // json | text
+
+	// Rotation of orbit.log, audit.log and the service logs the agent collects;
+	// zero values use the logger's defaults.
+	MaxSizeMB  int           `mapstructure:"max_size_mb"` // rotate at this size; <0 never rotates
+	MaxBackups int           `mapstructure:"max_backups"` // rotated files kept; <0 keeps all
+	MaxAge     time.Duration `mapstructure:"max_age"`     // delete rotated files older than this
+	Compress   bool          `mapstructure:"compress"`    // gzip rotated files
+}
+
+// LoggingConfig ships the output of orbit containers to external log
+// stores. `orbit agent` follows the containers and pushes their lines, each
+// labelled with its service, node and image.
+type LoggingConfig struct {
+	Loki   LokiConfig        `mapstructure:"loki"`
+	HTTP   HTTPLogConfig     `mapstructure:"http"`
+	Syslog SyslogConfig      `mapstructure:"syslog"`
+	Labels map[string]string `mapstructure:"labels"` // added to every line, e.g. env: production
+}
+
+// Enabled reports whether any log store is configured.
+func (l LoggingConfig) Enabled() bool {
+	return l.Loki.URL != "" || l.HTTP.URL != "" || l.Syslog.Address != ""
+}
+
+// LokiConfig pushes lines to Grafana Loki.
+type LokiConfig struct {
+	URL      string            `mapstructure:"url"`       // e.g. http://loki:3100; /loki/api/v1/push is added when there is no path
+	TenantID string            `mapstructure:"tenant_id"` // X-Scope-OrgID, for multi-tenant Loki
+	Username string            `mapstructure:"username"`  // basic auth, e.g. for Grafana Cloud
+	Password string            `mapstructure:"password"`  // ${VAR} expanded
+	Headers  map[string]string `mapstructure:"headers"`   // ${VAR} expanded
+}
+
+// HTTPLogConfig POSTs batches of lines, as a JSON array, to any endpoint.
+type HTTPLogConfig struct {
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"` // ${VAR} expanded, e.g. Authorization
+}
+
+// SyslogConfig sends lines to a syslog server in RFC 5424 format.
+type SyslogConfig struct {
+	Address string `mapstructure:"address"` // udp://host:514 or tcp://host:601
+}
+
+// TUIConfig controls the interactive dashboard.
+type TUIConfig struct {
+	Theme string            `mapstructure:"theme"` // orbit-dark | orbit-light | high-contrast | name of ~/.orbit/themes/<name>.yaml
+	Keys  map[string]string `mapstructure:"keys"`  // action → key overrides, e.g. quit: "ctrl+q"
+}
+
+// SSHConfig bounds the SSH connection pool used to reach remote nodes.
+type SSHConfig struct {
+	MaxConnections int           `mapstructure:"max_connections"` // 0 = unlimited
+	IdleTimeout    time.Duration `mapstructure:"idle_timeout"`    // close connections unused this long; 0 = never
+	MaxSessions    int           `mapstructure:"max_sessions"`    // concurrent sessions per node; 0 = unlimited
+}
+
+// WatchdogConfig controls crash-loop detection in `orbit agent` and `orbit ui`.
+type WatchdogConfig struct {
+	MaxRestarts int           `mapstructure:"max_restarts"` // unexpected exits within Window that stop a container
+	Window      time.Duration `mapstructure:"window"`
+}
+
+// UpdatesConfig controls the background check for new Orbit releases.
+type UpdatesConfig struct {
+	Check    bool          `mapstructure:"check"`    // opt in to a notice when a newer release is out
+	Interval time.Duration `mapstructure:"interval"` // how often to ask GitHub
+}
+
+// ScanConfig controls image vulnerability scanning by `orbit scan` and,
+// optionally, before every deploy.
+type ScanConfig struct {
+	Scanner       string `mapstructure:"scanner"`        // trivy | grype; default: whichever is installed
+	FailOn        string `mapstructure:"fail_on"`        // severity at which orbit scan, and a gated deploy, fail
+	IgnoreUnfixed bool   `mapstructure:"ignore_unfixed"` // leave out vulnerabilities with no fixed version
+	Deploy        bool   `mapstructure:"deploy"`         // scan each image before deploying it; needs fail_on
+}
+
+// StateConfig configures the state DB kept in ~/.orbit.
+type StateConfig struct {
+	Sync StateSyncConfig `mapstructure:"sync"`
+}
+
+// StateSyncConfig shares the node registry and deployment history with the
+// other machines operating the fleet, through `orbit state sync`.
+type StateSyncConfig struct {
+	URL      string `mapstructure:"url"`      // s3://<bucket>/<key> or ssh://<node>/<absolute path>
+	Region   string `mapstructure:"region"`   // s3: default AWS_REGION, else us-east-1
+	Endpoint string `mapstructure:"endpoint"` // s3: an S3-compatible store, e.g. http://minio:9000; default AWS
+}
+
+// PluginConfig configures one plugin from ~/.orbit/plugins.
+type PluginConfig struct {
+	Enabled *bool             `mapstructure:"enabled"` // unset = enabled
+	Config  map[string]string `mapstructure:"config"`  // passed to the plugin's Init; keys are lower-cased
+	Timeout time.Duration     `mapstructure:"timeout"` // per hook call; 0 = the host default
+}
+
+// IsEnabled reports whether the plugin should be loaded.
+func (p PluginConfig) IsEnabled() bool {
+	return p.Enabled == nil || *p.Enabled
+}
+
+// AlertRule is one entry of the alerts: section, evaluated by `orbit agent`.
+// The rule fires once its condition has held continuously for For.
+type AlertRule struct {
+	Name    string        `mapstructure:"name"`
+	Metric  string        `mapstructure:"metric"`  // cpu | mem | host_mem | host_disk | host_load | unhealthy | node_offline
+	Service string        `mapstructure:"service"` // cpu, mem, unhealthy: only this service; empty = all
+	Node    string        `mapstructure:"node"`    // host_*, node_offline: only this node; empty = all
+	Above   float64       `mapstructure:"above"`   // percent, or the 1-minute load average for host_load
+	For     time.Duration `mapstructure:"for"`
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
 // Loader
 // ─────────────────────────────────────────────────────────────────────────────
 
+// LoadOptions tunes how Load interprets the configuration sources.
+type LoadOptions struct {
+	// Strict rejects keys that do not map to any config field
+	// (e.g. a misspelled "replcias:") instead of silently ignoring them.
+	Strict bool
+
+	// Vars overrides entries of the `vars:` section when rendering templates.
+	Vars map[string]string
+}
+
 // Load discovers and loads the configuration, walking up directories to find
 // orbit.yaml, then merging it with the global config and environment variables.
 func Load(explicitPath string) (*Config, error) {
+	return LoadWithOptions(explicitPath, LoadOptions{})
+}
+
+// LoadWithOptions is Load with explicit loader options.
+func LoadWithOptions(explicitPath string, opts LoadOptions) (*Config, error) {
 	v := viper.New()
 
 	// Apply defaults
@@ -108,18 +276,22 @@ func Load(explicitPath string) (*Config, error) {
 	}
 
 	// Load project config
-	if explicitPath != "" {
-		v.SetConfigFile(explicitPath)
-	} else {
-		path, err := discoverProjectConfig()
-		if err == nil {
-			v.SetConfigFile(path)
+	projectPath := explicitPath
+	if projectPath == "" {
+		if path, err := discoverProjectConfig(); err == nil {
+			projectPath = path
+		}
+	}
+
+	if projectPath != "" {
+		if err := mergeProjectConfig(v, projectPath, opts.Vars); err != nil {
+			return nil, fmt.Errorf("read project config %q: %w", projectPath, err)
 		}
 	}
 
-	if v.ConfigFileUsed() != "" || explicitPath != "" {
-		if err := v.MergeInConfig(); err != nil && explicitPath != "" {
-			return nil, fmt.Errorf("read project config %q: %w", explicitPath, err)
+	if opts.Strict {
+		if err := checkUnknownKeys(v.AllSettings()); err != nil {
+			return nil, fmt.Errorf("config validation: %w", err)
 		}
 	}
 
@@ -158,6 +330,12 @@ func (c *Config) NodeByName(name string) *v1.NodeSpec {
 	return nil
 }
 
+// Plugin returns the settings for the named plugin; plugins without an
+// entry are enabled with no config.
+func (c *Config) Plugin(name string) PluginConfig {
+	return c.Plugins[strings.ToLower(name)]
+}
+
 // IsSensitiveKey returns true if key matches a known sensitive pattern.
 func IsSensitiveKey(key string) bool {
 	return sensitiveKeyRegex.MatchString(key)
@@ -167,6 +345,30 @@ func IsSensitiveKey(key string) bool {
 // Internal helpers
 // ─────────────────────────────────────────────────────────────────────────────
 
+// mergeProjectConfig renders orbit.yaml templates and merges the result into v.
+func mergeProjectConfig(v *viper.Viper, path string, vars map[string]string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	rendered, err := RenderTemplate(filepath.Base(path), raw, vars)
+	if err != nil {
+		return err
+	}
+
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" || ext == "yml" {
+		ext = "yaml"
+	}
+	v.SetConfigType(ext)
+	return v.MergeConfig(bytes.NewReader(rendered))
+}
+
+// DiscoverProjectConfig returns the path of the nearest orbit.yaml above the CWD.
+func DiscoverProjectConfig() (string, error) {
+	return discoverProjectConfig()
+}
+
 // discoverProjectConfig walks up from the CWD looking for orbit.yaml.
 func discoverProjectConfig() (string, error) {
 	dir, err := os.Getwd()
@@ -196,10 +398,34 @@ func expandEnvInConfig(cfg *Config) {
 		}
 	}
 	cfg.SSL.Email = os.ExpandEnv(cfg.SSL.Email)
+	for k, v := range cfg.SSL.DNSCredentials {
+		cfg.SSL.DNSCredentials[k] = os.ExpandEnv(v)
+	}
+	cfg.Logging.Loki.Password = os.ExpandEnv(cfg.Logging.Loki.Password)
+	for _, headers := range []map[string]string{cfg.Logging.Loki.Headers, cfg.Logging.HTTP.Headers, cfg.Policy.Approval.Headers} {
+		for k, v := range headers {
+			headers[k] = os.ExpandEnv(v)
+		}
+	}
 }
 
 // validate performs semantic validation on the loaded config.
 func validate(cfg *Config) error {
+	switch cfg.Runtime {
+	case "", "docker", "podman":
+	default:
+		return fmt.Errorf("runtime: unknown container runtime %q (want docker or podman)", cfg.Runtime)
+	}
+
+	if err := validateDockerHost("docker_host", cfg.DockerHost); err != nil {
+		return err
+	}
+	for _, n := range cfg.Nodes {
+		if err := validateDockerHost(fmt.Sprintf("node %q: docker_host", n.Name), n.DockerHost); err != nil {
+			return err
+		}
+	}
+
 	seen := map[string]bool{}
 	for _, svc := range cfg.Services {
 		if svc.Name == "" {
@@ -212,6 +438,340 @@ func validate(cfg *Config) error {
 		if svc.Image == "" {
 			return fmt.Errorf("service %q: image is required", svc.Name)
 		}
+		if svc.Deploy != nil && svc.Deploy.Autoscale != nil {
+			as := svc.Deploy.Autoscale
+			switch {
+			case as.TargetCPU <= 0:
+				return fmt.Errorf("service %q: deploy.autoscale.target_cpu must be greater than 0", svc.Name)
+			case as.MaxReplicas < 1 || as.MaxReplicas < as.MinReplicas:
+				return fmt.Errorf("service %q: deploy.autoscale.max_replicas must be at least 1 and at least min_replicas", svc.Name)
+			case as.MinReplicas < 0 || as.ScaleUpCooldown < 0 || as.ScaleDownCooldown < 0:
+				return fmt.Errorf("service %q: deploy.autoscale values must not be negative", svc.Name)
+			}
+		}
+		if svc.StopGracePeriod < 0 {
+			return fmt.Errorf("service %q: stop_grace_period must not be negative", svc.Name)
+		}
+		if err := validateSecurity(svc); err != nil {
+			return err
+		}
+		if err := validateSystem(svc); err != nil {
+			return err
+		}
+		if err := validateProfiles(svc); err != nil {
+			return err
+		}
+		if err := validateReplicaPorts(svc); err != nil {
+			return err
+		}
+		if err := validateVerify(svc); err != nil {
+			return err
+		}
+		if err := validateHooks(svc); err != nil {
+			return err
+		}
+		if err := validateMigrations(svc); err != nil {
+			return err
+		}
+	}
+
+	if err := validateDependencies(cfg.Services); err != nil {
+		return err
+	}
+
+	switch cfg.Proxy.Backend {
+	case "", "nginx", "caddy", "traefik":
+	default:
+		return fmt.Errorf("proxy.backend: unknown backend %q (want nginx, caddy or traefik)", cfg.Proxy.Backend)
+	}
+
+	switch cfg.SSL.DNSProvider {
+	case "", "cloudflare", "route53", "digitalocean", "desec":
+	default:
+		return fmt.Errorf("ssl.dns_provider: unknown provider %q (want cloudflare, route53, digitalocean or desec)", cfg.SSL.DNSProvider)
+	}
+
+	if ep := cfg.Metrics.OTLPEndpoint; ep != "" {
+		if u, err := url.Parse(ep); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("metrics.otlp_endpoint: %q is not an http:// or https:// URL", ep)
+		}
+	}
+
+	for key, ep := range map[string]string{"logging.loki.url": cfg.Logging.Loki.URL, "logging.http.url": cfg.Logging.HTTP.URL} {
+		if u, err := url.Parse(ep); ep != "" && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+			return fmt.Errorf("%s: %q is not an http:// or https:// URL", key, ep)
+		}
+	}
+	if addr := cfg.Logging.Syslog.Address; addr != "" {
+		if u, err := url.Parse(addr); err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Port() == "" {
+			return fmt.Errorf("logging.syslog.address: %q is not a udp://host:port or tcp://host:port address", addr)
+		}
+	}
+
+	switch cfg.Scan.Scanner {
+	case "", "trivy", "grype":
+	default:
+		return fmt.Errorf("scan.scanner: unknown scanner %q (want trivy or grype)", cfg.Scan.Scanner)
+	}
+	switch strings.ToLower(cfg.Scan.FailOn) {
+	case "", "critical", "high", "medium", "low", "unknown":
+	default:
+		return fmt.Errorf("scan.fail_on: unknown severity %q (want critical, high, medium, low or unknown)", cfg.Scan.FailOn)
+	}
+	if cfg.Scan.Deploy && cfg.Scan.FailOn == "" {
+		return fmt.Errorf("scan.deploy: set scan.fail_on to the severity that refuses a deploy")
+	}
+	if err := validateDeployPolicy(cfg.Policy); err != nil {
+		return err
+	}
+
+	if sync := cfg.State.Sync; sync.URL != "" {
+		if u, err := url.Parse(sync.URL); err != nil || (u.Scheme != "s3" && u.Scheme != "ssh") || u.Host == "" || len(u.Path) < 2 {
+			return fmt.Errorf("state.sync.url: %q is not an s3://<bucket>/<key> or ssh://<node>/<path> URL", sync.URL)
+		}
+	}
+	if ep := cfg.State.Sync.Endpoint; ep != "" {
+		if u, err := url.Parse(ep); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("state.sync.endpoint: %q is not an http:// or https:// URL", ep)
+		}
+	}
+
+	rules := map[string]bool{}
+	for i, r := range cfg.Alerts {
+		if r.Name == "" {
+			return fmt.Errorf("alerts[%d]: name is required", i)
+		}
+		if rules[r.Name] {
+			return fmt.Errorf("duplicate alert name: %q", r.Name)
+		}
+		rules[r.Name] = true
+		switch r.Metric {
+		case "cpu", "mem", "host_mem", "host_disk", "host_load":
+			if r.Above <= 0 {
+				return fmt.Errorf("alert %q: above must be greater than 0 for metric %s", r.Name, r.Metric)
+			}
+		case "unhealthy", "node_offline":
+		default:
+			return fmt.Errorf("alert %q: unknown metric %q (want cpu, mem, host_mem, host_disk, host_load, unhealthy, or node_offline)", r.Name, r.Metric)
+		}
+		if r.For < 0 {
+			return fmt.Errorf("alert %q: for must not be negative", r.Name)
+		}
+	}
+	for name, p := range cfg.Plugins {
+		if p.Timeout < 0 {
+			return fmt.Errorf("plugin %q: timeout must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// validateDockerHost checks a docker_host given as a URL; other values name
+// Docker contexts, which are looked up when orbit connects.
+func validateDockerHost(key, host string) error {
+	if !strings.Contains(host, "://") {
+		return nil
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	switch u.Scheme {
+	case "unix", "npipe", "tcp", "http", "https":
+		return nil
+	case "ssh":
+		return fmt.Errorf("%s: ssh:// endpoints are not supported; add the machine as a node, or use a tcp:// or unix:// endpoint", key)
+	}
+	return fmt.Errorf("%s: unknown scheme in %q (want unix://, tcp:// or npipe://)", key, host)
+}
+
+// validateDependencies rejects depends_on entries naming unknown services
+// and dependency cycles, which leave no order to start services in.
+func validateDependencies(services []v1.ServiceSpec) error {
+	deps := map[string][]string{}
+	for _, svc := range services {
+		deps[svc.Name] = svc.DependsOn
+	}
+	for _, svc := range services {
+		for _, d := range svc.DependsOn {
+			if _, ok := deps[d]; !ok {
+				return fmt.Errorf("service %q: depends_on: unknown service %q", svc.Name, d)
+			}
+		}
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	mark := map[string]int{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch mark[name] {
+		case visiting:
+			return fmt.Errorf("depends_on cycle: %s", strings.Join(append(path, name), " → "))
+		case done:
+			return nil
+		}
+		mark[name] = visiting
+		for _, d := range deps[name] {
+			if err := visit(d, append(path, name)); err != nil {
+				return err
+			}
+		}
+		mark[name] = done
+		return nil
+	}
+	for _, svc := range services {
+		if err := visit(svc.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSecurity checks the form of a service's hardening settings; the
+// daemon checks capability and option names when it creates the container.
+func validateSecurity(svc v1.ServiceSpec) error {
+	for _, opt := range svc.SecurityOpt {
+		if opt != "no-new-privileges" && !strings.ContainsAny(opt, "=:") {
+			return fmt.Errorf("service %q: security_opt %q: want key=value, e.g. no-new-privileges:true or apparmor=profile", svc.Name, opt)
+		}
+	}
+	for _, t := range svc.Tmpfs {
+		if !strings.HasPrefix(t, "/") {
+			return fmt.Errorf("service %q: tmpfs %q: want an absolute path, optionally with :options", svc.Name, t)
+		}
+	}
+	return nil
+}
+
+// validateSystem checks a service's ulimits, extra hosts and nameservers.
+func validateSystem(svc v1.ServiceSpec) error {
+	for name, limit := range svc.Ulimits {
+		if _, err := units.ParseUlimit(name + "=" + limit); err != nil {
+			return fmt.Errorf("service %q: ulimits.%s: %w", svc.Name, name, err)
+		}
+	}
+	for _, h := range svc.ExtraHosts {
+		host, ip, ok := strings.Cut(h, ":")
+		if !ok || host == "" || (ip != "host-gateway" && net.ParseIP(ip) == nil) {
+			return fmt.Errorf("service %q: extra_hosts %q: want host:ip or host:host-gateway", svc.Name, h)
+		}
+	}
+	for _, ns := range svc.DNS {
+		if net.ParseIP(ns) == nil {
+			return fmt.Errorf("service %q: dns %q is not an IP address", svc.Name, ns)
+		}
+	}
+	return nil
+}
+
+// Warnings lists settings that are valid but weaken isolation, for
+// `orbit config validate` to point out.
+func (c *Config) Warnings() []string {
+	var warnings []string
+	for _, svc := range c.Services {
+		if svc.Privileged {
+			warnings = append(warnings, fmt.Sprintf("service %q is privileged: it gets every capability and the host's devices; grant what it needs with cap_add instead", svc.Name))
+		}
+		for _, c := range svc.CapAdd {
+			if strings.EqualFold(c, "ALL") {
+				warnings = append(warnings, fmt.Sprintf("service %q adds ALL capabilities; list the ones it needs instead", svc.Name))
+			}
+		}
+	}
+	return warnings
+}
+
+// validateReplicaPorts rejects a service that asks for several replicas on
+// host ports only one container can bind.
+func validateReplicaPorts(svc v1.ServiceSpec) error {
+	d := svc.Deploy
+	if d == nil {
+		return nil
+	}
+	switch d.ReplicaPorts {
+	case "":
+	case v1.ReplicaPortsOffset, v1.ReplicaPortsEphemeral:
+		return nil
+	default:
+		return fmt.Errorf("service %q: deploy.replica_ports: unknown mode %q (want offset or ephemeral)", svc.Name, d.ReplicaPorts)
+	}
+	replicas := d.Replicas
+	if d.Autoscale != nil {
+		replicas = d.Autoscale.MaxReplicas
+	}
+	for _, p := range svc.Ports {
+		if host, _, ok := strings.Cut(p, ":"); ok && replicas > 1 {
+			return fmt.Errorf("service %q: %d replicas cannot all bind host port %s; set deploy.replica_ports to offset or ephemeral", svc.Name, replicas, host)
+		}
+	}
+	return nil
+}
+
+// validateVerify checks a service's deploy.verify block.
+func validateVerify(svc v1.ServiceSpec) error {
+	if svc.Deploy == nil || svc.Deploy.Verify == nil {
+		return nil
+	}
+	v := svc.Deploy.Verify
+	switch {
+	case v.Window <= 0:
+		return fmt.Errorf("service %q: deploy.verify.window must be greater than 0", svc.Name)
+	case v.Interval < 0 || v.Interval > v.Window:
+		return fmt.Errorf("service %q: deploy.verify.interval must be between 0 and the window", svc.Name)
+	case v.MaxRestarts < 0 || v.MaxHealthFlaps < 0:
+		return fmt.Errorf("service %q: deploy.verify maximums must not be negative", svc.Name)
+	case v.MaxErrorRate < 0 || v.MaxErrorRate > 100:
+		return fmt.Errorf("service %q: deploy.verify.max_error_rate must be a percentage, 0 to 100", svc.Name)
+	}
+	if v.URL != "" {
+		if u, err := url.Parse(v.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("service %q: deploy.verify.url must be an http or https URL", svc.Name)
+		}
+	}
+	return nil
+}
+
+// validateHooks checks the commands of a service's hooks: block.
+func validateHooks(svc v1.ServiceSpec) error {
+	for _, hook := range []string{v1.HookCmdPreDeploy, v1.HookCmdPostDeploy, v1.HookCmdPreStop} {
+		for i, c := range svc.Hooks.Commands(hook) {
+			at := fmt.Sprintf("service %q: hooks.%s[%d]", svc.Name, hook, i)
+			if strings.TrimSpace(c.Command) == "" {
+				return fmt.Errorf("%s: command is required", at)
+			}
+			switch c.Where {
+			case "", v1.HookWhereLocal, v1.HookWhereNode, v1.HookWhereContainer:
+			default:
+				return fmt.Errorf("%s: unknown where %q (want local, node or container)", at, c.Where)
+			}
+			switch c.OnFailure {
+			case "", v1.HookFailureFail, v1.HookFailureIgnore:
+			default:
+				return fmt.Errorf("%s: unknown on_failure %q (want fail or ignore)", at, c.OnFailure)
+			}
+			if c.Timeout < 0 {
+				return fmt.Errorf("%s: timeout must not be negative", at)
+			}
+		}
+	}
+	return nil
+}
+
+// validateMigrations checks a service's migrations: block. Without a command
+// the container would run the service's own image as is, which serves
+// rather than exits.
+func validateMigrations(svc v1.ServiceSpec) error {
+	m := svc.Migrations
+	if m == nil {
+		return nil
+	}
+	if m.Image == "" && len(m.Command) == 0 {
+		return fmt.Errorf("service %q: migrations: command is required unless image is set", svc.Name)
+	}
+	if m.Timeout < 0 {
+		return fmt.Errorf("service %q: migrations: timeout must not be negative", svc.Name)
 	}
 	return nil
 }
@@ -230,6 +790,51 @@ func OrbitHome() string {
 	return orbitHome()
 }
 
+// KnownHostsFile is Orbit's managed known_hosts file, written by
+// `orbit nodes trust` and consulted for every SSH connection.
+func KnownHostsFile() string {
+	return filepath.Join(orbitHome(), "known_hosts")
+}
+
+// PluginDir is where plugins are installed and loaded from.
+func PluginDir() string {
+	return filepath.Join(orbitHome(), "plugins")
+}
+
+// ResolvedCertDir returns ssl.cert_dir with a leading ~/ expanded, or
+// ~/.orbit/certs when it is unset.
+func (s SSLConfig) ResolvedCertDir() string {
+	if s.CertDir == "" {
+		return filepath.Join(orbitHome(), "certs")
+	}
+	if rest, ok := strings.CutPrefix(s.CertDir, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return s.CertDir
+}
+
+// RemoteCertDir is ssl.cert_dir as used on remote nodes, where a leading ~/
+// refers to the SSH user's home; ~/.orbit/certs when it is unset.
+func (s SSLConfig) RemoteCertDir() string {
+	if s.CertDir == "" {
+		return "~/.orbit/certs"
+	}
+	return s.CertDir
+}
+
+// ACMEAccountKeyFile holds the key of the ACME account certificates are
+// ordered under, created on first issuance.
+func ACMEAccountKeyFile() string {
+	return filepath.Join(orbitHome(), "acme", "account.key")
+}
+
+// LocalCADir holds the CA that signs development certificates.
+func LocalCADir() string {
+	return filepath.Join(orbitHome(), "ca")
+}
+
 // DefaultConfigTemplate is the content written by `orbit init`.
 const DefaultConfigTemplate = `# orbit.yaml — Project manifest
 # See: https://github.com/f9-o/orbit/docs/cli-reference.md
@@ -239,6 +844,9 @@ project:
   name: my-app
   environment: production
 
+# runtime: docker   # or podman (uses the Podman API socket; rootless works)
+# docker_host: colima   # a docker context name or daemon URL; default: DOCKER_HOST, then the current context
+
 # nodes:
 #   - name: prod-01
 #     host: 192.168.1.10
@@ -260,6 +868,35 @@ services:
       retries: 3
     deploy:
       replicas: 1
+      # replica_ports: ephemeral # offset | ephemeral; needed for replicas > 1 with host ports
       strategy: rolling
       rollback_on_failure: true
+      # autoscale:       # applied by orbit agent; overrides replicas
+      #   min_replicas: 1
+      #   max_replicas: 4
+      #   target_cpu: 70 # percent of one CPU per replica
+
+# alerts:             # evaluated by orbit agent; active alerts show in orbit status
+#   - name: web-cpu
+#     metric: cpu      # cpu | mem | host_mem | host_disk | host_load | unhealthy | node_offline
+#     service: web
+#     above: 90        # percent (load average for host_load)
+#     for: 5m
+#   - name: nodes-down
+#     metric: node_offline
+#     for: 1m
+
+# ssl:
+#   email: ops@example.com
+#   dns_provider: cloudflare   # DNS-01 for wildcards and hosts without port 80
+#   dns_credentials:           # cloudflare | route53 | digitalocean | desec
+#     api_token: ${CLOUDFLARE_API_TOKEN}
+
+# proxy:
+#   backend: nginx             # nginx | caddy: orbit proxy push; traefik: container labels
+#   config_path: ~/.orbit/proxy
+#   reload_command: sudo nginx -s reload
+#   traefik:                   # with backend: traefik
+#     network: traefik         # Docker network Traefik shares with services
+#     cert_resolver: le        # for services with proxy.ssl
 `
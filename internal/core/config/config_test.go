@@ -0,0 +1,544 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/f9-o/orbit/internal/core/config"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir()) // isolate from ~/.orbit/config.yaml
+	path := filepath.Join(t.TempDir(), "orbit.yaml")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStrictRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `
+version: "1"
+services:
+  - name: web
+    image: nginx:alpine
+    deploy:
+      replcias: 3
+`)
+
+	// Lenient mode silently ignores the typo
+	if _, err := config.Load(path); err != nil {
+		t.Fatalf("lenient load failed: %v", err)
+	}
+
+	_, err := config.LoadWithOptions(path, config.LoadOptions{Strict: true})
+	var uk *config.UnknownKeyError
+	if !errors.As(err, &uk) {
+		t.Fatalf("expected UnknownKeyError, got %v", err)
+	}
+	if len(uk.Keys) != 1 || uk.Keys[0] != "services[0].deploy.replcias" {
+		t.Fatalf("unexpected keys: %v", uk.Keys)
+	}
+	if uk.Hints[uk.Keys[0]] != "replicas" {
+		t.Fatalf("expected hint 'replicas', got %q", uk.Hints[uk.Keys[0]])
+	}
+}
+
+func TestSchemaDisallowsAdditionalProperties(t *testing.T) {
+	s := config.Schema()
+	if s["additionalProperties"] != false {
+		t.Fatal("root schema must reject additional properties")
+	}
+	props := s["properties"].(map[string]any)
+	if _, ok := props["services"]; !ok {
+		t.Fatal("schema is missing the services property")
+	}
+}
+
+func TestTemplateVarsAndOverrides(t *testing.T) {
+	t.Setenv("ORBIT_TEST_DOMAIN", "example.org")
+	path := writeConfig(t, `
+version: "1"
+vars:
+  version: "1.0.0"
+services:
+  - name: web
+    image: myapp:{{ .vars.version }}
+    proxy:
+      domain: app.{{ env "ORBIT_TEST_DOMAIN" }}
+`)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := cfg.Services[0].Image; got != "myapp:1.0.0" {
+		t.Fatalf("image = %q, want myapp:1.0.0", got)
+	}
+	if got := cfg.Services[0].Proxy.Domain; got != "app.example.org" {
+		t.Fatalf("domain = %q, want app.example.org", got)
+	}
+
+	cfg, err = config.LoadWithOptions(path, config.LoadOptions{Vars: map[string]string{"version": "2.0.0"}})
+	if err != nil {
+		t.Fatalf("load with override: %v", err)
+	}
+	if got := cfg.Services[0].Image; got != "myapp:2.0.0" {
+		t.Fatalf("image = %q, want myapp:2.0.0", got)
+	}
+}
+
+func TestTemplateSkipsComments(t *testing.T) {
+	path := writeConfig(t, `
+vars:
+  version: "1.0.0"
+# image: myapp:{{ .vars.removed }}
+services:
+  - name: web
+    #   domain: {{ required "DOMAIN must be set" (env "ORBIT_TEST_UNSET") }}
+    image: myapp:{{ .vars.version }} # was {{ .vars.version }}
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := cfg.Services[0].Image; got != "myapp:1.0.0" {
+		t.Fatalf("image = %q, want myapp:1.0.0", got)
+	}
+
+	out, err := config.RenderTemplate("orbit.yaml", []byte("# {{ .vars.x }}\nname: {{ .vars.x }} # {{ .vars.x }}\n"),
+		map[string]string{"x": "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# {{ .vars.x }}\nname: y # y\n"; string(out) != want {
+		t.Errorf("rendered %q, want %q", out, want)
+	}
+}
+
+func TestTemplateUndefinedVar(t *testing.T) {
+	path := writeConfig(t, `
+services:
+  - name: web
+    image: myapp:{{ .vars.missing }}
+`)
+	if _, err := config.Load(path); err == nil {
+		t.Fatal("expected error for undefined template var")
+	}
+}
+
+func TestTemplateRequiredVar(t *testing.T) {
+	path := writeConfig(t, `
+vars:
+  version: "1.0.0"
+services:
+  - name: web
+    image: myapp:{{ required "vars.version is required" .vars.version }}
+  - name: worker
+    image: worker:{{ required "set --var tag" .vars.tag }}
+`)
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "tag") {
+		t.Fatalf("load without tag = %v, want an error naming it", err)
+	}
+	cfg, err := config.LoadWithOptions(path, config.LoadOptions{Vars: map[string]string{"tag": "7"}})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Services[0].Image != "myapp:1.0.0" || cfg.Services[1].Image != "worker:7" {
+		t.Errorf("images = %q, %q", cfg.Services[0].Image, cfg.Services[1].Image)
+	}
+}
+
+func TestDiscoveredConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	if err := os.WriteFile(filepath.Join(dir, "orbit.yaml"), []byte("services:\n  - name: web\n    image: app:{{ .vars.nope }}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if _, err := config.Load(""); err == nil {
+		t.Fatal("a broken auto-discovered orbit.yaml loaded")
+	}
+}
+
+func TestAlertRules(t *testing.T) {
+	path := writeConfig(t, `
+alerts:
+  - name: web-cpu
+    metric: cpu
+    service: web
+    above: 90
+    for: 5m
+`)
+	cfg, err := config.LoadWithOptions(path, config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(cfg.Alerts) != 1 || cfg.Alerts[0].For.Minutes() != 5 || cfg.Alerts[0].Above != 90 {
+		t.Fatalf("alerts = %+v", cfg.Alerts)
+	}
+
+	path = writeConfig(t, `
+alerts:
+  - name: typo
+    metric: cpuu
+    above: 90
+`)
+	if _, err := config.Load(path); err == nil {
+		t.Fatal("expected error for unknown alert metric")
+	}
+}
+
+func TestReplicaPorts(t *testing.T) {
+	const svc = `
+services:
+  - name: web
+    image: nginx
+    ports: ["8080:80"]
+    deploy:
+      replicas: 3
+`
+	if _, err := config.Load(writeConfig(t, svc)); err == nil {
+		t.Fatal("expected error for 3 replicas on one host port")
+	}
+	cfg, err := config.LoadWithOptions(writeConfig(t, svc+"      replica_ports: ephemeral\n"), config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := cfg.Services[0].Deploy.ReplicaPorts; got != "ephemeral" {
+		t.Errorf("replica_ports = %q", got)
+	}
+	if _, err := config.Load(writeConfig(t, svc+"      replica_ports: random\n")); err == nil {
+		t.Fatal("expected error for unknown replica_ports mode")
+	}
+}
+
+func TestLogging(t *testing.T) {
+	t.Setenv("LOKI_TOKEN", "s3cret")
+	cfg, err := config.LoadWithOptions(writeConfig(t, `
+logging:
+  labels:
+    env: prod
+  loki:
+    url: http://loki:3100
+    password: ${LOKI_TOKEN}
+`), config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !cfg.Logging.Enabled() || cfg.Logging.Loki.Password != "s3cret" || cfg.Logging.Labels["env"] != "prod" {
+		t.Errorf("logging = %+v", cfg.Logging)
+	}
+	for _, body := range []string{
+		"logging:\n  loki:\n    url: loki:3100\n",
+		"logging:\n  syslog:\n    address: logs.example.com:514\n",
+	} {
+		if _, err := config.Load(writeConfig(t, body)); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestDockerHost(t *testing.T) {
+	cfg, err := config.LoadWithOptions(writeConfig(t, `
+docker_host: colima
+nodes:
+  - name: edge
+    host: 10.0.0.5
+    docker_host: tcp://10.0.0.5:2376
+`), config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.DockerHost != "colima" || cfg.NodeByName("edge").DockerHost != "tcp://10.0.0.5:2376" {
+		t.Errorf("docker_host = %q, node = %+v", cfg.DockerHost, cfg.Nodes)
+	}
+	for _, body := range []string{
+		"docker_host: ssh://ops@10.0.0.5\n",
+		"nodes:\n  - name: edge\n    docker_host: ftp://10.0.0.5\n",
+	} {
+		if _, err := config.Load(writeConfig(t, body)); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestScanSettings(t *testing.T) {
+	cfg, err := config.LoadWithOptions(writeConfig(t, `
+scan:
+  scanner: grype
+  fail_on: HIGH
+  ignore_unfixed: true
+  deploy: true
+`), config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Scan.Scanner != "grype" || cfg.Scan.FailOn != "HIGH" || !cfg.Scan.IgnoreUnfixed || !cfg.Scan.Deploy {
+		t.Errorf("scan = %+v", cfg.Scan)
+	}
+	for _, body := range []string{
+		"scan:\n  scanner: clair\n",
+		"scan:\n  fail_on: severe\n",
+		"scan:\n  deploy: true\n",
+	} {
+		if _, err := config.Load(writeConfig(t, body)); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestDeployVerify(t *testing.T) {
+	service := "services:\n  - name: api\n    image: api:1\n    deploy:\n      verify:\n"
+	cfg, err := config.LoadWithOptions(writeConfig(t, service+`        window: 2m
+        interval: 10s
+        max_restarts: 1
+        url: http://localhost:8080/healthz
+        max_error_rate: 2.5
+`), config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	v := cfg.Services[0].Deploy.Verify
+	if v == nil || v.Window != 2*time.Minute || v.Interval != 10*time.Second || v.MaxRestarts != 1 || v.MaxErrorRate != 2.5 {
+		t.Errorf("verify = %+v", v)
+	}
+	for _, body := range []string{
+		"        max_restarts: 1\n",
+		"        window: 1m\n        interval: 2m\n",
+		"        window: 1m\n        max_error_rate: 150\n",
+		"        window: 1m\n        url: localhost:8080\n",
+	} {
+		if _, err := config.Load(writeConfig(t, service+body)); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestServiceHooks(t *testing.T) {
+	service := "services:\n  - name: api\n    image: api:1\n    hooks:\n"
+	cfg, err := config.LoadWithOptions(writeConfig(t, service+`      pre_deploy:
+        - command: ./migrate up
+          where: container
+          timeout: 10m
+      post_deploy:
+        - command: curl -fsS -X POST https://cdn.example/purge
+          on_failure: ignore
+      pre_stop:
+        - command: ./drain.sh
+          where: node
+`), config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	h := cfg.Services[0].Hooks
+	if h == nil || len(h.PreDeploy) != 1 || h.PreDeploy[0].Where != "container" || h.PreDeploy[0].Timeout != 10*time.Minute ||
+		h.PostDeploy[0].OnFailure != "ignore" || h.PreStop[0].Command != "./drain.sh" {
+		t.Errorf("hooks = %+v", h)
+	}
+	for _, body := range []string{
+		"      pre_deploy:\n        - where: node\n",
+		"      post_deploy:\n        - command: x\n          where: remote\n",
+		"      pre_stop:\n        - command: x\n          on_failure: retry\n",
+		"      pre_deploy:\n        - command: x\n          timeout: -1s\n",
+	} {
+		if _, err := config.Load(writeConfig(t, service+body)); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestServiceMigrations(t *testing.T) {
+	service := "services:\n  - name: api\n    image: api:1\n    migrations:\n"
+	cfg, err := config.LoadWithOptions(writeConfig(t, service+"      command: ./manage.py migrate --noinput\n      timeout: 20m\n"),
+		config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if m := cfg.Services[0].Migrations; m == nil || len(m.Command) != 1 || m.Timeout != 20*time.Minute {
+		t.Errorf("migrations = %+v", m)
+	}
+	for _, body := range []string{"      timeout: 1m\n", "      image: api-migrate:1\n      timeout: -1s\n"} {
+		if _, err := config.Load(writeConfig(t, service+body)); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestDeployPolicy(t *testing.T) {
+	t.Setenv("APPROVAL_TOKEN", "s3cret")
+	cfg, err := config.LoadWithOptions(writeConfig(t, `
+project:
+  environment: production
+deploy_policy:
+  protected: [production]
+  confirm: true
+  approval:
+    url: https://approvals.example.com/orbit
+    headers:
+      Authorization: Bearer ${APPROVAL_TOKEN}
+    timeout: 30m
+  windows:
+    - days: [sat, sun]
+      start: "02:00"
+      end: "05:00"
+      timezone: Europe/Berlin
+`), config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	p := cfg.Policy
+	if !p.Protects("production") || p.Protects("staging") || !p.Confirm || p.Approval.Timeout != 30*time.Minute || len(p.Windows) != 1 {
+		t.Errorf("deploy_policy = %+v", p)
+	}
+	if p.Approval.Headers["authorization"] != "Bearer s3cret" {
+		t.Errorf("headers = %v", p.Approval.Headers)
+	}
+	for _, body := range []string{
+		"deploy_policy:\n  confirm: true\n",
+		"deploy_policy:\n  protected: [production]\n  approval:\n    url: approvals:8080\n",
+		"deploy_policy:\n  protected: [production]\n  windows:\n    - start: \"25:00\"\n      end: \"02:00\"\n",
+		"deploy_policy:\n  protected: [production]\n  windows:\n    - days: [someday]\n      start: \"01:00\"\n      end: \"02:00\"\n",
+		"deploy_policy:\n  protected: [production]\n  windows:\n    - start: \"01:00\"\n      end: \"02:00\"\n      timezone: Mars/Olympus\n",
+	} {
+		if _, err := config.Load(writeConfig(t, body)); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestProcessSettings(t *testing.T) {
+	cfg, err := config.LoadWithOptions(writeConfig(t, `
+services:
+  - name: web
+    image: node:20
+    command: npm run "start prod"
+    entrypoint: [tini, --]
+    working_dir: /app
+    init: true
+    stop_signal: SIGINT
+    stop_grace_period: 45s
+`), config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	svc := cfg.Services[0]
+	if len(svc.Command) != 1 || svc.Command[0] != `npm run "start prod"` {
+		t.Errorf("command = %q, want the line unsplit", svc.Command)
+	}
+	if len(svc.Entrypoint) != 2 || svc.WorkingDir != "/app" || !svc.Init ||
+		svc.StopSignal != "SIGINT" || svc.StopGracePeriod != 45*time.Second {
+		t.Errorf("service = %+v", svc)
+	}
+	if _, err := config.Load(writeConfig(t, "services:\n  - name: web\n    image: nginx\n    stop_grace_period: -1s\n")); err == nil {
+		t.Error("expected an error for a negative stop_grace_period")
+	}
+}
+
+func TestSecuritySettings(t *testing.T) {
+	cfg, err := config.LoadWithOptions(writeConfig(t, `
+services:
+  - name: web
+    image: nginx
+    cap_drop: [ALL]
+    cap_add: [NET_BIND_SERVICE]
+    security_opt: ["no-new-privileges:true"]
+    read_only: true
+    tmpfs: ["/run:size=64m,mode=1777", /tmp]
+  - name: vpn
+    image: wireguard
+    privileged: true
+`), config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	web := cfg.Services[0]
+	if len(web.CapDrop) != 1 || len(web.CapAdd) != 1 || !web.ReadOnly || len(web.Tmpfs) != 2 || web.Tmpfs[0] != "/run:size=64m,mode=1777" {
+		t.Errorf("web = %+v", web)
+	}
+	if w := cfg.Warnings(); len(w) != 1 || !strings.Contains(w[0], `"vpn" is privileged`) {
+		t.Errorf("warnings = %q", w)
+	}
+	for _, body := range []string{
+		"services:\n  - name: web\n    image: nginx\n    security_opt: [apparmor]\n",
+		"services:\n  - name: web\n    image: nginx\n    tmpfs: [run]\n",
+	} {
+		if _, err := config.Load(writeConfig(t, body)); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestSystemSettings(t *testing.T) {
+	cfg, err := config.LoadWithOptions(writeConfig(t, `
+services:
+  - name: db
+    image: postgres:16
+    ulimits:
+      nofile: 65536:65536
+      memlock: -1
+    sysctls:
+      net.core.somaxconn: 1024
+    extra_hosts: ["metrics.internal:10.0.0.9", "host.docker.internal:host-gateway"]
+    dns: [1.1.1.1, "2606:4700:4700::1111"]
+    dns_search: [corp.example.com]
+`), config.LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	db := cfg.Services[0]
+	if db.Ulimits["nofile"] != "65536:65536" || db.Ulimits["memlock"] != "-1" || db.Sysctls["net.core.somaxconn"] != "1024" ||
+		len(db.ExtraHosts) != 2 || len(db.DNS) != 2 || db.DNSSearch[0] != "corp.example.com" {
+		t.Errorf("db = %+v", db)
+	}
+	for _, body := range []string{
+		"services:\n  - name: db\n    image: postgres\n    ulimits:\n      files: 1024\n",
+		"services:\n  - name: db\n    image: postgres\n    ulimits:\n      nofile: 2048:1024\n",
+		"services:\n  - name: db\n    image: postgres\n    extra_hosts: [metrics.internal]\n",
+		"services:\n  - name: db\n    image: postgres\n    dns: [ns1.example.com]\n",
+	} {
+		if _, err := config.Load(writeConfig(t, body)); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestDependsOn(t *testing.T) {
+	if _, err := config.LoadWithOptions(writeConfig(t, `
+services:
+  - name: web
+    image: nginx
+    depends_on: [api]
+  - name: api
+    image: api
+    depends_on: [db]
+  - name: db
+    image: postgres
+`), config.LoadOptions{Strict: true}); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	_, err := config.Load(writeConfig(t, `
+services:
+  - name: web
+    image: nginx
+    depends_on: [api]
+  - name: api
+    image: api
+    depends_on: [web]
+`))
+	if err == nil || !strings.Contains(err.Error(), "web → api → web") {
+		t.Errorf("cycle: err = %v", err)
+	}
+	if _, err := config.Load(writeConfig(t, "services:\n  - name: web\n    image: nginx\n    depends_on: [cache]\n")); err == nil {
+		t.Error("expected an error for an unknown dependency")
+	}
+}
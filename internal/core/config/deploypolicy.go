@@ -0,0 +1,136 @@
+// Package config: deploy policy, which guards deploys to protected
+// environments with a typed confirmation, an approval webhook and
+// maintenance windows.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DeployPolicyConfig guards deploys to the environments in Protected; the
+// rest deploy freely.
+type DeployPolicyConfig struct {
+	Protected []string            `mapstructure:"protected"` // project.environment values the policy applies to
+	Confirm   bool                `mapstructure:"confirm"`   // type the environment's name, or pass --confirm <environment>
+	Approval  ApprovalConfig      `mapstructure:"approval"`
+	Windows   []MaintenanceWindow `mapstructure:"windows"` // when set, deploys only within one of them
+}
+
+// ApprovalConfig is a webhook asked to approve each deploy.
+type ApprovalConfig struct {
+	URL     string            `mapstructure:"url"`     // POSTed the deploy as JSON; a 2xx answer approves it
+	Headers map[string]string `mapstructure:"headers"` // e.g. Authorization; ${VAR} expanded
+	Timeout time.Duration     `mapstructure:"timeout"` // how long to wait for an answer; default 10m
+}
+
+// MaintenanceWindow is a time of day, on some days of the week, when
+// deploys are allowed.
+type MaintenanceWindow struct {
+	Days     []string `mapstructure:"days"`     // mon … sun; every day when empty
+	Start    string   `mapstructure:"start"`    // HH:MM
+	End      string   `mapstructure:"end"`      // HH:MM; earlier than start for a window past midnight
+	Timezone string   `mapstructure:"timezone"` // IANA name, e.g. Europe/Berlin; default UTC
+}
+
+// weekdays are the names Days takes, in time.Weekday order.
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Protects reports whether the policy applies to environment.
+func (p DeployPolicyConfig) Protects(environment string) bool {
+	return slices.ContainsFunc(p.Protected, func(e string) bool { return strings.EqualFold(e, environment) })
+}
+
+// Contains reports whether t falls within w. A window past midnight counts
+// from its start day: mon 22:00-02:00 includes Tuesday 01:00.
+func (w MaintenanceWindow) Contains(t time.Time) (bool, error) {
+	loc, start, end, err := w.parse()
+	if err != nil {
+		return false, err
+	}
+	t = t.In(loc)
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case start <= end:
+		return minute >= start && minute < end && w.onDay(day), nil
+	case minute >= start:
+		return w.onDay(day), nil
+	case minute < end:
+		return w.onDay((day + 6) % 7), nil
+	}
+	return false, nil
+}
+
+func (w MaintenanceWindow) String() string {
+	days := "daily"
+	if len(w.Days) > 0 {
+		days = strings.Join(w.Days, ",")
+	}
+	tz := w.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	return fmt.Sprintf("%s %s-%s %s", days, w.Start, w.End, tz)
+}
+
+// onDay reports whether w opens on day.
+func (w MaintenanceWindow) onDay(day time.Weekday) bool {
+	return len(w.Days) == 0 || slices.ContainsFunc(w.Days, func(d string) bool { return strings.EqualFold(d, weekdays[day]) })
+}
+
+// parse reads w's time zone and its start and end as minutes of the day.
+func (w MaintenanceWindow) parse() (loc *time.Location, start, end int, err error) {
+	loc = time.UTC
+	if w.Timezone != "" {
+		if loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return nil, 0, 0, fmt.Errorf("timezone %q: %w", w.Timezone, err)
+		}
+	}
+	if start, err = minuteOfDay(w.Start); err != nil {
+		return nil, 0, 0, fmt.Errorf("start: %w", err)
+	}
+	if end, err = minuteOfDay(w.End); err != nil {
+		return nil, 0, 0, fmt.Errorf("end: %w", err)
+	}
+	if start == end {
+		return nil, 0, 0, fmt.Errorf("start and end are both %s", w.Start)
+	}
+	return loc, start, end, nil
+}
+
+// minuteOfDay reads HH:MM.
+func minuteOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day (HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// validateDeployPolicy checks deploy_policy.
+func validateDeployPolicy(p DeployPolicyConfig) error {
+	if len(p.Protected) == 0 && (p.Confirm || p.Approval.URL != "" || len(p.Windows) > 0) {
+		return fmt.Errorf("deploy_policy.protected: name the environments the policy applies to")
+	}
+	if u, err := url.Parse(p.Approval.URL); p.Approval.URL != "" && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+		return fmt.Errorf("deploy_policy.approval.url: %q is not an http:// or https:// URL", p.Approval.URL)
+	}
+	if p.Approval.Timeout < 0 {
+		return fmt.Errorf("deploy_policy.approval.timeout must not be negative")
+	}
+	for i, w := range p.Windows {
+		if _, _, _, err := w.parse(); err != nil {
+			return fmt.Errorf("deploy_policy.windows[%d]: %w", i, err)
+		}
+		for _, d := range w.Days {
+			if !slices.Contains(weekdays, strings.ToLower(d)) {
+				return fmt.Errorf("deploy_policy.windows[%d]: unknown day %q (want mon, tue, wed, thu, fri, sat or sun)", i, d)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Package config: profiles, which keep optional services from starting
+// unless asked for.
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// EnvProfiles names the profiles to activate when no --profile is given,
+// comma-separated.
+const EnvProfiles = "ORBIT_PROFILES"
+
+// AllProfiles activates every profile.
+const AllProfiles = "*"
+
+var profileNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ProfilesFromEnv returns the profiles ORBIT_PROFILES names.
+func ProfilesFromEnv() []string {
+	var profiles []string
+	for _, p := range strings.Split(os.Getenv(EnvProfiles), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles
+}
+
+// ActiveServices returns the services to start with profiles active, in
+// orbit.yaml order: those in no profile, those in one of profiles, and the
+// services they depend on, whatever their profiles.
+func (c *Config) ActiveServices(profiles []string) []v1.ServiceSpec {
+	all := slices.Contains(profiles, AllProfiles)
+	active := map[string]bool{}
+	var activate func(name string)
+	activate = func(name string) {
+		if active[name] {
+			return
+		}
+		active[name] = true
+		if svc := c.ServiceByName(name); svc != nil {
+			for _, d := range svc.DependsOn {
+				activate(d)
+			}
+		}
+	}
+	for _, svc := range c.Services {
+		if all || len(svc.Profiles) == 0 || slices.ContainsFunc(svc.Profiles, func(p string) bool { return slices.Contains(profiles, p) }) {
+			activate(svc.Name)
+		}
+	}
+
+	var out []v1.ServiceSpec
+	for _, svc := range c.Services {
+		if active[svc.Name] {
+			out = append(out, svc)
+		}
+	}
+	return out
+}
+
+// Profiles returns the profiles services of c are in, sorted.
+func (c *Config) Profiles() []string {
+	var profiles []string
+	for _, svc := range c.Services {
+		for _, p := range svc.Profiles {
+			if !slices.Contains(profiles, p) {
+				profiles = append(profiles, p)
+			}
+		}
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// CheckProfiles rejects profiles no service of c is in, which are more
+// likely misspelt than meant to start nothing extra.
+func (c *Config) CheckProfiles(profiles []string) error {
+	known := c.Profiles()
+	for _, p := range profiles {
+		if p != AllProfiles && !slices.Contains(known, p) {
+			if len(known) == 0 {
+				return fmt.Errorf("no service is in profile %q: no service in orbit.yaml has profiles", p)
+			}
+			return fmt.Errorf("no service is in profile %q (profiles: %s)", p, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// validateProfiles checks the form of a service's profile names.
+func validateProfiles(svc v1.ServiceSpec) error {
+	for _, p := range svc.Profiles {
+		if !profileNameRegex.MatchString(p) {
+			return fmt.Errorf("service %q: profiles: invalid profile name %q (letters, digits, '_', '.' and '-', starting with a letter or digit)", svc.Name, p)
+		}
+	}
+	return nil
+}
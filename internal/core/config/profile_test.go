@@ -0,0 +1,52 @@
+package config_test
+
+import (
+	"slices"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+)
+
+func TestActiveServices(t *testing.T) {
+	cfg := &config.Config{Services: []v1.ServiceSpec{
+		{Name: "db", Image: "postgres", Profiles: []string{"data"}},
+		{Name: "web", Image: "nginx"},
+		{Name: "adminer", Image: "adminer", Profiles: []string{"debug", "admin"}, DependsOn: []string{"db"}},
+		{Name: "mailhog", Image: "mailhog", Profiles: []string{"debug"}},
+	}}
+	names := func(profiles ...string) []string {
+		var out []string
+		for _, s := range cfg.ActiveServices(profiles) {
+			out = append(out, s.Name)
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		profiles []string
+		want     []string
+	}{
+		{nil, []string{"web"}},
+		{[]string{"admin"}, []string{"db", "web", "adminer"}}, // with its dependency
+		{[]string{"debug"}, []string{"db", "web", "adminer", "mailhog"}},
+		{[]string{"*"}, []string{"db", "web", "adminer", "mailhog"}},
+	} {
+		if got := names(tc.profiles...); !slices.Equal(got, tc.want) {
+			t.Errorf("%v: got %v, want %v", tc.profiles, got, tc.want)
+		}
+	}
+
+	if err := cfg.CheckProfiles([]string{"debug", "*"}); err != nil {
+		t.Error(err)
+	}
+	if err := cfg.CheckProfiles([]string{"dbeug"}); err == nil {
+		t.Error("an unknown profile passed")
+	}
+}
+
+func TestProfileNames(t *testing.T) {
+	path := writeConfig(t, "services:\n  - name: web\n    image: nginx\n    profiles: [\"-x\"]\n")
+	if _, err := config.Load(path); err == nil {
+		t.Error("profile -x loaded")
+	}
+}
@@ -0,0 +1,232 @@
+// Package config: JSON Schema generation and strict unknown-key detection.
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// SchemaID is the $id advertised in the generated orbit.yaml JSON Schema.
+const SchemaID = "https://github.com/f9-o/orbit/schema/orbit.schema.json"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// commandType is a command line, given as a string or a list of arguments.
+var commandType = reflect.TypeOf(v1.ShellCommand(nil))
+
+// Schema returns a JSON Schema (draft 2020-12) describing orbit.yaml.
+// It is derived from the Config struct tree, so it never drifts from the loader.
+func Schema() map[string]any {
+	s := schemaFor(reflect.TypeOf(Config{}))
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["$id"] = SchemaID
+	s["title"] = "orbit.yaml"
+	return s
+}
+
+// schemaFor builds the schema node for a single Go type.
+func schemaFor(t reflect.Type) map[string]any {
+	if t == durationType {
+		return map[string]any{
+			"type":    "string",
+			"pattern": `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`,
+		}
+	}
+
+	if t == commandType {
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		}}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Struct:
+		props := map[string]any{}
+		for _, f := range structFields(t) {
+			props[f.key] = schemaFor(f.typ)
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+	default:
+		return map[string]any{}
+	}
+}
+
+// field is a decoded struct field keyed by its mapstructure name.
+type field struct {
+	key string
+	typ reflect.Type
+}
+
+// structFields lists the exported, mapstructure-tagged fields of t.
+func structFields(t reflect.Type) []field {
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("mapstructure")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(tag, ",squash") {
+			out = append(out, structFields(sf.Type)...)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		out = append(out, field{key: name, typ: sf.Type})
+	}
+	return out
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Strict mode
+// ─────────────────────────────────────────────────────────────────────────────
+
+// UnknownKeyError lists configuration keys that do not map to any field.
+type UnknownKeyError struct {
+	Keys  []string // dotted paths, e.g. "services[0].deploy.replcias"
+	Hints map[string]string
+}
+
+func (e *UnknownKeyError) Error() string {
+	parts := make([]string, 0, len(e.Keys))
+	for _, k := range e.Keys {
+		if hint, ok := e.Hints[k]; ok {
+			parts = append(parts, fmt.Sprintf("%s (did you mean %q?)", k, hint))
+			continue
+		}
+		parts = append(parts, k)
+	}
+	return "unknown config keys: " + strings.Join(parts, ", ")
+}
+
+// checkUnknownKeys walks raw settings against the Config type and returns an
+// *UnknownKeyError if any key is not recognised.
+func checkUnknownKeys(settings map[string]any) error {
+	e := &UnknownKeyError{Hints: map[string]string{}}
+	walkUnknown(settings, reflect.TypeOf(Config{}), "", e)
+	if len(e.Keys) == 0 {
+		return nil
+	}
+	sort.Strings(e.Keys)
+	return e
+}
+
+func walkUnknown(val any, t reflect.Type, path string, e *UnknownKeyError) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t == durationType {
+			return
+		}
+		m, ok := val.(map[string]any)
+		if !ok {
+			return
+		}
+		fields := structFields(t)
+		known := make(map[string]reflect.Type, len(fields))
+		names := make([]string, 0, len(fields))
+		for _, f := range fields {
+			known[f.key] = f.typ
+			names = append(names, f.key)
+		}
+		for k, v := range m {
+			p := joinPath(path, k)
+			ft, ok := known[strings.ToLower(k)]
+			if !ok {
+				e.Keys = append(e.Keys, p)
+				if hint := closest(k, names); hint != "" {
+					e.Hints[p] = hint
+				}
+				continue
+			}
+			walkUnknown(v, ft, p, e)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := val.([]any)
+		if !ok {
+			return
+		}
+		for i, item := range items {
+			walkUnknown(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), e)
+		}
+	case reflect.Map:
+		m, ok := val.(map[string]any)
+		if !ok {
+			return
+		}
+		for k, v := range m {
+			walkUnknown(v, t.Elem(), joinPath(path, k), e)
+		}
+	}
+}
+
+func joinPath(base, key string) string {
+	if base == "" {
+		return key
+	}
+	return base + "." + key
+}
+
+// closest returns the candidate within edit distance 2 of s, or "".
+func closest(s string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := levenshtein(strings.ToLower(s), c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
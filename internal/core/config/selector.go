@@ -0,0 +1,165 @@
+// Package config: label selectors, which pick services out by their labels.
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// Selector picks services by label. Each requirement must hold for a service
+// to match; an empty Selector matches every service.
+type Selector []Requirement
+
+// Requirement is one clause of a Selector.
+type Requirement struct {
+	Key   string
+	Op    string // SelectEquals, SelectNotEquals, SelectExists or SelectNotExists
+	Value string
+}
+
+// Selector operators.
+const (
+	SelectEquals    = "="
+	SelectNotEquals = "!="
+	SelectExists    = "exists"
+	SelectNotExists = "!exists"
+)
+
+// ParseSelector parses selector expressions such as "tier=backend",
+// "tier!=edge,canary" and "!canary": comma-separated requirements that a
+// label equals a value, differs from it (or is unset), is set, or is unset.
+// Requirements of every expression must hold.
+func ParseSelector(exprs ...string) (Selector, error) {
+	var sel Selector
+	for _, expr := range exprs {
+		for _, clause := range strings.Split(expr, ",") {
+			clause = strings.TrimSpace(clause)
+			if clause == "" {
+				continue
+			}
+			r, err := parseRequirement(clause)
+			if err != nil {
+				return nil, err
+			}
+			sel = append(sel, r)
+		}
+	}
+	return sel, nil
+}
+
+func parseRequirement(clause string) (Requirement, error) {
+	var r Requirement
+	switch {
+	case strings.Contains(clause, "!="):
+		r.Key, r.Value, _ = strings.Cut(clause, "!=")
+		r.Op = SelectNotEquals
+	case strings.Contains(clause, "="):
+		r.Key, r.Value, _ = strings.Cut(clause, "=")
+		r.Value = strings.TrimPrefix(r.Value, "=") // key==value
+		r.Op = SelectEquals
+	case strings.HasPrefix(clause, "!"):
+		r.Key, r.Op = clause[1:], SelectNotExists
+	default:
+		r.Key, r.Op = clause, SelectExists
+	}
+	r.Key, r.Value = strings.TrimSpace(r.Key), strings.TrimSpace(r.Value)
+	if r.Key == "" || strings.ContainsAny(r.Key, "=! ") || strings.Contains(r.Value, "=") {
+		return Requirement{}, fmt.Errorf("selector %q: want key=value, key!=value, key or !key", clause)
+	}
+	return r, nil
+}
+
+// Matches reports whether labels meet every requirement of s.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		v, ok := labels[r.Key]
+		switch r.Op {
+		case SelectEquals:
+			if !ok || v != r.Value {
+				return false
+			}
+		case SelectNotEquals:
+			if ok && v == r.Value {
+				return false
+			}
+		case SelectExists:
+			if !ok {
+				return false
+			}
+		case SelectNotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// String renders s as ParseSelector reads it.
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		switch r.Op {
+		case SelectExists:
+			parts[i] = r.Key
+		case SelectNotExists:
+			parts[i] = "!" + r.Key
+		default:
+			parts[i] = r.Key + r.Op + r.Value
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// ServiceLabels are the labels a selector matches a service against: those
+// of its spec, when there is one, and the orbit.service, orbit.node and
+// orbit.project labels orbit gives it.
+func ServiceLabels(spec *v1.ServiceSpec, name, node, project string) map[string]string {
+	labels := map[string]string{}
+	if spec != nil {
+		for k, v := range spec.Labels {
+			labels[k] = v
+		}
+	}
+	labels["orbit.service"] = name
+	if node != "" {
+		labels["orbit.node"] = node
+	}
+	if project != "" {
+		labels["orbit.project"] = project
+	}
+	return labels
+}
+
+// SelectServices returns the services of c that sel matches on node, as
+// services of project, in orbit.yaml order.
+func (c *Config) SelectServices(sel Selector, node, project string) []v1.ServiceSpec {
+	var out []v1.ServiceSpec
+	for i := range c.Services {
+		svc := &c.Services[i]
+		if sel.Matches(ServiceLabels(svc, svc.Name, node, project)) {
+			out = append(out, *svc)
+		}
+	}
+	return out
+}
+
+// LabelKeys returns the label keys set on any service of c, sorted, for
+// suggesting what a selector that matched nothing could have used.
+func (c *Config) LabelKeys() []string {
+	seen := map[string]bool{}
+	for _, svc := range c.Services {
+		for k := range svc.Labels {
+			seen[k] = true
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
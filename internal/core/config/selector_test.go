@@ -0,0 +1,67 @@
+package config_test
+
+import (
+	"slices"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+)
+
+func TestSelector(t *testing.T) {
+	labels := map[string]string{"tier": "backend", "team": "payments", "orbit.service": "api"}
+	for _, tc := range []struct {
+		exprs []string
+		want  bool
+	}{
+		{nil, true},
+		{[]string{"tier=backend"}, true},
+		{[]string{"tier==backend"}, true},
+		{[]string{"tier=frontend"}, false},
+		{[]string{"tier=backend,team=payments"}, true},
+		{[]string{"tier=backend", "team=search"}, false},
+		{[]string{"tier!=frontend"}, true},
+		{[]string{"region!=eu"}, true},
+		{[]string{"team"}, true},
+		{[]string{"!canary"}, true},
+		{[]string{"!team"}, false},
+		{[]string{"orbit.service=api"}, true},
+	} {
+		sel, err := config.ParseSelector(tc.exprs...)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.exprs, err)
+		}
+		if got := sel.Matches(labels); got != tc.want {
+			t.Errorf("%q matches = %v, want %v", tc.exprs, got, tc.want)
+		}
+		again, err := config.ParseSelector(sel.String())
+		if err != nil || again.String() != sel.String() {
+			t.Errorf("%q does not round-trip: %q, %v", sel, again, err)
+		}
+	}
+
+	for _, bad := range []string{"=backend", "!", "tier=a=b", "a b=c"} {
+		if _, err := config.ParseSelector(bad); err == nil {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+}
+
+func TestSelectServices(t *testing.T) {
+	cfg := &config.Config{Services: []v1.ServiceSpec{
+		{Name: "web", Labels: map[string]string{"tier": "frontend"}},
+		{Name: "api", Labels: map[string]string{"tier": "backend"}},
+		{Name: "worker", Labels: map[string]string{"tier": "backend", "canary": "true"}},
+	}}
+	sel, _ := config.ParseSelector("tier=backend,!canary")
+	if got := cfg.SelectServices(sel, "local", "shop"); len(got) != 1 || got[0].Name != "api" {
+		t.Errorf("tier=backend,!canary selected %+v", got)
+	}
+	sel, _ = config.ParseSelector("orbit.project=shop,orbit.node=local")
+	if got := cfg.SelectServices(sel, "local", "shop"); len(got) != 3 {
+		t.Errorf("orbit labels selected %d services", len(got))
+	}
+	if keys := cfg.LabelKeys(); !slices.Equal(keys, []string{"canary", "tier"}) {
+		t.Errorf("label keys = %v", keys)
+	}
+}
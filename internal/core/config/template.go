@@ -0,0 +1,119 @@
+// Package config: Go-template expansion of orbit.yaml with a `vars:` section.
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateFuncs are the helpers available inside orbit.yaml templates.
+//
+//	image: myapp:{{ .vars.version }}
+//	image: myapp:{{ env "CI_SHA" | default "latest" }}
+//	domain: {{ required "DOMAIN must be set" (env "DOMAIN") }}
+var templateFuncs = template.FuncMap{
+	"env": os.Getenv,
+	"default": func(def string, val any) string {
+		s := fmt.Sprint(val)
+		if val == nil || s == "" {
+			return def
+		}
+		return s
+	},
+	"required": func(msg string, val any) (string, error) {
+		s := fmt.Sprint(val)
+		if val == nil || s == "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return s, nil
+	},
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"trim":    strings.TrimSpace,
+	"replace": strings.ReplaceAll,
+}
+
+// RenderTemplate expands Go-template expressions in an orbit.yaml document.
+//
+// Rendering happens in two passes: the first pass renders with empty vars so
+// the `vars:` section can be parsed (vars themselves are therefore not
+// templated with other vars), the second renders the full document with
+// those vars (plus any overrides, which win) bound to `.vars`. `required`
+// only fails in the second pass, once vars are known.
+//
+// Whole-line comments are left as written, so commenting out a line that
+// holds an expression stops it being evaluated. A comment after a value on
+// the same line is rendered with it.
+func RenderTemplate(name string, raw []byte, overrides map[string]string) ([]byte, error) {
+	if !bytes.Contains(raw, []byte("{{")) && len(overrides) == 0 {
+		return raw, nil
+	}
+	raw = escapeComments(raw)
+
+	first, err := execTemplate(name, raw, discoveryFuncs, map[string]any{"vars": map[string]any{}}, "zero")
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Vars map[string]any `yaml:"vars"`
+	}
+	if err := yaml.Unmarshal(first, &doc); err != nil {
+		return nil, fmt.Errorf("parse vars: %w", err)
+	}
+	vars := doc.Vars
+	if vars == nil {
+		vars = map[string]any{}
+	}
+	for k, v := range overrides {
+		vars[k] = v
+	}
+
+	return execTemplate(name, raw, templateFuncs, map[string]any{"vars": vars}, "error")
+}
+
+// escapeComments quotes the "{{" of raw's whole-line YAML comments, so they
+// render as they are.
+func escapeComments(raw []byte) []byte {
+	lines := bytes.SplitAfter(raw, []byte("\n"))
+	for i, line := range lines {
+		if bytes.HasPrefix(bytes.TrimLeft(line, " \t"), []byte("#")) {
+			lines[i] = bytes.ReplaceAll(line, []byte("{{"), []byte(`{{"{{"}}`))
+		}
+	}
+	return bytes.Join(lines, nil)
+}
+
+// discoveryFuncs are templateFuncs for the vars-discovery pass, where every
+// var is still empty: `required` passes its value through instead of failing,
+// a placeholder for nothing as missing keys render, so the YAML still parses.
+var discoveryFuncs = func() template.FuncMap {
+	funcs := template.FuncMap{}
+	for name, fn := range templateFuncs {
+		funcs[name] = fn
+	}
+	funcs["required"] = func(_ string, val any) string {
+		return fmt.Sprint(val)
+	}
+	return funcs
+}()
+
+// execTemplate renders raw with funcs and data. missingKey is the text/template
+// missingkey option: "zero" for the vars-discovery pass, "error" for the final
+// pass so references to undefined vars are reported instead of rendered empty.
+func execTemplate(name string, raw []byte, funcs template.FuncMap, data map[string]any, missingKey string) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=" + missingKey).Parse(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,59 @@
+// Package logger: reading back the audit trail.
+package logger
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// AuditFile is the name of the audit log in the Orbit home directory.
+const AuditFile = "audit.log"
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Since   time.Time // entries at or after this time
+	RunID   string
+	Op      string    // an op, or a command group: "nodes" matches "nodes add"
+	User    string
+	Node    string
+	Service string
+	Failed  bool // only entries whose result is failure
+}
+
+// Match reports whether e passes the filter.
+func (f AuditFilter) Match(e AuditEntry) bool {
+	switch {
+	case !f.Since.IsZero() && e.Timestamp.Before(f.Since):
+		return false
+	case f.Op != "" && e.Op != f.Op && !strings.HasPrefix(e.Op, f.Op+" "):
+		return false
+	case f.RunID != "" && e.RunID != f.RunID:
+		return false
+	case f.User != "" && e.User != f.User:
+		return false
+	case f.Node != "" && e.Node != f.Node:
+		return false
+	case f.Service != "" && e.Service != f.Service:
+		return false
+	case f.Failed && e.Result != "failure":
+		return false
+	}
+	return true
+}
+
+// ReadAudit returns the entries of the audit log at path, and of its rotated
+// files, that match f, oldest first. Lines that are not JSON are skipped.
+func ReadAudit(path string, f AuditFilter) ([]AuditEntry, error) {
+	var out []AuditEntry
+	err := ScanRotated(path, f.Since, func(line []byte) {
+		var e AuditEntry
+		if json.Unmarshal(line, &e) == nil && f.Match(e) {
+			out = append(out, e)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
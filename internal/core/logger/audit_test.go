@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditWritesJSON(t *testing.T) {
+	home := t.TempDir()
+	log, err := Init("error", "text", "", home, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := map[string]string{"image": "api:v2", "token": "s3cret"}
+	log.Audit(AuditEntry{
+		Op:       "deploy",
+		Service:  `api "v2"`,
+		Args:     []string{"deploy", "api", "--tag", "v2"},
+		ExitCode: 1,
+		Error:    "health check failed",
+		Meta:     meta,
+	})
+
+	data, err := os.ReadFile(filepath.Join(home, AuditFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got AuditEntry
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("audit line %q: %v", data, err)
+	}
+	if got.Timestamp.IsZero() || got.User == "" || got.Result != "failure" || got.Service != `api "v2"` {
+		t.Errorf("entry = %+v", got)
+	}
+	if !reflect.DeepEqual(got.Meta, map[string]string{"image": "api:v2", "token": Redacted}) || len(got.Args) != 4 {
+		t.Errorf("meta/args = %v %v", got.Meta, got.Args)
+	}
+	if meta["token"] != "s3cret" {
+		t.Errorf("Audit changed the caller's meta: %v", meta)
+	}
+}
+
+func TestRunIDOnRecordsAndAudit(t *testing.T) {
+	home := t.TempDir()
+	logFile := filepath.Join(home, "logs", "orbit.log")
+	SetRunID("0123456789ab")
+	defer SetRunID("")
+	log, err := Init("info", "text", logFile, home, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Info("deploy.start", "service", "api")
+	log.Audit(AuditEntry{Op: "deploy"})
+
+	data, _ := os.ReadFile(logFile)
+	if !strings.Contains(string(data), "run_id=0123456789ab") {
+		t.Errorf("log record without run ID: %s", data)
+	}
+	entries, err := ReadAudit(filepath.Join(home, AuditFile), AuditFilter{RunID: "0123456789ab"})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("audit entries for run = %v, %v", entries, err)
+	}
+	if id := NewRunID(); len(id) != 12 || id == NewRunID() {
+		t.Errorf("NewRunID = %q", id)
+	}
+}
+
+func TestReadAuditAcrossRotations(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, AuditFile)
+	w, err := OpenRotating(path, Rotation{MaxSizeMB: 1, MaxBackups: -1, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := &Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), auditW: w}
+	start := time.Now().Add(-48 * time.Hour)
+	ops := []string{"deploy", "nodes add", "scale", "deploy"}
+	for i := 0; i < 4000; i++ { // enough to rotate a couple of times
+		log.Audit(AuditEntry{
+			Timestamp: start.Add(time.Duration(i) * 43 * time.Second),
+			Op:        ops[i%len(ops)],
+			Service:   "api",
+			Args:      []string{strings.Repeat("x", 400)},
+			ExitCode:  i % 2,
+		})
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.backups()) == 0 {
+		t.Fatal("audit log never rotated")
+	}
+
+	all, err := ReadAudit(path, AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4000 || !all[0].Timestamp.Equal(start) {
+		t.Fatalf("read %d entries, first at %v", len(all), all[0].Timestamp)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Timestamp.Before(all[i-1].Timestamp) {
+			t.Fatalf("entry %d out of order", i)
+		}
+	}
+
+	deploys, _ := ReadAudit(path, AuditFilter{Op: "deploy", Failed: true})
+	if len(deploys) != 1000 {
+		t.Errorf("failed deploys = %d, want 1000", len(deploys))
+	}
+	nodes, _ := ReadAudit(path, AuditFilter{Op: "nodes"})
+	if len(nodes) != 1000 || nodes[0].Op != "nodes add" {
+		t.Errorf("nodes group = %d", len(nodes))
+	}
+	since := start.Add(40 * time.Hour)
+	recent, _ := ReadAudit(path, AuditFilter{Since: since})
+	for _, e := range recent {
+		if e.Timestamp.Before(since) {
+			t.Fatalf("%v before --since %v", e.Timestamp, since)
+		}
+	}
+	if len(recent) == 0 || len(recent) == len(all) {
+		t.Errorf("since kept %d of %d", len(recent), len(all))
+	}
+}
@@ -1,14 +1,18 @@
 // Package logger provides the structured logging engine for Orbit.
-// Uses log/slog with support for multiple sinks: stderr, file, TUI.
-// Log file rotation is handled via a simple size-checked os.File writer —
+// Uses log/slog with support for multiple sinks: stderr, file, TUI; secrets
+// are masked before records reach any of them.
+// Log file rotation is handled by a size-checked os.File writer, RotatingFile —
 // no external dependencies required.
 package logger
 
 import (
-	"fmt"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"io"
 	"log/slog"
 	"os"
+	"os/user"
 	"path/filepath"
 	"sync"
 	"time"
@@ -23,6 +27,7 @@ type Logger struct {
 	*slog.Logger
 	tuiSink chan<- string // non-nil when TUI is active
 	auditW  io.Writer     // append-only audit log writer (nil = disabled)
+	runID   string        // attached to every record and audit entry
 }
 
 // TUISink returns a channel that receives formatted log lines for TUI display.
@@ -34,6 +39,27 @@ func SetTUISink(ch chan string) {
 	tuiSinkCh = ch
 }
 
+// runID identifies this invocation of orbit in logs, errors and history.
+var runID string
+
+// NewRunID returns a random ID for one invocation of orbit.
+func NewRunID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SetRunID sets the run ID attached to every log record and audit entry as
+// run_id. Call it before Init.
+func SetRunID(id string) {
+	runID = id
+}
+
+// RunID returns the run ID of the logger's records, or "".
+func (l *Logger) RunID() string {
+	return l.runID
+}
+
 // Init initialises the global logger. Safe to call multiple times (idempotent after first call).
 func Init(level, format, logFile, orbitHome string, debug bool) (*Logger, error) {
 	var lvl slog.Level
@@ -54,17 +80,13 @@ func Init(level, format, logFile, orbitHome string, debug bool) (*Logger, error)
 	// Build multi-writer: always write to stderr, optionally to file
 	writers := []io.Writer{os.Stderr}
 
-	var fileWriter io.Writer
 	if logFile != "" {
 		if err := os.MkdirAll(filepath.Dir(logFile), 0750); err == nil {
-			f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
-			if err == nil {
-				fileWriter = f
+			if f, err := OpenRotating(logFile, rotation); err == nil {
 				writers = append(writers, f)
 			}
 		}
 	}
-	_ = fileWriter
 
 	// TUI sink: forward log lines to channel
 	if tuiSinkCh != nil {
@@ -81,14 +103,17 @@ func Init(level, format, logFile, orbitHome string, debug bool) (*Logger, error)
 		handler = slog.NewTextHandler(out, opts)
 	}
 
-	base := slog.New(handler)
+	base := slog.New(&redactHandler{next: handler})
+	if runID != "" {
+		base = base.With("run_id", runID)
+	}
 	slog.SetDefault(base)
 
 	// Audit log
 	var auditW io.Writer
 	if orbitHome != "" {
-		auditPath := filepath.Join(orbitHome, "audit.log")
-		if af, err := os.OpenFile(auditPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640); err == nil {
+		auditPath := filepath.Join(orbitHome, AuditFile)
+		if af, err := OpenRotating(auditPath, rotation); err == nil {
 			auditW = af
 		}
 	}
@@ -97,6 +122,7 @@ func Init(level, format, logFile, orbitHome string, debug bool) (*Logger, error)
 		Logger:  base,
 		tuiSink: nil,
 		auditW:  auditW,
+		runID:   runID,
 	}, nil
 }
 
@@ -104,34 +130,86 @@ func Init(level, format, logFile, orbitHome string, debug bool) (*Logger, error)
 // Audit logging
 // ─────────────────────────────────────────────────────────────────────────────
 
-// AuditEntry represents a single audit log event.
+// AuditEntry represents a single audit log event, one JSON object per line
+// of audit.log.
 type AuditEntry struct {
 	Timestamp time.Time         `json:"ts"`
-	Op        string            `json:"op"`
+	RunID     string            `json:"run_id,omitempty"`
+	Op        string            `json:"op"` // command path, e.g. "deploy" or "nodes add"
 	User      string            `json:"user"`
 	Node      string            `json:"node,omitempty"`
 	Service   string            `json:"service,omitempty"`
-	Result    string            `json:"result"` // success | failure
+	Args      []string          `json:"args,omitempty"` // command line, without the program name
+	Result    string            `json:"result"`         // success | failure
+	ExitCode  int               `json:"exit_code"`
+	Error     string            `json:"error,omitempty"`
 	Meta      map[string]string `json:"meta,omitempty"`
 }
 
-// Audit writes an append-only audit log entry.
+// Audit writes an append-only audit log entry. A zero Timestamp is now and
+// an empty User the OS user running Orbit. Secrets in Args and Meta are
+// masked.
 func (l *Logger) Audit(entry AuditEntry) {
-	l.Info("audit",
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.Timestamp = entry.Timestamp.UTC()
+	if entry.User == "" {
+		entry.User = CurrentUser()
+	}
+	if entry.RunID == "" {
+		entry.RunID = l.runID
+	}
+	entry.Args = redactArgs(entry.Args)
+	entry.Meta = redactMeta(entry.Meta)
+	if entry.Result == "" {
+		entry.Result = "success"
+		if entry.ExitCode != 0 || entry.Error != "" {
+			entry.Result = "failure"
+		}
+	}
+	l.Debug("audit",
 		"op", entry.Op,
 		"user", entry.User,
 		"node", entry.Node,
 		"service", entry.Service,
 		"result", entry.Result,
+		"exit_code", entry.ExitCode,
 	)
 	if l.auditW == nil {
 		return
 	}
-	line := fmt.Sprintf(`{"ts":%q,"op":%q,"user":%q,"node":%q,"service":%q,"result":%q}`+"\n",
-		entry.Timestamp.UTC().Format(time.RFC3339),
-		entry.Op, entry.User, entry.Node, entry.Service, entry.Result,
-	)
-	_, _ = l.auditW.Write([]byte(line))
+	line, err := json.Marshal(entry)
+	if err != nil {
+		l.Warn("audit: encode entry", "op", entry.Op, "err", err)
+		return
+	}
+	_, _ = l.auditW.Write(append(line, '\n'))
+}
+
+// redactMeta returns a copy of meta with sensitive values masked, leaving
+// the caller's map alone.
+func redactMeta(meta map[string]string) map[string]string {
+	if meta == nil {
+		return nil
+	}
+	out := make(map[string]string, len(meta))
+	for k, v := range meta {
+		if SensitiveName(k) && v != "" {
+			v = Redacted
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// CurrentUser names the OS user running Orbit, as audit entries and
+// approval requests record it, falling back to $USER.
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
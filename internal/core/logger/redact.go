@@ -0,0 +1,130 @@
+// Package logger: masking of secrets in log records.
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/f9-o/orbit/internal/core/config"
+)
+
+// Redacted replaces the value of a sensitive attribute.
+const Redacted = "[redacted]"
+
+// sensitiveEnvNames are environment variables that carry credentials
+// without saying so in their name.
+var sensitiveEnvNames = map[string]bool{
+	"DATABASE_URL":  true,
+	"REDIS_URL":     true,
+	"MONGODB_URI":   true,
+	"AMQP_URL":      true,
+	"DSN":           true,
+	"SENTRY_DSN":    true,
+	"AUTHORIZATION": true,
+	"COOKIE":        true,
+	"CREDENTIALS":   true,
+	"PGPASS":        true,
+}
+
+// SensitiveName reports whether an attribute key or environment variable
+// name holds a secret.
+func SensitiveName(name string) bool {
+	return sensitiveEnvNames[strings.ToUpper(name)] || config.IsSensitiveKey(name)
+}
+
+// redactHandler masks secrets before records reach the wrapped handler:
+// values of attributes with sensitive keys, and values in NAME=value strings
+// (environment entries) with sensitive names.
+type redactHandler struct {
+	next slog.Handler
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = redactAttr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(masked)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch {
+	case v.Kind() == slog.KindGroup:
+		group := v.Group()
+		masked := make([]any, len(group))
+		for i, g := range group {
+			masked[i] = redactAttr(g)
+		}
+		return slog.Group(a.Key, masked...)
+	case SensitiveName(a.Key) && !isEmpty(v):
+		return slog.String(a.Key, Redacted)
+	case v.Kind() == slog.KindString:
+		return slog.String(a.Key, redactEnv(v.String()))
+	case v.Kind() == slog.KindAny:
+		switch x := v.Any().(type) {
+		case []string:
+			masked := make([]string, len(x))
+			for i, s := range x {
+				masked[i] = redactEnv(s)
+			}
+			return slog.Any(a.Key, masked)
+		case map[string]string:
+			masked := make(map[string]string, len(x))
+			for k, s := range x {
+				if SensitiveName(k) && s != "" {
+					s = Redacted
+				}
+				masked[k] = s
+			}
+			return slog.Any(a.Key, masked)
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// redactEnv masks the value of s when s is a NAME=value environment entry
+// with a sensitive name.
+func redactEnv(s string) string {
+	name, val, ok := strings.Cut(s, "=")
+	if !ok || val == "" || strings.ContainsAny(name, " \t\n") || !SensitiveName(name) {
+		return s
+	}
+	return name + "=" + Redacted
+}
+
+// redactArgs masks secrets on a command line: NAME=value entries as in
+// redactEnv, and the value following a sensitive flag ("--password x").
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		out[i] = redactEnv(a)
+		if i > 0 && strings.HasPrefix(args[i-1], "-") && !strings.Contains(args[i-1], "=") &&
+			!strings.HasPrefix(a, "-") && SensitiveName(strings.TrimLeft(args[i-1], "-")) {
+			out[i] = Redacted
+		}
+	}
+	return out
+}
+
+func isEmpty(v slog.Value) bool {
+	return v.Kind() == slog.KindString && v.String() == ""
+}
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(&redactHandler{next: slog.NewTextHandler(&buf, nil)}).
+		With("api_token", "t0k3n")
+	log.Info("deploy",
+		"service", "api",
+		"password", "hunter2",
+		"env", []string{"DB_PASSWORD=hunter2", "PORT=8080", "DATABASE_URL=postgres://u:p@db/app"},
+		"cmd", "AWS_SECRET_ACCESS_KEY=abc",
+		slog.Group("registry", "user", "ci", "secret", "s3cr3t"),
+		"labels", map[string]string{"stripe_key": "sk_live", "team": "core"},
+		"key_file", "",
+	)
+	out := buf.String()
+	for _, secret := range []string{"t0k3n", "hunter2", "postgres://", "abc", "s3cr3t", "sk_live"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%q leaked: %s", secret, out)
+		}
+	}
+	for _, kept := range []string{"service=api", "PORT=8080", "registry.user=ci", "team:core", "key_file=\"\""} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("%q missing: %s", kept, out)
+		}
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	got := redactArgs([]string{"nodes", "add", "web", "--password", "hunter2", "--var", "db_password=x", "--token=abc", "--node", "n1"})
+	want := []string{"nodes", "add", "web", "--password", Redacted, "--var", "db_password=" + Redacted, "--token=" + Redacted, "--node", "n1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("redactArgs = %q", got)
+	}
+}
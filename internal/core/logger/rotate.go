@@ -0,0 +1,316 @@
+// Package logger: size-based rotation and retention of log files.
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotation defaults, used for zero Rotation fields.
+const (
+	DefaultMaxSizeMB  = 50
+	DefaultMaxBackups = 5
+)
+
+// backupTimeFormat stamps rotated files: orbit.log becomes
+// orbit-2024-05-01T10-04-05.000.log. It sorts chronologically.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// reopenCheck is how often a RotatingFile checks whether another process
+// has rotated its file away.
+const reopenCheck = time.Second
+
+// Rotation controls when log files are rotated and how many rotated files
+// are kept.
+type Rotation struct {
+	MaxSizeMB  int           // rotate before the file would grow past this; 0 = DefaultMaxSizeMB, <0 = never
+	MaxBackups int           // rotated files to keep; 0 = DefaultMaxBackups, <0 = keep all
+	MaxAge     time.Duration // delete rotated files older than this; 0 = no age limit
+	Compress   bool          // gzip rotated files
+}
+
+var rotation Rotation
+
+// SetRotation sets how the log and audit files are rotated. Call it before Init.
+func SetRotation(r Rotation) {
+	rotation = r
+}
+
+// ConfiguredRotation returns the Rotation set with SetRotation, for other
+// log files kept alongside orbit.log.
+func ConfiguredRotation() Rotation {
+	return rotation
+}
+
+// RotatingFile is an append-only file writer that renames the file aside
+// once it reaches the size limit and starts a new one, pruning old rotated
+// files. Several processes may write to the same file: each notices when
+// another has rotated it and reopens.
+type RotatingFile struct {
+	path string
+	rot  Rotation
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	checked time.Time
+	mill    sync.WaitGroup // compression and pruning in flight
+	millMu  sync.Mutex     // runs them one rotation at a time
+}
+
+// OpenRotating opens path for appending, creating it if needed.
+func OpenRotating(path string, rot Rotation) (*RotatingFile, error) {
+	if rot.MaxSizeMB == 0 {
+		rot.MaxSizeMB = DefaultMaxSizeMB
+	}
+	if rot.MaxBackups == 0 {
+		rot.MaxBackups = DefaultMaxBackups
+	}
+	w := &RotatingFile{path: path, rot: rot}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingFile) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f, w.size, w.checked = f, info.Size(), time.Now()
+	return nil
+}
+
+// Write appends p, rotating first when p would take the file past the limit.
+func (w *RotatingFile) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if time.Since(w.checked) >= reopenCheck {
+		w.reopenIfMoved()
+	}
+	if w.full(len(p)) {
+		// Another process may have rotated the file already.
+		w.reopenIfMoved()
+		if w.full(len(p)) {
+			if err := w.rotate(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// full reports whether writing n more bytes would take a non-empty file past
+// the size limit.
+func (w *RotatingFile) full(n int) bool {
+	return w.rot.MaxSizeMB > 0 && w.size > 0 && w.size+int64(n) > int64(w.rot.MaxSizeMB)<<20
+}
+
+// Close closes the file, waiting for any compression in progress.
+func (w *RotatingFile) Close() error {
+	w.mu.Lock()
+	err := w.f.Close()
+	w.mu.Unlock()
+	w.mill.Wait()
+	return err
+}
+
+// reopenIfMoved switches to a fresh file when the path no longer names the
+// open file, i.e. another process rotated it.
+func (w *RotatingFile) reopenIfMoved() {
+	w.checked = time.Now()
+	cur, err1 := w.f.Stat()
+	onDisk, err2 := os.Stat(w.path)
+	if err1 == nil && err2 == nil && os.SameFile(cur, onDisk) {
+		w.size = cur.Size()
+		return
+	}
+	old := w.f
+	if err := w.open(); err == nil {
+		old.Close()
+	}
+}
+
+// rotate renames the current file aside, opens a new one and hands the
+// rotated file to the mill.
+func (w *RotatingFile) rotate() error {
+	ext := filepath.Ext(w.path)
+	stamp := time.Now()
+	backup := ""
+	for {
+		backup = strings.TrimSuffix(w.path, ext) + "-" + stamp.Format(backupTimeFormat) + ext
+		_, err1 := os.Stat(backup)
+		_, err2 := os.Stat(backup + ".gz")
+		if os.IsNotExist(err1) && os.IsNotExist(err2) {
+			break
+		}
+		stamp = stamp.Add(time.Millisecond) // rotated twice within a millisecond
+	}
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(w.path, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotate %s: %w", w.path, err)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.mill.Add(1)
+	go func() {
+		defer w.mill.Done()
+		w.millMu.Lock()
+		defer w.millMu.Unlock()
+		if w.rot.Compress {
+			_ = compressFile(backup)
+		}
+		w.prune()
+	}()
+	return nil
+}
+
+// prune deletes rotated files beyond MaxBackups or older than MaxAge.
+func (w *RotatingFile) prune() {
+	backups := w.backups()
+	cutoff := time.Time{}
+	if w.rot.MaxAge > 0 {
+		cutoff = time.Now().Add(-w.rot.MaxAge)
+	}
+	for i, b := range backups { // newest first
+		if (w.rot.MaxBackups > 0 && i >= w.rot.MaxBackups) || (!cutoff.IsZero() && b.stamp.Before(cutoff)) {
+			_ = os.Remove(b.path)
+		}
+	}
+}
+
+type backupFile struct {
+	path  string
+	stamp time.Time
+}
+
+// backups lists the rotated files of w.path, newest first.
+func (w *RotatingFile) backups() []backupFile {
+	return rotatedFiles(w.path)
+}
+
+// rotatedFiles lists the rotated files of path, newest first. A file caught
+// mid-compression is listed once, as its .gz.
+func rotatedFiles(path string) []backupFile {
+	ext := filepath.Ext(path)
+	prefix := filepath.Base(strings.TrimSuffix(path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+	var out []backupFile
+	seen := map[string]int{}
+	for _, e := range entries {
+		name := e.Name()
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		rest = strings.TrimSuffix(strings.TrimSuffix(rest, ".gz"), ext)
+		stamp, err := time.ParseInLocation(backupTimeFormat, rest, time.Local)
+		if err != nil {
+			continue
+		}
+		b := backupFile{path: filepath.Join(filepath.Dir(path), name), stamp: stamp}
+		if i, ok := seen[rest]; ok {
+			if strings.HasSuffix(name, ".gz") {
+				out[i] = b
+			}
+			continue
+		}
+		seen[rest] = len(out)
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].stamp.After(out[j].stamp) })
+	return out
+}
+
+// ScanRotated calls fn with each line of the log at path and of its rotated
+// files, oldest first. Rotated files renamed aside before since are skipped:
+// everything in them was written earlier.
+func ScanRotated(path string, since time.Time, fn func(line []byte)) error {
+	rotated := rotatedFiles(path)
+	files := make([]string, 0, len(rotated)+1)
+	for i := len(rotated) - 1; i >= 0; i-- {
+		if since.IsZero() || !rotated[i].stamp.Before(since) {
+			files = append(files, rotated[i].path)
+		}
+	}
+	for _, f := range append(files, path) {
+		if err := scanFile(f, fn); err != nil && !os.IsNotExist(err) { // pruned meanwhile
+			return err
+		}
+	}
+	return nil
+}
+
+func scanFile(path string, fn func(line []byte)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		fn(sc.Bytes())
+	}
+	return sc.Err()
+}
+
+// compressFile gzips path to path.gz and removes the original.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileRotatesAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orbit.log")
+	w, err := OpenRotating(path, Rotation{MaxSizeMB: 1, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := bytes.Repeat([]byte("x"), 600<<10)
+	for i := 0; i < 5; i++ {
+		chunk[0] = byte('0' + i)
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 5 writes of 600 KiB against a 1 MiB limit: a new file every write, of
+	// which the current one and the two newest rotated ones remain.
+	backups := w.backups()
+	if len(backups) != 2 {
+		t.Fatalf("backups = %v", backups)
+	}
+	for i, b := range backups {
+		if !strings.HasSuffix(b.path, ".log.gz") {
+			t.Fatalf("%s not compressed", b.path)
+		}
+		f, _ := os.Open(b.path)
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(zr)
+		f.Close()
+		if want := byte('3' - i); len(data) != len(chunk) || data[0] != want {
+			t.Errorf("%s holds write %c, want %c", b.path, data[0], want)
+		}
+	}
+	if data, _ := os.ReadFile(path); len(data) != len(chunk) || data[0] != '4' {
+		t.Errorf("current file holds %d bytes starting %q", len(data), data[:1])
+	}
+}
+
+func TestRotatingFilePrunesByAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	old := filepath.Join(dir, "audit-"+time.Now().Add(-48*time.Hour).Format(backupTimeFormat)+".log")
+	recent := filepath.Join(dir, "audit-"+time.Now().Add(-time.Hour).Format(backupTimeFormat)+".log.gz")
+	os.WriteFile(old, []byte("old"), 0640)
+	os.WriteFile(recent, []byte("recent"), 0640)
+
+	w, err := OpenRotating(path, Rotation{MaxSizeMB: -1, MaxBackups: -1, MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.prune()
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("backup older than MaxAge kept")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Error("recent backup removed")
+	}
+	w.Close()
+}
+
+func TestRotatingFileFollowsOtherProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orbit.log")
+	a, _ := OpenRotating(path, Rotation{})
+	b, _ := OpenRotating(path, Rotation{})
+	defer a.Close()
+	defer b.Close()
+
+	a.Write([]byte("a1\n"))
+	a.mu.Lock()
+	a.rotate()
+	a.mu.Unlock()
+
+	b.checked = time.Time{} // as if reopenCheck had passed
+	b.Write([]byte("b1\n"))
+	if data, _ := os.ReadFile(path); string(data) != "b1\n" {
+		t.Errorf("new file = %q, want b's write after a rotated", data)
+	}
+}
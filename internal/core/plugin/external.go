@@ -0,0 +1,62 @@
+// Package plugin: out-of-process plugins started as separate binaries.
+package plugin
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/pluginrpc"
+)
+
+// startExternal starts the plugin binary at path.
+func (h *Host) startExternal(path string) (v1.PluginV1, error) {
+	c, err := pluginrpc.Launch(path, h.pluginLog(path))
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// stopExternal stops impl's process if it is an out-of-process plugin that
+// failed to register, or releases its runtime if it is a WASM plugin; a .so
+// stays mapped regardless.
+func stopExternal(impl v1.PluginV1) {
+	switch p := impl.(type) {
+	case *pluginrpc.Client:
+		p.Kill()
+	case *wasmPlugin:
+		p.close()
+	}
+}
+
+// isExecutable reports whether e is a regular file the host may run. On
+// Windows, where there is no exec bit, that means an .exe.
+func isExecutable(e fs.DirEntry) bool {
+	info, err := e.Info()
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(info.Name()), ".exe")
+	}
+	return info.Mode().Perm()&0o111 != 0
+}
+
+// pluginLog returns a writer that logs each line a plugin prints at debug
+// level, tagged with its path.
+func (h *Host) pluginLog(path string) io.Writer {
+	r, w := io.Pipe()
+	go func() {
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			h.log.Debug("plugin output", "path", path, "line", sc.Text())
+		}
+		_, _ = io.Copy(io.Discard, r)
+	}()
+	return w
+}
@@ -1,57 +1,207 @@
 // Package plugin implements the Orbit plugin host.
-// Plugins are loaded from ~/.orbit/plugins/ as Go shared objects (.so files).
-// Each .so must export an "OrbitPlugin" symbol implementing api/v1.PluginV1.
+// Plugins are loaded from ~/.orbit/plugins/ either as Go shared objects (.so
+// files) exporting an "OrbitPlugin" symbol implementing api/v1.PluginV1, or
+// as standalone executables serving the same interface over gRPC through
+// pkg/pluginrpc, or as WebAssembly modules (.wasm) run sandboxed in wazero.
+// The plugins: section of orbit.yaml enables or disables each plugin and
+// supplies the config passed to its Init.
 package plugin
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
 	"plugin"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
 	"github.com/f9-o/orbit/internal/core/logger"
 )
 
+// DefaultHookTimeout bounds one hook call of a plugin whose settings do not
+// set timeout.
+const DefaultHookTimeout = 30 * time.Second
+
+// errDisabled marks a plugin skipped because its settings disable it.
+var errDisabled = errors.New("disabled")
+
+// Plugin statuses reported in PluginInfo.
+const (
+	StatusLoaded   = "loaded"
+	StatusDisabled = "disabled"
+	StatusFailed   = "failed"
+)
+
+// Plugin kinds reported in PluginInfo.
+const (
+	KindSharedObject = "so"
+	KindBinary       = "binary"
+	KindWASM         = "wasm"
+)
+
+// PluginInfo describes one plugin file found by LoadDir and what became of it.
+type PluginInfo struct {
+	File       string   `json:"file"`
+	Kind       string   `json:"kind"`
+	Name       string   `json:"name,omitempty"` // empty when the plugin never started
+	Version    string   `json:"version,omitempty"`
+	APIVersion string   `json:"api_version,omitempty"`
+	Hooks      []string `json:"hooks,omitempty"`
+	Status     string   `json:"status"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// hookEntry is one plugin's subscription to a hook.
+type hookEntry struct {
+	plugin  string
+	fn      v1.HookFunc
+	timeout time.Duration
+}
+
 // Host manages plugin lifecycle and hook dispatch.
 type Host struct {
-	mu      sync.RWMutex
-	plugins map[string]v1.PluginV1   // name → plugin
-	hooks   map[string][]v1.HookFunc // hookName → ordered list
-	log     *logger.Logger
+	mu       sync.RWMutex
+	plugins  map[string]v1.PluginV1 // name → plugin
+	hooks    map[string][]hookEntry // hookName → ordered list
+	hookSubs map[string][]string    // name → hook names, sorted
+	infos    []PluginInfo           // one per file seen by LoadDir
+	settings map[string]config.PluginConfig
+	log      *logger.Logger
 }
 
 // NewHost creates and returns an empty plugin host.
 func NewHost(log *logger.Logger) *Host {
 	return &Host{
-		plugins: make(map[string]v1.PluginV1),
-		hooks:   make(map[string][]v1.HookFunc),
-		log:     log,
+		plugins:  make(map[string]v1.PluginV1),
+		hooks:    make(map[string][]hookEntry),
+		hookSubs: make(map[string][]string),
+		log:      log,
 	}
 }
 
-// LoadDir scans dir for *.so files and attempts to load each as an Orbit plugin.
-// Load failures are logged and skipped — they never abort the host startup.
+// WithConfig supplies the plugins: section of orbit.yaml, with any
+// `orbit plugin enable/disable` overrides applied; call it before LoadDir.
+func (h *Host) WithConfig(settings map[string]config.PluginConfig) *Host {
+	h.settings = settings
+	return h
+}
+
+// LoadDir scans dir for *.so files and executables and attempts to load each
+// as an Orbit plugin. Load failures are logged and skipped — they never abort
+// the host startup.
 func (h *Host) LoadDir(dir string) error {
-	matches, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("glob plugins: %w", err)
+		return fmt.Errorf("read plugin dir: %w", err)
 	}
 
-	for _, path := range matches {
-		if err := h.loadPlugin(path); err != nil {
-			h.log.Warn("plugin load failed, skipping",
-				"path", path,
-				"err", err,
-			)
+	for _, e := range entries {
+		if kindOf(e.Name()) == KindBinary && !isExecutable(e) {
+			continue
 		}
+		h.LoadFile(filepath.Join(dir, e.Name()))
 	}
 	return nil
 }
 
-// loadPlugin opens a single .so file and registers its hooks.
-func (h *Host) loadPlugin(path string) (retErr error) {
+// LoadFile loads a single plugin file, logs the outcome, and returns what
+// became of it.
+func (h *Host) LoadFile(path string) PluginInfo {
+	info := PluginInfo{File: path, Kind: kindOf(path)}
+	h.load(&info)
+	switch info.Status {
+	case StatusDisabled:
+		h.log.Debug("plugin disabled, skipping", "path", path)
+	case StatusFailed:
+		h.log.Warn("plugin load failed, skipping",
+			"path", path,
+			"err", info.Error,
+		)
+	}
+	h.mu.Lock()
+	h.infos = append(h.infos, info)
+	h.mu.Unlock()
+	return info
+}
+
+// load opens or starts the plugin file described by info, registers it, and
+// fills in the rest of info.
+func (h *Host) load(info *PluginInfo) {
+	// A .so cannot be unloaded once opened, and a binary need not be
+	// started, so skip disabled plugins by file name first; register
+	// catches the rest by Name().
+	if s, ok := h.settings[FileKey(info.File)]; ok && !s.IsEnabled() {
+		info.Status = StatusDisabled
+		return
+	}
+
+	var impl v1.PluginV1
+	var err error
+	switch info.Kind {
+	case KindBinary:
+		impl, err = h.startExternal(info.File)
+	case KindWASM:
+		impl, err = h.openWASM(info.File)
+	default:
+		impl, err = openSharedObject(info.File)
+	}
+	if err == nil {
+		describe(impl, info)
+		err = h.register(impl)
+		if err != nil {
+			stopExternal(impl)
+		}
+	}
+
+	switch {
+	case errors.Is(err, errDisabled):
+		info.Status = StatusDisabled
+	case err != nil:
+		info.Status, info.Error = StatusFailed, err.Error()
+	default:
+		info.Status = StatusLoaded
+		info.Hooks = h.hookNames(impl.Name())
+	}
+}
+
+// kindOf selects the plugin runtime for a file by its extension.
+func kindOf(path string) string {
+	switch filepath.Ext(path) {
+	case ".so":
+		return KindSharedObject
+	case ".wasm":
+		return KindWASM
+	}
+	return KindBinary
+}
+
+// describe copies what impl reports about itself into info.
+func describe(impl v1.PluginV1, info *PluginInfo) {
+	info.Name, info.APIVersion = impl.Name(), impl.APIVersion()
+	if ver, ok := impl.(v1.PluginVersioner); ok {
+		info.Version = ver.Version()
+	}
+}
+
+// FileKey is the plugins: key a plugin file is matched against before it is
+// loaded: its lower-cased base name without extension.
+func FileKey(path string) string {
+	base := filepath.Base(path)
+	return strings.ToLower(strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+// openSharedObject opens a single .so file and returns its OrbitPlugin.
+func openSharedObject(path string) (impl v1.PluginV1, retErr error) {
 	// Recover from plugin panics so a bad .so never crashes Orbit
 	defer func() {
 		if r := recover(); r != nil {
@@ -61,73 +211,135 @@ func (h *Host) loadPlugin(path string) (retErr error) {
 
 	p, err := plugin.Open(path)
 	if err != nil {
-		return fmt.Errorf("open shared object: %w", err)
+		return nil, fmt.Errorf("open shared object: %w", err)
 	}
 
 	sym, err := p.Lookup("OrbitPlugin")
 	if err != nil {
-		return fmt.Errorf("symbol OrbitPlugin not found: %w", err)
+		return nil, fmt.Errorf("symbol OrbitPlugin not found: %w", err)
 	}
 
 	impl, ok := sym.(v1.PluginV1)
 	if !ok {
-		return fmt.Errorf("OrbitPlugin does not implement PluginV1")
+		return nil, fmt.Errorf("OrbitPlugin does not implement PluginV1")
 	}
+	return impl, nil
+}
 
+// register checks impl's API version and configuration, initialises it, and
+// adds its hooks.
+func (h *Host) register(impl v1.PluginV1) error {
 	if impl.APIVersion() != v1.PluginAPIVersion {
 		return fmt.Errorf("API version mismatch: plugin=%q, host=%q",
 			impl.APIVersion(), v1.PluginAPIVersion)
 	}
 
-	if err := impl.Init(nil); err != nil {
+	settings := h.settings[strings.ToLower(impl.Name())]
+	if !settings.IsEnabled() {
+		return errDisabled
+	}
+	cfg := settings.Config
+	if cfg == nil {
+		cfg = map[string]string{}
+	}
+	if req, ok := impl.(v1.PluginConfigRequirer); ok {
+		if missing := missingKeys(req.RequiredConfig(), cfg); len(missing) > 0 {
+			return fmt.Errorf("missing required config %s (set plugins.%s.config in orbit.yaml)",
+				strings.Join(missing, ", "), strings.ToLower(impl.Name()))
+		}
+	}
+
+	if err := impl.Init(cfg); err != nil {
 		return fmt.Errorf("plugin Init() failed: %w", err)
 	}
 
+	timeout := settings.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	if w, ok := impl.(*wasmPlugin); ok {
+		w.timeout = timeout
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	name := impl.Name()
 	h.plugins[name] = impl
+	h.hookSubs[name] = nil
 
 	for hookName, fn := range impl.Hooks() {
-		h.hooks[hookName] = append(h.hooks[hookName], fn)
+		h.hooks[hookName] = append(h.hooks[hookName], hookEntry{plugin: name, fn: fn, timeout: timeout})
+		h.hookSubs[name] = append(h.hookSubs[name], hookName)
 	}
+	sort.Strings(h.hookSubs[name])
 
-	h.log.Info("plugin loaded", "name", name, "api_version", impl.APIVersion())
+	h.log.Debug("plugin loaded", "name", name, "api_version", impl.APIVersion())
 	return nil
 }
 
-// Fire dispatches a named hook to all registered plugins.
+// missingKeys returns the required keys absent from cfg, sorted. Keys are
+// compared lower-case, as orbit.yaml's are.
+func missingKeys(required []string, cfg map[string]string) []string {
+	var missing []string
+	for _, k := range required {
+		if _, ok := cfg[strings.ToLower(k)]; !ok {
+			missing = append(missing, k)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Fire dispatches a named hook to all registered plugins, one at a time.
 // Plugin errors are logged but do not prevent subsequent plugins from running.
-// The context may be used to cancel long-running hook implementations.
+// A call that outlives its plugin's timeout is abandoned and logged; the
+// context cancels dispatch to the plugins not yet called. Fire on a nil Host
+// does nothing.
 func (h *Host) Fire(ctx context.Context, hookName string, hctx v1.HookContext) {
+	if h == nil {
+		return
+	}
 	h.mu.RLock()
-	fns := h.hooks[hookName]
+	entries := h.hooks[hookName]
 	h.mu.RUnlock()
 
-	for _, fn := range fns {
+	for _, e := range entries {
 		select {
 		case <-ctx.Done():
 			return
 		default:
 		}
 
-		func(f v1.HookFunc) {
+		done := make(chan error, 1)
+		go func(f v1.HookFunc) {
 			defer func() {
 				if r := recover(); r != nil {
-					h.log.Error("plugin hook panicked",
-						"hook", hookName,
-						"panic", fmt.Sprintf("%v", r),
-					)
+					done <- fmt.Errorf("panic: %v", r)
 				}
 			}()
-			if err := f(hctx); err != nil {
+			done <- f(hctx)
+		}(e.fn)
+
+		timer := time.NewTimer(e.timeout)
+		select {
+		case err := <-done:
+			if err != nil {
 				h.log.Warn("plugin hook returned error",
+					"plugin", e.plugin,
 					"hook", hookName,
 					"err", err,
 				)
 			}
-		}(fn)
+		case <-timer.C:
+			h.log.Warn("plugin hook timed out",
+				"plugin", e.plugin,
+				"hook", hookName,
+				"timeout", e.timeout,
+			)
+		case <-ctx.Done():
+		}
+		timer.Stop()
 	}
 }
 
@@ -154,3 +366,17 @@ func (h *Host) List() []string {
 	}
 	return names
 }
+
+// hookNames returns the hooks the named plugin subscribed to.
+func (h *Host) hookNames(name string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.hookSubs[name]
+}
+
+// Plugins describes every plugin file LoadDir found, in directory order.
+func (h *Host) Plugins() []PluginInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]PluginInfo(nil), h.infos...)
+}
@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+type fakePlugin struct {
+	name     string
+	required []string
+	got      map[string]string
+}
+
+func (p *fakePlugin) Name() string                     { return p.name }
+func (p *fakePlugin) APIVersion() string               { return v1.PluginAPIVersion }
+func (p *fakePlugin) Init(cfg map[string]string) error { p.got = cfg; return nil }
+func (p *fakePlugin) Shutdown() error                  { return nil }
+func (p *fakePlugin) RequiredConfig() []string         { return p.required }
+func (p *fakePlugin) Hooks() map[string]v1.HookFunc {
+	return map[string]v1.HookFunc{"OnPreDeploy": func(v1.HookContext) error { return nil }}
+}
+
+func TestRegisterPassesConfig(t *testing.T) {
+	log, _ := logger.Init("error", "text", "", "", false)
+	off := false
+	h := NewHost(log).WithConfig(map[string]config.PluginConfig{
+		"slack": {Config: map[string]string{"webhook": "https://hooks.example/x"}},
+		"audit": {Enabled: &off},
+	})
+
+	slack := &fakePlugin{name: "Slack", required: []string{"webhook"}}
+	if err := h.register(slack); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if slack.got["webhook"] != "https://hooks.example/x" {
+		t.Errorf("Init got %v", slack.got)
+	}
+
+	if err := h.register(&fakePlugin{name: "audit"}); !errors.Is(err, errDisabled) {
+		t.Errorf("disabled plugin: err = %v", err)
+	}
+
+	bare := &fakePlugin{name: "metrics"}
+	if err := h.register(bare); err != nil || bare.got == nil {
+		t.Errorf("unconfigured plugin: err = %v, cfg = %v (want empty, non-nil)", err, bare.got)
+	}
+
+	err := h.register(&fakePlugin{name: "pager", required: []string{"token", "Channel"}})
+	if err == nil || !strings.Contains(err.Error(), "Channel, token") {
+		t.Errorf("missing keys: err = %v", err)
+	}
+
+	if names := h.List(); len(names) != 2 {
+		t.Errorf("loaded = %v, want slack and metrics", names)
+	}
+	h.Fire(context.Background(), "OnPreDeploy", v1.HookContext{})
+}
+
+func TestLoadDirReportsEachFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, mode os.FileMode) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\nexit 1\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("audit", 0o755)
+	write("broken", 0o755)
+	write("README.md", 0o644)
+	write("notify.wasm", 0o644)
+
+	log, _ := logger.Init("error", "text", "", "", false)
+	off := false
+	h := NewHost(log).WithConfig(map[string]config.PluginConfig{"audit": {Enabled: &off}})
+	if err := h.LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+
+	got := map[string]PluginInfo{}
+	for _, p := range h.Plugins() {
+		got[filepath.Base(p.File)] = p
+	}
+	if len(got) != 3 {
+		t.Fatalf("plugins = %+v, want audit, broken and notify.wasm", got)
+	}
+	if got["audit"].Status != StatusDisabled {
+		t.Errorf("audit = %+v", got["audit"])
+	}
+	if b := got["broken"]; b.Status != StatusFailed || b.Kind != KindBinary || !strings.Contains(b.Error, "handshake") {
+		t.Errorf("broken = %+v", b)
+	}
+	if w := got["notify.wasm"]; w.Status != StatusFailed || w.Kind != KindWASM {
+		t.Errorf("notify.wasm = %+v", w)
+	}
+}
+
+// slowPlugin's hook blocks until released.
+type slowPlugin struct {
+	fakePlugin
+	release chan struct{}
+}
+
+func (p *slowPlugin) Hooks() map[string]v1.HookFunc {
+	return map[string]v1.HookFunc{v1.HookPostDeploy: func(v1.HookContext) error {
+		<-p.release
+		return nil
+	}}
+}
+
+func TestFireAbandonsSlowHook(t *testing.T) {
+	log, _ := logger.Init("error", "text", "", "", false)
+	h := NewHost(log).WithConfig(map[string]config.PluginConfig{"slow": {Timeout: 20 * time.Millisecond}})
+	slow := &slowPlugin{fakePlugin: fakePlugin{name: "slow"}, release: make(chan struct{})}
+	defer close(slow.release)
+	if err := h.register(slow); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	start := time.Now()
+	h.Fire(context.Background(), v1.HookPostDeploy, v1.HookContext{})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Fire blocked for %s despite a 20ms timeout", elapsed)
+	}
+
+	var nilHost *Host
+	nilHost.Fire(context.Background(), v1.HookPostDeploy, v1.HookContext{})
+}
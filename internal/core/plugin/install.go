@@ -0,0 +1,143 @@
+// Package plugin: installing plugin files from a URL or an OCI registry.
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxPluginSize caps a downloaded plugin so a bad source cannot fill the disk.
+const maxPluginSize = 256 << 20
+
+// Installer downloads plugins into Dir.
+type Installer struct {
+	Dir    string
+	client *http.Client
+}
+
+// NewInstaller returns an Installer writing to dir.
+func NewInstaller(dir string) *Installer {
+	return &Installer{Dir: dir, client: &http.Client{Timeout: 5 * time.Minute}}
+}
+
+// InstallOptions tune Install.
+type InstallOptions struct {
+	// Name is the file name to install as; by default it is taken from src.
+	Name string
+	// SHA256, when set, is the hex digest the download must match.
+	SHA256 string
+}
+
+// Install fetches the plugin at src and writes it to Dir, returning its path.
+// src is an http(s) URL of a .so, .wasm or plugin binary, or an OCI artifact
+// reference of the form oci://registry/repository[:tag|@digest] whose
+// layer holds the file. The file only replaces an existing one of the same
+// name once it has been fully downloaded and verified.
+func (in *Installer) Install(ctx context.Context, src string, opts InstallOptions) (string, error) {
+	var (
+		body     io.ReadCloser
+		fromName string
+		digest   string // sha256 the OCI manifest promises, if any
+		err      error
+	)
+	if ref, ok := strings.CutPrefix(src, "oci://"); ok {
+		body, fromName, digest, err = in.fetchOCI(ctx, ref)
+	} else {
+		body, fromName, err = in.fetchURL(ctx, src)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	name := opts.Name
+	if name == "" {
+		name = fromName
+	}
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("cannot derive a plugin file name from %s; pass --name", src)
+	}
+
+	if err := os.MkdirAll(in.Dir, 0o750); err != nil {
+		return "", fmt.Errorf("create plugin dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(in.Dir, ".install-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(body, maxPluginSize+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", src, err)
+	}
+	if n > maxPluginSize {
+		return "", fmt.Errorf("download %s: larger than %d MiB", src, maxPluginSize>>20)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	for _, want := range []string{digest, strings.ToLower(opts.SHA256)} {
+		if want != "" && want != sum {
+			return "", fmt.Errorf("download %s: sha256 %s does not match expected %s", src, sum, want)
+		}
+	}
+
+	mode := os.FileMode(0o755)
+	if kindOf(name) != KindBinary {
+		mode = 0o644
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return "", err
+	}
+	dst := filepath.Join(in.Dir, name)
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", fmt.Errorf("install %s: %w", dst, err)
+	}
+	return dst, nil
+}
+
+// fetchURL starts downloading an http(s) URL.
+func (in *Installer) fetchURL(ctx context.Context, src string) (io.ReadCloser, string, error) {
+	u, err := url.Parse(src)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, "", fmt.Errorf("plugin source %q is neither an http(s) URL nor oci:// reference", src)
+	}
+	resp, err := in.get(ctx, src, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.Body, path.Base(u.Path), nil
+}
+
+// get issues a GET and fails on any non-2xx status.
+func (in *Installer) get(ctx context.Context, src string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := in.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", src, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download %s: %s", src, resp.Status)
+	}
+	return resp, nil
+}
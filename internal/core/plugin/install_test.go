@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/f9-o/orbit/internal/registry"
+)
+
+func TestInstallFromURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("#!/bin/sh\n"))
+	}))
+	defer srv.Close()
+	in := NewInstaller(t.TempDir())
+
+	path, err := in.Install(context.Background(), srv.URL+"/releases/notify", InstallOptions{})
+	if err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if filepath.Base(path) != "notify" {
+		t.Errorf("installed as %s", path)
+	}
+	if fi, _ := os.Stat(path); fi == nil || fi.Mode().Perm()&0o111 == 0 {
+		t.Errorf("installed binary is not executable: %v", fi)
+	}
+
+	_, err = in.Install(context.Background(), srv.URL+"/releases/notify", InstallOptions{Name: "other", SHA256: "00"})
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("checksum mismatch: err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(in.Dir, "other")); !os.IsNotExist(err) {
+		t.Error("file kept after checksum mismatch")
+	}
+}
+
+func TestInstallFromOCI(t *testing.T) {
+	blob := []byte("plugin-binary")
+	sum := sha256.Sum256(blob)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	platform := "notify_" + runtime.GOOS + "_" + runtime.GOARCH
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:acme/notify:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "t0k"})
+		case r.Header.Get("Authorization") != "Bearer t0k":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/acme/notify/manifests/1.0":
+			json.NewEncoder(w).Encode(ociManifest{Layers: []ociLayer{
+				{Digest: "sha256:ffff", Annotations: map[string]string{ociTitle: "notify_plan9_mips"}},
+				{Digest: digest, Annotations: map[string]string{ociTitle: platform}},
+			}})
+		case r.URL.Path == "/v2/acme/notify/blobs/"+digest:
+			w.Write(blob)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	in := NewInstaller(t.TempDir())
+	in.client = srv.Client()
+	host := strings.TrimPrefix(srv.URL, "http://") // plain HTTP, as for any registry on localhost
+
+	path, err := in.Install(context.Background(), "oci://"+host+"/acme/notify:1.0", InstallOptions{})
+	if err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if filepath.Base(path) != platform {
+		t.Errorf("installed as %s, want the layer title", path)
+	}
+	if got, _ := os.ReadFile(path); string(got) != string(blob) {
+		t.Errorf("content = %q", got)
+	}
+}
+
+func TestParseOCIRef(t *testing.T) {
+	for in, want := range map[string]registry.Ref{
+		"ghcr.io/acme/notify":             {Registry: "ghcr.io", Repo: "acme/notify", Tag: "latest"},
+		"ghcr.io/acme/notify:1.2":         {Registry: "ghcr.io", Repo: "acme/notify", Tag: "1.2"},
+		"localhost:5000/notify":           {Registry: "localhost:5000", Repo: "notify", Tag: "latest"},
+		"docker.io/acme/notify@sha256:ab": {Registry: "docker.io", Repo: "acme/notify", Digest: "sha256:ab"},
+	} {
+		got, err := parseOCIRef(in)
+		if err != nil || got != want {
+			t.Errorf("parseOCIRef(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+	if _, err := parseOCIRef("notify"); err == nil {
+		t.Error("reference without a registry accepted")
+	}
+}
@@ -0,0 +1,104 @@
+// Package plugin: pulling a plugin file from an OCI artifact.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/f9-o/orbit/internal/registry"
+)
+
+// ociTitle is the layer annotation tools such as oras set to the file name.
+const ociTitle = "org.opencontainers.image.title"
+
+var ociManifestTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ociManifest is the part of an image manifest Install reads.
+type ociManifest struct {
+	Layers []ociLayer `json:"layers"`
+}
+
+type ociLayer struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// parseOCIRef parses registry/repository[:tag|@digest]. Unlike an image
+// reference, it must name its registry. The tag defaults to latest.
+func parseOCIRef(s string) (registry.Ref, error) {
+	r, err := registry.ParseRef(s)
+	if err != nil {
+		return registry.Ref{}, fmt.Errorf("OCI reference %q: %w", s, err)
+	}
+	if !strings.HasPrefix(s, r.Registry+"/") {
+		return registry.Ref{}, fmt.Errorf("OCI reference %q: want registry/repository[:tag]", s)
+	}
+	return r, nil
+}
+
+// fetchOCI resolves ref's manifest and starts downloading the layer holding
+// the plugin. It returns the layer's title and sha256 for verification.
+// Registries are reached as `orbit outdated` reaches them, with the
+// credentials `docker login` saved.
+func (in *Installer) fetchOCI(ctx context.Context, s string) (io.ReadCloser, string, string, error) {
+	ref, err := parseOCIRef(s)
+	if err != nil {
+		return nil, "", "", err
+	}
+	reg := registry.New().WithHTTPClient(in.client)
+	data, err := reg.Manifest(ctx, ref, ociManifestTypes...)
+	if err != nil {
+		return nil, "", "", err
+	}
+	var m ociManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, "", "", fmt.Errorf("OCI manifest of %s: %w", s, err)
+	}
+	layer, err := pickLayer(m.Layers)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("OCI artifact %s: %w", s, err)
+	}
+	algo, sum, _ := strings.Cut(layer.Digest, ":")
+	if algo != "sha256" {
+		return nil, "", "", fmt.Errorf("OCI artifact %s: unsupported digest %q", s, layer.Digest)
+	}
+
+	blob, err := reg.Blob(ctx, ref, layer.Digest)
+	if err != nil {
+		return nil, "", "", err
+	}
+	name := layer.Annotations[ociTitle]
+	if name == "" {
+		name = path.Base(ref.Repo)
+	}
+	return blob, name, sum, nil
+}
+
+// pickLayer returns the only layer, or with several, the one whose title
+// names this platform (e.g. "notify_linux_amd64").
+func pickLayer(layers []ociLayer) (ociLayer, error) {
+	switch len(layers) {
+	case 0:
+		return ociLayer{}, fmt.Errorf("manifest has no layers")
+	case 1:
+		return layers[0], nil
+	}
+	var titles []string
+	for _, l := range layers {
+		title := l.Annotations[ociTitle]
+		if strings.Contains(title, runtime.GOOS) && strings.Contains(title, runtime.GOARCH) {
+			return l, nil
+		}
+		titles = append(titles, title)
+	}
+	return ociLayer{}, fmt.Errorf("no layer for %s/%s among %s", runtime.GOOS, runtime.GOARCH, strings.Join(titles, ", "))
+}
@@ -0,0 +1,222 @@
+// Package plugin: WebAssembly plugins.
+//
+// A .wasm plugin runs sandboxed in wazero, with WASI for its standard
+// streams and clock but no filesystem, network or environment. Whatever the
+// language it is written in, the module exports:
+//
+//	memory                         its linear memory
+//	orbit_alloc(size i32) i32      a buffer of size bytes for the host to fill
+//	orbit_manifest() i64           its manifest
+//	orbit_init(ptr, len i32) i64   Init, given the plugin's config
+//	orbit_hook(ptr, len i32) i64   a hook call
+//	orbit_shutdown()               optional; Shutdown
+//
+// Arguments are JSON the host writes into a buffer from orbit_alloc: the
+// config map for orbit_init, {"hook": ..., "context": HookContext} for
+// orbit_hook. Results are a pointer and a length packed into an i64, the
+// pointer in the high 32 bits: for orbit_manifest the JSON
+// {"name", "api_version", "version", "hooks", "required_config"}, and for
+// orbit_init and orbit_hook an error message, length 0 meaning success. The
+// module may import orbit.log(ptr, len i32) to log a line. A reactor
+// module's _initialize runs when it is loaded.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// wasmManifest is what orbit_manifest returns.
+type wasmManifest struct {
+	Name           string   `json:"name"`
+	APIVersion     string   `json:"api_version"`
+	Version        string   `json:"version,omitempty"`
+	Hooks          []string `json:"hooks"`
+	RequiredConfig []string `json:"required_config,omitempty"`
+}
+
+// wasmHookCall is the argument of orbit_hook.
+type wasmHookCall struct {
+	Hook    string         `json:"hook"`
+	Context v1.HookContext `json:"context"`
+}
+
+// wasmPlugin is a loaded .wasm plugin. A module instance runs one call at a
+// time; a call that outlives timeout closes the module, and later calls
+// fail.
+type wasmPlugin struct {
+	runtime  wazero.Runtime
+	mod      api.Module
+	manifest wasmManifest
+	timeout  time.Duration
+
+	mu sync.Mutex
+}
+
+// openWASM compiles and instantiates the .wasm plugin at path and reads its
+// manifest.
+func (h *Host) openWASM(path string) (v1.PluginV1, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read wasm module: %w", err)
+	}
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	w := &wasmPlugin{runtime: r, timeout: DefaultHookTimeout}
+	if err := w.instantiate(ctx, h, path, code); err != nil {
+		_ = r.Close(ctx)
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *wasmPlugin) instantiate(ctx context.Context, h *Host, path string, code []byte) error {
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, w.runtime); err != nil {
+		return fmt.Errorf("wasi: %w", err)
+	}
+	_, err := w.runtime.NewHostModuleBuilder("orbit").
+		NewFunctionBuilder().
+		WithFunc(func(_ context.Context, m api.Module, ptr, size uint32) {
+			if b, ok := m.Memory().Read(ptr, size); ok {
+				h.log.Debug("plugin output", "path", path, "line", string(b))
+			}
+		}).
+		Export("log").
+		Instantiate(ctx)
+	if err != nil {
+		return fmt.Errorf("host module: %w", err)
+	}
+
+	out := h.pluginLog(path)
+	w.mod, err = w.runtime.InstantiateWithConfig(ctx, code, wazero.NewModuleConfig().
+		WithName("").
+		WithStdout(out).
+		WithStderr(out).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		return fmt.Errorf("instantiate wasm module: %w", err)
+	}
+	for _, fn := range []string{"orbit_alloc", "orbit_manifest", "orbit_init", "orbit_hook"} {
+		if w.mod.ExportedFunction(fn) == nil {
+			return fmt.Errorf("wasm module does not export %s", fn)
+		}
+	}
+	if w.mod.Memory() == nil {
+		return errors.New("wasm module does not export its memory")
+	}
+
+	b, err := w.call("orbit_manifest", nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &w.manifest); err != nil {
+		return fmt.Errorf("orbit_manifest: %w", err)
+	}
+	if w.manifest.Name == "" {
+		return errors.New("orbit_manifest: no name")
+	}
+	return nil
+}
+
+// call calls the module's function fn, with in, when not nil, as its
+// argument, and returns the bytes its result points at.
+func (w *wasmPlugin) call(fn string, in []byte) ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	var params []uint64
+	if in != nil {
+		res, err := w.mod.ExportedFunction("orbit_alloc").Call(ctx, uint64(len(in)))
+		if err != nil {
+			return nil, fmt.Errorf("orbit_alloc: %w", err)
+		}
+		ptr := uint32(res[0])
+		if !w.mod.Memory().Write(ptr, in) {
+			return nil, fmt.Errorf("orbit_alloc: buffer %#x of %d bytes is out of memory", ptr, len(in))
+		}
+		params = []uint64{uint64(ptr), uint64(len(in))}
+	}
+	res, err := w.mod.ExportedFunction(fn).Call(ctx, params...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	ptr, size := uint32(res[0]>>32), uint32(res[0])
+	if size == 0 {
+		return nil, nil
+	}
+	b, ok := w.mod.Memory().Read(ptr, size)
+	if !ok {
+		return nil, fmt.Errorf("%s: result %#x of %d bytes is out of memory", fn, ptr, size)
+	}
+	return append([]byte(nil), b...), nil
+}
+
+// callErr calls fn with in marshalled, returning the error message it
+// reports as an error.
+func (w *wasmPlugin) callErr(fn string, in any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	msg, err := w.call(fn, b)
+	if err != nil {
+		return err
+	}
+	if len(msg) > 0 {
+		return errors.New(string(msg))
+	}
+	return nil
+}
+
+func (w *wasmPlugin) Name() string             { return w.manifest.Name }
+func (w *wasmPlugin) APIVersion() string       { return w.manifest.APIVersion }
+func (w *wasmPlugin) Version() string          { return w.manifest.Version }
+func (w *wasmPlugin) RequiredConfig() []string { return w.manifest.RequiredConfig }
+
+func (w *wasmPlugin) Init(cfg map[string]string) error {
+	return w.callErr("orbit_init", cfg)
+}
+
+func (w *wasmPlugin) Hooks() map[string]v1.HookFunc {
+	hooks := make(map[string]v1.HookFunc, len(w.manifest.Hooks))
+	for _, hook := range w.manifest.Hooks {
+		hooks[hook] = func(hctx v1.HookContext) error {
+			return w.callErr("orbit_hook", wasmHookCall{Hook: hook, Context: hctx})
+		}
+	}
+	return hooks
+}
+
+// Shutdown calls orbit_shutdown, if the module exports it, and releases the
+// runtime.
+func (w *wasmPlugin) Shutdown() error {
+	var err error
+	if w.mod.ExportedFunction("orbit_shutdown") != nil {
+		_, err = w.call("orbit_shutdown", nil)
+	}
+	w.close()
+	return err
+}
+
+// close releases the runtime without calling the module.
+func (w *wasmPlugin) close() {
+	_ = w.runtime.Close(context.Background())
+}
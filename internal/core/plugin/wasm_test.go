@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+// wasmModule assembles a plugin module whose orbit_manifest returns
+// manifest, whose orbit_init logs its config and succeeds, and whose
+// orbit_hook fails with its argument as the message.
+func wasmModule(manifest string) []byte {
+	uleb := func(n int) []byte {
+		var b []byte
+		for {
+			c := byte(n & 0x7f)
+			n >>= 7
+			if n == 0 {
+				return append(b, c)
+			}
+			b = append(b, c|0x80)
+		}
+	}
+	sleb := func(n int64) []byte {
+		var b []byte
+		for {
+			c := byte(n & 0x7f)
+			n >>= 7
+			if (n == 0 && c&0x40 == 0) || (n == -1 && c&0x40 != 0) {
+				return append(b, c)
+			}
+			b = append(b, c|0x80)
+		}
+	}
+	cat := func(parts ...[]byte) []byte {
+		var b []byte
+		for _, p := range parts {
+			b = append(b, p...)
+		}
+		return b
+	}
+	vec := func(items ...[]byte) []byte { return cat(append([][]byte{uleb(len(items))}, items...)...) }
+	name := func(s string) []byte { return cat(uleb(len(s)), []byte(s)) }
+	section := func(id byte, payload []byte) []byte { return cat([]byte{id}, uleb(len(payload)), payload) }
+	body := func(code ...byte) []byte { return cat(uleb(len(code)+1), []byte{0}, code) } // no locals
+
+	const i32, i64, manifestAt = 0x7f, 0x7e, 1024
+	packed := sleb(manifestAt<<32 | int64(len(manifest)))
+	return cat(
+		[]byte("\x00asm\x01\x00\x00\x00"),
+		section(1, vec( // types
+			[]byte{0x60, 2, i32, i32, 0},        // 0: (i32, i32)
+			[]byte{0x60, 1, i32, 1, i32},        // 1: (i32) i32
+			[]byte{0x60, 0, 1, i64},             // 2: () i64
+			[]byte{0x60, 2, i32, i32, 1, i64})), // 3: (i32, i32) i64
+		section(2, vec(cat(name("orbit"), name("log"), []byte{0, 0}))), // func 0
+		section(3, vec([]byte{1}, []byte{2}, []byte{3}, []byte{3})),    // funcs 1-4
+		section(5, vec([]byte{0, 1})),                                  // one page
+		section(7, vec(
+			cat(name("memory"), []byte{2, 0}),
+			cat(name("orbit_alloc"), []byte{0, 1}),
+			cat(name("orbit_manifest"), []byte{0, 2}),
+			cat(name("orbit_init"), []byte{0, 3}),
+			cat(name("orbit_hook"), []byte{0, 4}))),
+		section(10, vec(
+			body(cat([]byte{0x41}, sleb(4096), []byte{0x0b})...),             // i32.const 4096
+			body(cat([]byte{0x42}, packed, []byte{0x0b})...),                 // i64.const manifest
+			body(0x20, 0, 0x20, 1, 0x10, 0, 0x42, 0, 0x0b),                   // log(ptr, len); 0
+			body(0x20, 0, 0xad, 0x42, 32, 0x86, 0x20, 1, 0xad, 0x84, 0x0b))), // ptr<<32 | len
+		section(11, vec(cat([]byte{0, 0x41}, sleb(manifestAt), []byte{0x0b}, name(manifest)))),
+	)
+}
+
+func TestWASMPlugin(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `{"name":"Notify","api_version":"v1","version":"0.3.0","hooks":["OnPreDeploy"],"required_config":["channel"]}`
+	if err := os.WriteFile(filepath.Join(dir, "notify.wasm"), wasmModule(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "empty.wasm"), wasmModule(`{"api_version":"v1"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	log, _ := logger.Init("error", "text", "", "", false)
+	h := NewHost(log).WithConfig(map[string]config.PluginConfig{"notify": {Config: map[string]string{"channel": "#ops"}}})
+	if err := h.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Shutdown()
+
+	got := map[string]PluginInfo{}
+	for _, p := range h.Plugins() {
+		got[filepath.Base(p.File)] = p
+	}
+	want := PluginInfo{File: filepath.Join(dir, "notify.wasm"), Kind: KindWASM, Name: "Notify", Version: "0.3.0",
+		APIVersion: "v1", Hooks: []string{"OnPreDeploy"}, Status: StatusLoaded}
+	if !reflect.DeepEqual(got["notify.wasm"], want) {
+		t.Errorf("notify.wasm = %+v", got["notify.wasm"])
+	}
+	if e := got["empty.wasm"]; e.Status != StatusFailed || !strings.Contains(e.Error, "no name") {
+		t.Errorf("empty.wasm = %+v", e)
+	}
+
+	// The test module's hook fails with the call it was given.
+	hook := h.plugins["Notify"].Hooks()[v1.HookPreDeploy]
+	err := hook(v1.HookContext{Service: &v1.ServiceSpec{Name: "api"}, ImageTo: "api:2"})
+	if err == nil {
+		t.Fatal("hook did not report its argument")
+	}
+	var call wasmHookCall
+	if jerr := json.Unmarshal([]byte(err.Error()), &call); jerr != nil {
+		t.Fatalf("hook argument %q: %v", err, jerr)
+	}
+	if call.Hook != v1.HookPreDeploy || call.Context.Service.Name != "api" || call.Context.ImageTo != "api:2" {
+		t.Errorf("hook argument = %+v", call)
+	}
+}
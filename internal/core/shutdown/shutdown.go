@@ -0,0 +1,146 @@
+// Package shutdown traps SIGINT/SIGTERM for a CLI invocation, cancels the
+// command's context, and runs the cleanup steps registered by long operations
+// (temporary containers, service locks, the state DB) so an interrupted
+// deploy does not leave debris behind.
+package shutdown
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// CleanupTimeout bounds how long all cleanup steps may take together.
+const CleanupTimeout = 30 * time.Second
+
+// GracePeriod is how long an interrupted command gets to unwind on its own
+// before cleanup runs without it.
+const GracePeriod = 10 * time.Second
+
+// step is a registered cleanup action.
+type step struct {
+	id   int
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Report is the outcome of one cleanup step.
+type Report struct {
+	Name string
+	Err  error
+}
+
+// Manager owns the signal handler and the cleanup stack for one invocation.
+type Manager struct {
+	mu          sync.Mutex
+	steps       []step
+	nextID      int
+	interrupted bool
+	cleaned     bool
+	reports     []Report
+}
+
+// New returns an idle Manager; call Start to install the signal handler.
+func New() *Manager {
+	return &Manager{}
+}
+
+type contextKey struct{}
+
+// Start installs the SIGINT/SIGTERM handler and returns a context, carrying the
+// Manager, that is cancelled on the first signal. onForce is called (and
+// should exit) if the command has not returned within GracePeriod or a second
+// signal arrives; cleanup has already run by then.
+func (m *Manager) Start(parent context.Context, onInterrupt func(), onForce func([]Report)) context.Context {
+	ctx, cancel := context.WithCancel(context.WithValue(parent, contextKey{}, m))
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		m.mu.Lock()
+		m.interrupted = true
+		m.mu.Unlock()
+		if onInterrupt != nil {
+			onInterrupt()
+		}
+		cancel()
+
+		select {
+		case <-sigs:
+		case <-time.After(GracePeriod):
+		}
+		if onForce != nil {
+			onForce(m.Cleanup())
+		}
+	}()
+	return ctx
+}
+
+// Interrupted reports whether a signal has been received.
+func (m *Manager) Interrupted() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.interrupted
+}
+
+// Register pushes a cleanup step. The returned func removes it again and
+// should be called once the resource no longer needs cleaning up (the
+// temporary container was renamed, the lock released normally, ...).
+func (m *Manager) Register(name string, fn func(ctx context.Context) error) (unregister func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	id := m.nextID
+	m.steps = append(m.steps, step{id: id, name: name, fn: fn})
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, s := range m.steps {
+			if s.id == id {
+				m.steps = append(m.steps[:i], m.steps[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Cleanup runs every registered step once, most recent first, under a fresh
+// context bounded by CleanupTimeout. Later calls return the first run's
+// reports.
+func (m *Manager) Cleanup() []Report {
+	m.mu.Lock()
+	if m.cleaned {
+		defer m.mu.Unlock()
+		return m.reports
+	}
+	m.cleaned = true
+	steps := m.steps
+	m.steps = nil
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), CleanupTimeout)
+	defer cancel()
+
+	reports := make([]Report, 0, len(steps))
+	for i := len(steps) - 1; i >= 0; i-- {
+		reports = append(reports, Report{Name: steps[i].name, Err: steps[i].fn(ctx)})
+	}
+
+	m.mu.Lock()
+	m.reports = reports
+	m.mu.Unlock()
+	return reports
+}
+
+// Register adds a cleanup step to the Manager carried by ctx. Without one
+// (tests, the TUI's own contexts) it is a no-op.
+func Register(ctx context.Context, name string, fn func(ctx context.Context) error) (unregister func()) {
+	if m, ok := ctx.Value(contextKey{}).(*Manager); ok {
+		return m.Register(name, fn)
+	}
+	return func() {}
+}
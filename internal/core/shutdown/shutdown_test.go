@@ -0,0 +1,46 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCleanupOrder(t *testing.T) {
+	m := New()
+	ctx := context.WithValue(context.Background(), contextKey{}, m)
+
+	var ran []string
+	step := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+	Register(ctx, "close db", step("close db", nil))
+	forget := Register(ctx, "remove temp", step("remove temp", nil))
+	Register(ctx, "release lock", step("release lock", errors.New("boom")))
+	forget()
+
+	reports := m.Cleanup()
+	if len(ran) != 2 || ran[0] != "release lock" || ran[1] != "close db" {
+		t.Fatalf("ran = %v, want [release lock close db]", ran)
+	}
+	if len(reports) != 2 || reports[0].Err == nil || reports[1].Err != nil {
+		t.Fatalf("reports = %+v", reports)
+	}
+
+	// A second Cleanup must not re-run steps.
+	m.Cleanup()
+	if len(ran) != 2 {
+		t.Fatalf("steps re-ran: %v", ran)
+	}
+}
+
+func TestRegisterWithoutManager(t *testing.T) {
+	forget := Register(context.Background(), "noop", func(context.Context) error {
+		t.Fatal("step ran without a manager")
+		return nil
+	})
+	forget()
+}
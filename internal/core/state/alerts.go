@@ -0,0 +1,56 @@
+// Package state: active alerts raised by the agent's rule evaluator.
+package state
+
+import (
+	"encoding/json"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// PutAlert records a, replacing any earlier reading of the same alert.
+func (db *DB) PutAlert(a v1.Alert) error {
+	if err := db.putJSON(bucketAlerts, AlertKey(a), a); err != nil {
+		return errs.Wrap(err, errs.ErrStateWrite, "state.PutAlert").WithNode(a.Node)
+	}
+	return nil
+}
+
+// DeleteAlert removes a once its condition no longer holds.
+func (db *DB) DeleteAlert(a v1.Alert) error {
+	err := db.store.Update(func(tx Tx) error {
+		return tx.Bucket(bucketAlerts).Delete([]byte(AlertKey(a)))
+	})
+	if err != nil {
+		return errs.New(errs.ErrStateWrite, "state.DeleteAlert", err).WithNode(a.Node)
+	}
+	return nil
+}
+
+// ListAlerts returns every active alert.
+func (db *DB) ListAlerts() ([]v1.Alert, error) {
+	var alerts []v1.Alert
+	err := db.store.View(func(tx Tx) error {
+		return tx.Bucket(bucketAlerts).ForEach(func(k, v []byte) error {
+			var a v1.Alert
+			data, err := db.crypto.Decrypt(v)
+			if err != nil {
+				return errs.New(errs.ErrStateRead, "state.ListAlerts.Decrypt", err).WithNode(string(k))
+			}
+			if err := json.Unmarshal(data, &a); err != nil {
+				return errs.New(errs.ErrStateRead, "state.ListAlerts.Unmarshal", err).WithNode(string(k))
+			}
+			alerts = append(alerts, a)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, errs.ErrStateRead, "state.ListAlerts")
+	}
+	return alerts, nil
+}
+
+// AlertKey identifies an alert: one rule fires at most once per node and subject.
+func AlertKey(a v1.Alert) string {
+	return a.Rule + "/" + a.Node + "/" + a.Subject
+}
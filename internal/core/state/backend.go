@@ -0,0 +1,88 @@
+// Package state: the storage a DB keeps its encrypted records in.
+package state
+
+import (
+	"errors"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// Backend is the storage under a DB: named buckets of encrypted records,
+// each kept in key order. BoltDB (the default) and SQLite implement it.
+type Backend interface {
+	// Name is the backend's name, as given to OpenOptions.Backend.
+	Name() string
+	View(fn func(Tx) error) error
+	Update(fn func(Tx) error) error
+	// Check verifies the backend's own structures, not the records.
+	Check() error
+	Close() error
+}
+
+// Tx is a transaction. Values it returns are only valid until it ends.
+type Tx interface {
+	// Bucket returns the named bucket, or nil when it does not exist.
+	Bucket(name []byte) Bucket
+	CreateBucketIfNotExists(name []byte) (Bucket, error)
+	DeleteBucket(name []byte) error
+}
+
+// Bucket is a set of records kept in key order. A backend may also keep a
+// record's Index, to answer a Range without reading every record; callers
+// still check what it returns, as a backend need not.
+type Bucket interface {
+	Get(key []byte) []byte
+	// Put stores value under key, keeping the record's index if it has one.
+	Put(key, value []byte) error
+	PutIndexed(key, value []byte, ix Index) error
+	Delete(key []byte) error
+	// ForEach calls fn for every record in key order.
+	ForEach(fn func(k, v []byte) error) error
+	// Scan calls fn for the records r selects until fn returns ErrStop.
+	Scan(r Range, fn func(k, v []byte) error) error
+	Len() int
+	NextSequence() (uint64, error)
+}
+
+// ErrStop ends a Scan early without failing it.
+var ErrStop = errors.New("stop scan")
+
+// Index is what a record is looked up by besides its key.
+type Index struct {
+	Project string
+	Node    string
+	Service string
+	Time    time.Time
+}
+
+// Range selects records of a bucket: keys from From up to but not including
+// To, either nil for no bound, and those whose index matches Where's
+// non-zero fields and has a Time within [Since, Until].
+type Range struct {
+	From, To     []byte
+	Reverse      bool // newest key first
+	Where        Index
+	Since, Until time.Time
+}
+
+// indexOf is the index of a record.
+func indexOf(v any) Index {
+	switch r := v.(type) {
+	case v1.NodeInfo:
+		return Index{Node: r.Spec.Name, Time: r.LastSeen}
+	case v1.ServiceState:
+		return Index{Project: r.Project, Node: r.Node, Service: r.Name, Time: r.StartedAt}
+	case v1.DeploymentRecord:
+		return Index{Project: r.Project, Node: r.Node, Service: r.Service, Time: r.StartedAt}
+	case v1.Alert:
+		return Index{Node: r.Node, Time: r.FiredAt}
+	case v1.Event:
+		return Index{Node: r.Node, Time: r.Time}
+	case Lock:
+		return Index{Project: r.Project, Node: r.Node, Service: r.Service, Time: r.AcquiredAt}
+	case PluginToggle:
+		return Index{Time: r.ChangedAt}
+	}
+	return Index{}
+}
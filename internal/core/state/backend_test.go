@@ -0,0 +1,155 @@
+package state_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+// eachBackend runs fn against a new DB in each backend.
+func eachBackend(t *testing.T, fn func(t *testing.T, db *state.DB)) {
+	for _, backend := range []string{state.BackendBolt, state.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+			path := filepath.Join(t.TempDir(), state.Filename(backend))
+			db, err := state.OpenWithOptions(path, state.OpenOptions{Backend: backend})
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			t.Cleanup(func() { db.Close() })
+			fn(t, db)
+		})
+	}
+}
+
+func deploymentIDs(recs []v1.DeploymentRecord) []string {
+	out := make([]string, len(recs))
+	for i, r := range recs {
+		out[i] = r.ID
+	}
+	return out
+}
+
+func TestBackendQueries(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db *state.DB) {
+		for _, s := range []v1.ServiceState{
+			{Name: "web", Node: "edge-1"},
+			{Name: "api", Node: "edge-2"},
+			{Name: "db", Node: "edge-1"},
+		} {
+			if err := db.PutServiceState(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		states, err := db.ListServiceStates("edge-1")
+		if err != nil || len(states) != 2 {
+			t.Errorf("edge-1 services = %+v, %v", states, err)
+		}
+
+		t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, r := range []v1.DeploymentRecord{
+			{ID: "1-web", Service: "web", Node: "edge-1"},
+			{ID: "2-api", Service: "api", Node: "edge-2"},
+			{ID: "3-web", Service: "web", Node: "edge-2"},
+			{ID: "4-web", Service: "web", Node: "edge-1"},
+		} {
+			r.StartedAt = t0.Add(time.Duration(i) * time.Hour)
+			if err := db.PutDeployment(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, tc := range []struct {
+			q    state.DeploymentQuery
+			want []string
+		}{
+			{state.DeploymentQuery{Service: "web"}, []string{"1-web", "3-web", "4-web"}},
+			{state.DeploymentQuery{Service: "web", Node: "edge-1"}, []string{"1-web", "4-web"}},
+			{state.DeploymentQuery{Since: t0.Add(time.Hour), Until: t0.Add(2 * time.Hour)}, []string{"2-api", "3-web"}},
+			{state.DeploymentQuery{Service: "web", Limit: 2}, []string{"3-web", "4-web"}},
+		} {
+			recs, err := db.QueryDeployments(tc.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := deploymentIDs(recs); !slices.Equal(got, tc.want) {
+				t.Errorf("%+v: got %v, want %v", tc.q, got, tc.want)
+			}
+		}
+
+		// Rekeyed records keep what they are looked up by.
+		engine, err := encryption.NewEngine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Rekey(engine, func() error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+		if recs, err := db.QueryDeployments(state.DeploymentQuery{Node: "edge-2"}); err != nil || len(recs) != 2 {
+			t.Errorf("after rekey: %v, %v", deploymentIDs(recs), err)
+		}
+		if n, err := db.Check(); err != nil || n != 7 {
+			t.Errorf("check: %d records, %v", n, err)
+		}
+	})
+}
+
+func TestCopyTo(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db *state.DB) {
+		if err := db.PutServiceState(v1.ServiceState{Name: "web", Node: "edge-1"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.AppendEvent(v1.Event{Type: v1.EventDeploy, Resource: "service/web", Node: "edge-1", Message: "web v1"}); err != nil {
+			t.Fatal(err)
+		}
+		for _, to := range []string{state.BackendBolt, state.BackendSQLite} {
+			path := filepath.Join(t.TempDir(), state.Filename(to))
+			err := db.CopyTo(path, state.OpenOptions{Backend: to})
+			if err != nil {
+				t.Fatalf("copy to %s: %v", to, err)
+			}
+			dst, err := state.OpenWithOptions(path, state.OpenOptions{Backend: to, ReadOnly: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			states, _ := dst.ListServiceStates("edge-1")
+			events, _ := dst.ListEvents(state.EventQuery{Node: "edge-1"})
+			dst.Close()
+			if len(states) != 1 || len(events) != 1 {
+				t.Errorf("%s copy: %d service(s), %d event(s)", to, len(states), len(events))
+			}
+		}
+	})
+}
+
+func TestSQLiteReadersBesideWriter(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	path := filepath.Join(t.TempDir(), state.SQLiteFilename)
+	w, err := state.OpenWithOptions(path, state.OpenOptions{Backend: state.BackendSQLite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	r, err := state.OpenWithOptions(path, state.OpenOptions{Backend: state.BackendSQLite, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("reader beside a writer: %v", err)
+	}
+	defer r.Close()
+
+	if err := w.PutServiceState(v1.ServiceState{Name: "web", Node: "local"}); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := r.GetServiceState("local", "web"); err != nil || s == nil {
+		t.Errorf("reader: %+v, %v", s, err)
+	}
+	if err := r.PutServiceState(v1.ServiceState{Name: "api", Node: "local"}); err == nil {
+		t.Error("a read-only DB accepted a write")
+	}
+	if got, backend := state.Locate(filepath.Dir(path)); got != path || backend != state.BackendSQLite {
+		t.Errorf("Locate = %s, %s", got, backend)
+	}
+}
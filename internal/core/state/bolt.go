@@ -0,0 +1,222 @@
+// Package state: the BoltDB backend, a single file one process at a time
+// may write.
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// BackendBolt names the BoltDB backend.
+const BackendBolt = "bolt"
+
+type boltBackend struct {
+	db     *bbolt.DB
+	path   string
+	holder *Holder // written to path's holder file while open for writing
+}
+
+// openBolt opens the BoltDB file at path. BoltDB locks the whole file:
+// shared by readers, or held by one writer.
+func openBolt(path string, opts OpenOptions) (*boltBackend, error) {
+	wait := opts.wait()
+	if opts.ReadOnly {
+		// A read-only open cannot create the file or its buckets.
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) || !hasBuckets(path, wait) {
+			db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: wait})
+			if errors.Is(err, bbolt.ErrTimeout) {
+				return nil, lockedErr(path, opts.Wait)
+			}
+			if err != nil {
+				return nil, errs.New(errs.ErrStateRead, "state.Open", err)
+			}
+			err = createBuckets(&boltBackend{db: db})
+			db.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: wait, ReadOnly: opts.ReadOnly})
+	if errors.Is(err, bbolt.ErrTimeout) {
+		return nil, lockedErr(path, opts.Wait)
+	}
+	if err != nil {
+		return nil, errs.New(errs.ErrStateRead, "state.Open", err).WithAdvice("Ensure you have file permissions and no other process holds the DB lock.")
+	}
+	b := &boltBackend{db: db, path: path}
+	if opts.ReadOnly {
+		return b, nil
+	}
+	if err := createBuckets(b); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	b.holder = &Holder{PID: os.Getpid(), Host: host, Command: opts.Holder, Since: time.Now().UTC()}
+	if data, err := json.Marshal(b.holder); err == nil {
+		_ = os.WriteFile(holderFile(path), data, 0600)
+	}
+	return b, nil
+}
+
+// hasBuckets reports whether the DB at path has every bucket, as a DB
+// written by an older orbit may not.
+func hasBuckets(path string, wait time.Duration) bool {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: wait, ReadOnly: true})
+	if err != nil {
+		return true // let the real open report it
+	}
+	defer db.Close()
+	ok := true
+	_ = db.View(func(tx *bbolt.Tx) error {
+		for _, b := range buckets {
+			ok = ok && tx.Bucket(b) != nil
+		}
+		return nil
+	})
+	return ok
+}
+
+func (b *boltBackend) Name() string { return BackendBolt }
+
+func (b *boltBackend) View(fn func(Tx) error) error {
+	return b.db.View(func(tx *bbolt.Tx) error { return fn(boltTx{tx}) })
+}
+
+func (b *boltBackend) Update(fn func(Tx) error) error {
+	return b.db.Update(func(tx *bbolt.Tx) error { return fn(boltTx{tx}) })
+}
+
+// Check walks every page of the file.
+func (b *boltBackend) Check() error {
+	return b.db.View(func(tx *bbolt.Tx) error {
+		var pageErrs []error
+		for err := range tx.Check() {
+			pageErrs = append(pageErrs, err)
+		}
+		return errors.Join(pageErrs...)
+	})
+}
+
+func (b *boltBackend) Close() error {
+	b.releaseHolder()
+	return b.db.Close()
+}
+
+// releaseHolder removes the holder file, if it is still this process's.
+func (b *boltBackend) releaseHolder() {
+	if b.holder == nil {
+		return
+	}
+	if h, err := ReadHolder(b.path); err == nil && h != nil && h.PID == b.holder.PID && h.Since.Equal(b.holder.Since) {
+		_ = os.Remove(holderFile(b.path))
+	}
+	b.holder = nil
+}
+
+type boltTx struct{ tx *bbolt.Tx }
+
+func (t boltTx) Bucket(name []byte) Bucket {
+	if b := t.tx.Bucket(name); b != nil {
+		return boltBucket{b}
+	}
+	return nil
+}
+
+func (t boltTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	b, err := t.tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return boltBucket{b}, nil
+}
+
+func (t boltTx) DeleteBucket(name []byte) error {
+	if err := t.tx.DeleteBucket(name); err != nil && !errors.Is(err, bbolt.ErrBucketNotFound) {
+		return err
+	}
+	return nil
+}
+
+// boltBucket keeps no index: a Range is answered by its keys alone.
+type boltBucket struct{ b *bbolt.Bucket }
+
+func (b boltBucket) Get(key []byte) []byte       { return b.b.Get(key) }
+func (b boltBucket) Put(key, value []byte) error { return b.b.Put(key, value) }
+func (b boltBucket) Delete(key []byte) error     { return b.b.Delete(key) }
+
+func (b boltBucket) PutIndexed(key, value []byte, _ Index) error {
+	return b.b.Put(key, value)
+}
+
+func (b boltBucket) ForEach(fn func(k, v []byte) error) error {
+	return b.b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return nil // nested bucket
+		}
+		return fn(k, v)
+	})
+}
+
+// Len counts with a cursor, which unlike Stats sees the transaction's own
+// writes.
+func (b boltBucket) Len() int {
+	n := 0
+	c := b.b.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		n++
+	}
+	return n
+}
+
+func (b boltBucket) NextSequence() (uint64, error) {
+	return b.b.NextSequence()
+}
+
+func (b boltBucket) Scan(r Range, fn func(k, v []byte) error) error {
+	c := b.b.Cursor()
+	var k, v []byte
+	inRange := func() bool {
+		return k != nil && (r.From == nil || bytes.Compare(k, r.From) >= 0) && (r.To == nil || bytes.Compare(k, r.To) < 0)
+	}
+	step := c.Next
+	switch {
+	case !r.Reverse && r.From != nil:
+		k, v = c.Seek(r.From)
+	case !r.Reverse:
+		k, v = c.First()
+	case r.To != nil:
+		// Seek finds the first key at or after To; the range ends before it.
+		if k, v = c.Seek(r.To); k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+		step = c.Prev
+	default:
+		k, v = c.Last()
+		step = c.Prev
+	}
+	for ; inRange(); k, v = step() {
+		if v == nil {
+			continue // nested bucket
+		}
+		if err := fn(k, v); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Package state: integrity verification for `orbit doctor`.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// Check verifies the backend's structures — every page of a BoltDB file,
+// or SQLite's integrity check — and that each bucket exists and every record
+// decrypts and decodes. It returns the number of records inspected.
+func (db *DB) Check() (int, error) {
+	if err := db.store.Check(); err != nil {
+		return 0, errs.New(errs.ErrStateRead, "state.Check.pages", err)
+	}
+	records := 0
+	err := db.store.View(func(tx Tx) error {
+		for _, name := range buckets {
+			b := tx.Bucket(name)
+			if b == nil {
+				return errs.Newf(errs.ErrStateRead, "state.Check.buckets", "bucket %q is missing", name)
+			}
+			err := b.ForEach(func(k, v []byte) error {
+				records++
+				plain, err := db.crypto.Decrypt(v)
+				if err != nil {
+					return errs.New(errs.ErrStateRead, "state.Check.decrypt", fmt.Errorf("%s/%s: %w", name, k, err))
+				}
+				var out any
+				if err := json.Unmarshal(plain, &out); err != nil {
+					return errs.New(errs.ErrStateRead, "state.Check.decode", fmt.Errorf("%s/%s: %w", name, k, err))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return records, err
+}
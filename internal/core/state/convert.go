@@ -0,0 +1,22 @@
+// Package state: copying the DB into another backend.
+package state
+
+// CopyTo copies every record but the locks into a new DB at path, opened as
+// opts asks, encrypted with db's key. Records already at path are replaced.
+func (db *DB) CopyTo(path string, opts OpenOptions) error {
+	dump, err := db.Export()
+	if err != nil {
+		return err
+	}
+	opts.ReadOnly = false
+	store, err := openBackend(path, opts)
+	if err != nil {
+		return err
+	}
+	dst := &DB{store: store, path: path, crypto: db.crypto, events: db.events}
+	if err := dst.Import(dump, true); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,197 @@
+// Package state: plain-text export and import of the whole DB.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// DumpVersion is the format version Export writes and Import accepts.
+const DumpVersion = 1
+
+// Dump is the DB's contents, decrypted, for review, migration between
+// machines and disaster recovery. Locks belong to running processes and are
+// left out.
+type Dump struct {
+	Version     int                   `json:"version"`
+	ExportedAt  time.Time             `json:"exported_at"`
+	Nodes       []v1.NodeInfo         `json:"nodes"`
+	Services    []v1.ServiceState     `json:"services"`
+	Deployments []v1.DeploymentRecord `json:"deployments"`
+	Alerts      []v1.Alert            `json:"alerts"`
+	Plugins     []PluginToggle        `json:"plugins"`
+	Events      []v1.Event            `json:"events"`
+}
+
+// decodeAll decrypts and decodes every record of bucket b, in key order.
+func decodeAll[T any](db *DB, b Bucket) ([]T, error) {
+	out := []T{}
+	err := b.ForEach(func(k, v []byte) error {
+		data, err := db.crypto.Decrypt(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		var item T
+		if err := json.Unmarshal(data, &item); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		out = append(out, item)
+		return nil
+	})
+	return out, err
+}
+
+// Export reads the whole DB in one transaction.
+func (db *DB) Export() (*Dump, error) {
+	d := &Dump{Version: DumpVersion, ExportedAt: time.Now().UTC()}
+	err := db.store.View(func(tx Tx) error {
+		var err error
+		if d.Nodes, err = decodeAll[v1.NodeInfo](db, tx.Bucket(bucketNodes)); err != nil {
+			return err
+		}
+		if d.Services, err = decodeAll[v1.ServiceState](db, tx.Bucket(bucketServices)); err != nil {
+			return err
+		}
+		if d.Deployments, err = decodeAll[v1.DeploymentRecord](db, tx.Bucket(bucketDeployments)); err != nil {
+			return err
+		}
+		if d.Alerts, err = decodeAll[v1.Alert](db, tx.Bucket(bucketAlerts)); err != nil {
+			return err
+		}
+		if d.Plugins, err = decodeAll[PluginToggle](db, tx.Bucket(bucketPlugins)); err != nil {
+			return err
+		}
+		d.Events, err = decodeAll[v1.Event](db, tx.Bucket(bucketEvents))
+		return err
+	})
+	if err != nil {
+		return nil, errs.New(errs.ErrStateRead, "state.Export", err)
+	}
+	return d, nil
+}
+
+// ParseDump reads a dump written by Export as YAML or JSON.
+func ParseDump(data []byte) (*Dump, error) {
+	// YAML is a superset of JSON; re-encoding as JSON applies the same
+	// field names and types Export wrote.
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errs.New(errs.ErrStateRead, "state.ParseDump", err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, errs.New(errs.ErrStateRead, "state.ParseDump", err)
+	}
+	var d Dump
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, errs.New(errs.ErrStateRead, "state.ParseDump", err)
+	}
+	switch {
+	case d.Version == 0:
+		return nil, errs.Newf(errs.ErrStateRead, "state.ParseDump", "not an orbit state dump: no version").
+			WithAdvice("Create dumps with `orbit state export`")
+	case d.Version > DumpVersion:
+		return nil, errs.Newf(errs.ErrStateRead, "state.ParseDump",
+			"dump version %d is newer than this orbit supports (%d)", d.Version, DumpVersion).
+			WithAdvice("Upgrade orbit with `orbit self-update`")
+	}
+	return &d, nil
+}
+
+// Import writes d's records in one transaction, replacing records with the
+// same keys. With replace, the existing nodes, services, deployments,
+// alerts, plugin toggles and events are deleted first; locks are kept.
+func (db *DB) Import(d *Dump, replace bool) error {
+	err := db.store.Update(func(tx Tx) error {
+		if replace {
+			for _, name := range buckets {
+				if string(name) == string(bucketLocks) {
+					continue
+				}
+				if err := tx.DeleteBucket(name); err != nil {
+					return err
+				}
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+		}
+		put := func(bucket []byte, key string, val any) error {
+			if key == "" {
+				return fmt.Errorf("%s: a record has no name or ID", bucket)
+			}
+			data, err := json.Marshal(val)
+			if err != nil {
+				return err
+			}
+			enc, err := db.crypto.Encrypt(data)
+			if err != nil {
+				return err
+			}
+			return tx.Bucket(bucket).PutIndexed([]byte(key), enc, indexOf(val))
+		}
+		for _, n := range d.Nodes {
+			if err := put(bucketNodes, n.Spec.Name, n); err != nil {
+				return err
+			}
+		}
+		for _, s := range d.Services {
+			if err := put(bucketServices, serviceKey(s.Project, s.Node, s.Name), s); err != nil {
+				return err
+			}
+		}
+		for _, r := range d.Deployments {
+			if err := put(bucketDeployments, r.ID, r); err != nil {
+				return err
+			}
+		}
+		for _, a := range d.Alerts {
+			if err := put(bucketAlerts, AlertKey(a), a); err != nil {
+				return err
+			}
+		}
+		for _, t := range d.Plugins {
+			if err := put(bucketPlugins, t.Name, t); err != nil {
+				return err
+			}
+		}
+		events := tx.Bucket(bucketEvents)
+		for _, e := range d.Events {
+			if !isEventKey(e.ID) {
+				seq, err := events.NextSequence()
+				if err != nil {
+					return err
+				}
+				e.ID = string(eventKey(e.Time, seq))
+			}
+			if err := put(bucketEvents, e.ID, e); err != nil {
+				return err
+			}
+		}
+		return db.pruneEvents(events)
+	})
+	if err != nil {
+		return errs.New(errs.ErrStateWrite, "state.Import", err)
+	}
+	return nil
+}
+
+// isEventKey reports whether id has eventKey's form, so it can be kept.
+func isEventKey(id string) bool {
+	if len(id) != 24 {
+		return false
+	}
+	for _, c := range id {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package state_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/state"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := openEvents(t)
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(src.PutNode(v1.NodeInfo{Spec: v1.NodeSpec{Name: "edge", Host: "10.0.0.5"}, Status: v1.NodeOnline, LastSeen: at}))
+	must(src.PutServiceState(v1.ServiceState{Name: "web", Node: "edge", Image: "web:2", RestartCount: 3, StartedAt: at}))
+	must(src.PutDeployment(v1.DeploymentRecord{ID: "d1", Service: "web", Node: "edge", ToImage: "web:2", StartedAt: at}))
+	must(src.PutAlert(v1.Alert{Rule: "disk", Node: "edge", Subject: "edge", Value: 93.5, FiredAt: at}))
+	must(src.SetPluginEnabled(state.PluginToggle{Name: "slack", Enabled: true, ChangedAt: at}))
+	_, err := src.AppendEvent(v1.Event{Time: time.Now(), Type: v1.EventDeploy, Resource: "service/web", Message: "web deploy to web:2"})
+	must(err)
+	unlock, err := src.AcquireLock("edge", "web", "deploy")
+	must(err)
+	defer unlock()
+
+	dump, err := src.Export()
+	must(err)
+	// Write it as YAML, the way `orbit state export` does.
+	data, _ := json.Marshal(dump)
+	var doc yaml.Node
+	must(yaml.Unmarshal(data, &doc))
+	text, err := yaml.Marshal(&doc)
+	must(err)
+
+	parsed, err := state.ParseDump(text)
+	must(err)
+	dst := openEvents(t)
+	must(dst.PutServiceState(v1.ServiceState{Name: "stale", Node: "local"}))
+	must(dst.Import(parsed, true))
+
+	got, err := dst.Export()
+	must(err)
+	got.ExportedAt = dump.ExportedAt
+	if !reflect.DeepEqual(normalize(t, got), normalize(t, dump)) {
+		t.Errorf("round trip:\n got %+v\nwant %+v", got, dump)
+	}
+	if locks, _ := dst.ListLocks(); len(locks) != 0 {
+		t.Errorf("locks were imported: %+v", locks)
+	}
+
+	// Without replace, records are merged.
+	must(dst.PutServiceState(v1.ServiceState{Name: "api", Node: "edge"}))
+	must(dst.Import(parsed, false))
+	if states, _ := dst.ListServiceStates("edge"); len(states) != 2 {
+		t.Errorf("merge: services = %+v", states)
+	}
+}
+
+// normalize compares dumps by their JSON, as times lose their monotonic
+// clock reading and location on the way through.
+func normalize(t *testing.T, d *state.Dump) any {
+	t.Helper()
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out any
+	_ = json.Unmarshal(data, &out)
+	return out
+}
+
+func TestParseDumpRejectsForeignFiles(t *testing.T) {
+	for _, text := range []string{"services: []\n", "version: 99\n", "{not yaml"} {
+		if _, err := state.ParseDump([]byte(text)); err == nil {
+			t.Errorf("expected an error for %q", text)
+		}
+	}
+	if _, err := state.ParseDump([]byte(`{"version": 1, "services": [{"name": "web", "node": "local"}]}`)); err != nil {
+		t.Errorf("JSON dump: %v", err)
+	}
+}
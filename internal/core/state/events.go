@@ -0,0 +1,166 @@
+// Package state: the event log behind timelines and notification replay.
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// EventRetention caps the event log. AppendEvent drops the oldest events
+// beyond MaxEvents and those older than MaxAge; a zero field is no limit.
+type EventRetention struct {
+	MaxEvents int
+	MaxAge    time.Duration
+}
+
+// DefaultEventRetention is the retention of a newly opened DB.
+var DefaultEventRetention = EventRetention{MaxEvents: 10000, MaxAge: 30 * 24 * time.Hour}
+
+// SetEventRetention changes the retention applied by later AppendEvent calls.
+func (db *DB) SetEventRetention(r EventRetention) {
+	db.events = r
+}
+
+// EventQuery selects events from the log. Zero fields match everything.
+type EventQuery struct {
+	Since    time.Time // events at or after this time
+	Until    time.Time // events at or before this time
+	Type     string
+	Resource string
+	Node     string
+	Limit    int // only the newest Limit matching events
+}
+
+func (q EventQuery) match(e v1.Event) bool {
+	return (q.Type == "" || e.Type == q.Type) &&
+		(q.Resource == "" || e.Resource == q.Resource) &&
+		(q.Node == "" || e.Node == q.Node)
+}
+
+// eventKey orders events by time, then by the bucket's sequence for events
+// recorded in the same nanosecond. The time is readable from the key alone,
+// so range queries and pruning skip decrypting events they do not return.
+func eventKey(t time.Time, seq uint64) []byte {
+	return []byte(fmt.Sprintf("%016x%08x", t.UnixNano(), seq&0xffffffff))
+}
+
+// eventTime is the time encoded in an event key.
+func eventTime(k []byte) time.Time {
+	if len(k) < 16 {
+		return time.Time{}
+	}
+	n, _ := strconv.ParseInt(string(k[:16]), 16, 64)
+	return time.Unix(0, n).UTC()
+}
+
+// AppendEvent adds e to the log and applies the retention. It returns e as
+// stored: with its ID, and its time and severity defaulted when unset.
+func (db *DB) AppendEvent(e v1.Event) (v1.Event, error) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	if e.Severity == "" {
+		e.Severity = v1.SeverityInfo
+	}
+	err := db.store.Update(func(tx Tx) error {
+		b := tx.Bucket(bucketEvents)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := eventKey(e.Time, seq)
+		e.ID = string(key)
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		enc, err := db.crypto.Encrypt(data)
+		if err != nil {
+			return err
+		}
+		if err := b.PutIndexed(key, enc, indexOf(e)); err != nil {
+			return err
+		}
+		return db.pruneEvents(b)
+	})
+	if err != nil {
+		return e, errs.Wrap(err, errs.ErrStateWrite, "state.AppendEvent").WithNode(e.Node)
+	}
+	return e, nil
+}
+
+// pruneEvents deletes the oldest events the retention no longer keeps.
+func (db *DB) pruneEvents(b Bucket) error {
+	r := db.events
+	var cutoff time.Time
+	if r.MaxAge > 0 {
+		cutoff = time.Now().Add(-r.MaxAge)
+	}
+	excess := 0
+	if r.MaxEvents > 0 {
+		excess = b.Len() - r.MaxEvents
+	}
+	var drop [][]byte
+	err := b.Scan(Range{}, func(k, _ []byte) error {
+		if len(drop) >= excess && !eventTime(k).Before(cutoff) {
+			return ErrStop
+		}
+		drop = append(drop, bytes.Clone(k))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range drop {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListEvents returns the events matching q, oldest first.
+func (db *DB) ListEvents(q EventQuery) ([]v1.Event, error) {
+	var events []v1.Event
+	// Walk back from Until so Limit can stop at the newest matches.
+	r := Range{Reverse: true, Where: Index{Node: q.Node}}
+	if !q.Since.IsZero() {
+		r.From = eventKey(q.Since, 0)
+	}
+	if !q.Until.IsZero() {
+		r.To = eventKey(q.Until.Add(time.Nanosecond), 0)
+	}
+	err := db.store.View(func(tx Tx) error {
+		return tx.Bucket(bucketEvents).Scan(r, func(k, v []byte) error {
+			var e v1.Event
+			data, err := db.crypto.Decrypt(v)
+			if err != nil {
+				return errs.New(errs.ErrStateRead, "state.ListEvents.Decrypt", err).WithNode(string(k))
+			}
+			if err := json.Unmarshal(data, &e); err != nil {
+				return errs.New(errs.ErrStateRead, "state.ListEvents.Unmarshal", err).WithNode(string(k))
+			}
+			if !q.match(e) {
+				return nil
+			}
+			events = append(events, e)
+			if q.Limit > 0 && len(events) == q.Limit {
+				return ErrStop
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, errs.ErrStateRead, "state.ListEvents")
+	}
+	slices.Reverse(events)
+	return events, nil
+}
@@ -0,0 +1,95 @@
+package state_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+func openEvents(t *testing.T) *state.DB {
+	t.Helper()
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "orbit_test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func messages(events []v1.Event) []string {
+	out := make([]string, len(events))
+	for i, e := range events {
+		out[i] = e.Message
+	}
+	return out
+}
+
+func TestEventQueries(t *testing.T) {
+	db := openEvents(t)
+	t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i, e := range []v1.Event{
+		{Type: v1.EventDeploy, Resource: "service/web", Message: "web v1"},
+		{Type: v1.EventAlert, Resource: "node/edge-1", Severity: v1.SeverityWarning, Message: "disk"},
+		{Type: v1.EventDeploy, Resource: "service/api", Message: "api v1"},
+		{Type: v1.EventDeploy, Resource: "service/web", Message: "web v2"},
+	} {
+		e.Time = t0.Add(time.Duration(i) * time.Minute)
+		if _, err := db.AppendEvent(e); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	// Two events in the same instant keep their order.
+	for _, m := range []string{"same 1", "same 2"} {
+		db.AppendEvent(v1.Event{Type: v1.EventScale, Resource: "service/web", Time: t0.Add(10 * time.Minute), Message: m})
+	}
+
+	for _, tc := range []struct {
+		name string
+		q    state.EventQuery
+		want []string
+	}{
+		{"all", state.EventQuery{}, []string{"web v1", "disk", "api v1", "web v2", "same 1", "same 2"}},
+		{"type", state.EventQuery{Type: v1.EventDeploy}, []string{"web v1", "api v1", "web v2"}},
+		{"resource", state.EventQuery{Resource: "service/web", Limit: 2}, []string{"same 1", "same 2"}},
+		{"range", state.EventQuery{Since: t0.Add(time.Minute), Until: t0.Add(3 * time.Minute)}, []string{"disk", "api v1", "web v2"}},
+		{"limit", state.EventQuery{Until: t0.Add(2 * time.Minute), Limit: 2}, []string{"disk", "api v1"}},
+	} {
+		events, err := db.ListEvents(tc.q)
+		if got := messages(events); err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, %v; want %v", tc.name, got, err, tc.want)
+		}
+	}
+
+	events, _ := db.ListEvents(state.EventQuery{Type: v1.EventAlert})
+	if len(events) != 1 || events[0].ID == "" || events[0].Severity != v1.SeverityWarning {
+		t.Errorf("alert event = %+v", events)
+	}
+	events, _ = db.ListEvents(state.EventQuery{Type: v1.EventDeploy, Limit: 1})
+	if events[0].Severity != v1.SeverityInfo {
+		t.Errorf("default severity = %q", events[0].Severity)
+	}
+}
+
+func TestEventRetention(t *testing.T) {
+	db := openEvents(t)
+	db.SetEventRetention(state.EventRetention{MaxEvents: 3, MaxAge: 24 * time.Hour})
+
+	now := time.Now()
+	db.AppendEvent(v1.Event{Time: now.Add(-48 * time.Hour), Message: "stale"})
+	for _, m := range []string{"1", "2", "3", "4"} {
+		db.AppendEvent(v1.Event{Message: m})
+	}
+	events, err := db.ListEvents(state.EventQuery{})
+	if got := messages(events); err != nil || !slices.Equal(got, []string{"2", "3", "4"}) {
+		t.Fatalf("kept %v, %v", got, err)
+	}
+	if n, err := db.Check(); err != nil || n != 3 {
+		t.Errorf("check = %d, %v", n, err)
+	}
+}
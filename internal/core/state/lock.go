@@ -0,0 +1,168 @@
+// Package state: per-service operation locks with stale-lock detection.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// LockTTL is how long a lock is honoured when its holder's liveness cannot
+// be checked (a different host, or a platform without process probing).
+const LockTTL = time.Hour
+
+// Lock records who holds the exclusive right to change a service.
+type Lock struct {
+	Project    string    `json:"project,omitempty"`
+	Service    string    `json:"service"`
+	Node       string    `json:"node"`
+	Operation  string    `json:"operation"` // deploy | scale | up | down | rollback
+	Holder     string    `json:"holder"`    // user@host
+	Host       string    `json:"host"`
+	PID        int       `json:"pid"`
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+// Stale reports whether the lock's holder is gone: its process no longer
+// exists on this host, or the lock is older than LockTTL.
+func (l Lock) Stale(now time.Time) bool {
+	if host, _ := os.Hostname(); l.Host == host {
+		if alive, known := processAlive(l.PID); known {
+			return !alive
+		}
+	}
+	return now.Sub(l.AcquiredAt) > LockTTL
+}
+
+// AcquireLock takes the lock for service on node for operation. A lock held
+// by someone else fails with ErrStateLocked unless it is stale, in which case
+// it is taken over. The returned func releases the lock.
+func (db *DB) AcquireLock(node, service, operation string) (func(), error) {
+	lock := newLock(node, service, operation)
+	lock.Project = db.writeProject()
+	key := lockKey(lock.Project, node, service)
+
+	var locked error
+	err := db.store.Update(func(tx Tx) error {
+		b := tx.Bucket(bucketLocks)
+		if raw := b.Get([]byte(key)); raw != nil {
+			var held Lock
+			data, err := db.crypto.Decrypt(raw)
+			if err == nil {
+				err = json.Unmarshal(data, &held)
+			}
+			if err == nil && !held.Stale(time.Now()) {
+				locked = errs.Newf(errs.ErrStateLocked, "state.AcquireLock",
+					"%s is locked by %s (pid %d) for %s since %s",
+					service, held.Holder, held.PID, held.Operation, held.AcquiredAt.Local().Format(time.Stamp)).
+					WithNode(node).
+					WithAdvice(fmt.Sprintf("Wait for it to finish, or run `orbit locks unlock %s` if it was interrupted", service))
+				return nil
+			}
+		}
+		data, err := json.Marshal(lock)
+		if err != nil {
+			return errs.New(errs.ErrStateWrite, "state.AcquireLock.Marshal", err)
+		}
+		enc, err := db.crypto.Encrypt(data)
+		if err != nil {
+			return errs.New(errs.ErrStateWrite, "state.AcquireLock.Encrypt", err)
+		}
+		return b.PutIndexed([]byte(key), enc, indexOf(lock))
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, errs.ErrStateWrite, "state.AcquireLock")
+	}
+	if locked != nil {
+		return nil, locked
+	}
+	return func() { db.releaseLock(lock) }, nil
+}
+
+// releaseLock deletes the lock only if it is still the one we took, so a
+// takeover after staleness is not undone by the original holder.
+func (db *DB) releaseLock(lock Lock) {
+	_ = db.store.Update(func(tx Tx) error {
+		b := tx.Bucket(bucketLocks)
+		key := []byte(lockKey(lock.Project, lock.Node, lock.Service))
+		raw := b.Get(key)
+		if raw == nil {
+			return nil
+		}
+		var held Lock
+		data, err := db.crypto.Decrypt(raw)
+		if err != nil || json.Unmarshal(data, &held) != nil {
+			return nil
+		}
+		if held.Host == lock.Host && held.PID == lock.PID && held.AcquiredAt.Equal(lock.AcquiredAt) {
+			return b.Delete(key)
+		}
+		return nil
+	})
+}
+
+// ListLocks returns every lock held in the handle's project, stale or not.
+func (db *DB) ListLocks() ([]Lock, error) {
+	var locks []Lock
+	err := db.store.View(func(tx Tx) error {
+		return tx.Bucket(bucketLocks).ForEach(func(k, v []byte) error {
+			var l Lock
+			data, err := db.crypto.Decrypt(v)
+			if err != nil {
+				return errs.New(errs.ErrStateRead, "state.ListLocks.Decrypt", err).WithNode(string(k))
+			}
+			if err := json.Unmarshal(data, &l); err != nil {
+				return errs.New(errs.ErrStateRead, "state.ListLocks.Unmarshal", err).WithNode(string(k))
+			}
+			if db.owns(l.Project) {
+				locks = append(locks, l)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, errs.ErrStateRead, "state.ListLocks")
+	}
+	return locks, nil
+}
+
+// ForceUnlock removes the lock on service in the handle's project regardless
+// of holder. It reports whether a lock existed.
+func (db *DB) ForceUnlock(node, service string) (bool, error) {
+	var existed bool
+	err := db.store.Update(func(tx Tx) error {
+		b := tx.Bucket(bucketLocks)
+		key := []byte(lockKey(db.writeProject(), node, service))
+		existed = b.Get(key) != nil
+		return b.Delete(key)
+	})
+	if err != nil {
+		return false, errs.New(errs.ErrStateWrite, "state.ForceUnlock", err).WithNode(service)
+	}
+	return existed, nil
+}
+
+func lockKey(project, node, service string) string {
+	return serviceKey(project, node, service)
+}
+
+func newLock(node, service, operation string) Lock {
+	host, _ := os.Hostname()
+	holder := host
+	if u, err := user.Current(); err == nil {
+		holder = u.Username + "@" + host
+	}
+	return Lock{
+		Service:    service,
+		Node:       node,
+		Operation:  operation,
+		Holder:     holder,
+		Host:       host,
+		PID:        os.Getpid(),
+		AcquiredAt: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package state
+
+// processAlive cannot probe processes on this platform; locks expire by age.
+func processAlive(pid int) (alive, known bool) {
+	return false, false
+}
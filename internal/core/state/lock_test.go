@@ -0,0 +1,75 @@
+package state_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+func TestServiceLock(t *testing.T) {
+	os.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	defer os.Unsetenv(encryption.EnvSecretKey)
+
+	db, err := state.Open(filepath.Join(t.TempDir(), "orbit_test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	unlock, err := db.AcquireLock("local", "web", "deploy")
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+
+	// Our own PID is alive, so a second acquire must be refused.
+	if _, err := db.AcquireLock("local", "web", "scale"); !errs.IsCode(err, errs.ErrStateLocked) {
+		t.Fatalf("second acquire: want ErrStateLocked, got %v", err)
+	}
+	// Other services and nodes are independent.
+	if _, err := db.AcquireLock("local", "api", "deploy"); err != nil {
+		t.Fatalf("other service: %v", err)
+	}
+	if _, err := db.AcquireLock("edge", "web", "deploy"); err != nil {
+		t.Fatalf("other node: %v", err)
+	}
+
+	locks, err := db.ListLocks()
+	if err != nil || len(locks) != 3 {
+		t.Fatalf("ListLocks = %d locks, %v; want 3", len(locks), err)
+	}
+
+	unlock()
+	relock, err := db.AcquireLock("local", "web", "scale")
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	relock()
+
+	existed, err := db.ForceUnlock("local", "api")
+	if err != nil || !existed {
+		t.Fatalf("ForceUnlock = %v, %v; want true", existed, err)
+	}
+}
+
+func TestLockStale(t *testing.T) {
+	host, _ := os.Hostname()
+	now := time.Now()
+
+	live := state.Lock{Host: host, PID: os.Getpid(), AcquiredAt: now}
+	if live.Stale(now) {
+		t.Error("lock held by this process reported stale")
+	}
+	old := state.Lock{Host: "elsewhere", PID: 1, AcquiredAt: now.Add(-2 * state.LockTTL)}
+	if !old.Stale(now) {
+		t.Error("lock older than LockTTL not reported stale")
+	}
+	remote := state.Lock{Host: "elsewhere", PID: 1, AcquiredAt: now}
+	if remote.Stale(now) {
+		t.Error("fresh lock from another host reported stale")
+	}
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin
+
+package state
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive probes pid with signal 0. EPERM means the process exists but
+// belongs to another user.
+func processAlive(pid int) (alive, known bool) {
+	if pid <= 0 {
+		return false, true
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM), true
+}
@@ -0,0 +1,156 @@
+// Package state: opening the DB alongside other orbit processes.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// DefaultLockWait is how long Open waits for another process to release the
+// DB before failing.
+const DefaultLockWait = 2 * time.Second
+
+// OpenOptions control which backend the DB is kept in and how it is shared
+// with other orbit processes. A BoltDB file is open for writing by one
+// process at a time, or read-only by any number; SQLite lets readers carry
+// on while one process writes.
+type OpenOptions struct {
+	Backend  string        // BackendBolt ("" too) or BackendSQLite
+	ReadOnly bool          // open for reading only, shared with other readers
+	Wait     time.Duration // how long to wait for the DB; 0 is DefaultLockWait, negative waits indefinitely
+	Holder   string        // what is opening it, e.g. "orbit deploy", shown to processes kept waiting
+}
+
+// The state DB's file in orbit's home, for each backend.
+const (
+	BoltFilename   = "state.db"
+	SQLiteFilename = "state.sqlite"
+)
+
+// Filename is the state DB's file in orbit's home when kept in backend.
+func Filename(backend string) string {
+	if backend == BackendSQLite {
+		return SQLiteFilename
+	}
+	return BoltFilename
+}
+
+// Locate returns the state DB in dir and its backend: state.sqlite once the
+// DB has been converted to SQLite, otherwise state.db.
+func Locate(dir string) (path, backend string) {
+	if _, err := os.Stat(filepath.Join(dir, SQLiteFilename)); err == nil {
+		return filepath.Join(dir, SQLiteFilename), BackendSQLite
+	}
+	return filepath.Join(dir, BoltFilename), BackendBolt
+}
+
+// Holder describes the process that has a BoltDB file open for writing. It
+// is kept in the DB's holder file, beside it, while the DB is open.
+type Holder struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command,omitempty"`
+	Since   time.Time `json:"since"`
+}
+
+// holderFile is the file describing the process that has the DB at path open.
+func holderFile(path string) string {
+	return path + ".holder"
+}
+
+// OpenWithOptions opens (or creates) the state database at path as opts
+// asks. A DB that another process keeps locked past opts.Wait fails with
+// ErrStateLocked, naming the process when it is known.
+func OpenWithOptions(path string, opts OpenOptions) (*DB, error) {
+	cryptoEngine, err := encryption.NewEngine()
+	if err != nil {
+		return nil, errs.Wrap(err, errs.ErrInternal, "state.Open.InitCrypto")
+	}
+
+	store, err := openBackend(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{store: store, path: path, crypto: cryptoEngine, events: DefaultEventRetention}, nil
+}
+
+// openBackend opens the file at path in the backend opts names.
+func openBackend(path string, opts OpenOptions) (Backend, error) {
+	switch opts.Backend {
+	case "", BackendBolt:
+		return openBolt(path, opts)
+	case BackendSQLite:
+		return openSQLite(path, opts)
+	}
+	return nil, errs.Newf(errs.ErrValidation, "state.Open", "unknown state backend %q (valid: %s, %s)", opts.Backend, BackendBolt, BackendSQLite)
+}
+
+// wait is the lock wait for the backend: never 0, which BoltDB takes to
+// mean indefinitely.
+func (o OpenOptions) wait() time.Duration {
+	switch {
+	case o.Wait == 0:
+		return DefaultLockWait
+	case o.Wait < 0:
+		return 0
+	}
+	return o.Wait
+}
+
+// createBuckets ensures all buckets exist.
+func createBuckets(b Backend) error {
+	return b.Update(func(tx Tx) error {
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return errs.New(errs.ErrStateWrite, "state.InitBuckets", err)
+			}
+		}
+		return nil
+	})
+}
+
+// lockedErr is the error for a DB another process kept locked.
+func lockedErr(path string, wait time.Duration) error {
+	who := "another orbit process has the state DB open"
+	if h, err := ReadHolder(path); err == nil && h != nil {
+		who = fmt.Sprintf("another orbit process holds the state DB lock (pid %d", h.PID)
+		if h.Command != "" {
+			who += ", " + h.Command
+		}
+		who += fmt.Sprintf(", since %s)", h.Since.Local().Format(time.Stamp))
+	}
+	advice := "Wait for it to finish, or retry with a longer --lock-wait, such as --lock-wait 1m"
+	if wait < 0 {
+		advice = "Stop the process holding it"
+	}
+	return errs.Newf(errs.ErrStateLocked, "state.Open", "%s", who).WithAdvice(advice)
+}
+
+// ReadHolder returns the process that has the DB at path open for writing,
+// or nil when none does, or it has exited without closing the DB.
+func ReadHolder(path string) (*Holder, error) {
+	data, err := os.ReadFile(holderFile(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var h Holder
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, err
+	}
+	if host, _ := os.Hostname(); h.Host == host {
+		if alive, known := processAlive(h.PID); known && !alive {
+			return nil, nil
+		}
+	}
+	return &h, nil
+}
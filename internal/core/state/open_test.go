@@ -0,0 +1,55 @@
+package state_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+func TestOpenSharing(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	path := filepath.Join(t.TempDir(), "state.db")
+	wait := 50 * time.Millisecond
+
+	// Readers of a DB that does not exist yet get an empty one.
+	r1, err := state.OpenWithOptions(path, state.OpenOptions{ReadOnly: true, Wait: wait})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := state.OpenWithOptions(path, state.OpenOptions{ReadOnly: true, Wait: wait})
+	if err != nil {
+		t.Fatalf("second reader: %v", err)
+	}
+	if states, err := r2.ListServiceStates(""); err != nil || len(states) != 0 {
+		t.Errorf("states = %+v, %v", states, err)
+	}
+	if err := r1.PutServiceState(v1.ServiceState{Name: "web", Node: "local"}); err == nil {
+		t.Error("a read-only DB accepted a write")
+	}
+	if _, err := state.OpenWithOptions(path, state.OpenOptions{Wait: wait}); !errs.IsCode(err, errs.ErrStateLocked) {
+		t.Errorf("writer beside readers: want ErrStateLocked, got %v", err)
+	}
+	r1.Close()
+	r2.Close()
+
+	w, err := state.OpenWithOptions(path, state.OpenOptions{Wait: wait, Holder: "orbit deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = state.OpenWithOptions(path, state.OpenOptions{ReadOnly: true, Wait: wait})
+	if !errs.IsCode(err, errs.ErrStateLocked) || !strings.Contains(err.Error(), fmt.Sprintf("pid %d, orbit deploy", os.Getpid())) {
+		t.Errorf("reader beside a writer: %v", err)
+	}
+	w.Close()
+	if h, err := state.ReadHolder(path); h != nil || err != nil {
+		t.Errorf("holder after close = %+v, %v", h, err)
+	}
+}
@@ -0,0 +1,48 @@
+// Package state: plugin enable/disable overrides set with `orbit plugin`.
+package state
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// PluginToggle records that a plugin was enabled or disabled from the CLI.
+// It overrides plugins.<name>.enabled in orbit.yaml.
+type PluginToggle struct {
+	Name      string    `json:"name"` // lower-case plugin name
+	Enabled   bool      `json:"enabled"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// SetPluginEnabled records t, replacing any earlier toggle of the same plugin.
+func (db *DB) SetPluginEnabled(t PluginToggle) error {
+	if err := db.putJSON(bucketPlugins, t.Name, t); err != nil {
+		return errs.Wrap(err, errs.ErrStateWrite, "state.SetPluginEnabled")
+	}
+	return nil
+}
+
+// ListPluginToggles returns every recorded toggle.
+func (db *DB) ListPluginToggles() ([]PluginToggle, error) {
+	var toggles []PluginToggle
+	err := db.store.View(func(tx Tx) error {
+		return tx.Bucket(bucketPlugins).ForEach(func(k, v []byte) error {
+			var t PluginToggle
+			data, err := db.crypto.Decrypt(v)
+			if err != nil {
+				return errs.New(errs.ErrStateRead, "state.ListPluginToggles.Decrypt", err).WithNode(string(k))
+			}
+			if err := json.Unmarshal(data, &t); err != nil {
+				return errs.New(errs.ErrStateRead, "state.ListPluginToggles.Unmarshal", err).WithNode(string(k))
+			}
+			toggles = append(toggles, t)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, errs.ErrStateRead, "state.ListPluginToggles")
+	}
+	return toggles, nil
+}
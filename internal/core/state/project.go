@@ -0,0 +1,70 @@
+// Package state: keeping the service state, history and locks of projects
+// that share a DB apart.
+package state
+
+import (
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// AllProjects is the project of a handle whose listings span every project.
+// Its other reads and writes act on the default project.
+const AllProjects = "*"
+
+// Project returns a handle on the same DB whose service state, deployment
+// history and locks are project's, named by project.name in orbit.yaml.
+// Nodes, alerts, plugin toggles and events stay shared. "" is the default
+// project. Records written before projects were kept apart belong to none:
+// every project reads them until one writes the same service and claims it.
+func (db *DB) Project(name string) *DB {
+	scoped := *db
+	scoped.project = name
+	return &scoped
+}
+
+// ProjectName is the project the handle is scoped to.
+func (db *DB) ProjectName() string {
+	return db.project
+}
+
+// writeProject is the project records written through db belong to.
+func (db *DB) writeProject() string {
+	if db.project == AllProjects {
+		return ""
+	}
+	return db.project
+}
+
+// owns reports whether a record of project is visible through db.
+func (db *DB) owns(project string) bool {
+	return db.project == AllProjects || project == "" || project == db.project
+}
+
+// serviceKey is a service state's key. The default project keeps the key
+// records had before projects were kept apart.
+func serviceKey(project, node, name string) string {
+	if project == "" {
+		return node + "/" + name
+	}
+	return project + "/" + node + "/" + name
+}
+
+// dropShadowed removes unclaimed records of services db's project has its
+// own record for.
+func (db *DB) dropShadowed(states []v1.ServiceState) []v1.ServiceState {
+	if db.project == "" || db.project == AllProjects {
+		return states
+	}
+	own := map[string]bool{}
+	for _, s := range states {
+		if s.Project == db.project {
+			own[s.Node+"/"+s.Name] = true
+		}
+	}
+	out := states[:0]
+	for _, s := range states {
+		if s.Project != "" || !own[s.Node+"/"+s.Name] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package state_test
+
+import (
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/state"
+)
+
+func TestProjectsKeepStateApart(t *testing.T) {
+	db := openEvents(t)
+	shop, blog := db.Project("shop"), db.Project("blog")
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A record from before projects were kept apart is every project's.
+	must(db.PutServiceState(v1.ServiceState{Name: "web", Node: "local", Image: "web:old"}))
+	if s, err := blog.GetServiceState("local", "web"); err != nil || s == nil || s.Image != "web:old" {
+		t.Fatalf("unclaimed web = %+v, %v", s, err)
+	}
+
+	// Writing it claims it for that project alone.
+	must(shop.PutServiceState(v1.ServiceState{Name: "web", Node: "local", Image: "shop:1"}))
+	must(blog.PutServiceState(v1.ServiceState{Name: "web", Node: "local", Image: "blog:1"}))
+	for p, want := range map[*state.DB]string{shop: "shop:1", blog: "blog:1"} {
+		states, err := p.ListServiceStates("")
+		if err != nil || len(states) != 1 || states[0].Image != want || states[0].Project != p.ProjectName() {
+			t.Errorf("%s: states = %+v, %v", p.ProjectName(), states, err)
+		}
+	}
+	if s, _ := db.GetServiceState("local", "web"); s != nil {
+		t.Errorf("default project still sees %+v", s)
+	}
+	all, _ := db.Project(state.AllProjects).ListServiceStates("")
+	if len(all) != 2 {
+		t.Errorf("all projects = %+v", all)
+	}
+
+	must(shop.PutDeployment(v1.DeploymentRecord{ID: "d1", Service: "web", Node: "local"}))
+	if recs, _ := blog.ListDeployments("web"); len(recs) != 0 {
+		t.Errorf("blog sees shop's history: %+v", recs)
+	}
+	if recs, _ := shop.ListDeployments("web"); len(recs) != 1 || recs[0].Project != "shop" {
+		t.Errorf("shop history = %+v", recs)
+	}
+
+	// Each project's web has its own lock.
+	unlock, err := shop.AcquireLock("local", "web", "deploy")
+	must(err)
+	defer unlock()
+	unlockBlog, err := blog.AcquireLock("local", "web", "deploy")
+	must(err)
+	unlockBlog()
+	if locks, _ := blog.ListLocks(); len(locks) != 0 {
+		t.Errorf("blog locks = %+v", locks)
+	}
+
+	must(blog.DeleteServiceState("local", "web"))
+	if s, _ := shop.GetServiceState("local", "web"); s == nil {
+		t.Error("deleting blog's web deleted shop's")
+	}
+}
@@ -0,0 +1,48 @@
+// Package state: re-encrypting the DB under a new master key.
+package state
+
+import (
+	"bytes"
+
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// Rekey re-encrypts every record with to, in one transaction, and uses it
+// from then on. save runs last inside the transaction to store the new key;
+// if it fails, the DB is left encrypted with the old one.
+func (db *DB) Rekey(to *encryption.Engine, save func() error) error {
+	err := db.store.Update(func(tx Tx) error {
+		for _, name := range buckets {
+			b := tx.Bucket(name)
+			var keys, values [][]byte
+			err := b.ForEach(func(k, v []byte) error {
+				data, err := db.crypto.Decrypt(v)
+				if err != nil {
+					return errs.New(errs.ErrStateRead, "state.Rekey.Decrypt", err).WithNode(string(name) + "/" + string(k))
+				}
+				enc, err := to.Encrypt(data)
+				if err != nil {
+					return err
+				}
+				keys, values = append(keys, bytes.Clone(k)), append(values, enc)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			// A bucket must not be written while it is iterated.
+			for i, k := range keys {
+				if err := b.Put(k, values[i]); err != nil {
+					return err
+				}
+			}
+		}
+		return save()
+	})
+	if err != nil {
+		return errs.Wrap(err, errs.ErrStateWrite, "state.Rekey")
+	}
+	db.crypto = to
+	return nil
+}
@@ -0,0 +1,65 @@
+package state_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+func TestRekey(t *testing.T) {
+	const oldKey, newKey = "12345678901234567890123456789012", "abcdefghijklmnopqrstuvwxyz012345"
+	path := filepath.Join(t.TempDir(), "state.db")
+	open := func(key string) *state.DB {
+		t.Helper()
+		t.Setenv(encryption.EnvSecretKey, key)
+		db, err := state.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	engine := func(key string) *encryption.Engine {
+		t.Helper()
+		t.Setenv(encryption.EnvSecretKey, key)
+		e, err := encryption.NewEngine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	db := open(oldKey)
+	if err := db.PutServiceState(v1.ServiceState{Name: "web", Node: "local", Image: "web:1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AppendEvent(v1.Event{Type: v1.EventDeploy, Message: "web deploy"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A key that cannot be stored leaves the DB as it was.
+	if err := db.Rekey(engine(newKey), func() error { return errors.New("keychain locked") }); err == nil {
+		t.Fatal("Rekey succeeded though the key was not saved")
+	}
+	if s, err := db.GetServiceState("local", "web"); err != nil || s == nil {
+		t.Fatalf("after a failed rekey: %+v, %v", s, err)
+	}
+
+	saved := false
+	if err := db.Rekey(engine(newKey), func() error { saved = true; return nil }); err != nil || !saved {
+		t.Fatalf("rekey: %v, saved = %v", err, saved)
+	}
+	db.Close()
+
+	db = open(newKey)
+	defer db.Close()
+	if n, err := db.Check(); err != nil || n != 2 {
+		t.Fatalf("check after rekey: %d records, %v", n, err)
+	}
+	if s, _ := db.GetServiceState("local", "web"); s == nil || s.Image != "web:1" {
+		t.Errorf("web = %+v", s)
+	}
+}
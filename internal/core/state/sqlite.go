@@ -0,0 +1,358 @@
+// Package state: the SQLite backend, which indexes records by node, service
+// and time, and lets readers carry on while one process writes.
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// BackendSQLite names the SQLite backend.
+const BackendSQLite = "sqlite"
+
+// sqliteSchema keeps every bucket in one table, in key order, with the
+// columns of a record's Index to look it up by.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS buckets (
+	name TEXT PRIMARY KEY,
+	seq  INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS records (
+	bucket  TEXT NOT NULL,
+	key     BLOB NOT NULL,
+	value   BLOB NOT NULL,
+	project TEXT NOT NULL DEFAULT '',
+	node    TEXT NOT NULL DEFAULT '',
+	service TEXT NOT NULL DEFAULT '',
+	time    INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (bucket, key)
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS records_by_node ON records (bucket, node, key);
+CREATE INDEX IF NOT EXISTS records_by_service ON records (bucket, service, time);
+`
+
+type sqliteBackend struct {
+	read  *sql.DB // deferred transactions, shared with other readers
+	write *sql.DB // one connection taking the write lock as it begins; nil when read-only
+	path  string
+	wait  int64 // busy timeout, in milliseconds
+}
+
+// openSQLite opens the SQLite file at path in WAL mode, so a writer only
+// holds the lock for the length of a transaction and never blocks readers.
+func openSQLite(path string, opts OpenOptions) (*sqliteBackend, error) {
+	b := &sqliteBackend{path: path, wait: opts.wait().Milliseconds()}
+	if opts.Wait < 0 {
+		b.wait = math.MaxInt32
+	}
+
+	if opts.ReadOnly {
+		// A read-only open cannot create the file or its schema.
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			created, err := openSQLite(path, OpenOptions{Wait: opts.Wait})
+			if err != nil {
+				return nil, err
+			}
+			created.Close()
+		}
+	} else {
+		w, err := sql.Open("sqlite", b.dsn("rwc", "immediate"))
+		if err != nil {
+			return nil, errs.New(errs.ErrStateRead, "state.Open", err)
+		}
+		w.SetMaxOpenConns(1)
+		b.write = w
+		if _, err := w.Exec(`PRAGMA journal_mode = WAL`); err != nil {
+			w.Close()
+			return nil, b.openErr(err, opts.Wait)
+		}
+		if _, err := w.Exec(sqliteSchema); err != nil {
+			w.Close()
+			return nil, b.openErr(err, opts.Wait)
+		}
+	}
+
+	mode := "rw"
+	if opts.ReadOnly {
+		mode = "ro"
+	}
+	r, err := sql.Open("sqlite", b.dsn(mode, "deferred"))
+	if err != nil {
+		b.Close()
+		return nil, errs.New(errs.ErrStateRead, "state.Open", err)
+	}
+	b.read = r
+	if err := r.Ping(); err != nil {
+		b.Close()
+		return nil, b.openErr(err, opts.Wait)
+	}
+	if b.write != nil {
+		if err := createBuckets(b); err != nil {
+			b.Close()
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (b *sqliteBackend) dsn(mode, txlock string) string {
+	q := url.Values{}
+	q.Set("mode", mode)
+	q.Set("_pragma", fmt.Sprintf("busy_timeout(%d)", b.wait))
+	q.Set("_txlock", txlock)
+	return "file:" + b.path + "?" + q.Encode()
+}
+
+func (b *sqliteBackend) openErr(err error, wait time.Duration) error {
+	if busy(err) {
+		return lockedErr(b.path, wait)
+	}
+	return errs.New(errs.ErrStateRead, "state.Open", err).WithAdvice("Ensure you have file permissions on " + b.path)
+}
+
+// busy reports whether err is SQLite giving up waiting for a lock.
+func busy(err error) bool {
+	var se *sqlite.Error
+	if !errors.As(err, &se) {
+		return false
+	}
+	code := se.Code() & 0xff // the primary code of an extended one
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+func (b *sqliteBackend) Name() string { return BackendSQLite }
+
+func (b *sqliteBackend) View(fn func(Tx) error) error {
+	return b.run(b.read, fn)
+}
+
+func (b *sqliteBackend) Update(fn func(Tx) error) error {
+	if b.write == nil {
+		return errs.Newf(errs.ErrStateWrite, "state.Update", "the state DB is open read-only")
+	}
+	return b.run(b.write, fn)
+}
+
+func (b *sqliteBackend) run(db *sql.DB, fn func(Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		if busy(err) {
+			return lockedErr(b.path, 0)
+		}
+		return err
+	}
+	if err := fn(sqliteTx{tx}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		if busy(err) {
+			return lockedErr(b.path, 0)
+		}
+		return err
+	}
+	return nil
+}
+
+// Check runs SQLite's integrity check.
+func (b *sqliteBackend) Check() error {
+	rows, err := b.read.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var problems []error
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return err
+		}
+		if msg != "ok" {
+			problems = append(problems, errors.New(msg))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return errors.Join(problems...)
+}
+
+func (b *sqliteBackend) Close() error {
+	var err error
+	if b.read != nil {
+		err = b.read.Close()
+	}
+	if b.write != nil {
+		err = errors.Join(err, b.write.Close())
+	}
+	return err
+}
+
+type sqliteTx struct{ tx *sql.Tx }
+
+func (t sqliteTx) Bucket(name []byte) Bucket {
+	var n string
+	err := t.tx.QueryRow(`SELECT name FROM buckets WHERE name = ?`, string(name)).Scan(&n)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return sqliteBucket{tx: t.tx, name: string(name), err: err}
+}
+
+func (t sqliteTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	if _, err := t.tx.Exec(`INSERT OR IGNORE INTO buckets (name) VALUES (?)`, string(name)); err != nil {
+		return nil, err
+	}
+	return sqliteBucket{tx: t.tx, name: string(name)}, nil
+}
+
+func (t sqliteTx) DeleteBucket(name []byte) error {
+	if _, err := t.tx.Exec(`DELETE FROM records WHERE bucket = ?`, string(name)); err != nil {
+		return err
+	}
+	_, err := t.tx.Exec(`DELETE FROM buckets WHERE name = ?`, string(name))
+	return err
+}
+
+// sqliteBucket is one bucket's rows of the records table. err is why the
+// bucket could not be looked up, returned by every method that can fail.
+type sqliteBucket struct {
+	tx   *sql.Tx
+	name string
+	err  error
+}
+
+func (b sqliteBucket) Get(key []byte) []byte {
+	if b.err != nil {
+		return nil
+	}
+	var v []byte
+	if err := b.tx.QueryRow(`SELECT value FROM records WHERE bucket = ? AND key = ?`, b.name, key).Scan(&v); err != nil {
+		return nil
+	}
+	return v
+}
+
+func (b sqliteBucket) Put(key, value []byte) error {
+	if b.err != nil {
+		return b.err
+	}
+	_, err := b.tx.Exec(`INSERT INTO records (bucket, key, value) VALUES (?, ?, ?)
+		ON CONFLICT (bucket, key) DO UPDATE SET value = excluded.value`, b.name, key, value)
+	return err
+}
+
+func (b sqliteBucket) PutIndexed(key, value []byte, ix Index) error {
+	if b.err != nil {
+		return b.err
+	}
+	_, err := b.tx.Exec(`INSERT INTO records (bucket, key, value, project, node, service, time) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (bucket, key) DO UPDATE SET value = excluded.value, project = excluded.project,
+			node = excluded.node, service = excluded.service, time = excluded.time`,
+		b.name, key, value, ix.Project, ix.Node, ix.Service, unixNano(ix.Time))
+	return err
+}
+
+func (b sqliteBucket) Delete(key []byte) error {
+	if b.err != nil {
+		return b.err
+	}
+	_, err := b.tx.Exec(`DELETE FROM records WHERE bucket = ? AND key = ?`, b.name, key)
+	return err
+}
+
+func (b sqliteBucket) ForEach(fn func(k, v []byte) error) error {
+	return b.Scan(Range{}, fn)
+}
+
+func (b sqliteBucket) Scan(r Range, fn func(k, v []byte) error) error {
+	if b.err != nil {
+		return b.err
+	}
+	where := []string{"bucket = ?"}
+	args := []any{b.name}
+	add := func(cond string, arg any) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if r.From != nil {
+		add("key >= ?", r.From)
+	}
+	if r.To != nil {
+		add("key < ?", r.To)
+	}
+	if r.Where.Project != "" {
+		add("project = ?", r.Where.Project)
+	}
+	if r.Where.Node != "" {
+		add("node = ?", r.Where.Node)
+	}
+	if r.Where.Service != "" {
+		add("service = ?", r.Where.Service)
+	}
+	if !r.Since.IsZero() {
+		add("time >= ?", r.Since.UnixNano())
+	}
+	if !r.Until.IsZero() {
+		add("time <= ?", r.Until.UnixNano())
+	}
+	order := "ASC"
+	if r.Reverse {
+		order = "DESC"
+	}
+
+	rows, err := b.tx.Query(`SELECT key, value FROM records WHERE `+strings.Join(where, " AND ")+` ORDER BY key `+order, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k, v []byte
+		if err := rows.Scan(&k, &v); err != nil {
+			return err
+		}
+		if err := fn(k, v); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// unixNano is t as the time column keeps it: 0 for no time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func (b sqliteBucket) Len() int {
+	var n int
+	if b.err == nil {
+		_ = b.tx.QueryRow(`SELECT COUNT(*) FROM records WHERE bucket = ?`, b.name).Scan(&n)
+	}
+	return n
+}
+
+func (b sqliteBucket) NextSequence() (uint64, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	var seq uint64
+	err := b.tx.QueryRow(`UPDATE buckets SET seq = seq + 1 WHERE name = ? RETURNING seq`, b.name).Scan(&seq)
+	return seq, err
+}
@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"time"
 
-	"go.etcd.io/bbolt"
-
 	v1 "github.com/f9-o/orbit/api/v1"
 	"github.com/f9-o/orbit/pkg/encryption"
 	"github.com/f9-o/orbit/pkg/errs"
@@ -16,47 +14,45 @@ var (
 	bucketNodes       = []byte("nodes")
 	bucketServices    = []byte("services")
 	bucketDeployments = []byte("deployments")
+	bucketLocks       = []byte("locks")
+	bucketAlerts      = []byte("alerts")
+	bucketPlugins     = []byte("plugins")
+	bucketEvents      = []byte("events")
+	bucketSync        = []byte("sync")
 )
 
-// DB wraps a BoltDB instance with typed accessor methods and encryption handling.
+// buckets lists every bucket created by Open and verified by Check.
+var buckets = [][]byte{bucketNodes, bucketServices, bucketDeployments, bucketLocks, bucketAlerts, bucketPlugins, bucketEvents, bucketSync}
+
+// DB wraps a Backend with typed accessor methods and encryption handling.
 type DB struct {
-	bolt   *bbolt.DB
-	crypto *encryption.Engine
+	store   Backend
+	path    string
+	crypto  *encryption.Engine
+	events  EventRetention
+	project string // see Project
 }
 
-// Open opens (or creates) the state database at the given path.
+// Open opens (or creates) the state database at the given path for reading
+// and writing, waiting DefaultLockWait for another process to release it.
 // It initializes the encryption engine which is required to securely store data.
 func Open(path string) (*DB, error) {
-	cryptoEngine, err := encryption.NewEngine()
-	if err != nil {
-		return nil, errs.Wrap(err, errs.ErrInternal, "state.Open.InitCrypto")
-	}
-
-	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 2 * time.Second})
-	if err != nil {
-		return nil, errs.New(errs.ErrStateRead, "state.Open", err).WithAdvice("Ensure you have file permissions and no other process holds the DB lock.")
-	}
+	return OpenWithOptions(path, OpenOptions{})
+}
 
-	// Ensure all buckets exist
-	err = db.Update(func(tx *bbolt.Tx) error {
-		for _, b := range [][]byte{bucketNodes, bucketServices, bucketDeployments} {
-			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
-				return errs.New(errs.ErrStateWrite, "state.InitBuckets", err)
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		db.Close()
-		return nil, err
-	}
+// Close closes the underlying backend.
+func (db *DB) Close() error {
+	return db.store.Close()
+}
 
-	return &DB{bolt: db, crypto: cryptoEngine}, nil
+// Backend is the name of the backend the DB is kept in.
+func (db *DB) Backend() string {
+	return db.store.Name()
 }
 
-// Close closes the underlying BoltDB file.
-func (db *DB) Close() error {
-	return db.bolt.Close()
+// Path is the file the DB is kept in.
+func (db *DB) Path() string {
+	return db.path
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -87,7 +83,7 @@ func (db *DB) GetNode(name string) (*v1.NodeInfo, error) {
 
 // DeleteNode removes a node record.
 func (db *DB) DeleteNode(name string) error {
-	err := db.bolt.Update(func(tx *bbolt.Tx) error {
+	err := db.store.Update(func(tx Tx) error {
 		return tx.Bucket(bucketNodes).Delete([]byte(name))
 	})
 	if err != nil {
@@ -99,7 +95,7 @@ func (db *DB) DeleteNode(name string) error {
 // ListNodes returns all registered nodes.
 func (db *DB) ListNodes() ([]v1.NodeInfo, error) {
 	var nodes []v1.NodeInfo
-	err := db.bolt.View(func(tx *bbolt.Tx) error {
+	err := db.store.View(func(tx Tx) error {
 		return tx.Bucket(bucketNodes).ForEach(func(k, v []byte) error {
 			var info v1.NodeInfo
 			data, err := db.crypto.Decrypt(v)
@@ -138,21 +134,46 @@ func (db *DB) UpdateNodeStatus(name string, status v1.NodeStatus, failCount int)
 // Service state operations
 // ─────────────────────────────────────────────────────────────────────────────
 
-// PutServiceState upserts a ServiceState record.
+// PutServiceState upserts a ServiceState record. A state without a
+// project is stamped with the handle's; writing it claims any record of the
+// service from before projects were kept apart.
 func (db *DB) PutServiceState(state v1.ServiceState) error {
-	key := state.Node + "/" + state.Name
-	err := db.putJSON(bucketServices, key, state)
+	if state.Project == "" {
+		state.Project = db.writeProject()
+	}
+	key := serviceKey(state.Project, state.Node, state.Name)
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errs.New(errs.ErrStateWrite, "state.PutServiceState.Marshal", err).WithNode(key)
+	}
+	enc, err := db.crypto.Encrypt(data)
+	if err != nil {
+		return errs.New(errs.ErrStateWrite, "state.PutServiceState.Encrypt", err).WithNode(key)
+	}
+	err = db.store.Update(func(tx Tx) error {
+		b := tx.Bucket(bucketServices)
+		if state.Project != "" {
+			if err := b.Delete([]byte(serviceKey("", state.Node, state.Name))); err != nil {
+				return err
+			}
+		}
+		return b.PutIndexed([]byte(key), enc, indexOf(state))
+	})
 	if err != nil {
 		return errs.Wrap(err, errs.ErrStateWrite, "state.PutServiceState").WithNode(key)
 	}
 	return nil
 }
 
-// GetServiceState retrieves a ServiceState. Returns nil, nil if not found.
+// GetServiceState retrieves a ServiceState of the handle's project, or the
+// service's unclaimed record. Returns nil, nil if not found.
 func (db *DB) GetServiceState(node, name string) (*v1.ServiceState, error) {
 	var s v1.ServiceState
-	key := node + "/" + name
+	key := serviceKey(db.writeProject(), node, name)
 	found, err := db.getJSON(bucketServices, key, &s)
+	if err == nil && !found && db.writeProject() != "" {
+		found, err = db.getJSON(bucketServices, serviceKey("", node, name), &s)
+	}
 	if err != nil {
 		return nil, errs.Wrap(err, errs.ErrStateRead, "state.GetServiceState").WithNode(key)
 	}
@@ -162,11 +183,28 @@ func (db *DB) GetServiceState(node, name string) (*v1.ServiceState, error) {
 	return &s, nil
 }
 
-// ListServiceStates returns all service states, optionally filtered by node.
+// DeleteServiceState removes a service's state record, and any unclaimed one.
+func (db *DB) DeleteServiceState(node, name string) error {
+	key := serviceKey(db.writeProject(), node, name)
+	err := db.store.Update(func(tx Tx) error {
+		b := tx.Bucket(bucketServices)
+		if err := b.Delete([]byte(serviceKey("", node, name))); err != nil {
+			return err
+		}
+		return b.Delete([]byte(key))
+	})
+	if err != nil {
+		return errs.New(errs.ErrStateWrite, "state.DeleteServiceState", err).WithNode(key)
+	}
+	return nil
+}
+
+// ListServiceStates returns the service states of the handle's project,
+// optionally filtered by node.
 func (db *DB) ListServiceStates(node string) ([]v1.ServiceState, error) {
 	var states []v1.ServiceState
-	err := db.bolt.View(func(tx *bbolt.Tx) error {
-		return tx.Bucket(bucketServices).ForEach(func(k, v []byte) error {
+	err := db.store.View(func(tx Tx) error {
+		return tx.Bucket(bucketServices).Scan(Range{Where: Index{Node: node}}, func(k, v []byte) error {
 			var s v1.ServiceState
 			data, err := db.crypto.Decrypt(v)
 			if err != nil {
@@ -175,7 +213,7 @@ func (db *DB) ListServiceStates(node string) ([]v1.ServiceState, error) {
 			if err := json.Unmarshal(data, &s); err != nil {
 				return errs.New(errs.ErrStateRead, "state.ListServiceStates.Unmarshal", err).WithNode(string(k))
 			}
-			if node == "" || s.Node == node {
+			if (node == "" || s.Node == node) && db.owns(s.Project) {
 				states = append(states, s)
 			}
 			return nil
@@ -184,7 +222,7 @@ func (db *DB) ListServiceStates(node string) ([]v1.ServiceState, error) {
 	if err != nil {
 		return nil, errs.Wrap(err, errs.ErrStateRead, "state.ListServiceStates")
 	}
-	return states, nil
+	return db.dropShadowed(states), nil
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -193,6 +231,9 @@ func (db *DB) ListServiceStates(node string) ([]v1.ServiceState, error) {
 
 // PutDeployment appends a deployment record to the history.
 func (db *DB) PutDeployment(rec v1.DeploymentRecord) error {
+	if rec.Project == "" {
+		rec.Project = db.writeProject()
+	}
 	err := db.putJSON(bucketDeployments, rec.ID, rec)
 	if err != nil {
 		return errs.Wrap(err, errs.ErrStateWrite, "state.PutDeployment").WithNode(rec.ID)
@@ -203,9 +244,33 @@ func (db *DB) PutDeployment(rec v1.DeploymentRecord) error {
 // ListDeployments returns all deployment records for a given service name.
 // Pass empty string to return all deployments.
 func (db *DB) ListDeployments(service string) ([]v1.DeploymentRecord, error) {
+	return db.QueryDeployments(DeploymentQuery{Service: service})
+}
+
+// DeploymentQuery selects deployment records. Zero fields match everything.
+type DeploymentQuery struct {
+	Service string
+	Node    string
+	Since   time.Time // deployments started at or after this time
+	Until   time.Time // deployments started at or before this time
+	Limit   int       // only the Limit most recently started
+}
+
+func (q DeploymentQuery) match(r v1.DeploymentRecord) bool {
+	return (q.Service == "" || r.Service == q.Service) &&
+		(q.Node == "" || r.Node == q.Node) &&
+		(q.Since.IsZero() || !r.StartedAt.Before(q.Since)) &&
+		(q.Until.IsZero() || !r.StartedAt.After(q.Until))
+}
+
+// QueryDeployments returns the deployment records matching q in ID order,
+// which starts with the time they started. The SQLite backend answers it
+// from its service and time index.
+func (db *DB) QueryDeployments(q DeploymentQuery) ([]v1.DeploymentRecord, error) {
 	var recs []v1.DeploymentRecord
-	err := db.bolt.View(func(tx *bbolt.Tx) error {
-		return tx.Bucket(bucketDeployments).ForEach(func(k, v []byte) error {
+	rng := Range{Where: Index{Service: q.Service, Node: q.Node}, Since: q.Since, Until: q.Until}
+	err := db.store.View(func(tx Tx) error {
+		return tx.Bucket(bucketDeployments).Scan(rng, func(k, v []byte) error {
 			var r v1.DeploymentRecord
 			data, err := db.crypto.Decrypt(v)
 			if err != nil {
@@ -214,7 +279,7 @@ func (db *DB) ListDeployments(service string) ([]v1.DeploymentRecord, error) {
 			if err := json.Unmarshal(data, &r); err != nil {
 				return errs.New(errs.ErrStateRead, "state.ListDeployments.Unmarshal", err).WithNode(string(k))
 			}
-			if service == "" || r.Service == service {
+			if q.match(r) && db.owns(r.Project) {
 				recs = append(recs, r)
 			}
 			return nil
@@ -223,6 +288,9 @@ func (db *DB) ListDeployments(service string) ([]v1.DeploymentRecord, error) {
 	if err != nil {
 		return nil, errs.Wrap(err, errs.ErrStateRead, "state.ListDeployments")
 	}
+	if q.Limit > 0 && len(recs) > q.Limit {
+		recs = recs[len(recs)-q.Limit:]
+	}
 	return recs, nil
 }
 
@@ -241,14 +309,14 @@ func (db *DB) putJSON(bucket []byte, key string, val any) error {
 		return errs.New(errs.ErrStateWrite, "state.putJSON.Encrypt", err)
 	}
 
-	return db.bolt.Update(func(tx *bbolt.Tx) error {
-		return tx.Bucket(bucket).Put([]byte(key), encryptedData)
+	return db.store.Update(func(tx Tx) error {
+		return tx.Bucket(bucket).PutIndexed([]byte(key), encryptedData, indexOf(val))
 	})
 }
 
 func (db *DB) getJSON(bucket []byte, key string, out any) (bool, error) {
 	var found bool
-	err := db.bolt.View(func(tx *bbolt.Tx) error {
+	err := db.store.View(func(tx Tx) error {
 		encryptedData := tx.Bucket(bucket).Get([]byte(key))
 		if encryptedData == nil {
 			return nil
@@ -0,0 +1,73 @@
+// Package state: what `orbit state sync` last agreed with the shared state.
+package state
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// SyncBase is a record as it was when last synced with the shared state:
+// the hash of its shared content, so a sync can tell which side changed it
+// since.
+type SyncBase struct {
+	Key    string    `json:"key"` // e.g. nodes/edge-1 or deployments/<id>
+	Hash   string    `json:"hash"`
+	Synced time.Time `json:"synced"`
+}
+
+// SyncBases returns the records as last synced, by key.
+func (db *DB) SyncBases() (map[string]SyncBase, error) {
+	bases := map[string]SyncBase{}
+	err := db.store.View(func(tx Tx) error {
+		return tx.Bucket(bucketSync).ForEach(func(k, v []byte) error {
+			var b SyncBase
+			data, err := db.crypto.Decrypt(v)
+			if err != nil {
+				return errs.New(errs.ErrStateRead, "state.SyncBases.Decrypt", err).WithNode(string(k))
+			}
+			if err := json.Unmarshal(data, &b); err != nil {
+				return errs.New(errs.ErrStateRead, "state.SyncBases.Unmarshal", err).WithNode(string(k))
+			}
+			bases[b.Key] = b
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, errs.ErrStateRead, "state.SyncBases")
+	}
+	return bases, nil
+}
+
+// SetSyncBases replaces every sync base with bases, in one transaction.
+func (db *DB) SetSyncBases(bases map[string]SyncBase) error {
+	err := db.store.Update(func(tx Tx) error {
+		if err := tx.DeleteBucket(bucketSync); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucketIfNotExists(bucketSync)
+		if err != nil {
+			return err
+		}
+		for key, base := range bases {
+			base.Key = key
+			data, err := json.Marshal(base)
+			if err != nil {
+				return err
+			}
+			enc, err := db.crypto.Encrypt(data)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(key), enc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errs.New(errs.ErrStateWrite, "state.SetSyncBases", err)
+	}
+	return nil
+}
@@ -0,0 +1,379 @@
+// Package daemon installs `orbit agent` as a service the init system keeps
+// running: a systemd unit on Linux, a launchd job on macOS.
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Name is the service's name: the systemd unit orbit-agent.service.
+const Name = "orbit-agent"
+
+// Label is the launchd job label.
+const Label = "io.github.f9-o.orbit.agent"
+
+// Init systems.
+const (
+	Systemd = "systemd"
+	Launchd = "launchd"
+)
+
+// PassEnv lists environment variables copied from the installing shell into
+// the service's environment when set, since the agent needs them to open
+// the state DB and reach the container runtime.
+var PassEnv = []string{"ORBIT_SECRET_KEY", "ORBIT_PASSPHRASE", "DOCKER_HOST", "DOCKER_CERT_PATH", "DOCKER_TLS_VERIFY", "CONTAINER_HOST"}
+
+// Unit describes the service to install.
+type Unit struct {
+	Exec    string            // absolute path of the orbit binary
+	Args    []string          // arguments after Exec, starting with "agent"
+	User    string            // account to run as; "" = root, or the owner for a per-user service
+	WorkDir string            // directory orbit.yaml is discovered from
+	Env     map[string]string // written to a 0600 file, not the unit
+	LogFile string            // launchd only: where stdout and stderr go
+}
+
+// File is a file an install writes.
+type File struct {
+	Path string
+	Mode os.FileMode
+	Data []byte
+}
+
+// Runner runs an init-system command such as systemctl.
+type Runner func(ctx context.Context, name string, args ...string) error
+
+// Manager installs and removes the agent service for one init system.
+type Manager struct {
+	system  string
+	perUser bool   // systemctl --user / ~/Library/LaunchAgents
+	root    string // prefix for every path written; "" in production
+	home    string
+	run     Runner
+}
+
+// New returns a Manager for this platform's init system. perUser installs
+// a service of the invoking user instead of a system-wide one.
+func New(perUser bool) (*Manager, error) {
+	system := ""
+	switch runtime.GOOS {
+	case "linux":
+		system = Systemd
+	case "darwin":
+		system = Launchd
+	default:
+		return nil, fmt.Errorf("installing the agent as a service is not supported on %s; run `orbit agent` under your service manager", runtime.GOOS)
+	}
+	home, _ := os.UserHomeDir()
+	return &Manager{system: system, perUser: perUser, home: home, run: execRunner}, nil
+}
+
+// WithSystem overrides the init system, e.g. to preview a unit for
+// another platform.
+func (m *Manager) WithSystem(system string) *Manager {
+	m.system = system
+	return m
+}
+
+// WithRoot writes every file under dir instead of /, for tests and
+// image builds.
+func (m *Manager) WithRoot(dir string) *Manager {
+	m.root = dir
+	return m
+}
+
+// WithRunner replaces how init-system commands are run.
+func (m *Manager) WithRunner(r Runner) *Manager {
+	m.run = r
+	return m
+}
+
+// System returns the init system the Manager targets.
+func (m *Manager) System() string {
+	return m.system
+}
+
+func execRunner(ctx context.Context, name string, args ...string) error {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput() //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// UnitPath is where the unit or job definition is written.
+func (m *Manager) UnitPath() string {
+	switch {
+	case m.system == Launchd && m.perUser:
+		return filepath.Join(m.root, m.home, "Library/LaunchAgents", Label+".plist")
+	case m.system == Launchd:
+		return filepath.Join(m.root, "/Library/LaunchDaemons", Label+".plist")
+	case m.perUser:
+		return filepath.Join(m.root, m.home, ".config/systemd/user", Name+".service")
+	default:
+		return filepath.Join(m.root, "/etc/systemd/system", Name+".service")
+	}
+}
+
+// envPath is where a systemd unit's environment file is written.
+func (m *Manager) envPath() string {
+	if m.perUser {
+		return filepath.Join(m.root, m.home, ".orbit", "agent.env")
+	}
+	return filepath.Join(m.root, "/etc/orbit", "agent.env")
+}
+
+// Render returns the files that install u, without writing them.
+func (m *Manager) Render(u Unit) ([]File, error) {
+	if !filepath.IsAbs(u.Exec) {
+		return nil, fmt.Errorf("agent binary path %q is not absolute", u.Exec)
+	}
+	if m.system == Launchd {
+		data, err := execute(launchdTemplate, m.templateData(u))
+		if err != nil {
+			return nil, err
+		}
+		// The job carries the environment itself, secrets included.
+		return []File{{Path: m.UnitPath(), Mode: 0o600, Data: data}}, nil
+	}
+	unit, err := execute(systemdTemplate, m.templateData(u))
+	if err != nil {
+		return nil, err
+	}
+	var env bytes.Buffer
+	env.WriteString("# Generated by Orbit — environment of " + Name + ".service\n")
+	for _, k := range sortedKeys(u.Env) {
+		fmt.Fprintf(&env, "%s=%s\n", k, envQuote(u.Env[k]))
+	}
+	return []File{
+		{Path: m.envPath(), Mode: 0o600, Data: env.Bytes()},
+		{Path: m.UnitPath(), Mode: 0o644, Data: unit},
+	}, nil
+}
+
+// Install writes u's files and registers the service to start at boot (or
+// login, per user), starting it now unless start is false. Reinstalling
+// replaces the previous definition.
+func (m *Manager) Install(ctx context.Context, u Unit, start bool) ([]File, error) {
+	files, err := m.Render(u)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(f.Path, f.Data, f.Mode); err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(f.Path, f.Mode); err != nil { // WriteFile keeps an existing file's mode
+			return nil, err
+		}
+	}
+
+	if m.system == Launchd {
+		_ = m.run(ctx, "launchctl", "unload", m.UnitPath()) // an earlier install may be loaded
+		if !start {
+			return files, nil
+		}
+		return files, m.run(ctx, "launchctl", "load", "-w", m.UnitPath())
+	}
+	if err := m.systemctl(ctx, "daemon-reload"); err != nil {
+		return files, err
+	}
+	if err := m.systemctl(ctx, "enable", Name+".service"); err != nil || !start {
+		return files, err
+	}
+	return files, m.systemctl(ctx, "restart", Name+".service")
+}
+
+// Uninstall stops the service and removes what Install wrote.
+func (m *Manager) Uninstall(ctx context.Context) error {
+	if m.system == Launchd {
+		_ = m.run(ctx, "launchctl", "unload", "-w", m.UnitPath())
+		return removeIfExists(m.UnitPath())
+	}
+	_ = m.systemctl(ctx, "disable", "--now", Name+".service")
+	for _, p := range []string{m.UnitPath(), m.envPath()} {
+		if err := removeIfExists(p); err != nil {
+			return err
+		}
+	}
+	return m.systemctl(ctx, "daemon-reload")
+}
+
+func (m *Manager) systemctl(ctx context.Context, args ...string) error {
+	if m.perUser {
+		args = append([]string{"--user"}, args...)
+	}
+	return m.run(ctx, "systemctl", args...)
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// templateData carries values into the unit templates.
+type templateData struct {
+	Unit
+	Name, Label string
+	EnvFile     string // path as seen on the host, without the test root
+	PerUser     bool
+	Command     []string
+}
+
+func (m *Manager) templateData(u Unit) templateData {
+	return templateData{
+		Unit:    u,
+		Name:    Name,
+		Label:   Label,
+		EnvFile: strings.TrimPrefix(m.envPath(), m.root),
+		PerUser: m.perUser,
+		Command: append([]string{u.Exec}, u.Args...),
+	}
+}
+
+func execute(tmpl *template.Template, data templateData) ([]byte, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return nil, fmt.Errorf("render %s: %w", tmpl.Name(), err)
+	}
+	return b.Bytes(), nil
+}
+
+var funcs = template.FuncMap{
+	"spec":   func(s string) string { return strings.ReplaceAll(s, "%", "%%") },
+	"xml":    xmlEscape,
+	"sorted": sortedKeys,
+	"join": func(args []string) string {
+		q := make([]string, len(args))
+		for i, a := range args {
+			q[i] = systemdQuote(a)
+		}
+		return strings.Join(q, " ")
+	},
+}
+
+// systemdTemplate is the unit for `orbit agent`. The agent stops cleanly on
+// SIGTERM; Restart=always brings it back after a crash or an upgrade.
+var systemdTemplate = template.Must(template.New("systemd unit").Funcs(funcs).Parse(`# Generated by Orbit — re-run ` + "`orbit agent install`" + ` to change it
+[Unit]
+Description=Orbit agent (health checks, restarts, node heartbeats, alerts)
+Documentation=https://github.com/f9-o/orbit
+{{- if not .PerUser }}
+After=network-online.target docker.service
+Wants=network-online.target
+{{- end }}
+
+[Service]
+Type=simple
+ExecStart={{ join .Command }}
+{{- if .WorkDir }}
+WorkingDirectory={{ spec .WorkDir }}
+{{- end }}
+{{- if and .User (not .PerUser) }}
+User={{ .User }}
+{{- end }}
+EnvironmentFile=-{{ .EnvFile }}
+Restart=always
+RestartSec=5
+KillSignal=SIGTERM
+TimeoutStopSec=30
+
+[Install]
+WantedBy={{ if .PerUser }}default.target{{ else }}multi-user.target{{ end }}
+`))
+
+// launchdTemplate is the launchd job for `orbit agent`.
+var launchdTemplate = template.Must(template.New("launchd plist").Funcs(funcs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<!-- Generated by Orbit — re-run ` + "`orbit agent install`" + ` to change it -->
+<plist version="1.0">
+<dict>
+  <key>Label</key>
+  <string>{{ .Label }}</string>
+  <key>ProgramArguments</key>
+  <array>
+{{- range .Command }}
+    <string>{{ xml . }}</string>
+{{- end }}
+  </array>
+{{- if .WorkDir }}
+  <key>WorkingDirectory</key>
+  <string>{{ xml .WorkDir }}</string>
+{{- end }}
+{{- if and .User (not .PerUser) }}
+  <key>UserName</key>
+  <string>{{ xml .User }}</string>
+{{- end }}
+{{- if .Env }}
+  <key>EnvironmentVariables</key>
+  <dict>
+{{- range $k := sorted .Env }}
+    <key>{{ xml $k }}</key>
+    <string>{{ xml (index $.Env $k) }}</string>
+{{- end }}
+  </dict>
+{{- end }}
+  <key>RunAtLoad</key>
+  <true/>
+  <key>KeepAlive</key>
+  <true/>
+  <key>ThrottleInterval</key>
+  <integer>5</integer>
+{{- if .LogFile }}
+  <key>StandardOutPath</key>
+  <string>{{ xml .LogFile }}</string>
+  <key>StandardErrorPath</key>
+  <string>{{ xml .LogFile }}</string>
+{{- end }}
+</dict>
+</plist>
+`))
+
+// systemdQuote quotes s for a unit file when it needs it, and escapes the
+// % that systemd would expand as a specifier.
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\$;") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// envQuote quotes s for a systemd environment file when it needs it.
+func envQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\"'\\#;") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
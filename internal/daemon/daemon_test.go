@@ -0,0 +1,149 @@
+package daemon_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/f9-o/orbit/internal/daemon"
+)
+
+type recorder struct{ calls []string }
+
+func (r *recorder) run(_ context.Context, name string, args ...string) error {
+	r.calls = append(r.calls, name+" "+strings.Join(args, " "))
+	return nil
+}
+
+func testUnit() daemon.Unit {
+	return daemon.Unit{
+		Exec:    "/usr/local/bin/orbit",
+		Args:    []string{"agent", "--config", "/srv/my app/orbit.yaml"},
+		User:    "deploy",
+		WorkDir: "/srv/my app",
+		Env:     map[string]string{"ORBIT_SECRET_KEY": "s3cret key", "DOCKER_HOST": "unix:///run/docker.sock"},
+	}
+}
+
+func TestInstallSystemd(t *testing.T) {
+	root := t.TempDir()
+	rec := &recorder{}
+	m, err := daemon.New(false)
+	if err != nil {
+		t.Skip(err)
+	}
+	m.WithSystem(daemon.Systemd).WithRoot(root).WithRunner(rec.run)
+
+	if _, err := m.Install(context.Background(), testUnit(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	unit, err := os.ReadFile(filepath.Join(root, "etc/systemd/system/orbit-agent.service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`ExecStart=/usr/local/bin/orbit agent --config "/srv/my app/orbit.yaml"`,
+		"WorkingDirectory=/srv/my app",
+		"User=deploy",
+		"EnvironmentFile=-/etc/orbit/agent.env",
+		"Restart=always",
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(string(unit), want+"\n") {
+			t.Errorf("unit is missing %q:\n%s", want, unit)
+		}
+	}
+	if strings.Contains(string(unit), "s3cret") {
+		t.Error("unit contains the secret key; it belongs in the env file")
+	}
+
+	envPath := filepath.Join(root, "etc/orbit/agent.env")
+	env, err := os.ReadFile(envPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(env), "DOCKER_HOST=unix:///run/docker.sock\nORBIT_SECRET_KEY=\"s3cret key\"\n") {
+		t.Errorf("env file:\n%s", env)
+	}
+	if fi, _ := os.Stat(envPath); fi.Mode().Perm() != 0o600 {
+		t.Errorf("env file mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	want := []string{"systemctl daemon-reload", "systemctl enable orbit-agent.service", "systemctl restart orbit-agent.service"}
+	if strings.Join(rec.calls, "; ") != strings.Join(want, "; ") {
+		t.Errorf("ran %q, want %q", rec.calls, want)
+	}
+}
+
+func TestInstallSystemdPerUserNoStart(t *testing.T) {
+	root := t.TempDir()
+	rec := &recorder{}
+	m, err := daemon.New(true)
+	if err != nil {
+		t.Skip(err)
+	}
+	m.WithSystem(daemon.Systemd).WithRoot(root).WithRunner(rec.run)
+
+	files, err := m.Install(context.Background(), testUnit(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unit := string(files[len(files)-1].Data)
+	if strings.Contains(unit, "User=") || !strings.Contains(unit, "WantedBy=default.target") {
+		t.Errorf("per-user unit:\n%s", unit)
+	}
+	want := []string{"systemctl --user daemon-reload", "systemctl --user enable orbit-agent.service"}
+	if strings.Join(rec.calls, "; ") != strings.Join(want, "; ") {
+		t.Errorf("ran %q, want %q", rec.calls, want)
+	}
+
+	rec.calls = nil
+	if err := m.Uninstall(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(m.UnitPath()); !os.IsNotExist(err) {
+		t.Errorf("unit still present after uninstall: %v", err)
+	}
+}
+
+func TestRenderLaunchd(t *testing.T) {
+	m, err := daemon.New(false)
+	if err != nil {
+		t.Skip(err)
+	}
+	u := testUnit()
+	u.Env["TOKEN"] = "a<b&c"
+	u.LogFile = "/var/log/orbit-agent.log"
+	files, err := m.WithSystem(daemon.Launchd).Render(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Mode != 0o600 || !strings.HasSuffix(files[0].Path, "/Library/LaunchDaemons/"+daemon.Label+".plist") {
+		t.Fatalf("files = %+v", files)
+	}
+	plist := string(files[0].Data)
+	for _, want := range []string{
+		"<string>/srv/my app/orbit.yaml</string>",
+		"<key>UserName</key>\n  <string>deploy</string>",
+		"<string>a&lt;b&amp;c</string>",
+		"<key>KeepAlive</key>\n  <true/>",
+		"<string>/var/log/orbit-agent.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist is missing %q:\n%s", want, plist)
+		}
+	}
+}
+
+func TestRenderRelativeExec(t *testing.T) {
+	m, err := daemon.New(false)
+	if err != nil {
+		t.Skip(err)
+	}
+	if _, err := m.Render(daemon.Unit{Exec: "orbit", Args: []string{"agent"}}); err == nil {
+		t.Error("want an error for a relative binary path")
+	}
+}
@@ -0,0 +1,329 @@
+// Package doctor: the individual diagnostics.
+package doctor
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// MinDockerVersion is the oldest Docker Engine release Orbit is tested against.
+const MinDockerVersion = "20.10"
+
+// MinPodmanVersion is the oldest Podman release whose Docker-compatible API Orbit supports.
+const MinPodmanVersion = "4.0"
+
+// Disk-space thresholds for the Orbit home directory.
+const (
+	DiskWarnBytes = 1 << 30   // 1 GiB
+	DiskFailBytes = 100 << 20 // 100 MiB
+)
+
+// CertWarnWindow is how close to expiry a certificate must be to warn.
+const CertWarnWindow = 14 * 24 * time.Hour
+
+// Docker checks daemon connectivity and that the engine is recent enough.
+// docker is nil when the client could not be constructed; clientErr explains why.
+// A Podman backend is checked against MinPodmanVersion instead.
+func Docker(docker *orchestrator.Client, clientErr error) Check {
+	return Check{Name: "docker", Run: func(ctx context.Context) []Result {
+		advice := "Start the Docker daemon and check docker_host, DOCKER_HOST, the Docker context and your permissions on the Docker socket"
+		engine, minVersion := "Docker", MinDockerVersion
+		if docker != nil && docker.Name() == orchestrator.RuntimePodman {
+			advice = "Start the Podman API socket (systemctl --user enable --now podman.socket) or set CONTAINER_HOST"
+			engine, minVersion = "Podman", MinPodmanVersion
+		}
+		if docker == nil {
+			return []Result{problem("docker", StatusFail, errs.New(errs.ErrDockerConnect, "doctor.docker", clientErr).WithAdvice(advice))}
+		}
+		v, err := docker.ServerVersion(ctx)
+		if err != nil {
+			return []Result{problem("docker", StatusFail, errs.New(errs.ErrDockerConnect, "doctor.docker", err).WithAdvice(advice))}
+		}
+		detail := fmt.Sprintf("%s %s (API %s, %s/%s) at %s", engine, v.Version, v.APIVersion, v.Os, v.Arch, docker.Host())
+		if !versionAtLeast(v.Version, minVersion) {
+			return []Result{problem("docker", StatusWarn, errs.Newf(errs.ErrDockerConnect, "doctor.docker",
+				"%s is older than the minimum supported %s", detail, minVersion).
+				WithAdvice("Upgrade "+engine+" to "+minVersion+" or newer"))}
+		}
+		return []Result{pass("docker", detail)}
+	}}
+}
+
+// versionAtLeast compares the major.minor prefix of two dotted versions.
+func versionAtLeast(have, want string) bool {
+	parse := func(v string) (int, int) {
+		parts := strings.SplitN(strings.TrimPrefix(v, "v"), ".", 3)
+		major, _ := strconv.Atoi(parts[0])
+		minor := 0
+		if len(parts) > 1 {
+			minor, _ = strconv.Atoi(parts[1])
+		}
+		return major, minor
+	}
+	hm, hn := parse(have)
+	wm, wn := parse(want)
+	return hm > wm || (hm == wm && hn >= wn)
+}
+
+// State verifies the integrity of the state database.
+func State(db *state.DB) Check {
+	return Check{Name: "state", Run: func(ctx context.Context) []Result {
+		n, err := db.Check()
+		if err != nil {
+			if oe := errs.AsOrbit(err); oe != nil && oe.Advice == "" {
+				oe.WithAdvice("Back up ~/.orbit/state.db and check ORBIT_SECRET_KEY; the database may be corrupt or encrypted with a different key")
+			}
+			return []Result{problem("state", StatusFail, err)}
+		}
+		return []Result{pass("state", fmt.Sprintf("%d records verified, master key from %s", n, encryption.CurrentSource()))}
+	}}
+}
+
+// Config strictly validates orbit.yaml at path ("" = auto-discover).
+func Config(path string) Check {
+	return Check{Name: "config", Run: func(ctx context.Context) []Result {
+		if path == "" {
+			found, err := config.DiscoverProjectConfig()
+			if err != nil {
+				return []Result{skip("config", "no orbit.yaml found (run `orbit init` to create one)")}
+			}
+			path = found
+		}
+		if _, err := config.LoadWithOptions(path, config.LoadOptions{Strict: true}); err != nil {
+			var unknown *config.UnknownKeyError
+			if errors.As(err, &unknown) {
+				return []Result{problem("config", StatusWarn, errs.New(errs.ErrConfig, "doctor.config", err).
+					WithAdvice("Remove or fix the unknown keys; run `orbit config validate` for details"))}
+			}
+			return []Result{problem("config", StatusFail, errs.New(errs.ErrConfig, "doctor.config", err).
+				WithAdvice("Fix "+path+" and re-run `orbit config validate`"))}
+		}
+		return []Result{pass("config", path+" is valid")}
+	}}
+}
+
+// Nodes checks SSH reachability of every registered node.
+func Nodes(registry *remote.Registry, pool *remote.Pool) Check {
+	return Check{Name: "nodes", Run: func(ctx context.Context) []Result {
+		nodes, err := registry.List()
+		if err != nil {
+			return []Result{problem("nodes", StatusFail, err)}
+		}
+		if len(nodes) == 0 {
+			return []Result{skip("nodes", "no remote nodes registered")}
+		}
+		var results []Result
+		for _, n := range nodes {
+			name := "node " + n.Spec.Name
+			start := time.Now()
+			if _, _, err := pool.Run(ctx, n, "true"); err != nil {
+				oe := errs.AsOrbit(err)
+				if oe == nil {
+					oe = errs.New(errs.ErrNodeConnect, "doctor.nodes", err)
+				}
+				oe.WithNode(n.Spec.Name)
+				if oe.Advice == "" {
+					oe.WithAdvice(fmt.Sprintf("Check that %s:%d is reachable and the SSH key is authorised; run `orbit nodes test %s` for details", n.Spec.Host, n.Spec.Port, n.Spec.Name))
+				}
+				results = append(results, problem(name, StatusFail, oe))
+				continue
+			}
+			results = append(results, pass(name, fmt.Sprintf("%s reachable in %s", n.Spec.Host, time.Since(start).Round(time.Millisecond))))
+		}
+		return results
+	}}
+}
+
+// SSHPool reports connection pool usage against its limits. Run it after
+// Nodes so the pool reflects real connections.
+func SSHPool(pool *remote.Pool) Check {
+	return Check{Name: "ssh pool", Run: func(ctx context.Context) []Result {
+		s := pool.Stats()
+		detail := fmt.Sprintf("%d connections (%d idle), %d sessions in use", s.Connections, s.Idle, s.Sessions)
+		if max := s.Limits.MaxConns; max > 0 {
+			detail += fmt.Sprintf(", limit %d", max)
+			if s.Connections >= max {
+				return []Result{problem("ssh pool", StatusWarn, errs.Newf(errs.ErrNodeConnect, "doctor.ssh_pool",
+					"connection limit reached: %s", detail).
+					WithAdvice("Raise ssh.max_connections in orbit.yaml or lower ssh.idle_timeout"))}
+			}
+		}
+		return []Result{pass("ssh pool", detail)}
+	}}
+}
+
+// Disk checks free space on the filesystem holding dir.
+func Disk(dir string) Check {
+	return Check{Name: "disk", Run: func(ctx context.Context) []Result {
+		free, err := freeBytes(dir)
+		if errors.Is(err, errUnsupported) {
+			return []Result{skip("disk", "free-space check not supported on this platform")}
+		}
+		if err != nil {
+			return []Result{problem("disk", StatusWarn, errs.New(errs.ErrHostDiskSpace, "doctor.disk", err))}
+		}
+		detail := fmt.Sprintf("%s free under %s", humanBytes(free), dir)
+		advice := "Free space under " + dir + " (old logs live in " + filepath.Join(dir, "logs") + ")"
+		switch {
+		case free < DiskFailBytes:
+			return []Result{problem("disk", StatusFail, errs.Newf(errs.ErrHostDiskSpace, "doctor.disk", "only %s", detail).WithAdvice(advice))}
+		case free < DiskWarnBytes:
+			return []Result{problem("disk", StatusWarn, errs.Newf(errs.ErrHostDiskSpace, "doctor.disk", "only %s", detail).WithAdvice(advice))}
+		}
+		return []Result{pass("disk", detail)}
+	}}
+}
+
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Certificates reports the expiry of every PEM certificate under dir.
+func Certificates(dir string, now time.Time) Check {
+	return Check{Name: "certs", Run: func(ctx context.Context) []Result {
+		dir = expandHome(dir)
+		var files []string
+		for _, pattern := range []string{"*.crt", "*.pem", "*/*.crt", "*/*.pem"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+		if len(files) == 0 {
+			return []Result{skip("certs", "no certificates under "+dir)}
+		}
+
+		var results []Result
+		for _, f := range files {
+			cert, err := readCert(f)
+			if err != nil {
+				continue // keys and chains without a leaf are not ours to judge
+			}
+			name := "cert " + certName(cert, f)
+			left := cert.NotAfter.Sub(now)
+			switch {
+			case left <= 0:
+				results = append(results, problem(name, StatusFail, errs.Newf(errs.ErrSSLExpired, "doctor.certs",
+					"expired %s", cert.NotAfter.Format("2006-01-02")).WithAdvice("Run: orbit ssl renew "+certName(cert, f))))
+			case left < CertWarnWindow:
+				results = append(results, problem(name, StatusWarn, errs.Newf(errs.ErrSSLExpired, "doctor.certs",
+					"expires in %d days (%s)", int(left.Hours()/24), cert.NotAfter.Format("2006-01-02")).WithAdvice("Run: orbit ssl renew "+certName(cert, f))))
+			default:
+				results = append(results, pass(name, fmt.Sprintf("valid until %s", cert.NotAfter.Format("2006-01-02"))))
+			}
+		}
+		return results
+	}}
+}
+
+func readCert(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no certificate", path)
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+func certName(cert *x509.Certificate, path string) string {
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	return filepath.Base(path)
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// Ports detects host ports claimed by more than one service and ports that
+// are already bound by another process before a service has been started.
+func Ports(specs []v1.ServiceSpec, running map[string]bool) Check {
+	return Check{Name: "ports", Run: func(ctx context.Context) []Result {
+		owners := map[string][]string{}
+		for _, spec := range specs {
+			for _, p := range spec.Ports {
+				host, _, ok := strings.Cut(p, ":")
+				if !ok {
+					continue
+				}
+				owners[host] = append(owners[host], spec.Name)
+			}
+		}
+		if len(owners) == 0 {
+			return []Result{skip("ports", "no host ports published")}
+		}
+
+		ports := make([]string, 0, len(owners))
+		for p := range owners {
+			ports = append(ports, p)
+		}
+		sort.Strings(ports)
+
+		var results []Result
+		for _, p := range ports {
+			svcs := owners[p]
+			if len(svcs) > 1 {
+				results = append(results, problem("port "+p, StatusFail, errs.Newf(errs.ErrHostPortConflict, "doctor.ports",
+					"claimed by %s", strings.Join(svcs, ", ")).WithAdvice("Give each service a distinct host port in orbit.yaml")))
+				continue
+			}
+			if running[svcs[0]] {
+				continue // bound by our own container
+			}
+			l, err := net.Listen("tcp", ":"+p)
+			if err != nil {
+				results = append(results, problem("port "+p, StatusWarn, errs.Newf(errs.ErrHostPortConflict, "doctor.ports",
+					"needed by %s but already in use", svcs[0]).WithAdvice("Stop the process listening on :"+p+" or change the port for "+svcs[0])))
+				continue
+			}
+			l.Close()
+		}
+		if len(results) == 0 {
+			return []Result{pass("ports", fmt.Sprintf("%d host port(s) free of conflicts", len(ports)))}
+		}
+		return results
+	}}
+}
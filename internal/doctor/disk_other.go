@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package doctor
+
+func freeBytes(dir string) (uint64, error) {
+	return 0, errUnsupported
+}
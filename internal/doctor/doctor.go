@@ -0,0 +1,88 @@
+// Package doctor runs environment diagnostics for `orbit doctor`.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// Status is the outcome of a single diagnostic check.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// errUnsupported marks a check that cannot run on this platform.
+var errUnsupported = errors.New("unsupported platform")
+
+// CheckTimeout bounds each individual check.
+const CheckTimeout = 10 * time.Second
+
+// Result is the outcome of one check, e.g. {Name: "docker", Status: pass, Detail: "Docker 26.1.4 (API 1.45)"}.
+type Result struct {
+	Name   string         `json:"name"`
+	Status Status         `json:"status"`
+	Detail string         `json:"detail"`
+	Code   errs.ErrorCode `json:"code,omitempty"`
+	Advice string         `json:"advice,omitempty"`
+}
+
+// Check is a named diagnostic. A check returns one or more results so that,
+// for example, every registered node is reported on its own line.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) []Result
+}
+
+// Run executes checks in order, each bounded by CheckTimeout.
+func Run(ctx context.Context, checks []Check) []Result {
+	var results []Result
+	for _, c := range checks {
+		cctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+		results = append(results, c.Run(cctx)...)
+		cancel()
+	}
+	return results
+}
+
+// Failed reports whether any result failed.
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// pass returns a passing result.
+func pass(name, detail string) Result {
+	return Result{Name: name, Status: StatusPass, Detail: detail}
+}
+
+// skip returns a result for a check that does not apply.
+func skip(name, detail string) Result {
+	return Result{Name: name, Status: StatusSkip, Detail: detail}
+}
+
+// problem turns an error into a warn/fail result, carrying the error code and
+// remediation advice when err is an *errs.OrbitError.
+func problem(name string, status Status, err error) Result {
+	r := Result{Name: name, Status: status, Detail: err.Error()}
+	var oe *errs.OrbitError
+	if errors.As(err, &oe) {
+		r.Code = oe.Code
+		r.Advice = oe.Advice
+		if oe.Cause != nil {
+			r.Detail = oe.Cause.Error()
+		}
+	}
+	return r
+}
@@ -0,0 +1,144 @@
+package doctor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+func TestVersionAtLeast(t *testing.T) {
+	cases := []struct {
+		have, want string
+		ok         bool
+	}{
+		{"26.1.4", "20.10", true},
+		{"20.10.7", "20.10", true},
+		{"20.9.1", "20.10", false},
+		{"19.03.12", "20.10", false},
+	}
+	for _, c := range cases {
+		if got := versionAtLeast(c.have, c.want); got != c.ok {
+			t.Errorf("versionAtLeast(%q, %q) = %v, want %v", c.have, c.want, got, c.ok)
+		}
+	}
+}
+
+func writeCert(t *testing.T, dir, name string, notAfter time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name+".crt"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertificates(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	writeCert(t, dir, "ok.example.com", now.Add(60*24*time.Hour))
+	writeCert(t, dir, "soon.example.com", now.Add(3*24*time.Hour))
+	writeCert(t, dir, "old.example.com", now.Add(-24*time.Hour))
+
+	got := map[string]Result{}
+	for _, r := range Certificates(dir, now).Run(context.Background()) {
+		got[r.Name] = r
+	}
+	want := map[string]Status{
+		"cert ok.example.com":   StatusPass,
+		"cert soon.example.com": StatusWarn,
+		"cert old.example.com":  StatusFail,
+	}
+	for name, status := range want {
+		r, ok := got[name]
+		if !ok {
+			t.Errorf("missing result for %s", name)
+			continue
+		}
+		if r.Status != status {
+			t.Errorf("%s: status = %s, want %s (%s)", name, r.Status, status, r.Detail)
+		}
+		if status != StatusPass && (r.Code != errs.ErrSSLExpired || !strings.Contains(r.Advice, "orbit ssl renew")) {
+			t.Errorf("%s: code=%s advice=%q", name, r.Code, r.Advice)
+		}
+	}
+}
+
+func TestPorts(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, busy, _ := net.SplitHostPort(l.Addr().String())
+
+	specs := []v1.ServiceSpec{
+		{Name: "web", Ports: []string{"18080:80"}},
+		{Name: "admin", Ports: []string{"18080:8080"}},
+		{Name: "api", Ports: []string{busy + ":3000"}},
+	}
+
+	results := Ports(specs, nil).Run(context.Background())
+	byName := map[string]Result{}
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+	if r := byName["port 18080"]; r.Status != StatusFail || !strings.Contains(r.Detail, "web, admin") {
+		t.Errorf("port 18080 = %+v, want fail naming both services", r)
+	}
+	if r := byName["port "+busy]; r.Status != StatusWarn || r.Code != errs.ErrHostPortConflict {
+		t.Errorf("port %s = %+v, want in-use warning", busy, r)
+	}
+
+	// A running service is expected to hold its own port.
+	results = Ports(specs[2:], map[string]bool{"api": true}).Run(context.Background())
+	if len(results) != 1 || results[0].Status != StatusPass {
+		t.Errorf("running service: got %+v, want a single pass", results)
+	}
+}
+
+func TestStateCheck(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.PutServiceState(v1.ServiceState{Name: "web", Node: "local"}); err != nil {
+		t.Fatal(err)
+	}
+
+	results := State(db).Run(context.Background())
+	if len(results) != 1 || results[0].Status != StatusPass || results[0].Detail != "1 records verified, master key from env" {
+		t.Errorf("got %+v", results)
+	}
+}
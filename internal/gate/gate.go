@@ -0,0 +1,195 @@
+// Package gate enforces deploy_policy on deploys to protected environments:
+// they wait for a typed confirmation, an approval webhook's answer, and a
+// maintenance window.
+package gate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// DefaultApprovalTimeout is how long an approval webhook may take to answer
+// when deploy_policy.approval.timeout is not set. It is long so the webhook
+// can hold the request until someone approves.
+const DefaultApprovalTimeout = 10 * time.Minute
+
+// Request is what the approval webhook is POSTed for each deploy.
+type Request struct {
+	Project     string `json:"project"`
+	Environment string `json:"environment"`
+	Service     string `json:"service"`
+	Node        string `json:"node"`
+	Image       string `json:"image"`
+	User        string `json:"user"`
+}
+
+// Gate applies one project's deploy policy to deploys on one node.
+type Gate struct {
+	policy      config.DeployPolicyConfig
+	project     string
+	environment string
+	node        string
+	http        *http.Client
+	now         func() time.Time
+}
+
+// New returns the Gate for cfg's deploy_policy, for deploys of project's
+// services to node.
+func New(cfg *config.Config, project, node string) *Gate {
+	return &Gate{
+		policy:      cfg.Policy,
+		project:     project,
+		environment: cfg.Project.Environment,
+		node:        node,
+		http:        &http.Client{},
+		now:         time.Now,
+	}
+}
+
+// WithHTTPClient sends approval requests through h.
+func (g *Gate) WithHTTPClient(h *http.Client) *Gate {
+	g.http = h
+	return g
+}
+
+// Environment is the project's environment, the phrase a confirmation is
+// typed as.
+func (g *Gate) Environment() string { return g.environment }
+
+// Protected reports whether the policy applies: the project's environment
+// is one deploy_policy.protected lists.
+func (g *Gate) Protected() bool {
+	return g.policy.Protects(g.environment)
+}
+
+// NeedsConfirm reports whether deploys wait for the environment's name to
+// be typed.
+func (g *Gate) NeedsConfirm() bool {
+	return g.Protected() && g.policy.Confirm
+}
+
+// CheckConfirm accepts typed as the confirmation of a deploy when it is the
+// environment's name.
+func (g *Gate) CheckConfirm(typed string) error {
+	if !g.NeedsConfirm() || strings.TrimSpace(typed) == g.environment {
+		return nil
+	}
+	op := "policy.confirm"
+	if typed == "" {
+		return errs.Newf(errs.ErrPolicyConfirm, op, "deploys to %s must be confirmed by typing its name, or with --confirm %s", g.environment, g.environment)
+	}
+	return errs.Newf(errs.ErrPolicyConfirm, op, "%q does not confirm a deploy to %s", typed, g.environment).
+		WithAdvice("Type " + g.environment + " exactly")
+}
+
+// CheckWindow fails when now is outside every maintenance window.
+func (g *Gate) CheckWindow() error {
+	if !g.Protected() || len(g.policy.Windows) == 0 {
+		return nil
+	}
+	now := g.now()
+	names := make([]string, len(g.policy.Windows))
+	for i, w := range g.policy.Windows {
+		in, err := w.Contains(now)
+		if err != nil {
+			return errs.New(errs.ErrConfig, "policy.window", err)
+		}
+		if in {
+			return nil
+		}
+		names[i] = w.String()
+	}
+	return errs.Newf(errs.ErrPolicyWindow, "policy.window",
+		"deploys to %s are allowed only in maintenance windows: %s", g.environment, strings.Join(names, "; ")).
+		WithAdvice("Deploy within a window, or change deploy_policy.windows in orbit.yaml")
+}
+
+// Approve asks the approval webhook to approve deploying image as spec. A
+// 2xx answer approves, unless its JSON body has "approved": false; a 403
+// denies, with the "reason" of its JSON body or its text. Anything else, or
+// no answer in time, refuses the deploy.
+func (g *Gate) Approve(ctx context.Context, spec v1.ServiceSpec, image string) error {
+	a := g.policy.Approval
+	if !g.Protected() || a.URL == "" {
+		return nil
+	}
+	op := "policy.approval"
+	timeout := a.Timeout
+	if timeout == 0 {
+		timeout = DefaultApprovalTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(Request{
+		Project: g.project, Environment: g.environment,
+		Service: spec.Name, Node: g.node, Image: image, User: logger.CurrentUser(),
+	})
+	if err != nil {
+		return errs.New(errs.ErrInternal, op, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return errs.New(errs.ErrConfig, op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range a.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := g.http.Do(req)
+	if err != nil {
+		return errs.New(errs.ErrPolicyApproval, op, fmt.Errorf("no answer from the approval webhook: %w", err)).
+			WithNode(spec.Name)
+	}
+	defer resp.Body.Close()
+	text, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var answer struct {
+		Approved *bool  `json:"approved"`
+		Reason   string `json:"reason"`
+	}
+	_ = json.Unmarshal(text, &answer)
+	reason := answer.Reason
+	if reason == "" && answer.Approved == nil {
+		reason = strings.TrimSpace(string(text))
+	}
+
+	switch {
+	case resp.StatusCode/100 == 2 && (answer.Approved == nil || *answer.Approved):
+		return nil
+	case resp.StatusCode/100 == 2 || resp.StatusCode == http.StatusForbidden:
+		if reason == "" {
+			reason = "no reason given"
+		}
+		return errs.Newf(errs.ErrPolicyApproval, op, "deploy of %s to %s denied: %s", spec.Name, g.environment, reason).
+			WithNode(spec.Name)
+	}
+	return errs.Newf(errs.ErrPolicyApproval, op, "the approval webhook answered %s", resp.Status).
+		WithNode(spec.Name).
+		WithAdvice("Check deploy_policy.approval in orbit.yaml and the webhook's logs")
+}
+
+// Policy is the check each deploy makes before anything is pulled: within
+// a maintenance window, and approved. It is nil when the environment is not
+// protected. A typed confirmation is asked for by the commands themselves.
+func (g *Gate) Policy() func(ctx context.Context, spec v1.ServiceSpec, image string) error {
+	if !g.Protected() {
+		return nil
+	}
+	return func(ctx context.Context, spec v1.ServiceSpec, image string) error {
+		if err := g.CheckWindow(); err != nil {
+			return err
+		}
+		return g.Approve(ctx, spec, image)
+	}
+}
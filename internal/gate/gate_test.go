@@ -0,0 +1,114 @@
+package gate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+func protected(policy config.DeployPolicyConfig) *Gate {
+	policy.Protected = []string{"production"}
+	cfg := &config.Config{Project: config.ProjectConfig{Name: "shop", Environment: "production"}, Policy: policy}
+	return New(cfg, "shop", "edge")
+}
+
+func TestUnprotected(t *testing.T) {
+	cfg := &config.Config{
+		Project: config.ProjectConfig{Environment: "staging"},
+		Policy:  config.DeployPolicyConfig{Protected: []string{"production"}, Confirm: true, Approval: config.ApprovalConfig{URL: "http://127.0.0.1:1"}},
+	}
+	g := New(cfg, "shop", "edge")
+	if g.Protected() || g.NeedsConfirm() || g.Policy() != nil || g.CheckConfirm("") != nil {
+		t.Error("the policy applied to an environment it does not protect")
+	}
+}
+
+func TestCheckConfirm(t *testing.T) {
+	g := protected(config.DeployPolicyConfig{Confirm: true})
+	if err := g.CheckConfirm("production"); err != nil {
+		t.Errorf("the environment's name did not confirm: %v", err)
+	}
+	for _, typed := range []string{"", "prod", "yes"} {
+		if err := g.CheckConfirm(typed); !errs.IsCode(err, errs.ErrPolicyConfirm) {
+			t.Errorf("CheckConfirm(%q) = %v", typed, err)
+		}
+	}
+}
+
+func TestCheckWindow(t *testing.T) {
+	g := protected(config.DeployPolicyConfig{Windows: []config.MaintenanceWindow{
+		{Days: []string{"sat", "sun"}, Start: "02:00", End: "05:00"},
+		{Days: []string{"mon"}, Start: "22:00", End: "01:00", Timezone: "Europe/Berlin"},
+	}})
+	for _, tc := range []struct {
+		at   string
+		open bool
+	}{
+		{"2026-10-17T03:00:00Z", true},  // Saturday
+		{"2026-10-17T05:00:00Z", false}, // Saturday, as the window closes
+		{"2026-10-16T03:00:00Z", false}, // Friday
+		{"2026-10-19T21:30:00Z", true},  // Monday 23:30 in Berlin
+		{"2026-10-19T22:30:00Z", true},  // Tuesday 00:30 in Berlin, in Monday's window
+		{"2026-10-20T21:30:00Z", false}, // Tuesday 23:30 in Berlin
+	} {
+		at, _ := time.Parse(time.RFC3339, tc.at)
+		g.now = func() time.Time { return at }
+		if err := g.CheckWindow(); (err == nil) != tc.open {
+			t.Errorf("at %s: %v", tc.at, err)
+		}
+	}
+}
+
+func TestApprove(t *testing.T) {
+	var got Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("authorization") != "Bearer s3cret" {
+			http.Error(w, "who are you", http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		switch got.Service {
+		case "web":
+			w.WriteHeader(http.StatusNoContent)
+		case "api":
+			json.NewEncoder(w).Encode(map[string]any{"approved": false, "reason": "change freeze"})
+		case "db":
+			http.Error(w, "ask the DBA", http.StatusForbidden)
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	g := protected(config.DeployPolicyConfig{Approval: config.ApprovalConfig{URL: srv.URL, Headers: map[string]string{"authorization": "Bearer s3cret"}}})
+	ctx := context.Background()
+	if err := g.Approve(ctx, v1.ServiceSpec{Name: "web"}, "acme/web:2"); err != nil {
+		t.Errorf("web: %v", err)
+	}
+	if got.Project != "shop" || got.Environment != "production" || got.Node != "edge" || got.Image != "acme/web:2" {
+		t.Errorf("request = %+v", got)
+	}
+	for service, want := range map[string]string{
+		"api":   "change freeze",
+		"db":    "ask the DBA",
+		"cache": "500",
+	} {
+		err := g.Approve(ctx, v1.ServiceSpec{Name: service}, "acme/"+service+":2")
+		if !errs.IsCode(err, errs.ErrPolicyApproval) || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: %v, want %q", service, err, want)
+		}
+	}
+
+	g = protected(config.DeployPolicyConfig{Approval: config.ApprovalConfig{URL: srv.URL}})
+	if err := g.Policy()(ctx, v1.ServiceSpec{Name: "web"}, "acme/web:2"); err == nil {
+		t.Error("a webhook that refused the credentials approved the deploy")
+	}
+}
@@ -0,0 +1,210 @@
+// Package gitops keeps a node converged on the orbit.yaml held in a git
+// repository — a lightweight Flux for single nodes and small fleets. The
+// Syncer polls the repository and, for each new commit, plans the changes
+// against what is running and deploys them, recording the commit in each
+// service's deployment history.
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// DefaultInterval is how often the repository is polled.
+const DefaultInterval = time.Minute
+
+// DefaultPath is the config file's path within the repository.
+const DefaultPath = "orbit.yaml"
+
+// Reason is recorded on the deployments the Syncer makes.
+const Reason = "gitops"
+
+// Planner computes the changes that converge a node on specs. It is
+// satisfied by *orchestrator.Planner.
+type Planner interface {
+	Plan(ctx context.Context, specs []v1.ServiceSpec, node string, prune bool) (*orchestrator.Plan, error)
+}
+
+// Deployer rolls a service out. It is satisfied by *orchestrator.Deployer.
+type Deployer interface {
+	Deploy(ctx context.Context, spec v1.ServiceSpec, node string, opts orchestrator.DeployOptions) error
+}
+
+// Remover stops and removes services. It is satisfied by
+// *orchestrator.LifecycleManager.
+type Remover interface {
+	Down(ctx context.Context, node string, names []string, removeVolumes bool) error
+}
+
+// Event reports one commit the Syncer applied, or failed to.
+type Event struct {
+	Commit string
+	Config *config.Config     // the commit's configuration; nil when it could not be loaded
+	Plan   *orchestrator.Plan // what was applied; nil when planning failed
+	Err    error              // every failure, as an *errs.MultiError when there are several
+	Time   time.Time
+}
+
+// Syncer applies each new commit of a repository's orbit.yaml to a node.
+type Syncer struct {
+	repo     *Repo
+	path     string
+	node     string
+	planner  Planner
+	deployer Deployer
+	remover  Remover // set by WithPrune
+	load     config.LoadOptions
+	profiles []string // active profiles of the services applied
+	log      *logger.Logger
+	events   chan Event
+	applied  string // last commit applied, successfully or not
+}
+
+// NewSyncer returns a Syncer that applies the config file at path within
+// repo to node.
+func NewSyncer(repo *Repo, path, node string, planner Planner, deployer Deployer, log *logger.Logger) *Syncer {
+	if path == "" {
+		path = DefaultPath
+	}
+	return &Syncer{
+		repo:     repo,
+		path:     path,
+		node:     node,
+		planner:  planner,
+		deployer: deployer,
+		log:      log,
+		events:   make(chan Event, 4),
+	}
+}
+
+// WithPrune removes services that are running but no longer in the
+// repository's config.
+func (s *Syncer) WithPrune(r Remover) *Syncer {
+	s.remover = r
+	return s
+}
+
+// WithLoadOptions sets how the repository's config is loaded, e.g. strictly.
+func (s *Syncer) WithLoadOptions(opts config.LoadOptions) *Syncer {
+	s.load = opts
+	return s
+}
+
+// WithProfiles applies the services in profiles, besides those in none.
+func (s *Syncer) WithProfiles(profiles []string) *Syncer {
+	s.profiles = profiles
+	return s
+}
+
+// Events returns the channel on which Run publishes an Event per commit.
+func (s *Syncer) Events() <-chan Event {
+	return s.events
+}
+
+// Run polls the repository every interval until ctx is cancelled, applying
+// each new commit and publishing the outcome on Events.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ev, changed := s.Sync(ctx)
+			if !changed {
+				continue
+			}
+			select {
+			case s.events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// Sync fetches the repository and, when it has moved to a commit not yet
+// applied, applies it. changed is false when nothing was applied: there was
+// no new commit, or the fetch failed, which ev.Err then reports and the next
+// poll retries. A commit is applied once: a failed apply is retried with
+// the next commit, or when the agent restarts, not on every poll.
+func (s *Syncer) Sync(ctx context.Context) (ev Event, changed bool) {
+	commit, err := s.repo.Sync(ctx)
+	if err != nil {
+		s.log.Warn("gitops.fetch.failed", "err", err)
+		return Event{Err: err, Time: time.Now()}, false
+	}
+	if commit == s.applied {
+		return Event{}, false
+	}
+	s.applied = commit
+	ev = s.apply(ctx, commit)
+	if ev.Err != nil {
+		s.log.Error("gitops.apply.failed", "commit", commit, "err", ev.Err)
+	} else {
+		s.log.Info("gitops.applied", "commit", commit, "changes", ev.Plan.String())
+	}
+	return ev, true
+}
+
+func (s *Syncer) apply(ctx context.Context, commit string) Event {
+	ev := Event{Commit: commit, Time: time.Now()}
+	cfg, err := config.LoadWithOptions(filepath.Join(s.repo.Dir(), s.path), s.load)
+	if err != nil {
+		ev.Err = fmt.Errorf("%s at %s: %w", s.path, ShortCommit(commit), err)
+		return ev
+	}
+	ev.Config = cfg
+
+	plan, err := s.planner.Plan(ctx, cfg.ActiveServices(s.profiles), s.node, s.remover != nil)
+	if err != nil {
+		ev.Err = fmt.Errorf("plan: %w", err)
+		return ev
+	}
+	// Services of inactive profiles are still in the config: not pruned.
+	plan.Services = slices.DeleteFunc(plan.Services, func(c orchestrator.ServiceChange) bool {
+		return c.Action == orchestrator.ActionDestroy && cfg.ServiceByName(c.Service) != nil
+	})
+	ev.Plan = plan
+
+	failed := errs.NewGroup(errs.ErrServiceStart, "gitops")
+	var removed []string
+	for _, change := range plan.Services {
+		switch change.Action {
+		case orchestrator.ActionCreate, orchestrator.ActionUpdate:
+			spec := cfg.ServiceByName(change.Service)
+			failed.Add(change.Service, s.deployer.Deploy(ctx, *spec, s.node, orchestrator.DeployOptions{
+				Reason: Reason,
+				Commit: commit,
+			}))
+		case orchestrator.ActionDestroy:
+			removed = append(removed, change.Service)
+		}
+	}
+	if len(removed) > 0 {
+		failed.Add("prune", s.remover.Down(ctx, s.node, removed, false))
+	}
+	ev.Err = failed.Err()
+	return ev
+}
+
+// ShortCommit abbreviates a commit hash for display.
+func ShortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
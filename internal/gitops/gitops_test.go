@@ -0,0 +1,171 @@
+package gitops_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/gitops"
+	"github.com/f9-o/orbit/internal/orchestrator"
+)
+
+// origin is a local repository standing in for the remote.
+type origin struct {
+	t   *testing.T
+	dir string
+}
+
+func newOrigin(t *testing.T) *origin {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	o := &origin{t: t, dir: t.TempDir()}
+	o.git("init", "--quiet", "--initial-branch", "main")
+	return o
+}
+
+func (o *origin) git(args ...string) string {
+	o.t.Helper()
+	args = append([]string{"-c", "user.name=orbit", "-c", "user.email=orbit@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = o.dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		o.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+// commit writes orbit.yaml and commits it, returning the commit hash.
+func (o *origin) commit(config string) string {
+	o.t.Helper()
+	if err := os.WriteFile(filepath.Join(o.dir, "orbit.yaml"), []byte(config), 0o644); err != nil {
+		o.t.Fatal(err)
+	}
+	o.git("add", "orbit.yaml")
+	o.git("commit", "--quiet", "-m", "update")
+	out := o.git("rev-parse", "HEAD")
+	return out[:len(out)-1]
+}
+
+// fakePlanner plans every service as an update.
+type fakePlanner struct{}
+
+func (fakePlanner) Plan(_ context.Context, specs []v1.ServiceSpec, node string, _ bool) (*orchestrator.Plan, error) {
+	plan := &orchestrator.Plan{Node: node}
+	for _, s := range specs {
+		plan.Services = append(plan.Services, orchestrator.ServiceChange{Service: s.Name, Action: orchestrator.ActionUpdate})
+	}
+	return plan, nil
+}
+
+type deployCall struct {
+	service, image string
+	opts           orchestrator.DeployOptions
+}
+
+type fakeDeployer struct {
+	calls []deployCall
+	fail  map[string]error
+}
+
+func (d *fakeDeployer) Deploy(_ context.Context, spec v1.ServiceSpec, _ string, opts orchestrator.DeployOptions) error {
+	d.calls = append(d.calls, deployCall{spec.Name, spec.Image, opts})
+	return d.fail[spec.Name]
+}
+
+func newSyncer(t *testing.T, o *origin, d *fakeDeployer) *gitops.Syncer {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir()) // no global config
+	log, _ := logger.Init("error", "text", "", "", false)
+	repo := gitops.NewRepo(o.dir, "main", filepath.Join(t.TempDir(), "checkout"))
+	return gitops.NewSyncer(repo, "", "local", fakePlanner{}, d, log)
+}
+
+func TestSyncAppliesEachCommitOnce(t *testing.T) {
+	o := newOrigin(t)
+	first := o.commit("services:\n  - name: web\n    image: nginx:1.25\n")
+	d := &fakeDeployer{}
+	s := newSyncer(t, o, d)
+	ctx := context.Background()
+
+	ev, changed := s.Sync(ctx)
+	if !changed || ev.Err != nil || ev.Commit != first {
+		t.Fatalf("first sync: changed=%v commit=%s err=%v", changed, ev.Commit, ev.Err)
+	}
+	if len(d.calls) != 1 || d.calls[0].image != "nginx:1.25" ||
+		d.calls[0].opts.Commit != first || d.calls[0].opts.Reason != gitops.Reason {
+		t.Fatalf("deploys = %+v", d.calls)
+	}
+
+	if _, changed := s.Sync(ctx); changed {
+		t.Error("second sync of the same commit applied it again")
+	}
+	if len(d.calls) != 1 {
+		t.Errorf("deploys = %+v, want no more", d.calls)
+	}
+
+	second := o.commit("services:\n  - name: web\n    image: nginx:1.27\n")
+	ev, changed = s.Sync(ctx)
+	if !changed || ev.Commit != second || ev.Config.Services[0].Image != "nginx:1.27" {
+		t.Fatalf("sync after a new commit: changed=%v commit=%s", changed, ev.Commit)
+	}
+	if len(d.calls) != 2 || d.calls[1].image != "nginx:1.27" || d.calls[1].opts.Commit != second {
+		t.Errorf("deploys = %+v", d.calls)
+	}
+}
+
+func TestSyncReportsFailures(t *testing.T) {
+	o := newOrigin(t)
+	o.commit("services:\n  - name: web\n    image: nginx\n  - name: api\n    image: api:1\n")
+	boom := errors.New("pull failed")
+	d := &fakeDeployer{fail: map[string]error{"web": boom}}
+	s := newSyncer(t, o, d)
+
+	ev, changed := s.Sync(context.Background())
+	if !changed || !errors.Is(ev.Err, boom) {
+		t.Fatalf("changed=%v err=%v, want the deploy failure", changed, ev.Err)
+	}
+	if len(d.calls) != 2 {
+		t.Errorf("deploys = %+v, want api deployed despite web failing", d.calls)
+	}
+
+	o.commit("services: [")
+	ev, changed = s.Sync(context.Background())
+	if !changed || ev.Err == nil || ev.Config != nil {
+		t.Errorf("broken config: changed=%v config=%v err=%v", changed, ev.Config, ev.Err)
+	}
+}
+
+func TestSyncFetchFailure(t *testing.T) {
+	d := &fakeDeployer{}
+	t.Setenv("HOME", t.TempDir())
+	log, _ := logger.Init("error", "text", "", "", false)
+	repo := gitops.NewRepo(filepath.Join(t.TempDir(), "missing"), "", filepath.Join(t.TempDir(), "checkout"))
+	s := gitops.NewSyncer(repo, "", "local", fakePlanner{}, d, log)
+
+	ev, changed := s.Sync(context.Background())
+	if changed || ev.Err == nil {
+		t.Errorf("changed=%v err=%v, want a fetch error", changed, ev.Err)
+	}
+}
+
+func TestSyncLeavesOutInactiveProfiles(t *testing.T) {
+	o := newOrigin(t)
+	o.commit("services:\n  - name: web\n    image: nginx\n  - name: adminer\n    image: adminer\n    profiles: [debug]\n")
+	d := &fakeDeployer{}
+	if _, changed := newSyncer(t, o, d).Sync(context.Background()); !changed || len(d.calls) != 1 || d.calls[0].service != "web" {
+		t.Fatalf("deploys = %+v, want only web", d.calls)
+	}
+
+	d = &fakeDeployer{}
+	if _, changed := newSyncer(t, o, d).WithProfiles([]string{"debug"}).Sync(context.Background()); !changed || len(d.calls) != 2 {
+		t.Errorf("deploys with debug active = %+v", d.calls)
+	}
+}
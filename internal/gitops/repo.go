@@ -0,0 +1,97 @@
+// Package gitops: the local checkout of the watched repository.
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Repo is a shallow checkout of one branch of a git repository, kept in
+// step with the remote by Sync. It drives the git binary, so the usual git
+// credentials — SSH keys, credential helpers — apply.
+type Repo struct {
+	url    string
+	branch string // "" = the remote's default branch
+	dir    string
+}
+
+// NewRepo returns a Repo for branch of url, checked out in dir.
+func NewRepo(url, branch, dir string) *Repo {
+	return &Repo{url: url, branch: branch, dir: dir}
+}
+
+// CheckoutDir is where the checkout of url lives under the Orbit home, one
+// directory per repository.
+func CheckoutDir(home, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(home, "gitops", hex.EncodeToString(sum[:6]))
+}
+
+// Dir returns the checkout's directory.
+func (r *Repo) Dir() string {
+	return r.dir
+}
+
+// Sync brings the checkout up to the remote branch's latest commit, cloning
+// it first if needed, and returns that commit. Local changes in the
+// checkout are discarded.
+func (r *Repo) Sync(ctx context.Context) (string, error) {
+	if _, err := os.Stat(filepath.Join(r.dir, ".git")); err != nil {
+		if err := os.MkdirAll(filepath.Dir(r.dir), 0o700); err != nil {
+			return "", err
+		}
+		_ = os.RemoveAll(r.dir) // a clone that was interrupted
+		args := []string{"clone", "--quiet", "--depth", "1"}
+		if r.branch != "" {
+			args = append(args, "--branch", r.branch)
+		}
+		if _, err := r.git(ctx, "", append(args, "--", r.url, r.dir)...); err != nil {
+			return "", err
+		}
+	} else {
+		ref := r.branch
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if _, err := r.git(ctx, r.dir, "remote", "set-url", "origin", r.url); err != nil {
+			return "", err
+		}
+		if _, err := r.git(ctx, r.dir, "fetch", "--quiet", "--depth", "1", "origin", ref); err != nil {
+			return "", err
+		}
+		if _, err := r.git(ctx, r.dir, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+		if _, err := r.git(ctx, r.dir, "clean", "--quiet", "-fdx"); err != nil {
+			return "", err
+		}
+	}
+	return r.git(ctx, r.dir, "rev-parse", "HEAD")
+}
+
+// git runs a git command in dir and returns its trimmed output. Prompts
+// are disabled: with no credentials, git fails instead of waiting on a
+// terminal the agent does not have.
+func (r *Repo) git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
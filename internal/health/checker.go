@@ -4,10 +4,16 @@ package health
 import (
 	"context"
 	"fmt"
+	"net"
+	"strconv"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	v1 "github.com/f9-o/orbit/api/v1"
 	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/telemetry"
 )
 
 // DefaultInterval is used when spec.HealthCheck.Interval is zero.
@@ -19,9 +25,17 @@ const DefaultTimeout = 5 * time.Second
 // DefaultRetries is used when spec.HealthCheck.Retries is zero.
 const DefaultRetries = 3
 
+// Execer runs a command inside a container. It is satisfied by
+// *orchestrator.Client and kept as an interface to avoid an import cycle.
+type Execer interface {
+	Exec(ctx context.Context, containerID string, cmd []string) (exitCode int, output string, err error)
+}
+
 // Checker dispatches health probes for a ServiceSpec.
 type Checker struct {
-	log *logger.Logger
+	log       *logger.Logger
+	execer    Execer
+	inspector HealthInspector
 }
 
 // NewChecker constructs a Checker.
@@ -29,84 +43,94 @@ func NewChecker(log *logger.Logger) *Checker {
 	return &Checker{log: log}
 }
 
+// WithExecer enables "exec" probes that run inside the service container.
+func (c *Checker) WithExecer(e Execer) *Checker {
+	c.execer = e
+	return c
+}
+
+// WithInspector enables "docker" probes and, for services without a
+// health_check, falls back to the image's own HEALTHCHECK.
+func (c *Checker) WithInspector(i HealthInspector) *Checker {
+	c.inspector = i
+	return c
+}
+
 // Check performs a single health probe for spec and returns nil if healthy.
-func (c *Checker) Check(ctx context.Context, spec v1.ServiceSpec, containerID string) error {
-	hc := spec.HealthCheck
+func (c *Checker) Check(ctx context.Context, spec v1.ServiceSpec, containerID string) (err error) {
+	ctx, span := startCheckSpan(ctx, spec, containerID, "")
+	defer func() { telemetry.End(span, err) }()
+	spec = c.withNative(ctx, spec, containerID)
+	return c.checkSpec(ctx, spec.HealthCheck, containerID)
+}
+
+// startCheckSpan opens the span for one probe attempt; kind is empty for the
+// base health check.
+func startCheckSpan(ctx context.Context, spec v1.ServiceSpec, containerID string, kind ProbeKind) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("orbit.service", spec.Name),
+		attribute.String("orbit.container", containerID),
+	}
+	if kind != "" {
+		attrs = append(attrs, attribute.String("orbit.probe", string(kind)))
+	}
+	return telemetry.Start(ctx, "orbit.healthcheck", attrs...)
+}
+
+// checkSpec dispatches a single probe attempt for hc.
+func (c *Checker) checkSpec(ctx context.Context, hc *v1.HealthCheckSpec, containerID string) error {
 	if hc == nil {
 		return nil // No health check configured — assume healthy
 	}
 
 	switch hc.Type {
 	case "http":
-		return CheckHTTP(ctx, hc.URL, hc.ExpectedCode, hc.Timeout)
+		return CheckHTTP(ctx, hc.URL, HTTPOptions{
+			Method:          hc.Method,
+			Headers:         hc.Headers,
+			ExpectedCode:    hc.ExpectedCode,
+			ExpectBody:      hc.ExpectBody,
+			ExpectBodyRegex: hc.ExpectBodyRegex,
+			ExpectHeaders:   hc.ExpectHeaders,
+			TLSSkipVerify:   hc.TLSSkipVerify,
+			CAFile:          hc.CAFile,
+			ServerName:      hc.ServerName,
+		}, hc.Timeout)
 	case "tcp":
 		host := "localhost"
 		return CheckTCP(ctx, host, hc.Port, hc.Timeout)
 	case "cmd":
 		return CheckCmd(ctx, hc.Command, hc.Timeout)
+	case "exec":
+		return CheckExec(ctx, c.execer, containerID, hc.Command, hc.Timeout)
+	case "grpc":
+		addr := net.JoinHostPort("localhost", strconv.Itoa(hc.Port))
+		return CheckGRPC(ctx, addr, GRPCOptions{
+			Service:       hc.GRPCService,
+			TLS:           hc.TLS,
+			TLSSkipVerify: hc.TLSSkipVerify,
+			CAFile:        hc.CAFile,
+			ServerName:    hc.ServerName,
+		}, hc.Timeout)
+	case "docker":
+		return CheckDocker(ctx, c.inspector, containerID)
 	default:
 		return fmt.Errorf("unknown health check type %q", hc.Type)
 	}
 }
 
 // WaitHealthy polls the health check until it passes or ctx is cancelled.
-// Uses exponential backoff up to the configured interval.
+// It is equivalent to WaitReady: the startup probe (if configured) followed by
+// the readiness probe.
 func (c *Checker) WaitHealthy(ctx context.Context, spec v1.ServiceSpec, containerID string) error {
-	hc := spec.HealthCheck
-	if hc == nil {
-		return nil
-	}
-
-	interval := hc.Interval
-	if interval == 0 {
-		interval = DefaultInterval
-	}
-	retries := hc.Retries
-	if retries == 0 {
-		retries = DefaultRetries
-	}
-
-	var lastErr error
-	for attempt := 0; attempt <= retries; attempt++ {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		if attempt > 0 {
-			// Wait interval between attempts
-			timer := time.NewTimer(interval)
-			select {
-			case <-ctx.Done():
-				timer.Stop()
-				return ctx.Err()
-			case <-timer.C:
-			}
-		}
-
-		lastErr = c.Check(ctx, spec, containerID)
-		if lastErr == nil {
-			c.log.Info("health check passed", "service", spec.Name, "attempt", attempt+1)
-			return nil
-		}
-
-		c.log.Debug("health check attempt failed",
-			"service", spec.Name,
-			"attempt", attempt+1,
-			"of", retries+1,
-			"err", lastErr,
-		)
-	}
-
-	return fmt.Errorf("health check failed after %d attempts: %w", retries+1, lastErr)
+	return c.WaitReady(ctx, spec, containerID)
 }
 
-// Probe performs a one-off health check for a service and returns the ServiceStatus.
+// Probe performs a one-off readiness check for a service and returns the ServiceStatus.
 func (c *Checker) Probe(ctx context.Context, spec v1.ServiceSpec, containerID string) v1.ServiceStatus {
-	if err := c.Check(ctx, spec, containerID); err != nil {
+	err := c.CheckProbe(ctx, spec, containerID, ProbeReadiness)
+	if err != nil {
 		c.log.Debug("health probe unhealthy", "service", spec.Name, "err", err)
-		return v1.StatusUnhealthy
 	}
-	return v1.StatusHealthy
+	return statusFor(err)
 }
@@ -0,0 +1,115 @@
+// Package health: Docker-native HEALTHCHECK integration.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// Docker health states as reported by ContainerInspect.
+const (
+	dockerHealthStarting  = "starting"
+	dockerHealthHealthy   = "healthy"
+	dockerHealthUnhealthy = "unhealthy"
+)
+
+// nativeInterval is how often Docker's health state is polled for images
+// whose HEALTHCHECK is used in place of an orbit.yaml health_check.
+const nativeInterval = 2 * time.Second
+
+// nativeResync replaces nativeInterval when the monitor follows container
+// events: health_status events report each transition as it happens.
+const nativeResync = 30 * time.Second
+
+// ErrStarting is returned while a container's Docker HEALTHCHECK is still in
+// its start period. Waiters keep polling instead of counting it as a failure.
+var ErrStarting = errors.New("container health is starting")
+
+// HealthInspector reports the state of a container's Docker-native HEALTHCHECK.
+// It is satisfied by *orchestrator.Client. status is "" when the image
+// defines no HEALTHCHECK.
+type HealthInspector interface {
+	ContainerHealth(ctx context.Context, containerID string) (status, lastOutput string, err error)
+}
+
+// CheckDocker translates the container's Docker health state into a probe result.
+func CheckDocker(ctx context.Context, inspector HealthInspector, containerID string) error {
+	if inspector == nil {
+		return fmt.Errorf("docker health check: no container runtime available")
+	}
+	if containerID == "" {
+		return fmt.Errorf("docker health check: container id is required")
+	}
+
+	status, out, err := inspector.ContainerHealth(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("docker health check: %w", err)
+	}
+	return dockerHealthErr(status, out)
+}
+
+// dockerHealthErr maps a Docker health status and its last probe output to a
+// probe result.
+func dockerHealthErr(status, out string) error {
+	switch status {
+	case dockerHealthHealthy:
+		return nil
+	case dockerHealthStarting:
+		return ErrStarting
+	case dockerHealthUnhealthy:
+		return fmt.Errorf("docker reports unhealthy (output: %s)", strings.TrimSpace(out))
+	case "":
+		return fmt.Errorf("docker health check: image defines no HEALTHCHECK")
+	default:
+		return fmt.Errorf("docker health check: unexpected status %q", status)
+	}
+}
+
+// withNative returns spec with a "docker" health check filled in when orbit.yaml
+// configures none but the container's image defines its own HEALTHCHECK.
+func (c *Checker) withNative(ctx context.Context, spec v1.ServiceSpec, containerID string) v1.ServiceSpec {
+	if spec.HealthCheck != nil || c.inspector == nil || containerID == "" {
+		return spec
+	}
+	status, _, err := c.inspector.ContainerHealth(ctx, containerID)
+	if err != nil || status == "" {
+		return spec
+	}
+	c.log.Debug("using docker HEALTHCHECK", "service", spec.Name)
+	spec.HealthCheck = &v1.HealthCheckSpec{Type: "docker", Interval: nativeInterval}
+	return spec
+}
+
+// onHealthStatus applies a health_status event to a service whose container
+// is probed through its Docker HEALTHCHECK.
+func (m *Monitor) onHealthStatus(ev v1.ContainerEvent) {
+	spec, ok := m.specs[ev.Service]
+	if ok && spec.HealthCheck == nil {
+		spec, ok = m.resolved[ev.ID]
+	}
+	if !ok || spec.HealthCheck == nil || spec.HealthCheck.Type != "docker" {
+		return
+	}
+	s, err := m.state.GetServiceState(m.node, ev.Service)
+	if err != nil || s == nil || s.ContainerID != ev.ID {
+		return
+	}
+	m.record(*s, dockerHealthErr(ev.Health, ""))
+}
+
+// statusFor maps a probe result to a ServiceStatus.
+func statusFor(err error) v1.ServiceStatus {
+	switch {
+	case err == nil:
+		return v1.StatusHealthy
+	case errors.Is(err, ErrStarting):
+		return v1.StatusUnknown
+	default:
+		return v1.StatusUnhealthy
+	}
+}
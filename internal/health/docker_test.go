@@ -0,0 +1,94 @@
+package health
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+// fakeInspector replays a fixed sequence of Docker health states.
+type fakeInspector struct {
+	states []string
+	calls  int
+}
+
+func (f *fakeInspector) ContainerHealth(context.Context, string) (string, string, error) {
+	i := min(f.calls, len(f.states)-1)
+	f.calls++
+	return f.states[i], "curl: (7) connection refused", nil
+}
+
+func TestNativeHealthcheckFallback(t *testing.T) {
+	log, _ := logger.Init("error", "text", "", "", false)
+	insp := &fakeInspector{states: []string{"starting", "starting", "starting", "starting", "starting", "healthy"}}
+	c := NewChecker(log).WithInspector(insp)
+
+	spec := v1.ServiceSpec{Name: "web"} // no health_check in orbit.yaml
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// withNative consumes one state; starting must not count toward the failure threshold
+	got := c.withNative(ctx, spec, "abc")
+	if got.HealthCheck == nil || got.HealthCheck.Type != "docker" {
+		t.Fatalf("expected docker health check, got %+v", got.HealthCheck)
+	}
+	got.HealthCheck.Interval = time.Millisecond
+	if err := c.WaitReady(ctx, got, "abc"); err != nil {
+		t.Fatalf("WaitReady: %v", err)
+	}
+
+	if s := statusFor(CheckDocker(ctx, &fakeInspector{states: []string{"unhealthy"}}, "abc")); s != v1.StatusUnhealthy {
+		t.Errorf("unhealthy maps to %q", s)
+	}
+	if s := statusFor(CheckDocker(ctx, &fakeInspector{states: []string{"starting"}}, "abc")); s != v1.StatusUnknown {
+		t.Errorf("starting maps to %q", s)
+	}
+	if c := NewChecker(log).WithInspector(&fakeInspector{states: []string{""}}); c.withNative(ctx, spec, "abc").HealthCheck != nil {
+		t.Error("image without HEALTHCHECK should stay unprobed")
+	}
+}
+
+func TestHealthStatusEventUpdatesState(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open state: %v", err)
+	}
+	defer db.Close()
+	if err := db.PutServiceState(v1.ServiceState{
+		Name: "web", ContainerID: "abc", Node: "local", Status: v1.StatusHealthy, Ready: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	log, _ := logger.Init("error", "text", "", "", false)
+	checker := NewChecker(log).WithInspector(&fakeInspector{states: []string{"healthy"}})
+	m := NewMonitor(checker, db, "local", []v1.ServiceSpec{{Name: "web"}}, log).
+		WithWatchdog(make(fakeEvents), &fakeStopper{}, DefaultCrashLoopPolicy)
+
+	m.sweep(context.Background())
+	if spec := m.resolved["abc"]; spec.HealthCheck == nil || spec.HealthCheck.Interval != nativeResync {
+		t.Fatalf("resolved = %+v", spec.HealthCheck)
+	}
+
+	m.handleEvent(context.Background(), v1.ContainerEvent{ID: "abc", Service: "web", Action: "health_status", Health: "unhealthy"})
+	ev := <-m.Events()
+	if ev.From != v1.StatusHealthy || ev.To != v1.StatusUnhealthy {
+		t.Fatalf("event = %+v", ev)
+	}
+	if s, _ := db.GetServiceState("local", "web"); s.Status != v1.StatusUnhealthy || s.Ready {
+		t.Fatalf("state = %+v", s)
+	}
+
+	// Events for a container the service no longer runs are ignored.
+	m.handleEvent(context.Background(), v1.ContainerEvent{ID: "old", Service: "web", Action: "health_status", Health: "healthy"})
+	if s, _ := db.GetServiceState("local", "web"); s.Status != v1.StatusUnhealthy {
+		t.Fatalf("state = %+v", s)
+	}
+}
@@ -0,0 +1,89 @@
+// Package health: restart history from container inspection, which tells a
+// healthy service from one its restart policy keeps quietly restarting.
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// exitSync is how often the monitor inspects each service's container for
+// restarts the event stream did not report, e.g. while no agent ran.
+const exitSync = 15 * time.Second
+
+// ExitInspector reports a container's restart history. It is satisfied by
+// *orchestrator.Client.
+type ExitInspector interface {
+	ContainerExit(ctx context.Context, containerID string) (v1.ContainerExit, error)
+}
+
+// WithExits makes Run inspect each service's container for its restart
+// count, exit code and OOM kills, and keeps ServiceState in step with them.
+func (m *Monitor) WithExits(x ExitInspector) *Monitor {
+	m.exits = x
+	return m
+}
+
+// syncExits merges the restart history of s's container into its state and
+// returns the state as stored.
+func (m *Monitor) syncExits(ctx context.Context, s v1.ServiceState, now time.Time) v1.ServiceState {
+	if m.exits == nil {
+		return s
+	}
+	key := s.Name + "/exits"
+	if next, ok := m.due[key]; ok && now.Before(next) {
+		return s
+	}
+	m.due[key] = now.Add(exitSync)
+
+	x, err := m.exits.ContainerExit(ctx, s.ContainerID)
+	if err != nil {
+		m.log.Debug("health monitor: inspect exits", "service", s.Name, "err", err)
+		return s
+	}
+	if x.Restarts <= s.RestartCount && !x.ExitedAt.After(s.LastExit) {
+		return s
+	}
+
+	cur, err := m.state.GetServiceState(m.node, s.Name)
+	if err != nil || cur == nil || cur.ContainerID != s.ContainerID {
+		return s
+	}
+	cur.RestartCount = max(cur.RestartCount, x.Restarts)
+	if x.ExitedAt.After(cur.LastExit) {
+		cur.ExitCode, cur.OOMKilled, cur.LastExit = x.ExitCode, x.OOMKilled, x.ExitedAt
+	}
+	if err := m.state.PutServiceState(*cur); err != nil {
+		m.log.Warn("health monitor: state update failed", "service", s.Name, "err", err)
+		return s
+	}
+	return *cur
+}
+
+// restartWindow is how long after an unexpected exit a service counts as
+// restarting: the crash-loop window.
+func (m *Monitor) restartWindow() time.Duration {
+	if m.crashes != nil {
+		return m.crashes.policy.Window
+	}
+	return DefaultCrashLoopPolicy.Window
+}
+
+// restarting reports whether s exited unexpectedly within the restart
+// window. Its probes may pass between restarts, but it is not healthy.
+func (m *Monitor) restarting(s v1.ServiceState, now time.Time) bool {
+	return s.RestartCount > 0 && !s.LastExit.IsZero() && now.Sub(s.LastExit) < m.restartWindow()
+}
+
+// restartErr describes a service's recent restarts for a ServiceEvent.
+func restartErr(s v1.ServiceState) error {
+	cause := fmt.Sprintf("exit code %d", s.ExitCode)
+	if s.OOMKilled {
+		cause = "killed for running out of memory"
+	}
+	return fmt.Errorf("restarted %d time(s), last %s ago (%s)",
+		s.RestartCount, time.Since(s.LastExit).Round(time.Second), cause)
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+type fakeExits struct{ exit v1.ContainerExit }
+
+func (f *fakeExits) ContainerExit(context.Context, string) (v1.ContainerExit, error) {
+	return f.exit, nil
+}
+
+// A service whose restart policy restarts it between probes passes them,
+// but inspection shows the restarts and it is reported degraded.
+func TestMonitorDegradesRestartingService(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open state: %v", err)
+	}
+	defer db.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	spec := v1.ServiceSpec{Name: "db", HealthCheck: &v1.HealthCheckSpec{
+		Type: "tcp", Port: ln.Addr().(*net.TCPAddr).Port, Timeout: time.Second,
+	}}
+	if err := db.PutServiceState(v1.ServiceState{
+		Name: "db", ContainerID: "abc123", Node: "local", Status: v1.StatusHealthy, Ready: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	log, _ := logger.Init("error", "text", "", "", false)
+	exitedAt := time.Now().Add(-time.Minute).UTC()
+	exits := &fakeExits{exit: v1.ContainerExit{Restarts: 4, ExitCode: 137, OOMKilled: true, ExitedAt: exitedAt}}
+	m := NewMonitor(NewChecker(log), db, "local", []v1.ServiceSpec{spec}, log).WithExits(exits)
+
+	m.sweep(context.Background())
+	ev := <-m.Events()
+	if ev.From != v1.StatusHealthy || ev.To != v1.StatusDegraded || ev.Err == nil {
+		t.Fatalf("event = %+v", ev)
+	}
+	s, _ := db.GetServiceState("local", "db")
+	if s.Status != v1.StatusDegraded || !s.Ready || s.RestartCount != 4 || s.ExitCode != 137 ||
+		!s.OOMKilled || !s.LastExit.Equal(exitedAt) {
+		t.Fatalf("state = %+v", s)
+	}
+
+	// Once the last exit is older than the crash-loop window it is healthy again.
+	s.LastExit = time.Now().Add(-time.Hour)
+	if err := db.PutServiceState(*s); err != nil {
+		t.Fatal(err)
+	}
+	exits.exit.ExitedAt = s.LastExit
+	m.due = map[string]time.Time{}
+	m.sweep(context.Background())
+	if ev := <-m.Events(); ev.To != v1.StatusHealthy {
+		t.Fatalf("after the window: event = %+v", ev)
+	}
+}
@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// fakeHealthServer serves grpc.health.v1.Health with service reporting status.
+func fakeHealthServer(t *testing.T, service string, status healthpb.HealthCheckResponse_ServingStatus) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := grpchealth.NewServer()
+	hs.SetServingStatus(service, status)
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestCheckGRPC(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  healthpb.HealthCheckResponse_ServingStatus
+		service string
+		wantErr string
+	}{
+		{"serving", healthpb.HealthCheckResponse_SERVING, "api", ""},
+		{"not serving", healthpb.HealthCheckResponse_NOT_SERVING, "api", "NOT_SERVING"},
+		{"unknown service", healthpb.HealthCheckResponse_SERVING, "billing", "NotFound"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := fakeHealthServer(t, "api", tt.status)
+
+			err := CheckGRPC(context.Background(), addr, GRPCOptions{Service: tt.service}, 2*time.Second)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
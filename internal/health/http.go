@@ -4,13 +4,33 @@ package health
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
 )
 
-// CheckHTTP performs an HTTP GET to url and verifies the response code.
-// If expectedCode is 0, any 2xx is accepted.
-func CheckHTTP(ctx context.Context, url string, expectedCode int, timeout time.Duration) error {
+// maxProbeBody caps how much of a response body is read for matching.
+const maxProbeBody = 1 << 20
+
+// HTTPOptions configures an HTTP probe beyond the target URL.
+type HTTPOptions struct {
+	Method          string            // default GET
+	Headers         map[string]string // request headers
+	ExpectedCode    int               // 0 accepts any 2xx
+	ExpectBody      string            // required body substring
+	ExpectBodyRegex string            // required body regexp
+	ExpectHeaders   map[string]string // required response headers (case-insensitive substring); "" checks presence only
+
+	TLSSkipVerify bool
+	CAFile        string
+	ServerName    string
+}
+
+// CheckHTTP performs an HTTP request to url and verifies the response code,
+// and optionally the response body and headers.
+func CheckHTTP(ctx context.Context, url string, opts HTTPOptions, timeout time.Duration) error {
 	if url == "" {
 		return fmt.Errorf("http health check: url is required")
 	}
@@ -18,8 +38,28 @@ func CheckHTTP(ctx context.Context, url string, expectedCode int, timeout time.D
 		timeout = DefaultTimeout
 	}
 
+	var bodyRe *regexp.Regexp
+	if opts.ExpectBodyRegex != "" {
+		re, err := regexp.Compile(opts.ExpectBodyRegex)
+		if err != nil {
+			return fmt.Errorf("invalid expect_body_regex: %w", err)
+		}
+		bodyRe = re
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.TLSSkipVerify || opts.CAFile != "" || opts.ServerName != "" {
+		tlsCfg, err := probeTLSConfig(opts.TLSSkipVerify, opts.CAFile, opts.ServerName)
+		if err != nil {
+			return err
+		}
+		transport.TLSClientConfig = tlsCfg
+	}
+	defer transport.CloseIdleConnections()
+
 	client := &http.Client{
-		Timeout: timeout,
+		Timeout:   timeout,
+		Transport: transport,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) > 5 {
 				return fmt.Errorf("too many redirects")
@@ -28,26 +68,70 @@ func CheckHTTP(ctx context.Context, url string, expectedCode int, timeout time.D
 		},
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	method := http.MethodGet
+	if opts.Method != "" {
+		method = strings.ToUpper(opts.Method)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return fmt.Errorf("build request: %w", err)
 	}
 	req.Header.Set("User-Agent", "orbit-health/1.0")
+	for k, v := range opts.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("http get %q: %w", url, err)
+		return fmt.Errorf("http %s %q: %w", strings.ToLower(method), url, err)
 	}
 	defer resp.Body.Close()
 
-	if expectedCode != 0 {
-		if resp.StatusCode != expectedCode {
-			return fmt.Errorf("expected status %d, got %d", expectedCode, resp.StatusCode)
+	if opts.ExpectedCode != 0 {
+		if resp.StatusCode != opts.ExpectedCode {
+			return fmt.Errorf("expected status %d, got %d", opts.ExpectedCode, resp.StatusCode)
 		}
 	} else {
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			return fmt.Errorf("non-2xx status: %d", resp.StatusCode)
 		}
 	}
+
+	for k, want := range opts.ExpectHeaders {
+		got, ok := resp.Header[http.CanonicalHeaderKey(k)]
+		if !ok {
+			return fmt.Errorf("missing response header %q", k)
+		}
+		if want != "" && !containsFold(got, want) {
+			return fmt.Errorf("response header %q = %q, want %q", k, strings.Join(got, ", "), want)
+		}
+	}
+
+	if opts.ExpectBody == "" && bodyRe == nil {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	if opts.ExpectBody != "" && !strings.Contains(string(body), opts.ExpectBody) {
+		return fmt.Errorf("response body does not contain %q", opts.ExpectBody)
+	}
+	if bodyRe != nil && !bodyRe.Match(body) {
+		return fmt.Errorf("response body does not match %q", opts.ExpectBodyRegex)
+	}
 	return nil
 }
+
+func containsFold(values []string, want string) bool {
+	for _, v := range values {
+		if strings.Contains(strings.ToLower(v), strings.ToLower(want)) {
+			return true
+		}
+	}
+	return false
+}
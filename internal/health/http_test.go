@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckHTTPMatching(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method == http.MethodHead {
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"ok","db":"up"}`))
+	}))
+	defer srv.Close()
+
+	base := HTTPOptions{
+		Headers:       map[string]string{"Authorization": "Bearer s3cret"},
+		TLSSkipVerify: true,
+	}
+	with := func(f func(*HTTPOptions)) HTTPOptions {
+		o := base
+		f(&o)
+		return o
+	}
+
+	tests := []struct {
+		name    string
+		opts    HTTPOptions
+		wantErr string
+	}{
+		{"ok", base, ""},
+		{"substring", with(func(o *HTTPOptions) { o.ExpectBody = `"db":"up"` }), ""},
+		{"regex", with(func(o *HTTPOptions) { o.ExpectBodyRegex = `"status":\s*"ok"` }), ""},
+		{"regex miss", with(func(o *HTTPOptions) { o.ExpectBodyRegex = `"status":"degraded"` }), "does not match"},
+		{"header", with(func(o *HTTPOptions) { o.ExpectHeaders = map[string]string{"content-type": "application/json"} }), ""},
+		{"header missing", with(func(o *HTTPOptions) { o.ExpectHeaders = map[string]string{"X-Ready": ""} }), "missing response header"},
+		{"head", with(func(o *HTTPOptions) { o.Method = "head" }), ""},
+		{"no auth", with(func(o *HTTPOptions) { o.Headers = nil }), "non-2xx"},
+		{"tls verify", with(func(o *HTTPOptions) { o.TLSSkipVerify = false }), "certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckHTTP(context.Background(), srv.URL, tt.opts, 2*time.Second)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
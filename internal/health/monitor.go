@@ -0,0 +1,301 @@
+// Package health: background monitor that keeps ServiceState.Status current.
+package health
+
+import (
+	"context"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+)
+
+// MonitorTick is how often the monitor checks which probes are due.
+// Each probe still runs at its own configured interval.
+const MonitorTick = time.Second
+
+// ServiceEvent is emitted on the event channel when a service's health changes
+// or the monitor restarts it after liveness failures.
+type ServiceEvent struct {
+	Node        string
+	Service     string
+	ContainerID string
+	From        v1.ServiceStatus
+	To          v1.ServiceStatus
+	Restarted   bool
+	CrashLoop   bool  // the watchdog stopped the container after repeated exits
+	Err         error // last probe error or restart cause, nil on recovery
+	Time        time.Time
+}
+
+// Restarter restarts a container. It is satisfied by *orchestrator.Client.
+type Restarter interface {
+	RestartContainer(ctx context.Context, containerID string) error
+}
+
+// Monitor periodically probes every running service on a node, persists
+// status transitions to state, and publishes them as ServiceEvents.
+type Monitor struct {
+	checker   *Checker
+	state     *state.DB
+	node      string
+	specs     map[string]v1.ServiceSpec
+	restarter Restarter
+	events    chan ServiceEvent
+	updates   chan []v1.ServiceSpec // from SetServices, applied by Run
+	log       *logger.Logger
+
+	eventSrc EventSource // container events for the watchdog and native health; nil when disabled
+	stopper  Stopper
+	crashes  *crashTracker
+	exits    ExitInspector // restart history by inspection; nil when disabled
+
+	due      map[string]time.Time // "service/kind" → next probe time
+	liveness map[string]*LivenessTracker
+	resolved map[string]v1.ServiceSpec // container ID → spec with any native HEALTHCHECK applied
+}
+
+// NewMonitor constructs a Monitor for the given services on node.
+// The events channel is buffered; consumers should drain it promptly.
+func NewMonitor(checker *Checker, db *state.DB, node string, specs []v1.ServiceSpec, log *logger.Logger) *Monitor {
+	return &Monitor{
+		checker:  checker,
+		state:    db,
+		node:     node,
+		specs:    specsByName(specs),
+		events:   make(chan ServiceEvent, 64),
+		updates:  make(chan []v1.ServiceSpec, 1),
+		log:      log,
+		due:      make(map[string]time.Time),
+		liveness: make(map[string]*LivenessTracker),
+		resolved: make(map[string]v1.ServiceSpec),
+	}
+}
+
+// WithRestarter enables liveness-driven restarts.
+func (m *Monitor) WithRestarter(r Restarter) *Monitor {
+	m.restarter = r
+	return m
+}
+
+// SetServices replaces the services the monitor probes, e.g. after
+// orbit.yaml changed. It is safe to call while Run is running.
+func (m *Monitor) SetServices(specs []v1.ServiceSpec) {
+	for {
+		select {
+		case m.updates <- specs:
+			return
+		case <-m.updates: // drop an update Run has not picked up yet
+		}
+	}
+}
+
+func specsByName(specs []v1.ServiceSpec) map[string]v1.ServiceSpec {
+	bySvc := make(map[string]v1.ServiceSpec, len(specs))
+	for _, s := range specs {
+		bySvc[s.Name] = s
+	}
+	return bySvc
+}
+
+// Events returns the channel on which ServiceEvents are published.
+func (m *Monitor) Events() <-chan ServiceEvent {
+	return m.events
+}
+
+// Run starts the monitor loop. Blocks until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(MonitorTick)
+	defer ticker.Stop()
+
+	evs, errc := m.subscribe(ctx)
+	var retry <-chan time.Time
+
+	m.sweep(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sweep(ctx)
+		case specs := <-m.updates:
+			m.specs = specsByName(specs)
+			clear(m.due)
+			clear(m.resolved)
+		case ev, ok := <-evs:
+			if !ok {
+				select {
+				case err := <-errc:
+					if err != nil {
+						m.log.Debug("health monitor: event stream ended", "err", err)
+					}
+				default:
+				}
+				evs, retry = nil, time.After(watchdogRetry)
+				continue
+			}
+			m.handleEvent(ctx, ev)
+		case <-retry:
+			evs, errc = m.subscribe(ctx)
+			retry = nil
+		}
+	}
+}
+
+// sweep runs every probe that is due.
+func (m *Monitor) sweep(ctx context.Context) {
+	states, err := m.state.ListServiceStates(m.node)
+	if err != nil {
+		m.log.Debug("health monitor: list services", "err", err)
+		return
+	}
+
+	now := time.Now()
+	live := make(map[string]bool, len(states))
+	for _, s := range states {
+		spec, ok := m.specs[s.Name]
+		if !ok || s.ContainerID == "" {
+			continue
+		}
+		live[s.ContainerID] = true
+		s = m.syncExits(ctx, s, now)
+		if spec, ok = m.resolve(ctx, spec, s.ContainerID); !ok {
+			continue
+		}
+
+		if m.takeDue(s.Name, ProbeReadiness, spec, now) {
+			err := m.checker.CheckProbe(ctx, spec, s.ContainerID, ProbeReadiness)
+			m.record(s, err)
+		}
+
+		if m.restarter != nil && m.takeDue(s.Name, ProbeLiveness, spec, now) {
+			err := m.checker.CheckProbe(ctx, spec, s.ContainerID, ProbeLiveness)
+			m.observeLiveness(ctx, spec, s, err)
+		}
+	}
+
+	// Forget containers replaced by a deploy.
+	for id := range m.liveness {
+		if !live[id] {
+			delete(m.liveness, id)
+		}
+	}
+	for id := range m.resolved {
+		if !live[id] {
+			delete(m.resolved, id)
+		}
+	}
+}
+
+// resolve returns the spec to probe for a container, consulting the image's
+// HEALTHCHECK once per container. ok is false when there is nothing to probe.
+func (m *Monitor) resolve(ctx context.Context, spec v1.ServiceSpec, containerID string) (v1.ServiceSpec, bool) {
+	if spec.HealthCheck == nil {
+		cached, seen := m.resolved[containerID]
+		if !seen {
+			cached = m.checker.withNative(ctx, spec, containerID)
+			if cached.HealthCheck != nil && m.eventSrc != nil {
+				// health_status events carry transitions; polling only resyncs.
+				cached.HealthCheck.Interval = nativeResync
+			}
+			m.resolved[containerID] = cached
+		}
+		spec = cached
+	}
+	return spec, spec.HealthCheck != nil
+}
+
+// takeDue reports whether the probe is due and, if so, schedules the next run.
+func (m *Monitor) takeDue(service string, kind ProbeKind, spec v1.ServiceSpec, now time.Time) bool {
+	key := service + "/" + string(kind)
+	if next, ok := m.due[key]; ok && now.Before(next) {
+		return false
+	}
+	m.due[key] = now.Add(Resolve(spec.HealthCheck, kind).Interval)
+	return true
+}
+
+// record persists a readiness result if it changes the service's status. A
+// passing service that is restarting is degraded rather than healthy.
+func (m *Monitor) record(s v1.ServiceState, probeErr error) {
+	to := statusFor(probeErr)
+	cause := probeErr
+	if to == v1.StatusHealthy && m.restarting(s, time.Now()) {
+		to, cause = v1.StatusDegraded, restartErr(s)
+	}
+	if s.Status == to && s.Ready == (probeErr == nil) {
+		return
+	}
+
+	// Re-read so a deploy that replaced the container since the sweep began wins.
+	cur, err := m.state.GetServiceState(m.node, s.Name)
+	if err != nil || cur == nil || cur.ContainerID != s.ContainerID {
+		return
+	}
+	from := cur.Status
+	cur.Status = to
+	cur.Ready = probeErr == nil
+	if err := m.state.PutServiceState(*cur); err != nil {
+		m.log.Warn("health monitor: state update failed", "service", s.Name, "err", err)
+		return
+	}
+
+	if from == to {
+		return
+	}
+	m.log.Info("health.transition", "service", s.Name, "from", from, "to", to, "err", cause)
+	m.emit(ServiceEvent{
+		Node:        m.node,
+		Service:     s.Name,
+		ContainerID: s.ContainerID,
+		From:        from,
+		To:          to,
+		Err:         cause,
+		Time:        time.Now().UTC(),
+	})
+}
+
+// observeLiveness feeds a liveness result to the service's tracker and
+// restarts the container once the failure threshold is crossed.
+func (m *Monitor) observeLiveness(ctx context.Context, spec v1.ServiceSpec, s v1.ServiceState, probeErr error) {
+	tr, ok := m.liveness[s.ContainerID]
+	if !ok {
+		tr = NewLivenessTracker(spec)
+		m.liveness[s.ContainerID] = tr
+	}
+	if !tr.Observe(probeErr) {
+		return
+	}
+
+	m.log.Warn("health.liveness_failed", "service", s.Name, "id", shortID(s.ContainerID), "err", probeErr)
+	if err := m.restarter.RestartContainer(ctx, s.ContainerID); err != nil {
+		m.log.Error("health monitor: restart failed", "service", s.Name, "err", err)
+		return
+	}
+	m.emit(ServiceEvent{
+		Node:        m.node,
+		Service:     s.Name,
+		ContainerID: s.ContainerID,
+		From:        s.Status,
+		To:          v1.StatusUnknown,
+		Restarted:   true,
+		Err:         probeErr,
+		Time:        time.Now().UTC(),
+	})
+}
+
+// emit sends a ServiceEvent without blocking (drops if channel full).
+func (m *Monitor) emit(ev ServiceEvent) {
+	select {
+	case m.events <- ev:
+	default:
+		m.log.Debug("health event channel full, dropping event", "service", ev.Service)
+	}
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+func TestMonitorPersistsTransitions(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open state: %v", err)
+	}
+	defer db.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	spec := v1.ServiceSpec{Name: "db", HealthCheck: &v1.HealthCheckSpec{
+		Type:    "tcp",
+		Port:    port,
+		Timeout: time.Second,
+	}}
+	if err := db.PutServiceState(v1.ServiceState{
+		Name: "db", ContainerID: "abc123", Node: "local", Status: v1.StatusUnknown,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	log, _ := logger.Init("error", "text", "", "", false)
+	m := NewMonitor(NewChecker(log), db, "local", []v1.ServiceSpec{spec}, log)
+
+	m.sweep(context.Background())
+	ev := <-m.Events()
+	if ev.From != v1.StatusUnknown || ev.To != v1.StatusHealthy {
+		t.Fatalf("event = %+v", ev)
+	}
+	if s, _ := db.GetServiceState("local", "db"); s.Status != v1.StatusHealthy || !s.Ready {
+		t.Fatalf("state = %+v", s)
+	}
+
+	ln.Close()
+	m.due = map[string]time.Time{} // force the next probe
+	m.sweep(context.Background())
+	ev = <-m.Events()
+	if ev.To != v1.StatusUnhealthy || ev.Err == nil {
+		t.Fatalf("event = %+v", ev)
+	}
+	if s, _ := db.GetServiceState("local", "db"); s.Status != v1.StatusUnhealthy || s.Ready {
+		t.Fatalf("state = %+v", s)
+	}
+}
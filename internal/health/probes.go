@@ -0,0 +1,222 @@
+// Package health: startup, readiness, and liveness probe resolution.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/telemetry"
+)
+
+// ProbeKind selects which purpose-specific probe to run.
+type ProbeKind string
+
+const (
+	ProbeStartup   ProbeKind = "startup"
+	ProbeReadiness ProbeKind = "readiness"
+	ProbeLiveness  ProbeKind = "liveness"
+)
+
+// DefaultLivenessThreshold is the number of consecutive liveness failures
+// before a container is considered dead.
+const DefaultLivenessThreshold = 3
+
+// ProbeConfig is the fully-resolved configuration for one probe kind.
+type ProbeConfig struct {
+	Check            v1.HealthCheckSpec
+	InitialDelay     time.Duration
+	Interval         time.Duration
+	FailureThreshold int
+	SuccessThreshold int
+}
+
+// Resolve returns the effective ProbeConfig for kind, or nil when the service
+// has no health check (or no startup probe, which is opt-in).
+func Resolve(hc *v1.HealthCheckSpec, kind ProbeKind) *ProbeConfig {
+	if hc == nil {
+		return nil
+	}
+
+	var override *v1.ProbeSpec
+	switch kind {
+	case ProbeStartup:
+		override = hc.Startup
+		if override == nil {
+			return nil
+		}
+	case ProbeReadiness:
+		override = hc.Readiness
+	case ProbeLiveness:
+		override = hc.Liveness
+	}
+
+	pc := &ProbeConfig{
+		Check:            *hc,
+		Interval:         hc.Interval,
+		FailureThreshold: hc.Retries + 1,
+		SuccessThreshold: 1,
+	}
+	if hc.Retries == 0 {
+		pc.FailureThreshold = DefaultRetries + 1
+	}
+	if kind == ProbeLiveness {
+		pc.FailureThreshold = DefaultLivenessThreshold
+	}
+
+	if o := override; o != nil {
+		if o.Type != "" {
+			pc.Check.Type = o.Type
+		}
+		if o.URL != "" {
+			pc.Check.URL = o.URL
+		}
+		if o.Port != 0 {
+			pc.Check.Port = o.Port
+		}
+		if o.Command != "" {
+			pc.Check.Command = o.Command
+		}
+		if o.Timeout != 0 {
+			pc.Check.Timeout = o.Timeout
+		}
+		if o.Interval != 0 {
+			pc.Interval = o.Interval
+		}
+		if o.FailureThreshold > 0 {
+			pc.FailureThreshold = o.FailureThreshold
+		}
+		if o.SuccessThreshold > 0 {
+			pc.SuccessThreshold = o.SuccessThreshold
+		}
+		pc.InitialDelay = o.InitialDelay
+	}
+
+	if pc.Interval == 0 {
+		pc.Interval = DefaultInterval
+	}
+	return pc
+}
+
+// WaitProbe polls the probe of the given kind until SuccessThreshold consecutive
+// passes, failing after FailureThreshold consecutive failures or when ctx ends.
+// A service without that probe configured passes immediately.
+func (c *Checker) WaitProbe(ctx context.Context, spec v1.ServiceSpec, containerID string, kind ProbeKind) (err error) {
+	ctx, span := telemetry.Start(ctx, "orbit.healthcheck.wait",
+		attribute.String("orbit.service", spec.Name),
+		attribute.String("orbit.container", containerID),
+		attribute.String("orbit.probe", string(kind)))
+	defer func() { telemetry.End(span, err) }()
+	spec = c.withNative(ctx, spec, containerID)
+	pc := Resolve(spec.HealthCheck, kind)
+	if pc == nil {
+		return nil
+	}
+
+	if pc.InitialDelay > 0 {
+		if err := sleepCtx(ctx, pc.InitialDelay); err != nil {
+			return err
+		}
+	}
+
+	successes, failures := 0, 0
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		lastErr = c.checkSpec(ctx, &pc.Check, containerID)
+		if lastErr == nil {
+			successes++
+			failures = 0
+			if successes >= pc.SuccessThreshold {
+				c.log.Info("probe passed", "service", spec.Name, "probe", kind, "attempt", attempt)
+				return nil
+			}
+		} else if errors.Is(lastErr, ErrStarting) {
+			// Docker's own start period — bounded by ctx, not the failure threshold
+			successes = 0
+		} else {
+			failures++
+			successes = 0
+			c.log.Debug("probe attempt failed",
+				"service", spec.Name,
+				"probe", kind,
+				"failures", failures,
+				"of", pc.FailureThreshold,
+				"err", lastErr,
+			)
+			if failures >= pc.FailureThreshold {
+				return fmt.Errorf("%s probe failed after %d consecutive attempts: %w", kind, failures, lastErr)
+			}
+		}
+
+		if err := sleepCtx(ctx, pc.Interval); err != nil {
+			return err
+		}
+	}
+}
+
+// WaitReady gates deploy cut-over: it waits for the startup probe (if any) and
+// then for the readiness probe to pass.
+func (c *Checker) WaitReady(ctx context.Context, spec v1.ServiceSpec, containerID string) error {
+	if err := c.WaitProbe(ctx, spec, containerID, ProbeStartup); err != nil {
+		return err
+	}
+	return c.WaitProbe(ctx, spec, containerID, ProbeReadiness)
+}
+
+// CheckProbe performs a single attempt of the given probe kind.
+func (c *Checker) CheckProbe(ctx context.Context, spec v1.ServiceSpec, containerID string, kind ProbeKind) (err error) {
+	ctx, span := startCheckSpan(ctx, spec, containerID, kind)
+	defer func() { telemetry.End(span, err) }()
+	spec = c.withNative(ctx, spec, containerID)
+	pc := Resolve(spec.HealthCheck, kind)
+	if pc == nil {
+		return nil
+	}
+	return c.checkSpec(ctx, &pc.Check, containerID)
+}
+
+// LivenessTracker counts consecutive liveness failures for a container and
+// reports when the restart threshold has been crossed.
+type LivenessTracker struct {
+	threshold int
+	failures  int
+}
+
+// NewLivenessTracker returns a tracker for spec's liveness probe, or nil if
+// the service has no health check.
+func NewLivenessTracker(spec v1.ServiceSpec) *LivenessTracker {
+	pc := Resolve(spec.HealthCheck, ProbeLiveness)
+	if pc == nil {
+		return nil
+	}
+	return &LivenessTracker{threshold: pc.FailureThreshold}
+}
+
+// Observe records a probe result and returns true when the container should be restarted.
+func (t *LivenessTracker) Observe(err error) bool {
+	if err == nil {
+		t.failures = 0
+		return false
+	}
+	t.failures++
+	if t.failures >= t.threshold {
+		t.failures = 0
+		return true
+	}
+	return false
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
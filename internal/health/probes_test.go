@@ -0,0 +1,57 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+func TestResolveProbeOverrides(t *testing.T) {
+	hc := &v1.HealthCheckSpec{
+		Type:     "http",
+		URL:      "http://localhost:8080/health",
+		Interval: 2 * time.Second,
+		Retries:  4,
+		Readiness: &v1.ProbeSpec{
+			URL:              "http://localhost:8080/ready",
+			SuccessThreshold: 2,
+		},
+	}
+
+	if pc := Resolve(hc, ProbeStartup); pc != nil {
+		t.Fatalf("startup probe should be opt-in, got %+v", pc)
+	}
+
+	ready := Resolve(hc, ProbeReadiness)
+	if ready.Check.URL != "http://localhost:8080/ready" || ready.Check.Type != "http" {
+		t.Errorf("readiness check = %+v", ready.Check)
+	}
+	if ready.FailureThreshold != 5 || ready.SuccessThreshold != 2 || ready.Interval != 2*time.Second {
+		t.Errorf("readiness thresholds = %+v", ready)
+	}
+
+	live := Resolve(hc, ProbeLiveness)
+	if live.Check.URL != hc.URL || live.FailureThreshold != DefaultLivenessThreshold {
+		t.Errorf("liveness = %+v", live)
+	}
+}
+
+func TestLivenessTracker(t *testing.T) {
+	tr := NewLivenessTracker(v1.ServiceSpec{HealthCheck: &v1.HealthCheckSpec{
+		Type:     "tcp",
+		Liveness: &v1.ProbeSpec{FailureThreshold: 2},
+	}})
+	fail := errors.New("down")
+
+	if tr.Observe(fail) {
+		t.Fatal("restart after first failure")
+	}
+	if tr.Observe(nil) || tr.Observe(fail) {
+		t.Fatal("success should reset the failure count")
+	}
+	if !tr.Observe(fail) {
+		t.Fatal("expected restart after threshold")
+	}
+}
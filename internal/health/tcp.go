@@ -1,4 +1,4 @@
-// Package health: TCP and command probe implementations.
+// Package health: TCP, command, and container exec probe implementations.
 package health
 
 import (
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"os/exec"
+	"strings"
 	"time"
 )
 
@@ -49,3 +50,32 @@ func CheckCmd(ctx context.Context, command string, timeout time.Duration) error
 	}
 	return nil
 }
+
+// CheckExec runs command inside the container via execer and returns nil if it exits 0.
+// The command is run through /bin/sh -c, matching Docker's HEALTHCHECK CMD-SHELL form.
+func CheckExec(ctx context.Context, execer Execer, containerID, command string, timeout time.Duration) error {
+	if command == "" {
+		return fmt.Errorf("exec health check: command is required")
+	}
+	if execer == nil {
+		return fmt.Errorf("exec health check: no container runtime available")
+	}
+	if containerID == "" {
+		return fmt.Errorf("exec health check: container id is required")
+	}
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	code, out, err := execer.Exec(ctx, containerID, []string{"/bin/sh", "-c", command})
+	if err != nil {
+		return fmt.Errorf("exec probe %q: %w", command, err)
+	}
+	if code != 0 {
+		return fmt.Errorf("exec probe %q exited %d (output: %s)", command, code, strings.TrimSpace(out))
+	}
+	return nil
+}
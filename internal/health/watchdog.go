@@ -0,0 +1,194 @@
+// Package health: restart watchdog with crash-loop detection.
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// CrashLoopPolicy decides when a container is restarting too often.
+type CrashLoopPolicy struct {
+	MaxRestarts int           // exits within Window that mark a crash loop
+	Window      time.Duration // sliding window over which exits are counted
+}
+
+// DefaultCrashLoopPolicy applies when no watchdog: section is configured.
+var DefaultCrashLoopPolicy = CrashLoopPolicy{MaxRestarts: 5, Window: 10 * time.Minute}
+
+// watchdogRetry is how long the monitor waits before resubscribing after
+// the event stream drops.
+const watchdogRetry = 5 * time.Second
+
+// EventSource streams container lifecycle events. It is satisfied by
+// *orchestrator.Client.
+type EventSource interface {
+	ContainerEvents(ctx context.Context) (<-chan v1.ContainerEvent, <-chan error)
+}
+
+// Stopper stops a container without removing it. It is satisfied by
+// *orchestrator.Client.
+type Stopper interface {
+	StopContainer(ctx context.Context, idOrName string, remove bool) error
+}
+
+// crashTracker counts unexpected exits per container. Exits that follow a
+// kill (docker stop, a deploy cut-over, orbit down) are deliberate and ignored.
+type crashTracker struct {
+	policy CrashLoopPolicy
+	exits  map[string][]time.Time // container ID → recent unexpected exits
+	killed map[string]time.Time   // container ID → last kill
+	oom    map[string]bool        // container ID → OOM-killed, until its exit
+}
+
+func newCrashTracker(p CrashLoopPolicy) *crashTracker {
+	if p.MaxRestarts <= 0 {
+		p.MaxRestarts = DefaultCrashLoopPolicy.MaxRestarts
+	}
+	if p.Window <= 0 {
+		p.Window = DefaultCrashLoopPolicy.Window
+	}
+	return &crashTracker{policy: p, exits: map[string][]time.Time{}, killed: map[string]time.Time{}, oom: map[string]bool{}}
+}
+
+// observe records ev. crashed reports an unexpected exit; looping reports
+// that it pushed the container over the policy's threshold.
+func (t *crashTracker) observe(ev v1.ContainerEvent) (crashed, looping bool) {
+	switch ev.Action {
+	case "kill":
+		t.killed[ev.ID] = ev.Time
+		return false, false
+	case "oom": // precedes the exit it causes
+		t.oom[ev.ID] = true
+		return false, false
+	case "destroy":
+		delete(t.exits, ev.ID)
+		delete(t.killed, ev.ID)
+		delete(t.oom, ev.ID)
+		return false, false
+	case "die":
+	default:
+		return false, false
+	}
+
+	if at, ok := t.killed[ev.ID]; ok {
+		delete(t.killed, ev.ID)
+		if ev.Time.Sub(at) < time.Minute {
+			return false, false
+		}
+	}
+	if ev.Task {
+		return false, false
+	}
+
+	cutoff := ev.Time.Add(-t.policy.Window)
+	recent := t.exits[ev.ID][:0]
+	for _, at := range t.exits[ev.ID] {
+		if at.After(cutoff) {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, ev.Time)
+	t.exits[ev.ID] = recent
+	return true, len(recent) == t.policy.MaxRestarts
+}
+
+// takeOOM reports whether the container's latest exit was an OOM kill.
+func (t *crashTracker) takeOOM(id string) bool {
+	oom := t.oom[id]
+	delete(t.oom, id)
+	return oom
+}
+
+// WithWatchdog makes Run also follow the runtime's event stream: every
+// unexpected container exit increments the service's RestartCount, records
+// its exit code and any OOM kill, and degrades a healthy service; and a
+// container that exits policy.MaxRestarts times within policy.Window is
+// stopped so its restart policy cannot keep cycling it, and the service is
+// flagged CrashLoop until its next deploy.
+func (m *Monitor) WithWatchdog(src EventSource, stop Stopper, policy CrashLoopPolicy) *Monitor {
+	m.eventSrc = src
+	m.stopper = stop
+	m.crashes = newCrashTracker(policy)
+	return m
+}
+
+// subscribe opens the container event stream; it returns nil channels when
+// the watchdog is disabled, which Run's select then never reads.
+func (m *Monitor) subscribe(ctx context.Context) (<-chan v1.ContainerEvent, <-chan error) {
+	if m.eventSrc == nil {
+		return nil, nil
+	}
+	return m.eventSrc.ContainerEvents(ctx)
+}
+
+// handleEvent dispatches one container event from Run's loop.
+func (m *Monitor) handleEvent(ctx context.Context, ev v1.ContainerEvent) {
+	if ev.Action == "health_status" {
+		m.onHealthStatus(ev)
+		return
+	}
+	if crashed, looping := m.crashes.observe(ev); crashed {
+		m.onCrash(ctx, ev, looping)
+	}
+}
+
+// onCrash persists the restart count and exit and, once looping, stops the
+// container.
+func (m *Monitor) onCrash(ctx context.Context, ev v1.ContainerEvent, looping bool) {
+	oom := m.crashes.takeOOM(ev.ID)
+	m.log.Warn("watchdog.exit", "service", ev.Service, "id", shortID(ev.ID), "exit_code", ev.ExitCode, "oom", oom)
+
+	cur, err := m.state.GetServiceState(m.node, ev.Service)
+	if err != nil || cur == nil {
+		return
+	}
+	from := cur.Status
+	cur.RestartCount++
+	cur.ExitCode, cur.OOMKilled, cur.LastExit = ev.ExitCode, oom, ev.Time
+	switch {
+	case looping:
+		cur.CrashLoop = true
+		cur.Status = v1.StatusUnhealthy
+		cur.Ready = false
+	case from == v1.StatusHealthy:
+		cur.Status = v1.StatusDegraded
+	}
+	if err := m.state.PutServiceState(*cur); err != nil {
+		m.log.Warn("watchdog: state update failed", "service", ev.Service, "err", err)
+	}
+	if !looping {
+		if cur.Status != from {
+			m.emit(ServiceEvent{
+				Node:        m.node,
+				Service:     ev.Service,
+				ContainerID: ev.ID,
+				From:        from,
+				To:          cur.Status,
+				Err:         restartErr(*cur),
+				Time:        ev.Time,
+			})
+		}
+		return
+	}
+
+	policy := m.crashes.policy
+	m.log.Error("watchdog.crash_loop", "service", ev.Service, "id", shortID(ev.ID),
+		"restarts", policy.MaxRestarts, "window", policy.Window)
+	if err := m.stopper.StopContainer(ctx, ev.ID, false); err != nil {
+		m.log.Error("watchdog: stop failed", "service", ev.Service, "err", err)
+	}
+	m.emit(ServiceEvent{
+		Node:        m.node,
+		Service:     ev.Service,
+		ContainerID: ev.ID,
+		From:        from,
+		To:          v1.StatusUnhealthy,
+		CrashLoop:   true,
+		Err: fmt.Errorf("exited %d times in %s (last exit code %d); container stopped",
+			policy.MaxRestarts, policy.Window, ev.ExitCode),
+		Time: ev.Time,
+	})
+}
@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+func TestCrashTrackerIgnoresDeliberateExits(t *testing.T) {
+	tr := newCrashTracker(CrashLoopPolicy{MaxRestarts: 2, Window: time.Minute})
+	now := time.Now()
+
+	tr.observe(v1.ContainerEvent{ID: "a", Action: "kill", Time: now})
+	if crashed, _ := tr.observe(v1.ContainerEvent{ID: "a", Action: "die", Time: now.Add(time.Second)}); crashed {
+		t.Fatal("die after kill counted as a crash")
+	}
+	if crashed, _ := tr.observe(v1.ContainerEvent{ID: "t", Action: "die", Task: true, Time: now}); crashed {
+		t.Fatal("task exit counted as a crash")
+	}
+	if crashed, _ := tr.observe(v1.ContainerEvent{ID: "a", Action: "start", Time: now}); crashed {
+		t.Fatal("start counted as a crash")
+	}
+}
+
+func TestCrashTrackerWindow(t *testing.T) {
+	tr := newCrashTracker(CrashLoopPolicy{MaxRestarts: 3, Window: time.Minute})
+	now := time.Now()
+	die := func(at time.Duration) bool {
+		crashed, looping := tr.observe(v1.ContainerEvent{ID: "a", Action: "die", Time: now.Add(at)})
+		if !crashed {
+			t.Fatalf("die at %s not counted", at)
+		}
+		return looping
+	}
+
+	if die(0) || die(30*time.Second) {
+		t.Fatal("looping before threshold")
+	}
+	// The first exit has left the window, so this is only the second.
+	if die(70 * time.Second) {
+		t.Fatal("exit outside the window was counted")
+	}
+	if !die(80 * time.Second) {
+		t.Fatal("third exit within the window not flagged")
+	}
+
+	tr.observe(v1.ContainerEvent{ID: "a", Action: "destroy", Time: now})
+	if len(tr.exits) != 0 || len(tr.killed) != 0 {
+		t.Fatalf("destroy left entries: %v %v", tr.exits, tr.killed)
+	}
+}
+
+type fakeEvents chan v1.ContainerEvent
+
+func (f fakeEvents) ContainerEvents(context.Context) (<-chan v1.ContainerEvent, <-chan error) {
+	return f, nil
+}
+
+type fakeStopper struct{ stopped []string }
+
+func (f *fakeStopper) StopContainer(_ context.Context, id string, remove bool) error {
+	f.stopped = append(f.stopped, id)
+	return nil
+}
+
+func TestWatchdogStopsCrashLoop(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open state: %v", err)
+	}
+	defer db.Close()
+	if err := db.PutServiceState(v1.ServiceState{
+		Name: "api", ContainerID: "abc123", Node: "local", Status: v1.StatusHealthy, Ready: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	log, _ := logger.Init("error", "text", "", "", false)
+	stop := &fakeStopper{}
+	m := NewMonitor(NewChecker(log), db, "local", nil, log).
+		WithWatchdog(make(fakeEvents), stop, CrashLoopPolicy{MaxRestarts: 2, Window: time.Minute})
+
+	now := time.Now()
+	m.handleEvent(context.Background(), v1.ContainerEvent{ID: "abc123", Service: "api", Action: "oom", Time: now})
+	m.handleEvent(context.Background(), v1.ContainerEvent{ID: "abc123", Service: "api", Action: "die", ExitCode: 137, Time: now})
+
+	// The first exit degrades the service even though its restart policy
+	// brings it straight back.
+	ev := <-m.Events()
+	if ev.CrashLoop || ev.From != v1.StatusHealthy || ev.To != v1.StatusDegraded || ev.Err == nil {
+		t.Fatalf("first exit: event = %+v", ev)
+	}
+	if s, _ := db.GetServiceState("local", "api"); s.RestartCount != 1 || s.ExitCode != 137 || !s.OOMKilled || !s.LastExit.Equal(now) {
+		t.Fatalf("first exit: state = %+v", s)
+	}
+
+	m.handleEvent(context.Background(), v1.ContainerEvent{ID: "abc123", Service: "api", Action: "die", ExitCode: 1, Time: now.Add(time.Second)})
+	ev = <-m.Events()
+	if !ev.CrashLoop || ev.To != v1.StatusUnhealthy || ev.Err == nil {
+		t.Fatalf("event = %+v", ev)
+	}
+	if len(stop.stopped) != 1 || stop.stopped[0] != "abc123" {
+		t.Fatalf("stopped = %v", stop.stopped)
+	}
+	s, _ := db.GetServiceState("local", "api")
+	if s.RestartCount != 2 || !s.CrashLoop || s.Ready || s.ExitCode != 1 || s.OOMKilled {
+		t.Fatalf("state = %+v", s)
+	}
+}
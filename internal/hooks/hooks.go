@@ -0,0 +1,246 @@
+// Package hooks runs the shell commands of services' hooks: blocks in
+// orbit.yaml — on the machine running orbit, on the service's node over SSH,
+// or inside its container — with a timeout and a failure policy each.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/sshutil"
+)
+
+// DefaultTimeout bounds a hook command with no timeout of its own.
+const DefaultTimeout = 5 * time.Minute
+
+// LocalNode is the node name of the machine running orbit; its "node"
+// commands run locally.
+const LocalNode = "local"
+
+// Execer runs a command inside a container, as orchestrator.Client does.
+type Execer interface {
+	Exec(ctx context.Context, containerID string, cmd []string) (exitCode int, output string, err error)
+}
+
+// NodeExec runs a shell command on the named node and returns its exit
+// status, as remote.Pool.Exec does for a registered node.
+type NodeExec func(ctx context.Context, node, cmd string, stdout, stderr io.Writer) (int, error)
+
+// Runner runs hook commands. It implements orchestrator.CommandHooks.
+type Runner struct {
+	docker Execer
+	nodes  NodeExec
+	out    io.Writer
+	log    *logger.Logger
+}
+
+// New returns a Runner that execs "container" commands with docker. Their
+// output is logged until WithOutput is given somewhere to print it.
+func New(docker Execer, log *logger.Logger) *Runner {
+	return &Runner{docker: docker, log: log}
+}
+
+// WithNodes runs "node" commands on remote nodes with fn.
+func (r *Runner) WithNodes(fn NodeExec) *Runner {
+	r.nodes = fn
+	return r
+}
+
+// WithOutput prints commands' output to w, each line under a
+// "[service hook]" prefix.
+func (r *Runner) WithOutput(w io.Writer) *Runner {
+	r.out = w
+	return r
+}
+
+// Run runs the service's commands for hook in order. A failing command
+// stops the rest and is returned as an ErrServiceHook error, unless its
+// on_failure is ignore. containerID is where "container" commands run.
+func (r *Runner) Run(ctx context.Context, hook string, hctx v1.HookContext, containerID string) error {
+	spec, node := hctx.Service, LocalNode
+	if hctx.Node != nil && hctx.Node.Name != "" {
+		node = hctx.Node.Name
+	}
+	for _, c := range spec.Hooks.Commands(hook) {
+		r.log.Info("hook.run", "service", spec.Name, "hook", hook, "where", where(c), "command", c.Command)
+		err := r.run(ctx, hook, hctx, node, containerID, c)
+		if err == nil {
+			continue
+		}
+		if c.OnFailure == v1.HookFailureIgnore {
+			r.log.Warn("hook.failed", "service", spec.Name, "hook", hook, "command", c.Command, "err", err)
+			continue
+		}
+		return errs.Newf(errs.ErrServiceHook, "hooks."+hook, "%s hook of %s failed: %s: %v", hook, spec.Name, c.Command, err).
+			WithNode(node)
+	}
+	return nil
+}
+
+func (r *Runner) run(ctx context.Context, hook string, hctx v1.HookContext, node, containerID string, c v1.HookCommand) error {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	w := r.output(hctx.Service.Name, hook)
+	defer w.Flush()
+	env := Environment(hook, hctx, node)
+
+	var err error
+	switch where(c) {
+	case v1.HookWhereNode:
+		if node != LocalNode {
+			err = r.runNode(ctx, node, exports(env)+c.Command, w)
+			break
+		}
+		fallthrough
+	case v1.HookWhereLocal:
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", c.Command)
+		cmd.Env = append(os.Environ(), env...)
+		cmd.Stdout, cmd.Stderr = w, w
+		killGroup(cmd)
+		cmd.WaitDelay = 5 * time.Second // for children still holding the output open
+		err = cmd.Run()
+	case v1.HookWhereContainer:
+		err = r.runContainer(ctx, containerID, exports(env)+c.Command, w)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
+
+func (r *Runner) runNode(ctx context.Context, node, script string, w io.Writer) error {
+	if r.nodes == nil {
+		return fmt.Errorf("cannot reach node %s", node)
+	}
+	code, err := r.nodes(ctx, node, "sh -c "+sshutil.Quote(script), w, w)
+	if err != nil {
+		return err
+	}
+	return exitErr(code)
+}
+
+func (r *Runner) runContainer(ctx context.Context, containerID, script string, w io.Writer) error {
+	if containerID == "" {
+		return errors.New("the service has no running container")
+	}
+	if r.docker == nil {
+		return errors.New("no container runtime")
+	}
+	code, out, err := r.docker.Exec(ctx, containerID, []string{"sh", "-c", script})
+	io.WriteString(w, out)
+	if err != nil {
+		return err
+	}
+	return exitErr(code)
+}
+
+// output is where a hook's command output goes: r.out under a prefix, or
+// the log.
+func (r *Runner) output(service, hook string) *lineWriter {
+	if r.out != nil {
+		prefix := fmt.Sprintf("[%s %s] ", service, hook)
+		return &lineWriter{line: func(s string) { fmt.Fprintln(r.out, prefix+s) }}
+	}
+	return &lineWriter{line: func(s string) {
+		r.log.Info("hook.output", "service", service, "hook", hook, "line", s)
+	}}
+}
+
+// Environment is what a hook command finds in its environment about the
+// operation it runs for: ORBIT_HOOK, ORBIT_SERVICE, ORBIT_NODE,
+// ORBIT_ACTION (deploy, rollback, up or down), ORBIT_IMAGE and, when there
+// was one, ORBIT_PREVIOUS_IMAGE.
+func Environment(hook string, hctx v1.HookContext, node string) []string {
+	vars := map[string]string{
+		"ORBIT_HOOK":    hook,
+		"ORBIT_SERVICE": hctx.Service.Name,
+		"ORBIT_NODE":    node,
+		"ORBIT_ACTION":  hctx.Metadata["action"],
+		"ORBIT_IMAGE":   hctx.ImageTo,
+	}
+	if vars["ORBIT_IMAGE"] == "" {
+		vars["ORBIT_IMAGE"] = hctx.Service.Image
+	}
+	if hctx.ImageFrom != "" {
+		vars["ORBIT_PREVIOUS_IMAGE"] = hctx.ImageFrom
+	}
+	env := make([]string, 0, len(vars))
+	for k, v := range vars {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// exports sets env in a shell script, for commands run where os/exec's
+// environment does not reach.
+func exports(env []string) string {
+	var sb strings.Builder
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(&sb, "export %s=%s; ", k, sshutil.Quote(v))
+	}
+	return sb.String()
+}
+
+func where(c v1.HookCommand) string {
+	if c.Where == "" {
+		return v1.HookWhereLocal
+	}
+	return c.Where
+}
+
+func exitErr(code int) error {
+	if code != 0 {
+		return fmt.Errorf("exit status %d", code)
+	}
+	return nil
+}
+
+// lineWriter hands each complete line written to it to line.
+type lineWriter struct {
+	mu   sync.Mutex
+	buf  []byte
+	line func(string)
+}
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		w.line(strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// Flush hands on a trailing partial line.
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.line(string(w.buf))
+		w.buf = nil
+	}
+}
@@ -0,0 +1,113 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// fakeExecer records the commands exec'd in containers.
+type fakeExecer struct {
+	id   string
+	cmd  []string
+	code int
+}
+
+func (f *fakeExecer) Exec(_ context.Context, id string, cmd []string) (int, string, error) {
+	f.id, f.cmd = id, cmd
+	return f.code, "migrated\n", nil
+}
+
+func hookContext(hooks *v1.HooksSpec) v1.HookContext {
+	return v1.HookContext{
+		Service:   &v1.ServiceSpec{Name: "api", Image: "api:2", Hooks: hooks},
+		Node:      &v1.NodeSpec{Name: "n1"},
+		Metadata:  map[string]string{"action": "deploy"},
+		ImageFrom: "api:1",
+		ImageTo:   "api:2",
+	}
+}
+
+func TestRunLocal(t *testing.T) {
+	log, _ := logger.Init("error", "text", "", "", false)
+	var out bytes.Buffer
+	r := New(nil, log).WithOutput(&out)
+
+	hctx := hookContext(&v1.HooksSpec{PreDeploy: []v1.HookCommand{
+		{Command: `echo "$ORBIT_SERVICE $ORBIT_ACTION $ORBIT_PREVIOUS_IMAGE -> $ORBIT_IMAGE on $ORBIT_NODE"`},
+		{Command: "exit 3", OnFailure: v1.HookFailureIgnore},
+		{Command: "printf partial"},
+	}})
+	if err := r.Run(context.Background(), v1.HookCmdPreDeploy, hctx, ""); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := "[api pre_deploy] api deploy api:1 -> api:2 on n1\n[api pre_deploy] partial\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	hctx.Service.Hooks = &v1.HooksSpec{PostDeploy: []v1.HookCommand{
+		{Command: "exit 2"},
+		{Command: "echo never"},
+	}}
+	out.Reset()
+	err := r.Run(context.Background(), v1.HookCmdPostDeploy, hctx, "")
+	if !errs.IsCode(err, errs.ErrServiceHook) || !strings.Contains(err.Error(), "exit status 2") {
+		t.Fatalf("failing command: err = %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("commands after a failure ran: %q", out.String())
+	}
+
+	hctx.Service.Hooks = &v1.HooksSpec{PreStop: []v1.HookCommand{{Command: "sleep 5", Timeout: 50 * time.Millisecond}}}
+	start := time.Now()
+	err = r.Run(context.Background(), v1.HookCmdPreStop, hctx, "")
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Fatalf("slow command: err = %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("timeout took %s", time.Since(start))
+	}
+}
+
+func TestRunContainerAndNode(t *testing.T) {
+	log, _ := logger.Init("error", "text", "", "", false)
+	docker := &fakeExecer{}
+	var node, script string
+	r := New(docker, log).WithNodes(func(_ context.Context, name, cmd string, stdout, _ io.Writer) (int, error) {
+		node, script = name, cmd
+		io.WriteString(stdout, "purged\n")
+		return 0, nil
+	})
+
+	hctx := hookContext(&v1.HooksSpec{PostDeploy: []v1.HookCommand{
+		{Command: "./migrate up", Where: v1.HookWhereContainer},
+		{Command: "purge-cdn", Where: v1.HookWhereNode},
+	}})
+	if err := r.Run(context.Background(), v1.HookCmdPostDeploy, hctx, "c0ffee"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if docker.id != "c0ffee" || len(docker.cmd) != 3 || !strings.HasSuffix(docker.cmd[2], "./migrate up") ||
+		!strings.Contains(docker.cmd[2], "export ORBIT_SERVICE='api'; ") {
+		t.Errorf("container exec = %s %q", docker.id, docker.cmd)
+	}
+	if node != "n1" || !strings.HasPrefix(script, "sh -c ") || !strings.Contains(script, "purge-cdn") {
+		t.Errorf("node exec = %s %q", node, script)
+	}
+
+	docker.code = 1
+	err := r.Run(context.Background(), v1.HookCmdPostDeploy, hctx, "c0ffee")
+	if !errs.IsCode(err, errs.ErrServiceHook) {
+		t.Errorf("failing container command: err = %v", err)
+	}
+	if err := r.Run(context.Background(), v1.HookCmdPostDeploy, hctx, ""); err == nil {
+		t.Error("container command ran without a container")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package hooks
+
+import "os/exec"
+
+// killGroup leaves cmd as it is: on timeout only the shell is killed, and
+// WaitDelay bounds the wait for its children.
+func killGroup(cmd *exec.Cmd) {}
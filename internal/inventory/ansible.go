@@ -0,0 +1,442 @@
+// Package inventory: nodes from Ansible inventories, INI or YAML.
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// Ansible reads nodes from an Ansible inventory. YAML is expected for
+// .yml, .yaml and .json files, the INI format otherwise.
+//
+// Every host becomes a node named after its inventory hostname, in the
+// groups it belongs to directly or through :children (except all and
+// ungrouped). ansible_host, ansible_user, ansible_port and
+// ansible_ssh_private_key_file — set on the host, its groups or all — give
+// its address, user, port and key. Hosts with ansible_connection=local are
+// skipped.
+func Ansible(name string, data []byte, opts Options) ([]v1.NodeSpec, error) {
+	var inv *ansibleInventory
+	var err error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yml", ".yaml", ".json":
+		inv, err = parseAnsibleYAML(data)
+	default:
+		inv, err = parseAnsibleINI(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := inv.nodes()
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("inventory lists no hosts")
+	}
+	return opts.finish(nodes)
+}
+
+// ansibleInventory is an inventory in either format.
+type ansibleInventory struct {
+	groups   map[string]*ansibleGroup
+	hosts    []string                     // in order of first appearance
+	hostVars map[string]map[string]string // variables set on the host line
+}
+
+type ansibleGroup struct {
+	hosts    []string
+	children []string
+	vars     map[string]string
+}
+
+func newAnsibleInventory() *ansibleInventory {
+	return &ansibleInventory{groups: map[string]*ansibleGroup{}, hostVars: map[string]map[string]string{}}
+}
+
+func (inv *ansibleInventory) group(name string) *ansibleGroup {
+	g, ok := inv.groups[name]
+	if !ok {
+		g = &ansibleGroup{vars: map[string]string{}}
+		inv.groups[name] = g
+	}
+	return g
+}
+
+// addHost puts host in group, merging vars into the host's own.
+func (inv *ansibleInventory) addHost(group, host string, vars map[string]string) {
+	hv, ok := inv.hostVars[host]
+	if !ok {
+		hv = map[string]string{}
+		inv.hostVars[host] = hv
+		inv.hosts = append(inv.hosts, host)
+	}
+	for k, v := range vars {
+		hv[k] = v
+	}
+	g := inv.group(group)
+	g.hosts = append(g.hosts, host)
+}
+
+// nodes resolves every host's groups and variables. As in Ansible, host
+// variables beat group variables, and a group's variables beat those of
+// the groups it is a child of.
+func (inv *ansibleInventory) nodes() ([]v1.NodeSpec, error) {
+	parents := map[string][]string{}
+	for name, g := range inv.groups {
+		for _, c := range g.children {
+			parents[c] = append(parents[c], name)
+		}
+	}
+	depth := map[string]int{}
+	var depthOf func(name string, seen map[string]bool) int
+	depthOf = func(name string, seen map[string]bool) int {
+		if d, ok := depth[name]; ok {
+			return d
+		}
+		if seen[name] {
+			return 0 // a cycle; Ansible rejects these, so do not recurse forever
+		}
+		seen[name] = true
+		d := 0
+		for _, p := range parents[name] {
+			d = max(d, depthOf(p, seen)+1)
+		}
+		depth[name] = d
+		return d
+	}
+
+	var nodes []v1.NodeSpec
+	for _, host := range inv.hosts {
+		// The host's groups: those listing it, and their ancestors.
+		member := map[string]bool{}
+		var walk func(string)
+		walk = func(g string) {
+			if member[g] {
+				return
+			}
+			member[g] = true
+			for _, p := range parents[g] {
+				walk(p)
+			}
+		}
+		for name, g := range inv.groups {
+			for _, h := range g.hosts {
+				if h == host {
+					walk(name)
+				}
+			}
+		}
+		groups := make([]string, 0, len(member))
+		for g := range member {
+			groups = append(groups, g)
+		}
+		sort.Slice(groups, func(i, j int) bool {
+			di, dj := depthOf(groups[i], map[string]bool{}), depthOf(groups[j], map[string]bool{})
+			if di != dj {
+				return di < dj
+			}
+			return groups[i] < groups[j]
+		})
+
+		vars := map[string]string{}
+		for k, v := range inv.group("all").vars {
+			vars[k] = v
+		}
+		for _, g := range groups {
+			for k, v := range inv.groups[g].vars {
+				vars[k] = v
+			}
+		}
+		for k, v := range inv.hostVars[host] {
+			vars[k] = v
+		}
+		if vars["ansible_connection"] == "local" {
+			continue
+		}
+
+		n := v1.NodeSpec{
+			Name: host,
+			Host: first(vars, "ansible_host", "ansible_ssh_host"),
+			User: first(vars, "ansible_user", "ansible_ssh_user"),
+			Key:  first(vars, "ansible_ssh_private_key_file", "ansible_private_key_file"),
+		}
+		if n.Host == "" {
+			n.Host = host
+		}
+		port, err := parsePort(first(vars, "ansible_port", "ansible_ssh_port"))
+		if err != nil {
+			return nil, fmt.Errorf("host %s: %w", host, err)
+		}
+		n.Port = port
+		for _, g := range groups {
+			if g != "all" && g != "ungrouped" {
+				n.Groups = append(n.Groups, g)
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func first(vars map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := vars[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseAnsibleINI reads the INI inventory format: [group] sections of host
+// lines with key=value variables, [group:vars] and [group:children].
+func parseAnsibleINI(data []byte) (*ansibleInventory, error) {
+	inv := newAnsibleInventory()
+	section, kind := "ungrouped", "hosts"
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("inventory line %d: unterminated section header", lineNo)
+			}
+			section, kind, _ = strings.Cut(line[1:len(line)-1], ":")
+			switch kind {
+			case "":
+				kind = "hosts"
+			case "vars", "children":
+			default:
+				return nil, fmt.Errorf("inventory line %d: unknown section type %q", lineNo, kind)
+			}
+			inv.group(section)
+			continue
+		}
+
+		fields, err := splitINIFields(line)
+		if err != nil {
+			return nil, fmt.Errorf("inventory line %d: %w", lineNo, err)
+		}
+		switch kind {
+		case "vars":
+			k, v, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("inventory line %d: want key=value in [%s:vars]", lineNo, section)
+			}
+			inv.group(section).vars[strings.TrimSpace(k)] = unquote(strings.TrimSpace(v))
+		case "children":
+			inv.group(section).children = append(inv.group(section).children, fields[0])
+			inv.group(fields[0])
+		default:
+			vars := map[string]string{}
+			for _, f := range fields[1:] {
+				k, v, ok := strings.Cut(f, "=")
+				if !ok {
+					return nil, fmt.Errorf("inventory line %d: want key=value, got %q", lineNo, f)
+				}
+				vars[k] = unquote(v)
+			}
+			hosts, err := expandHostPattern(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("inventory line %d: %w", lineNo, err)
+			}
+			for _, h := range hosts {
+				inv.addHost(section, h, vars)
+			}
+		}
+	}
+	return inv, sc.Err()
+}
+
+// splitINIFields splits a host line on whitespace, keeping quoted values
+// such as ansible_ssh_common_args="-o Foo=bar" whole.
+func splitINIFields(line string) ([]string, error) {
+	var fields []string
+	var cur strings.Builder
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			cur.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			cur.WriteRune(r)
+		case r == ' ' || r == '\t':
+			if cur.Len() > 0 {
+				fields = append(fields, cur.String())
+				cur.Reset()
+			}
+		case r == '#' && cur.Len() == 0:
+			return fields, nil // trailing comment
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if cur.Len() > 0 {
+		fields = append(fields, cur.String())
+	}
+	return fields, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// expandHostPattern expands Ansible host ranges: web[01:03].example.com is
+// web01, web02 and web03, db-[a:c] is db-a, db-b and db-c.
+func expandHostPattern(pattern string) ([]string, error) {
+	open := strings.IndexByte(pattern, '[')
+	if open < 0 {
+		return []string{pattern}, nil
+	}
+	end := strings.IndexByte(pattern[open:], ']')
+	if end < 0 {
+		return nil, fmt.Errorf("host pattern %q: unterminated range", pattern)
+	}
+	end += open
+	prefix, spec, suffix := pattern[:open], pattern[open+1:end], pattern[end+1:]
+	lo, hi, ok := strings.Cut(spec, ":")
+	if !ok || lo == "" || hi == "" {
+		return nil, fmt.Errorf("host pattern %q: want [start:end]", pattern)
+	}
+	step := 1
+	if h, s, ok := strings.Cut(hi, ":"); ok {
+		hi = h
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			step = n
+		}
+	}
+
+	var items []string
+	if a, errA := strconv.Atoi(lo); errA == nil {
+		b, err := strconv.Atoi(hi)
+		if err != nil || b < a {
+			return nil, fmt.Errorf("host pattern %q: bad range", pattern)
+		}
+		for i := a; i <= b; i += step {
+			s := strconv.Itoa(i)
+			if len(lo) > 1 && lo[0] == '0' { // zero-padded: [01:10]
+				s = fmt.Sprintf("%0*d", len(lo), i)
+			}
+			items = append(items, s)
+		}
+	} else if len(lo) == 1 && len(hi) == 1 && lo[0] <= hi[0] {
+		for c := lo[0]; c <= hi[0]; c += byte(step) {
+			items = append(items, string(c))
+			if int(c)+step > 255 {
+				break
+			}
+		}
+	} else {
+		return nil, fmt.Errorf("host pattern %q: bad range", pattern)
+	}
+
+	var out []string
+	for _, item := range items {
+		rest, err := expandHostPattern(suffix)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rest {
+			out = append(out, prefix+item+r)
+		}
+	}
+	return out, nil
+}
+
+// yamlGroup is a group in the YAML inventory format.
+type yamlGroup struct {
+	Hosts    map[string]map[string]any `yaml:"hosts"`
+	Vars     map[string]any            `yaml:"vars"`
+	Children map[string]*yamlGroup     `yaml:"children"`
+}
+
+// parseAnsibleYAML reads the YAML inventory format: nested groups, each
+// with hosts, vars and children.
+func parseAnsibleYAML(data []byte) (*ansibleInventory, error) {
+	var top map[string]*yamlGroup
+	if err := yaml.Unmarshal(data, &top); err != nil {
+		return nil, fmt.Errorf("parse inventory: %w", err)
+	}
+	inv := newAnsibleInventory()
+	var add func(name string, g *yamlGroup) error
+	add = func(name string, g *yamlGroup) error {
+		grp := inv.group(name)
+		if g == nil {
+			return nil
+		}
+		for k, v := range g.Vars {
+			grp.vars[k] = scalar(v)
+		}
+		hosts := make([]string, 0, len(g.Hosts))
+		for h := range g.Hosts {
+			hosts = append(hosts, h)
+		}
+		sort.Strings(hosts)
+		for _, pattern := range hosts {
+			vars := map[string]string{}
+			for k, v := range g.Hosts[pattern] {
+				vars[k] = scalar(v)
+			}
+			expanded, err := expandHostPattern(pattern)
+			if err != nil {
+				return err
+			}
+			for _, h := range expanded {
+				inv.addHost(name, h, vars)
+			}
+		}
+		children := make([]string, 0, len(g.Children))
+		for c := range g.Children {
+			children = append(children, c)
+		}
+		sort.Strings(children)
+		for _, c := range children {
+			grp.children = append(grp.children, c)
+			if err := add(c, g.Children[c]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	names := make([]string, 0, len(top))
+	for name := range top {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := add(name, top[name]); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+// scalar renders a YAML variable value as the string an INI inventory
+// would have held.
+func scalar(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,91 @@
+// Package inventory reads node definitions out of existing infrastructure-as-
+// code outputs — Terraform/OpenTofu state and Ansible inventories — so they
+// can be registered in bulk instead of one `orbit nodes add` at a time.
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// Options fill in what a source leaves out.
+type Options struct {
+	User   string   // SSH user when the source names none (default "root", as for `nodes add`)
+	Port   int      // SSH port when the source names none (default 22)
+	Key    string   // private key when the source names none
+	Groups []string // added to every node read
+
+	// Private prefers a Terraform instance's private address over its
+	// public one, for nodes reached over a VPN or through a bastion.
+	Private bool
+}
+
+// finish applies o's defaults and extra groups to nodes and rejects
+// duplicate names.
+func (o Options) finish(nodes []v1.NodeSpec) ([]v1.NodeSpec, error) {
+	seen := make(map[string]bool, len(nodes))
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Host == "" {
+			return nil, fmt.Errorf("node %q has no address", n.Name)
+		}
+		if seen[n.Name] {
+			return nil, fmt.Errorf("node name %q appears more than once", n.Name)
+		}
+		seen[n.Name] = true
+
+		if n.User == "" {
+			n.User = o.User
+		}
+		if n.User == "" {
+			n.User = "root"
+		}
+		if n.Port == 0 {
+			n.Port = o.Port
+		}
+		if n.Port == 0 {
+			n.Port = 22
+		}
+		if n.Key == "" {
+			n.Key = o.Key
+		}
+		n.Key = expandHome(n.Key)
+		n.Groups = append(n.Groups, o.Groups...)
+		slices.Sort(n.Groups)
+		n.Groups = slices.Compact(n.Groups)
+	}
+	return nodes, nil
+}
+
+// expandHome resolves a leading ~/ in path, as inventories often use it.
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// parsePort reads a port value from an inventory; 0 when absent.
+func parsePort(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	p, err := strconv.Atoi(s)
+	if err != nil || p <= 0 || p > 65535 {
+		return 0, fmt.Errorf("invalid SSH port %q", s)
+	}
+	return p, nil
+}
+
+// splitList splits a group list such as "web,edge" or "web edge".
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == ' ' })
+}
@@ -0,0 +1,197 @@
+package inventory
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+const tfState = `{
+  "version": 4,
+  "resources": [
+    {"mode": "managed", "type": "aws_instance", "name": "web", "instances": [
+      {"index_key": 0, "attributes": {"public_ip": "3.3.3.1", "private_ip": "10.0.0.1",
+        "tags": {"Name": "frontend", "orbit_groups": "web,edge", "orbit_user": "ubuntu"}}},
+      {"index_key": 1, "attributes": {"public_ip": "3.3.3.2", "private_ip": "10.0.0.2", "tags": {}}}
+    ]},
+    {"mode": "managed", "type": "aws_eip", "name": "ip", "instances": [{"attributes": {"public_ip": "3.3.3.9"}}]},
+    {"module": "module.db", "mode": "managed", "type": "google_compute_instance", "name": "db", "instances": [
+      {"attributes": {"name": "db-1", "labels": {"orbit_port": "2222"},
+        "network_interface": [{"network_ip": "10.1.0.5", "access_config": [{"nat_ip": "34.1.1.1"}]}]}}
+    ]}
+  ]
+}`
+
+func TestTerraformState(t *testing.T) {
+	nodes, err := Terraform([]byte(tfState), Options{User: "deploy", Groups: []string{"prod"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []v1.NodeSpec{
+		{Name: "frontend", Host: "3.3.3.1", User: "ubuntu", Port: 22, Groups: []string{"edge", "prod", "web"}},
+		{Name: "web-1", Host: "3.3.3.2", User: "deploy", Port: 22, Groups: []string{"prod"}},
+		{Name: "db-1", Host: "34.1.1.1", User: "deploy", Port: 2222, Groups: []string{"prod"}},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("nodes =\n%+v\nwant\n%+v", nodes, want)
+	}
+}
+
+func TestTerraformDuplicateNames(t *testing.T) {
+	state := strings.Replace(tfState, `"tags": {}`, `"tags": {"Name": "db-1"}`, 1)
+	if _, err := Terraform([]byte(state), Options{}); err == nil || !strings.Contains(err.Error(), `"db-1"`) {
+		t.Errorf("err = %v, want a duplicate name error", err)
+	}
+}
+
+func TestTerraformPrivate(t *testing.T) {
+	nodes, err := Terraform([]byte(tfState), Options{Private: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hosts []string
+	for _, n := range nodes {
+		hosts = append(hosts, n.Host)
+	}
+	if want := []string{"10.0.0.1", "10.0.0.2", "10.1.0.5"}; !reflect.DeepEqual(hosts, want) {
+		t.Errorf("hosts = %v, want %v", hosts, want)
+	}
+}
+
+func TestTerraformShowJSON(t *testing.T) {
+	show := `{"format_version": "1.0", "values": {"root_module": {
+	  "resources": [],
+	  "child_modules": [{"resources": [
+	    {"address": "module.k.hcloud_server.node[\"a\"]", "mode": "managed", "type": "hcloud_server",
+	     "name": "node", "index": "a", "values": {"ipv4_address": "5.5.5.5"}}
+	  ]}]}}}`
+	nodes, err := Terraform([]byte(show), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Name != "node-a" || nodes[0].Host != "5.5.5.5" || nodes[0].User != "root" {
+		t.Errorf("nodes = %+v", nodes)
+	}
+}
+
+func TestTerraformOutput(t *testing.T) {
+	out := `{
+	  "orbit_nodes": {"sensitive": false, "type": ["map", "object"], "value": {
+	    "edge-1": {"host": "1.2.3.4", "port": 2200, "groups": ["edge"]},
+	    "app-1": {"host": "1.2.3.5", "user": "app", "groups": "app,prod"}
+	  }},
+	  "vpc_id": {"value": "vpc-1"}
+	}`
+	nodes, err := Terraform([]byte(out), Options{Key: "/k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []v1.NodeSpec{
+		{Name: "app-1", Host: "1.2.3.5", User: "app", Key: "/k", Port: 22, Groups: []string{"app", "prod"}},
+		{Name: "edge-1", Host: "1.2.3.4", User: "root", Key: "/k", Port: 2200, Groups: []string{"edge"}},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("nodes =\n%+v\nwant\n%+v", nodes, want)
+	}
+}
+
+func TestTerraformNothing(t *testing.T) {
+	if _, err := Terraform([]byte(`{"version": 4, "resources": []}`), Options{}); err == nil {
+		t.Error("want an error for a state with no nodes")
+	}
+}
+
+const ansibleINI = `
+# comment
+bastion ansible_host=1.1.1.1
+
+[web]
+web[01:02].example.com ansible_user=www
+
+[db]
+db-a ansible_host=10.0.0.5 ansible_port=2222 ansible_ssh_private_key_file="/keys/db key"
+local ansible_connection=local
+
+[prod:children]
+web
+db
+
+[prod:vars]
+ansible_user=deploy
+
+[all:vars]
+ansible_port=2200
+`
+
+func TestAnsibleINI(t *testing.T) {
+	nodes, err := Ansible("hosts", []byte(ansibleINI), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []v1.NodeSpec{
+		{Name: "bastion", Host: "1.1.1.1", User: "root", Port: 2200},
+		{Name: "web01.example.com", Host: "web01.example.com", User: "www", Port: 2200, Groups: []string{"prod", "web"}},
+		{Name: "web02.example.com", Host: "web02.example.com", User: "www", Port: 2200, Groups: []string{"prod", "web"}},
+		{Name: "db-a", Host: "10.0.0.5", User: "deploy", Key: "/keys/db key", Port: 2222, Groups: []string{"db", "prod"}},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("nodes =\n%+v\nwant\n%+v", nodes, want)
+	}
+}
+
+func TestAnsibleYAML(t *testing.T) {
+	inv := `
+all:
+  vars:
+    ansible_user: ops
+  hosts:
+    bastion:
+      ansible_host: 1.1.1.1
+  children:
+    prod:
+      vars:
+        ansible_port: 2222
+      children:
+        web:
+          hosts:
+            web-[a:b]:
+          vars:
+            ansible_user: www
+`
+	nodes, err := Ansible("hosts.yml", []byte(inv), Options{Groups: []string{"imported"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []v1.NodeSpec{
+		{Name: "bastion", Host: "1.1.1.1", User: "ops", Port: 22, Groups: []string{"imported"}},
+		{Name: "web-a", Host: "web-a", User: "www", Port: 2222, Groups: []string{"imported", "prod", "web"}},
+		{Name: "web-b", Host: "web-b", User: "www", Port: 2222, Groups: []string{"imported", "prod", "web"}},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("nodes =\n%+v\nwant\n%+v", nodes, want)
+	}
+}
+
+func TestExpandHostPattern(t *testing.T) {
+	for pattern, want := range map[string][]string{
+		"plain":        {"plain"},
+		"n[8:10]":      {"n8", "n9", "n10"},
+		"n[08:10].x":   {"n08.x", "n09.x", "n10.x"},
+		"r[1:2]-[a:b]": {"r1-a", "r1-b", "r2-a", "r2-b"},
+		"s[0:4:2]":     {"s0", "s2", "s4"},
+	} {
+		got, err := expandHostPattern(pattern)
+		if err != nil {
+			t.Errorf("%s: %v", pattern, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v, want %v", pattern, got, want)
+		}
+	}
+	if _, err := expandHostPattern("bad[1:"); err == nil {
+		t.Error("want an error for an unterminated range")
+	}
+}
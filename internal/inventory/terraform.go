@@ -0,0 +1,309 @@
+// Package inventory: nodes from Terraform/OpenTofu state.
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// NodesOutput is the Terraform output that, when present, lists the nodes
+// explicitly — a list of objects, or a map of name to object, with the
+// fields name, host, user, port, key and groups. It takes precedence over
+// reading instances out of the state.
+const NodesOutput = "orbit_nodes"
+
+// instanceAddrs lists, per compute resource type, the attributes holding
+// its public and private address, as dotted paths into the attributes.
+var instanceAddrs = map[string]struct{ public, private string }{
+	"aws_instance":                  {"public_ip", "private_ip"},
+	"aws_lightsail_instance":        {"public_ip_address", "private_ip_address"},
+	"azurerm_linux_virtual_machine": {"public_ip_address", "private_ip_address"},
+	"digitalocean_droplet":          {"ipv4_address", "ipv4_address_private"},
+	"google_compute_instance":       {"network_interface.0.access_config.0.nat_ip", "network_interface.0.network_ip"},
+	"hcloud_server":                 {"ipv4_address", "network.0.ip"},
+	"linode_instance":               {"ip_address", "private_ip_address"},
+	"openstack_compute_instance_v2": {"access_ip_v4", "network.0.fixed_ip_v4"},
+	"scaleway_instance_server":      {"public_ip", "private_ip"},
+	"vultr_instance":                {"main_ip", "internal_ip"},
+}
+
+// Terraform reads nodes from Terraform or OpenTofu JSON: a state file
+// (terraform.tfstate), the output of `terraform show -json`, or the output
+// of `terraform output -json`.
+//
+// The NodesOutput output is used when there is one. Otherwise every
+// instance of a known compute resource type becomes a node, named after its
+// Name tag or name attribute, with its public address (private with
+// Options.Private). The tags or labels orbit_user, orbit_port and
+// orbit_groups set the SSH user, port and groups of an instance.
+func Terraform(data []byte, opts Options) ([]v1.NodeSpec, error) {
+	var doc struct {
+		Resources []struct {
+			Module    string `json:"module"`
+			Mode      string `json:"mode"`
+			Type      string `json:"type"`
+			Name      string `json:"name"`
+			Instances []struct {
+				IndexKey   any            `json:"index_key"`
+				Attributes map[string]any `json:"attributes"`
+			} `json:"instances"`
+		} `json:"resources"`
+		Outputs map[string]tfOutput `json:"outputs"`
+		Values  *struct {
+			Outputs    map[string]tfOutput `json:"outputs"`
+			RootModule tfModule            `json:"root_module"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse terraform JSON: %w", err)
+	}
+
+	outputs := doc.Outputs
+	var instances []tfInstance
+	switch {
+	case doc.Values != nil: // terraform show -json
+		outputs = doc.Values.Outputs
+		instances = doc.Values.RootModule.instances()
+	case doc.Resources != nil || doc.Outputs != nil: // state file
+		for _, r := range doc.Resources {
+			for _, in := range r.Instances {
+				addr := r.Type + "." + r.Name
+				if r.Module != "" {
+					addr = r.Module + "." + addr
+				}
+				instances = append(instances, tfInstance{
+					Mode: r.Mode, Type: r.Type, Name: r.Name, Address: addr,
+					Index: in.IndexKey, Attributes: in.Attributes,
+				})
+			}
+		}
+	default: // terraform output -json: the outputs themselves
+		if err := json.Unmarshal(data, &outputs); err != nil {
+			return nil, fmt.Errorf("parse terraform outputs: %w", err)
+		}
+	}
+
+	var nodes []v1.NodeSpec
+	var err error
+	if out, ok := outputs[NodesOutput]; ok {
+		nodes, err = outputNodes(out.Value)
+	} else {
+		nodes, err = instanceNodes(instances, opts.Private)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no %s output and no compute instances found", NodesOutput)
+	}
+	return opts.finish(nodes)
+}
+
+type tfOutput struct {
+	Value any `json:"value"`
+}
+
+// tfModule is a module in `terraform show -json` output.
+type tfModule struct {
+	Resources []struct {
+		Address string         `json:"address"`
+		Mode    string         `json:"mode"`
+		Type    string         `json:"type"`
+		Name    string         `json:"name"`
+		Index   any            `json:"index"`
+		Values  map[string]any `json:"values"`
+	} `json:"resources"`
+	ChildModules []tfModule `json:"child_modules"`
+}
+
+func (m tfModule) instances() []tfInstance {
+	var out []tfInstance
+	for _, r := range m.Resources {
+		addr, _, _ := strings.Cut(r.Address, "[") // the index is kept separately
+		out = append(out, tfInstance{
+			Mode: r.Mode, Type: r.Type, Name: r.Name, Address: addr,
+			Index: r.Index, Attributes: r.Values,
+		})
+	}
+	for _, c := range m.ChildModules {
+		out = append(out, c.instances()...)
+	}
+	return out
+}
+
+// tfInstance is one instance of a resource, from either JSON layout.
+type tfInstance struct {
+	Mode, Type, Name, Address string
+	Index                     any // nil, a count index or a for_each key
+	Attributes                map[string]any
+}
+
+func instanceNodes(instances []tfInstance, private bool) ([]v1.NodeSpec, error) {
+	var nodes []v1.NodeSpec
+	for _, in := range instances {
+		addrs, ok := instanceAddrs[in.Type]
+		if !ok || in.Mode == "data" {
+			continue
+		}
+		host := attrString(in.Attributes, addrs.public)
+		if private || host == "" {
+			if p := attrString(in.Attributes, addrs.private); p != "" {
+				host = p
+			}
+		}
+		if host == "" {
+			return nil, fmt.Errorf("%s has no address in the state; has it been applied?", in.label())
+		}
+
+		tags := instanceTags(in.Attributes)
+		port, err := parsePort(tags["orbit_port"])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", in.label(), err)
+		}
+		name := tags["Name"]
+		if name == "" {
+			name = attrString(in.Attributes, "name")
+		}
+		if name == "" {
+			name = in.Name + indexSuffix(in.Index)
+		}
+		nodes = append(nodes, v1.NodeSpec{
+			Name:   name,
+			Host:   host,
+			User:   tags["orbit_user"],
+			Port:   port,
+			Groups: splitList(tags["orbit_groups"]),
+		})
+	}
+	return nodes, nil
+}
+
+func (in tfInstance) label() string {
+	if in.Index == nil {
+		return in.Address
+	}
+	if s, ok := in.Index.(string); ok {
+		return fmt.Sprintf("%s[%q]", in.Address, s)
+	}
+	return fmt.Sprintf("%s[%v]", in.Address, in.Index)
+}
+
+// indexSuffix names the instances of a counted or for_each resource apart.
+func indexSuffix(index any) string {
+	switch v := index.(type) {
+	case nil:
+		return ""
+	case float64:
+		return "-" + strconv.Itoa(int(v))
+	default:
+		return "-" + fmt.Sprint(v)
+	}
+}
+
+// instanceTags merges an instance's tags and labels, whichever its
+// provider uses.
+func instanceTags(attrs map[string]any) map[string]string {
+	tags := map[string]string{}
+	for _, key := range []string{"labels", "tags"} {
+		if m, ok := attrs[key].(map[string]any); ok {
+			for k, v := range m {
+				if s, ok := v.(string); ok {
+					tags[k] = s
+				}
+			}
+		}
+	}
+	return tags
+}
+
+// attrString follows a dotted path such as "network_interface.0.network_ip"
+// through nested attributes; "" when any step is missing.
+func attrString(attrs map[string]any, path string) string {
+	var cur any = attrs
+	for _, step := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]any:
+			cur = v[step]
+		case []any:
+			i, err := strconv.Atoi(step)
+			if err != nil || i >= len(v) {
+				return ""
+			}
+			cur = v[i]
+		default:
+			return ""
+		}
+	}
+	s, _ := cur.(string)
+	return s
+}
+
+// outputNodes reads the NodesOutput value.
+func outputNodes(value any) ([]v1.NodeSpec, error) {
+	var entries []map[string]any
+	switch v := value.(type) {
+	case []any:
+		for i, e := range v {
+			m, ok := e.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("output %s[%d] is not an object", NodesOutput, i)
+			}
+			entries = append(entries, m)
+		}
+	case map[string]any:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			m, ok := v[name].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("output %s[%q] is not an object", NodesOutput, name)
+			}
+			if _, ok := m["name"]; !ok {
+				m["name"] = name
+			}
+			entries = append(entries, m)
+		}
+	default:
+		return nil, fmt.Errorf("output %s must be a list or a map of objects", NodesOutput)
+	}
+
+	nodes := make([]v1.NodeSpec, 0, len(entries))
+	for i, e := range entries {
+		str := func(k string) string {
+			if e[k] == nil {
+				return ""
+			}
+			return fmt.Sprint(e[k])
+		}
+		n := v1.NodeSpec{Name: str("name"), Host: str("host"), User: str("user"), Key: str("key")}
+		if n.Name == "" {
+			n.Name = n.Host
+		}
+		if n.Name == "" {
+			return nil, fmt.Errorf("output %s[%d] has neither name nor host", NodesOutput, i)
+		}
+		port, err := parsePort(str("port"))
+		if err != nil {
+			return nil, fmt.Errorf("output %s: node %q: %w", NodesOutput, n.Name, err)
+		}
+		n.Port = port
+		switch g := e["groups"].(type) {
+		case string:
+			n.Groups = splitList(g)
+		case []any:
+			for _, s := range g {
+				n.Groups = append(n.Groups, fmt.Sprint(s))
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,338 @@
+// Package logship collects the output of orbit containers. The Shipper, run
+// by orbit agent, follows every orbit container on a node and keeps each
+// service's lines in rotated files under ~/.orbit/logs/services/, so its
+// logs outlive the containers that wrote them — Docker drops a container's
+// logs when a deploy replaces it — and pushes them to external stores such
+// as Loki. Read reads the files back for `orbit logs`.
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/pkg/retry"
+)
+
+// PollInterval is how often the container set is re-listed while the event
+// stream is down.
+const PollInterval = 5 * time.Second
+
+// Dir is the directory holding node's service log files.
+func Dir(node string) string {
+	return filepath.Join(config.OrbitHome(), "logs", "services", node)
+}
+
+// Path is the log file of service on node.
+func Path(node, service string) string {
+	return filepath.Join(Dir(node), service+".log")
+}
+
+// Line is one line of a service's output, stored as a JSON object per line.
+type Line struct {
+	Time      time.Time `json:"time"`
+	Service   string    `json:"service,omitempty"`
+	Node      string    `json:"node,omitempty"`
+	Image     string    `json:"image,omitempty"`
+	Container string    `json:"container"` // replica name, e.g. web-2
+	ID        string    `json:"id"`        // short container ID
+	Stream    string    `json:"stream"`    // stdout | stderr
+	Text      string    `json:"text"`
+}
+
+// String renders the line as `orbit logs --timestamps` prints it.
+func (l Line) String() string {
+	return l.Time.Format(time.RFC3339Nano) + " " + l.Container + " " + l.Text
+}
+
+// containerRuntime is the part of *orchestrator.Client the Shipper uses.
+type containerRuntime interface {
+	orchestrator.EventSource
+	FollowLogs(ctx context.Context, idOrName string, since time.Time, fn func(orchestrator.LogLine)) error
+}
+
+// Shipper follows the output of every orbit container on a node, appends it
+// to its service's file and pushes it to sinks. The container set is kept
+// by an orchestrator.Follower, which the agent shares with the metrics
+// Collector, so a deploy's replacements are followed as they start.
+type Shipper struct {
+	docker containerRuntime
+	node   string
+	dir    string // "" keeps no files; set by WithFiles
+	rot    logger.Rotation
+	sinks  []*queue // set by WithSinks
+	log    *logger.Logger
+
+	follow     *orchestrator.Follower
+	ownsFollow bool // Run runs follow; false when it is shared
+
+	mu    sync.Mutex
+	files map[string]*logger.RotatingFile // service → open log file
+	last  map[string]time.Time            // container ID → time of its last line written
+}
+
+// NewShipper returns a Shipper for the containers of node. Give it
+// somewhere to put their lines with WithFiles or WithSinks.
+func NewShipper(docker *orchestrator.Client, node string, log *logger.Logger) *Shipper {
+	return newShipper(docker, node, log)
+}
+
+func newShipper(docker containerRuntime, node string, log *logger.Logger) *Shipper {
+	s := &Shipper{
+		docker: docker,
+		node:   node,
+		log:    log,
+		files:  make(map[string]*logger.RotatingFile),
+		last:   make(map[string]time.Time),
+	}
+	s.follow = orchestrator.NewFollower(docker, "logship", PollInterval, log).Watch(s.stream, s.forget)
+	s.ownsFollow = true
+	return s
+}
+
+// WithFollower reads containers through f, shared with other consumers,
+// instead of a Follower of the Shipper's own. The caller runs f.
+func (s *Shipper) WithFollower(f *orchestrator.Follower) *Shipper {
+	s.follow, s.ownsFollow = f.Watch(s.stream, s.forget), false
+	return s
+}
+
+// WithFiles keeps each service's lines in a file under dir, rotated as rot
+// says; Dir(node) is where `orbit logs` looks.
+func (s *Shipper) WithFiles(dir string, rot logger.Rotation) *Shipper {
+	s.dir, s.rot = dir, rot
+	return s
+}
+
+// WithSinks pushes every line to sinks too, in batches.
+func (s *Shipper) WithSinks(sinks ...Sink) *Shipper {
+	for _, sink := range sinks {
+		s.sinks = append(s.sinks, &queue{sink: sink, lines: make(chan Line, queueSize)})
+	}
+	return s
+}
+
+// Run follows containers until ctx is cancelled, then sends the lines still
+// queued for sinks before returning.
+func (s *Shipper) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, q := range s.sinks {
+		wg.Add(1)
+		go func() { defer wg.Done(); q.run(ctx, s.log) }()
+	}
+	defer wg.Wait()
+	defer s.closeAll()
+	if s.ownsFollow {
+		s.follow.Run(ctx)
+		return
+	}
+	<-ctx.Done()
+}
+
+// stream returns the follower of a container's output. A container seen
+// starting is followed from its first line. One found by a listing —
+// running when the agent started, or started while the event stream was
+// down — is followed from where its service's file ends, so a restarted
+// agent does not ship lines twice; without files, from now. Either way a
+// container followed before resumes after the last line written for it.
+func (s *Shipper) stream(c orchestrator.Followed) func(ctx context.Context) error {
+	var from time.Time
+	if c.Listed {
+		from = time.Now()
+		if s.dir != "" {
+			from = lastTime(s.path(c.Service))
+		}
+	}
+	origin := s.origin(c.ID, c.Service, c.Replica, c.Image)
+	since := s.since(c.ID, from)
+	return func(ctx context.Context) error {
+		return s.docker.FollowLogs(ctx, c.ID, since, func(l orchestrator.LogLine) {
+			if !l.Time.After(since) {
+				return // the runtime's since is inclusive
+			}
+			since = l.Time
+			line := origin
+			line.Time, line.Stream, line.Text = l.Time, l.Stream, l.Text
+			s.write(c.ID, line)
+		})
+	}
+}
+
+// forget drops what is kept about a container once it is destroyed.
+func (s *Shipper) forget(id string, destroyed bool) {
+	if !destroyed {
+		return
+	}
+	s.mu.Lock()
+	delete(s.last, id)
+	s.mu.Unlock()
+}
+
+// since is where to follow container id from: after the last line written
+// for it, or else def.
+func (s *Shipper) since(id string, def time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.last[id]; ok {
+		return t
+	}
+	return def
+}
+
+// origin is the part of Line that says where a container's lines come from.
+// The container is named after its service and replica rather than by its
+// container name, which is temporary while a deploy starts it.
+func (s *Shipper) origin(id, service, replica, image string) Line {
+	name := service
+	if replica != "" {
+		name += "-" + replica
+	}
+	return Line{Service: service, Node: s.node, Image: image, Container: name, ID: id[:min(12, len(id))]}
+}
+
+// write queues l, from container id, for the sinks and appends it to its
+// service's file.
+func (s *Shipper) write(id string, l Line) {
+	for _, q := range s.sinks {
+		q.push(l)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last[id] = l.Time
+	if s.dir != "" {
+		s.writeFile(l)
+	}
+}
+
+// writeFile appends l to its service's file, opening it on first use.
+func (s *Shipper) writeFile(l Line) {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return
+	}
+	w, ok := s.files[l.Service]
+	if !ok {
+		if err := os.MkdirAll(s.dir, 0750); err != nil {
+			s.log.Warn("logship: create log dir", "dir", s.dir, "err", err)
+			return
+		}
+		if w, err = logger.OpenRotating(s.path(l.Service), s.rot); err != nil {
+			s.log.Warn("logship: open log file", "service", l.Service, "err", err)
+			return
+		}
+		s.files[l.Service] = w
+	}
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		s.log.Warn("logship: write", "service", l.Service, "err", err)
+	}
+}
+
+func (s *Shipper) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for svc, w := range s.files {
+		w.Close()
+		delete(s.files, svc)
+	}
+}
+
+func (s *Shipper) path(service string) string {
+	return filepath.Join(s.dir, service+".log")
+}
+
+// lastTime returns the time of the last line in the log file at path, or the
+// zero time when it is empty or missing.
+func lastTime(path string) time.Time {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}
+	}
+	defer f.Close()
+	const tail = 64 << 10
+	if info, err := f.Stat(); err == nil && info.Size() > tail {
+		if _, err := f.Seek(-tail, io.SeekEnd); err != nil {
+			return time.Time{}
+		}
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return time.Time{}
+	}
+	lines := bytes.Split(bytes.TrimRight(b, "\n"), []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		var l Line
+		if json.Unmarshal(lines[i], &l) == nil && !l.Time.IsZero() {
+			return l.Time
+		}
+	}
+	return time.Time{}
+}
+
+// queue batches lines for a sink. When the sink falls behind by queueSize
+// lines, new lines are dropped and counted rather than blocking followers.
+type queue struct {
+	sink    Sink
+	lines   chan Line
+	dropped atomic.Int64
+}
+
+func (q *queue) push(l Line) {
+	select {
+	case q.lines <- l:
+	default:
+		q.dropped.Add(1)
+	}
+}
+
+// run sends batches of up to BatchSize lines, at least every FlushInterval,
+// until ctx is cancelled; then it sends what is still queued.
+func (q *queue) run(ctx context.Context, log *logger.Logger) {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+	batch := make([]Line, 0, BatchSize)
+	flush := func(ctx context.Context) {
+		if n := q.dropped.Swap(0); n > 0 {
+			log.Warn("logship: sink behind, lines dropped", "sink", q.sink.Name(), "lines", n)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := retry.Do(ctx, sendPolicy, func(ctx context.Context) error { return q.sink.Send(ctx, batch) }); err != nil {
+			log.Warn("logship: send failed", "sink", q.sink.Name(), "lines", len(batch), "err", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			fctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+			defer cancel()
+			for {
+				select {
+				case l := <-q.lines:
+					if batch = append(batch, l); len(batch) == BatchSize {
+						flush(fctx)
+					}
+				default:
+					flush(fctx)
+					return
+				}
+			}
+		case l := <-q.lines:
+			if batch = append(batch, l); len(batch) == BatchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package logship
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/orchestrator"
+)
+
+var t0 = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// fakeRuntime replays each container's output, honouring since inclusively
+// as Docker does, and then waits as a followed container would.
+type fakeRuntime struct {
+	mu      sync.Mutex
+	running []types.Container
+	output  map[string][]orchestrator.LogLine
+	events  chan v1.ContainerEvent
+}
+
+func (f *fakeRuntime) ListContainers(context.Context, string) ([]types.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]types.Container(nil), f.running...), nil
+}
+
+func (f *fakeRuntime) ContainerEvents(context.Context) (<-chan v1.ContainerEvent, <-chan error) {
+	return f.events, nil
+}
+
+func (f *fakeRuntime) FollowLogs(ctx context.Context, id string, since time.Time, fn func(orchestrator.LogLine)) error {
+	f.mu.Lock()
+	lines := f.output[id]
+	f.mu.Unlock()
+	for _, l := range lines {
+		if !l.Time.Before(since) {
+			fn(l)
+		}
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func container(id, name string) types.Container {
+	return types.Container{ID: id, Names: []string{"/" + name}, Labels: map[string]string{"orbit.service": "web"}}
+}
+
+func output(texts ...string) []orchestrator.LogLine {
+	lines := make([]orchestrator.LogLine, len(texts))
+	for i, t := range texts {
+		lines[i] = orchestrator.LogLine{Time: t0.Add(time.Duration(i) * time.Second), Stream: "stdout", Text: t}
+	}
+	return lines
+}
+
+// ship runs a Shipper over rt until the web log has want lines.
+func ship(t *testing.T, rt *fakeRuntime, dir string, want int, then func()) []Line {
+	t.Helper()
+	log, _ := logger.Init("error", "text", "", "", false)
+	s := newShipper(rt, "local", log).WithFiles(dir, logger.Rotation{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { s.Run(ctx); close(done) }()
+	defer func() { cancel(); <-done }()
+
+	path := filepath.Join(dir, "web.log")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lines, _ := ReadFile(path, Filter{})
+		if len(lines) >= want {
+			if then == nil {
+				return lines
+			}
+			then()
+			then, want = nil, want+1
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("web.log has %d lines, want %d: %v", len(lines), want, lines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShipperKeepsLogsAcrossReplacements(t *testing.T) {
+	dir := t.TempDir()
+	rt := &fakeRuntime{
+		running: []types.Container{container("aaaaaaaaaaaaaaaa", "web")},
+		output:  map[string][]orchestrator.LogLine{"aaaaaaaaaaaaaaaa": output("booted", "served /")},
+		events:  make(chan v1.ContainerEvent),
+	}
+	// A deploy replaces the container; the new one's output is collected
+	// into the same file.
+	lines := ship(t, rt, dir, 2, func() {
+		rt.mu.Lock()
+		rt.output["bbbbbbbbbbbbbbbb"] = []orchestrator.LogLine{{Time: t0.Add(time.Minute), Stream: "stderr", Text: "v2 booted"}}
+		rt.mu.Unlock()
+		rt.events <- v1.ContainerEvent{ID: "aaaaaaaaaaaaaaaa", Service: "web", Action: "destroy"}
+		rt.events <- v1.ContainerEvent{ID: "bbbbbbbbbbbbbbbb", Name: "web", Service: "web", Action: "start"}
+	})
+	if len(lines) != 3 || lines[0].Text != "booted" || lines[2].Text != "v2 booted" ||
+		lines[2].ID != "bbbbbbbbbbbb" || lines[2].Stream != "stderr" || lines[2].Container != "web" ||
+		lines[2].Service != "web" || lines[2].Node != "local" {
+		t.Fatalf("lines = %+v", lines)
+	}
+
+	// A restarted agent picks up where the file ends instead of writing the
+	// running container's output again.
+	rt.running = []types.Container{container("bbbbbbbbbbbbbbbb", "web")}
+	rt.output["bbbbbbbbbbbbbbbb"] = append(rt.output["bbbbbbbbbbbbbbbb"],
+		orchestrator.LogLine{Time: t0.Add(2 * time.Minute), Stream: "stdout", Text: "served /health"})
+	lines = ship(t, rt, dir, 4, nil)
+	if len(lines) != 4 || lines[3].Text != "served /health" {
+		t.Fatalf("after restart lines = %+v", lines)
+	}
+}
+
+func TestReadFilters(t *testing.T) {
+	dir := t.TempDir()
+	rt := &fakeRuntime{
+		running: []types.Container{container("aaaaaaaaaaaaaaaa", "web")},
+		output:  map[string][]orchestrator.LogLine{"aaaaaaaaaaaaaaaa": output("a", "b", "c", "d", "e")},
+		events:  make(chan v1.ContainerEvent),
+	}
+	rt.output["aaaaaaaaaaaaaaaa"][3].Stream = "stderr"
+	rt.output["aaaaaaaaaaaaaaaa"][4].Text = "\x1b[31me\x1b[0m"
+	ship(t, rt, dir, 5, nil)
+	path := filepath.Join(dir, "web.log")
+
+	lines, err := ReadFile(path, Filter{Since: t0.Add(2 * time.Second)})
+	if err != nil || len(lines) != 3 || lines[0].Text != "c" {
+		t.Errorf("since: lines = %+v, err = %v", lines, err)
+	}
+	lines, _ = ReadFile(path, Filter{Tail: 2})
+	if len(lines) != 2 || lines[0].Text != "d" || lines[1].Text != "\x1b[31me\x1b[0m" {
+		t.Errorf("tail: lines = %+v", lines)
+	}
+	lines, _ = ReadFile(path, Filter{Stream: "stderr"})
+	if len(lines) != 1 || lines[0].Text != "d" {
+		t.Errorf("stream: lines = %+v", lines)
+	}
+	lines, _ = ReadFile(path, Filter{Match: regexp.MustCompile(`^(b|e)$`), Tail: 1})
+	if len(lines) != 1 || lines[0].Stream != "stdout" || lines[0].Time != t0.Add(4*time.Second) {
+		t.Errorf("match: lines = %+v", lines)
+	}
+	if lines, err := ReadFile(filepath.Join(dir, "missing.log"), Filter{}); err != nil || len(lines) != 0 {
+		t.Errorf("missing file: lines = %+v, err = %v", lines, err)
+	}
+}
@@ -0,0 +1,64 @@
+// Package logship: reading service log files back.
+package logship
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+// Filter selects lines read back. Zero fields match everything.
+type Filter struct {
+	Since  time.Time      // lines at or after this time
+	Tail   int            // only the last Tail matching lines
+	Stream string         // lines of this stream, stdout or stderr
+	Match  *regexp.Regexp // lines whose text, without colours, it matches
+}
+
+// matches reports whether l passes f's Stream and Match.
+func (f Filter) matches(l Line) bool {
+	if f.Stream != "" && l.Stream != f.Stream {
+		return false
+	}
+	return f.Match == nil || f.Match.MatchString(pprint.StripANSI(l.Text))
+}
+
+// Collected reports whether the agent has been collecting service's logs
+// on node.
+func Collected(node, service string) bool {
+	_, err := os.Stat(Path(node, service))
+	return err == nil
+}
+
+// Read returns the lines of service's log on node, and of its rotated
+// files, that match f, oldest first.
+func Read(node, service string, f Filter) ([]Line, error) {
+	return ReadFile(Path(node, service), f)
+}
+
+// ReadFile returns the lines of the service log at path, and of its rotated
+// files, that match f, oldest first. Lines that are not JSON are skipped.
+func ReadFile(path string, f Filter) ([]Line, error) {
+	var out []Line
+	err := logger.ScanRotated(path, f.Since, func(b []byte) {
+		var l Line
+		if json.Unmarshal(b, &l) != nil || l.Time.Before(f.Since) || !f.matches(l) {
+			return
+		}
+		out = append(out, l)
+		if f.Tail > 0 && len(out) > 2*f.Tail {
+			out = append(out[:0], out[len(out)-f.Tail:]...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if f.Tail > 0 && len(out) > f.Tail {
+		out = out[len(out)-f.Tail:]
+	}
+	return out, nil
+}
@@ -0,0 +1,246 @@
+// Package logship: pushing lines to Loki, HTTP endpoints and syslog.
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/pkg/retry"
+)
+
+// Batching of lines pushed to sinks.
+const (
+	BatchSize     = 500             // lines per request at most
+	FlushInterval = time.Second     // how long a line waits for its batch to fill
+	queueSize     = 10000           // lines buffered per sink before new ones are dropped
+	drainTimeout  = 5 * time.Second // for sending what is queued when the agent stops
+)
+
+// sendPolicy retries a failed batch a couple of times before dropping it.
+var sendPolicy = retry.Policy{Attempts: 3, Retryable: retryable}
+
+// Sink receives batches of lines for an external log store. Send must not
+// keep lines after it returns.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, lines []Line) error
+}
+
+// NewSinks returns a Sink for each store configured in the logging: section.
+func NewSinks(cfg config.LoggingConfig) ([]Sink, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	var sinks []Sink
+	if c := cfg.Loki; c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return nil, fmt.Errorf("logging.loki.url: %w", err)
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/loki/api/v1/push"
+		}
+		sinks = append(sinks, &lokiSink{url: u.String(), cfg: c, labels: cfg.Labels, client: client})
+	}
+	if c := cfg.HTTP; c.URL != "" {
+		sinks = append(sinks, &httpSink{cfg: c, labels: cfg.Labels, client: client})
+	}
+	if addr := cfg.Syslog.Address; addr != "" {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, fmt.Errorf("logging.syslog.address: %w", err)
+		}
+		sinks = append(sinks, &syslogSink{network: u.Scheme, addr: u.Host})
+	}
+	return sinks, nil
+}
+
+// StatusError is an HTTP sink's non-2xx response.
+type StatusError struct {
+	Sink string
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: HTTP %d: %s", e.Sink, e.Code, e.Body)
+}
+
+// retryable retries network failures, rate limiting and server errors, but
+// not a request the store rejected.
+func retryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusTooManyRequests || se.Code >= 500
+	}
+	return true
+}
+
+// post sends body to url and turns a non-2xx response into a StatusError.
+func post(ctx context.Context, client *http.Client, sink, url string, body []byte, header func(*http.Request)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	header(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{Sink: sink, Code: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+	}
+	return nil
+}
+
+// lokiSink pushes to Loki's push API, one stream per service, node, image
+// and output stream.
+type lokiSink struct {
+	url    string
+	cfg    config.LokiConfig
+	labels map[string]string
+	client *http.Client
+}
+
+func (s *lokiSink) Name() string { return "loki" }
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (s *lokiSink) Send(ctx context.Context, lines []Line) error {
+	byKey := map[string]*lokiStream{}
+	var keys []string
+	for _, l := range lines {
+		key := l.Service + "\x00" + l.Node + "\x00" + l.Image + "\x00" + l.Stream
+		st, ok := byKey[key]
+		if !ok {
+			labels := map[string]string{"service": l.Service, "node": l.Node, "stream": l.Stream}
+			if l.Image != "" {
+				labels["image"] = l.Image
+			}
+			for k, v := range s.labels {
+				labels[k] = v
+			}
+			st = &lokiStream{Stream: labels}
+			byKey[key] = st
+			keys = append(keys, key)
+		}
+		st.Values = append(st.Values, [2]string{strconv.FormatInt(l.Time.UnixNano(), 10), l.Text})
+	}
+	sort.Strings(keys)
+	streams := make([]*lokiStream, len(keys))
+	for i, k := range keys {
+		streams[i] = byKey[k]
+	}
+	body, err := json.Marshal(map[string]any{"streams": streams})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, "loki", s.url, body, func(req *http.Request) {
+		if s.cfg.TenantID != "" {
+			req.Header.Set("X-Scope-OrgID", s.cfg.TenantID)
+		}
+		if s.cfg.Username != "" {
+			req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+		}
+		for k, v := range s.cfg.Headers {
+			req.Header.Set(k, v)
+		}
+	})
+}
+
+// httpSink POSTs each batch as a JSON array of lines.
+type httpSink struct {
+	cfg    config.HTTPLogConfig
+	labels map[string]string
+	client *http.Client
+}
+
+func (s *httpSink) Name() string { return "http" }
+
+type httpLine struct {
+	Line
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func (s *httpSink) Send(ctx context.Context, lines []Line) error {
+	out := make([]httpLine, len(lines))
+	for i, l := range lines {
+		out[i] = httpLine{Line: l, Labels: s.labels}
+	}
+	body, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, "http", s.cfg.URL, body, func(req *http.Request) {
+		for k, v := range s.cfg.Headers {
+			req.Header.Set(k, v)
+		}
+	})
+}
+
+// syslogSink sends RFC 5424 messages: the node is the hostname, the service
+// the app name and the replica the process ID. Over TCP they are framed by
+// octet counting (RFC 6587).
+type syslogSink struct {
+	network, addr string
+}
+
+func (s *syslogSink) Name() string { return "syslog" }
+
+func (s *syslogSink) Send(ctx context.Context, lines []Line) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, s.network, s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	var buf bytes.Buffer
+	for _, l := range lines {
+		msg := syslogMessage(l)
+		buf.Reset()
+		if s.network == "tcp" {
+			fmt.Fprintf(&buf, "%d ", len(msg))
+		}
+		buf.WriteString(msg)
+		if _, err := conn.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syslogMessage formats l as an RFC 5424 message of facility user, at
+// severity error for stderr and info otherwise.
+func syslogMessage(l Line) string {
+	pri := 1*8 + 6
+	if l.Stream == "stderr" {
+		pri = 1*8 + 3
+	}
+	field := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return strings.ReplaceAll(s, " ", "_")
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s %s - - %s", pri, l.Time.UTC().Format(time.RFC3339Nano),
+		field(l.Node), field(l.Service), field(l.Container), l.Text)
+}
@@ -0,0 +1,143 @@
+package logship
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/orchestrator"
+)
+
+type lokiPush struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func TestLokiSink(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		pushes []lokiPush
+		status = http.StatusNoContent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.URL.Path != "/loki/api/v1/push" || r.Header.Get("X-Scope-OrgID") != "acme" || user != "u" || pass != "p" {
+			t.Errorf("request %s org=%q auth=%s:%s", r.URL.Path, r.Header.Get("X-Scope-OrgID"), user, pass)
+		}
+		var p lokiPush
+		json.NewDecoder(r.Body).Decode(&p)
+		mu.Lock()
+		defer mu.Unlock()
+		pushes = append(pushes, p)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sinks, err := NewSinks(config.LoggingConfig{
+		Loki:   config.LokiConfig{URL: srv.URL, TenantID: "acme", Username: "u", Password: "p"},
+		Labels: map[string]string{"env": "prod"},
+	})
+	if err != nil || len(sinks) != 1 {
+		t.Fatalf("sinks = %v, err = %v", sinks, err)
+	}
+	lines := []Line{
+		{Time: t0, Service: "web", Node: "prod-01", Image: "web:2", Container: "web", Stream: "stdout", Text: "a"},
+		{Time: t0.Add(time.Second), Service: "web", Node: "prod-01", Image: "web:2", Container: "web-2", Stream: "stdout", Text: "b"},
+		{Time: t0, Service: "api", Node: "prod-01", Image: "api:1", Container: "api", Stream: "stderr", Text: "c"},
+	}
+	if err := sinks[0].Send(context.Background(), lines); err != nil {
+		t.Fatal(err)
+	}
+	p := pushes[0]
+	if len(p.Streams) != 2 {
+		t.Fatalf("streams = %+v", p.Streams)
+	}
+	web := p.Streams[1]
+	if web.Stream["service"] != "web" || web.Stream["node"] != "prod-01" || web.Stream["image"] != "web:2" ||
+		web.Stream["env"] != "prod" || len(web.Values) != 2 || web.Values[1][1] != "b" ||
+		web.Values[0][0] != "1714557600000000000" {
+		t.Errorf("web stream = %+v", web)
+	}
+
+	status = http.StatusBadRequest
+	if err := sinks[0].Send(context.Background(), lines); err == nil || retryable(err) {
+		t.Errorf("rejected push: err = %v, want a non-retryable error", err)
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		b, _ := io.ReadAll(conn) // the sink closes the connection after the batch
+		got <- string(b)
+	}()
+
+	sinks, _ := NewSinks(config.LoggingConfig{Syslog: config.SyslogConfig{Address: "tcp://" + ln.Addr().String()}})
+	l := Line{Time: t0, Service: "web", Node: "prod-01", Container: "web-2", Stream: "stderr", Text: "boom"}
+	if err := sinks[0].Send(context.Background(), []Line{l}); err != nil {
+		t.Fatal(err)
+	}
+	want := "<11>1 2024-05-01T10:00:00Z prod-01 web web-2 - - boom"
+	if msg := <-got; msg != fmt.Sprintf("%d %s", len(want), want) {
+		t.Errorf("frame = %q, want %q with its length", msg, want)
+	}
+}
+
+// collectSink keeps what it is sent.
+type collectSink struct {
+	mu    sync.Mutex
+	lines []Line
+}
+
+func (c *collectSink) Name() string { return "collect" }
+
+func (c *collectSink) Send(_ context.Context, lines []Line) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines = append(c.lines, lines...)
+	return nil
+}
+
+func TestShipperSendsQueuedLinesOnStop(t *testing.T) {
+	rt := &fakeRuntime{
+		output: map[string][]orchestrator.LogLine{"aaaaaaaaaaaaaaaa": output("one", "two")},
+		events: make(chan v1.ContainerEvent),
+	}
+	log, _ := logger.Init("error", "text", "", "", false)
+	sink := &collectSink{}
+	s := newShipper(rt, "local", log).WithSinks(sink)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { s.Run(ctx); close(done) }()
+
+	rt.events <- v1.ContainerEvent{ID: "aaaaaaaaaaaaaaaa", Service: "web", Replica: "2", Image: "web:1", Action: "start"}
+	time.Sleep(50 * time.Millisecond) // well inside FlushInterval
+	cancel()
+	<-done
+
+	if len(sink.lines) != 2 || sink.lines[0].Container != "web-2" || sink.lines[0].Image != "web:1" || sink.lines[1].Text != "two" {
+		t.Errorf("sent = %+v", sink.lines)
+	}
+}
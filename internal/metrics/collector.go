@@ -1,9 +1,10 @@
-// Package metrics polls Docker container stats and exposes them for TUI and Prometheus.
+// Package metrics streams Docker container stats and exposes them for TUI and Prometheus.
 package metrics
 
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	v1 "github.com/f9-o/orbit/api/v1"
@@ -11,7 +12,8 @@ import (
 	"github.com/f9-o/orbit/internal/orchestrator"
 )
 
-// PollInterval is how often metrics are collected.
+// PollInterval is how often the container set is re-listed while the event
+// stream is down. Stats themselves arrive on per-container streams.
 const PollInterval = 2 * time.Second
 
 // Snapshot holds the most recent metrics for all services on a node.
@@ -34,29 +36,76 @@ func (s *Snapshot) set(m v1.Metrics) {
 	s.mu.Unlock()
 }
 
-// Collector polls Docker stats continuously and publishes to a Snapshot.
+// DiskInterval is how often writable-layer sizes are measured. The daemon
+// walks each layer to size it, so this is much slower than the stats stream.
+const DiskInterval = time.Minute
+
+// containerRuntime is the part of *orchestrator.Client the Collector uses.
+type containerRuntime interface {
+	orchestrator.EventSource
+	StreamStats(ctx context.Context, idOrName string, fn func(v1.ServiceMetrics)) error
+	DataRoot(ctx context.Context) (string, error)
+	ContainerDiskUsage(ctx context.Context, idOrName string) (int64, error)
+}
+
+// Collector keeps one streaming stats reader per running orbit container,
+// through an orchestrator.Follower, and publishes per-service totals to
+// Snapshots.
 type Collector struct {
-	docker    *orchestrator.Client
+	docker    containerRuntime
 	node      string
 	snapshots map[string]*Snapshot // service name → snapshot
-	mu        sync.RWMutex
+	samples   map[string]sample    // container ID → latest reading
+	disk      map[string]int64     // container ID → writable layer size
+	host      *v1.HostMetrics      // nil until the first host reading
+	mu        sync.RWMutex         // guards snapshots, samples, disk, and host
 	log       *logger.Logger
+
+	follow     *orchestrator.Follower
+	ownsFollow bool        // Run runs follow; false when it is shared
+	diskBusy   atomic.Bool // a disk sweep is still running
+}
+
+// sample is the latest reading from one container.
+type sample struct {
+	service string
+	metrics v1.ServiceMetrics
 }
 
 // NewCollector constructs a Collector for a given Docker node.
 func NewCollector(docker *orchestrator.Client, node string, log *logger.Logger) *Collector {
-	return &Collector{
+	return newCollector(docker, node, log)
+}
+
+func newCollector(docker containerRuntime, node string, log *logger.Logger) *Collector {
+	c := &Collector{
 		docker:    docker,
 		node:      node,
 		snapshots: make(map[string]*Snapshot),
+		samples:   make(map[string]sample),
+		disk:      make(map[string]int64),
 		log:       log,
 	}
+	c.follow = orchestrator.NewFollower(docker, "metrics collect", PollInterval, log).Watch(c.stream, c.forget)
+	c.ownsFollow = true
+	return c
+}
+
+// WithFollower reads containers through f, shared with other consumers,
+// instead of a Follower of the Collector's own. The caller runs f.
+func (c *Collector) WithFollower(f *orchestrator.Follower) *Collector {
+	c.follow, c.ownsFollow = f.Watch(c.stream, c.forget), false
+	return c
 }
 
 // GetSnapshot returns the Snapshot for a service, creating it if needed.
 func (c *Collector) GetSnapshot(service string) *Snapshot {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.snapshotLocked(service)
+}
+
+func (c *Collector) snapshotLocked(service string) *Snapshot {
 	if _, ok := c.snapshots[service]; !ok {
 		c.snapshots[service] = &Snapshot{}
 	}
@@ -65,47 +114,142 @@ func (c *Collector) GetSnapshot(service string) *Snapshot {
 
 // Run starts the collection loop. Blocks until ctx is cancelled.
 func (c *Collector) Run(ctx context.Context) {
-	ticker := time.NewTicker(PollInterval)
-	defer ticker.Stop()
+	hostTicker := time.NewTicker(HostInterval)
+	defer hostTicker.Stop()
+	diskTicker := time.NewTicker(DiskInterval)
+	defer diskTicker.Stop()
 
+	if c.ownsFollow {
+		followed := make(chan struct{})
+		go func() { c.follow.Run(ctx); close(followed) }()
+		defer func() { <-followed }()
+	}
+
+	root := c.dataRoot(ctx)
+	c.sampleHost(root)
+	c.sampleDisk(ctx)
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			c.collect(ctx)
+		case <-hostTicker.C:
+			c.sampleHost(root)
+		case <-diskTicker.C:
+			c.sampleDisk(ctx)
 		}
 	}
 }
 
-func (c *Collector) collect(ctx context.Context) {
-	containers, err := c.docker.ListContainers(ctx, "")
+// dataRoot asks the runtime for its data root, falling back to DefaultDataRoot.
+func (c *Collector) dataRoot(ctx context.Context) string {
+	root, err := c.docker.DataRoot(ctx)
+	if err != nil || root == "" {
+		c.log.Debug("metrics collect: data root", "err", err)
+		return DefaultDataRoot
+	}
+	return root
+}
+
+// sampleHost records a local host reading. Failures (non-Linux hosts, a data
+// root this user cannot stat) leave the previous reading in place.
+func (c *Collector) sampleHost(root string) {
+	h, err := LocalHost(root)
 	if err != nil {
-		c.log.Debug("metrics collect: list containers", "err", err)
+		c.log.Debug("metrics collect: host", "err", err)
 		return
 	}
+	c.mu.Lock()
+	c.host = &h
+	c.mu.Unlock()
+}
 
-	for _, ctr := range containers {
-		serviceName := ctr.Labels["orbit.service"]
-		if serviceName == "" {
-			continue
+// sampleDisk measures the writable layer of every tracked container in the
+// background, skipping the round if the previous one has not finished.
+func (c *Collector) sampleDisk(ctx context.Context) {
+	if !c.diskBusy.CompareAndSwap(false, true) {
+		return
+	}
+	ids := c.follow.IDs()
+	go func() {
+		defer c.diskBusy.Store(false)
+		for _, id := range ids {
+			size, err := c.docker.ContainerDiskUsage(ctx, id)
+			if err != nil {
+				c.log.Debug("metrics collect: disk usage", "container", id[:min(12, len(id))], "err", err)
+				continue
+			}
+			c.mu.Lock()
+			if s, ok := c.samples[id]; ok {
+				c.disk[id] = size
+				c.publishLocked(s.service)
+			}
+			c.mu.Unlock()
 		}
+	}()
+}
 
-		stats, err := c.docker.ContainerStats(ctx, ctr.ID)
-		if err != nil {
-			c.log.Debug("metrics collect: stats", "container", ctr.ID[:12], "err", err)
+// stream returns the reader of a container's stats stream.
+func (c *Collector) stream(ctr orchestrator.Followed) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return c.docker.StreamStats(ctx, ctr.ID, func(m v1.ServiceMetrics) {
+			c.record(ctx, ctr.ID, ctr.Service, m)
+		})
+	}
+}
+
+// forget removes a stopped container's reading from the totals.
+func (c *Collector) forget(id string, _ bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.disk, id)
+	if s, ok := c.samples[id]; ok {
+		delete(c.samples, id)
+		c.publishLocked(s.service)
+	}
+}
+
+// record stores a container's reading and republishes its service's totals.
+// Checking ctx under the lock drops a late sample from a reader that has
+// already been stopped.
+func (c *Collector) record(ctx context.Context, id, service string, m v1.ServiceMetrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	c.samples[id] = sample{service: service, metrics: m}
+	c.publishLocked(service)
+}
+
+// publishLocked sums the readings of a service's containers into its
+// snapshot, dropping the snapshot once no container reports. c.mu must be held.
+func (c *Collector) publishLocked(service string) {
+	var total v1.ServiceMetrics
+	n := 0
+	for id, s := range c.samples {
+		if s.service != service {
 			continue
 		}
-
-		snap := c.GetSnapshot(serviceName)
-		snap.set(v1.Metrics{
-			Timestamp: time.Now().UTC(),
-			Node:      c.node,
-			Services: map[string]v1.ServiceMetrics{
-				serviceName: stats,
-			},
-		})
+		n++
+		total.BlockReadBytes += s.metrics.BlockReadBytes
+		total.BlockWriteBytes += s.metrics.BlockWriteBytes
+		total.DiskBytes += c.disk[id]
+		total.CPUPercent += s.metrics.CPUPercent
+		total.MemBytes += s.metrics.MemBytes
+		total.MemLimit += s.metrics.MemLimit
+		total.NetRxBytes += s.metrics.NetRxBytes
+		total.NetTxBytes += s.metrics.NetTxBytes
+		total.PIDs += s.metrics.PIDs
 	}
+	if n == 0 {
+		delete(c.snapshots, service)
+		return
+	}
+	c.snapshotLocked(service).set(v1.Metrics{
+		Timestamp: time.Now().UTC(),
+		Node:      c.node,
+		Services:  map[string]v1.ServiceMetrics{service: total},
+	})
 }
 
 // AllMetrics returns a combined Metrics snapshot across all known services.
@@ -118,6 +262,10 @@ func (c *Collector) AllMetrics() v1.Metrics {
 		Node:      c.node,
 		Services:  make(map[string]v1.ServiceMetrics),
 	}
+	if c.host != nil {
+		h := *c.host
+		m.Host = &h
+	}
 	for name, snap := range c.snapshots {
 		data := snap.Get()
 		if svc, ok := data.Services[name]; ok {
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+// fakeRuntime reports a fixed reading per container and holds each stats
+// stream open until its reader is detached.
+type fakeRuntime struct {
+	mem    map[string]int64
+	events chan v1.ContainerEvent
+}
+
+func (f *fakeRuntime) ListContainers(context.Context, string) ([]types.Container, error) {
+	return nil, nil
+}
+
+func (f *fakeRuntime) ContainerEvents(context.Context) (<-chan v1.ContainerEvent, <-chan error) {
+	return f.events, nil
+}
+
+func (f *fakeRuntime) DataRoot(context.Context) (string, error) {
+	return "", nil
+}
+
+func (f *fakeRuntime) ContainerDiskUsage(_ context.Context, id string) (int64, error) {
+	return f.mem[id] * 100, nil
+}
+
+func (f *fakeRuntime) StreamStats(ctx context.Context, id string, fn func(v1.ServiceMetrics)) error {
+	fn(v1.ServiceMetrics{MemBytes: f.mem[id], PIDs: 1})
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func waitFor(t *testing.T, c *Collector, cond func(v1.Metrics) bool) v1.Metrics {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		m := c.AllMetrics()
+		if cond(m) {
+			return m
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("metrics = %+v", m.Services)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCollectorSumsReplicasAndDetaches(t *testing.T) {
+	log, _ := logger.Init("error", "text", "", "", false)
+	rt := &fakeRuntime{mem: map[string]int64{"a1": 10, "a2": 20, "w1": 5}, events: make(chan v1.ContainerEvent)}
+	c := newCollector(rt, "local", log)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { c.Run(ctx); close(done) }()
+	defer func() { cancel(); <-done }()
+
+	rt.events <- v1.ContainerEvent{ID: "a1", Service: "api", Action: "start"}
+	rt.events <- v1.ContainerEvent{ID: "a2", Service: "api", Action: "start"}
+	rt.events <- v1.ContainerEvent{ID: "t1", Service: "api", Task: true, Action: "start"}
+	rt.events <- v1.ContainerEvent{ID: "w1", Service: "web", Action: "start"}
+	waitFor(t, c, func(m v1.Metrics) bool {
+		return m.Services["api"].MemBytes == 30 && m.Services["api"].PIDs == 2 && m.Services["web"].MemBytes == 5
+	})
+	if ids := c.follow.IDs(); len(ids) != 3 {
+		t.Fatalf("readers = %v, want 3 (task containers are skipped)", ids)
+	}
+	c.sampleDisk(ctx)
+	waitFor(t, c, func(m v1.Metrics) bool { return m.Services["api"].DiskBytes == 3000 })
+
+	rt.events <- v1.ContainerEvent{ID: "a1", Service: "api", Action: "die"}
+	rt.events <- v1.ContainerEvent{ID: "w1", Service: "web", Action: "die"}
+	m := waitFor(t, c, func(m v1.Metrics) bool { _, web := m.Services["web"]; return !web })
+	if m.Services["api"].MemBytes != 20 {
+		t.Errorf("api = %+v, want only a2's reading", m.Services["api"])
+	}
+	if ids := c.follow.IDs(); len(ids) != 1 {
+		t.Errorf("readers = %v, want 1", ids)
+	}
+}
@@ -0,0 +1,167 @@
+// Package metrics: host-level load, memory, disk, and inode readings.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// HostInterval is how often the collector samples the local host.
+const HostInterval = 10 * time.Second
+
+// DefaultDataRoot is assumed when the runtime does not report its data root.
+const DefaultDataRoot = "/var/lib/docker"
+
+// HostScript prints the inputs of ParseHostReport on a remote Linux node. It
+// always exits 0 so that a node without docker or /proc still heartbeats.
+const HostScript = `r=$(docker info --format '{{.DockerRootDir}}' 2>/dev/null); [ -d "$r" ] || r=` + DefaultDataRoot + `; [ -d "$r" ] || r=/
+echo "== root"; echo "$r"
+echo "== loadavg"; cat /proc/loadavg 2>/dev/null
+echo "== meminfo"; cat /proc/meminfo 2>/dev/null
+echo "== df"; df -Pk "$r" 2>/dev/null
+echo "== dfi"; df -Pi "$r" 2>/dev/null
+exit 0`
+
+// ParseHostReport parses the output of HostScript. Sections that are missing
+// or unreadable leave their fields zero; an error means nothing was usable.
+func ParseHostReport(out string) (v1.HostMetrics, error) {
+	sections := map[string]string{}
+	var name string
+	var body strings.Builder
+	flush := func() {
+		if name != "" {
+			sections[name] = body.String()
+		}
+		body.Reset()
+	}
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if rest, ok := strings.CutPrefix(line, "== "); ok {
+			flush()
+			name = strings.TrimSpace(rest)
+			continue
+		}
+		body.WriteString(line + "\n")
+	}
+	flush()
+
+	h := v1.HostMetrics{DiskPath: strings.TrimSpace(sections["root"]), CollectedAt: time.Now().UTC()}
+	okLoad := parseLoadavg(sections["loadavg"], &h) == nil
+	okMem := parseMeminfo(sections["meminfo"], &h) == nil
+	h.DiskTotal, h.DiskFree, _ = parseDf(sections["df"], 1024)
+	h.InodesTotal, h.InodesFree, _ = parseDf(sections["dfi"], 1)
+	if !okLoad && !okMem && h.DiskTotal == 0 {
+		return v1.HostMetrics{}, fmt.Errorf("host report: no readable metrics")
+	}
+	return h, nil
+}
+
+// LocalHost reads host metrics for this machine; root is the runtime's data
+// root whose filesystem is measured.
+func LocalHost(root string) (v1.HostMetrics, error) {
+	if root == "" {
+		root = DefaultDataRoot
+	}
+	h := v1.HostMetrics{DiskPath: root, CollectedAt: time.Now().UTC()}
+	loadavg, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return v1.HostMetrics{}, fmt.Errorf("host metrics: %w", err)
+	}
+	if err := parseLoadavg(string(loadavg), &h); err != nil {
+		return v1.HostMetrics{}, err
+	}
+	meminfo, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return v1.HostMetrics{}, fmt.Errorf("host metrics: %w", err)
+	}
+	if err := parseMeminfo(string(meminfo), &h); err != nil {
+		return v1.HostMetrics{}, err
+	}
+	if err := statDisk(root, &h); err != nil {
+		return v1.HostMetrics{}, fmt.Errorf("host metrics: statfs %s: %w", root, err)
+	}
+	return h, nil
+}
+
+// UsedPercent returns the used share of total, or 0 when total is unknown.
+func UsedPercent(total, free int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(total-free) / float64(total) * 100
+}
+
+// parseLoadavg reads the three load averages from /proc/loadavg.
+func parseLoadavg(s string, h *v1.HostMetrics) error {
+	f := strings.Fields(s)
+	if len(f) < 3 {
+		return fmt.Errorf("loadavg: unexpected format %q", strings.TrimSpace(s))
+	}
+	var err error
+	loads := [3]*float64{&h.Load1, &h.Load5, &h.Load15}
+	for i, dst := range loads {
+		if *dst, err = strconv.ParseFloat(f[i], 64); err != nil {
+			return fmt.Errorf("loadavg: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseMeminfo reads MemTotal and MemAvailable (kB) from /proc/meminfo.
+func parseMeminfo(s string, h *v1.HostMetrics) error {
+	found := 0
+	for _, line := range strings.Split(s, "\n") {
+		key, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		var dst *int64
+		switch key {
+		case "MemTotal":
+			dst = &h.MemTotal
+		case "MemAvailable":
+			dst = &h.MemAvailable
+		default:
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(rest), " kB"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("meminfo %s: %w", key, err)
+		}
+		*dst = kb * 1024
+		found++
+	}
+	if found < 2 {
+		return fmt.Errorf("meminfo: MemTotal/MemAvailable not found")
+	}
+	return nil
+}
+
+// parseDf reads total and free from the data line of `df -P` (unit bytes per
+// block) or `df -Pi` (unit 1). Columns are counted from the right, since the
+// filesystem name may contain spaces.
+func parseDf(s string, unit int64) (total, free int64, err error) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) < 2 {
+		return 0, 0, fmt.Errorf("df: no data line")
+	}
+	f := strings.Fields(lines[len(lines)-1])
+	if len(f) < 6 {
+		return 0, 0, fmt.Errorf("df: unexpected format %q", lines[len(lines)-1])
+	}
+	n := len(f)
+	if total, err = strconv.ParseInt(f[n-5], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("df: %w", err)
+	}
+	if free, err = strconv.ParseInt(f[n-3], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("df: %w", err)
+	}
+	return total * unit, free * unit, nil
+}
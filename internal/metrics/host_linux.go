@@ -0,0 +1,22 @@
+//go:build linux
+
+package metrics
+
+import (
+	"syscall"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// statDisk fills disk and inode figures for the filesystem holding path.
+func statDisk(path string, h *v1.HostMetrics) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return err
+	}
+	h.DiskTotal = int64(st.Blocks) * st.Bsize
+	h.DiskFree = int64(st.Bavail) * st.Bsize
+	h.InodesTotal = int64(st.Files)
+	h.InodesFree = int64(st.Ffree)
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package metrics
+
+import (
+	"errors"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// statDisk is unreachable off Linux: LocalHost fails on /proc first.
+func statDisk(path string, h *v1.HostMetrics) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+const hostReport = `== root
+/var/lib/docker
+== loadavg
+0.52 0.41 0.30 2/811 12345
+== meminfo
+MemTotal:        8000000 kB
+MemFree:          500000 kB
+MemAvailable:    2000000 kB
+== df
+Filesystem     1024-blocks     Used Available Capacity Mounted on
+/dev/sda1        100000000 70000000  30000000      70% /
+== dfi
+Filesystem      Inodes  IUsed   IFree IUse% Mounted on
+/dev/sda1      6000000 600000 5400000   10% /
+`
+
+func TestParseHostReport(t *testing.T) {
+	h, err := ParseHostReport(hostReport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Load1 != 0.52 || h.Load15 != 0.30 {
+		t.Errorf("load = %v %v %v", h.Load1, h.Load5, h.Load15)
+	}
+	if h.MemTotal != 8000000*1024 || h.MemAvailable != 2000000*1024 {
+		t.Errorf("mem = %d/%d", h.MemAvailable, h.MemTotal)
+	}
+	if h.DiskPath != "/var/lib/docker" || h.DiskTotal != 100000000*1024 || h.DiskFree != 30000000*1024 {
+		t.Errorf("disk = %+v", h)
+	}
+	if h.InodesTotal != 6000000 || h.InodesFree != 5400000 {
+		t.Errorf("inodes = %d/%d", h.InodesFree, h.InodesTotal)
+	}
+	if got := UsedPercent(h.MemTotal, h.MemAvailable); got != 75 {
+		t.Errorf("mem used = %v%%, want 75", got)
+	}
+
+	// A node without /proc or df still heartbeats; its report is just unusable.
+	if _, err := ParseHostReport("== root\n/\n== loadavg\n== meminfo\n== df\n== dfi\n"); err == nil {
+		t.Error("empty report parsed without error")
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	m := v1.Metrics{
+		Node:     "local",
+		Services: map[string]v1.ServiceMetrics{"api": {CPUPercent: 12.5, MemBytes: 1024}},
+		Host:     &v1.HostMetrics{Load1: 0.5, DiskPath: "/var/lib/docker", DiskTotal: 100},
+	}
+	nodes := []v1.NodeInfo{
+		{Spec: v1.NodeSpec{Name: "prod-01"}, Host: &v1.HostMetrics{Load1: 2}},
+		{Spec: v1.NodeSpec{Name: "prod-02"}}, // never heartbeated
+	}
+	var buf bytes.Buffer
+	if err := WritePrometheus(&buf, m, nodes); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE orbit_service_cpu_percent gauge\n",
+		`orbit_service_cpu_percent{node="local",service="api"} 12.5`,
+		`orbit_host_load1{node="local"} 0.5`,
+		`orbit_host_load1{node="prod-01"} 2`,
+		`orbit_host_disk_total_bytes{node="local",path="/var/lib/docker"} 100`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "prod-02") {
+		t.Error("node without a host reading was exported")
+	}
+}
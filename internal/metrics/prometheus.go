@@ -0,0 +1,126 @@
+// Package metrics: Prometheus text exposition of service and host metrics.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// promMetric describes one exposed metric family.
+type promMetric struct {
+	name, help, kind string
+}
+
+var (
+	promServiceCPU    = promMetric{"orbit_service_cpu_percent", "CPU usage of the service's containers, in percent of one CPU.", "gauge"}
+	promServiceMem    = promMetric{"orbit_service_memory_bytes", "Memory used by the service's containers.", "gauge"}
+	promServiceMemLim = promMetric{"orbit_service_memory_limit_bytes", "Memory limit of the service's containers.", "gauge"}
+	promServiceRx     = promMetric{"orbit_service_network_receive_bytes_total", "Bytes received on eth0.", "counter"}
+	promServiceTx     = promMetric{"orbit_service_network_transmit_bytes_total", "Bytes sent on eth0.", "counter"}
+	promServicePIDs   = promMetric{"orbit_service_pids", "Processes running in the service's containers.", "gauge"}
+	promServiceBlkR   = promMetric{"orbit_service_block_read_bytes_total", "Bytes read from block devices.", "counter"}
+	promServiceBlkW   = promMetric{"orbit_service_block_write_bytes_total", "Bytes written to block devices.", "counter"}
+	promServiceDisk   = promMetric{"orbit_service_disk_bytes", "Size of the containers' writable layers.", "gauge"}
+
+	promHostLoad1    = promMetric{"orbit_host_load1", "1-minute load average.", "gauge"}
+	promHostLoad5    = promMetric{"orbit_host_load5", "5-minute load average.", "gauge"}
+	promHostLoad15   = promMetric{"orbit_host_load15", "15-minute load average.", "gauge"}
+	promHostMemTotal = promMetric{"orbit_host_memory_total_bytes", "Total host memory.", "gauge"}
+	promHostMemAvail = promMetric{"orbit_host_memory_available_bytes", "Host memory available for new work.", "gauge"}
+	promHostDisk     = promMetric{"orbit_host_disk_total_bytes", "Size of the filesystem holding the runtime data root.", "gauge"}
+	promHostDiskFree = promMetric{"orbit_host_disk_free_bytes", "Free space on the filesystem holding the runtime data root.", "gauge"}
+	promHostInodes   = promMetric{"orbit_host_inodes_total", "Inodes on the filesystem holding the runtime data root.", "gauge"}
+	promHostInoFree  = promMetric{"orbit_host_inodes_free", "Free inodes on the filesystem holding the runtime data root.", "gauge"}
+)
+
+// promSample is one labelled value.
+type promSample struct {
+	labels string
+	value  float64
+}
+
+// WritePrometheus writes m, its host reading, and the host readings of
+// nodes in the Prometheus text exposition format.
+func WritePrometheus(w io.Writer, m v1.Metrics, nodes []v1.NodeInfo) error {
+	families := map[promMetric][]promSample{}
+	add := func(metric promMetric, labels string, v float64) {
+		families[metric] = append(families[metric], promSample{labels, v})
+	}
+
+	services := make([]string, 0, len(m.Services))
+	for name := range m.Services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	for _, name := range services {
+		s := m.Services[name]
+		l := promLabels("node", m.Node, "service", name)
+		add(promServiceCPU, l, s.CPUPercent)
+		add(promServiceMem, l, float64(s.MemBytes))
+		add(promServiceMemLim, l, float64(s.MemLimit))
+		add(promServiceRx, l, float64(s.NetRxBytes))
+		add(promServiceTx, l, float64(s.NetTxBytes))
+		add(promServicePIDs, l, float64(s.PIDs))
+		add(promServiceBlkR, l, float64(s.BlockReadBytes))
+		add(promServiceBlkW, l, float64(s.BlockWriteBytes))
+		add(promServiceDisk, l, float64(s.DiskBytes))
+	}
+
+	addHost := func(node string, h *v1.HostMetrics) {
+		if h == nil {
+			return
+		}
+		l := promLabels("node", node)
+		add(promHostLoad1, l, h.Load1)
+		add(promHostLoad5, l, h.Load5)
+		add(promHostLoad15, l, h.Load15)
+		add(promHostMemTotal, l, float64(h.MemTotal))
+		add(promHostMemAvail, l, float64(h.MemAvailable))
+		dl := promLabels("node", node, "path", h.DiskPath)
+		add(promHostDisk, dl, float64(h.DiskTotal))
+		add(promHostDiskFree, dl, float64(h.DiskFree))
+		add(promHostInodes, dl, float64(h.InodesTotal))
+		add(promHostInoFree, dl, float64(h.InodesFree))
+	}
+	addHost(m.Node, m.Host)
+	for _, n := range nodes {
+		if n.Spec.Name != m.Node {
+			addHost(n.Spec.Name, n.Host)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, metric := range []promMetric{
+		promServiceCPU, promServiceMem, promServiceMemLim, promServiceRx, promServiceTx, promServicePIDs,
+		promServiceBlkR, promServiceBlkW, promServiceDisk,
+		promHostLoad1, promHostLoad5, promHostLoad15, promHostMemTotal, promHostMemAvail,
+		promHostDisk, promHostDiskFree, promHostInodes, promHostInoFree,
+	} {
+		samples := families[metric]
+		if len(samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, s := range samples {
+			fmt.Fprintf(bw, "%s{%s} %g\n", metric.name, s.labels, s.value)
+		}
+	}
+	return bw.Flush()
+}
+
+// promLabels renders alternating name/value pairs as a label set.
+func promLabels(kv ...string) string {
+	parts := make([]string, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		parts = append(parts, kv[i]+`="`+promEscape.Replace(kv[i+1])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+// promEscape escapes label values as the exposition format requires.
+var promEscape = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,126 @@
+// Package orchestrator: CPU-driven replica autoscaling for deploy.autoscale.
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+// Cooldowns used when deploy.autoscale leaves them unset. Scaling down waits
+// longer so a brief lull does not shed capacity the next spike needs.
+const (
+	DefaultScaleUpCooldown   = time.Minute
+	DefaultScaleDownCooldown = 5 * time.Minute
+)
+
+// AutoscaleInterval is how often `orbit agent` evaluates autoscaled services.
+const AutoscaleInterval = 30 * time.Second
+
+// autoscaleTolerance is the relative distance from the CPU target within
+// which the replica count is left alone.
+const autoscaleTolerance = 0.1
+
+// ScaleDecision is one replica change made by the Autoscaler.
+type ScaleDecision struct {
+	Service string
+	From    int
+	To      int
+	Reason  string
+	Err     error
+	Time    time.Time
+}
+
+// Autoscaler adjusts the replica count of services with deploy.autoscale
+// from their CPU readings. It is not safe for concurrent use.
+type Autoscaler struct {
+	scaler *Scaler
+	node   string
+	log    *logger.Logger
+	last   map[string]time.Time // service → last scaling attempt
+}
+
+// NewAutoscaler constructs an Autoscaler that scales services on node.
+func NewAutoscaler(scaler *Scaler, node string, log *logger.Logger) *Autoscaler {
+	return &Autoscaler{scaler: scaler, node: node, log: log, last: map[string]time.Time{}}
+}
+
+// Evaluate scales every autoscaled service in specs whose CPU, averaged over
+// its replicas, is outside the target band and whose cooldown has passed.
+// Services without a reading in m, or scaled to zero, are left alone.
+func (a *Autoscaler) Evaluate(ctx context.Context, specs []v1.ServiceSpec, m v1.Metrics, now time.Time) []ScaleDecision {
+	var decisions []ScaleDecision
+	for _, spec := range specs {
+		if spec.Deploy == nil || spec.Deploy.Autoscale == nil {
+			continue
+		}
+		as := *spec.Deploy.Autoscale
+		usage, ok := m.Services[spec.Name]
+		if !ok {
+			continue
+		}
+		current, err := a.scaler.Replicas(ctx, spec.Name)
+		if err != nil {
+			a.log.Warn("autoscale: list replicas failed", "service", spec.Name, "err", err)
+			continue
+		}
+		if current == 0 {
+			continue
+		}
+		target := DesiredReplicas(as, current, usage.CPUPercent)
+		if target == current {
+			continue
+		}
+		cooldown := cooldownOr(as.ScaleUpCooldown, DefaultScaleUpCooldown)
+		if target < current {
+			cooldown = cooldownOr(as.ScaleDownCooldown, DefaultScaleDownCooldown)
+		}
+		if now.Sub(a.last[spec.Name]) < cooldown {
+			continue
+		}
+
+		reason := fmt.Sprintf("autoscale: cpu %.0f%% per replica, target %g%%",
+			usage.CPUPercent/float64(current), as.TargetCPU)
+		a.log.Info("autoscale", "service", spec.Name, "from", current, "to", target, "reason", reason)
+		err = a.scaler.scale(ctx, spec, a.node, target, reason)
+		a.last[spec.Name] = now
+		decisions = append(decisions, ScaleDecision{
+			Service: spec.Name, From: current, To: target, Reason: reason, Err: err, Time: now,
+		})
+	}
+	return decisions
+}
+
+// DesiredReplicas returns the replica count that brings CPU usage per replica
+// to the target, given totalCPU across current replicas, clamped to
+// [min_replicas, max_replicas].
+func DesiredReplicas(as v1.AutoscaleSpec, current int, totalCPU float64) int {
+	desired := current
+	if as.TargetCPU > 0 && current > 0 {
+		ratio := totalCPU / float64(current) / as.TargetCPU
+		if math.Abs(ratio-1) > autoscaleTolerance {
+			desired = int(math.Ceil(totalCPU / as.TargetCPU))
+		}
+	}
+	return clampReplicas(as, desired)
+}
+
+// clampReplicas bounds n to the autoscale range; min_replicas is at least 1.
+func clampReplicas(as v1.AutoscaleSpec, n int) int {
+	n = max(n, as.MinReplicas, 1)
+	if as.MaxReplicas > 0 {
+		n = min(n, as.MaxReplicas)
+	}
+	return n
+}
+
+func cooldownOr(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,46 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+func TestDesiredReplicas(t *testing.T) {
+	as := v1.AutoscaleSpec{MinReplicas: 2, MaxReplicas: 6, TargetCPU: 50}
+	tests := []struct {
+		name     string
+		current  int
+		totalCPU float64
+		want     int
+	}{
+		{"on target", 2, 100, 2},
+		{"within tolerance", 2, 108, 2},
+		{"scale up", 2, 240, 5},
+		{"capped at max", 3, 900, 6},
+		{"scale down", 4, 60, 2},
+		{"floored at min", 4, 10, 2},
+		{"raised to min", 1, 20, 2},
+	}
+	for _, tt := range tests {
+		if got := DesiredReplicas(as, tt.current, tt.totalCPU); got != tt.want {
+			t.Errorf("%s: DesiredReplicas(%d, %g) = %d, want %d", tt.name, tt.current, tt.totalCPU, got, tt.want)
+		}
+	}
+}
+
+func TestDeployKeepsAutoscaledCount(t *testing.T) {
+	spec := v1.ServiceSpec{Name: "web", Deploy: &v1.DeploySpec{
+		Replicas:  1,
+		Autoscale: &v1.AutoscaleSpec{MinReplicas: 1, MaxReplicas: 4, TargetCPU: 50},
+	}}
+	running := make([]types.Container, 3)
+	if got := desiredReplicas(spec, nil, running); got != 3 {
+		t.Errorf("desiredReplicas = %d, want the 3 running replicas", got)
+	}
+	if got := desiredReplicas(spec, nil, make([]types.Container, 6)); got != 4 {
+		t.Errorf("desiredReplicas = %d, want max_replicas 4", got)
+	}
+}
@@ -4,12 +4,20 @@ package orchestrator
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/docker/docker/api/types"
+	"go.opentelemetry.io/otel/attribute"
+
 	v1 "github.com/f9-o/orbit/api/v1"
 	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/shutdown"
 	"github.com/f9-o/orbit/internal/core/state"
 	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/internal/telemetry"
 	"github.com/f9-o/orbit/pkg/errs"
 )
 
@@ -18,21 +26,60 @@ type DeployOptions struct {
 	Tag     string        // image tag override
 	Timeout time.Duration // health check timeout per replica
 	DryRun  bool
+	Reason  string // recorded in history: why the deploy was made
+	Commit  string // recorded in history: the git commit of orbit.yaml being applied
+
+	action string // recorded in history; set by Rollback
 }
 
 // DefaultDeployTimeout is used when no timeout is specified.
 const DefaultDeployTimeout = 120 * time.Second
 
+// DeployStep identifies a phase of a rolling deploy, reported via WithProgress.
+type DeployStep string
+
+const (
+	StepPull     DeployStep = "pull"
+	StepStart    DeployStep = "start"
+	StepHealth   DeployStep = "healthcheck"
+	StepCutover  DeployStep = "cutover"
+	StepRollback DeployStep = "rollback"
+
+	// StepVerify follows the last cut-over when deploy.verify is set.
+	StepVerify DeployStep = "verify"
+
+	// StepMigrate runs the service's migrations container before the first
+	// replica of a new image starts, when it has one.
+	StepMigrate DeployStep = "migrate"
+
+	// StepPreDeploy and StepPostDeploy run the service's hooks: commands,
+	// when it has some: before the pull, and after cut-over and verification.
+	StepPreDeploy  DeployStep = "pre_deploy"
+	StepPostDeploy DeployStep = "post_deploy"
+)
+
+// DeploySteps is the ordered list of steps in a successful deploy.
+var DeploySteps = []DeployStep{StepPull, StepStart, StepHealth, StepCutover}
+
 // Deployer orchestrates rolling updates for a single service.
 type Deployer struct {
-	docker  *Client
-	state   *state.DB
-	checker *health.Checker
-	log     *logger.Logger
+	docker   Runtime
+	state    *state.DB
+	checker  *health.Checker
+	log      *logger.Logger
+	progress func(DeployStep)
+	hooks    v1.HookDispatcher
+	commands CommandHooks
+	policies []Policy
 }
 
+// Policy decides whether image may be deployed as spec, before anything is
+// pulled or started; an error refuses the deploy. Rollbacks are not checked:
+// they return to an image that already ran.
+type Policy func(ctx context.Context, spec v1.ServiceSpec, image string) error
+
 // NewDeployer constructs a Deployer.
-func NewDeployer(docker *Client, db *state.DB, checker *health.Checker, log *logger.Logger) *Deployer {
+func NewDeployer(docker Runtime, db *state.DB, checker *health.Checker, log *logger.Logger) *Deployer {
 	return &Deployer{
 		docker:  docker,
 		state:   db,
@@ -41,17 +88,68 @@ func NewDeployer(docker *Client, db *state.DB, checker *health.Checker, log *log
 	}
 }
 
-// Deploy performs a rolling update for spec on the given node.
-// If RollbackOnFailure is set and a health check fails, the old container is restarted.
-func (d *Deployer) Deploy(ctx context.Context, spec v1.ServiceSpec, node string, opts DeployOptions) error {
-	image := spec.Image
-	if opts.Tag != "" {
-		if idx := lastColonIdx(image); idx != -1 {
-			image = image[:idx+1] + opts.Tag
-		} else {
-			image = image + ":" + opts.Tag
-		}
+// WithProgress registers fn to be called as each deploy step begins.
+func (d *Deployer) WithProgress(fn func(DeployStep)) *Deployer {
+	d.progress = fn
+	return d
+}
+
+// WithHooks fires OnPreDeploy and OnPostDeploy at hooks around each deploy.
+func (d *Deployer) WithHooks(hooks v1.HookDispatcher) *Deployer {
+	d.hooks = hooks
+	return d
+}
+
+// WithCommandHooks runs services' pre_deploy and post_deploy commands at
+// commands. A failing pre_deploy command refuses the deploy; a failing
+// post_deploy command rolls the new replicas back.
+func (d *Deployer) WithCommandHooks(commands CommandHooks) *Deployer {
+	d.commands = commands
+	return d
+}
+
+// WithPolicy checks every image against policy before deploying it, after
+// the policies added before it.
+func (d *Deployer) WithPolicy(policy Policy) *Deployer {
+	d.policies = append(d.policies, policy)
+	return d
+}
+
+// runHook reports step and runs the service's commands for hook, if it has
+// any.
+func (d *Deployer) runHook(ctx context.Context, step DeployStep, hook string, hctx v1.HookContext, containerID string) error {
+	if d.commands == nil || len(hctx.Service.Hooks.Commands(hook)) == 0 {
+		return nil
 	}
+	d.step(step)
+	return runCommandHooks(ctx, d.commands, hook, hctx, containerID)
+}
+
+func (d *Deployer) step(s DeployStep) {
+	if d.progress != nil {
+		d.progress(s)
+	}
+}
+
+// Deploy performs a rolling update of spec's replica set on the given node,
+// replacing deploy.max_surge replicas at a time. If a batch fails its health
+// check its replacements are removed and the remaining old replicas keep
+// serving; with RollbackOnFailure, batches already cut over are returned to
+// their previous image. With deploy.verify, the new replicas are then watched
+// for a window and all of them rolled back if they regress.
+func (d *Deployer) Deploy(ctx context.Context, spec v1.ServiceSpec, node string, opts DeployOptions) (err error) {
+	image := ResolveImage(spec.Image, opts.Tag)
+
+	action := v1.DeployActionDeploy
+	if opts.action != "" {
+		action = opts.action
+	}
+	ctx, span := telemetry.Start(ctx, "orbit."+action,
+		attribute.String("orbit.service", spec.Name),
+		attribute.String("orbit.node", node),
+		attribute.String("orbit.image", image),
+		attribute.Bool("orbit.dry_run", opts.DryRun))
+	defer func() { telemetry.End(span, err) }()
 
 	timeout := DefaultDeployTimeout
 	if opts.Timeout > 0 {
@@ -66,98 +164,437 @@ func (d *Deployer) Deploy(ctx context.Context, spec v1.ServiceSpec, node string,
 		"image", image, "dry_run", opts.DryRun,
 	)
 
+	hctx := hookContext(spec, node, map[string]string{"action": action})
+	hctx.ImageTo, hctx.DryRun = image, opts.DryRun
+
 	if opts.DryRun {
+		fireHook(ctx, d.hooks, v1.HookPreDeploy, hctx)
 		d.log.Info("deploy.dryrun — no changes made", "service", spec.Name)
 		return nil
 	}
 
+	unlock, err := lockService(ctx, d.state, node, spec.Name, "deploy")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	rec := newRecord(spec.Name, node, action)
+	rec.ToImage, rec.Reason, rec.Commit = image, opts.Reason, opts.Commit
+	defer func() { finishRecord(d.state, d.log, rec, err) }()
+
 	// Get existing container state
 	existing, err := d.state.GetServiceState(node, spec.Name)
 	if err != nil {
 		return errs.Wrap(err, errs.ErrStateRead, "deploy.getstate")
 	}
+	if existing != nil {
+		rec.FromImage = existing.Image
+	}
+	hctx.ImageFrom = rec.FromImage
+	fireHook(ctx, d.hooks, v1.HookPreDeploy, hctx)
+	defer func() { firePostHook(ctx, d.hooks, v1.HookPostDeploy, hctx, resultOf(rec, err), err) }()
+
+	if action != v1.DeployActionRollback {
+		for _, policy := range d.policies {
+			if err := policy(ctx, spec, image); err != nil {
+				return err
+			}
+		}
+	}
 
-	// 1. Pull new image
-	if err := d.docker.PullImage(ctx, image); err != nil {
-		return errs.New(errs.ErrDockerPull, "deploy.pull", err).
-			WithNode(node).
-			WithAdvice("Check your registry credentials and image name")
+	var current string
+	if existing != nil {
+		current = existing.ContainerID
+	}
+	if err := d.runHook(ctx, StepPreDeploy, v1.HookCmdPreDeploy, hctx, current); err != nil {
+		return err
 	}
 
-	// 2. Start new container with a unique temporary name
-	newName := fmt.Sprintf("%s-new-%d", spec.Name, time.Now().Unix())
-	newSpec := spec
-	newSpec.Image = image
-	if newSpec.Labels == nil {
-		newSpec.Labels = map[string]string{}
+	// 1. Pull new image, unless the change can be made to the running
+	// replicas in place
+	inPlace, ok := d.planInPlace(ctx, spec, existing, image)
+	if !ok {
+		d.step(StepPull)
+		if err := d.docker.PullImage(ctx, image); err != nil {
+			return errs.New(errs.ErrDockerPull, "deploy.pull", err).
+				WithNode(node).
+				WithAdvice("Check your registry credentials and image name")
+		}
+	}
+
+	// Migrate before the new containers start, while the old replicas still
+	// serve. A rollback does not: its image's migrations already ran.
+	if !ok && spec.Migrations != nil && action != v1.DeployActionRollback {
+		d.step(StepMigrate)
+		if err := d.migrate(ctx, spec, node, image); err != nil {
+			return err
+		}
 	}
-	newSpec.Labels["orbit.service"] = spec.Name
-	newSpec.Labels["orbit.node"] = node
 
-	newID, err := d.docker.RunContainer(ctx, newSpec, newName)
+	running, err := d.docker.ListContainers(ctx, spec.Name)
 	if err != nil {
-		return errs.New(errs.ErrDockerRun, "deploy.run", err).WithNode(node)
+		return errs.New(errs.ErrDockerRun, "deploy.list", err).WithNode(node)
 	}
+	slots, excess := replicaSlots(spec, running, desiredReplicas(spec, existing, running))
+	if err := CheckReplicaPorts(spec, len(slots)); err != nil {
+		return err
+	}
+	surge := maxSurge(spec, len(slots))
+	rec.Replicas = len(slots)
 
-	// 3. Wait for health check to pass
-	if spec.HealthCheck != nil {
-		d.log.Info("deploy.healthcheck", "service", spec.Name, "timeout", timeout)
+	roll := slots
+	if ok {
+		if roll, err = d.updateInPlace(ctx, inPlace, slots); err != nil {
+			return errs.New(errs.ErrDockerRun, "deploy.update", err).WithNode(node)
+		}
+	}
 
-		hctx, cancel := context.WithTimeout(ctx, timeout)
-		defer cancel()
+	// 2–4. Roll the replica set in batches of max_surge. Replicas outside the
+	// current batch keep serving; a batch's old containers are retired only
+	// once every replacement in it is ready.
+	var done []*replicaSlot
+	for start := 0; start < len(roll); start += surge {
+		batch := roll[start:min(start+surge, len(roll))]
+		d.log.Info("deploy.batch", "service", spec.Name,
+			"replicas", fmt.Sprintf("%d-%d/%d", start+1, start+len(batch), len(roll)))
 
-		if err := d.checker.WaitHealthy(hctx, spec, newID); err != nil {
-			d.log.Warn("deploy.healthcheck.failed", "service", spec.Name, "err", err)
+		if err := d.startBatch(ctx, spec, node, image, batch, timeout); err != nil {
+			if spec.Deploy != nil && spec.Deploy.RollbackOnFailure && len(done) > 0 {
+				d.step(StepRollback)
+				d.rollbackReplicas(ctx, spec, node, done)
+				rec.Result = v1.DeployResultRolledBack
+			}
+			return err
+		}
 
-			// Stop the new (failed) container
-			_ = d.docker.StopContainer(ctx, newID, true)
+		d.step(StepCutover)
+		for _, r := range batch {
+			d.cutover(ctx, spec, r)
+		}
+		done = append(done, batch...)
+	}
 
-			// Rollback: restart old image if enabled
-			if existing != nil && spec.Deploy != nil && spec.Deploy.RollbackOnFailure {
-				d.log.Warn("deploy.rollback", "service", spec.Name, "old_container", existing.ContainerID[:12])
-				rollbackSpec := spec
-				rollbackSpec.Image = existing.Image
-				if _, rollErr := d.docker.RunContainer(ctx, rollbackSpec, spec.Name); rollErr != nil {
-					d.log.Warn("deploy.rollback.failed", "err", rollErr)
-				}
+	// 5. Watch the new replicas while the deploy can still be undone. A
+	// rollback is not verified: it returns to an image that already ran.
+	if spec.Deploy != nil && spec.Deploy.Verify != nil && action != v1.DeployActionRollback && len(done) > 0 {
+		d.step(StepVerify)
+		if err := d.verify(ctx, spec, node, done); err != nil {
+			if errs.IsCode(err, errs.ErrServiceVerify) {
+				d.step(StepRollback)
+				d.rollbackReplicas(ctx, spec, node, done)
+				rec.Result = v1.DeployResultRolledBack
 			}
-
-			return errs.New(errs.ErrServiceHealthFail, "deploy.healthcheck", err).
-				WithNode(node).
-				WithAdvice(fmt.Sprintf("New container failed health check. Run: orbit logs %s", spec.Name))
+			return err
 		}
 	}
 
-	// 4. Stop old container
-	if existing != nil && existing.ContainerID != "" {
-		d.log.Info("deploy.stop_old", "id", existing.ContainerID[:12])
-		if err := d.docker.StopContainer(ctx, existing.ContainerID, true); err != nil {
-			d.log.Warn("deploy.stop_old.failed", "err", err)
+	// 6. Run post_deploy commands in the new primary replica; their failure
+	// undoes the deploy like a failed verification, except for a rollback.
+	primary := slots[0].newID
+	if primary == "" {
+		primary = slots[0].oldID
+	}
+	if err := d.runHook(ctx, StepPostDeploy, v1.HookCmdPostDeploy, hctx, primary); err != nil {
+		if action != v1.DeployActionRollback && len(done) > 0 {
+			d.step(StepRollback)
+			d.rollbackReplicas(ctx, spec, node, done)
+			rec.Result = v1.DeployResultRolledBack
 		}
+		return err
 	}
 
-	// 5. Rename new container to canonical name
-	if err := d.docker.docker.ContainerRename(ctx, newID, spec.Name); err != nil {
-		d.log.Warn("deploy.rename.failed", "err", err)
+	// 7. Retire replicas beyond the desired count
+	for _, c := range excess {
+		d.log.Info("deploy.stop_excess", "service", spec.Name, "id", c.ID[:12])
+		if err := d.docker.StopContainer(ctx, c.ID, true); err != nil {
+			d.log.Warn("deploy.stop_excess.failed", "err", err)
+		}
 	}
 
-	// 6. Persist state
+	// 8. Persist state
 	newState := v1.ServiceState{
 		Name:        spec.Name,
-		ContainerID: newID,
+		ContainerID: slots[0].newID,
 		Image:       image,
 		Status:      v1.StatusHealthy,
+		Replicas:    len(slots),
 		Node:        node,
 		StartedAt:   time.Now().UTC(),
+		Ready:       true,
+	}
+	if existing != nil {
+		newState.Scale = existing.Scale
+	}
+	if ctrs, err := d.docker.ListContainers(ctx, spec.Name); err == nil {
+		newState.Ports = publishedPorts(ctrs)
 	}
 	if err := d.state.PutServiceState(newState); err != nil {
 		d.log.Warn("deploy.state_persist.failed", "err", err)
 	}
 
-	d.log.Info("deploy.complete", "service", spec.Name, "image", image)
+	d.log.Info("deploy.complete", "service", spec.Name, "image", image, "replicas", len(slots))
 	return nil
 }
 
+// planInPlace reports whether deploying image is only a change Docker can
+// make to the running replicas — restart policy, networks or replica count —
+// and returns it. Images under a mutable tag such as latest are always
+// pulled and rolled out, as a deploy is how they pick up a new build.
+func (d *Deployer) planInPlace(ctx context.Context, spec v1.ServiceSpec, existing *v1.ServiceState, image string) (UpdatePlan, bool) {
+	if existing == nil || existing.ContainerID == "" || existing.Image != image || mutableTag(image) {
+		return UpdatePlan{}, false
+	}
+	spec.Image = image
+	u := PlanUpdate(NewPlanner(d.docker, d.state, d.log).diffService(ctx, spec, *existing))
+	if !u.InPlace() || u.Rename { // a misnamed primary is replaced by the rolling path
+		return UpdatePlan{}, false
+	}
+	d.log.Info("deploy.in_place", "service", spec.Name, "ops", u.String())
+	return u, true
+}
+
+// updateInPlace applies u to the slots' running containers, which keep
+// serving as their own replacements, and returns the slots still without a
+// container.
+func (d *Deployer) updateInPlace(ctx context.Context, u UpdatePlan, slots []*replicaSlot) ([]*replicaSlot, error) {
+	var empty []*replicaSlot
+	for _, r := range slots {
+		if r.oldID == "" {
+			empty = append(empty, r)
+			continue
+		}
+		if err := u.apply(ctx, d.docker, d.log, r.oldID, ""); err != nil {
+			return nil, err
+		}
+		r.newID = r.oldID
+	}
+	return empty, nil
+}
+
+// replicaSlot is one position in a service's replica set: the container
+// currently serving it (if any) and its replacement.
+type replicaSlot struct {
+	index    int    // 1-based; 1 is the primary container named after the service
+	name     string // canonical container name
+	oldID    string
+	oldImage string
+	newID    string
+	forget   func() // drops newID's interrupt cleanup once it is no longer temporary
+}
+
+// desiredReplicas is the count set with `orbit scale`, else deploy.replicas
+// when set, otherwise the number of replicas currently running (at least
+// one). An autoscaled service keeps the count the autoscaler chose, within
+// its bounds.
+func desiredReplicas(spec v1.ServiceSpec, existing *v1.ServiceState, running []types.Container) int {
+	n := len(running)
+	if existing != nil && existing.Replicas > n {
+		n = existing.Replicas
+	}
+	if spec.Deploy != nil && spec.Deploy.Autoscale != nil {
+		return clampReplicas(*spec.Deploy.Autoscale, n)
+	}
+	if existing != nil && existing.Scale > 0 {
+		return existing.Scale
+	}
+	if spec.Deploy != nil && spec.Deploy.Replicas > 0 {
+		return spec.Deploy.Replicas
+	}
+	return max(n, 1)
+}
+
+// maxSurge is deploy.max_surge clamped to [1, replicas].
+func maxSurge(spec v1.ServiceSpec, replicas int) int {
+	surge := 1
+	if spec.Deploy != nil && spec.Deploy.MaxSurge > 0 {
+		surge = spec.Deploy.MaxSurge
+	}
+	return min(surge, max(replicas, 1))
+}
+
+// replicaSlots pairs the desired replica positions with the service's running
+// containers, using the same naming as Scaler ("web", "web-2", ...). Running
+// containers that fit no slot are returned as excess.
+func replicaSlots(spec v1.ServiceSpec, running []types.Container, desired int) ([]*replicaSlot, []types.Container) {
+	byName := map[string]types.Container{}
+	for _, c := range running {
+		if c.Labels["orbit.task"] != "" {
+			continue
+		}
+		for _, n := range c.Names {
+			byName[strings.TrimPrefix(n, "/")] = c
+		}
+	}
+
+	slots := make([]*replicaSlot, desired)
+	for i := range slots {
+		r := &replicaSlot{index: i + 1, name: replicaName(spec.Name, i+1)}
+		if c, ok := byName[r.name]; ok {
+			r.oldID, r.oldImage = c.ID, c.Image
+			delete(byName, r.name)
+		}
+		slots[i] = r
+	}
+
+	seen := map[string]bool{}
+	var excess []types.Container
+	for _, c := range byName {
+		if !seen[c.ID] {
+			seen[c.ID] = true
+			excess = append(excess, c)
+		}
+	}
+	sort.Slice(excess, func(i, j int) bool { return excess[i].ID < excess[j].ID })
+	return slots, excess
+}
+
+// replicaName is the canonical container name for replica index i.
+func replicaName(service string, i int) string {
+	if i == 1 {
+		return service
+	}
+	return fmt.Sprintf("%s-%d", service, i)
+}
+
+// startBatch starts a replacement for each slot under a temporary name and
+// waits for all of them to become ready. On failure every replacement in the
+// batch is removed, leaving the old replicas serving.
+func (d *Deployer) startBatch(ctx context.Context, spec v1.ServiceSpec, node, image string, batch []*replicaSlot, timeout time.Duration) error {
+	abort := func() {
+		for _, r := range batch {
+			if r.newID == "" {
+				continue
+			}
+			// After an interrupt ctx is done and this fails; the shutdown
+			// manager then removes the container instead.
+			if err := d.docker.StopContainer(ctx, r.newID, true); err == nil {
+				r.forget()
+			}
+			r.newID = ""
+		}
+	}
+
+	d.step(StepStart)
+	for _, r := range batch {
+		id, err := d.runReplica(ctx, spec, node, image, r, "new")
+		if err != nil {
+			abort()
+			return errs.New(errs.ErrDockerRun, "deploy.run", err).WithNode(node)
+		}
+		r.newID = id
+		r.forget = d.trackTemp(ctx, spec.Name, id)
+	}
+
+	// Wait for startup + readiness probes to pass before cut-over. Without a
+	// health_check the checker waits on the image's own HEALTHCHECK, if any.
+	d.step(StepHealth)
+	d.log.Info("deploy.healthcheck", "service", spec.Name, "timeout", timeout, "batch", len(batch))
+	hctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for _, r := range batch {
+		if err := d.checker.WaitReady(hctx, spec, r.newID); err != nil {
+			d.log.Warn("deploy.healthcheck.failed", "service", spec.Name, "replica", r.name, "err", err)
+			abort()
+			return errs.New(errs.ErrServiceHealthFail, "deploy.healthcheck", err).
+				WithNode(node).
+				WithAdvice(fmt.Sprintf("New container failed health check. Run: orbit logs %s", spec.Name))
+		}
+	}
+	return nil
+}
+
+// runReplica starts a container for slot r under a temporary name.
+func (d *Deployer) runReplica(ctx context.Context, spec v1.ServiceSpec, node, image string, r *replicaSlot, suffix string) (string, error) {
+	rs := spec
+	rs.Image = image
+	rs.Labels = map[string]string{}
+	for k, v := range spec.Labels {
+		rs.Labels[k] = v
+	}
+	rs.Labels["orbit.service"] = spec.Name
+	rs.Labels["orbit.node"] = node
+	if r.index > 1 {
+		rs.Labels["orbit.replica"] = strconv.Itoa(r.index)
+	}
+	rs.Ports = replicaPorts(spec, r.index)
+	return d.docker.RunContainer(ctx, rs, fmt.Sprintf("%s-%s-%d", r.name, suffix, time.Now().UnixNano()))
+}
+
+// cutover retires the slot's old container and gives the replacement the
+// canonical name.
+func (d *Deployer) cutover(ctx context.Context, spec v1.ServiceSpec, r *replicaSlot) {
+	if r.oldID != "" {
+		d.log.Info("deploy.stop_old", "replica", r.name, "id", r.oldID[:12])
+		if err := d.docker.StopContainer(ctx, r.oldID, true); err != nil {
+			d.log.Warn("deploy.stop_old.failed", "err", err)
+		}
+	}
+	if err := d.docker.RenameContainer(ctx, r.newID, r.name); err != nil {
+		d.log.Warn("deploy.rename.failed", "replica", r.name, "err", err)
+	}
+	r.forget() // serving now; an interrupt must not remove it
+}
+
+// trackTemp registers temporary container id for removal if the deploy is
+// interrupted before it is cut over or aborted.
+func (d *Deployer) trackTemp(ctx context.Context, service, id string) func() {
+	return shutdown.Register(ctx, fmt.Sprintf("remove temporary %s container %s", service, id[:12]), func(ctx context.Context) error {
+		return d.docker.StopContainer(ctx, id, true)
+	})
+}
+
+// rollbackReplicas returns already cut-over slots to the image they ran
+// before this deploy. Slots that had no previous container are removed.
+// Failures are logged; the deploy error is what gets reported.
+func (d *Deployer) rollbackReplicas(ctx context.Context, spec v1.ServiceSpec, node string, done []*replicaSlot) {
+	for _, r := range done {
+		if r.oldImage == "" {
+			d.log.Warn("deploy.rollback.remove", "replica", r.name)
+			_ = d.docker.StopContainer(ctx, r.newID, true)
+			continue
+		}
+		d.log.Warn("deploy.rollback", "replica", r.name, "image", r.oldImage)
+		id, err := d.runReplica(ctx, spec, node, r.oldImage, r, "rollback")
+		if err != nil {
+			d.log.Warn("deploy.rollback.failed", "replica", r.name, "err", err)
+			continue
+		}
+		forget := d.trackTemp(ctx, spec.Name, id)
+		_ = d.docker.StopContainer(ctx, r.newID, true)
+		if err := d.docker.RenameContainer(ctx, id, r.name); err != nil {
+			d.log.Warn("deploy.rename.failed", "replica", r.name, "err", err)
+		}
+		forget()
+	}
+}
+
+// Rollback returns a service to the image deployed by rec, using the same
+// rolling, health-gated path as Deploy.
+func (d *Deployer) Rollback(ctx context.Context, spec v1.ServiceSpec, node string, rec v1.DeploymentRecord) error {
+	if rec.Service != spec.Name {
+		return fmt.Errorf("deployment %s belongs to service %q, not %q", rec.ID, rec.Service, spec.Name)
+	}
+	if rec.ToImage == "" {
+		return fmt.Errorf("deployment %s has no recorded image", rec.ID)
+	}
+	d.log.Info("deploy.rollback_to", "service", spec.Name, "record", rec.ID, "image", rec.ToImage)
+	spec.Image = rec.ToImage
+	return d.Deploy(ctx, spec, node, DeployOptions{action: v1.DeployActionRollback})
+}
+
+// ResolveImage applies a tag override to an image reference.
+// An empty tag returns image unchanged.
+func ResolveImage(image, tag string) string {
+	if tag == "" {
+		return image
+	}
+	if idx := lastColonIdx(image); idx != -1 {
+		return image[:idx+1] + tag
+	}
+	return image + ":" + tag
+}
+
 // lastColonIdx finds the last colon in a string (for tag parsing).
 func lastColonIdx(s string) int {
 	for i := len(s) - 1; i >= 0; i-- {
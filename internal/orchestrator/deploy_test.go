@@ -0,0 +1,98 @@
+package orchestrator
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+func TestReplicaSlots(t *testing.T) {
+	spec := v1.ServiceSpec{Name: "web"}
+	running := []types.Container{
+		{ID: "c1", Names: []string{"/web"}, Image: "web:1"},
+		{ID: "c3", Names: []string{"/web-3"}, Image: "web:1"},
+		{ID: "c4", Names: []string{"/web-4"}, Image: "web:1"},
+		{ID: "t1", Names: []string{"/web-task-1"}, Labels: map[string]string{"orbit.task": "true"}},
+	}
+
+	slots, excess := replicaSlots(spec, running, 3)
+	want := []struct{ name, oldID string }{{"web", "c1"}, {"web-2", ""}, {"web-3", "c3"}}
+	if len(slots) != len(want) {
+		t.Fatalf("got %d slots, want %d", len(slots), len(want))
+	}
+	for i, w := range want {
+		if slots[i].name != w.name || slots[i].oldID != w.oldID || slots[i].index != i+1 {
+			t.Errorf("slot %d = %+v, want name=%s old=%s", i, *slots[i], w.name, w.oldID)
+		}
+	}
+	if len(excess) != 1 || excess[0].ID != "c4" {
+		t.Errorf("excess = %v, want [c4]", excess)
+	}
+}
+
+func TestMaxSurgeAndDesiredReplicas(t *testing.T) {
+	tests := []struct {
+		deploy   *v1.DeploySpec
+		running  int
+		replicas int
+		surge    int
+	}{
+		{nil, 0, 1, 1},
+		{nil, 3, 3, 1},
+		{&v1.DeploySpec{Replicas: 4, MaxSurge: 2}, 1, 4, 2},
+		{&v1.DeploySpec{Replicas: 2, MaxSurge: 5}, 2, 2, 2},
+		{&v1.DeploySpec{MaxSurge: 0}, 2, 2, 1},
+	}
+	for _, tt := range tests {
+		spec := v1.ServiceSpec{Name: "web", Deploy: tt.deploy}
+		running := make([]types.Container, tt.running)
+		n := desiredReplicas(spec, nil, running)
+		if n != tt.replicas {
+			t.Errorf("desiredReplicas(%+v, %d running) = %d, want %d", tt.deploy, tt.running, n, tt.replicas)
+		}
+		if s := maxSurge(spec, n); s != tt.surge {
+			t.Errorf("maxSurge(%+v, %d) = %d, want %d", tt.deploy, n, s, tt.surge)
+		}
+	}
+}
+
+// healthInspector reports each of states in turn, then the last one.
+type healthInspector struct {
+	states []string
+	calls  int
+}
+
+func (h *healthInspector) ContainerHealth(context.Context, string) (string, string, error) {
+	i := min(h.calls, len(h.states)-1)
+	h.calls++
+	return h.states[i], "", nil
+}
+
+func TestDeployWaitsForImageHealthcheck(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	// No health_check in orbit.yaml: the image's HEALTHCHECK gates cut-over.
+	inspector := &healthInspector{states: []string{"starting", "healthy"}}
+	checker := health.NewChecker(log).WithInspector(inspector)
+	spec := v1.ServiceSpec{Name: "api", Image: "api:1"}
+	if err := NewDeployer(&verifyRuntime{}, db, checker, log).Deploy(context.Background(), spec, "local", DeployOptions{Tag: "2"}); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if inspector.calls < 2 {
+		t.Errorf("docker health inspected %d time(s), want the deploy to wait on it", inspector.calls)
+	}
+}
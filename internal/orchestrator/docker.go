@@ -2,53 +2,155 @@
 package orchestrator
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	networktypes "github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/system"
 	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 
 	v1 "github.com/f9-o/orbit/api/v1"
 	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/pprint"
+	"github.com/f9-o/orbit/pkg/retry"
 )
 
 // Client wraps the Docker API client with Orbit-specific helpers.
 type Client struct {
 	docker *dockerclient.Client
 	log    *logger.Logger
+	name   string // runtime backend: docker | podman
+
+	// proxy, when set, rewrites a spec before its container is created, e.g.
+	// to add the labels an edge proxy discovers services by.
+	proxy func(v1.ServiceSpec) v1.ServiceSpec
+
+	// pullProgress, when set, receives the layer progress of image pulls.
+	pullProgress func(PullEvent)
+
+	// project scopes the containers the client starts and lists; see
+	// WithProject.
+	project string
 }
 
-// NewClient creates a new Docker API client.
+// NewClient creates a new Docker API client for host, a daemon URL or Docker
+// context name as resolved by ResolveDockerHost.
 func NewClient(host string, log *logger.Logger) (*Client, error) {
-	opts := []dockerclient.Opt{
-		dockerclient.WithAPIVersionNegotiation(),
-	}
-	if host != "" {
-		opts = append(opts, dockerclient.WithHost(host))
-	} else {
-		opts = append(opts, dockerclient.FromEnv)
+	ep, err := ResolveDockerHost(host)
+	if err != nil {
+		return nil, err
 	}
-
+	opts := append([]dockerclient.Opt{dockerclient.WithAPIVersionNegotiation()}, ep.opts()...)
 	dc, err := dockerclient.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("docker client: %w", err)
 	}
-	return &Client{docker: dc, log: log}, nil
+	if ep.Context != "" {
+		log.Debug("docker: using context", "context", ep.Context, "host", ep.Host)
+	}
+	return &Client{docker: dc, log: log, name: RuntimeDocker}, nil
+}
+
+// Name returns the runtime backend this client talks to, "docker" or "podman".
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Host is the daemon URL the client connects to.
+func (c *Client) Host() string {
+	return c.docker.DaemonHost()
 }
 
-// Ping verifies Docker daemon connectivity.
+// WithProxy makes RunContainer pass every spec through fn first.
+func (c *Client) WithProxy(fn func(v1.ServiceSpec) v1.ServiceSpec) *Client {
+	c.proxy = fn
+	return c
+}
+
+// PullEvent is one progress message of an image pull: a layer changing
+// status ("Downloading", "Pull complete") or advancing within one.
+type PullEvent struct {
+	Image   string
+	Layer   string // short layer ID; "" for image-wide messages such as the digest
+	Status  string
+	Current int64 // bytes done in this status (downloading or extracting)
+	Total   int64 // 0 when unknown
+}
+
+// WithPullProgress makes PullImage report each progress message to fn.
+func (c *Client) WithPullProgress(fn func(PullEvent)) *Client {
+	c.pullProgress = fn
+	return c
+}
+
+// Ping verifies Docker daemon connectivity, allowing a daemon that is just
+// starting a few seconds to answer.
 func (c *Client) Ping(ctx context.Context) error {
-	_, err := c.docker.Ping(ctx)
-	return err
+	return retry.Do(ctx, c.retryPolicy("ping"), func(ctx context.Context) error {
+		_, err := c.docker.Ping(ctx)
+		return err
+	})
+}
+
+// retryPolicy retries a read-only or idempotent Docker call, op, while the
+// daemon or registry is unreachable.
+func (c *Client) retryPolicy(op string) retry.Policy {
+	return retry.Policy{
+		Retryable: dockerRetryable,
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			c.log.Warn("docker call failed, retrying", "op", op, "attempt", attempt, "wait", wait, "err", err)
+		},
+	}
+}
+
+// dockerRetryable reports whether a Docker API error is transient: the
+// daemon could not be reached or said it was unavailable. Errors about the
+// request itself — no such object, a conflict, bad credentials — are not.
+func dockerRetryable(err error) bool {
+	if errdefs.IsNotFound(err) || errdefs.IsInvalidParameter(err) || errdefs.IsConflict(err) ||
+		errdefs.IsUnauthorized(err) || errdefs.IsForbidden(err) || errdefs.IsNotImplemented(err) {
+		return false
+	}
+	return dockerclient.IsErrConnectionFailed(err) || errdefs.IsUnavailable(err) || errs.Retryable(err)
+}
+
+// ServerVersion returns the daemon's version information.
+func (c *Client) ServerVersion(ctx context.Context) (types.Version, error) {
+	return c.docker.ServerVersion(ctx)
+}
+
+// Info returns the daemon's system-wide information, as `docker info`
+// prints it.
+func (c *Client) Info(ctx context.Context) (system.Info, error) {
+	return c.docker.Info(ctx)
+}
+
+// DataRoot returns the directory where the daemon stores images and
+// containers (DockerRootDir; Podman reports its graph root there).
+func (c *Client) DataRoot(ctx context.Context) (string, error) {
+	info, err := c.docker.Info(ctx)
+	if err != nil {
+		return "", fmt.Errorf("info: %w", err)
+	}
+	return info.DockerRootDir, nil
 }
 
 // Close releases the Docker API client resources.
@@ -56,9 +158,24 @@ func (c *Client) Close() error {
 	return c.docker.Close()
 }
 
-// PullImage pulls the specified image and streams progress to the logger.
+// PullImage pulls the specified image, streaming progress to the logger and
+// to the WithPullProgress callback. A pull cut short by a network error is
+// retried; layers already downloaded are not fetched again.
 func (c *Client) PullImage(ctx context.Context, img string) error {
 	c.log.Info("pulling image", "image", img)
+	return retry.Do(ctx, c.retryPolicy("pull "+img), func(ctx context.Context) error {
+		return c.pullOnce(ctx, img)
+	})
+}
+
+// transientPullErrors are fragments of the errors the daemon reports in the
+// pull stream when the registry connection, rather than the image, failed.
+var transientPullErrors = []string{
+	"connection reset", "connection refused", "timeout", "unexpected EOF",
+	"TLS handshake", "temporary failure", "502 Bad Gateway", "503 Service Unavailable",
+}
+
+func (c *Client) pullOnce(ctx context.Context, img string) error {
 	rc, err := c.docker.ImagePull(ctx, img, image.PullOptions{})
 	if err != nil {
 		return fmt.Errorf("image pull %q: %w", img, err)
@@ -68,9 +185,14 @@ func (c *Client) PullImage(ctx context.Context, img string) error {
 	dec := json.NewDecoder(rc)
 	for {
 		var msg struct {
-			Status   string `json:"status"`
-			Progress string `json:"progress"`
-			Error    string `json:"error"`
+			ID             string `json:"id"`
+			Status         string `json:"status"`
+			Progress       string `json:"progress"`
+			ProgressDetail struct {
+				Current int64 `json:"current"`
+				Total   int64 `json:"total"`
+			} `json:"progressDetail"`
+			Error string `json:"error"`
 		}
 		if err := dec.Decode(&msg); err != nil {
 			if err == io.EOF {
@@ -79,29 +201,55 @@ func (c *Client) PullImage(ctx context.Context, img string) error {
 			return err
 		}
 		if msg.Error != "" {
+			for _, frag := range transientPullErrors {
+				if strings.Contains(msg.Error, frag) {
+					return errs.Newf(errs.ErrDockerPull, "image.pull", "image pull error: %s", msg.Error).WithNode(img)
+				}
+			}
 			return fmt.Errorf("image pull error: %s", msg.Error)
 		}
 		if msg.Status != "" {
 			c.log.Debug("pull", "status", msg.Status, "progress", msg.Progress)
 		}
+		if c.pullProgress != nil && msg.Status != "" {
+			layer := msg.ID
+			if strings.HasPrefix(msg.Status, "Pulling from") {
+				layer = "" // ID is the tag
+			}
+			c.pullProgress(PullEvent{
+				Image:   img,
+				Layer:   layer,
+				Status:  msg.Status,
+				Current: msg.ProgressDetail.Current,
+				Total:   msg.ProgressDetail.Total,
+			})
+		}
 	}
 	return nil
 }
 
+// EnsureImage pulls img unless it is already present, and reports whether
+// it pulled.
+func (c *Client) EnsureImage(ctx context.Context, img string) (bool, error) {
+	if _, _, err := c.docker.ImageInspectWithRaw(ctx, img); err == nil {
+		return false, nil
+	}
+	if err := c.PullImage(ctx, img); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // RunContainer creates and starts a container according to spec.
 func (c *Client) RunContainer(ctx context.Context, spec v1.ServiceSpec, name string) (string, error) {
-	// Build port bindings
-	exposedPorts := nat.PortSet{}
-	portBindings := nat.PortMap{}
-	for _, p := range spec.Ports {
-		parts := strings.SplitN(p, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		hostPort, containerPortStr := parts[0], parts[1]
-		containerPort := nat.Port(containerPortStr + "/tcp")
-		exposedPorts[containerPort] = struct{}{}
-		portBindings[containerPort] = []nat.PortBinding{{HostPort: hostPort}}
+	if c.proxy != nil {
+		spec = c.proxy(spec)
+	}
+
+	// Build port bindings, from entries in any form docker run -p takes
+	exposedPorts, portBindings, err := nat.ParsePortSpecs(spec.Ports)
+	if err != nil {
+		return "", fmt.Errorf("ports of %q: %w", name, err)
 	}
 
 	// Environment slice
@@ -119,7 +267,7 @@ func (c *Client) RunContainer(ctx context.Context, spec v1.ServiceSpec, name str
 	containerCfg := &containertypes.Config{
 		Image:        spec.Image,
 		Env:          envSlice,
-		Labels:       spec.Labels,
+		Labels:       c.projectLabels(spec.Labels),
 		ExposedPorts: exposedPorts,
 	}
 	if spec.User != "" {
@@ -131,14 +279,34 @@ func (c *Client) RunContainer(ctx context.Context, spec v1.ServiceSpec, name str
 		Binds:         spec.Volumes,
 		RestartPolicy: containertypes.RestartPolicy{Name: restartPolicyName},
 	}
+	if len(spec.Networks) > 0 {
+		hostCfg.NetworkMode = containertypes.NetworkMode(spec.Networks[0])
+	}
+	if err := applyProcess(spec, containerCfg, hostCfg); err != nil {
+		return "", err
+	}
+	if err := applySecurity(spec, hostCfg); err != nil {
+		return "", err
+	}
+	if err := applySystem(spec, hostCfg); err != nil {
+		return "", err
+	}
 
 	netCfg := &networktypes.NetworkingConfig{}
 
+	name = c.containerName(name)
 	resp, err := c.docker.ContainerCreate(ctx, containerCfg, hostCfg, netCfg, nil, name)
 	if err != nil {
 		return "", fmt.Errorf("container create %q: %w", name, err)
 	}
 
+	for _, n := range spec.Networks[min(1, len(spec.Networks)):] {
+		if err := c.docker.NetworkConnect(ctx, n, resp.ID, nil); err != nil {
+			_ = c.docker.ContainerRemove(ctx, resp.ID, containertypes.RemoveOptions{Force: true})
+			return "", fmt.Errorf("network connect %q: %w", n, err)
+		}
+	}
+
 	if err := c.docker.ContainerStart(ctx, resp.ID, containertypes.StartOptions{}); err != nil {
 		_ = c.docker.ContainerRemove(ctx, resp.ID, containertypes.RemoveOptions{Force: true})
 		return "", fmt.Errorf("container start %q: %w", resp.ID[:12], err)
@@ -148,12 +316,22 @@ func (c *Client) RunContainer(ctx context.Context, spec v1.ServiceSpec, name str
 	return resp.ID, nil
 }
 
-// StopContainer gracefully stops a container and optionally removes it.
+// StopContainer gracefully stops a container and optionally removes it. The
+// container gets its stop_signal and, before it is killed, its
+// stop_grace_period (10s unless set), both fixed when it was created.
 func (c *Client) StopContainer(ctx context.Context, idOrName string, remove bool) error {
-	timeout := 10
-	stopOpts := containertypes.StopOptions{Timeout: &timeout}
+	return c.StopContainerTimeout(ctx, idOrName, 0, remove)
+}
 
-	if err := c.docker.ContainerStop(ctx, idOrName, stopOpts); err != nil {
+// StopContainerTimeout is StopContainer with timeout, rounded up to whole
+// seconds, in place of the container's stop_grace_period; zero keeps it.
+func (c *Client) StopContainerTimeout(ctx context.Context, idOrName string, timeout time.Duration, remove bool) error {
+	var opts containertypes.StopOptions
+	if timeout > 0 {
+		secs := int((timeout + time.Second - 1) / time.Second)
+		opts.Timeout = &secs
+	}
+	if err := c.docker.ContainerStop(ctx, idOrName, opts); err != nil {
 		return fmt.Errorf("container stop %q: %w", idOrName, err)
 	}
 	c.log.Info("container stopped", "id", idOrName)
@@ -167,33 +345,267 @@ func (c *Client) StopContainer(ctx context.Context, idOrName string, remove bool
 	return nil
 }
 
+// RenameContainer gives a container a new name.
+func (c *Client) RenameContainer(ctx context.Context, idOrName, name string) error {
+	return c.docker.ContainerRename(ctx, idOrName, c.containerName(name))
+}
+
+// UpdateRestartPolicy changes a container's restart policy without
+// recreating it.
+func (c *Client) UpdateRestartPolicy(ctx context.Context, idOrName, policy string) error {
+	_, err := c.docker.ContainerUpdate(ctx, idOrName, containertypes.UpdateConfig{
+		RestartPolicy: containertypes.RestartPolicy{Name: containertypes.RestartPolicyMode(policy)},
+	})
+	if err != nil {
+		return fmt.Errorf("container update %q: %w", idOrName, err)
+	}
+	return nil
+}
+
+// ConnectNetwork attaches a running container to network.
+func (c *Client) ConnectNetwork(ctx context.Context, network, idOrName string) error {
+	if err := c.docker.NetworkConnect(ctx, network, idOrName, nil); err != nil {
+		return fmt.Errorf("network connect %q: %w", network, err)
+	}
+	return nil
+}
+
+// DisconnectNetwork detaches a running container from network.
+func (c *Client) DisconnectNetwork(ctx context.Context, network, idOrName string) error {
+	if err := c.docker.NetworkDisconnect(ctx, network, idOrName, false); err != nil {
+		return fmt.Errorf("network disconnect %q: %w", network, err)
+	}
+	return nil
+}
+
+// RestartContainer stops and restarts a container in place, stopping it as
+// StopContainer does.
+func (c *Client) RestartContainer(ctx context.Context, idOrName string) error {
+	if err := c.docker.ContainerRestart(ctx, idOrName, containertypes.StopOptions{}); err != nil {
+		return fmt.Errorf("container restart %q: %w", idOrName, err)
+	}
+	c.log.Info("container restarted", "id", idOrName)
+	return nil
+}
+
 // InspectContainer returns full container JSON for the given id/name.
 func (c *Client) InspectContainer(ctx context.Context, idOrName string) (types.ContainerJSON, error) {
-	return c.docker.ContainerInspect(ctx, idOrName)
+	return retry.Value(ctx, c.retryPolicy("inspect"), func(ctx context.Context) (types.ContainerJSON, error) {
+		return c.docker.ContainerInspect(ctx, idOrName)
+	})
+}
+
+// ContainerHealth returns the container's Docker HEALTHCHECK status
+// ("starting", "healthy", "unhealthy") and the output of the most recent check.
+// status is "" when the image defines no HEALTHCHECK.
+func (c *Client) ContainerHealth(ctx context.Context, idOrName string) (string, string, error) {
+	info, err := c.docker.ContainerInspect(ctx, idOrName)
+	if err != nil {
+		return "", "", fmt.Errorf("container inspect %q: %w", idOrName, err)
+	}
+	if info.State == nil || info.State.Health == nil {
+		return "", "", nil
+	}
+	h := info.State.Health
+	var out string
+	if n := len(h.Log); n > 0 {
+		out = h.Log[n-1].Output
+	}
+	return h.Status, out, nil
+}
+
+// ContainerExit reports how often a container has restarted and how it
+// last exited.
+func (c *Client) ContainerExit(ctx context.Context, idOrName string) (v1.ContainerExit, error) {
+	info, err := c.docker.ContainerInspect(ctx, idOrName)
+	if err != nil {
+		return v1.ContainerExit{}, fmt.Errorf("container inspect %q: %w", idOrName, err)
+	}
+	var x v1.ContainerExit
+	if info.ContainerJSONBase == nil {
+		return x, nil
+	}
+	x.Restarts = info.RestartCount
+	if st := info.State; st != nil {
+		x.ExitCode, x.OOMKilled = st.ExitCode, st.OOMKilled
+		if at, err := time.Parse(time.RFC3339Nano, st.FinishedAt); err == nil && at.Year() > 1 {
+			x.ExitedAt = at.UTC()
+		}
+	}
+	return x, nil
+}
+
+// ImageEnv returns the environment baked into an image's config.
+func (c *Client) ImageEnv(ctx context.Context, ref string) ([]string, error) {
+	img, _, err := c.docker.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("image inspect %q: %w", ref, err)
+	}
+	if img.Config == nil {
+		return nil, nil
+	}
+	return img.Config.Env, nil
+}
+
+// ImageRepoDigests returns the registry digests (name@sha256:…) an image was
+// pulled by; ref may be the image's ID. A locally built image has none.
+func (c *Client) ImageRepoDigests(ctx context.Context, ref string) ([]string, error) {
+	img, _, err := c.docker.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("image inspect %q: %w", ref, err)
+	}
+	return img.RepoDigests, nil
+}
+
+// Exec runs cmd inside a running container and returns its exit code and
+// combined stdout/stderr output.
+func (c *Client) Exec(ctx context.Context, idOrName string, cmd []string) (int, string, error) {
+	created, err := c.docker.ContainerExecCreate(ctx, idOrName, types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return -1, "", fmt.Errorf("exec create %q: %w", idOrName, err)
+	}
+
+	resp, err := c.docker.ContainerExecAttach(ctx, created.ID, types.ExecStartCheck{})
+	if err != nil {
+		return -1, "", fmt.Errorf("exec attach %q: %w", idOrName, err)
+	}
+	defer resp.Close()
+
+	var out bytes.Buffer
+	if _, err := stdcopy.StdCopy(&out, &out, resp.Reader); err != nil {
+		return -1, out.String(), fmt.Errorf("exec read %q: %w", idOrName, err)
+	}
+
+	info, err := c.docker.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return -1, out.String(), fmt.Errorf("exec inspect %q: %w", idOrName, err)
+	}
+	return info.ExitCode, out.String(), nil
+}
+
+// StatPath describes a path inside a container.
+func (c *Client) StatPath(ctx context.Context, idOrName, path string) (types.ContainerPathStat, error) {
+	return c.docker.ContainerStatPath(ctx, idOrName, path)
+}
+
+// CopyFromContainer streams path out of a container as a tar archive.
+func (c *Client) CopyFromContainer(ctx context.Context, idOrName, path string) (io.ReadCloser, types.ContainerPathStat, error) {
+	return c.docker.CopyFromContainer(ctx, idOrName, path)
+}
+
+// CopyToContainer extracts a tar archive into dir, which must exist in the container.
+func (c *Client) CopyToContainer(ctx context.Context, idOrName, dir string, archive io.Reader) error {
+	return c.docker.CopyToContainer(ctx, idOrName, dir, archive, types.CopyToContainerOptions{})
 }
 
-// ListContainers returns running containers matching Orbit labels.
+// ListContainers returns the running orbit containers of the client's
+// project, of serviceFilter only when it is set.
 func (c *Client) ListContainers(ctx context.Context, serviceFilter string) ([]types.Container, error) {
 	f := filters.NewArgs()
 	f.Add("label", "orbit.service")
 	if serviceFilter != "" {
 		f.Add("label", "orbit.service="+serviceFilter)
 	}
-	return c.docker.ContainerList(ctx, containertypes.ListOptions{
-		Filters: f,
+	ctrs, err := retry.Value(ctx, c.retryPolicy("list"), func(ctx context.Context) ([]types.Container, error) {
+		return c.docker.ContainerList(ctx, containertypes.ListOptions{
+			Filters: f,
+		})
 	})
+	if err != nil {
+		return nil, err
+	}
+	ctrs = slices.DeleteFunc(ctrs, func(ctr types.Container) bool { return !c.sees(ctr.Labels) })
+	for i := range ctrs {
+		for j, n := range ctrs[i].Names {
+			ctrs[i].Names[j] = c.localName(n)
+		}
+	}
+	return ctrs, nil
+}
+
+// ContainerEvents subscribes to lifecycle events for orbit-labelled
+// containers. The event channel closes when the stream ends — ctx cancelled,
+// daemon restarted, connection lost — after the cause, if any, has been sent
+// on the buffered error channel. Callers resubscribe to keep watching.
+func (c *Client) ContainerEvents(ctx context.Context) (<-chan v1.ContainerEvent, <-chan error) {
+	f := filters.NewArgs(
+		filters.Arg("type", string(events.ContainerEventType)),
+		filters.Arg("label", "orbit.service"),
+	)
+	msgs, derrc := c.docker.Events(ctx, types.EventsOptions{Filters: f})
+
+	out := make(chan v1.ContainerEvent)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(out)
+		for {
+			select {
+			case err := <-derrc:
+				if err != nil && ctx.Err() == nil {
+					errc <- err
+				}
+				return
+			case m := <-msgs:
+				if !c.sees(m.Actor.Attributes) {
+					continue
+				}
+				// Exec and health events arrive as "exec_start: ...", "health_status: healthy"
+				action, detail, _ := strings.Cut(string(m.Action), ":")
+				exitCode, _ := strconv.Atoi(m.Actor.Attributes["exitCode"])
+				ev := v1.ContainerEvent{
+					ID:       m.Actor.ID,
+					Name:     c.localName(m.Actor.Attributes["name"]),
+					Service:  m.Actor.Attributes["orbit.service"],
+					Task:     m.Actor.Attributes["orbit.task"] != "",
+					Replica:  m.Actor.Attributes["orbit.replica"],
+					Image:    m.Actor.Attributes["image"],
+					Action:   action,
+					ExitCode: exitCode,
+					Time:     time.Unix(0, m.TimeNano).UTC(),
+				}
+				if action == "health_status" {
+					ev.Health = strings.TrimSpace(detail)
+				}
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, errc
+}
+
+// LogStreamOptions selects the lines StreamLogs writes, and how.
+type LogStreamOptions struct {
+	Follow     bool
+	Since      time.Duration  // only lines written this long ago or later; 0: all
+	Timestamps bool           // start each line with the time the daemon recorded it
+	StderrOnly bool           // leave out stdout
+	StripColor bool           // remove ANSI escape sequences
+	Filter     *regexp.Regexp // only lines whose text, without colours, it matches
 }
 
-// StreamLogs streams container logs to the provided writer.
-func (c *Client) StreamLogs(ctx context.Context, idOrName string, follow bool, since time.Duration, w io.Writer) error {
+// StreamLogs writes a container's log lines to w as opts selects,
+// demultiplexing stdout and stderr, until the log ends or, with Follow, ctx
+// is cancelled.
+func (c *Client) StreamLogs(ctx context.Context, idOrName string, opts LogStreamOptions, w io.Writer) error {
 	sinceStr := ""
-	if since > 0 {
-		sinceStr = fmt.Sprintf("%ds", int(since.Seconds()))
+	if opts.Since > 0 {
+		sinceStr = fmt.Sprintf("%ds", int(opts.Since.Seconds()))
 	}
+	// Timestamps always come, so that lines split from them the same way
+	// with and without opts.Timestamps.
 	rc, err := c.docker.ContainerLogs(ctx, idOrName, containertypes.LogsOptions{
-		ShowStdout: true,
+		ShowStdout: !opts.StderrOnly,
 		ShowStderr: true,
-		Follow:     follow,
+		Follow:     opts.Follow,
 		Timestamps: true,
 		Since:      sinceStr,
 	})
@@ -201,11 +613,115 @@ func (c *Client) StreamLogs(ctx context.Context, idOrName string, follow bool, s
 		return fmt.Errorf("logs %q: %w", idOrName, err)
 	}
 	defer rc.Close()
-	_, err = io.Copy(w, rc)
+
+	var werr error
+	write := func(l LogLine) {
+		text := l.Text
+		if opts.StripColor {
+			text = pprint.StripANSI(text)
+		}
+		if opts.Filter != nil && !opts.Filter.MatchString(pprint.StripANSI(text)) {
+			return
+		}
+		if opts.Timestamps {
+			text = l.Time.Format(time.RFC3339Nano) + " " + text
+		}
+		if werr == nil {
+			_, werr = io.WriteString(w, text+"\n")
+		}
+	}
+	stdout, stderr := &logLineWriter{stream: "stdout", fn: write}, &logLineWriter{stream: "stderr", fn: write}
+	// Non-TTY containers multiplex stdout/stderr with 8-byte frame headers.
+	if info, ierr := c.docker.ContainerInspect(ctx, idOrName); ierr == nil && info.Config != nil && info.Config.Tty {
+		_, err = io.Copy(stdout, rc)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, rc)
+	}
+	stdout.flush()
+	stderr.flush()
+	if err == nil {
+		err = werr
+	}
+	return err
+}
+
+// LogLine is one line of a container's output.
+type LogLine struct {
+	Time   time.Time
+	Stream string // stdout | stderr
+	Text   string
+}
+
+// FollowLogs calls fn with each line the container writes after since (the
+// zero time: from its start), until it stops or ctx is cancelled.
+func (c *Client) FollowLogs(ctx context.Context, idOrName string, since time.Time, fn func(LogLine)) error {
+	opts := containertypes.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true, Timestamps: true}
+	if !since.IsZero() {
+		opts.Since = fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond())
+	}
+	rc, err := c.docker.ContainerLogs(ctx, idOrName, opts)
+	if err != nil {
+		return fmt.Errorf("logs %q: %w", idOrName, err)
+	}
+	defer rc.Close()
+
+	stdout, stderr := &logLineWriter{stream: "stdout", fn: fn}, &logLineWriter{stream: "stderr", fn: fn}
+	defer stdout.flush()
+	defer stderr.flush()
+	if info, ierr := c.docker.ContainerInspect(ctx, idOrName); ierr == nil && info.Config != nil && info.Config.Tty {
+		_, err = io.Copy(stdout, rc)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, rc)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
 	return err
 }
 
-// ContainerStats returns a single stats snapshot for the container.
+// logLineWriter splits timestamped log output into LogLines.
+type logLineWriter struct {
+	stream string
+	fn     func(LogLine)
+	buf    []byte
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.emit(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// flush emits a last line that ended without a newline.
+func (w *logLineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *logLineWriter) emit(line string) {
+	l := LogLine{Stream: w.stream, Text: strings.TrimSuffix(line, "\r")}
+	if stamp, text, ok := strings.Cut(l.Text, " "); ok {
+		if t, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+			l.Time, l.Text = t, text
+		}
+	}
+	if l.Time.IsZero() {
+		l.Time = time.Now().UTC()
+	}
+	w.fn(l)
+}
+
+// ContainerStats returns a single stats sample for a container. The daemon
+// leaves PreCPUStats empty in one-shot mode, so CPUPercent is zero; use
+// StreamStats for CPU usage.
 func (c *Client) ContainerStats(ctx context.Context, idOrName string) (v1.ServiceMetrics, error) {
 	resp, err := c.docker.ContainerStatsOneShot(ctx, idOrName)
 	if err != nil {
@@ -217,23 +733,87 @@ func (c *Client) ContainerStats(ctx context.Context, idOrName string) (v1.Servic
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
 		return v1.ServiceMetrics{}, err
 	}
+	return serviceMetrics(&raw, nil), nil
+}
+
+// StreamStats follows a container's stats stream, calling fn with each sample
+// (about once a second) until ctx is cancelled or the container stops, which
+// returns nil. CPU usage is computed between consecutive samples.
+func (c *Client) StreamStats(ctx context.Context, idOrName string, fn func(v1.ServiceMetrics)) error {
+	resp, err := c.docker.ContainerStats(ctx, idOrName, true)
+	if err != nil {
+		return fmt.Errorf("stats %q: %w", idOrName, err)
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	var prev *types.StatsJSON
+	for {
+		var cur types.StatsJSON
+		if err := dec.Decode(&cur); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("stats %q: %w", idOrName, err)
+		}
+		fn(serviceMetrics(&cur, prev))
+		prev = &cur
+	}
+}
 
-	// CPU percent calculation
-	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage - raw.PreCPUStats.CPUUsage.TotalUsage)
-	sysDelta := float64(raw.CPUStats.SystemUsage - raw.PreCPUStats.SystemUsage)
-	numCPU := float64(len(raw.CPUStats.CPUUsage.PercpuUsage))
+// serviceMetrics converts a raw stats sample. CPU percent is measured against
+// prev when given, otherwise against the sample's own PreCPUStats; it is zero
+// when neither baseline is available.
+func serviceMetrics(cur, prev *types.StatsJSON) v1.ServiceMetrics {
+	base := cur.PreCPUStats
+	if prev != nil {
+		base = prev.CPUStats
+	}
 	cpuPercent := 0.0
-	if sysDelta > 0 && cpuDelta > 0 {
-		cpuPercent = (cpuDelta / sysDelta) * numCPU * 100.0
+	if base.SystemUsage > 0 && cur.CPUStats.SystemUsage > base.SystemUsage &&
+		cur.CPUStats.CPUUsage.TotalUsage > base.CPUUsage.TotalUsage {
+		cpuDelta := float64(cur.CPUStats.CPUUsage.TotalUsage - base.CPUUsage.TotalUsage)
+		sysDelta := float64(cur.CPUStats.SystemUsage - base.SystemUsage)
+		numCPU := float64(cur.CPUStats.OnlineCPUs)
+		if numCPU == 0 {
+			numCPU = float64(max(1, len(cur.CPUStats.CPUUsage.PercpuUsage)))
+		}
+		cpuPercent = cpuDelta / sysDelta * numCPU * 100.0
 	}
 
-	netStats := raw.Networks["eth0"]
-	return v1.ServiceMetrics{
+	netStats := cur.Networks["eth0"]
+	m := v1.ServiceMetrics{
 		CPUPercent: cpuPercent,
-		MemBytes:   int64(raw.MemoryStats.Usage),
-		MemLimit:   int64(raw.MemoryStats.Limit),
+		MemBytes:   int64(cur.MemoryStats.Usage),
+		MemLimit:   int64(cur.MemoryStats.Limit),
 		NetRxBytes: int64(netStats.RxBytes),
 		NetTxBytes: int64(netStats.TxBytes),
-		PIDs:       int(raw.PidsStats.Current),
-	}, nil
+		PIDs:       int(cur.PidsStats.Current),
+	}
+	// cgroup v1 reports "Read"/"Write", cgroup v2 "read"/"write".
+	for _, e := range cur.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			m.BlockReadBytes += int64(e.Value)
+		case "write":
+			m.BlockWriteBytes += int64(e.Value)
+		}
+	}
+	return m
+}
+
+// ContainerDiskUsage returns the size of a container's writable layer. The
+// daemon computes it by walking the layer, so call it sparingly.
+func (c *Client) ContainerDiskUsage(ctx context.Context, idOrName string) (int64, error) {
+	info, _, err := c.docker.ContainerInspectWithRaw(ctx, idOrName, true)
+	if err != nil {
+		return 0, fmt.Errorf("inspect %q: %w", idOrName, err)
+	}
+	if info.SizeRw == nil {
+		return 0, nil
+	}
+	return *info.SizeRw, nil
 }
@@ -0,0 +1,137 @@
+// Package orchestrator: choosing the Docker daemon from a host URL or a
+// Docker context.
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	dockerclient "github.com/docker/docker/client"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// Endpoint is the Docker daemon a Client talks to.
+type Endpoint struct {
+	Host    string // daemon URL; "" means DOCKER_HOST or the platform default
+	Context string // Docker context the endpoint came from, if any
+
+	// TLS client files, from the context's TLS store.
+	CACert, Cert, Key string
+}
+
+// dockerConfigDir is the Docker CLI's configuration directory.
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".docker")
+}
+
+// ResolveDockerHost turns a docker_host value into an Endpoint. A value with
+// a scheme (unix://, tcp://, npipe://) is a daemon URL; any other value names
+// a Docker context. An empty value follows the Docker CLI: DOCKER_HOST, then
+// DOCKER_CONTEXT, then the current context of ~/.docker/config.json.
+func ResolveDockerHost(value string) (Endpoint, error) {
+	if value == "" {
+		if os.Getenv("DOCKER_HOST") != "" {
+			return Endpoint{}, nil
+		}
+		value = os.Getenv("DOCKER_CONTEXT")
+		if value == "" {
+			value = currentContext()
+		}
+		if value == "" || value == "default" {
+			return Endpoint{}, nil
+		}
+	}
+	if strings.Contains(value, "://") {
+		if strings.HasPrefix(value, "ssh://") {
+			return Endpoint{}, errs.Newf(errs.ErrDockerConnect, "orchestrator.ResolveDockerHost",
+				"docker host %q: ssh:// endpoints are not supported", value).
+				WithAdvice("Add the machine as an orbit node, or forward its socket: ssh -NL /tmp/docker.sock:/var/run/docker.sock host")
+		}
+		return Endpoint{Host: value}, nil
+	}
+	if value == "default" {
+		return Endpoint{Context: value}, nil
+	}
+	return loadContext(value)
+}
+
+// currentContext is the context `docker context use` selected, if any.
+func currentContext() string {
+	data, err := os.ReadFile(filepath.Join(dockerConfigDir(), "config.json"))
+	if err != nil {
+		return ""
+	}
+	var cfg struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	_ = json.Unmarshal(data, &cfg)
+	return cfg.CurrentContext
+}
+
+// loadContext reads the Docker endpoint of context name from the Docker
+// CLI's context store, where contexts live under the SHA-256 of their name.
+func loadContext(name string) (Endpoint, error) {
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+	dir := filepath.Join(dockerConfigDir(), "contexts")
+
+	data, err := os.ReadFile(filepath.Join(dir, "meta", id, "meta.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Endpoint{}, errs.Newf(errs.ErrDockerConnect, "orchestrator.loadContext", "docker context %q not found", name).
+			WithAdvice("List contexts with `docker context ls`, or give docker_host as a URL such as unix:///var/run/docker.sock")
+	}
+	if err != nil {
+		return Endpoint{}, errs.New(errs.ErrDockerConnect, "orchestrator.loadContext", err)
+	}
+	var meta struct {
+		Endpoints map[string]struct {
+			Host string `json:"Host"`
+		} `json:"Endpoints"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return Endpoint{}, errs.Newf(errs.ErrDockerConnect, "orchestrator.loadContext", "docker context %q: %v", name, err)
+	}
+	ep := meta.Endpoints["docker"]
+	if ep.Host == "" {
+		return Endpoint{}, errs.Newf(errs.ErrDockerConnect, "orchestrator.loadContext", "docker context %q has no docker endpoint", name)
+	}
+	if strings.HasPrefix(ep.Host, "ssh://") {
+		return Endpoint{}, errs.Newf(errs.ErrDockerConnect, "orchestrator.loadContext",
+			"docker context %q uses %s: ssh:// endpoints are not supported", name, ep.Host).
+			WithAdvice("Add the machine as an orbit node, or create a context with a tcp:// or unix:// endpoint")
+	}
+
+	out := Endpoint{Host: ep.Host, Context: name}
+	tls := filepath.Join(dir, "tls", id, "docker")
+	if _, err := os.Stat(filepath.Join(tls, "cert.pem")); err == nil {
+		out.CACert = filepath.Join(tls, "ca.pem")
+		out.Cert = filepath.Join(tls, "cert.pem")
+		out.Key = filepath.Join(tls, "key.pem")
+	}
+	return out, nil
+}
+
+// opts are the Docker client options that connect to e.
+func (e Endpoint) opts() []dockerclient.Opt {
+	if e.Host == "" {
+		return []dockerclient.Opt{dockerclient.FromEnv}
+	}
+	opts := []dockerclient.Opt{dockerclient.WithHost(e.Host)}
+	switch {
+	case e.Cert != "":
+		opts = append(opts, dockerclient.WithTLSClientConfig(e.CACert, e.Cert, e.Key))
+	case strings.HasPrefix(e.Host, "tcp://"):
+		opts = append(opts, dockerclient.WithTLSClientConfigFromEnv()) // DOCKER_CERT_PATH, as the docker CLI
+	}
+	return opts
+}
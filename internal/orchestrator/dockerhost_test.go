@@ -0,0 +1,80 @@
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// dockerContext writes a context to the Docker CLI's store under dir.
+func dockerContext(t *testing.T, dir, name, host string, tls bool) {
+	t.Helper()
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+	meta := filepath.Join(dir, "contexts", "meta", id)
+	if err := os.MkdirAll(meta, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	doc := `{"Name":"` + name + `","Metadata":{},"Endpoints":{"docker":{"Host":"` + host + `","SkipTLSVerify":false}}}`
+	if err := os.WriteFile(filepath.Join(meta, "meta.json"), []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if tls {
+		certs := filepath.Join(dir, "contexts", "tls", id, "docker")
+		os.MkdirAll(certs, 0o755)
+		for _, f := range []string{"ca.pem", "cert.pem", "key.pem"} {
+			os.WriteFile(filepath.Join(certs, f), nil, 0o600)
+		}
+	}
+}
+
+func TestResolveDockerHost(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+	dockerContext(t, dir, "colima", "unix:///Users/me/.colima/default/docker.sock", false)
+	dockerContext(t, dir, "prod", "tcp://10.0.0.5:2376", true)
+	dockerContext(t, dir, "remote", "ssh://ops@10.0.0.6", false)
+
+	ep, err := ResolveDockerHost("tcp://127.0.0.1:2375")
+	if err != nil || ep.Host != "tcp://127.0.0.1:2375" || ep.Context != "" {
+		t.Errorf("url: %+v, %v", ep, err)
+	}
+	ep, err = ResolveDockerHost("prod")
+	if err != nil || ep.Host != "tcp://10.0.0.5:2376" || ep.Context != "prod" ||
+		filepath.Base(ep.Cert) != "cert.pem" || filepath.Base(ep.CACert) != "ca.pem" {
+		t.Errorf("context with TLS: %+v, %v", ep, err)
+	}
+	if ep, _ := ResolveDockerHost("colima"); ep.Cert != "" {
+		t.Errorf("context without TLS has cert %q", ep.Cert)
+	}
+
+	// With nothing set, the current context applies; DOCKER_CONTEXT and
+	// DOCKER_HOST take precedence over it.
+	if ep, err := ResolveDockerHost(""); err != nil || ep.Host != "" {
+		t.Errorf("no context: %+v, %v", ep, err)
+	}
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"auths":{},"currentContext":"colima"}`), 0o600)
+	if ep, _ := ResolveDockerHost(""); ep.Context != "colima" {
+		t.Errorf("current context: %+v", ep)
+	}
+	t.Setenv("DOCKER_CONTEXT", "prod")
+	if ep, _ := ResolveDockerHost(""); ep.Context != "prod" {
+		t.Errorf("DOCKER_CONTEXT: %+v", ep)
+	}
+	t.Setenv("DOCKER_HOST", "unix:///var/run/docker.sock")
+	if ep, _ := ResolveDockerHost(""); ep.Host != "" || ep.Context != "" {
+		t.Errorf("DOCKER_HOST: %+v", ep)
+	}
+
+	for _, bad := range []string{"missing", "remote", "ssh://ops@10.0.0.6"} {
+		if _, err := ResolveDockerHost(bad); !errs.IsCode(err, errs.ErrDockerConnect) {
+			t.Errorf("%s: err = %v", bad, err)
+		}
+	}
+}
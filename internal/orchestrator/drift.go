@@ -0,0 +1,152 @@
+// Package orchestrator: live config drift between a service's spec and its container.
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// Remedies: the command that converges a drifted service.
+const (
+	RemedyNone   = ""
+	RemedyUp     = "up"     // `orbit up` starts it, updates it in place or recreates it
+	RemedyDeploy = "deploy" // new image, replica count, or several replicas to recreate: roll it out
+)
+
+// Drift is how a service's running container differs from its spec.
+type Drift struct {
+	Service     string        `json:"service"`
+	Node        string        `json:"node"`
+	ContainerID string        `json:"container_id,omitempty"`
+	Changes     []FieldChange `json:"changes"`
+	Remedy      string        `json:"remedy,omitempty"`
+}
+
+// Drifted reports whether the container differs from the spec.
+func (d *Drift) Drifted() bool {
+	return len(d.Changes) > 0
+}
+
+// Drift compares spec field by field against the configuration of the
+// service's container on node: image, environment, ports, volumes, labels,
+// user, restart policy, process and security settings, networks, name and
+// replica count. Unlike Plan it needs the container runtime.
+func (p *Planner) Drift(ctx context.Context, spec v1.ServiceSpec, node string) (*Drift, error) {
+	if p.docker == nil {
+		return nil, errs.Newf(errs.ErrDockerConnect, "drift", "the container runtime is not reachable").
+			WithAdvice("Start Docker, or check DOCKER_HOST")
+	}
+	d := &Drift{Service: spec.Name, Node: node, Changes: []FieldChange{}}
+	s, err := p.state.GetServiceState(node, spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil || s.ContainerID == "" {
+		d.Changes = append(d.Changes, FieldChange{Field: "container", From: "none", To: "running"})
+		d.Remedy = RemedyUp
+		return d, nil
+	}
+	d.ContainerID = s.ContainerID
+	d.Changes = append(d.Changes, p.diffService(ctx, spec, *s)...)
+	d.Remedy = remedy(spec, d.Changes)
+	return d, nil
+}
+
+// remedy picks the command that converges a service with these changes.
+func remedy(spec v1.ServiceSpec, changes []FieldChange) string {
+	if len(changes) == 0 {
+		return RemedyNone
+	}
+	for _, c := range changes {
+		if c.Field == "container" { // up recreates a stopped service from its current spec
+			return RemedyUp
+		}
+	}
+	u := PlanUpdate(changes)
+	multi := spec.Deploy != nil && spec.Deploy.Replicas > 1
+	if u.Pull || u.Replicas > 0 || (multi && !u.InPlace()) {
+		return RemedyDeploy
+	}
+	return RemedyUp
+}
+
+// diffVolumes compares the spec's volumes with the container's binds.
+func diffVolumes(desired []string, info types.ContainerJSON) []FieldChange {
+	var actual []string
+	if info.HostConfig != nil {
+		actual = info.HostConfig.Binds
+	}
+	return diffList("volumes", desired, actual)
+}
+
+// diffLabels reports the spec's labels that the container lacks or has
+// with another value. Labels the container has beyond those — from the
+// image, Orbit itself or a proxy integration — are not drift.
+func diffLabels(desired map[string]string, info types.ContainerJSON) []FieldChange {
+	var actual map[string]string
+	if info.Config != nil {
+		actual = info.Config.Labels
+	}
+	keys := make([]string, 0, len(desired))
+	for k := range desired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var changes []FieldChange
+	for _, k := range keys {
+		if got, ok := actual[k]; !ok || got != desired[k] {
+			changes = append(changes, FieldChange{Field: "labels." + k, From: got, To: desired[k]})
+		}
+	}
+	return changes
+}
+
+// diffRuntime compares the user and restart policy the container runs with.
+// Without a user in the spec the container runs as the image's USER, which
+// is not drift.
+func diffRuntime(spec v1.ServiceSpec, info types.ContainerJSON) []FieldChange {
+	var changes []FieldChange
+	if info.Config != nil && spec.User != "" && info.Config.User != spec.User {
+		changes = append(changes, FieldChange{Field: "user", From: info.Config.User, To: spec.User})
+	}
+	if info.HostConfig != nil && info.HostConfig.RestartPolicy.Name != "" {
+		want := spec.RestartPolicy
+		if want == "" {
+			want = "unless-stopped" // RunContainer's default
+		}
+		if got := string(info.HostConfig.RestartPolicy.Name); got != want {
+			changes = append(changes, FieldChange{Field: "restart", From: got, To: want})
+		}
+	}
+	return changes
+}
+
+// diffList compares two unordered lists, reporting them whole when they differ.
+func diffList(field string, desired, actual []string) []FieldChange {
+	want := append([]string(nil), desired...)
+	got := append([]string(nil), actual...)
+	sort.Strings(want)
+	sort.Strings(got)
+	if strings.Join(want, ",") == strings.Join(got, ",") {
+		return nil
+	}
+	return []FieldChange{{Field: field, From: strings.Join(got, ","), To: strings.Join(want, ",")}}
+}
+
+// Command renders the remedy as the orbit command line to run.
+func (d *Drift) Command() string {
+	switch d.Remedy {
+	case RemedyDeploy:
+		return fmt.Sprintf("orbit deploy %s", d.Service)
+	case RemedyNone:
+		return ""
+	}
+	return "orbit " + d.Remedy
+}
@@ -0,0 +1,103 @@
+package orchestrator
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+// inspectRuntime returns one fixed container and image.
+type inspectRuntime struct {
+	Runtime
+	info     types.ContainerJSON
+	imageEnv []string
+}
+
+func (r *inspectRuntime) InspectContainer(context.Context, string) (types.ContainerJSON, error) {
+	return r.info, nil
+}
+
+func (r *inspectRuntime) ImageEnv(context.Context, string) ([]string, error) {
+	return r.imageEnv, nil
+}
+
+func runningContainer() types.ContainerJSON {
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			State: &types.ContainerState{Running: true, Status: "running"},
+			HostConfig: &containertypes.HostConfig{
+				Binds:         []string{"data:/var/lib/app"},
+				RestartPolicy: containertypes.RestartPolicy{Name: "unless-stopped"},
+				PortBindings:  nat.PortMap{"80/tcp": {{HostPort: "8080"}}},
+			},
+		},
+		Config: &containertypes.Config{
+			Image:  "app:1",
+			User:   "app", // the image's USER
+			Env:    []string{"PATH=/usr/bin", "MODE=prod"},
+			Labels: map[string]string{"team": "core", "orbit.service": "web", "org.opencontainers.image.version": "1"},
+		},
+	}
+}
+
+func TestDrift(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+	if err := db.PutServiceState(v1.ServiceState{Name: "web", Node: "local", ContainerID: "0123456789abcdef", Image: "app:1"}); err != nil {
+		t.Fatal(err)
+	}
+	rt := &inspectRuntime{info: runningContainer(), imageEnv: []string{"PATH=/usr/bin"}}
+	planner := NewPlanner(rt, db, log)
+
+	spec := v1.ServiceSpec{
+		Name: "web", Image: "app:1", Ports: []string{"8080:80"}, Volumes: []string{"data:/var/lib/app"},
+		Environment: map[string]string{"MODE": "prod"}, Labels: map[string]string{"team": "core"},
+	}
+	d, err := planner.Drift(context.Background(), spec, "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Drifted() || d.Remedy != RemedyNone {
+		t.Errorf("in-sync container drifted: %+v", d.Changes)
+	}
+
+	spec.Volumes = []string{"data:/var/lib/app", "./conf:/etc/app:ro"}
+	spec.Labels = map[string]string{"team": "edge"}
+	spec.RestartPolicy = "always"
+	d, _ = planner.Drift(context.Background(), spec, "local")
+	fields := map[string]FieldChange{}
+	for _, c := range d.Changes {
+		fields[c.Field] = c
+	}
+	if len(d.Changes) != 3 || fields["volumes"].To != "./conf:/etc/app:ro,data:/var/lib/app" ||
+		fields["labels.team"].From != "core" || fields["restart"].From != "unless-stopped" {
+		t.Errorf("changes = %+v", d.Changes)
+	}
+	if d.Remedy != RemedyUp || d.Command() != "orbit up" {
+		t.Errorf("remedy = %q, want %q", d.Remedy, RemedyUp)
+	}
+
+	spec.Image = "app:2"
+	if d, _ = planner.Drift(context.Background(), spec, "local"); d.Remedy != RemedyDeploy || d.Command() != "orbit deploy web" {
+		t.Errorf("image change: remedy = %q", d.Remedy)
+	}
+
+	d, _ = planner.Drift(context.Background(), v1.ServiceSpec{Name: "api", Image: "api:1"}, "local")
+	if d.Remedy != RemedyUp || !d.Drifted() {
+		t.Errorf("undeployed service: %+v", d)
+	}
+}
@@ -0,0 +1,222 @@
+// Package orchestrator: keeping a reader on every running orbit container.
+package orchestrator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+// FollowRetry is how long a Follower waits before reopening a dropped event
+// stream, and before re-running a reader that returned while its container
+// is still followed.
+const FollowRetry = 5 * time.Second
+
+// EventSource is the part of *Client a Follower uses.
+type EventSource interface {
+	ListContainers(ctx context.Context, serviceFilter string) ([]types.Container, error)
+	ContainerEvents(ctx context.Context) (<-chan v1.ContainerEvent, <-chan error)
+}
+
+// Followed is a running orbit container a Follower reads from.
+type Followed struct {
+	ID      string
+	Service string
+	Replica string // orbit.replica label; "" for the first replica
+	Image   string
+	Listed  bool // found by a listing rather than by its start event
+}
+
+// Follower keeps the readers of its watchers running for each running orbit
+// service container, leaving out one-off task containers. The container set
+// comes from a listing when the event stream opens and is then kept current
+// from start/die/destroy events, so a deploy's replacements are read as they
+// start; while the stream is down it is re-listed every poll interval. One
+// Follower serves every watcher, so the agent holds a single event
+// subscription however many consumers read its containers.
+type Follower struct {
+	source   EventSource
+	name     string // prefixes log messages
+	poll     time.Duration
+	watchers []watcher
+	log      *logger.Logger
+
+	mu      sync.Mutex
+	readers map[string]context.CancelFunc // container ID → its readers
+}
+
+// watcher is one consumer of a Follower's containers.
+type watcher struct {
+	read    func(c Followed) func(ctx context.Context) error
+	stopped func(id string, destroyed bool)
+}
+
+// NewFollower returns a Follower of the containers source lists. Add
+// consumers with Watch before running it.
+func NewFollower(source EventSource, name string, poll time.Duration, log *logger.Logger) *Follower {
+	return &Follower{
+		source:  source,
+		name:    name,
+		poll:    poll,
+		log:     log,
+		readers: make(map[string]context.CancelFunc),
+	}
+}
+
+// Watch adds a consumer. For each container the Follower calls read once,
+// and runs the function it returns until the container stops, again after
+// FollowRetry each time it returns early. stopped, if not nil, is called
+// after the container's readers are stopped; destroyed is set when the
+// container was removed rather than having exited. Watch must be called
+// before Run.
+func (f *Follower) Watch(read func(c Followed) func(ctx context.Context) error, stopped func(id string, destroyed bool)) *Follower {
+	f.watchers = append(f.watchers, watcher{read: read, stopped: stopped})
+	return f
+}
+
+// IDs returns the containers being read.
+func (f *Follower) IDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := make([]string, 0, len(f.readers))
+	for id := range f.readers {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Run follows containers until ctx is cancelled, then stops every reader.
+func (f *Follower) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.poll)
+	defer ticker.Stop()
+	defer f.stopAll()
+
+	evs := f.subscribe(ctx)
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if evs == nil {
+				f.resync(ctx) // no events while the stream is down
+			}
+		case ev, ok := <-evs:
+			if !ok {
+				evs, retry = nil, time.After(FollowRetry)
+				continue
+			}
+			f.apply(ctx, ev)
+		case <-retry:
+			evs, retry = f.subscribe(ctx), nil
+		}
+	}
+}
+
+// subscribe opens the event stream and then lists running containers, so
+// nothing that started before the stream opened is missed.
+func (f *Follower) subscribe(ctx context.Context) <-chan v1.ContainerEvent {
+	evs, _ := f.source.ContainerEvents(ctx)
+	f.resync(ctx)
+	return evs
+}
+
+// resync starts a reader for running containers without one and stops
+// those of containers no longer running.
+func (f *Follower) resync(ctx context.Context) {
+	containers, err := f.source.ListContainers(ctx, "")
+	if err != nil {
+		f.log.Debug(f.name+": list containers", "err", err)
+		return
+	}
+	running := make(map[string]bool, len(containers))
+	for _, c := range containers {
+		svc := c.Labels["orbit.service"]
+		if svc == "" || c.Labels["orbit.task"] != "" {
+			continue
+		}
+		running[c.ID] = true
+		f.start(ctx, Followed{ID: c.ID, Service: svc, Replica: c.Labels["orbit.replica"], Image: c.Image, Listed: true})
+	}
+	for _, id := range f.IDs() {
+		if !running[id] {
+			f.stop(id, false)
+		}
+	}
+}
+
+// apply starts or stops a reader for a container event.
+func (f *Follower) apply(ctx context.Context, ev v1.ContainerEvent) {
+	if ev.Task || ev.Service == "" {
+		return
+	}
+	switch ev.Action {
+	case "start":
+		f.start(ctx, Followed{ID: ev.ID, Service: ev.Service, Replica: ev.Replica, Image: ev.Image})
+	case "die":
+		f.stop(ev.ID, false)
+	case "destroy":
+		f.stop(ev.ID, true)
+	}
+}
+
+// start runs every watcher's reader for c unless they are running.
+func (f *Follower) start(ctx context.Context, c Followed) {
+	f.mu.Lock()
+	if _, ok := f.readers[c.ID]; ok {
+		f.mu.Unlock()
+		return
+	}
+	rctx, cancel := context.WithCancel(ctx)
+	f.readers[c.ID] = cancel
+	f.mu.Unlock()
+
+	for _, w := range f.watchers {
+		go f.loop(rctx, c.ID, w.read(c))
+	}
+}
+
+// loop runs read until ctx is cancelled.
+func (f *Follower) loop(ctx context.Context, id string, read func(ctx context.Context) error) {
+	for {
+		if err := read(ctx); err != nil && ctx.Err() == nil {
+			f.log.Debug(f.name+": follow", "container", id[:min(12, len(id))], "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(FollowRetry):
+		}
+	}
+}
+
+// stop cancels a container's readers. The stopped callbacks also run for a
+// container destroyed after it died, when no reader is left.
+func (f *Follower) stop(id string, destroyed bool) {
+	f.mu.Lock()
+	cancel, ok := f.readers[id]
+	delete(f.readers, id)
+	f.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	if !ok && !destroyed {
+		return
+	}
+	for _, w := range f.watchers {
+		if w.stopped != nil {
+			w.stopped(id, destroyed)
+		}
+	}
+}
+
+func (f *Follower) stopAll() {
+	for _, id := range f.IDs() {
+		f.stop(id, false)
+	}
+}
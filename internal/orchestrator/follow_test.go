@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+type followSource struct {
+	running []types.Container
+	events  chan v1.ContainerEvent
+}
+
+func (f *followSource) ListContainers(context.Context, string) ([]types.Container, error) {
+	return f.running, nil
+}
+
+func (f *followSource) ContainerEvents(context.Context) (<-chan v1.ContainerEvent, <-chan error) {
+	return f.events, nil
+}
+
+func TestFollower(t *testing.T) {
+	log, _ := logger.Init("error", "text", "", "", false)
+	src := &followSource{
+		running: []types.Container{
+			{ID: "a1", Image: "api:1", Labels: map[string]string{"orbit.service": "api"}},
+			{ID: "t1", Labels: map[string]string{"orbit.service": "api", "orbit.task": "true"}},
+			{ID: "x1"}, // not an orbit container
+		},
+		events: make(chan v1.ContainerEvent),
+	}
+
+	var mu sync.Mutex
+	var followed []Followed
+	var stopped []string
+	read := func(c Followed) func(context.Context) error {
+		mu.Lock()
+		followed = append(followed, c)
+		mu.Unlock()
+		return func(ctx context.Context) error { <-ctx.Done(); return nil }
+	}
+	var second []string // a second watcher's reads and stops
+	f := NewFollower(src, "test", time.Hour, log).Watch(read, func(id string, destroyed bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if destroyed {
+			id += " destroyed"
+		}
+		stopped = append(stopped, id)
+	}).Watch(func(c Followed) func(context.Context) error {
+		mu.Lock()
+		second = append(second, "read "+c.ID)
+		mu.Unlock()
+		return func(ctx context.Context) error { <-ctx.Done(); return nil }
+	}, func(id string, _ bool) {
+		mu.Lock()
+		second = append(second, "stop "+id)
+		mu.Unlock()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { f.Run(ctx); close(done) }()
+
+	src.events <- v1.ContainerEvent{ID: "a2", Service: "api", Replica: "2", Action: "start"}
+	src.events <- v1.ContainerEvent{ID: "a1", Service: "api", Action: "start"} // already followed
+	src.events <- v1.ContainerEvent{ID: "a1", Service: "api", Action: "die"}
+	src.events <- v1.ContainerEvent{ID: "a1", Service: "api", Action: "destroy"}
+	src.events <- v1.ContainerEvent{ID: "t2", Service: "api", Task: true, Action: "start"}
+	ids := f.IDs()
+	cancel()
+	<-done
+
+	if len(ids) != 1 || ids[0] != "a2" {
+		t.Errorf("IDs = %v, want [a2]", ids)
+	}
+	if len(followed) != 2 || followed[0] != (Followed{ID: "a1", Service: "api", Image: "api:1", Listed: true}) ||
+		followed[1] != (Followed{ID: "a2", Service: "api", Replica: "2"}) {
+		t.Errorf("followed = %+v", followed)
+	}
+	sort.Strings(stopped)
+	if want := []string{"a1", "a1 destroyed", "a2"}; !reflect.DeepEqual(stopped, want) {
+		t.Errorf("stopped = %v, want %v", stopped, want)
+	}
+	sort.Strings(second)
+	if want := []string{"read a1", "read a2", "stop a1", "stop a1", "stop a2"}; !reflect.DeepEqual(second, want) {
+		t.Errorf("second watcher = %v, want %v", second, want)
+	}
+}
@@ -0,0 +1,54 @@
+// Package orchestrator: plugin hooks fired around deploys, scales and ups,
+// and the shell commands of services' hooks: blocks.
+package orchestrator
+
+import (
+	"context"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// fireHook fires hook at hooks, if any.
+func fireHook(ctx context.Context, hooks v1.HookDispatcher, hook string, hctx v1.HookContext) {
+	if hooks != nil {
+		hooks.Fire(ctx, hook, hctx)
+	}
+}
+
+// firePostHook fires a post-operation hook with the outcome recorded in
+// Metadata. It ignores ctx's cancellation: an interrupted operation still
+// reports how it ended.
+func firePostHook(ctx context.Context, hooks v1.HookDispatcher, hook string, hctx v1.HookContext, result string, err error) {
+	meta := make(map[string]string, len(hctx.Metadata)+2)
+	for k, v := range hctx.Metadata {
+		meta[k] = v
+	}
+	meta["result"] = result
+	if err != nil {
+		meta["error"] = err.Error()
+	}
+	hctx.Metadata = meta
+	fireHook(context.WithoutCancel(ctx), hooks, hook, hctx)
+}
+
+// hookContext returns the HookContext common to a service's hooks on node.
+func hookContext(spec v1.ServiceSpec, node string, meta map[string]string) v1.HookContext {
+	return v1.HookContext{Service: &spec, Node: &v1.NodeSpec{Name: node}, Metadata: meta}
+}
+
+// CommandHooks runs the shell commands of a service's hooks: block in
+// orbit.yaml for hook, one of the v1.HookCmd constants; hctx.Service is the
+// service. containerID is the container that "container" commands exec in,
+// "" when the service has none. An error stops the operation.
+type CommandHooks interface {
+	Run(ctx context.Context, hook string, hctx v1.HookContext, containerID string) error
+}
+
+// runCommandHooks runs the service's commands for hook at hooks, if it has
+// any.
+func runCommandHooks(ctx context.Context, hooks CommandHooks, hook string, hctx v1.HookContext, containerID string) error {
+	if hooks == nil || len(hctx.Service.Hooks.Commands(hook)) == 0 {
+		return nil
+	}
+	return hooks.Run(ctx, hook, hctx, containerID)
+}
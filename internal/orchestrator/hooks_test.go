@@ -0,0 +1,176 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+// scaleRuntime runs containers until fail is set; other Runtime methods are
+// not used by Scale.
+type scaleRuntime struct {
+	Runtime
+	fail error
+}
+
+func (r *scaleRuntime) ListContainers(context.Context, string) ([]types.Container, error) {
+	return nil, nil
+}
+
+func (r *scaleRuntime) RunContainer(_ context.Context, _ v1.ServiceSpec, name string) (string, error) {
+	return name + "-0123456789ab", r.fail
+}
+
+type recordedHook struct {
+	name string
+	hctx v1.HookContext
+}
+
+type hookRecorder []recordedHook
+
+func (h *hookRecorder) Fire(_ context.Context, hook string, hctx v1.HookContext) {
+	*h = append(*h, recordedHook{hook, hctx})
+}
+
+func TestScaleFiresHooks(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	rt := &scaleRuntime{}
+	hooks := &hookRecorder{}
+	s := NewScaler(rt, db, log).WithHooks(hooks)
+	spec := v1.ServiceSpec{Name: "api", Image: "api:1"}
+
+	if err := s.Scale(context.Background(), spec, "local", 2); err != nil {
+		t.Fatalf("scale: %v", err)
+	}
+	if len(*hooks) != 2 || (*hooks)[0].name != v1.HookPreScale || (*hooks)[1].name != v1.HookPostScale {
+		t.Fatalf("hooks = %+v", *hooks)
+	}
+	pre, post := (*hooks)[0].hctx, (*hooks)[1].hctx
+	if pre.Service.Name != "api" || pre.Node.Name != "local" || pre.Metadata["replicas"] != "2" || pre.Metadata["current"] != "0" {
+		t.Errorf("pre = %+v (%v)", pre, pre.Metadata)
+	}
+	if post.Metadata["result"] != v1.DeployResultSuccess || pre.Metadata["result"] != "" {
+		t.Errorf("post metadata = %v, pre = %v", post.Metadata, pre.Metadata)
+	}
+
+	*hooks = nil
+	rt.fail = errors.New("no space left")
+	if err := s.Scale(context.Background(), spec, "local", 1); err == nil {
+		t.Fatal("scale succeeded with a failing runtime")
+	}
+	if post := (*hooks)[len(*hooks)-1].hctx; post.Metadata["result"] != v1.DeployResultFailure || post.Metadata["error"] == "" {
+		t.Errorf("post after failure = %v", post.Metadata)
+	}
+}
+
+func TestDeployPolicyRefuses(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	// A refused image is never pulled or run: the runtime has no methods.
+	hooks := &hookRecorder{}
+	var checked string
+	d := NewDeployer(&scaleRuntime{}, db, health.NewChecker(log), log).WithHooks(hooks).
+		WithPolicy(func(_ context.Context, spec v1.ServiceSpec, image string) error {
+			checked = spec.Name + " " + image
+			return errors.New("vulnerable")
+		})
+	spec := v1.ServiceSpec{Name: "api", Image: "api:1"}
+	if err := d.Deploy(context.Background(), spec, "local", DeployOptions{Tag: "2"}); err == nil || err.Error() != "vulnerable" {
+		t.Fatalf("deploy = %v, want the policy's error", err)
+	}
+	if checked != "api api:2" {
+		t.Errorf("policy checked %q", checked)
+	}
+	if post := (*hooks)[len(*hooks)-1]; post.name != v1.HookPostDeploy || post.hctx.Metadata["result"] != v1.DeployResultFailure {
+		t.Errorf("last hook = %+v", post)
+	}
+	recs, err := db.ListDeployments("api")
+	if err != nil || len(recs) != 1 || recs[0].Result != v1.DeployResultFailure {
+		t.Errorf("history = %+v, %v", recs, err)
+	}
+}
+
+// commandRecorder records hook commands run, failing those of fail.
+type commandRecorder struct {
+	ran  []string
+	fail string
+}
+
+func (c *commandRecorder) Run(_ context.Context, hook string, hctx v1.HookContext, containerID string) error {
+	c.ran = append(c.ran, hook+" "+hctx.ImageTo+" "+containerID)
+	if hook == c.fail {
+		return errors.New(hook + " failed")
+	}
+	return nil
+}
+
+func TestDeployCommandHooks(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	spec := v1.ServiceSpec{Name: "api", Image: "api:1", Hooks: &v1.HooksSpec{
+		PreDeploy:  []v1.HookCommand{{Command: "./migrate up"}},
+		PostDeploy: []v1.HookCommand{{Command: "purge-cdn"}},
+	}}
+	deploy := func(rt Runtime, commands *commandRecorder) error {
+		return NewDeployer(rt, db, health.NewChecker(log), log).WithCommandHooks(commands).
+			Deploy(context.Background(), spec, "local", DeployOptions{Tag: "2"})
+	}
+
+	// pre_deploy runs in the recorded container, none on the first deploy;
+	// post_deploy in the new one.
+	rt, commands := &verifyRuntime{}, &commandRecorder{}
+	for range 2 {
+		if err := deploy(rt, commands); err != nil {
+			t.Fatalf("deploy: %v", err)
+		}
+	}
+	want := []string{"pre_deploy api:2 ", "post_deploy api:2 0000000000000001",
+		"pre_deploy api:2 0000000000000001", "post_deploy api:2 0000000000000002"}
+	if strings.Join(commands.ran, "|") != strings.Join(want, "|") {
+		t.Errorf("ran = %q, want %q", commands.ran, want)
+	}
+
+	// A failing pre_deploy command refuses the deploy before the pull.
+	commands = &commandRecorder{fail: v1.HookCmdPreDeploy}
+	if err := deploy(&scaleRuntime{}, commands); err == nil || len(commands.ran) != 1 {
+		t.Fatalf("deploy = %v, ran %q", err, commands.ran)
+	}
+
+	// A failing post_deploy command rolls the new replicas back.
+	rt, commands = &verifyRuntime{}, &commandRecorder{fail: v1.HookCmdPostDeploy}
+	if err := deploy(rt, commands); err == nil {
+		t.Fatal("deploy succeeded with a failing post_deploy command")
+	}
+	if len(rt.runs) != 2 || rt.runs[1] != "api:1" {
+		t.Errorf("runs = %v, want api:2 rolled back to api:1", rt.runs)
+	}
+}
@@ -4,49 +4,157 @@ package orchestrator
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	v1 "github.com/f9-o/orbit/api/v1"
 	"github.com/f9-o/orbit/internal/core/logger"
 	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/pkg/errs"
 )
 
 // LifecycleManager handles 'orbit up' and 'orbit down' for a set of services.
 type LifecycleManager struct {
-	docker *Client
-	state  *state.DB
-	log    *logger.Logger
+	docker      Runtime
+	state       *state.DB
+	log         *logger.Logger
+	hooks       v1.HookDispatcher
+	commands    CommandHooks
+	checker     *health.Checker
+	policies    []Policy
+	services    []v1.ServiceSpec
+	stopTimeout time.Duration
 }
 
 // NewLifecycleManager constructs a LifecycleManager.
-func NewLifecycleManager(docker *Client, db *state.DB, log *logger.Logger) *LifecycleManager {
+func NewLifecycleManager(docker Runtime, db *state.DB, log *logger.Logger) *LifecycleManager {
 	return &LifecycleManager{docker: docker, state: db, log: log}
 }
 
-// Up ensures all services in specs are running.
-// Existing containers with the same name are skipped unless forceRecreate is true.
+// WithHooks fires OnPreDeploy and OnPostDeploy (action "up") at hooks
+// around each container Up starts.
+func (m *LifecycleManager) WithHooks(hooks v1.HookDispatcher) *LifecycleManager {
+	m.hooks = hooks
+	return m
+}
+
+// WithCommandHooks runs services' pre_deploy and post_deploy commands at
+// commands around each container Up starts, and pre_stop commands before
+// Down stops a service. A failing command fails that service: Up does not
+// start it, or leaves it started but reports the error; Down leaves it
+// running.
+func (m *LifecycleManager) WithCommandHooks(commands CommandHooks) *LifecycleManager {
+	m.commands = commands
+	return m
+}
+
+// WithChecker makes Up wait for each container it starts to pass its
+// startup and readiness probes — health_check's, or else the image's
+// HEALTHCHECK — and record whether it is ready, so the proxy routes to it
+// without an agent running. Without a checker a service with a readiness
+// probe is recorded not ready until the agent's health monitor probes it.
+func (m *LifecycleManager) WithChecker(c *health.Checker) *LifecycleManager {
+	m.checker = c
+	return m
+}
+
+// WithPolicy makes Up check each service it starts or changes against
+// policy, as a Deployer checks a deploy, leaving it as it is when refused.
+func (m *LifecycleManager) WithPolicy(policy Policy) *LifecycleManager {
+	m.policies = append(m.policies, policy)
+	return m
+}
+
+// WithServices gives Down orbit.yaml's services, for their depends_on and
+// stop_grace_period.
+func (m *LifecycleManager) WithServices(specs []v1.ServiceSpec) *LifecycleManager {
+	m.services = specs
+	return m
+}
+
+// WithStopTimeout makes Down wait d for each container to stop before
+// killing it, overriding every service's stop_grace_period.
+func (m *LifecycleManager) WithStopTimeout(d time.Duration) *LifecycleManager {
+	m.stopTimeout = d
+	return m
+}
+
+// Up ensures all services in specs are running, starting each after the
+// services it depends on. A running service whose spec changed is updated
+// in place when Docker allows it and recreated otherwise; an unchanged one
+// is skipped. forceRecreate recreates every service.
+// A service that fails to start does not stop the rest; every failure is
+// returned, as an *errs.MultiError when there are several.
 func (m *LifecycleManager) Up(ctx context.Context, specs []v1.ServiceSpec, node string, forceRecreate bool) error {
-	for _, spec := range specs {
-		if err := m.upOne(ctx, spec, node, forceRecreate); err != nil {
-			return fmt.Errorf("up %q: %w", spec.Name, err)
+	failed := errs.NewGroup(errs.ErrServiceStart, "up")
+	for _, spec := range startOrder(specs) {
+		if ctx.Err() != nil {
+			failed.Add(spec.Name, ctx.Err())
+			continue
 		}
+		failed.Add(spec.Name, m.upOne(ctx, spec, node, forceRecreate))
 	}
-	return nil
+	return failed.Err()
 }
 
-func (m *LifecycleManager) upOne(ctx context.Context, spec v1.ServiceSpec, node string, forceRecreate bool) error {
+func (m *LifecycleManager) upOne(ctx context.Context, spec v1.ServiceSpec, node string, forceRecreate bool) (err error) {
+	unlock, err := lockService(ctx, m.state, node, spec.Name, "up")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	existing, err := m.state.GetServiceState(node, spec.Name)
 	if err != nil {
 		return err
 	}
 
+	var update *UpdatePlan // of the running container, when it has changed
 	if existing != nil && existing.ContainerID != "" && !forceRecreate {
 		// Verify the container is actually running
 		info, inspectErr := m.docker.InspectContainer(ctx, existing.ContainerID)
 		if inspectErr == nil && info.State.Running {
-			m.log.Info("service already running, skipping", "service", spec.Name)
-			return nil
+			// Replica counts are left to deploy and scale.
+			changes := slices.DeleteFunc(NewPlanner(m.docker, m.state, m.log).diffService(ctx, spec, *existing),
+				func(c FieldChange) bool { return c.Field == "replicas" })
+			if len(changes) == 0 {
+				m.log.Info("service already running, skipping", "service", spec.Name)
+				return nil
+			}
+			u := PlanUpdate(changes)
+			update = &u
+		}
+	}
+
+	for _, policy := range m.policies {
+		if err := policy(ctx, spec, spec.Image); err != nil {
+			return err
+		}
+	}
+	if update != nil {
+		if update.InPlace() {
+			return m.updateInPlace(ctx, spec, existing.ContainerID, *update)
 		}
+		m.log.Info("service config changed, recreating", "service", spec.Name, "fields", strings.Join(update.Recreate, ","))
+	}
+
+	hctx := hookContext(spec, node, map[string]string{"action": "up"})
+	hctx.ImageTo = spec.Image
+	if existing != nil {
+		hctx.ImageFrom = existing.Image
+	}
+	fireHook(ctx, m.hooks, v1.HookPreDeploy, hctx)
+	defer func() {
+		firePostHook(ctx, m.hooks, v1.HookPostDeploy, hctx, resultOf(v1.DeploymentRecord{}, err), err)
+	}()
+	var current string
+	if existing != nil {
+		current = existing.ContainerID
+	}
+	if err := runCommandHooks(ctx, m.commands, v1.HookCmdPreDeploy, hctx, current); err != nil {
+		return err
 	}
 
 	// If forceRecreate or container is not running, stop + remove existing
@@ -62,23 +170,74 @@ func (m *LifecycleManager) upOne(ctx context.Context, spec v1.ServiceSpec, node
 	spec.Labels["orbit.node"] = node
 	spec.Labels["orbit.started"] = time.Now().UTC().Format(time.RFC3339)
 
-	id, err := m.docker.RunContainer(ctx, spec, spec.Name)
+	id, err := m.docker.RunContainer(ctx, withReplicaPorts(spec, 1), spec.Name)
 	if err != nil {
 		return err
 	}
 
-	return m.state.PutServiceState(v1.ServiceState{
+	st := v1.ServiceState{
 		Name:        spec.Name,
 		ContainerID: id,
 		Image:       spec.Image,
 		Status:      v1.StatusUnknown,
 		Node:        node,
 		StartedAt:   time.Now().UTC(),
-	})
+		Ready:       health.Resolve(spec.HealthCheck, health.ProbeReadiness) == nil, // else the health monitor marks it
+	}
+	var waitErr error
+	if m.checker != nil {
+		wctx, cancel := context.WithTimeout(ctx, DefaultDeployTimeout)
+		waitErr = m.checker.WaitReady(wctx, spec, id)
+		cancel()
+		st.Ready, st.Status = waitErr == nil, v1.StatusHealthy
+		if waitErr != nil {
+			m.log.Warn("up.healthcheck.failed", "service", spec.Name, "err", waitErr)
+			st.Status = v1.StatusUnhealthy
+		}
+	}
+	if existing != nil { // up replaces the primary container; the other replicas and any scale stay
+		st.Replicas, st.Scale = existing.Replicas, existing.Scale
+	}
+	if len(spec.Ports) > 0 {
+		if ctrs, err := m.docker.ListContainers(ctx, spec.Name); err == nil {
+			st.Ports = publishedPorts(ctrs)
+		}
+	}
+	if err := m.state.PutServiceState(st); err != nil {
+		return err
+	}
+	if waitErr != nil {
+		return errs.New(errs.ErrServiceHealthFail, "up.healthcheck", waitErr).
+			WithNode(node).
+			WithAdvice(fmt.Sprintf("The container is running but not ready. Run: orbit logs %s", spec.Name))
+	}
+	return runCommandHooks(ctx, m.commands, v1.HookCmdPostDeploy, hctx, id)
+}
+
+// updateInPlace applies u to every replica of spec, restoring the primary
+// container's name when it has lost it.
+func (m *LifecycleManager) updateInPlace(ctx context.Context, spec v1.ServiceSpec, primary string, u UpdatePlan) error {
+	ctrs, err := serviceContainers(ctx, m.docker, spec.Name)
+	if err != nil {
+		return errs.New(errs.ErrDockerRun, "up.update", err)
+	}
+	for _, c := range ctrs {
+		name := ""
+		if c.ID == primary {
+			name = spec.Name
+		}
+		if err := u.apply(ctx, m.docker, m.log, c.ID, name); err != nil {
+			return errs.New(errs.ErrDockerRun, "up.update", err).
+				WithAdvice(fmt.Sprintf("Recreate the service instead: orbit up --force (or orbit deploy %s)", spec.Name))
+		}
+	}
+	return nil
 }
 
-// Down stops and removes the specified services (or all if names is empty).
-// If removeVolumes is true, named volumes are also removed.
+// Down stops and removes the specified services (or all if names is empty),
+// every replica of each, stopping dependents before the services they
+// depend on. If removeVolumes is true, named volumes are also removed. Like
+// Up, it carries on past a service that fails and returns every failure.
 func (m *LifecycleManager) Down(ctx context.Context, node string, names []string, removeVolumes bool) error {
 	states, err := m.state.ListServiceStates(node)
 	if err != nil {
@@ -90,13 +249,50 @@ func (m *LifecycleManager) Down(ctx context.Context, node string, names []string
 		nameSet[n] = true
 	}
 
-	for _, s := range states {
+	failed := errs.NewGroup(errs.ErrServiceStop, "down")
+	for _, s := range stopOrder(states, m.services) {
 		if len(names) > 0 && !nameSet[s.Name] {
 			continue
 		}
-		m.log.Info("stopping service", "service", s.Name, "id", s.ContainerID[:12])
-		if err := m.docker.StopContainer(ctx, s.ContainerID, true); err != nil {
-			m.log.Warn("stop failed", "service", s.Name, "err", err)
+		failed.Add(s.Name, m.downOne(ctx, node, s))
+	}
+	return failed.Err()
+}
+
+func (m *LifecycleManager) downOne(ctx context.Context, node string, s v1.ServiceState) error {
+	unlock, err := lockService(ctx, m.state, node, s.Name, "down")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	timeout := m.stopTimeout
+	if i := slices.IndexFunc(m.services, func(spec v1.ServiceSpec) bool { return spec.Name == s.Name }); i >= 0 {
+		if timeout == 0 {
+			timeout = m.services[i].StopGracePeriod
+		}
+		hctx := hookContext(m.services[i], node, map[string]string{"action": "down"})
+		hctx.ImageTo = s.Image
+		if err := runCommandHooks(ctx, m.commands, v1.HookCmdPreStop, hctx, s.ContainerID); err != nil {
+			return err
+		}
+	}
+	var ids []string
+	if s.ContainerID != "" {
+		ids = append(ids, s.ContainerID)
+	}
+	if ctrs, err := serviceContainers(ctx, m.docker, s.Name); err == nil {
+		for _, c := range ctrs {
+			if c.ID != s.ContainerID {
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+
+	m.log.Info("stopping service", "service", s.Name, "id", s.ContainerID[:min(12, len(s.ContainerID))], "timeout", timeout)
+	for _, id := range ids {
+		if err := m.docker.StopContainerTimeout(ctx, id, timeout, true); err != nil {
+			m.log.Warn("stop failed", "service", s.Name, "id", id, "err", err)
 		}
 	}
 	return nil
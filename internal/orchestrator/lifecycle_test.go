@@ -0,0 +1,162 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// upRuntime starts every container except those named in fail.
+type upRuntime struct {
+	Runtime
+	fail map[string]bool
+}
+
+func (r *upRuntime) RunContainer(_ context.Context, _ v1.ServiceSpec, name string) (string, error) {
+	if r.fail[name] {
+		return "", errors.New("port is already allocated")
+	}
+	return name + "-0123456789ab", nil
+}
+
+func TestUpCarriesOnPastFailures(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	rt := &upRuntime{fail: map[string]bool{"web": true, "worker": true}}
+	specs := []v1.ServiceSpec{{Name: "web", Image: "web:1"}, {Name: "api", Image: "api:1"}, {Name: "worker", Image: "worker:1"}}
+	err = NewLifecycleManager(rt, db, log).Up(context.Background(), specs, "local", false)
+
+	var multi *errs.MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("err = %v, want a MultiError", err)
+	}
+	if multi.Total != 3 || len(multi.Errors) != 2 || multi.Errors[0].Node != "web" || multi.Errors[1].Node != "worker" {
+		t.Errorf("multi = %+v", multi)
+	}
+	if !errs.IsCode(err, errs.ErrServiceStart) {
+		t.Errorf("code lost: %v", err)
+	}
+	if s, _ := db.GetServiceState("local", "api"); s == nil {
+		t.Error("api was not started after web failed")
+	}
+}
+
+func TestUpChecksPolicies(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	var checked []string
+	refuse := func(_ context.Context, spec v1.ServiceSpec, image string) error {
+		checked = append(checked, image)
+		if spec.Name == "web" {
+			return errs.New(errs.ErrPolicyApproval, "policy.approval", errors.New("denied"))
+		}
+		return nil
+	}
+	specs := []v1.ServiceSpec{{Name: "web", Image: "web:1"}, {Name: "api", Image: "api:1"}}
+	err = NewLifecycleManager(&upRuntime{}, db, log).WithPolicy(refuse).Up(context.Background(), specs, "local", false)
+
+	if !errs.IsCode(err, errs.ErrPolicyApproval) {
+		t.Errorf("err = %v, want the policy's", err)
+	}
+	slices.Sort(checked)
+	if want := []string{"api:1", "web:1"}; !slices.Equal(checked, want) {
+		t.Errorf("checked %v, want %v", checked, want)
+	}
+	if s, _ := db.GetServiceState("local", "web"); s != nil {
+		t.Error("web was started though its policy refused it")
+	}
+	if s, _ := db.GetServiceState("local", "api"); s == nil {
+		t.Error("api was not started")
+	}
+}
+
+// orderRuntime records the order containers start and stop in; web has a
+// second replica.
+type orderRuntime struct {
+	Runtime
+	events []string
+}
+
+func (r *orderRuntime) RunContainer(_ context.Context, _ v1.ServiceSpec, name string) (string, error) {
+	r.events = append(r.events, "start "+name)
+	return name + "-0123456789ab", nil
+}
+
+func (r *orderRuntime) ListContainers(_ context.Context, service string) ([]types.Container, error) {
+	ctrs := []types.Container{{ID: service + "-0123456789ab"}}
+	if service == "web" {
+		ctrs = append(ctrs, types.Container{ID: "web-2-0123456789ab"})
+	}
+	return ctrs, nil
+}
+
+func (r *orderRuntime) StopContainerTimeout(_ context.Context, id string, timeout time.Duration, _ bool) error {
+	r.events = append(r.events, fmt.Sprintf("stop %s %s", id[:len(id)-13], timeout))
+	return nil
+}
+
+func TestUpAndDownFollowDependencies(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	specs := []v1.ServiceSpec{
+		{Name: "web", Image: "web:1", DependsOn: []string{"api"}, StopGracePeriod: 30 * time.Second},
+		{Name: "api", Image: "api:1", DependsOn: []string{"db"}},
+		{Name: "db", Image: "postgres:16", StopGracePeriod: time.Minute},
+	}
+	rt := &orderRuntime{}
+	lm := NewLifecycleManager(rt, db, log).WithServices(specs)
+	if err := lm.Up(context.Background(), specs, "local", false); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := db.PutServiceState(v1.ServiceState{Name: "old", ContainerID: "old-0123456789ab", Node: "local"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lm.Down(context.Background(), "local", nil, false); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	want := []string{
+		"start db", "start api", "start web",
+		"stop old 0s", "stop web 30s", "stop web-2 30s", "stop api 0s", "stop db 1m0s",
+	}
+	if !slices.Equal(rt.events, want) {
+		t.Errorf("events = %q\nwant %q", rt.events, want)
+	}
+
+	rt.events = nil
+	if err := lm.WithStopTimeout(5*time.Second).Down(context.Background(), "local", []string{"db"}, false); err != nil {
+		t.Fatalf("down db: %v", err)
+	}
+	if !slices.Equal(rt.events, []string{"stop db 5s"}) {
+		t.Errorf("with --timeout: events = %q", rt.events)
+	}
+}
@@ -0,0 +1,28 @@
+// Package orchestrator: per-service locks held for the duration of an operation.
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/f9-o/orbit/internal/core/shutdown"
+	"github.com/f9-o/orbit/internal/core/state"
+)
+
+// lockService takes the service lock for operation and registers its release
+// with the shutdown manager, so an interrupt that outruns the caller's defer
+// still frees it.
+func lockService(ctx context.Context, db *state.DB, node, service, operation string) (unlock func(), err error) {
+	release, err := db.AcquireLock(node, service, operation)
+	if err != nil {
+		return nil, err
+	}
+	forget := shutdown.Register(ctx, fmt.Sprintf("release %s lock on %s", operation, service), func(context.Context) error {
+		release()
+		return nil
+	})
+	return func() {
+		forget()
+		release()
+	}, nil
+}
@@ -0,0 +1,72 @@
+// Package orchestrator: the one-off migration container a deploy runs before
+// starting the new image.
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// DefaultMigrationTimeout bounds a migration with no timeout of its own.
+const DefaultMigrationTimeout = 10 * time.Minute
+
+// migrationTailLines is how much of a failed migration's output its error
+// quotes; the log has all of it.
+const migrationTailLines = 5
+
+// migrate runs spec.Migrations in a task container of the service built from
+// image, or the migration's own image, and returns an ErrServiceMigration
+// error unless it exits 0 within its timeout.
+func (d *Deployer) migrate(ctx context.Context, spec v1.ServiceSpec, node, image string) error {
+	m := spec.Migrations
+	task := spec
+	task.Image = image
+	if m.Image != "" {
+		task.Image = m.Image
+	}
+	cmd, err := splitCommand(m.Command)
+	if err != nil {
+		return errs.Newf(errs.ErrServiceMigration, "deploy.migrate", "service %q: migrations: command: %v", spec.Name, err)
+	}
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = DefaultMigrationTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	d.log.Info("deploy.migrate", "service", spec.Name, "image", task.Image, "command", strings.Join(cmd, " "))
+	var out bytes.Buffer
+	code, err := d.docker.RunTask(ctx, task, TaskOptions{Cmd: cmd, Stdout: &out, Stderr: &out})
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	for _, line := range lines {
+		if line != "" {
+			d.log.Info("deploy.migrate.output", "service", spec.Name, "line", line)
+		}
+	}
+
+	fail := func(format string, args ...any) error {
+		msg := fmt.Sprintf(format, args...)
+		if tail := lines[max(0, len(lines)-migrationTailLines):]; out.Len() > 0 {
+			msg += ":\n  " + strings.Join(tail, "\n  ")
+		}
+		return errs.Newf(errs.ErrServiceMigration, "deploy.migrate", "migration of %s %s", spec.Name, msg).
+			WithNode(node)
+	}
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return fail("timed out after %s", timeout)
+	case err != nil:
+		return fail("failed: %v", err)
+	case code != 0:
+		return fail("exited with code %d", code)
+	}
+	d.log.Info("deploy.migrate.done", "service", spec.Name)
+	return nil
+}
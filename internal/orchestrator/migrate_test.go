@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// migrateRuntime is a verifyRuntime whose task containers exit with code.
+type migrateRuntime struct {
+	verifyRuntime
+	code  int
+	tasks []string
+}
+
+func (r *migrateRuntime) RunTask(_ context.Context, spec v1.ServiceSpec, opts TaskOptions) (int, error) {
+	r.tasks = append(r.tasks, spec.Image+" "+strings.Join(opts.Cmd, " "))
+	io.WriteString(opts.Stdout, "applying 0042_add_orders\n")
+	return r.code, nil
+}
+
+func TestDeployMigrations(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	spec := v1.ServiceSpec{Name: "api", Image: "api:1", Migrations: &v1.MigrationSpec{Command: v1.ShellCommand{"./manage.py migrate"}}}
+	var steps []DeployStep
+	deploy := func(rt *migrateRuntime) error {
+		steps = nil
+		return NewDeployer(rt, db, health.NewChecker(log), log).WithProgress(func(s DeployStep) { steps = append(steps, s) }).
+			Deploy(context.Background(), spec, "local", DeployOptions{Tag: "2"})
+	}
+
+	rt := &migrateRuntime{}
+	if err := deploy(rt); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if len(rt.tasks) != 1 || rt.tasks[0] != "api:2 ./manage.py migrate" || len(rt.runs) != 1 {
+		t.Errorf("tasks = %q, runs = %q", rt.tasks, rt.runs)
+	}
+	if !strings.Contains(stepsString(steps), "pull migrate start") {
+		t.Errorf("steps = %v, want migrate before start", steps)
+	}
+
+	// A failing migration fails the deploy before any replica is replaced.
+	rt = &migrateRuntime{code: 3}
+	err = deploy(rt)
+	if !errs.IsCode(err, errs.ErrServiceMigration) || !strings.Contains(err.Error(), "exited with code 3") ||
+		!strings.Contains(err.Error(), "0042_add_orders") {
+		t.Fatalf("deploy = %v, want the migration's failure", err)
+	}
+	if len(rt.runs) != 0 {
+		t.Errorf("runs = %q after a failed migration", rt.runs)
+	}
+
+	// A migration image of its own runs instead of the service's.
+	spec.Migrations = &v1.MigrationSpec{Image: "api-migrations:2"}
+	rt = &migrateRuntime{}
+	if err := deploy(rt); err != nil || len(rt.tasks) != 1 || rt.tasks[0] != "api-migrations:2 " {
+		t.Errorf("deploy = %v, tasks = %q", err, rt.tasks)
+	}
+
+	// A rollback does not migrate.
+	recs, err := db.ListDeployments("api")
+	if err != nil || len(recs) == 0 {
+		t.Fatalf("history = %+v, %v", recs, err)
+	}
+	rt = &migrateRuntime{}
+	if err := NewDeployer(rt, db, health.NewChecker(log), log).Rollback(context.Background(), spec, "local", recs[0]); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if len(rt.tasks) != 0 {
+		t.Errorf("rollback migrated: %q", rt.tasks)
+	}
+}
+
+func stepsString(steps []DeployStep) string {
+	s := make([]string, len(steps))
+	for i, step := range steps {
+		s[i] = string(step)
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,57 @@
+// Package orchestrator: the order services start and stop in.
+package orchestrator
+
+import (
+	"slices"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// startOrder sorts specs so every service follows the services it depends
+// on, keeping orbit.yaml's order otherwise. Config validation rejects
+// dependency cycles; a service caught in one anyway keeps its place after
+// the rest.
+func startOrder(specs []v1.ServiceSpec) []v1.ServiceSpec {
+	known := map[string]bool{}
+	for _, s := range specs {
+		known[s.Name] = true
+	}
+	placed := map[string]bool{}
+	ordered := make([]v1.ServiceSpec, 0, len(specs))
+	for progress := true; progress; {
+		progress = false
+		for _, s := range specs {
+			if placed[s.Name] || slices.ContainsFunc(s.DependsOn, func(dep string) bool { return known[dep] && !placed[dep] }) {
+				continue
+			}
+			ordered = append(ordered, s)
+			placed[s.Name], progress = true, true
+		}
+	}
+	for _, s := range specs {
+		if !placed[s.Name] {
+			ordered = append(ordered, s)
+			placed[s.Name] = true
+		}
+	}
+	return ordered
+}
+
+// stopOrder sorts services for stopping: dependents before the services
+// they depend on. Services without a spec have no known dependencies and
+// stop first.
+func stopOrder(states []v1.ServiceState, specs []v1.ServiceSpec) []v1.ServiceState {
+	pos := map[string]int{}
+	for i, s := range startOrder(specs) {
+		pos[s.Name] = i
+	}
+	at := func(name string) int {
+		if i, ok := pos[name]; ok {
+			return i
+		}
+		return len(specs)
+	}
+	out := slices.Clone(states)
+	slices.SortStableFunc(out, func(a, b v1.ServiceState) int { return at(b.Name) - at(a.Name) })
+	return out
+}
@@ -0,0 +1,292 @@
+// Package orchestrator: desired-vs-actual planning for `orbit plan` and --dry-run.
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+)
+
+// ChangeAction is the planned action for a single service.
+type ChangeAction string
+
+const (
+	ActionCreate    ChangeAction = "create"
+	ActionUpdate    ChangeAction = "update"
+	ActionUnchanged ChangeAction = "unchanged"
+	ActionDestroy   ChangeAction = "destroy"
+)
+
+// FieldChange describes a single differing field between desired and actual state.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// ServiceChange is the planned change for one service.
+type ServiceChange struct {
+	Service string        `json:"service"`
+	Action  ChangeAction  `json:"action"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// Plan is the ordered set of changes needed to converge a node on orbit.yaml.
+type Plan struct {
+	Node     string          `json:"node"`
+	Services []ServiceChange `json:"services"`
+}
+
+// Count returns how many services are planned for the given action.
+func (p *Plan) Count(action ChangeAction) int {
+	n := 0
+	for _, s := range p.Services {
+		if s.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// HasChanges reports whether applying the plan would modify anything.
+func (p *Plan) HasChanges() bool {
+	return p.Count(ActionUnchanged) != len(p.Services)
+}
+
+// Planner compares desired specs against persisted state and live containers.
+type Planner struct {
+	docker Runtime // optional — when nil, only state is consulted
+	state  *state.DB
+	log    *logger.Logger
+}
+
+// NewPlanner constructs a Planner. docker may be nil if the daemon is unreachable;
+// the plan then only reflects differences visible in the state DB.
+func NewPlanner(docker Runtime, db *state.DB, log *logger.Logger) *Planner {
+	if c, ok := docker.(*Client); ok && c == nil {
+		docker = nil // a nil *Client would otherwise be a non-nil Runtime
+	}
+	return &Planner{docker: docker, state: db, log: log}
+}
+
+// Plan computes the changes needed for specs on node. Services present in state
+// but absent from specs are planned for destruction when prune is true.
+func (p *Planner) Plan(ctx context.Context, specs []v1.ServiceSpec, node string, prune bool) (*Plan, error) {
+	states, err := p.state.ListServiceStates(node)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]v1.ServiceState, len(states))
+	for _, s := range states {
+		current[s.Name] = s
+	}
+
+	plan := &Plan{Node: node}
+	desired := map[string]bool{}
+	for _, spec := range specs {
+		desired[spec.Name] = true
+		s, ok := current[spec.Name]
+		if !ok || s.ContainerID == "" {
+			plan.Services = append(plan.Services, ServiceChange{
+				Service: spec.Name,
+				Action:  ActionCreate,
+				Changes: []FieldChange{{Field: "image", To: spec.Image}},
+			})
+			continue
+		}
+
+		changes := p.diffService(ctx, spec, s)
+		action := ActionUnchanged
+		if len(changes) > 0 {
+			action = ActionUpdate
+		}
+		plan.Services = append(plan.Services, ServiceChange{Service: spec.Name, Action: action, Changes: changes})
+	}
+
+	if prune {
+		for _, s := range states {
+			if !desired[s.Name] {
+				plan.Services = append(plan.Services, ServiceChange{Service: s.Name, Action: ActionDestroy})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// PlanDown plans the removal of the named services (all services if names is empty).
+func (p *Planner) PlanDown(node string, names []string) (*Plan, error) {
+	states, err := p.state.ListServiceStates(node)
+	if err != nil {
+		return nil, err
+	}
+	nameSet := map[string]bool{}
+	for _, n := range names {
+		nameSet[n] = true
+	}
+
+	plan := &Plan{Node: node}
+	for _, s := range states {
+		if len(names) > 0 && !nameSet[s.Name] {
+			continue
+		}
+		plan.Services = append(plan.Services, ServiceChange{Service: s.Name, Action: ActionDestroy})
+	}
+	return plan, nil
+}
+
+// diffService compares a spec against its persisted state and, when Docker is
+// available, against the live container configuration.
+func (p *Planner) diffService(ctx context.Context, spec v1.ServiceSpec, s v1.ServiceState) []FieldChange {
+	var changes []FieldChange
+	if s.Image != spec.Image {
+		changes = append(changes, FieldChange{Field: "image", From: s.Image, To: spec.Image})
+	}
+	changes = append(changes, diffReplicas(spec, s)...)
+	if p.docker == nil {
+		return changes
+	}
+
+	info, err := p.docker.InspectContainer(ctx, s.ContainerID)
+	if err != nil {
+		p.log.Debug("plan.inspect failed", "service", spec.Name, "err", err)
+		return append(changes, FieldChange{Field: "container", From: "missing", To: "running"})
+	}
+	if info.State != nil && !info.State.Running {
+		changes = append(changes, FieldChange{Field: "container", From: info.State.Status, To: "running"})
+	}
+
+	imageEnv, err := p.docker.ImageEnv(ctx, info.Image)
+	if err != nil {
+		p.log.Debug("plan.image_env failed", "service", spec.Name, "err", err)
+	}
+	changes = append(changes, diffEnv(spec.Environment, containerEnv(info, imageEnv))...)
+	changes = append(changes, diffPorts(spec.Ports, info)...)
+	changes = append(changes, diffVolumes(spec.Volumes, info)...)
+	changes = append(changes, diffLabels(spec.Labels, info)...)
+	changes = append(changes, diffRuntime(spec, info)...)
+	changes = append(changes, diffProcess(spec, info)...)
+	changes = append(changes, diffSecurity(spec, info)...)
+	changes = append(changes, diffSystem(spec, info)...)
+	changes = append(changes, diffNetworks(spec, info)...)
+	changes = append(changes, diffName(spec, info)...)
+	return changes
+}
+
+// containerEnv returns the user-supplied environment of a container — its env
+// minus entries inherited unchanged from the image.
+func containerEnv(info types.ContainerJSON, imageEnv []string) map[string]string {
+	inherited := map[string]bool{}
+	for _, e := range imageEnv {
+		inherited[e] = true
+	}
+	env := map[string]string{}
+	if info.Config == nil {
+		return env
+	}
+	for _, e := range info.Config.Env {
+		if inherited[e] {
+			continue
+		}
+		k, v, _ := strings.Cut(e, "=")
+		env[k] = v
+	}
+	return env
+}
+
+func diffEnv(desired, actual map[string]string) []FieldChange {
+	keys := map[string]bool{}
+	for k := range desired {
+		keys[k] = true
+	}
+	for k := range actual {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []FieldChange
+	for _, k := range sorted {
+		want, inWant := desired[k]
+		got, inGot := actual[k]
+		if inWant && inGot && want == got {
+			continue
+		}
+		fc := FieldChange{Field: "env." + k, From: maskValue(k, got), To: maskValue(k, want)}
+		if !inGot {
+			fc.From = ""
+		}
+		if !inWant {
+			fc.To = ""
+		}
+		changes = append(changes, fc)
+	}
+	return changes
+}
+
+// diffPorts compares the port entries of the spec with the container's
+// bindings. Both sides are put in one form first, so "80:80/tcp" matches
+// "80:80" and a range matches the ports it covers; entries nat cannot parse
+// are compared as written.
+func diffPorts(desired []string, info types.ContainerJSON) []FieldChange {
+	want := desired
+	if _, bindings, err := nat.ParsePortSpecs(desired); err == nil {
+		want = portEntries(bindings)
+	}
+	var actual []string
+	if info.HostConfig != nil {
+		actual = portEntries(info.HostConfig.PortBindings)
+	}
+	want = append([]string(nil), want...)
+	sort.Strings(want)
+	if strings.Join(want, ",") == strings.Join(actual, ",") {
+		return nil
+	}
+	return []FieldChange{{Field: "ports", From: strings.Join(actual, ","), To: strings.Join(want, ",")}}
+}
+
+// portEntries renders bindings as sorted "[ip:]host:container[/proto]"
+// entries, leaving out the default tcp.
+func portEntries(bindings nat.PortMap) []string {
+	var entries []string
+	for port, bs := range bindings {
+		ctr := port.Port()
+		if proto := port.Proto(); proto != "tcp" {
+			ctr += "/" + proto
+		}
+		for _, b := range bs {
+			entry := b.HostPort + ":" + ctr
+			if b.HostIP != "" {
+				entry = b.HostIP + ":" + entry
+			}
+			entries = append(entries, entry)
+		}
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// maskValue hides values of sensitive-looking keys in plan output.
+func maskValue(key, val string) string {
+	if val != "" && config.IsSensitiveKey(key) {
+		return "(sensitive)"
+	}
+	return val
+}
+
+// String renders a one-line summary, e.g. "1 to create, 2 to update, 0 to destroy".
+func (p *Plan) String() string {
+	return fmt.Sprintf("%d to create, %d to update, %d to destroy",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionDestroy))
+}
@@ -0,0 +1,61 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestDiffPorts(t *testing.T) {
+	cases := []struct {
+		name    string
+		desired []string
+		actual  nat.PortMap
+		changed bool
+		to      string // To of the change
+	}{
+		{"same", []string{"8080:80"},
+			nat.PortMap{"80/tcp": {{HostPort: "8080"}}}, false, ""},
+		{"host ip", []string{"127.0.0.1:80:80"},
+			nat.PortMap{"80/tcp": {{HostIP: "127.0.0.1", HostPort: "80"}}}, false, ""},
+		{"explicit tcp", []string{"80:80/tcp"},
+			nat.PortMap{"80/tcp": {{HostPort: "80"}}}, false, ""},
+		{"udp", []string{"53:53/udp"},
+			nat.PortMap{"53/udp": {{HostPort: "53"}}}, false, ""},
+		{"bare port", []string{"80"},
+			nat.PortMap{"80/tcp": {{HostPort: ""}}}, false, ""},
+		{"range", []string{"8000-8001:9000-9001"},
+			nat.PortMap{"9000/tcp": {{HostPort: "8000"}}, "9001/tcp": {{HostPort: "8001"}}}, false, ""},
+		{"host port changed", []string{"8081:80"},
+			nat.PortMap{"80/tcp": {{HostPort: "8080"}}}, true, "8081:80"},
+		{"host ip dropped", []string{"80:80"},
+			nat.PortMap{"80/tcp": {{HostIP: "127.0.0.1", HostPort: "80"}}}, true, "80:80"},
+		{"protocol changed", []string{"53:53/udp"},
+			nat.PortMap{"53/tcp": {{HostPort: "53"}}}, true, "53:53/udp"},
+		{"added", []string{"80:80", "443:443"},
+			nat.PortMap{"80/tcp": {{HostPort: "80"}}}, true, "443:443,80:80"},
+		{"removed", nil,
+			nat.PortMap{"80/tcp": {{HostPort: "80"}}}, true, ""},
+		{"unparseable", []string{"web:80"},
+			nat.PortMap{"80/tcp": {{HostPort: "80"}}}, true, "web:80"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+				HostConfig: &containertypes.HostConfig{PortBindings: tc.actual},
+			}}
+			changes := diffPorts(tc.desired, info)
+			if !tc.changed {
+				if len(changes) != 0 {
+					t.Errorf("changes = %+v, want none", changes)
+				}
+				return
+			}
+			if len(changes) != 1 || changes[0].To != tc.to {
+				t.Errorf("changes = %+v, want one to %q", changes, tc.to)
+			}
+		})
+	}
+}
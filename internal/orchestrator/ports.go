@@ -0,0 +1,115 @@
+// Package orchestrator: host ports for services with several replicas.
+package orchestrator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// replicaPortMode is the spec's deploy.replica_ports.
+func replicaPortMode(spec v1.ServiceSpec) string {
+	if spec.Deploy == nil {
+		return ""
+	}
+	return spec.Deploy.ReplicaPorts
+}
+
+// hostPorts returns the host side of spec's "host:container" port entries.
+func hostPorts(spec v1.ServiceSpec) []string {
+	var hosts []string
+	for _, p := range spec.Ports {
+		if host, _, ok := strings.Cut(p, ":"); ok {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// CheckReplicaPorts reports whether replicas replicas of spec can run side
+// by side on one node: a service that publishes host ports needs
+// deploy.replica_ports to run more than one.
+func CheckReplicaPorts(spec v1.ServiceSpec, replicas int) error {
+	hosts := hostPorts(spec)
+	if replicas <= 1 || len(hosts) == 0 {
+		return nil
+	}
+	switch replicaPortMode(spec) {
+	case v1.ReplicaPortsEphemeral:
+		return nil
+	case v1.ReplicaPortsOffset:
+		for _, h := range hosts {
+			n, err := strconv.Atoi(h)
+			if err != nil {
+				return errs.Newf(errs.ErrHostPortConflict, "scale", "service %q: host port %q cannot be offset per replica", spec.Name, h).
+					WithAdvice("Use a single host port per entry, or set deploy.replica_ports: ephemeral")
+			}
+			if last := n + replicas - 1; last > 65535 {
+				return errs.Newf(errs.ErrHostPortConflict, "scale", "service %q: %d replicas would need host port %d", spec.Name, replicas, last)
+			}
+		}
+		return nil
+	}
+	return errs.Newf(errs.ErrHostPortConflict, "scale",
+		"service %q publishes host port %s, which only one of %d replicas can bind", spec.Name, hosts[0], replicas).
+		WithAdvice("Set deploy.replica_ports in orbit.yaml: offset gives replica N host port + N-1, ephemeral lets Docker pick free ports for the proxy to route to")
+}
+
+// replicaPorts returns the port entries replica index (1-based) of spec
+// publishes under its deploy.replica_ports mode.
+func replicaPorts(spec v1.ServiceSpec, index int) []string {
+	mode := replicaPortMode(spec)
+	if len(spec.Ports) == 0 || mode == "" || (mode == v1.ReplicaPortsOffset && index <= 1) {
+		return spec.Ports
+	}
+	ports := make([]string, len(spec.Ports))
+	for i, p := range spec.Ports {
+		ports[i] = p
+		host, ctr, ok := strings.Cut(p, ":")
+		if !ok {
+			continue
+		}
+		switch mode {
+		case v1.ReplicaPortsEphemeral:
+			ports[i] = ":" + ctr
+		case v1.ReplicaPortsOffset:
+			if n, err := strconv.Atoi(host); err == nil {
+				ports[i] = fmt.Sprintf("%d:%s", n+index-1, ctr)
+			}
+		}
+	}
+	return ports
+}
+
+// withReplicaPorts returns spec with the ports replica index publishes.
+func withReplicaPorts(spec v1.ServiceSpec, index int) v1.ServiceSpec {
+	spec.Ports = replicaPorts(spec, index)
+	return spec
+}
+
+// publishedPorts lists the "host:container" TCP ports ctrs publish, as
+// recorded in ServiceState.Ports.
+func publishedPorts(ctrs []types.Container) []string {
+	seen := map[string]bool{}
+	var ports []string
+	for _, c := range ctrs {
+		for _, p := range c.Ports {
+			if p.PublicPort == 0 || p.Type != "tcp" {
+				continue
+			}
+			entry := fmt.Sprintf("%d:%d", p.PublicPort, p.PrivatePort)
+			if !seen[entry] { // listed once per address family
+				seen[entry] = true
+				ports = append(ports, entry)
+			}
+		}
+	}
+	sort.Strings(ports)
+	return ports
+}
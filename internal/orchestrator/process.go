@@ -0,0 +1,136 @@
+// Package orchestrator: a service's process — command, entrypoint, working
+// directory, init and how it is stopped.
+package orchestrator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// splitCommand returns the arguments of c. A single element is a command
+// line, split into words as a shell would: on unquoted whitespace, with
+// single quotes taken literally and backslash escapes outside them.
+func splitCommand(c v1.ShellCommand) ([]string, error) {
+	if len(c) != 1 {
+		return c, nil
+	}
+	line := c[0]
+	var (
+		args    []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", line)
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
+
+// stopTimeout is spec's stop_grace_period in whole seconds, at least one.
+func stopTimeout(spec v1.ServiceSpec) int {
+	return max(1, int(spec.StopGracePeriod.Round(time.Second)/time.Second))
+}
+
+// applyProcess sets spec's process overrides on a container's config.
+func applyProcess(spec v1.ServiceSpec, cfg *containertypes.Config, hostCfg *containertypes.HostConfig) error {
+	cmd, err := splitCommand(spec.Command)
+	if err != nil {
+		return fmt.Errorf("service %q: command: %w", spec.Name, err)
+	}
+	entrypoint, err := splitCommand(spec.Entrypoint)
+	if err != nil {
+		return fmt.Errorf("service %q: entrypoint: %w", spec.Name, err)
+	}
+	if len(cmd) > 0 {
+		cfg.Cmd = cmd
+	}
+	if len(entrypoint) > 0 {
+		cfg.Entrypoint = entrypoint
+	}
+	cfg.WorkingDir = spec.WorkingDir
+	cfg.StopSignal = spec.StopSignal
+	if spec.StopGracePeriod > 0 {
+		secs := stopTimeout(spec)
+		cfg.StopTimeout = &secs
+	}
+	if spec.Init {
+		on := true
+		hostCfg.Init = &on
+	}
+	return nil
+}
+
+// diffProcess compares the process overrides the spec sets with the
+// container's. Those it leaves unset come from the image and are not drift.
+func diffProcess(spec v1.ServiceSpec, info types.ContainerJSON) []FieldChange {
+	if info.Config == nil || info.HostConfig == nil {
+		return nil
+	}
+	var changes []FieldChange
+	diff := func(field, want, got string) {
+		if want != "" && want != got {
+			changes = append(changes, FieldChange{Field: field, From: got, To: want})
+		}
+	}
+	cmd, _ := splitCommand(spec.Command)
+	diff("command", strings.Join(cmd, " "), strings.Join(info.Config.Cmd, " "))
+	entrypoint, _ := splitCommand(spec.Entrypoint)
+	diff("entrypoint", strings.Join(entrypoint, " "), strings.Join(info.Config.Entrypoint, " "))
+	diff("working_dir", spec.WorkingDir, info.Config.WorkingDir)
+	diff("stop_signal", spec.StopSignal, info.Config.StopSignal)
+	if spec.StopGracePeriod > 0 {
+		got := ""
+		if t := info.Config.StopTimeout; t != nil {
+			got = (time.Duration(*t) * time.Second).String()
+		}
+		diff("stop_grace_period", (time.Duration(stopTimeout(spec)) * time.Second).String(), got)
+	}
+	if got := info.HostConfig.Init != nil && *info.HostConfig.Init; got != spec.Init {
+		changes = append(changes, FieldChange{Field: "init", From: strconv.FormatBool(got), To: strconv.FormatBool(spec.Init)})
+	}
+	return changes
+}
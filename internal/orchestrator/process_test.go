@@ -0,0 +1,68 @@
+package orchestrator
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+func TestSplitCommand(t *testing.T) {
+	for _, tc := range []struct {
+		in   v1.ShellCommand
+		want []string
+	}{
+		{v1.ShellCommand{`npm run "start prod"`}, []string{"npm", "run", "start prod"}},
+		{v1.ShellCommand{`sh -c 'echo $HOME' a\ b`}, []string{"sh", "-c", "echo $HOME", "a b"}},
+		{v1.ShellCommand{`printf ""`}, []string{"printf", ""}},
+		{v1.ShellCommand{"sh", "-c", "a b"}, []string{"sh", "-c", "a b"}},
+	} {
+		got, err := splitCommand(tc.in)
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("splitCommand(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+	}
+	if _, err := splitCommand(v1.ShellCommand{`echo "oops`}); err == nil {
+		t.Error("expected an error for an unterminated quote")
+	}
+}
+
+func TestApplyAndDiffProcess(t *testing.T) {
+	spec := v1.ServiceSpec{
+		Name:            "web",
+		Command:         v1.ShellCommand{"node server.js"},
+		WorkingDir:      "/app",
+		Init:            true,
+		StopSignal:      "SIGINT",
+		StopGracePeriod: 1500 * time.Millisecond,
+	}
+	cfg := &containertypes.Config{Cmd: []string{"from-image"}, Entrypoint: []string{"docker-entrypoint.sh"}}
+	hostCfg := &containertypes.HostConfig{}
+	if err := applyProcess(spec, cfg, hostCfg); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.Cmd, []string{"node", "server.js"}) || cfg.Entrypoint[0] != "docker-entrypoint.sh" ||
+		cfg.WorkingDir != "/app" || cfg.StopSignal != "SIGINT" || *cfg.StopTimeout != 2 || !*hostCfg.Init {
+		t.Fatalf("config = %+v, init = %v", cfg, hostCfg.Init)
+	}
+
+	info := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: hostCfg},
+		Config:            cfg,
+	}
+	if changes := diffProcess(spec, info); len(changes) != 0 {
+		t.Errorf("unchanged spec: changes = %+v", changes)
+	}
+	spec.Command, spec.Init, spec.StopGracePeriod = v1.ShellCommand{"node", "worker.js"}, false, time.Minute
+	var fields []string
+	for _, c := range diffProcess(spec, info) {
+		fields = append(fields, c.Field)
+	}
+	if !slices.Equal(fields, []string{"command", "stop_grace_period", "init"}) {
+		t.Errorf("fields = %v", fields)
+	}
+}
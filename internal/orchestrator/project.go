@@ -0,0 +1,78 @@
+// Package orchestrator: keeping the containers of projects apart.
+package orchestrator
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/f9-o/orbit/internal/core/state"
+)
+
+// projectNameUnsafe matches the characters of a project name Docker does
+// not allow in a container name.
+var projectNameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// WithProject keeps the client to project's containers, as state.DB.Project
+// does for state. Those it starts are labelled orbit.project and, outside
+// the default project, named "<project>_<name>", so two projects' services
+// of the same name do not collide. Listings and events leave out other
+// projects' containers and give names without the prefix, so replicas are
+// named alike in every project. Containers started before projects were
+// kept apart carry no label and are seen by every project, as their state
+// records are. state.AllProjects sees every project's containers.
+func (c *Client) WithProject(name string) *Client {
+	c.project = name
+	return c
+}
+
+// writeProject is the project of the containers the client starts.
+func (c *Client) writeProject() string {
+	if c.project == state.AllProjects {
+		return ""
+	}
+	return c.project
+}
+
+// containerName is the Docker name of the client's container name.
+func (c *Client) containerName(name string) string {
+	p := c.writeProject()
+	if p == "" {
+		return name
+	}
+	return projectNameUnsafe.ReplaceAllString(p, "-") + "_" + name
+}
+
+// localName is name, a Docker container name, without the client's project
+// prefix.
+func (c *Client) localName(name string) string {
+	slash := strings.HasPrefix(name, "/")
+	prefix := c.containerName("")
+	if prefix == "" || !strings.HasPrefix(strings.TrimPrefix(name, "/"), prefix) {
+		return name
+	}
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "/"), prefix)
+	if slash {
+		name = "/" + name
+	}
+	return name
+}
+
+// sees reports whether a container with labels belongs to the client's
+// project.
+func (c *Client) sees(labels map[string]string) bool {
+	p := labels["orbit.project"]
+	return c.project == state.AllProjects || p == "" || p == c.project
+}
+
+// projectLabels returns labels with the client's orbit.project added,
+// leaving labels itself unchanged.
+func (c *Client) projectLabels(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	if p := c.writeProject(); p != "" {
+		out["orbit.project"] = p
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+)
+
+func TestProjectsKeepContainersApart(t *testing.T) {
+	running := []types.Container{
+		{ID: "a1", Names: []string{"/shop_web"}, Labels: map[string]string{"orbit.service": "web", "orbit.project": "shop"}},
+		{ID: "b1", Names: []string{"/blog_web"}, Labels: map[string]string{"orbit.service": "web", "orbit.project": "blog"}},
+		{ID: "d1", Names: []string{"/web-2"}, Labels: map[string]string{"orbit.service": "web"}}, // from before projects
+	}
+	var created []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			w.Header().Set("API-Version", "1.45")
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			json.NewEncoder(w).Encode(running)
+		case strings.HasSuffix(r.URL.Path, "/containers/create"):
+			var cfg containertypes.Config
+			json.NewDecoder(r.Body).Decode(&cfg)
+			created = append(created, fmt.Sprintf("%s %s", r.URL.Query().Get("name"), cfg.Labels["orbit.project"]))
+			fmt.Fprint(w, `{"Id":"0123456789abcdef"}`)
+		case strings.HasSuffix(r.URL.Path, "/start"):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	log, _ := logger.Init("error", "text", "", "", false)
+	client := func(project string) *Client {
+		c, err := NewClient("tcp://"+strings.TrimPrefix(srv.URL, "http://"), log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.WithProject(project)
+	}
+	names := func(c *Client) []string {
+		ctrs, err := c.ListContainers(context.Background(), "web")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, ctr := range ctrs {
+			out = append(out, ctr.ID+" "+ctr.Names[0])
+		}
+		return out
+	}
+
+	cases := []struct {
+		project string
+		want    []string
+	}{
+		{"shop", []string{"a1 /web", "d1 /web-2"}},
+		{"", []string{"d1 /web-2"}},
+		{state.AllProjects, []string{"a1 /shop_web", "b1 /blog_web", "d1 /web-2"}},
+	}
+	for _, tc := range cases {
+		if got := names(client(tc.project)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("project %q lists %v, want %v", tc.project, got, tc.want)
+		}
+	}
+
+	spec := v1.ServiceSpec{Name: "web", Image: "web:1", Labels: map[string]string{"orbit.service": "web"}}
+	for _, project := range []string{"shop", "my app", ""} {
+		if _, err := client(project).RunContainer(context.Background(), spec, "web"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"shop_web shop", "my-app_web my app", "web "}; !reflect.DeepEqual(created, want) {
+		t.Errorf("created %q, want %q", created, want)
+	}
+	if _, ok := spec.Labels["orbit.project"]; ok {
+		t.Error("RunContainer changed the spec's labels")
+	}
+}
@@ -0,0 +1,275 @@
+// Package orchestrator: garbage collection of orphaned containers, state, and images.
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/volume"
+	dockerclient "github.com/docker/docker/client"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+)
+
+// PruneKind is the type of resource a PruneItem refers to.
+type PruneKind string
+
+const (
+	PruneContainer PruneKind = "container"
+	PruneState     PruneKind = "state"
+	PruneImage     PruneKind = "image"
+	PruneVolume    PruneKind = "volume"
+	PruneNetwork   PruneKind = "network"
+)
+
+// PruneItem is one resource found to be garbage.
+type PruneItem struct {
+	Kind    PruneKind `json:"kind"`
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Service string    `json:"service,omitempty"`
+	Reason  string    `json:"reason"`
+	Error   string    `json:"error,omitempty"` // set by Prune when removal failed
+}
+
+// tempContainerName matches the temporary names Deployer gives replacements
+// and rollbacks before cut-over: "<replica>-new-<nanos>", "<replica>-rollback-<nanos>".
+var tempContainerName = regexp.MustCompile(`^(.+)-(new|rollback)-\d+$`)
+
+// Pruner finds and removes orbit resources that nothing refers to any more.
+type Pruner struct {
+	docker *Client
+	state  *state.DB
+	log    *logger.Logger
+}
+
+// NewPruner constructs a Pruner.
+func NewPruner(docker *Client, db *state.DB, log *logger.Logger) *Pruner {
+	return &Pruner{docker: docker, state: db, log: log}
+}
+
+// Find lists garbage on node without changing anything:
+//   - orbit containers whose service has no state entry, and temporary
+//     deploy containers left by an interrupted deploy;
+//   - state entries whose container no longer exists;
+//   - images recorded in deployment history that no container uses and no
+//     service currently runs;
+//   - dangling volumes and networks labelled orbit.service.
+//
+// Services with a held lock are skipped: their deploy may still be running.
+func (p *Pruner) Find(ctx context.Context, node string) ([]PruneItem, error) {
+	states, err := p.state.ListServiceStates(node)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, s := range states {
+		known[s.Name] = true
+	}
+	locked := map[string]bool{}
+	if locks, err := p.state.ListLocks(); err == nil {
+		for _, l := range locks {
+			if l.Node == node && !l.Stale(time.Now()) {
+				locked[l.Service] = true
+			}
+		}
+	}
+
+	ctrs, err := p.docker.docker.ContainerList(ctx, containertypes.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "orbit.service")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+
+	var items []PruneItem
+	for _, c := range ctrs {
+		svc := c.Labels["orbit.service"]
+		if n := c.Labels["orbit.node"]; (n != "" && n != node) || locked[svc] || !p.docker.sees(c.Labels) {
+			continue
+		}
+		name := p.docker.localName(containerName(c))
+		switch {
+		case tempContainerName.MatchString(name):
+			items = append(items, PruneItem{Kind: PruneContainer, ID: c.ID, Name: name, Service: svc,
+				Reason: "temporary container from an interrupted deploy"})
+		case c.Labels["orbit.task"] != "":
+			if c.State != "running" {
+				items = append(items, PruneItem{Kind: PruneContainer, ID: c.ID, Name: name, Service: svc,
+					Reason: "finished task container"})
+			}
+		case !known[svc]:
+			items = append(items, PruneItem{Kind: PruneContainer, ID: c.ID, Name: name, Service: svc,
+				Reason: "service not in state"})
+		}
+	}
+
+	existing := map[string]bool{}
+	for _, c := range ctrs {
+		existing[c.ID] = true
+	}
+	for _, s := range states {
+		if locked[s.Name] || s.ContainerID == "" || existing[s.ContainerID] {
+			continue
+		}
+		if _, err := p.docker.InspectContainer(ctx, s.ContainerID); err == nil || !dockerclient.IsErrNotFound(err) {
+			continue
+		}
+		items = append(items, PruneItem{Kind: PruneState, ID: s.Node + "/" + s.Name, Name: s.Name, Service: s.Name,
+			Reason: "container " + shortID(s.ContainerID) + " no longer exists"})
+	}
+
+	images, err := p.staleImages(ctx, node, states)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, images...)
+
+	orphans, err := p.danglingVolumesAndNetworks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(items, orphans...), nil
+}
+
+// staleImages returns images that orbit deployed at some point but that no
+// container (orbit or not) uses and no current service state references.
+func (p *Pruner) staleImages(ctx context.Context, node string, states []v1.ServiceState) ([]PruneItem, error) {
+	recs, err := p.state.ListDeployments("")
+	if err != nil {
+		return nil, err
+	}
+	deployed := map[string]bool{}
+	for _, r := range recs {
+		if r.Node != "" && r.Node != node {
+			continue
+		}
+		for _, ref := range []string{r.FromImage, r.ToImage} {
+			if ref != "" {
+				deployed[normalizeRef(ref)] = true
+			}
+		}
+	}
+	current := map[string]bool{}
+	for _, s := range states {
+		current[normalizeRef(s.Image)] = true
+	}
+
+	all, err := p.docker.docker.ContainerList(ctx, containertypes.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+	used := map[string]bool{}
+	for _, c := range all {
+		used[c.ImageID] = true
+	}
+
+	imgs, err := p.docker.docker.ImageList(ctx, image.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list images: %w", err)
+	}
+	var items []PruneItem
+	for _, img := range imgs {
+		if used[img.ID] {
+			continue
+		}
+		for _, tag := range img.RepoTags {
+			if deployed[tag] && !current[tag] {
+				items = append(items, PruneItem{Kind: PruneImage, ID: img.ID, Name: tag,
+					Reason: "superseded by a later deploy"})
+				break
+			}
+		}
+	}
+	return items, nil
+}
+
+// danglingVolumesAndNetworks lists unused volumes and networks carrying an
+// orbit.service label. Unlabelled named volumes from orbit.yaml are never
+// considered: they may hold data for a service that is merely stopped.
+func (p *Pruner) danglingVolumesAndNetworks(ctx context.Context) ([]PruneItem, error) {
+	f := filters.NewArgs(filters.Arg("label", "orbit.service"), filters.Arg("dangling", "true"))
+
+	vols, err := p.docker.docker.VolumeList(ctx, volume.ListOptions{Filters: f})
+	if err != nil {
+		return nil, fmt.Errorf("list volumes: %w", err)
+	}
+	var items []PruneItem
+	for _, v := range vols.Volumes {
+		items = append(items, PruneItem{Kind: PruneVolume, ID: v.Name, Name: v.Name, Service: v.Labels["orbit.service"],
+			Reason: "not mounted by any container"})
+	}
+
+	nets, err := p.docker.docker.NetworkList(ctx, types.NetworkListOptions{Filters: f})
+	if err != nil {
+		return nil, fmt.Errorf("list networks: %w", err)
+	}
+	for _, n := range nets {
+		items = append(items, PruneItem{Kind: PruneNetwork, ID: n.ID, Name: n.Name, Service: n.Labels["orbit.service"],
+			Reason: "no containers attached"})
+	}
+	return items, nil
+}
+
+// Prune removes items and returns them with Error set on those that failed.
+// Containers go first so the images and volumes they held become removable.
+func (p *Pruner) Prune(ctx context.Context, node string, items []PruneItem) []PruneItem {
+	order := map[PruneKind]int{PruneContainer: 0, PruneState: 1, PruneNetwork: 2, PruneVolume: 3, PruneImage: 4}
+	sorted := append([]PruneItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool { return order[sorted[i].Kind] < order[sorted[j].Kind] })
+
+	for i := range sorted {
+		it := &sorted[i]
+		var err error
+		switch it.Kind {
+		case PruneContainer:
+			err = p.docker.docker.ContainerRemove(ctx, it.ID, containertypes.RemoveOptions{Force: true})
+		case PruneState:
+			err = p.state.DeleteServiceState(node, it.Name)
+		case PruneImage:
+			_, err = p.docker.docker.ImageRemove(ctx, it.ID, image.RemoveOptions{PruneChildren: true})
+		case PruneVolume:
+			err = p.docker.docker.VolumeRemove(ctx, it.ID, false)
+		case PruneNetwork:
+			err = p.docker.docker.NetworkRemove(ctx, it.ID)
+		}
+		if err != nil {
+			it.Error = err.Error()
+			p.log.Warn("prune.failed", "kind", it.Kind, "name", it.Name, "err", err)
+			continue
+		}
+		p.log.Info("prune.removed", "kind", it.Kind, "name", it.Name)
+	}
+	return sorted
+}
+
+// containerName returns a container's primary name without the leading slash.
+func containerName(c types.Container) string {
+	if len(c.Names) == 0 {
+		return shortID(c.ID)
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// normalizeRef adds the implicit :latest tag so refs compare like RepoTags.
+func normalizeRef(ref string) string {
+	if lastColonIdx(ref) <= strings.LastIndex(ref, "/") {
+		return ref + ":latest"
+	}
+	return ref
+}
+
+func shortID(id string) string {
+	return id[:min(12, len(id))]
+}
@@ -0,0 +1,33 @@
+package orchestrator
+
+import "testing"
+
+func TestTempContainerName(t *testing.T) {
+	cases := map[string]bool{
+		"web-new-1718000000000000000":        true,
+		"web-2-rollback-1718000000000000000": true,
+		"web":                                false,
+		"web-2":                              false,
+		"web-new":                            false,
+		"api-newer-17":                       false,
+	}
+	for name, want := range cases {
+		if got := tempContainerName.MatchString(name); got != want {
+			t.Errorf("tempContainerName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestNormalizeRef(t *testing.T) {
+	cases := map[string]string{
+		"nginx":                       "nginx:latest",
+		"nginx:1.25":                  "nginx:1.25",
+		"localhost:5000/app":          "localhost:5000/app:latest",
+		"registry.io:443/team/app:v2": "registry.io:443/team/app:v2",
+	}
+	for in, want := range cases {
+		if got := normalizeRef(in); got != want {
+			t.Errorf("normalizeRef(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
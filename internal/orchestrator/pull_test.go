@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+func TestPullImageReportsProgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			w.Header().Set("API-Version", "1.45")
+		case strings.HasSuffix(r.URL.Path, "/images/create"):
+			fmt.Fprintln(w, `{"status":"Pulling from library/web","id":"1.2"}`)
+			fmt.Fprintln(w, `{"status":"Pulling fs layer","progressDetail":{},"id":"a1b2c3d4e5f6"}`)
+			fmt.Fprintln(w, `{"status":"Downloading","progressDetail":{"current":512,"total":2048},"progress":"[=====>  ]","id":"a1b2c3d4e5f6"}`)
+			fmt.Fprintln(w, `{"status":"Pull complete","progressDetail":{},"id":"a1b2c3d4e5f6"}`)
+			fmt.Fprintln(w, `{"status":"Status: Downloaded newer image for web:1.2"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	log, _ := logger.Init("error", "text", "", "", false)
+	c, err := NewClient("tcp://"+strings.TrimPrefix(srv.URL, "http://"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []PullEvent
+	c.WithPullProgress(func(ev PullEvent) { got = append(got, ev) })
+	if err := c.PullImage(context.Background(), "web:1.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []PullEvent{
+		{Image: "web:1.2", Status: "Pulling from library/web"},
+		{Image: "web:1.2", Layer: "a1b2c3d4e5f6", Status: "Pulling fs layer"},
+		{Image: "web:1.2", Layer: "a1b2c3d4e5f6", Status: "Downloading", Current: 512, Total: 2048},
+		{Image: "web:1.2", Layer: "a1b2c3d4e5f6", Status: "Pull complete"},
+		{Image: "web:1.2", Status: "Status: Downloaded newer image for web:1.2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v", got)
+	}
+}
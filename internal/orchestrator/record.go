@@ -0,0 +1,92 @@
+// Package orchestrator: deployment history records written by Deployer and Scaler.
+package orchestrator
+
+import (
+	"fmt"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+)
+
+// newRecord starts a DeploymentRecord for action on service. IDs sort by
+// start time so the bolt bucket iterates chronologically.
+func newRecord(service, node, action string) v1.DeploymentRecord {
+	start := time.Now().UTC()
+	return v1.DeploymentRecord{
+		ID:        fmt.Sprintf("%s-%s", start.Format("20060102T150405.000000000"), service),
+		Service:   service,
+		Node:      node,
+		Action:    action,
+		StartedAt: start,
+	}
+}
+
+// finishRecord stamps rec with its completion time, outcome and run ID and persists
+// it, with an entry in the event log. A result already set (e.g. rolledback) is kept; otherwise it is derived
+// from err. Persistence failures are logged, never returned — history must not
+// turn a successful deploy into a failed one.
+func finishRecord(db *state.DB, log *logger.Logger, rec v1.DeploymentRecord, err error) {
+	rec.CompletedAt = time.Now().UTC()
+	rec.DurationMS = rec.CompletedAt.Sub(rec.StartedAt).Milliseconds()
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	rec.Result = resultOf(rec, err)
+	rec.RunID = log.RunID()
+	if perr := db.PutDeployment(rec); perr != nil {
+		log.Warn("deploy.record.failed", "service", rec.Service, "err", perr)
+	}
+	if _, perr := db.AppendEvent(eventOf(rec)); perr != nil {
+		log.Warn("deploy.event.failed", "service", rec.Service, "err", perr)
+	}
+}
+
+// eventOf is the event log entry for a finished rec.
+func eventOf(rec v1.DeploymentRecord) v1.Event {
+	e := v1.Event{
+		Time:     rec.CompletedAt,
+		Type:     rec.Action,
+		Resource: "service/" + rec.Service,
+		Node:     rec.Node,
+		Severity: v1.SeverityInfo,
+		RunID:    rec.RunID,
+	}
+	switch rec.Action {
+	case v1.DeployActionScale:
+		e.Message = fmt.Sprintf("%s scaled to %d replicas", rec.Service, rec.Replicas)
+	default:
+		e.Message = rec.Service + " " + rec.Action
+		if rec.ToImage != "" {
+			e.Message += " to " + rec.ToImage
+		}
+	}
+	switch rec.Result {
+	case v1.DeployResultFailure:
+		e.Severity = v1.SeverityError
+		e.Message += " failed: " + rec.Error
+	case v1.DeployResultRolledBack:
+		e.Severity = v1.SeverityWarning
+		e.Message += " rolled back"
+		if rec.Error != "" {
+			e.Message += ": " + rec.Error
+		}
+	}
+	if rec.Reason != "" {
+		e.Message += " (" + rec.Reason + ")"
+	}
+	return e
+}
+
+// resultOf is rec's outcome: a result already set on it, or else success or
+// failure according to err.
+func resultOf(rec v1.DeploymentRecord, err error) string {
+	switch {
+	case rec.Result != "":
+		return rec.Result
+	case err != nil:
+		return v1.DeployResultFailure
+	}
+	return v1.DeployResultSuccess
+}
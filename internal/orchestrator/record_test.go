@@ -0,0 +1,80 @@
+package orchestrator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+func TestFinishRecord(t *testing.T) {
+	os.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	defer os.Unsetenv(encryption.EnvSecretKey)
+
+	db, err := state.Open(filepath.Join(t.TempDir(), "orbit_test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	logger.SetRunID("0123456789ab")
+	defer logger.SetRunID("")
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	ok := newRecord("web", "local", v1.DeployActionDeploy)
+	ok.FromImage, ok.ToImage = "web:1", "web:2"
+	finishRecord(db, log, ok, nil)
+
+	failed := newRecord("web", "local", v1.DeployActionDeploy)
+	finishRecord(db, log, failed, errors.New("health check failed"))
+
+	rolled := newRecord("web", "local", v1.DeployActionDeploy)
+	rolled.Result = v1.DeployResultRolledBack
+	finishRecord(db, log, rolled, errors.New("health check failed"))
+
+	finishRecord(db, log, newRecord("api", "local", v1.DeployActionScale), nil)
+
+	recs, err := db.ListDeployments("web")
+	if err != nil {
+		t.Fatalf("ListDeployments: %v", err)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("got %d records for web, want 3", len(recs))
+	}
+	want := []string{v1.DeployResultSuccess, v1.DeployResultFailure, v1.DeployResultRolledBack}
+	for i, r := range recs {
+		if r.Result != want[i] {
+			t.Errorf("record %d result = %q, want %q", i, r.Result, want[i])
+		}
+		if r.RunID != "0123456789ab" {
+			t.Errorf("record %d run ID = %q", i, r.RunID)
+		}
+		if r.CompletedAt.Before(r.StartedAt) {
+			t.Errorf("record %d completed before it started", i)
+		}
+	}
+	if recs[0].FromImage != "web:1" || recs[0].ToImage != "web:2" || recs[0].Error != "" {
+		t.Errorf("success record = %+v", recs[0])
+	}
+	if recs[1].Error != "health check failed" {
+		t.Errorf("failure record error = %q", recs[1].Error)
+	}
+
+	events, err := db.ListEvents(state.EventQuery{Resource: "service/web"})
+	if err != nil || len(events) != 3 {
+		t.Fatalf("events = %+v, %v", events, err)
+	}
+	wantSeverity := []string{v1.SeverityInfo, v1.SeverityError, v1.SeverityWarning}
+	for i, e := range events {
+		if e.Type != v1.EventDeploy || e.Severity != wantSeverity[i] || e.RunID != "0123456789ab" {
+			t.Errorf("event %d = %+v", i, e)
+		}
+	}
+	if events[0].Message != "web deploy to web:2" || events[1].Message != "web deploy failed: health check failed" {
+		t.Errorf("messages = %q, %q", events[0].Message, events[1].Message)
+	}
+}
@@ -0,0 +1,100 @@
+// Package orchestrator: container runtime abstraction and backend selection.
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// Supported values for the `runtime:` key in orbit.yaml.
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// Runtime is the container engine the deploy, scale, lifecycle, and plan
+// engines drive. *Client implements it for both Docker and Podman.
+type Runtime interface {
+	// Name is the backend name, "docker" or "podman".
+	Name() string
+	PullImage(ctx context.Context, img string) error
+	RunContainer(ctx context.Context, spec v1.ServiceSpec, name string) (string, error)
+	StopContainer(ctx context.Context, idOrName string, remove bool) error
+	StopContainerTimeout(ctx context.Context, idOrName string, timeout time.Duration, remove bool) error
+	RenameContainer(ctx context.Context, idOrName, name string) error
+	UpdateRestartPolicy(ctx context.Context, idOrName, policy string) error
+	ConnectNetwork(ctx context.Context, network, idOrName string) error
+	DisconnectNetwork(ctx context.Context, network, idOrName string) error
+	InspectContainer(ctx context.Context, idOrName string) (types.ContainerJSON, error)
+	ListContainers(ctx context.Context, serviceFilter string) ([]types.Container, error)
+	ImageEnv(ctx context.Context, ref string) ([]string, error)
+	RunTask(ctx context.Context, spec v1.ServiceSpec, opts TaskOptions) (int, error)
+}
+
+var _ Runtime = (*Client)(nil)
+
+// NewRuntime connects to the runtime named by kind ("" means docker) at host,
+// a daemon URL or Docker context name; "" picks the runtime's default.
+func NewRuntime(kind, host string, log *logger.Logger) (*Client, error) {
+	switch kind {
+	case "", RuntimeDocker:
+		return NewClient(host, log)
+	case RuntimePodman:
+		return NewPodmanClient(host, log)
+	default:
+		return nil, errs.Newf(errs.ErrConfig, "orchestrator.NewRuntime", "unknown runtime %q", kind).
+			WithAdvice("Set runtime to docker or podman in orbit.yaml")
+	}
+}
+
+// NewPodmanClient connects to Podman's Docker-compatible API. host defaults to
+// $CONTAINER_HOST, then the rootless socket under $XDG_RUNTIME_DIR, then the
+// rootful /run/podman/podman.sock.
+func NewPodmanClient(host string, log *logger.Logger) (*Client, error) {
+	if host == "" {
+		host = os.Getenv("CONTAINER_HOST")
+	}
+	if host == "" {
+		sock, err := podmanSocket()
+		if err != nil {
+			return nil, err
+		}
+		host = "unix://" + sock
+	}
+	c, err := NewClient(host, log)
+	if err != nil {
+		return nil, err
+	}
+	c.name = RuntimePodman
+	return c, nil
+}
+
+// podmanSocket returns the first Podman API socket that exists.
+func podmanSocket() (string, error) {
+	var candidates []string
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "podman", "podman.sock"))
+	}
+	candidates = append(candidates,
+		fmt.Sprintf("/run/user/%d/podman/podman.sock", os.Getuid()),
+		"/run/podman/podman.sock",
+	)
+	for _, p := range candidates {
+		if fi, err := os.Stat(p); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			return p, nil
+		}
+	}
+	return "", errs.Newf(errs.ErrDockerConnect, "orchestrator.podmanSocket",
+		"no Podman API socket found (tried %s)", strings.Join(candidates, ", ")).
+		WithAdvice("Run `systemctl --user enable --now podman.socket` (rootless) or set CONTAINER_HOST")
+}
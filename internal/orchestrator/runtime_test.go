@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+func TestNewRuntime(t *testing.T) {
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	c, err := NewRuntime("", "", log)
+	if err != nil {
+		t.Fatalf("default runtime: %v", err)
+	}
+	if c.Name() != RuntimeDocker {
+		t.Errorf("default runtime = %q, want docker", c.Name())
+	}
+
+	t.Setenv("CONTAINER_HOST", "unix:///run/podman/podman.sock")
+	c, err = NewRuntime(RuntimePodman, "", log)
+	if err != nil {
+		t.Fatalf("podman runtime: %v", err)
+	}
+	if c.Name() != RuntimePodman {
+		t.Errorf("podman runtime = %q, want podman", c.Name())
+	}
+
+	if _, err := NewRuntime("containerd", "", log); err == nil {
+		t.Error("unknown runtime accepted")
+	}
+}
+
+func TestPodmanSocket(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+
+	sock := filepath.Join(dir, "podman", "podman.sock")
+	if got, err := podmanSocket(); err == nil && got == sock {
+		t.Fatalf("found socket %s before it was created", got)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(sock), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer ln.Close()
+
+	got, err := podmanSocket()
+	if err != nil || got != sock {
+		t.Fatalf("podmanSocket() = %q, %v; want %q", got, err, sock)
+	}
+}
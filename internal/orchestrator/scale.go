@@ -4,49 +4,170 @@ package orchestrator
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"go.opentelemetry.io/otel/attribute"
 
 	v1 "github.com/f9-o/orbit/api/v1"
 	"github.com/f9-o/orbit/internal/core/logger"
 	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/internal/telemetry"
 )
 
 // Scaler manages replica counts for services.
 type Scaler struct {
-	docker *Client
-	state  *state.DB
-	log    *logger.Logger
+	docker   Runtime
+	state    *state.DB
+	log      *logger.Logger
+	progress func(current, target int)
+	hooks    v1.HookDispatcher
 }
 
 // NewScaler constructs a Scaler.
-func NewScaler(docker *Client, db *state.DB, log *logger.Logger) *Scaler {
+func NewScaler(docker Runtime, db *state.DB, log *logger.Logger) *Scaler {
 	return &Scaler{docker: docker, state: db, log: log}
 }
 
+// WithProgress registers fn to be called after each replica starts or stops.
+func (s *Scaler) WithProgress(fn func(current, target int)) *Scaler {
+	s.progress = fn
+	return s
+}
+
+// WithHooks fires OnPreScale and OnPostScale at hooks around each scale.
+func (s *Scaler) WithHooks(hooks v1.HookDispatcher) *Scaler {
+	s.hooks = hooks
+	return s
+}
+
+// Replicas returns the number of live containers for a service.
+func (s *Scaler) Replicas(ctx context.Context, service string) (int, error) {
+	ctrs, err := s.replicas(ctx, service)
+	return len(ctrs), err
+}
+
+// replicas lists a service's containers ordered by replica index; the primary
+// container (no orbit.replica label) sorts first.
+func (s *Scaler) replicas(ctx context.Context, service string) ([]types.Container, error) {
+	ctrs, err := s.docker.ListContainers(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	index := func(c types.Container) int {
+		n, _ := strconv.Atoi(c.Labels["orbit.replica"])
+		return n
+	}
+	sort.SliceStable(ctrs, func(i, j int) bool { return index(ctrs[i]) < index(ctrs[j]) })
+	return ctrs, nil
+}
+
+// report persists the replica count on the service's state and notifies the
+// progress callback. A service scaled up before it was ever brought up gets
+// its state here, with started, the replica just started, as its container.
+// A manual scale also records target as the count up and deploy keep.
+func (s *Scaler) report(spec v1.ServiceSpec, node, started string, current, target int, manual bool) {
+	st, err := s.state.GetServiceState(node, spec.Name)
+	switch {
+	case err != nil:
+		s.log.Warn("scale: state read failed", "service", spec.Name, "err", err)
+	case st == nil && started != "":
+		st = &v1.ServiceState{Name: spec.Name, ContainerID: started, Image: spec.Image,
+			Status: v1.StatusUnknown, Node: node, StartedAt: time.Now().UTC(),
+			Ready: health.Resolve(spec.HealthCheck, health.ProbeReadiness) == nil}
+	}
+	if st != nil {
+		st.Replicas = current
+		if manual {
+			st.Scale = ScaleOverride(spec, target)
+		}
+		if err := s.state.PutServiceState(*st); err != nil {
+			s.log.Warn("scale: state update failed", "service", spec.Name, "err", err)
+		}
+	}
+	if s.progress != nil {
+		s.progress(current, target)
+	}
+}
+
+// DefaultReplicas is the replica count orbit.yaml asks for: deploy.replicas,
+// or one.
+func DefaultReplicas(spec v1.ServiceSpec) int {
+	if spec.Deploy != nil && spec.Deploy.Replicas > 0 {
+		return spec.Deploy.Replicas
+	}
+	return 1
+}
+
+// ScaleOverride is the ServiceState.Scale recorded when spec is scaled to
+// target by hand: target, or 0 when that is what orbit.yaml asks for anyway,
+// so a later change to deploy.replicas takes effect again.
+func ScaleOverride(spec v1.ServiceSpec, target int) int {
+	if target == DefaultReplicas(spec) {
+		return 0
+	}
+	return target
+}
+
 // Scale adjusts the running replica count for a service to target.
 // This implementation uses a simple container-per-replica model with indexed names.
 func (s *Scaler) Scale(ctx context.Context, spec v1.ServiceSpec, node string, target int) error {
+	return s.scale(ctx, spec, node, target, "")
+}
+
+// scale is Scale with a reason recorded in the deployment history.
+func (s *Scaler) scale(ctx context.Context, spec v1.ServiceSpec, node string, target int, reason string) (err error) {
 	if target < 0 {
 		return fmt.Errorf("replica count must be >= 0")
 	}
 
-	current, err := s.state.ListServiceStates(node)
+	ctx, span := telemetry.Start(ctx, "orbit.scale",
+		attribute.String("orbit.service", spec.Name),
+		attribute.String("orbit.node", node),
+		attribute.Int("orbit.replicas", target))
+	defer func() { telemetry.End(span, err) }()
+
+	unlock, err := lockService(ctx, s.state, node, spec.Name, "scale")
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	// Count existing replicas for this service
-	var running []v1.ServiceState
-	for _, ss := range current {
-		if ss.Name == spec.Name {
-			running = append(running, ss)
-		}
+	rec := newRecord(spec.Name, node, v1.DeployActionScale)
+	rec.FromImage, rec.ToImage, rec.Replicas, rec.Reason = spec.Image, spec.Image, target, reason
+	defer func() { finishRecord(s.state, s.log, rec, err) }()
+
+	running, err := s.replicas(ctx, spec.Name)
+	if err != nil {
+		return fmt.Errorf("list replicas: %w", err)
 	}
 
 	currentCount := len(running)
 	s.log.Info("scale", "service", spec.Name, "current", currentCount, "target", target)
 
+	hctx := hookContext(spec, node, map[string]string{
+		"replicas": strconv.Itoa(target),
+		"current":  strconv.Itoa(currentCount),
+	})
+	if reason != "" {
+		hctx.Metadata["reason"] = reason
+	}
+	fireHook(ctx, s.hooks, v1.HookPreScale, hctx)
+	defer func() { firePostHook(ctx, s.hooks, v1.HookPostScale, hctx, resultOf(rec, err), err) }()
+
+	manual := reason == ""
+	if target > currentCount {
+		if err := CheckReplicaPorts(spec, target); err != nil {
+			return err
+		}
+	}
 	if currentCount == target {
 		s.log.Info("already at target replica count", "service", spec.Name)
+		s.report(spec, node, "", currentCount, target, manual)
+		s.recordPorts(ctx, spec.Name, node)
 		return nil
 	}
 
@@ -57,23 +178,45 @@ func (s *Scaler) Scale(ctx context.Context, spec v1.ServiceSpec, node string, ta
 			spec.Labels = map[string]string{}
 		}
 		spec.Labels["orbit.service"] = spec.Name
+		spec.Labels["orbit.node"] = node
 		spec.Labels["orbit.replica"] = fmt.Sprintf("%d", i+1)
 
-		id, err := s.docker.RunContainer(ctx, spec, name)
+		id, err := s.docker.RunContainer(ctx, withReplicaPorts(spec, i+1), name)
 		if err != nil {
 			return fmt.Errorf("scale up replica %d: %w", i+1, err)
 		}
 		s.log.Info("replica started", "name", name, "id", id[:12])
+		s.report(spec, node, id, i+1, target, manual)
 	}
 
 	// Scale down: stop excess containers (from the end)
 	for i := currentCount - 1; i >= target; i-- {
-		ss := running[i]
-		s.log.Info("stopping excess replica", "name", ss.Name, "id", ss.ContainerID[:12])
-		if err := s.docker.StopContainer(ctx, ss.ContainerID, true); err != nil {
+		ctr := running[i]
+		s.log.Info("stopping excess replica", "service", spec.Name, "id", ctr.ID[:12])
+		if err := s.docker.StopContainer(ctx, ctr.ID, true); err != nil {
 			s.log.Warn("scale down: stop failed", "err", err)
 		}
+		s.report(spec, node, "", i, target, manual)
 	}
 
+	s.recordPorts(ctx, spec.Name, node)
 	return nil
 }
+
+// recordPorts stores the host ports the service's replicas publish on its
+// state, where the proxy finds replicas on ephemeral ports.
+func (s *Scaler) recordPorts(ctx context.Context, service, node string) {
+	ctrs, err := s.docker.ListContainers(ctx, service)
+	if err != nil {
+		s.log.Warn("scale: list replicas failed", "service", service, "err", err)
+		return
+	}
+	st, err := s.state.GetServiceState(node, service)
+	if err != nil || st == nil {
+		return
+	}
+	st.Ports = publishedPorts(ctrs)
+	if err := s.state.PutServiceState(*st); err != nil {
+		s.log.Warn("scale: state update failed", "service", service, "err", err)
+	}
+}
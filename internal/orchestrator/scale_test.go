@@ -0,0 +1,145 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// replicaRuntime keeps an in-memory list of a service's containers.
+type replicaRuntime struct {
+	Runtime
+	ctrs []types.Container
+}
+
+func (r *replicaRuntime) ListContainers(context.Context, string) ([]types.Container, error) {
+	return append([]types.Container(nil), r.ctrs...), nil
+}
+
+func (r *replicaRuntime) RunContainer(_ context.Context, spec v1.ServiceSpec, name string) (string, error) {
+	id := fmt.Sprintf("%s-%012d", name, len(r.ctrs))
+	r.ctrs = append(r.ctrs, types.Container{ID: id, Labels: map[string]string{"orbit.replica": spec.Labels["orbit.replica"]}})
+	return id, nil
+}
+
+func (r *replicaRuntime) StopContainer(_ context.Context, id string, _ bool) error {
+	for i, c := range r.ctrs {
+		if c.ID == id {
+			r.ctrs = append(r.ctrs[:i], r.ctrs[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func TestScaleRemembersCount(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	rt := &replicaRuntime{}
+	spec := v1.ServiceSpec{Name: "web", Image: "web:1", Deploy: &v1.DeploySpec{Replicas: 2}}
+	scaler := NewScaler(rt, db, log)
+
+	// Scaling a service that was never brought up creates its state.
+	if err := scaler.Scale(context.Background(), spec, "local", 3); err != nil {
+		t.Fatal(err)
+	}
+	st, _ := db.GetServiceState("local", "web")
+	if st == nil || st.Replicas != 3 || st.Scale != 3 || st.ContainerID == "" {
+		t.Fatalf("state after scale to 3 = %+v", st)
+	}
+	if n := desiredReplicas(spec, st, rt.ctrs); n != 3 {
+		t.Errorf("deploy would run %d replicas, want the scaled 3", n)
+	}
+
+	// Scaling back to deploy.replicas forgets the override.
+	if err := scaler.Scale(context.Background(), spec, "local", 2); err != nil {
+		t.Fatal(err)
+	}
+	st, _ = db.GetServiceState("local", "web")
+	if st.Replicas != 2 || st.Scale != 0 {
+		t.Errorf("state after scale to 2 = %+v", st)
+	}
+	spec.Deploy.Replicas = 4
+	if n := desiredReplicas(spec, st, rt.ctrs); n != 4 {
+		t.Errorf("deploy would run %d replicas, want deploy.replicas 4", n)
+	}
+}
+
+func TestScaleOverride(t *testing.T) {
+	spec := v1.ServiceSpec{Name: "web"}
+	if DefaultReplicas(spec) != 1 || ScaleOverride(spec, 1) != 0 || ScaleOverride(spec, 5) != 5 {
+		t.Error("without deploy.replicas the default is 1")
+	}
+	spec.Deploy = &v1.DeploySpec{Replicas: 3}
+	if DefaultReplicas(spec) != 3 || ScaleOverride(spec, 3) != 0 || ScaleOverride(spec, 1) != 1 {
+		t.Error("deploy.replicas is the default")
+	}
+}
+
+func TestReplicaPorts(t *testing.T) {
+	spec := v1.ServiceSpec{Name: "web", Ports: []string{"8080:80", "9100:9100"}}
+	if err := CheckReplicaPorts(spec, 1); err != nil {
+		t.Errorf("one replica: %v", err)
+	}
+	err := CheckReplicaPorts(spec, 2)
+	if e := errs.AsOrbit(err); e == nil || e.Code != errs.ErrHostPortConflict || !strings.Contains(e.Advice, "replica_ports") {
+		t.Errorf("two replicas on fixed ports: err = %v, want a port conflict with advice", err)
+	}
+
+	spec.Deploy = &v1.DeploySpec{ReplicaPorts: v1.ReplicaPortsOffset}
+	if err := CheckReplicaPorts(spec, 3); err != nil {
+		t.Errorf("offset: %v", err)
+	}
+	if got := replicaPorts(spec, 1); !slices.Equal(got, spec.Ports) {
+		t.Errorf("offset replica 1 ports = %v", got)
+	}
+	if got := replicaPorts(spec, 3); !slices.Equal(got, []string{"8082:80", "9102:9100"}) {
+		t.Errorf("offset replica 3 ports = %v", got)
+	}
+	spec.Ports = []string{"65535:80"}
+	if CheckReplicaPorts(spec, 2) == nil {
+		t.Error("offset past port 65535 was allowed")
+	}
+
+	spec.Ports = []string{"8080:80"}
+	spec.Deploy.ReplicaPorts = v1.ReplicaPortsEphemeral
+	if got := replicaPorts(spec, 1); !slices.Equal(got, []string{":80"}) {
+		t.Errorf("ephemeral ports = %v", got)
+	}
+}
+
+func TestScaleRejectsSharedHostPort(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	rt := &replicaRuntime{}
+	spec := v1.ServiceSpec{Name: "web", Image: "web:1", Ports: []string{"8080:80"}}
+	if err := NewScaler(rt, db, log).Scale(context.Background(), spec, "local", 2); err == nil {
+		t.Fatal("scaling to 2 replicas on one host port succeeded")
+	}
+	if len(rt.ctrs) != 0 {
+		t.Errorf("started %d replicas before failing", len(rt.ctrs))
+	}
+}
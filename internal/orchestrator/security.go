@@ -0,0 +1,112 @@
+// Package orchestrator: capabilities, security options and filesystem
+// restrictions a service's containers run with.
+package orchestrator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// securityOpts is spec's security_opt as the Docker API takes it: a seccomp
+// profile given as a file is sent as its JSON, as the docker CLI does.
+func securityOpts(spec v1.ServiceSpec) ([]string, error) {
+	opts := make([]string, 0, len(spec.SecurityOpt))
+	for _, opt := range spec.SecurityOpt {
+		key, val, ok := strings.Cut(opt, "=")
+		if !ok {
+			key, val, _ = strings.Cut(opt, ":")
+		}
+		if key != "seccomp" || val == "unconfined" || val == "builtin" {
+			opts = append(opts, opt)
+			continue
+		}
+		data, err := os.ReadFile(val)
+		if err != nil {
+			return nil, fmt.Errorf("service %q: seccomp profile: %w", spec.Name, err)
+		}
+		var profile bytes.Buffer
+		if err := json.Compact(&profile, data); err != nil {
+			return nil, fmt.Errorf("service %q: seccomp profile %s: %w", spec.Name, val, err)
+		}
+		opts = append(opts, "seccomp="+profile.String())
+	}
+	return opts, nil
+}
+
+// tmpfsMounts maps each tmpfs entry's path to its mount options.
+func tmpfsMounts(entries []string) map[string]string {
+	if len(entries) == 0 {
+		return nil
+	}
+	mounts := make(map[string]string, len(entries))
+	for _, e := range entries {
+		path, opts, _ := strings.Cut(e, ":")
+		mounts[path] = opts
+	}
+	return mounts
+}
+
+// applySecurity sets spec's hardening on a container's host config.
+func applySecurity(spec v1.ServiceSpec, hostCfg *containertypes.HostConfig) error {
+	opts, err := securityOpts(spec)
+	if err != nil {
+		return err
+	}
+	hostCfg.CapAdd = spec.CapAdd
+	hostCfg.CapDrop = spec.CapDrop
+	hostCfg.SecurityOpt = opts
+	hostCfg.ReadonlyRootfs = spec.ReadOnly
+	hostCfg.Privileged = spec.Privileged
+	hostCfg.Tmpfs = tmpfsMounts(spec.Tmpfs)
+	return nil
+}
+
+// normalizeCaps spells capabilities the one way, as the daemon may store
+// them with or without the CAP_ prefix.
+func normalizeCaps(caps []string) []string {
+	out := make([]string, len(caps))
+	for i, c := range caps {
+		c = strings.ToUpper(c)
+		if c != "ALL" && !strings.HasPrefix(c, "CAP_") {
+			c = "CAP_" + c
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// diffSecurity compares spec's hardening with the container's.
+func diffSecurity(spec v1.ServiceSpec, info types.ContainerJSON) []FieldChange {
+	if info.HostConfig == nil {
+		return nil
+	}
+	h := info.HostConfig
+	changes := diffList("cap_add", normalizeCaps(spec.CapAdd), normalizeCaps(h.CapAdd))
+	changes = append(changes, diffList("cap_drop", normalizeCaps(spec.CapDrop), normalizeCaps(h.CapDrop))...)
+	if opts, err := securityOpts(spec); err == nil {
+		changes = append(changes, diffList("security_opt", opts, h.SecurityOpt)...)
+	}
+	var tmpfs []string
+	for path, opts := range h.Tmpfs {
+		tmpfs = append(tmpfs, strings.TrimSuffix(path+":"+opts, ":"))
+	}
+	sort.Strings(tmpfs)
+	changes = append(changes, diffList("tmpfs", spec.Tmpfs, tmpfs)...)
+	if h.ReadonlyRootfs != spec.ReadOnly {
+		changes = append(changes, FieldChange{Field: "read_only", From: strconv.FormatBool(h.ReadonlyRootfs), To: strconv.FormatBool(spec.ReadOnly)})
+	}
+	if h.Privileged != spec.Privileged {
+		changes = append(changes, FieldChange{Field: "privileged", From: strconv.FormatBool(h.Privileged), To: strconv.FormatBool(spec.Privileged)})
+	}
+	return changes
+}
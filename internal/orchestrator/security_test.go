@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+func TestApplyAndDiffSecurity(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "seccomp.json")
+	if err := os.WriteFile(profile, []byte("{\n  \"defaultAction\": \"SCMP_ACT_ERRNO\"\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	spec := v1.ServiceSpec{
+		Name:        "web",
+		CapDrop:     []string{"ALL"},
+		CapAdd:      []string{"net_bind_service"},
+		SecurityOpt: []string{"no-new-privileges:true", "seccomp=" + profile},
+		ReadOnly:    true,
+		Tmpfs:       []string{"/run:size=64m", "/tmp"},
+	}
+	hostCfg := &containertypes.HostConfig{}
+	if err := applySecurity(spec, hostCfg); err != nil {
+		t.Fatal(err)
+	}
+	if hostCfg.SecurityOpt[1] != `seccomp={"defaultAction":"SCMP_ACT_ERRNO"}` {
+		t.Errorf("security_opt = %q", hostCfg.SecurityOpt)
+	}
+	if !hostCfg.ReadonlyRootfs || hostCfg.Privileged || hostCfg.Tmpfs["/run"] != "size=64m" || len(hostCfg.Tmpfs) != 2 {
+		t.Errorf("host config = %+v", hostCfg)
+	}
+
+	// The daemon may store capabilities with the CAP_ prefix.
+	stored := *hostCfg
+	stored.CapAdd = []string{"CAP_NET_BIND_SERVICE"}
+	info := types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &stored}}
+	if changes := diffSecurity(spec, info); len(changes) != 0 {
+		t.Errorf("unchanged spec: changes = %+v", changes)
+	}
+	spec.ReadOnly, spec.Privileged, spec.Tmpfs = false, true, []string{"/tmp"}
+	var fields []string
+	for _, c := range diffSecurity(spec, info) {
+		fields = append(fields, c.Field)
+	}
+	if !slices.Equal(fields, []string{"tmpfs", "read_only", "privileged"}) {
+		t.Errorf("fields = %v", fields)
+	}
+
+	spec.SecurityOpt = []string{"seccomp=" + filepath.Join(t.TempDir(), "missing.json")}
+	if err := applySecurity(spec, hostCfg); err == nil {
+		t.Error("expected an error for a missing seccomp profile")
+	}
+}
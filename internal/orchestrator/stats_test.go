@@ -0,0 +1,42 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func statsSample(total, system uint64) *types.StatsJSON {
+	var s types.StatsJSON
+	s.CPUStats.CPUUsage.TotalUsage = total
+	s.CPUStats.SystemUsage = system
+	s.CPUStats.OnlineCPUs = 2
+	return &s
+}
+
+func TestServiceMetricsCPU(t *testing.T) {
+	first := statsSample(1_000, 100_000)
+	if got := serviceMetrics(first, nil).CPUPercent; got != 0 {
+		t.Errorf("first sample without a baseline: CPU = %v, want 0", got)
+	}
+
+	// 500 of 10,000 system ticks across 2 CPUs = 10%.
+	second := statsSample(1_500, 110_000)
+	if got := serviceMetrics(second, first).CPUPercent; got != 10 {
+		t.Errorf("CPU = %v, want 10", got)
+	}
+
+	// PreCPUStats is the baseline when there is no previous sample.
+	second.PreCPUStats = first.CPUStats
+	if got := serviceMetrics(second, nil).CPUPercent; got != 10 {
+		t.Errorf("CPU from PreCPUStats = %v, want 10", got)
+	}
+
+	// cgroup v1 and v2 spell the block I/O ops differently.
+	second.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{
+		{Op: "Read", Value: 100}, {Op: "write", Value: 40}, {Op: "Sync", Value: 7}, {Op: "read", Value: 5},
+	}
+	if m := serviceMetrics(second, first); m.BlockReadBytes != 105 || m.BlockWriteBytes != 40 {
+		t.Errorf("block I/O = %d read, %d written", m.BlockReadBytes, m.BlockWriteBytes)
+	}
+}
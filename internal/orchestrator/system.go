@@ -0,0 +1,79 @@
+// Package orchestrator: the kernel limits, sysctls and name resolution a
+// service's containers see.
+package orchestrator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	units "github.com/docker/go-units"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// ulimits parses spec's ulimits, sorted by name.
+func ulimits(spec v1.ServiceSpec) ([]*units.Ulimit, error) {
+	names := make([]string, 0, len(spec.Ulimits))
+	for name := range spec.Ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]*units.Ulimit, 0, len(names))
+	for _, name := range names {
+		u, err := units.ParseUlimit(name + "=" + spec.Ulimits[name])
+		if err != nil {
+			return nil, fmt.Errorf("service %q: ulimits: %w", spec.Name, err)
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+// applySystem sets spec's kernel limits, sysctls and DNS settings on a
+// container's host config.
+func applySystem(spec v1.ServiceSpec, hostCfg *containertypes.HostConfig) error {
+	limits, err := ulimits(spec)
+	if err != nil {
+		return err
+	}
+	hostCfg.Ulimits = limits
+	hostCfg.Sysctls = spec.Sysctls
+	hostCfg.ExtraHosts = spec.ExtraHosts
+	hostCfg.DNS = spec.DNS
+	hostCfg.DNSSearch = spec.DNSSearch
+	return nil
+}
+
+// diffSystem compares spec's kernel limits, sysctls and DNS settings with
+// the container's.
+func diffSystem(spec v1.ServiceSpec, info types.ContainerJSON) []FieldChange {
+	if info.HostConfig == nil {
+		return nil
+	}
+	h := info.HostConfig
+	var want, got []string
+	if limits, err := ulimits(spec); err == nil {
+		for _, u := range limits {
+			want = append(want, u.String())
+		}
+	}
+	for _, u := range h.Ulimits {
+		got = append(got, u.String())
+	}
+	changes := diffList("ulimits", want, got)
+	changes = append(changes, diffList("sysctls", pairs(spec.Sysctls), pairs(h.Sysctls))...)
+	changes = append(changes, diffList("extra_hosts", spec.ExtraHosts, h.ExtraHosts)...)
+	changes = append(changes, diffList("dns", spec.DNS, h.DNS)...)
+	return append(changes, diffList("dns_search", spec.DNSSearch, h.DNSSearch)...)
+}
+
+// pairs renders a map as key=value entries.
+func pairs(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k, v := range m {
+		out = append(out, k+"="+v)
+	}
+	return out
+}
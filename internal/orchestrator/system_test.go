@@ -0,0 +1,49 @@
+package orchestrator
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+func TestApplyAndDiffSystem(t *testing.T) {
+	spec := v1.ServiceSpec{
+		Name:       "db",
+		Ulimits:    map[string]string{"nofile": "1024:4096", "memlock": "-1"},
+		Sysctls:    map[string]string{"net.core.somaxconn": "1024"},
+		ExtraHosts: []string{"metrics.internal:10.0.0.9"},
+		DNS:        []string{"1.1.1.1"},
+	}
+	hostCfg := &containertypes.HostConfig{}
+	if err := applySystem(spec, hostCfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(hostCfg.Ulimits) != 2 || hostCfg.Ulimits[1].Name != "nofile" || hostCfg.Ulimits[1].Soft != 1024 ||
+		hostCfg.Ulimits[1].Hard != 4096 || hostCfg.Ulimits[0].Hard != -1 || hostCfg.Sysctls["net.core.somaxconn"] != "1024" {
+		t.Fatalf("host config = %+v", hostCfg)
+	}
+
+	info := types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{HostConfig: hostCfg}}
+	if changes := diffSystem(spec, info); len(changes) != 0 {
+		t.Errorf("unchanged spec: changes = %+v", changes)
+	}
+	changed := spec
+	changed.Ulimits = map[string]string{"nofile": "65536"}
+	changed.DNS, changed.DNSSearch = nil, []string{"corp.example.com"}
+	var fields []string
+	for _, c := range diffSystem(changed, info) {
+		fields = append(fields, c.Field)
+	}
+	if !slices.Equal(fields, []string{"ulimits", "dns", "dns_search"}) {
+		t.Errorf("fields = %v", fields)
+	}
+
+	spec.Ulimits = map[string]string{"nofile": "lots"}
+	if err := applySystem(spec, hostCfg); err == nil {
+		t.Error("expected an error for an invalid ulimit")
+	}
+}
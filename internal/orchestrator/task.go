@@ -0,0 +1,161 @@
+// Package orchestrator: one-off task containers for `orbit run`.
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	containertypes "github.com/docker/docker/api/types/container"
+	networktypes "github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// TaskOptions configures a one-off task container.
+type TaskOptions struct {
+	Cmd    []string          // command to run; empty uses the image default
+	Env    map[string]string // overrides merged over the service environment
+	TTY    bool              // allocate a pseudo-terminal
+	Stdin  io.Reader         // attached when non-nil
+	Stdout io.Writer
+	Stderr io.Writer // ignored with TTY, where output is a single stream
+}
+
+// TaskName returns the container name for a task of service, e.g. "web-run-1718000000".
+func TaskName(service string) string {
+	return fmt.Sprintf("%s-run-%d", service, time.Now().Unix())
+}
+
+// RunTask runs a disposable container built from spec — same image, environment,
+// volumes, networks, user, entrypoint, working directory and hardening, but no
+// published ports or restart policy; the command is opts.Cmd, else the
+// service's — and returns its exit code. Output is streamed while it runs and the container is
+// removed afterwards, even if ctx is cancelled.
+func (c *Client) RunTask(ctx context.Context, spec v1.ServiceSpec, opts TaskOptions) (int, error) {
+	if _, err := c.EnsureImage(ctx, spec.Image); err != nil {
+		return -1, err
+	}
+
+	env := make(map[string]string, len(spec.Environment)+len(opts.Env))
+	for k, v := range spec.Environment {
+		env[k] = v
+	}
+	for k, v := range opts.Env {
+		env[k] = v
+	}
+	envSlice := make([]string, 0, len(env))
+	for k, v := range env {
+		envSlice = append(envSlice, k+"="+v)
+	}
+
+	labels := map[string]string{"orbit.service": spec.Name, "orbit.task": "true"}
+	for k, v := range spec.Labels {
+		if _, ok := labels[k]; !ok {
+			labels[k] = v
+		}
+	}
+
+	cfg := &containertypes.Config{
+		Image:        spec.Image,
+		Env:          envSlice,
+		Labels:       c.projectLabels(labels),
+		User:         spec.User,
+		Tty:          opts.TTY,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	hostCfg := &containertypes.HostConfig{Binds: spec.Volumes}
+	if err := applyProcess(spec, cfg, hostCfg); err != nil {
+		return -1, err
+	}
+	if err := applySecurity(spec, hostCfg); err != nil {
+		return -1, err
+	}
+	if err := applySystem(spec, hostCfg); err != nil {
+		return -1, err
+	}
+	if len(opts.Cmd) > 0 {
+		cfg.Cmd = opts.Cmd
+	}
+	if opts.Stdin != nil {
+		cfg.AttachStdin, cfg.OpenStdin, cfg.StdinOnce = true, true, true
+	}
+
+	if len(spec.Networks) > 0 {
+		hostCfg.NetworkMode = containertypes.NetworkMode(spec.Networks[0])
+	}
+
+	name := c.containerName(TaskName(spec.Name))
+	resp, err := c.docker.ContainerCreate(ctx, cfg, hostCfg, &networktypes.NetworkingConfig{}, nil, name)
+	if err != nil {
+		return -1, fmt.Errorf("container create %q: %w", name, err)
+	}
+	id := resp.ID
+	defer func() {
+		// Use a fresh context so an interrupted task is still cleaned up
+		rmCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := c.docker.ContainerRemove(rmCtx, id, containertypes.RemoveOptions{Force: true}); err != nil {
+			c.log.Warn("task remove failed", "id", id[:12], "err", err)
+		}
+	}()
+
+	for _, n := range spec.Networks[min(1, len(spec.Networks)):] {
+		if err := c.docker.NetworkConnect(ctx, n, id, nil); err != nil {
+			return -1, fmt.Errorf("network connect %q: %w", n, err)
+		}
+	}
+
+	attach, err := c.docker.ContainerAttach(ctx, id, containertypes.AttachOptions{
+		Stream: true,
+		Stdin:  opts.Stdin != nil,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return -1, fmt.Errorf("container attach %q: %w", name, err)
+	}
+	defer attach.Close()
+
+	// Register the wait before starting so a fast exit is not missed
+	waitC, waitErrC := c.docker.ContainerWait(ctx, id, containertypes.WaitConditionNextExit)
+
+	if err := c.docker.ContainerStart(ctx, id, containertypes.StartOptions{}); err != nil {
+		return -1, fmt.Errorf("container start %q: %w", name, err)
+	}
+	c.log.Info("task started", "service", spec.Name, "name", name, "id", id[:12])
+
+	outputDone := make(chan error, 1)
+	go func() {
+		var err error
+		if opts.TTY {
+			_, err = io.Copy(opts.Stdout, attach.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(opts.Stdout, opts.Stderr, attach.Reader)
+		}
+		outputDone <- err
+	}()
+	if opts.Stdin != nil {
+		go func() {
+			_, _ = io.Copy(attach.Conn, opts.Stdin)
+			_ = attach.CloseWrite()
+		}()
+	}
+
+	select {
+	case res := <-waitC:
+		<-outputDone // drain remaining output
+		if res.Error != nil {
+			return -1, fmt.Errorf("task %q: %s", name, res.Error.Message)
+		}
+		c.log.Info("task exited", "service", spec.Name, "id", id[:12], "code", res.StatusCode)
+		return int(res.StatusCode), nil
+	case err := <-waitErrC:
+		return -1, fmt.Errorf("container wait %q: %w", name, err)
+	case <-ctx.Done():
+		return -1, ctx.Err()
+	}
+}
@@ -0,0 +1,208 @@
+// Package orchestrator: applying config changes with the cheapest Docker
+// operation that achieves them.
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+// UpdatePlan maps a service's changes to Docker operations. Restart policy,
+// secondary networks and the container name change in place; replica
+// count changes start or stop replicas alone. Everything else Docker fixes
+// at create time — labels included — and needs a new container, which only
+// pulls when the image changed.
+type UpdatePlan struct {
+	Changes  []FieldChange
+	Pull     bool     // the image changed
+	Recreate []string // fields only a new container can apply
+	Restart  string   // restart policy to set; "" when unchanged
+	Connect  []string // networks to attach
+	Detach   []string // networks to leave
+	Rename   bool     // the primary container has lost its canonical name
+	Replicas int      // replica count to scale to; 0 when unchanged
+}
+
+// PlanUpdate classifies changes, as reported by Plan or Drift.
+func PlanUpdate(changes []FieldChange) UpdatePlan {
+	u := UpdatePlan{Changes: changes}
+	for _, c := range changes {
+		switch c.Field {
+		case "image":
+			u.Pull = true
+			u.Recreate = append(u.Recreate, c.Field)
+		case "restart":
+			u.Restart = c.To
+		case "networks":
+			want, got := splitList(c.To), splitList(c.From)
+			for _, n := range want {
+				if !slices.Contains(got, n) {
+					u.Connect = append(u.Connect, n)
+				}
+			}
+			for _, n := range got {
+				if !slices.Contains(want, n) {
+					u.Detach = append(u.Detach, n)
+				}
+			}
+		case "name":
+			u.Rename = true
+		case "replicas":
+			u.Replicas, _ = strconv.Atoi(c.To)
+		default:
+			u.Recreate = append(u.Recreate, c.Field)
+		}
+	}
+	return u
+}
+
+// InPlace reports whether there are changes and none needs a new container.
+func (u UpdatePlan) InPlace() bool {
+	return len(u.Changes) > 0 && len(u.Recreate) == 0
+}
+
+// String summarises the operations, e.g. "update restart policy, connect cache".
+func (u UpdatePlan) String() string {
+	var ops []string
+	if len(u.Recreate) > 0 {
+		op := "recreate"
+		if u.Pull {
+			op = "pull and recreate"
+		}
+		return fmt.Sprintf("%s (%s)", op, strings.Join(u.Recreate, ", "))
+	}
+	if u.Restart != "" {
+		ops = append(ops, "update restart policy")
+	}
+	for _, n := range u.Connect {
+		ops = append(ops, "connect "+n)
+	}
+	for _, n := range u.Detach {
+		ops = append(ops, "disconnect "+n)
+	}
+	if u.Rename {
+		ops = append(ops, "rename")
+	}
+	if u.Replicas > 0 {
+		ops = append(ops, fmt.Sprintf("scale to %d", u.Replicas))
+	}
+	return strings.Join(ops, ", ")
+}
+
+// apply makes u's in-place changes to container id; name is the canonical
+// name to restore when u.Rename is set, or "" for a container whose name is
+// already right.
+func (u UpdatePlan) apply(ctx context.Context, docker Runtime, log *logger.Logger, id, name string) error {
+	if u.Restart != "" {
+		if err := docker.UpdateRestartPolicy(ctx, id, u.Restart); err != nil {
+			return err
+		}
+	}
+	for _, n := range u.Detach {
+		if err := docker.DisconnectNetwork(ctx, n, id); err != nil {
+			return err
+		}
+	}
+	for _, n := range u.Connect {
+		if err := docker.ConnectNetwork(ctx, n, id); err != nil {
+			return err
+		}
+	}
+	if u.Rename && name != "" {
+		if err := docker.RenameContainer(ctx, id, name); err != nil {
+			return err
+		}
+	}
+	log.Info("container updated in place", "id", id[:min(12, len(id))], "ops", u.String())
+	return nil
+}
+
+// serviceContainers are a service's replicas, without one-off task containers.
+func serviceContainers(ctx context.Context, docker Runtime, service string) ([]types.Container, error) {
+	ctrs, err := docker.ListContainers(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(ctrs, func(c types.Container) bool { return c.Labels["orbit.task"] != "" }), nil
+}
+
+// mutableTag reports whether image names a tag that is moved to new builds
+// — latest, or no tag at all — so only a pull shows whether it changed.
+func mutableTag(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	i := lastColonIdx(image)
+	if i == -1 || strings.Contains(image[i:], "/") { // a registry port, not a tag
+		return true
+	}
+	return image[i+1:] == "latest"
+}
+
+// diffNetworks compares the spec's networks with the container's: the first
+// is its network mode, the rest are attached after it starts.
+func diffNetworks(spec v1.ServiceSpec, info types.ContainerJSON) []FieldChange {
+	if info.HostConfig == nil {
+		return nil
+	}
+	mode := string(info.HostConfig.NetworkMode)
+	want := ""
+	if len(spec.Networks) > 0 {
+		want = spec.Networks[0]
+	}
+	var changes []FieldChange
+	defaultMode := mode == "" || mode == "default" || mode == "bridge"
+	if want != mode && !(want == "" && defaultMode) {
+		changes = append(changes, FieldChange{Field: "network_mode", From: mode, To: want})
+	}
+
+	var attached []string
+	if info.NetworkSettings != nil {
+		for n := range info.NetworkSettings.Networks {
+			if n != mode && !(defaultMode && n == "bridge") {
+				attached = append(attached, n)
+			}
+		}
+	}
+	return append(changes, diffList("networks", spec.Networks[min(1, len(spec.Networks)):], attached)...)
+}
+
+// diffName reports a primary container that no longer has the service's
+// name, as after an interrupted cut-over.
+func diffName(spec v1.ServiceSpec, info types.ContainerJSON) []FieldChange {
+	if info.ContainerJSONBase == nil || info.Name == "" {
+		return nil
+	}
+	if got := strings.TrimPrefix(info.Name, "/"); got != spec.Name {
+		return []FieldChange{{Field: "name", From: got, To: spec.Name}}
+	}
+	return nil
+}
+
+// diffReplicas compares deploy.replicas with the replicas running, unless
+// `orbit scale` or the autoscaler has chosen the count.
+func diffReplicas(spec v1.ServiceSpec, s v1.ServiceState) []FieldChange {
+	if spec.Deploy == nil || spec.Deploy.Replicas == 0 || spec.Deploy.Autoscale != nil || s.Scale > 0 {
+		return nil
+	}
+	if got := max(s.Replicas, 1); got != spec.Deploy.Replicas {
+		return []FieldChange{{Field: "replicas", From: strconv.Itoa(got), To: strconv.Itoa(spec.Deploy.Replicas)}}
+	}
+	return nil
+}
+
+// splitList undoes diffList's joining.
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,133 @@
+package orchestrator
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+// updateRuntime records in-place updates and container starts.
+type updateRuntime struct {
+	inspectRuntime
+	ops []string
+}
+
+func (r *updateRuntime) ListContainers(context.Context, string) ([]types.Container, error) {
+	return []types.Container{{ID: "0123456789abcdef", Names: []string{"/web"}}}, nil
+}
+
+func (r *updateRuntime) UpdateRestartPolicy(_ context.Context, _, policy string) error {
+	r.ops = append(r.ops, "restart="+policy)
+	return nil
+}
+
+func (r *updateRuntime) ConnectNetwork(_ context.Context, network, _ string) error {
+	r.ops = append(r.ops, "connect="+network)
+	return nil
+}
+
+func (r *updateRuntime) StopContainer(context.Context, string, bool) error {
+	r.ops = append(r.ops, "stop")
+	return nil
+}
+
+func (r *updateRuntime) RunContainer(_ context.Context, _ v1.ServiceSpec, name string) (string, error) {
+	r.ops = append(r.ops, "run="+name)
+	return "fedcba9876543210", nil
+}
+
+func TestPlanUpdate(t *testing.T) {
+	u := PlanUpdate([]FieldChange{
+		{Field: "restart", From: "unless-stopped", To: "always"},
+		{Field: "networks", From: "old", To: "cache,queue"},
+		{Field: "replicas", From: "1", To: "3"},
+	})
+	if !u.InPlace() || u.Restart != "always" || u.Replicas != 3 ||
+		len(u.Connect) != 2 || len(u.Detach) != 1 || u.Detach[0] != "old" {
+		t.Errorf("in-place plan = %+v", u)
+	}
+	if got := u.String(); got != "update restart policy, connect cache, connect queue, disconnect old, scale to 3" {
+		t.Errorf("String() = %q", got)
+	}
+
+	u = PlanUpdate([]FieldChange{{Field: "restart", To: "always"}, {Field: "labels.team", To: "edge"}})
+	if u.InPlace() || u.Pull || u.String() != "recreate (labels.team)" {
+		t.Errorf("label change = %+v", u)
+	}
+	if u = PlanUpdate([]FieldChange{{Field: "image", To: "app:2"}}); !u.Pull || u.InPlace() {
+		t.Errorf("image change = %+v", u)
+	}
+
+	for image, mutable := range map[string]bool{
+		"nginx": true, "nginx:latest": true, "localhost:5000/app": true,
+		"nginx:1.27": false, "localhost:5000/app:v2": false, "app@sha256:abcd": false,
+	} {
+		if mutableTag(image) != mutable {
+			t.Errorf("mutableTag(%q) = %v", image, !mutable)
+		}
+	}
+}
+
+func TestDiffNetworks(t *testing.T) {
+	info := runningContainer()
+	info.HostConfig.NetworkMode = "front"
+	info.NetworkSettings = &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{"front": {}, "old": {}}}
+
+	changes := diffNetworks(v1.ServiceSpec{Networks: []string{"front", "cache"}}, info)
+	if len(changes) != 1 || changes[0].Field != "networks" || changes[0].From != "old" || changes[0].To != "cache" {
+		t.Errorf("secondary networks: %+v", changes)
+	}
+	changes = diffNetworks(v1.ServiceSpec{Networks: []string{"back"}}, info)
+	if len(changes) != 2 || changes[0].Field != "network_mode" {
+		t.Errorf("network mode: %+v", changes)
+	}
+	if changes := diffNetworks(v1.ServiceSpec{}, runningContainer()); len(changes) != 0 {
+		t.Errorf("default network: %+v", changes)
+	}
+}
+
+func TestUpUpdatesInPlace(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+	db.PutServiceState(v1.ServiceState{Name: "web", Node: "local", ContainerID: "0123456789abcdef", Image: "app:1"})
+
+	info := runningContainer()
+	info.HostConfig.NetworkMode = "front"
+	rt := &updateRuntime{inspectRuntime: inspectRuntime{info: info, imageEnv: []string{"PATH=/usr/bin"}}}
+	spec := v1.ServiceSpec{
+		Name: "web", Image: "app:1", Ports: []string{"8080:80"}, Volumes: []string{"data:/var/lib/app"},
+		Environment: map[string]string{"MODE": "prod"}, Labels: map[string]string{"team": "core"},
+		RestartPolicy: "always", Networks: []string{"front", "cache"},
+	}
+	lm := NewLifecycleManager(rt, db, log)
+	if err := lm.Up(context.Background(), []v1.ServiceSpec{spec}, "local", false); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(rt.ops) != 2 || rt.ops[0] != "restart=always" || rt.ops[1] != "connect=cache" {
+		t.Errorf("in-place ops = %v", rt.ops)
+	}
+
+	// A label change needs a new container, but no pull.
+	rt.ops = nil
+	spec.RestartPolicy, spec.Networks = "", []string{"front"}
+	spec.Labels = map[string]string{"team": "edge"}
+	if err := lm.Up(context.Background(), []v1.ServiceSpec{spec}, "local", false); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(rt.ops) != 2 || rt.ops[0] != "stop" || rt.ops[1] != "run=web" {
+		t.Errorf("recreate ops = %v", rt.ops)
+	}
+}
@@ -0,0 +1,138 @@
+// Package orchestrator: watching a deploy after cut-over, while it can still
+// be rolled back.
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// DefaultVerifyInterval is how often deploy.verify checks the new replicas
+// when no interval is set.
+const DefaultVerifyInterval = 5 * time.Second
+
+// verifyClient sends deploy.verify's error-rate probes; each request is
+// bounded by the check interval.
+var verifyClient = &http.Client{}
+
+// verifyStats is what deploy.verify has seen of the new replicas so far.
+type verifyStats struct {
+	restarts int
+	flaps    int
+	requests int
+	failures int
+}
+
+// verify watches the replicas of a finished rollout for spec.Deploy.Verify's
+// window and returns an ErrServiceVerify error as soon as they regress past
+// its limits.
+func (d *Deployer) verify(ctx context.Context, spec v1.ServiceSpec, node string, slots []*replicaSlot) error {
+	v := spec.Deploy.Verify
+	interval := v.Interval
+	if interval <= 0 {
+		interval = DefaultVerifyInterval
+	}
+	// The error budget of the whole window: spending it fails early.
+	expected := max(int(v.Window/interval), 1)
+	fail := func(format string, args ...any) error {
+		reason := fmt.Sprintf(format, args...)
+		d.log.Warn("deploy.verify.failed", "service", spec.Name, "reason", reason)
+		return errs.Newf(errs.ErrServiceVerify, "deploy.verify", "%s regressed within %s of cut-over: %s", spec.Name, v.Window, reason).
+			WithNode(node)
+	}
+
+	baseline := make(map[string]int, len(slots))
+	ready := make(map[string]bool, len(slots))
+	for _, r := range slots {
+		info, err := d.docker.InspectContainer(ctx, r.newID)
+		if err != nil {
+			return fail("replica %s: %v", r.name, err)
+		}
+		baseline[r.newID], ready[r.newID] = info.RestartCount, true
+	}
+
+	d.log.Info("deploy.verify", "service", spec.Name, "window", v.Window, "interval", interval, "replicas", len(slots))
+	var st verifyStats
+	deadline := time.Now().Add(v.Window)
+	for time.Now().Before(deadline) {
+		timer := time.NewTimer(min(interval, time.Until(deadline)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		st.restarts = 0
+		for _, r := range slots {
+			info, err := d.docker.InspectContainer(ctx, r.newID)
+			if err != nil {
+				return fail("replica %s: %v", r.name, err)
+			}
+			if info.State == nil || (!info.State.Running && !info.State.Restarting) {
+				code := 0
+				if info.State != nil {
+					code = info.State.ExitCode
+				}
+				return fail("replica %s exited with code %d", r.name, code)
+			}
+			st.restarts += info.RestartCount - baseline[r.newID]
+
+			if spec.HealthCheck != nil && d.checker != nil {
+				err := d.checker.CheckProbe(ctx, spec, r.newID, health.ProbeReadiness)
+				if err != nil && ready[r.newID] {
+					st.flaps++
+					d.log.Warn("deploy.verify.flap", "service", spec.Name, "replica", r.name, "err", err)
+				}
+				ready[r.newID] = err == nil
+			}
+		}
+		if st.restarts > v.MaxRestarts {
+			return fail("%d restart(s), %d allowed", st.restarts, v.MaxRestarts)
+		}
+		if st.flaps > v.MaxHealthFlaps {
+			return fail("readiness probe failed %d time(s) after passing, %d allowed", st.flaps, v.MaxHealthFlaps)
+		}
+
+		if v.URL != "" {
+			st.requests++
+			if err := checkVerifyURL(ctx, v.URL, interval); err != nil {
+				st.failures++
+				d.log.Warn("deploy.verify.request", "service", spec.Name, "url", v.URL, "err", err)
+			}
+			if float64(st.failures)*100 > v.MaxErrorRate*float64(max(expected, st.requests)) {
+				return fail("%d of %d requests to %s failed, over the %g%% allowed", st.failures, st.requests, v.URL, v.MaxErrorRate)
+			}
+		}
+	}
+
+	d.log.Info("deploy.verify.passed", "service", spec.Name,
+		"restarts", st.restarts, "flaps", st.flaps, "requests", st.requests, "failed_requests", st.failures)
+	return nil
+}
+
+// checkVerifyURL sends one error-rate probe to url. Only no answer or a 5xx
+// status is a failure: a 404 or 401 is the application answering.
+func checkVerifyURL(ctx context.Context, url string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := verifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
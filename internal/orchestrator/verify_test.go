@@ -0,0 +1,118 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/pkg/encryption"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// verifyRuntime serves one replica of api:1 and runs replacements whose
+// restart count grows by restarts at each inspection.
+type verifyRuntime struct {
+	Runtime
+	restarts int
+	inspects int
+	runs     []string
+}
+
+func (r *verifyRuntime) PullImage(context.Context, string) error { return nil }
+
+func (r *verifyRuntime) ListContainers(context.Context, string) ([]types.Container, error) {
+	return []types.Container{{ID: "0123456789abcdef", Names: []string{"/api"}, Image: "api:1"}}, nil
+}
+
+func (r *verifyRuntime) RunContainer(_ context.Context, spec v1.ServiceSpec, _ string) (string, error) {
+	r.runs = append(r.runs, spec.Image)
+	return fmt.Sprintf("%016d", len(r.runs)), nil
+}
+
+func (r *verifyRuntime) ImageEnv(context.Context, string) ([]string, error) { return nil, nil }
+
+func (r *verifyRuntime) StopContainer(context.Context, string, bool) error     { return nil }
+func (r *verifyRuntime) RenameContainer(context.Context, string, string) error { return nil }
+
+func (r *verifyRuntime) InspectContainer(context.Context, string) (types.ContainerJSON, error) {
+	count := r.restarts * r.inspects
+	r.inspects++
+	return types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+		State:        &types.ContainerState{Running: true},
+		RestartCount: count,
+	}}, nil
+}
+
+func TestDeployVerify(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	verify := &v1.VerifySpec{Window: 50 * time.Millisecond, Interval: 10 * time.Millisecond, URL: srv.URL}
+	spec := v1.ServiceSpec{Name: "api", Image: "api:1", Deploy: &v1.DeploySpec{Verify: verify}}
+	deploy := func(rt *verifyRuntime) error {
+		return NewDeployer(rt, db, health.NewChecker(log), log).Deploy(context.Background(), spec, "local", DeployOptions{Tag: "2"})
+	}
+
+	rt := &verifyRuntime{}
+	if err := deploy(rt); err != nil {
+		t.Fatalf("a steady deploy failed verification: %v", err)
+	}
+	if len(rt.runs) != 1 || rt.inspects < 3 {
+		t.Errorf("runs = %v, inspects = %d", rt.runs, rt.inspects)
+	}
+
+	cases := []struct {
+		name     string
+		restarts int
+		status   int
+	}{
+		{"restarts", 1, http.StatusOK},
+		{"errors", 0, http.StatusBadGateway},
+	}
+	for _, tc := range cases {
+		rt := &verifyRuntime{restarts: tc.restarts}
+		status = tc.status
+		err := deploy(rt)
+		if !errs.IsCode(err, errs.ErrServiceVerify) {
+			t.Fatalf("%s: deploy = %v, want a verification failure", tc.name, err)
+		}
+		if len(rt.runs) != 2 || rt.runs[1] != "api:1" {
+			t.Errorf("%s: runs = %v, want api:2 rolled back to api:1", tc.name, rt.runs)
+		}
+	}
+
+	recs, err := db.ListDeployments("api")
+	if err != nil || len(recs) != 3 {
+		t.Fatalf("history = %+v, %v", recs, err)
+	}
+	var rolledBack int
+	for _, rec := range recs {
+		if rec.Result == v1.DeployResultRolledBack {
+			rolledBack++
+		}
+	}
+	if rolledBack != 2 {
+		t.Errorf("history = %+v, want 2 rolled back", recs)
+	}
+}
@@ -0,0 +1,123 @@
+// Package caddy generates Caddyfile site blocks from Orbit service specs.
+package caddy
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"text/template"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/ssl"
+)
+
+// domainSafe validates a domain is safe to embed in a Caddyfile.
+var domainSafe = regexp.MustCompile(`^[a-zA-Z0-9.\-]+$`)
+
+// siteBlockTemplate is the Caddyfile site block for a proxied service. Sites
+// without SSL are served over plain HTTP so Caddy does not try to obtain a
+// certificate of its own; SSL sites use the certificate Orbit issued.
+const siteBlockTemplate = `# Generated by Orbit — do not edit manually
+{{ if .SSL }}{{ .Domain }}{{ if .Port }}:{{ .Port }}{{ end }} {
+	tls {{ .CertPath }} {{ .KeyPath }}
+{{ else }}http://{{ .Domain }}{{ if .Port }}:{{ .Port }}{{ end }} {
+{{ end }}	reverse_proxy 127.0.0.1:{{ .BackendPort }}
+}
+`
+
+// Generator writes Caddyfile snippets, one per service, for a main Caddyfile
+// to pull in with an import directive.
+type Generator struct {
+	configDir string // directory for generated .caddy files
+	log       *logger.Logger
+}
+
+// NewGenerator creates a Generator that writes snippets to configDir.
+func NewGenerator(configDir string, log *logger.Logger) *Generator {
+	return &Generator{configDir: configDir, log: log}
+}
+
+// templateData carries values into the site block template.
+type templateData struct {
+	Domain      string
+	Port        int // 0 uses Caddy's default for the scheme
+	SSL         bool
+	CertPath    string
+	KeyPath     string
+	BackendPort int
+}
+
+// GenerateAll writes one .caddy file per service that has a proxy spec configured.
+func (g *Generator) GenerateAll(services []v1.ServiceSpec, certDir string) error {
+	if err := os.MkdirAll(g.configDir, 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+
+	tmpl, err := template.New("site").Parse(siteBlockTemplate)
+	if err != nil {
+		return fmt.Errorf("parse template: %w", err)
+	}
+
+	for _, svc := range services {
+		if svc.Proxy == nil {
+			continue
+		}
+		if err := g.writeOne(tmpl, svc, certDir); err != nil {
+			g.log.Warn("proxy config gen failed", "service", svc.Name, "err", err)
+		}
+	}
+	return nil
+}
+
+func (g *Generator) writeOne(tmpl *template.Template, svc v1.ServiceSpec, certDir string) error {
+	px := svc.Proxy
+
+	if !domainSafe.MatchString(px.Domain) {
+		return fmt.Errorf("unsafe domain %q rejected", px.Domain)
+	}
+
+	backendPort := px.Backend
+	if backendPort == 0 {
+		backendPort = 8080
+	}
+
+	data := templateData{
+		Domain:      px.Domain,
+		Port:        px.Port,
+		SSL:         px.SSL,
+		BackendPort: backendPort,
+	}
+
+	if px.SSL {
+		data.CertPath, data.KeyPath = ssl.CertPaths(certDir, px.Domain)
+	}
+
+	outPath := filepath.Join(g.configDir, "orbit_"+svc.Name+".caddy")
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("create %q: %w", outPath, err)
+	}
+	defer f.Close()
+
+	if err := tmpl.Execute(f, data); err != nil {
+		return fmt.Errorf("template execute: %w", err)
+	}
+
+	g.log.Info("proxy config written", "service", svc.Name, "path", outPath)
+	return nil
+}
+
+// Reload asks the local Caddy to load its current Caddyfile, without dropping
+// connections.
+func (g *Generator) Reload(caddyfile string) error {
+	cmd := exec.Command("caddy", "reload", "--config", caddyfile, "--adapter", "caddyfile") //nolint:gosec
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("caddy reload: %w (output: %s)", err, string(out))
+	}
+	g.log.Info("caddy reloaded")
+	return nil
+}
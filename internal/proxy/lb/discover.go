@@ -0,0 +1,179 @@
+// Package lb: finding the replicas to route to.
+package lb
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/state"
+)
+
+// DefaultBackendPort is the container port proxied to when proxy.backend is
+// unset, as for the generated NGINX configs.
+const DefaultBackendPort = 8080
+
+// ContainerLister lists a service's running containers.
+type ContainerLister interface {
+	ListContainers(ctx context.Context, service string) ([]types.Container, error)
+}
+
+// StateDiscovery finds routes for the services of orbit.yaml that have a
+// proxy section, on every node the state DB records them as deployed to.
+// Replicas on LocalNode are reached directly through Containers; on a remote
+// node the service is reached at the port it publishes on the node's host.
+// Deployments that are not Serving are left out.
+type StateDiscovery struct {
+	Services   []v1.ServiceSpec
+	State      *state.DB
+	Containers ContainerLister // nil leaves out local replicas
+	LocalNode  string
+}
+
+// Routes implements DiscoverFunc.
+func (d *StateDiscovery) Routes(ctx context.Context) ([]Route, error) {
+	states, err := d.State.ListServiceStates("")
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := d.State.ListNodes()
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[string]string, len(nodes))
+	for _, n := range nodes {
+		hosts[n.Spec.Name] = n.Spec.Host
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Node < states[j].Node })
+
+	var routes []Route
+	for _, svc := range d.Services {
+		px := svc.Proxy
+		if px == nil || (px.Domain == "" && px.Port == 0) {
+			continue
+		}
+		port := px.Backend
+		if port == 0 {
+			port = DefaultBackendPort
+		}
+		r := Route{Service: svc.Name, Domain: px.Domain, SSL: px.SSL}
+		if px.Domain == "" {
+			r.Port = px.Port
+		}
+		for _, st := range states {
+			if st.Name != svc.Name || !Serving(st) {
+				continue
+			}
+			if st.Node == d.LocalNode {
+				if d.Containers == nil {
+					continue
+				}
+				ctrs, err := d.Containers.ListContainers(ctx, svc.Name)
+				if err != nil {
+					return nil, err
+				}
+				for _, c := range ctrs {
+					if addr := containerAddr(c, port); addr != "" {
+						r.Backends = append(r.Backends, Backend{Node: st.Node, Name: containerName(c), Addr: addr})
+					}
+				}
+				continue
+			}
+			host := hosts[st.Node]
+			if host == "" {
+				continue
+			}
+			for _, pub := range publishedPorts(st, svc.Ports, port) {
+				r.Backends = append(r.Backends, Backend{Node: st.Node, Name: svc.Name, Addr: net.JoinHostPort(host, strconv.Itoa(pub))})
+			}
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+// Serving reports whether a deployment may take traffic: its readiness probe
+// passes and it is neither unhealthy nor crash-looping.
+func Serving(st v1.ServiceState) bool {
+	return st.Ready && !st.CrashLoop && st.Status != v1.StatusUnhealthy
+}
+
+// publishedPorts returns the host ports a remote deployment's replicas
+// publish container port on: those recorded on its state, which include the
+// ports of replicas on ephemeral or offset ports, or else the one orbit.yaml
+// publishes.
+func publishedPorts(st v1.ServiceState, ports []string, container int) []int {
+	var pubs []int
+	for _, p := range st.Ports {
+		if pub := PublishedPort([]string{p}, container); pub > 0 {
+			pubs = append(pubs, pub)
+		}
+	}
+	if len(pubs) == 0 {
+		if pub := PublishedPort(ports, container); pub > 0 {
+			pubs = append(pubs, pub)
+		}
+	}
+	return pubs
+}
+
+// PublishedPort returns the host port that ports ("host:container" entries,
+// as in orbit.yaml) publish container port on, or 0 when it is not published.
+func PublishedPort(ports []string, container int) int {
+	for _, p := range ports {
+		hostPort, ctrPort, ok := strings.Cut(p, ":")
+		if !ok {
+			continue
+		}
+		ctrPort, _, _ = strings.Cut(ctrPort, "/")
+		if n, err := strconv.Atoi(ctrPort); err != nil || n != container {
+			continue
+		}
+		if n, err := strconv.Atoi(hostPort); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// containerAddr is the address to reach port of c at from the host: the
+// host port it is published on, or else the container's own address on its
+// first network.
+func containerAddr(c types.Container, port int) string {
+	for _, p := range c.Ports {
+		if int(p.PrivatePort) != port || p.PublicPort == 0 || p.Type != "tcp" {
+			continue
+		}
+		ip := p.IP
+		if ip == "" || ip == "0.0.0.0" || ip == "::" {
+			ip = "127.0.0.1"
+		}
+		return net.JoinHostPort(ip, strconv.Itoa(int(p.PublicPort)))
+	}
+	if c.NetworkSettings == nil {
+		return ""
+	}
+	names := make([]string, 0, len(c.NetworkSettings.Networks))
+	for name := range c.NetworkSettings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ep := c.NetworkSettings.Networks[name]; ep != nil && ep.IPAddress != "" {
+			return net.JoinHostPort(ep.IPAddress, strconv.Itoa(port))
+		}
+	}
+	return ""
+}
+
+func containerName(c types.Container) string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+	return c.ID[:min(12, len(c.ID))]
+}
@@ -0,0 +1,134 @@
+// Package lb: HTTP routing and TLS certificates.
+package lb
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f9-o/orbit/internal/ssl"
+	"github.com/f9-o/orbit/pkg/netutil"
+)
+
+// targetKey carries the chosen backend from ServeHTTP to the reverse proxy.
+type targetKey struct{}
+
+type target struct {
+	pool    *pool
+	backend *backend
+}
+
+// handler routes requests by Host to the matching service's backends. On
+// the plain HTTP listener, requests for SSL routes are redirected to HTTPS
+// when TLS termination is enabled.
+func (s *Server) handler(isTLS bool) http.Handler {
+	httpsPort := portOf(s.opts.HTTPSAddr)
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			t := pr.In.Context().Value(targetKey{}).(target)
+			pr.SetURL(&url.URL{Scheme: "http", Host: t.backend.Addr})
+			pr.SetXForwarded()
+			pr.Out.Host = pr.In.Host
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if r.Context().Err() != nil {
+				return // client went away
+			}
+			t := r.Context().Value(targetKey{}).(target)
+			s.setHealth(t.pool, t.backend, err)
+			http.Error(w, "502 bad gateway", http.StatusBadGateway)
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.ToLower(r.Host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		p := s.lookup(host)
+		if p == nil {
+			http.Error(w, "404 no route for host", http.StatusNotFound)
+			return
+		}
+		if !isTLS && p.ssl() && s.opts.HTTPSAddr != "" {
+			u := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: r.URL.RawQuery}
+			if httpsPort != "" && httpsPort != "443" {
+				u.Host = net.JoinHostPort(host, httpsPort)
+			}
+			http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
+			return
+		}
+		b := p.pick()
+		if b == nil {
+			http.Error(w, "503 no healthy backend", http.StatusServiceUnavailable)
+			return
+		}
+		ctx := context.WithValue(r.Context(), targetKey{}, target{pool: p, backend: b})
+		proxy.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// certStore serves certificates from the SSL cert store by SNI name,
+// reloading a certificate when its file changes so renewals apply without a
+// restart.
+type certStore struct {
+	dir   string
+	mu    sync.Mutex
+	cache map[string]cachedCert // by certificate file
+}
+
+type cachedCert struct {
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertStore(dir string) *certStore {
+	return &certStore{dir: dir, cache: map[string]cachedCert{}}
+}
+
+// get is a tls.Config.GetCertificate: it looks for a certificate issued for
+// the SNI name, then for a wildcard covering it.
+func (c *certStore) get(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if name == "" {
+		return nil, fmt.Errorf("client sent no server name")
+	}
+	// The name becomes a path in the cert store.
+	if !netutil.IsValidDomain(name) {
+		return nil, fmt.Errorf("client sent an invalid server name %q", name)
+	}
+	candidates := []string{name}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		candidates = append(candidates, "*"+name[i:])
+	}
+	for _, domain := range candidates {
+		certFile, keyFile := ssl.CertPaths(c.dir, domain)
+		fi, err := os.Stat(certFile)
+		if err != nil {
+			continue
+		}
+		c.mu.Lock()
+		cached, ok := c.cache[certFile]
+		c.mu.Unlock()
+		if ok && cached.modTime.Equal(fi.ModTime()) {
+			return cached.cert, nil
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("certificate for %s: %w", domain, err)
+		}
+		c.mu.Lock()
+		c.cache[certFile] = cachedCert{cert: &cert, modTime: fi.ModTime()}
+		c.mu.Unlock()
+		return &cert, nil
+	}
+	return nil, fmt.Errorf("no certificate for %s in %s", name, c.dir)
+}
@@ -0,0 +1,400 @@
+// Package lb is Orbit's built-in reverse proxy: it load-balances HTTP and TCP
+// traffic across a service's replicas, takes replicas that fail health checks
+// out of rotation, and terminates TLS with certificates from the SSL cert store.
+package lb
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+// Defaults for zero Options durations.
+const (
+	DefaultRefreshInterval = 5 * time.Second
+	DefaultHealthInterval  = 5 * time.Second
+	DefaultHealthTimeout   = 2 * time.Second
+)
+
+// Backend is one replica traffic can be sent to.
+type Backend struct {
+	Node string `json:"node"`
+	Name string `json:"name"` // container name
+	Addr string `json:"addr"` // host:port
+}
+
+// Route sends a service's traffic to its backends. Routes with a Domain are
+// matched on the Host header by the HTTP listeners, and on SNI for TLS;
+// routes without one are plain TCP and get a listener of their own on Port.
+type Route struct {
+	Service  string
+	Domain   string
+	Port     int
+	SSL      bool
+	Backends []Backend
+}
+
+// DiscoverFunc returns the current routes. It is called at start and then
+// every RefreshInterval.
+type DiscoverFunc func(ctx context.Context) ([]Route, error)
+
+// Options configure a Server.
+type Options struct {
+	HTTPAddr  string // plain HTTP listen address; "" disables it
+	HTTPSAddr string // TLS listen address; "" disables TLS termination
+	CertDir   string // SSL cert store, laid out as by ssl.CertPaths
+
+	RefreshInterval time.Duration
+	HealthInterval  time.Duration
+	HealthTimeout   time.Duration // also the dial timeout for TCP routes
+}
+
+// Event reports a backend entering or leaving rotation.
+type Event struct {
+	Time    time.Time
+	Service string
+	Backend Backend
+	Up      bool
+	Err     error // why the backend was taken out
+}
+
+// Server is the proxy. Build one with New and start it with Run.
+type Server struct {
+	opts     Options
+	discover DiscoverFunc
+	log      *logger.Logger
+	certs    *certStore
+	events   chan Event
+
+	mu    sync.RWMutex
+	pools map[string]*pool // by service
+	hosts map[string]*pool // HTTP routes by lower-case domain
+	tcp   map[int]*tcpListener
+}
+
+// New returns a Server routing to what discover finds.
+func New(opts Options, discover DiscoverFunc, log *logger.Logger) *Server {
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = DefaultRefreshInterval
+	}
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = DefaultHealthInterval
+	}
+	if opts.HealthTimeout <= 0 {
+		opts.HealthTimeout = DefaultHealthTimeout
+	}
+	return &Server{
+		opts:     opts,
+		discover: discover,
+		log:      log,
+		certs:    newCertStore(opts.CertDir),
+		events:   make(chan Event, 64),
+		pools:    map[string]*pool{},
+		hosts:    map[string]*pool{},
+		tcp:      map[int]*tcpListener{},
+	}
+}
+
+// Events delivers backend health transitions. Events are dropped when the
+// channel is full rather than holding up traffic.
+func (s *Server) Events() <-chan Event {
+	return s.events
+}
+
+// Run discovers routes, opens the listeners and serves until ctx is done.
+// A failed first discovery or an address that cannot be bound is returned
+// at once; later discovery failures keep the last known routes.
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.refresh(ctx); err != nil {
+		return err
+	}
+	s.checkAll(ctx)
+
+	var servers []*http.Server
+	errc := make(chan error, 2)
+	serve := func(addr string, tlsCfg *tls.Config) error {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		srv := &http.Server{Handler: s.handler(tlsCfg != nil), TLSConfig: tlsCfg, ReadHeaderTimeout: 10 * time.Second}
+		servers = append(servers, srv)
+		go func() {
+			if tlsCfg != nil {
+				errc <- srv.ServeTLS(ln, "", "")
+			} else {
+				errc <- srv.Serve(ln)
+			}
+		}()
+		s.log.Info("proxy.listen", "addr", ln.Addr().String(), "tls", tlsCfg != nil)
+		return nil
+	}
+	shutdown := func() {
+		sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, srv := range servers {
+			_ = srv.Shutdown(sctx)
+		}
+		s.closeTCP()
+	}
+
+	if s.opts.HTTPAddr != "" {
+		if err := serve(s.opts.HTTPAddr, nil); err != nil {
+			shutdown()
+			return err
+		}
+	}
+	if s.opts.HTTPSAddr != "" {
+		tlsCfg := &tls.Config{GetCertificate: s.certs.get, MinVersion: tls.VersionTLS12}
+		if err := serve(s.opts.HTTPSAddr, tlsCfg); err != nil {
+			shutdown()
+			return err
+		}
+	}
+
+	refresh := time.NewTicker(s.opts.RefreshInterval)
+	defer refresh.Stop()
+	check := time.NewTicker(s.opts.HealthInterval)
+	defer check.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			shutdown()
+			return nil
+		case err := <-errc:
+			if !errors.Is(err, http.ErrServerClosed) {
+				shutdown()
+				return err
+			}
+		case <-refresh.C:
+			if err := s.refresh(ctx); err != nil {
+				s.log.Warn("proxy: discovery failed, keeping current routes", "err", err)
+			}
+		case <-check.C:
+			s.checkAll(ctx)
+		}
+	}
+}
+
+// Routes returns the routes being served, ordered by service, with the
+// backends currently in rotation.
+func (s *Server) Routes() []Route {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Route, 0, len(s.pools))
+	for _, p := range s.pools {
+		out = append(out, p.snapshot())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Service < out[j].Service })
+	return out
+}
+
+// refresh replaces the routing tables with freshly discovered routes. Pools
+// of services that are still routed keep their backends' health.
+func (s *Server) refresh(ctx context.Context) error {
+	routes, err := s.discover(ctx)
+	if err != nil {
+		return err
+	}
+	pools := map[string]*pool{}
+	hosts := map[string]*pool{}
+	ports := map[int]*pool{}
+
+	s.mu.Lock()
+	for _, r := range routes {
+		p := s.pools[r.Service]
+		if p == nil {
+			p = &pool{}
+		}
+		p.update(r)
+		pools[r.Service] = p
+		switch {
+		case r.Domain != "":
+			hosts[strings.ToLower(r.Domain)] = p
+		case r.Port > 0:
+			ports[r.Port] = p
+		}
+	}
+	s.pools, s.hosts = pools, hosts
+	s.mu.Unlock()
+
+	s.syncTCP(ports)
+	return nil
+}
+
+// checkAll dials every backend, putting reachable ones back in rotation and
+// taking the rest out.
+func (s *Server) checkAll(ctx context.Context) {
+	s.mu.RLock()
+	pools := make([]*pool, 0, len(s.pools))
+	for _, p := range s.pools {
+		pools = append(pools, p)
+	}
+	s.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, p := range pools {
+		for _, b := range p.all() {
+			wg.Add(1)
+			go func(p *pool, b *backend) {
+				defer wg.Done()
+				d := net.Dialer{Timeout: s.opts.HealthTimeout}
+				conn, err := d.DialContext(ctx, "tcp", b.Addr)
+				if err == nil {
+					conn.Close()
+				}
+				if ctx.Err() == nil {
+					s.setHealth(p, b, err)
+				}
+			}(p, b)
+		}
+	}
+	wg.Wait()
+}
+
+// setHealth records the outcome of a check of, or a request to, b. A nil err
+// puts b in rotation; otherwise it is taken out until a health check passes.
+func (s *Server) setHealth(p *pool, b *backend, err error) {
+	if !p.setUp(b, err == nil) {
+		return
+	}
+	ev := Event{Time: time.Now(), Service: p.service(), Backend: b.Backend, Up: err == nil, Err: err}
+	if ev.Up {
+		s.log.Info("proxy.backend_up", "service", ev.Service, "backend", b.Addr)
+	} else {
+		s.log.Warn("proxy.backend_down", "service", ev.Service, "backend", b.Addr, "err", err)
+	}
+	select {
+	case s.events <- ev:
+	default:
+	}
+}
+
+// lookup returns the pool serving host, trying a wildcard route for its
+// parent domain when there is no exact one.
+func (s *Server) lookup(host string) *pool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if p := s.hosts[host]; p != nil {
+		return p
+	}
+	if i := strings.IndexByte(host, '.'); i > 0 {
+		return s.hosts["*"+host[i:]]
+	}
+	return nil
+}
+
+// pool is the set of backends of one route, picked from round-robin.
+type pool struct {
+	mu       sync.Mutex
+	route    Route // Backends unused; see backends
+	backends []*backend
+	next     int
+}
+
+// backend is a Backend with its place in rotation.
+type backend struct {
+	Backend
+	up bool
+}
+
+// update takes on r, keeping the health of backends already known.
+func (p *pool) update(r Route) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	known := make(map[string]*backend, len(p.backends))
+	for _, b := range p.backends {
+		known[b.Addr] = b
+	}
+	backends := make([]*backend, 0, len(r.Backends))
+	for _, rb := range r.Backends {
+		b := known[rb.Addr]
+		if b == nil {
+			b = &backend{Backend: rb, up: true} // until a check says otherwise
+		}
+		backends = append(backends, b)
+	}
+	r.Backends = nil
+	p.route, p.backends = r, backends
+	if p.next >= len(backends) {
+		p.next = 0
+	}
+}
+
+// pick returns the next backend in rotation, or nil when none is up.
+func (p *pool) pick() *backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := len(p.backends)
+	for i := 0; i < n; i++ {
+		b := p.backends[(p.next+i)%n]
+		if b.up {
+			p.next = (p.next + i + 1) % n
+			return b
+		}
+	}
+	return nil
+}
+
+// setUp updates b's place in rotation, reporting whether it changed.
+func (p *pool) setUp(b *backend, up bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if b.up == up {
+		return false
+	}
+	b.up = up
+	return true
+}
+
+func (p *pool) all() []*backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*backend(nil), p.backends...)
+}
+
+func (p *pool) service() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.route.Service
+}
+
+func (p *pool) ssl() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.route.SSL
+}
+
+// snapshot is the pool's route with the backends in rotation.
+func (p *pool) snapshot() Route {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.route
+	for _, b := range p.backends {
+		if b.up {
+			r.Backends = append(r.Backends, b.Backend)
+		}
+	}
+	return r
+}
+
+// portOf returns the port of a listen address, "" when it has none.
+func portOf(addr string) string {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return ""
+	}
+	return port
+}
@@ -0,0 +1,344 @@
+package lb
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/ssl"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+func testServer(t *testing.T, opts Options, routes ...Route) *Server {
+	t.Helper()
+	log, _ := logger.Init("error", "text", "", "", false)
+	s := New(opts, func(context.Context) ([]Route, error) { return routes, nil }, log)
+	if err := s.refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.closeTCP)
+	return s
+}
+
+// replica starts an HTTP backend that answers with its name.
+func replica(t *testing.T, name string) (*httptest.Server, Backend) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, name+" "+r.Host)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, Backend{Name: name, Addr: srv.Listener.Addr().String()}
+}
+
+func get(t *testing.T, h http.Handler, host string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://"+host+"/path?q=1", nil)
+	h.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestHTTPRoundRobinAndEjection(t *testing.T) {
+	_, a := replica(t, "a")
+	bSrv, b := replica(t, "b")
+	s := testServer(t, Options{}, Route{Service: "web", Domain: "App.example.com", Backends: []Backend{a, b}})
+	h := s.handler(false)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		code, body := get(t, h, "app.example.com:80")
+		if code != http.StatusOK {
+			t.Fatalf("status %d: %s", code, body)
+		}
+		got = append(got, body)
+	}
+	want := []string{"a app.example.com:80", "b app.example.com:80", "a app.example.com:80", "b app.example.com:80"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("responses = %q", got)
+	}
+
+	// A replica that stops answering is taken out on its first failed request.
+	bSrv.Close()
+	codes := map[int]int{}
+	for i := 0; i < 4; i++ {
+		code, _ := get(t, h, "app.example.com")
+		codes[code]++
+	}
+	if codes[http.StatusOK] != 3 || codes[http.StatusBadGateway] != 1 {
+		t.Errorf("status counts after replica b died = %v", codes)
+	}
+	ev := <-s.Events()
+	if ev.Up || ev.Service != "web" || ev.Backend.Name != "b" {
+		t.Errorf("event = %+v", ev)
+	}
+	if r := s.Routes(); len(r) != 1 || len(r[0].Backends) != 1 || r[0].Backends[0].Name != "a" {
+		t.Errorf("routes = %+v", r)
+	}
+
+	if code, _ := get(t, h, "other.example.com"); code != http.StatusNotFound {
+		t.Errorf("unknown host: status %d", code)
+	}
+}
+
+func TestHealthCheckRestoresBackend(t *testing.T) {
+	_, a := replica(t, "a")
+	s := testServer(t, Options{}, Route{Service: "web", Domain: "app.example.com", Backends: []Backend{a}})
+	p := s.lookup("app.example.com")
+	s.setHealth(p, p.all()[0], errors.New("connection refused"))
+	if code, _ := get(t, s.handler(false), "app.example.com"); code != http.StatusServiceUnavailable {
+		t.Errorf("no healthy backend: status %d", code)
+	}
+	s.checkAll(context.Background())
+	if code, _ := get(t, s.handler(false), "app.example.com"); code != http.StatusOK {
+		t.Errorf("after health check: status %d", code)
+	}
+}
+
+func TestRefreshKeepsHealth(t *testing.T) {
+	a := Backend{Name: "a", Addr: "127.0.0.1:1"}
+	b := Backend{Name: "b", Addr: "127.0.0.1:2"}
+	routes := []Route{{Service: "web", Domain: "app.example.com", Backends: []Backend{a, b}}}
+	log, _ := logger.Init("error", "text", "", "", false)
+	s := New(Options{}, func(context.Context) ([]Route, error) { return routes, nil }, log)
+	s.refresh(context.Background())
+	p := s.lookup("app.example.com")
+	s.setHealth(p, p.all()[0], errors.New("down"))
+
+	routes[0].Backends = []Backend{a, b, {Name: "c", Addr: "127.0.0.1:3"}}
+	s.refresh(context.Background())
+	if got := s.Routes()[0].Backends; !reflect.DeepEqual(got, []Backend{b, routes[0].Backends[2]}) {
+		t.Errorf("backends in rotation = %+v", got)
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	_, a := replica(t, "a")
+	s := testServer(t, Options{HTTPSAddr: ":8443"}, Route{Service: "web", Domain: "app.example.com", SSL: true, Backends: []Backend{a}})
+	rec := httptest.NewRecorder()
+	s.handler(false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://app.example.com/a/b?x=1", nil))
+	if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != "https://app.example.com:8443/a/b?x=1" {
+		t.Errorf("redirect = %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	if code, _ := get(t, s.handler(true), "app.example.com"); code != http.StatusOK {
+		t.Errorf("over TLS: status %d", code)
+	}
+}
+
+func TestCertStore(t *testing.T) {
+	certDir := t.TempDir()
+	ca, err := ssl.LoadOrCreateCA(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ca.Issue([]string{"*.example.com"}, certDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ca.Issue([]string{"app.example.com"}, certDir); err != nil {
+		t.Fatal(err)
+	}
+	store := newCertStore(certDir)
+	for name, want := range map[string]string{"app.example.com": "app.example.com", "api.example.com": "*.example.com"} {
+		cert, err := store.get(&tls.ClientHelloInfo{ServerName: name})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil || leaf.DNSNames[0] != want {
+			t.Errorf("%s: served certificate for %v (%v)", name, leaf.DNSNames, err)
+		}
+	}
+	if _, err := store.get(&tls.ClientHelloInfo{ServerName: "example.org"}); err == nil {
+		t.Error("served a certificate for an unknown name")
+	}
+	for _, name := range []string{"x/../app.example.com", "../" + filepath.Base(certDir) + "/app.example.com", `x\app.example.com`} {
+		if _, err := store.get(&tls.ClientHelloInfo{ServerName: name}); err == nil {
+			t.Errorf("served a certificate for %q", name)
+		}
+	}
+}
+
+func TestTCPRoute(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	go func() {
+		for {
+			c, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			go func() { io.Copy(c, c); c.Close() }()
+		}
+	}()
+
+	// The first backend refuses connections; the proxy moves on to the next.
+	dead, _ := net.Listen("tcp", "127.0.0.1:0")
+	deadAddr := dead.Addr().String()
+	dead.Close()
+
+	free, _ := net.Listen("tcp", "127.0.0.1:0")
+	port := free.Addr().(*net.TCPAddr).Port
+	free.Close()
+
+	s := testServer(t, Options{}, Route{Service: "db", Port: port, Backends: []Backend{
+		{Name: "dead", Addr: deadAddr},
+		{Name: "echo", Addr: backend.Addr().String()},
+	}})
+	if s.tcp[port] == nil {
+		t.Fatalf("no listener on %d", port)
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "ping")
+	conn.(*net.TCPConn).CloseWrite()
+	got, _ := io.ReadAll(conn)
+	if string(got) != "ping" {
+		t.Errorf("echo = %q", got)
+	}
+	if r := s.Routes(); len(r[0].Backends) != 1 || r[0].Backends[0].Name != "echo" {
+		t.Errorf("backends in rotation = %+v", r[0].Backends)
+	}
+}
+
+func TestStateDiscovery(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.PutNode(v1.NodeInfo{Spec: v1.NodeSpec{Name: "prod-01", Host: "10.0.0.5"}})
+	db.PutNode(v1.NodeInfo{Spec: v1.NodeSpec{Name: "prod-02", Host: "10.0.0.6"}})
+	db.PutNode(v1.NodeInfo{Spec: v1.NodeSpec{Name: "prod-03", Host: "10.0.0.7"}})
+	db.PutServiceState(v1.ServiceState{Name: "web", Node: "local", Status: v1.StatusHealthy, Ready: true})
+	db.PutServiceState(v1.ServiceState{Name: "web", Node: "prod-01", Status: v1.StatusHealthy, Ready: true})
+	db.PutServiceState(v1.ServiceState{Name: "web", Node: "prod-02", Status: v1.StatusHealthy, Ready: true,
+		Ports: []string{"32768:3000", "32769:3000", "9100:9100"}}) // replicas on ephemeral ports
+	db.PutServiceState(v1.ServiceState{Name: "web", Node: "prod-03", Status: v1.StatusUnknown}) // readiness not yet passing
+	db.PutServiceState(v1.ServiceState{Name: "db", Node: "local", Ready: true, CrashLoop: true})
+
+	containers := fakeContainers{"web": {
+		{ID: "c1", Names: []string{"/web"}, Ports: []types.Port{{IP: "0.0.0.0", PrivatePort: 3000, PublicPort: 8081, Type: "tcp"}}},
+		{ID: "c2", Names: []string{"/web-2"}, NetworkSettings: &types.SummaryNetworkSettings{
+			Networks: map[string]*network.EndpointSettings{"bridge": {IPAddress: "172.17.0.3"}},
+		}},
+	}}
+	d := &StateDiscovery{
+		Services: []v1.ServiceSpec{
+			{Name: "web", Ports: []string{"8081:3000"}, Proxy: &v1.ProxySpec{Domain: "app.example.com", Backend: 3000}},
+			{Name: "db", Proxy: &v1.ProxySpec{Port: 5432, Backend: 5432}},
+			{Name: "worker"},
+		},
+		State:      db,
+		Containers: containers,
+		LocalNode:  "local",
+	}
+	routes, err := d.Routes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Route{
+		{Service: "web", Domain: "app.example.com", Backends: []Backend{
+			{Node: "local", Name: "web", Addr: "127.0.0.1:8081"},
+			{Node: "local", Name: "web-2", Addr: "172.17.0.3:3000"},
+			{Node: "prod-01", Name: "web", Addr: "10.0.0.5:8081"},
+			{Node: "prod-02", Name: "web", Addr: "10.0.0.6:32768"},
+			{Node: "prod-02", Name: "web", Addr: "10.0.0.6:32769"},
+		}},
+		{Service: "db", Port: 5432},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("routes =\n%+v\nwant\n%+v", routes, want)
+	}
+}
+
+// upRuntime starts every container as web's, publishing 3000 on 8081.
+type upRuntime struct {
+	orchestrator.Runtime
+}
+
+func (upRuntime) RunContainer(_ context.Context, _ v1.ServiceSpec, name string) (string, error) {
+	return name + "-0123456789ab", nil
+}
+
+func (upRuntime) ListContainers(context.Context, string) ([]types.Container, error) {
+	return []types.Container{{ID: "web-0123456789ab", Names: []string{"/web"},
+		Ports: []types.Port{{PrivatePort: 3000, PublicPort: 8081, Type: "tcp"}}}}, nil
+}
+
+type healthyInspector struct{}
+
+func (healthyInspector) ContainerHealth(context.Context, string) (string, string, error) {
+	return "healthy", "", nil
+}
+
+// A service with a health_check brought up by orbit up, and one recorded
+// before readiness was tracked, are routed to with no agent running.
+func TestStateDiscoveryAfterUp(t *testing.T) {
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	log, _ := logger.Init("error", "text", "", "", false)
+
+	spec := v1.ServiceSpec{Name: "web", Image: "web:1", Ports: []string{"8081:3000"},
+		HealthCheck: &v1.HealthCheckSpec{Type: "docker", Interval: time.Millisecond, Timeout: time.Second, Retries: 1},
+		Proxy:       &v1.ProxySpec{Domain: "app.example.com", Backend: 3000}}
+	checker := health.NewChecker(log).WithInspector(healthyInspector{})
+	if err := orchestrator.NewLifecycleManager(upRuntime{}, db, log).WithChecker(checker).
+		Up(context.Background(), []v1.ServiceSpec{spec}, "local", false); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	var legacy v1.ServiceState
+	if err := json.Unmarshal([]byte(`{"name":"web","node":"prod-01","status":"healthy"}`), &legacy); err != nil {
+		t.Fatal(err)
+	}
+	db.PutServiceState(legacy)
+	db.PutNode(v1.NodeInfo{Spec: v1.NodeSpec{Name: "prod-01", Host: "10.0.0.5"}})
+
+	d := &StateDiscovery{Services: []v1.ServiceSpec{spec}, State: db, Containers: upRuntime{}, LocalNode: "local"}
+	routes, err := d.Routes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Route{{Service: "web", Domain: "app.example.com", Backends: []Backend{
+		{Node: "local", Name: "web", Addr: "127.0.0.1:8081"},
+		{Node: "prod-01", Name: "web", Addr: "10.0.0.5:8081"},
+	}}}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("routes =\n%+v\nwant\n%+v", routes, want)
+	}
+}
+
+type fakeContainers map[string][]types.Container
+
+func (f fakeContainers) ListContainers(_ context.Context, service string) ([]types.Container, error) {
+	return f[service], nil
+}
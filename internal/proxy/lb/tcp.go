@@ -0,0 +1,101 @@
+// Package lb: plain TCP routes.
+package lb
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// tcpListener accepts connections on one TCP route's port.
+type tcpListener struct {
+	ln   net.Listener
+	pool atomic.Pointer[pool]
+}
+
+// syncTCP opens listeners for new TCP routes, points existing ones at the
+// current pools and closes those whose route is gone. A port that cannot be
+// bound is logged and retried at the next refresh.
+func (s *Server) syncTCP(ports map[int]*pool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for port, l := range s.tcp {
+		if _, ok := ports[port]; !ok {
+			l.ln.Close()
+			delete(s.tcp, port)
+		}
+	}
+	for port, p := range ports {
+		if l := s.tcp[port]; l != nil {
+			l.pool.Store(p)
+			continue
+		}
+		ln, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+		if err != nil {
+			s.log.Warn("proxy: tcp listen failed", "service", p.service(), "port", port, "err", err)
+			continue
+		}
+		l := &tcpListener{ln: ln}
+		l.pool.Store(p)
+		s.tcp[port] = l
+		go s.serveTCP(l)
+		s.log.Info("proxy.listen", "addr", ln.Addr().String(), "service", p.service())
+	}
+}
+
+// closeTCP closes every TCP listener.
+func (s *Server) closeTCP() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for port, l := range s.tcp {
+		l.ln.Close()
+		delete(s.tcp, port)
+	}
+}
+
+func (s *Server) serveTCP(l *tcpListener) {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			return // listener closed
+		}
+		go s.proxyTCP(l.pool.Load(), conn)
+	}
+}
+
+// proxyTCP pipes in to a backend, moving on to the next one when a backend
+// cannot be reached.
+func (s *Server) proxyTCP(p *pool, in net.Conn) {
+	defer in.Close()
+	var out net.Conn
+	for range p.all() {
+		b := p.pick()
+		if b == nil {
+			return
+		}
+		var err error
+		if out, err = net.DialTimeout("tcp", b.Addr, s.opts.HealthTimeout); err == nil {
+			break
+		}
+		s.setHealth(p, b, err)
+	}
+	if out == nil {
+		return
+	}
+	defer out.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	pipe := func(dst, src net.Conn) {
+		defer wg.Done()
+		_, _ = io.Copy(dst, src)
+		if c, ok := dst.(*net.TCPConn); ok {
+			_ = c.CloseWrite()
+		}
+	}
+	go pipe(out, in)
+	go pipe(in, out)
+	wg.Wait()
+}
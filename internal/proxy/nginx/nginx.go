@@ -11,6 +11,7 @@ import (
 
 	v1 "github.com/f9-o/orbit/api/v1"
 	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/ssl"
 )
 
 // domainSafe validates a domain is safe to embed in an NGINX config.
@@ -112,8 +113,7 @@ func (g *Generator) writeOne(tmpl *template.Template, svc v1.ServiceSpec, certDi
 	}
 
 	if px.SSL {
-		data.CertPath = filepath.Join(certDir, px.Domain+".crt")
-		data.KeyPath = filepath.Join(certDir, px.Domain+".key")
+		data.CertPath, data.KeyPath = ssl.CertPaths(certDir, px.Domain)
 	}
 
 	outPath := filepath.Join(g.configDir, "orbit_"+svc.Name+".conf")
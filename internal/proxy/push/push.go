@@ -0,0 +1,386 @@
+// Package push distributes generated proxy configs and certificates to remote
+// nodes over SFTP, validates them there and reloads the proxy without
+// dropping connections. A config that fails validation is rolled back.
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/proxy/caddy"
+	"github.com/f9-o/orbit/internal/proxy/nginx"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/internal/ssl"
+	"github.com/f9-o/orbit/pkg/sshutil"
+)
+
+// DefaultConfigDir is where generated configs go on a node when
+// proxy.config_path is unset. The node's proxy must include it, e.g. with
+// `include /home/deploy/.orbit/proxy/*.conf;` in nginx.conf.
+const DefaultConfigDir = "~/.orbit/proxy"
+
+// Backend is a proxy that configs can be pushed to.
+type Backend struct {
+	Name     string
+	Validate string // checks the proxy's whole config; non-zero exit rejects it
+	Reload   string // applies the config without dropping connections
+	generate func(dir, certDir string, services []v1.ServiceSpec, log *logger.Logger) error
+}
+
+// Backends are the proxies push supports, by proxy.backend name.
+var Backends = map[string]Backend{
+	"nginx": {
+		Name:     "nginx",
+		Validate: "nginx -t",
+		Reload:   "nginx -s reload",
+		generate: func(dir, certDir string, services []v1.ServiceSpec, log *logger.Logger) error {
+			return nginx.NewGenerator(dir, log).GenerateAll(services, certDir)
+		},
+	},
+	"caddy": {
+		Name:     "caddy",
+		Validate: "caddy validate --config /etc/caddy/Caddyfile --adapter caddyfile",
+		Reload:   "caddy reload --config /etc/caddy/Caddyfile --adapter caddyfile",
+		generate: func(dir, certDir string, services []v1.ServiceSpec, log *logger.Logger) error {
+			return caddy.NewGenerator(dir, log).GenerateAll(services, certDir)
+		},
+	},
+}
+
+// Remote is the part of remote.Pool a Pusher uses.
+type Remote interface {
+	Run(ctx context.Context, node v1.NodeInfo, cmd string) (string, int, error)
+	SyncDir(ctx context.Context, node v1.NodeInfo, localDir, remoteDir string, opts remote.SyncOptions) (*remote.SyncResult, error)
+}
+
+// Options configure a Pusher. Remote paths may start with ~/ for the SSH
+// user's home directory.
+type Options struct {
+	Backend      string // nginx | caddy
+	ConfigDir    string // remote directory owned by Orbit; "" is DefaultConfigDir
+	CertDir      string // remote certificate directory
+	LocalCertDir string // where certificates are issued locally
+
+	ValidateCommand string // overrides the backend's, e.g. to add sudo
+	ReloadCommand   string // overrides the backend's
+	Force           bool   // validate and reload even when nothing changed
+
+	// Serving reports whether a service on a node may take traffic; a node's
+	// config leaves out those that may not. nil serves every service.
+	Serving func(node, service string) bool
+}
+
+// Result is the outcome of pushing to one node.
+type Result struct {
+	Node       string   `json:"node"`
+	Uploaded   []string `json:"uploaded"` // conf/<file> and certs/<domain>/<file>
+	Deleted    []string `json:"deleted"`
+	Reloaded   bool     `json:"reloaded"`
+	RolledBack bool     `json:"rolled_back"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Changed reports whether the push changed any file on the node.
+func (r Result) Changed() bool {
+	return len(r.Uploaded) > 0 || len(r.Deleted) > 0
+}
+
+// FileDiff is a config file whose content on a node differs from the one
+// Push would upload.
+type FileDiff struct {
+	Path string `json:"path"` // relative to the config dir
+	Old  string `json:"old"`  // on the node; "" when missing there
+	New  string `json:"new"`  // generated; "" when Push would delete it
+}
+
+// Pusher pushes the proxy config of a set of services.
+type Pusher struct {
+	remote   Remote
+	services []v1.ServiceSpec
+	backend  Backend
+	opts     Options
+	log      *logger.Logger
+}
+
+// New returns a Pusher for the proxied services among services.
+func New(r Remote, services []v1.ServiceSpec, opts Options, log *logger.Logger) (*Pusher, error) {
+	b, ok := Backends[opts.Backend]
+	if !ok {
+		return nil, fmt.Errorf("proxy backend %q has no config files to push (want nginx or caddy)", opts.Backend)
+	}
+	if opts.ValidateCommand != "" {
+		b.Validate = opts.ValidateCommand
+	}
+	if opts.ReloadCommand != "" {
+		b.Reload = opts.ReloadCommand
+	}
+	if opts.ConfigDir == "" {
+		opts.ConfigDir = DefaultConfigDir
+	}
+	var proxied []v1.ServiceSpec
+	for _, s := range services {
+		if s.Proxy != nil {
+			proxied = append(proxied, s)
+		}
+	}
+	return &Pusher{remote: r, services: proxied, backend: b, opts: opts, log: log}, nil
+}
+
+// Push brings node up to date:
+//
+//  1. configs are generated locally, pointing at the node's cert dir, and the
+//     certificates of SSL services are staged;
+//  2. the node's config dir is copied aside;
+//  3. configs and certificates are uploaded, replacing only changed files;
+//  4. the proxy's config is validated, and on failure the previous config
+//     dir is restored;
+//  5. the proxy is reloaded gracefully.
+//
+// Nothing is validated or reloaded when no file changed, unless Force is set.
+func (p *Pusher) Push(ctx context.Context, node v1.NodeInfo) Result {
+	res := Result{Node: node.Spec.Name}
+	if err := p.push(ctx, node, &res); err != nil {
+		res.Error = err.Error()
+		p.log.Warn("proxy.push.failed", "node", node.Spec.Name, "err", err)
+	}
+	return res
+}
+
+func (p *Pusher) push(ctx context.Context, node v1.NodeInfo, res *Result) error {
+	home, err := p.run(ctx, node, `printf '%s' "$HOME"`)
+	if err != nil {
+		return err
+	}
+	configDir := remotePath(home, p.opts.ConfigDir)
+	certDir := remotePath(home, p.opts.CertDir)
+
+	stage, err := os.MkdirTemp("", "orbit-proxy-push-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stage)
+	confStage, certStage := filepath.Join(stage, "conf"), filepath.Join(stage, "certs")
+	if err := p.backend.generate(confStage, certDir, p.servicesOn(node), p.log); err != nil {
+		return err
+	}
+	if err := p.stageCerts(certStage); err != nil {
+		return err
+	}
+
+	backup := configDir + ".prev"
+	if _, err := p.run(ctx, node, "rm -rf "+sshutil.Quote(backup)+" && if [ -d "+sshutil.Quote(configDir)+" ]; then cp -a "+
+		sshutil.Quote(configDir)+" "+sshutil.Quote(backup)+"; fi"); err != nil {
+		return fmt.Errorf("back up %s: %w", configDir, err)
+	}
+
+	conf, err := p.remote.SyncDir(ctx, node, confStage, configDir, remote.SyncOptions{Delete: true})
+	if conf != nil {
+		res.Uploaded = prefixed("conf/", conf.Uploaded)
+		res.Deleted = prefixed("conf/", conf.Deleted)
+	}
+	if err != nil {
+		return p.rollback(ctx, node, configDir, backup, res, fmt.Errorf("upload configs: %w", err))
+	}
+	if _, err := os.Stat(certStage); err == nil {
+		certs, err := p.remote.SyncDir(ctx, node, certStage, certDir, remote.SyncOptions{})
+		if certs != nil {
+			res.Uploaded = append(res.Uploaded, prefixed("certs/", certs.Uploaded)...)
+		}
+		if err != nil {
+			return p.rollback(ctx, node, configDir, backup, res, fmt.Errorf("upload certificates: %w", err))
+		}
+	}
+	if !res.Changed() && !p.opts.Force {
+		p.log.Info("proxy.push.unchanged", "node", node.Spec.Name)
+		return nil
+	}
+
+	if out, code, err := p.remote.Run(ctx, node, p.backend.Validate); err != nil || code != 0 {
+		if err == nil {
+			err = fmt.Errorf("%s exited %d: %s", p.backend.Validate, code, lastLines(out, 5))
+		}
+		return p.rollback(ctx, node, configDir, backup, res, fmt.Errorf("validation failed: %w", err))
+	}
+	if out, code, err := p.remote.Run(ctx, node, p.backend.Reload); err != nil || code != 0 {
+		if err == nil {
+			err = fmt.Errorf("%s exited %d: %s", p.backend.Reload, code, lastLines(out, 5))
+		}
+		return fmt.Errorf("reload: %w", err)
+	}
+	res.Reloaded = true
+	p.log.Info("proxy.push.reloaded", "node", node.Spec.Name, "backend", p.backend.Name,
+		"uploaded", len(res.Uploaded), "deleted", len(res.Deleted))
+	return nil
+}
+
+// Diff returns the config files Push would change on node, by path, without
+// changing anything. Certificates are not compared.
+func (p *Pusher) Diff(ctx context.Context, node v1.NodeInfo) ([]FileDiff, error) {
+	home, err := p.run(ctx, node, `printf '%s' "$HOME"`)
+	if err != nil {
+		return nil, err
+	}
+	configDir := remotePath(home, p.opts.ConfigDir)
+	stage, err := os.MkdirTemp("", "orbit-proxy-diff-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stage)
+	if err := p.backend.generate(stage, remotePath(home, p.opts.CertDir), p.servicesOn(node), p.log); err != nil {
+		return nil, err
+	}
+
+	files := map[string]bool{}
+	entries, err := os.ReadDir(stage)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		files[e.Name()] = true
+	}
+	listing, err := p.run(ctx, node, "ls -1A "+sshutil.Quote(configDir)+" 2>/dev/null || true")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Fields(listing) {
+		files[name] = true
+	}
+
+	var diffs []FileDiff
+	for name := range files {
+		d := FileDiff{Path: name}
+		if data, err := os.ReadFile(filepath.Join(stage, name)); err == nil {
+			d.New = string(data)
+		}
+		if d.Old, err = p.run(ctx, node, "cat "+sshutil.Quote(configDir+"/"+name)+" 2>/dev/null || true"); err != nil {
+			return nil, err
+		}
+		if d.Old != d.New {
+			diffs = append(diffs, d)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs, nil
+}
+
+// servicesOn returns the proxied services node's config routes to.
+func (p *Pusher) servicesOn(node v1.NodeInfo) []v1.ServiceSpec {
+	if p.opts.Serving == nil {
+		return p.services
+	}
+	var out []v1.ServiceSpec
+	for _, s := range p.services {
+		if p.opts.Serving(node.Spec.Name, s.Name) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// rollback restores the config dir saved before the upload and returns cause.
+// Certificates are left in place: they are only ever added or renewed.
+func (p *Pusher) rollback(ctx context.Context, node v1.NodeInfo, configDir, backup string, res *Result, cause error) error {
+	cmd := "rm -rf " + sshutil.Quote(configDir) + " && if [ -d " + sshutil.Quote(backup) + " ]; then mv " +
+		sshutil.Quote(backup) + " " + sshutil.Quote(configDir) + "; fi"
+	if _, err := p.run(ctx, node, cmd); err != nil {
+		return fmt.Errorf("%w; restoring the previous config also failed: %v", cause, err)
+	}
+	res.RolledBack = true
+	p.log.Warn("proxy.push.rolled_back", "node", node.Spec.Name, "err", cause)
+	return cause
+}
+
+// stageCerts copies the certificates of SSL services into dir, laid out as
+// the cert store is. Every SSL service must have one.
+func (p *Pusher) stageCerts(dir string) error {
+	var missing []string
+	for _, s := range p.services {
+		if !s.Proxy.SSL {
+			continue
+		}
+		certFile, keyFile := ssl.CertPaths(p.opts.LocalCertDir, s.Proxy.Domain)
+		stageCert, stageKey := ssl.CertPaths(dir, s.Proxy.Domain)
+		if err := copyFile(certFile, stageCert); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				missing = append(missing, s.Proxy.Domain)
+				continue
+			}
+			return err
+		}
+		if err := copyFile(keyFile, stageKey); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("no certificate in %s for %s; issue one with `orbit ssl issue` first",
+			p.opts.LocalCertDir, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// run runs cmd on node, treating a non-zero exit as an error.
+func (p *Pusher) run(ctx context.Context, node v1.NodeInfo, cmd string) (string, error) {
+	out, code, err := p.remote.Run(ctx, node, cmd)
+	if err == nil && code != 0 {
+		err = fmt.Errorf("exited %d: %s", code, lastLines(out, 5))
+	}
+	return out, err
+}
+
+// remotePath resolves a leading ~/ against the node's home directory.
+func remotePath(home, p string) string {
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		return strings.TrimSuffix(home, "/") + "/" + rest
+	}
+	return p
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func prefixed(prefix string, paths []string) []string {
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		out[i] = prefix + p
+	}
+	return out
+}
+
+// lastLines returns the last n non-empty lines of out, joined with "; ".
+func lastLines(out string, n int) string {
+	lines := strings.FieldsFunc(strings.TrimSpace(out), func(r rune) bool { return r == '\n' })
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "; ")
+}
@@ -0,0 +1,208 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/internal/ssl"
+)
+
+// localNode stands in for a remote node: commands run under sh with HOME set
+// to a temporary directory, and SyncDir copies files into it.
+type localNode struct {
+	home string
+	cmds []string
+}
+
+func (n *localNode) Run(_ context.Context, _ v1.NodeInfo, cmd string) (string, int, error) {
+	n.cmds = append(n.cmds, cmd)
+	c := exec.Command("sh", "-c", cmd)
+	c.Env = append(os.Environ(), "HOME="+n.home)
+	out, err := c.CombinedOutput()
+	if exit, ok := err.(*exec.ExitError); ok {
+		return string(out), exit.ExitCode(), nil
+	}
+	return string(out), 0, err
+}
+
+func (n *localNode) SyncDir(_ context.Context, _ v1.NodeInfo, localDir, remoteDir string, opts remote.SyncOptions) (*remote.SyncResult, error) {
+	res := &remote.SyncResult{}
+	seen := map[string]bool{}
+	err := filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(localDir, p)
+		seen[rel] = true
+		data, _ := os.ReadFile(p)
+		dst := filepath.Join(remoteDir, rel)
+		if old, err := os.ReadFile(dst); err == nil && bytes.Equal(old, data) {
+			res.Skipped = append(res.Skipped, rel)
+			return nil
+		}
+		os.MkdirAll(filepath.Dir(dst), 0755)
+		res.Uploaded = append(res.Uploaded, filepath.ToSlash(rel))
+		return os.WriteFile(dst, data, 0644)
+	})
+	if err != nil || !opts.Delete {
+		return res, err
+	}
+	err = filepath.WalkDir(remoteDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if rel, _ := filepath.Rel(remoteDir, p); !seen[rel] {
+			res.Deleted = append(res.Deleted, filepath.ToSlash(rel))
+			return os.Remove(p)
+		}
+		return nil
+	})
+	return res, err
+}
+
+func newPusher(t *testing.T, node *localNode, services []v1.ServiceSpec, certDir string) *Pusher {
+	t.Helper()
+	log, _ := logger.Init("error", "text", "", "", false)
+	p, err := New(node, services, Options{
+		Backend:         "nginx",
+		CertDir:         "~/.orbit/certs",
+		LocalCertDir:    certDir,
+		ValidateCommand: `! grep -rq broken "$HOME/.orbit/proxy"`,
+		ReloadCommand:   `touch "$HOME/reloaded"`,
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPush(t *testing.T) {
+	certDir := t.TempDir()
+	ca, err := ssl.LoadOrCreateCA(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ca.Issue([]string{"app.example.com"}, certDir); err != nil {
+		t.Fatal(err)
+	}
+	node := &localNode{home: t.TempDir()}
+	services := []v1.ServiceSpec{
+		{Name: "app", Proxy: &v1.ProxySpec{Domain: "app.example.com", SSL: true, Backend: 3000}},
+		{Name: "api", Proxy: &v1.ProxySpec{Domain: "api.example.com"}},
+		{Name: "worker"},
+	}
+	reloaded := filepath.Join(node.home, "reloaded")
+
+	res := newPusher(t, node, services, certDir).Push(context.Background(), v1.NodeInfo{Spec: v1.NodeSpec{Name: "n1"}})
+	want := []string{"conf/orbit_api.conf", "conf/orbit_app.conf", "certs/app.example.com/fullchain.pem", "certs/app.example.com/privkey.pem"}
+	if res.Error != "" || !res.Reloaded || !reflect.DeepEqual(res.Uploaded, want) {
+		t.Fatalf("first push = %+v", res)
+	}
+	conf, _ := os.ReadFile(filepath.Join(node.home, ".orbit/proxy/orbit_app.conf"))
+	if !strings.Contains(string(conf), filepath.Join(node.home, ".orbit/certs/app.example.com/fullchain.pem")) {
+		t.Errorf("config does not point at the node's cert dir:\n%s", conf)
+	}
+
+	// Nothing changed: no validation, no reload.
+	os.Remove(reloaded)
+	res = newPusher(t, node, services, certDir).Push(context.Background(), v1.NodeInfo{Spec: v1.NodeSpec{Name: "n1"}})
+	if res.Error != "" || res.Reloaded || res.Changed() {
+		t.Errorf("second push = %+v", res)
+	}
+
+	// Dropping a service removes its config.
+	res = newPusher(t, node, services[:1], certDir).Push(context.Background(), v1.NodeInfo{Spec: v1.NodeSpec{Name: "n1"}})
+	if !reflect.DeepEqual(res.Deleted, []string{"conf/orbit_api.conf"}) || !res.Reloaded {
+		t.Errorf("push without api = %+v", res)
+	}
+
+	// A service not serving on the node is left out of its config.
+	p := newPusher(t, node, services, certDir)
+	p.opts.Serving = func(node, service string) bool { return node != "n1" || service != "app" }
+	res = p.Push(context.Background(), v1.NodeInfo{Spec: v1.NodeSpec{Name: "n1"}})
+	if !reflect.DeepEqual(res.Uploaded, []string{"conf/orbit_api.conf"}) || !reflect.DeepEqual(res.Deleted, []string{"conf/orbit_app.conf"}) {
+		t.Errorf("push while app is not ready = %+v", res)
+	}
+}
+
+func TestPushRollsBackInvalidConfig(t *testing.T) {
+	node := &localNode{home: t.TempDir()}
+	good := []v1.ServiceSpec{{Name: "api", Proxy: &v1.ProxySpec{Domain: "api.example.com"}}}
+	if res := newPusher(t, node, good, t.TempDir()).Push(context.Background(), v1.NodeInfo{}); res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	os.Remove(filepath.Join(node.home, "reloaded"))
+
+	bad := append(good, v1.ServiceSpec{Name: "web", Proxy: &v1.ProxySpec{Domain: "broken.example.com"}})
+	res := newPusher(t, node, bad, t.TempDir()).Push(context.Background(), v1.NodeInfo{})
+	if !res.RolledBack || res.Reloaded || !strings.Contains(res.Error, "validation failed") {
+		t.Fatalf("push = %+v", res)
+	}
+	entries, _ := os.ReadDir(filepath.Join(node.home, ".orbit/proxy"))
+	if len(entries) != 1 || entries[0].Name() != "orbit_api.conf" {
+		t.Errorf("config dir after rollback = %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(node.home, "reloaded")); err == nil {
+		t.Error("proxy reloaded after failed validation")
+	}
+}
+
+func TestPushNeedsCertificates(t *testing.T) {
+	node := &localNode{home: t.TempDir()}
+	services := []v1.ServiceSpec{{Name: "app", Proxy: &v1.ProxySpec{Domain: "app.example.com", SSL: true}}}
+	res := newPusher(t, node, services, t.TempDir()).Push(context.Background(), v1.NodeInfo{})
+	if !strings.Contains(res.Error, "no certificate") || !strings.Contains(res.Error, "app.example.com") {
+		t.Errorf("push = %+v", res)
+	}
+	if len(node.cmds) != 1 {
+		t.Errorf("ran %q before finding the certificate missing", node.cmds)
+	}
+}
+
+func TestNewRejectsTraefik(t *testing.T) {
+	log, _ := logger.Init("error", "text", "", "", false)
+	if _, err := New(&localNode{}, nil, Options{Backend: "traefik"}, log); err == nil {
+		t.Error("traefik accepted")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	node := &localNode{home: t.TempDir()}
+	services := []v1.ServiceSpec{
+		{Name: "app", Proxy: &v1.ProxySpec{Domain: "app.example.com", Backend: 3000}},
+		{Name: "api", Proxy: &v1.ProxySpec{Domain: "api.example.com"}},
+	}
+	if res := newPusher(t, node, services, t.TempDir()).Push(context.Background(), v1.NodeInfo{}); res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	p := newPusher(t, node, services, t.TempDir())
+	if diffs, err := p.Diff(context.Background(), v1.NodeInfo{}); err != nil || len(diffs) != 0 {
+		t.Fatalf("diff after push = %+v, %v", diffs, err)
+	}
+
+	services[0].Proxy.Backend = 3001
+	diffs, err := newPusher(t, node, services[:1], t.TempDir()).Diff(context.Background(), v1.NodeInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 2 || diffs[0].Path != "orbit_api.conf" || diffs[0].New != "" || diffs[0].Old == "" {
+		t.Fatalf("diffs = %+v", diffs)
+	}
+	if d := diffs[1]; d.Path != "orbit_app.conf" || !strings.Contains(d.Old, ":3000") || !strings.Contains(d.New, ":3001") {
+		t.Errorf("app diff = %+v", d)
+	}
+	conf, _ := os.ReadFile(filepath.Join(node.home, ".orbit/proxy/orbit_app.conf"))
+	if !strings.Contains(string(conf), ":3000") {
+		t.Error("Diff changed the node's config")
+	}
+}
@@ -0,0 +1,141 @@
+// Package traefik routes services through an existing Traefik instance by
+// labelling their containers for Traefik's Docker provider, instead of
+// writing proxy config files.
+package traefik
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/health"
+)
+
+// Default entry point names, as in Traefik's own examples.
+const (
+	DefaultEntryPoint    = "web"
+	DefaultTLSEntryPoint = "websecure"
+)
+
+// Options are the proxy.traefik settings from orbit.yaml.
+type Options struct {
+	Network       string // Docker network shared with Traefik; joined by every proxied container
+	EntryPoint    string // entry point for plain HTTP routers
+	TLSEntryPoint string // entry point for routers of services with proxy.ssl
+	CertResolver  string // Traefik certificate resolver for TLS routers; "" uses Traefik's default certificate
+}
+
+// Labeler adds Traefik labels to the specs of proxied services.
+type Labeler struct {
+	opts Options
+}
+
+// New returns a Labeler, filling in the default entry points.
+func New(opts Options) *Labeler {
+	if opts.EntryPoint == "" {
+		opts.EntryPoint = DefaultEntryPoint
+	}
+	if opts.TLSEntryPoint == "" {
+		opts.TLSEntryPoint = DefaultTLSEntryPoint
+	}
+	return &Labeler{opts: opts}
+}
+
+// routerUnsafe matches characters Traefik does not allow in router names.
+var routerUnsafe = regexp.MustCompile(`[^a-zA-Z0-9-]+`)
+
+// RouterName is the Traefik router and service name used for a service.
+func RouterName(service string) string {
+	return "orbit-" + strings.Trim(routerUnsafe.ReplaceAllString(service, "-"), "-")
+}
+
+// Labels returns the Traefik labels for spec, or nil when it has no proxy
+// section.
+func (l *Labeler) Labels(spec v1.ServiceSpec) map[string]string {
+	px := spec.Proxy
+	if px == nil || px.Domain == "" {
+		return nil
+	}
+	name := RouterName(spec.Name)
+	router := "traefik.http.routers." + name
+	service := "traefik.http.services." + name
+
+	labels := map[string]string{
+		"traefik.enable":        "true",
+		router + ".rule":        "Host(`" + px.Domain + "`)",
+		router + ".service":     name,
+		router + ".entrypoints": l.opts.EntryPoint,
+	}
+	if px.Backend > 0 {
+		labels[service+".loadbalancer.server.port"] = strconv.Itoa(px.Backend)
+	}
+	if px.SSL {
+		labels[router+".entrypoints"] = l.opts.TLSEntryPoint
+		labels[router+".tls"] = "true"
+		if l.opts.CertResolver != "" {
+			labels[router+".tls.certresolver"] = l.opts.CertResolver
+		}
+	}
+	if l.opts.Network != "" {
+		labels["traefik.docker.network"] = l.opts.Network
+	}
+	for k, v := range readinessLabels(spec) {
+		labels[service+".loadbalancer.healthcheck."+k] = v
+	}
+	return labels
+}
+
+// readinessLabels has Traefik probe each container with the service's HTTP
+// readiness probe and leave it out of the load balancer while it fails.
+// Traefik already skips containers whose Docker HEALTHCHECK is not healthy;
+// other probe types have no Traefik equivalent.
+func readinessLabels(spec v1.ServiceSpec) map[string]string {
+	pc := health.Resolve(spec.HealthCheck, health.ProbeReadiness)
+	if pc == nil || pc.Check.Type != "http" {
+		return nil
+	}
+	u, err := url.Parse(pc.Check.URL)
+	if err != nil {
+		return nil
+	}
+	labels := map[string]string{"path": u.RequestURI()}
+	if u.Scheme == "https" {
+		labels["scheme"] = "https"
+	}
+	if pc.Check.Method != "" {
+		labels["method"] = pc.Check.Method
+	}
+	if pc.Interval > 0 {
+		labels["interval"] = pc.Interval.String()
+	}
+	if pc.Check.Timeout > 0 {
+		labels["timeout"] = pc.Check.Timeout.String()
+	}
+	return labels
+}
+
+// Apply returns spec with its Traefik labels added and the shared network
+// joined. Labels set in orbit.yaml win over generated ones, so any router
+// setting can be overridden per service.
+func (l *Labeler) Apply(spec v1.ServiceSpec) v1.ServiceSpec {
+	generated := l.Labels(spec)
+	if generated == nil {
+		return spec
+	}
+	labels := make(map[string]string, len(generated)+len(spec.Labels))
+	for k, v := range generated {
+		labels[k] = v
+	}
+	for k, v := range spec.Labels {
+		labels[k] = v
+	}
+	spec.Labels = labels
+
+	if n := l.opts.Network; n != "" && !slices.Contains(spec.Networks, n) {
+		spec.Networks = append(append([]string{}, spec.Networks...), n)
+	}
+	return spec
+}
@@ -0,0 +1,73 @@
+package traefik
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+func TestLabels(t *testing.T) {
+	spec := v1.ServiceSpec{Name: "web_app", Proxy: &v1.ProxySpec{Domain: "app.example.com", Backend: 8080, SSL: true}}
+	got := New(Options{Network: "edge", CertResolver: "le"}).Labels(spec)
+	want := map[string]string{
+		"traefik.enable": "true",
+		"traefik.http.routers.orbit-web-app.rule":                      "Host(`app.example.com`)",
+		"traefik.http.routers.orbit-web-app.service":                   "orbit-web-app",
+		"traefik.http.routers.orbit-web-app.entrypoints":               "websecure",
+		"traefik.http.routers.orbit-web-app.tls":                       "true",
+		"traefik.http.routers.orbit-web-app.tls.certresolver":          "le",
+		"traefik.http.services.orbit-web-app.loadbalancer.server.port": "8080",
+		"traefik.docker.network":                                       "edge",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Labels =\n%v\nwant\n%v", got, want)
+	}
+
+	spec.Proxy.SSL = false
+	if ep := New(Options{}).Labels(spec)["traefik.http.routers.orbit-web-app.entrypoints"]; ep != "web" {
+		t.Errorf("plain HTTP entry point = %q", ep)
+	}
+	if New(Options{}).Labels(v1.ServiceSpec{Name: "db"}) != nil {
+		t.Error("labels for a service without a proxy section")
+	}
+
+	// An HTTP readiness probe becomes Traefik's health check for the service.
+	spec.HealthCheck = &v1.HealthCheckSpec{Type: "tcp", Port: 8080, Interval: 5 * time.Second,
+		Readiness: &v1.ProbeSpec{Type: "http", URL: "http://localhost:8080/ready?full=1"}}
+	labels := New(Options{}).Labels(spec)
+	hc := "traefik.http.services.orbit-web-app.loadbalancer.healthcheck."
+	if labels[hc+"path"] != "/ready?full=1" || labels[hc+"interval"] != "5s" {
+		t.Errorf("health check labels = %v", labels)
+	}
+	spec.HealthCheck.Readiness = nil
+	if _, ok := New(Options{}).Labels(spec)[hc+"path"]; ok {
+		t.Error("health check labels for a tcp readiness probe")
+	}
+}
+
+func TestApply(t *testing.T) {
+	spec := v1.ServiceSpec{
+		Name:     "api",
+		Labels:   map[string]string{"traefik.http.routers.orbit-api.rule": "Host(`api.example.com`) && PathPrefix(`/v1`)", "team": "core"},
+		Networks: []string{"backend"},
+		Proxy:    &v1.ProxySpec{Domain: "api.example.com", Backend: 3000},
+	}
+	out := New(Options{Network: "edge"}).Apply(spec)
+	if out.Labels["traefik.http.routers.orbit-api.rule"] != "Host(`api.example.com`) && PathPrefix(`/v1`)" || out.Labels["team"] != "core" {
+		t.Errorf("user labels not kept: %v", out.Labels)
+	}
+	if out.Labels["traefik.enable"] != "true" {
+		t.Errorf("generated labels missing: %v", out.Labels)
+	}
+	if !reflect.DeepEqual(out.Networks, []string{"backend", "edge"}) {
+		t.Errorf("Networks = %v", out.Networks)
+	}
+	if len(spec.Labels) != 2 || len(spec.Networks) != 1 {
+		t.Error("Apply modified its argument")
+	}
+	if again := New(Options{Network: "edge"}).Apply(out); !reflect.DeepEqual(again.Networks, out.Networks) {
+		t.Errorf("network joined twice: %v", again.Networks)
+	}
+}
@@ -0,0 +1,75 @@
+// Package registry: checking a deployed image for updates.
+package registry
+
+import (
+	"context"
+	"strings"
+)
+
+// Update is what a registry has that is newer than a deployed image.
+type Update struct {
+	Image  string `json:"image"`            // as deployed
+	Latest string `json:"latest,omitempty"` // the newest tag of the same form, when newer
+	Kind   string `json:"kind,omitempty"`   // UpdateMajor, UpdateMinor, UpdatePatch or UpdateDigest; "" when up to date
+	Digest string `json:"digest,omitempty"` // what the deployed tag points at now, when it moved
+	Note   string `json:"note,omitempty"`   // why the image could not be checked, or not fully
+}
+
+// Check looks for updates to image: newer tags of the same form, for a tag
+// that reads as a version, and a new image behind the tag itself — how
+// latest, or a floating tag such as 1.25, is updated. repoDigests are the
+// RepoDigests (name@sha256:…) of the image deployed; without them, only
+// newer tags are looked for. An image pinned by digest is never outdated.
+func (c *Client) Check(ctx context.Context, image string, repoDigests []string) (Update, error) {
+	u := Update{Image: image}
+	ref, err := ParseRef(image)
+	if err != nil {
+		return u, err
+	}
+	if ref.Digest != "" {
+		u.Note = "pinned by digest"
+		return u, nil
+	}
+
+	_, versioned := ParseVersion(ref.Tag)
+	if versioned {
+		tags, err := c.Tags(ctx, ref)
+		if err != nil {
+			return u, err
+		}
+		u.Latest, u.Kind = Newest(ref.Tag, tags)
+	}
+
+	deployed := deployedDigest(ref, repoDigests)
+	if deployed == "" {
+		if !versioned {
+			u.Note = "not a version, and the digest it was pulled by is unknown"
+		}
+		return u, nil
+	}
+	current, err := c.Digest(ctx, ref)
+	if err != nil {
+		return u, err
+	}
+	if current != deployed {
+		u.Digest = current
+		if u.Kind == "" {
+			u.Kind = UpdateDigest
+		}
+	}
+	return u, nil
+}
+
+// deployedDigest picks the digest of ref's repository out of repoDigests.
+func deployedDigest(ref Ref, repoDigests []string) string {
+	for _, rd := range repoDigests {
+		name, digest, ok := strings.Cut(rd, "@")
+		if !ok {
+			continue
+		}
+		if r, err := ParseRef(name); err == nil && r.Registry == ref.Registry && r.Repo == ref.Repo {
+			return digest
+		}
+	}
+	return ""
+}
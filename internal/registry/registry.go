@@ -0,0 +1,406 @@
+// Package registry queries container registries over the Docker Registry
+// HTTP API V2: the tags of a repository, the digest a tag points at, and
+// manifests and blobs.
+// Registries that ask for credentials get those `docker login` saved in
+// ~/.docker/config.json; credential helpers are not consulted.
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DockerHub is the registry of images named without one.
+const DockerHub = "docker.io"
+
+// maxManifestSize caps a manifest read by Manifest.
+const maxManifestSize = 4 << 20
+
+// manifestTypes are the manifests Digest accepts, lists and indexes first so
+// the digest is the one `docker pull` records for multi-platform images.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Ref is a parsed image reference.
+type Ref struct {
+	Registry string // e.g. docker.io, ghcr.io, localhost:5000
+	Repo     string // e.g. library/nginx, acme/api
+	Tag      string // latest when neither a tag nor a digest is given
+	Digest   string // sha256:…, when pinned
+}
+
+// ParseRef parses an image reference as Docker does: a first component
+// with a dot or port, or localhost, names the registry, and Docker Hub's
+// official images live under library/.
+func ParseRef(image string) (Ref, error) {
+	var r Ref
+	rest := image
+	if name, digest, ok := strings.Cut(rest, "@"); ok {
+		rest, r.Digest = name, digest
+	}
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		rest, r.Tag = rest[:i], rest[i+1:]
+	}
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+	first, remainder, ok := strings.Cut(rest, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		r.Registry, r.Repo = first, remainder
+	} else {
+		r.Registry, r.Repo = DockerHub, rest
+	}
+	if r.Registry == DockerHub && !strings.Contains(r.Repo, "/") {
+		r.Repo = "library/" + r.Repo
+	}
+	if r.Repo == "" || strings.ToLower(r.Repo) != r.Repo {
+		return Ref{}, fmt.Errorf("image %q: want [registry/]repository[:tag][@digest] in lower case", image)
+	}
+	return r, nil
+}
+
+// Name is the repository as Docker names it in an image's RepoDigests:
+// nginx for Docker Hub's library/nginx, ghcr.io/acme/api for others.
+func (r Ref) Name() string {
+	if r.Registry == DockerHub {
+		return strings.TrimPrefix(r.Repo, "library/")
+	}
+	return r.Registry + "/" + r.Repo
+}
+
+// WithTag returns the reference to tag of the same repository.
+func (r Ref) WithTag(tag string) Ref {
+	return Ref{Registry: r.Registry, Repo: r.Repo, Tag: tag}
+}
+
+func (r Ref) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Client talks to registries, keeping the tokens it is given for each
+// repository.
+type Client struct {
+	http   *http.Client
+	auths  map[string]string // registry to base64 user:password, from the Docker config
+	mu     sync.Mutex
+	tokens map[string]string // registry/repo to Authorization header
+}
+
+// New returns a Client with the credentials of ~/.docker/config.json, or
+// $DOCKER_CONFIG/config.json.
+func New() *Client {
+	return &Client{
+		http:   &http.Client{Timeout: 30 * time.Second},
+		auths:  dockerAuths(),
+		tokens: map[string]string{},
+	}
+}
+
+// WithHTTPClient sends requests through h.
+func (c *Client) WithHTTPClient(h *http.Client) *Client {
+	c.http = h
+	return c
+}
+
+// Tags returns every tag of r's repository.
+func (c *Client) Tags(ctx context.Context, r Ref) ([]string, error) {
+	next := c.base(r) + "/tags/list?n=1000"
+	var tags []string
+	for next != "" {
+		resp, err := c.do(ctx, http.MethodGet, next, nil, r)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("tags of %s: %w", r.Name(), err)
+		}
+		tags = append(tags, page.Tags...)
+		next = nextPage(next, resp.Header.Get("Link"))
+	}
+	return tags, nil
+}
+
+// Digest returns the digest r's tag points at.
+func (c *Client) Digest(ctx context.Context, r Ref) (string, error) {
+	header := http.Header{"Accept": {strings.Join(manifestTypes, ", ")}}
+	resp, err := c.do(ctx, http.MethodHead, c.base(r)+"/manifests/"+r.reference(), header, r)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("%s: the registry sent no Docker-Content-Digest", r)
+	}
+	return digest, nil
+}
+
+// Manifest returns the manifest r points at, asking for one of mediaTypes.
+func (c *Client) Manifest(ctx context.Context, r Ref, mediaTypes ...string) ([]byte, error) {
+	header := http.Header{"Accept": {strings.Join(mediaTypes, ", ")}}
+	resp, err := c.do(ctx, http.MethodGet, c.base(r)+"/manifests/"+r.reference(), header, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return nil, fmt.Errorf("manifest of %s: %w", r, err)
+	}
+	return data, nil
+}
+
+// Blob starts downloading the blob with digest from r's repository.
+func (c *Client) Blob(ctx context.Context, r Ref, digest string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.base(r)+"/blobs/"+digest, nil, r)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// reference is what r's manifest is addressed by: its digest, or its tag.
+func (r Ref) reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// base is the API URL of r's repository. Registries on localhost are
+// reached over plain HTTP, as Docker allows.
+func (c *Client) base(r Ref) string {
+	host, scheme := r.Registry, "https"
+	if host == DockerHub {
+		host = "registry-1.docker.io"
+	}
+	if h, _, _ := strings.Cut(host, ":"); h == "localhost" || h == "127.0.0.1" {
+		scheme = "http"
+	}
+	return scheme + "://" + host + "/v2/" + r.Repo
+}
+
+// do sends a request for r's repository, answering an authentication
+// challenge once.
+func (c *Client) do(ctx context.Context, method, u string, header http.Header, r Ref) (*http.Response, error) {
+	key := r.Registry + "/" + r.Repo
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header = header.Clone()
+		if req.Header == nil {
+			req.Header = http.Header{}
+		}
+		c.mu.Lock()
+		if auth := c.tokens[key]; auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		c.mu.Unlock()
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Registry, err)
+		}
+		return resp, nil
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		auth, err := c.authorize(ctx, challenge, r)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.tokens[key] = auth
+		c.mu.Unlock()
+		if resp, err = send(); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return nil, statusErr(r, resp)
+	}
+	return resp, nil
+}
+
+// statusErr explains a failed response.
+func statusErr(r Ref, resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%s: access denied (%s); run docker login %s", r.Name(), resp.Status, r.Registry)
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: not found in %s", r, r.Registry)
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("%s: rate limited by %s; try again later, or docker login", r.Name(), r.Registry)
+	}
+	return fmt.Errorf("%s: %s", r.Name(), resp.Status)
+}
+
+// authorize answers a challenge: with the saved credentials for Basic, or
+// with a pull token fetched with them, or anonymously, for Bearer.
+func (c *Client) authorize(ctx context.Context, challenge string, r Ref) (string, error) {
+	basic := c.auths[r.Registry]
+	scheme, _, _ := strings.Cut(challenge, " ")
+	if strings.EqualFold(scheme, "Basic") {
+		if basic == "" {
+			return "", fmt.Errorf("%s requires a login; run docker login %s", r.Registry, r.Registry)
+		}
+		return "Basic " + basic, nil
+	}
+	params, ok := parseBearerChallenge(challenge)
+	if !ok || params["realm"] == "" {
+		return "", fmt.Errorf("%s requires authentication (%q)", r.Registry, challenge)
+	}
+	q := url.Values{}
+	if svc := params["service"]; svc != "" {
+		q.Set("service", svc)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + r.Repo + ":pull"
+	}
+	q.Set("scope", scope)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, params["realm"]+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if basic != "" {
+		req.Header.Set("Authorization", "Basic "+basic)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("registry token for %s: %s", r.Name(), resp.Status)
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("registry token: %w", err)
+	}
+	if tok.Token == "" {
+		tok.Token = tok.AccessToken
+	}
+	if tok.Token == "" {
+		return "", fmt.Errorf("registry token: empty response from %s", params["realm"])
+	}
+	return "Bearer " + tok.Token, nil
+}
+
+// parseBearerChallenge parses `Bearer realm="...",service="...",scope="..."`.
+func parseBearerChallenge(h string) (map[string]string, bool) {
+	rest, ok := strings.CutPrefix(h, "Bearer ")
+	if !ok {
+		return nil, false
+	}
+	params := map[string]string{}
+	for rest != "" {
+		key, after, ok := strings.Cut(strings.TrimLeft(rest, ", "), "=")
+		if !ok {
+			break
+		}
+		after = strings.TrimPrefix(after, `"`)
+		val, tail, _ := strings.Cut(after, `"`)
+		params[strings.ToLower(strings.TrimSpace(key))] = val
+		rest = tail
+	}
+	return params, true
+}
+
+var linkNext = regexp.MustCompile(`<([^>]+)>;\s*rel="?next"?`)
+
+// nextPage is the URL of the page a Link header points to next, resolved
+// against the current one, or "" on the last page.
+func nextPage(current, link string) string {
+	m := linkNext.FindStringSubmatch(link)
+	if m == nil {
+		return ""
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	next, err := base.Parse(m[1])
+	if err != nil {
+		return ""
+	}
+	return next.String()
+}
+
+// dockerAuths returns the credentials `docker login` saved, by registry.
+func dockerAuths() map[string]string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".docker")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return nil
+	}
+	var cfg struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+	if json.Unmarshal(data, &cfg) != nil {
+		return nil
+	}
+	auths := map[string]string{}
+	for server, a := range cfg.Auths {
+		auth := a.Auth
+		if auth == "" && a.Username != "" {
+			auth = base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
+		}
+		if auth == "" {
+			continue
+		}
+		host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+		host, _, _ = strings.Cut(host, "/")
+		if host == "index.docker.io" || host == "registry-1.docker.io" {
+			host = DockerHub
+		}
+		auths[host] = auth
+	}
+	return auths
+}
@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseRef(t *testing.T) {
+	for _, tc := range []struct {
+		image string
+		want  Ref
+		name  string
+	}{
+		{"nginx", Ref{Registry: DockerHub, Repo: "library/nginx", Tag: "latest"}, "nginx"},
+		{"nginx:1.25-alpine", Ref{Registry: DockerHub, Repo: "library/nginx", Tag: "1.25-alpine"}, "nginx"},
+		{"acme/api:v2", Ref{Registry: DockerHub, Repo: "acme/api", Tag: "v2"}, "acme/api"},
+		{"ghcr.io/acme/api:1.0.0", Ref{Registry: "ghcr.io", Repo: "acme/api", Tag: "1.0.0"}, "ghcr.io/acme/api"},
+		{"localhost:5000/web", Ref{Registry: "localhost:5000", Repo: "web", Tag: "latest"}, "localhost:5000/web"},
+		{"redis@sha256:abc", Ref{Registry: DockerHub, Repo: "library/redis", Digest: "sha256:abc"}, "redis"},
+	} {
+		got, err := ParseRef(tc.image)
+		if err != nil || got != tc.want || got.Name() != tc.name {
+			t.Errorf("ParseRef(%q) = %+v (%s), %v", tc.image, got, got.Name(), err)
+		}
+	}
+	if _, err := ParseRef("Acme/API"); err == nil {
+		t.Error("an upper-case repository parsed")
+	}
+}
+
+func TestNewest(t *testing.T) {
+	tags := []string{"latest", "1.24", "1.25", "1.27", "1.25-alpine", "1.27-alpine", "2.0-alpine", "1.25.3", "1.25.4", "1.26.0", "v1.3.0", "1.28-rc1"}
+	for _, tc := range []struct {
+		tag, newest, kind string
+	}{
+		{"1.25", "1.27", UpdateMinor},
+		{"1.25-alpine", "2.0-alpine", UpdateMajor},
+		{"1.25.3", "1.26.0", UpdateMinor},
+		{"1.26.0", "", ""},
+		{"v1.2.9", "v1.3.0", UpdateMinor},
+		{"latest", "", ""},
+	} {
+		newest, kind := Newest(tc.tag, tags)
+		if newest != tc.newest || kind != tc.kind {
+			t.Errorf("Newest(%s) = %s %s, want %s %s", tc.tag, newest, kind, tc.newest, tc.kind)
+		}
+	}
+	if newest, kind := Newest("1.25.3", []string{"1.25.4"}); newest != "1.25.4" || kind != UpdatePatch {
+		t.Errorf("patch: %s %s", newest, kind)
+	}
+}
+
+// fakeRegistry serves the tags of one repository, two to a page, and the
+// digest of its latest tag, to holders of a token.
+func fakeRegistry(t *testing.T, tags []string, digest string) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:acme/web:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "t0k"})
+			return
+		case r.Header.Get("Authorization") != "Bearer t0k":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="fake"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		case r.URL.Path == "/v2/acme/web/tags/list":
+			page := tags
+			if last := r.URL.Query().Get("last"); last != "" {
+				for i, tag := range tags {
+					if tag == last {
+						page = tags[i+1:]
+					}
+				}
+			}
+			if len(page) > 2 {
+				page = page[:2]
+				w.Header().Set("Link", `</v2/acme/web/tags/list?n=2&last=`+page[1]+`>; rel="next"`)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"name": "acme/web", "tags": page})
+		case r.URL.Path == "/v2/acme/web/manifests/latest" && r.Method == http.MethodHead:
+			if !strings.Contains(r.Header.Get("Accept"), "manifest.list") {
+				http.Error(w, "no accept", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCheck(t *testing.T) {
+	srv := fakeRegistry(t, []string{"1.0.0", "1.0.1", "1.1.0", "2.0.0-rc1", "latest"}, "sha256:new")
+	host := strings.TrimPrefix(srv.URL, "http://")
+	c := New()
+	ctx := context.Background()
+
+	u, err := c.Check(ctx, host+"/acme/web:1.0.0", nil)
+	if err != nil || u.Latest != "1.1.0" || u.Kind != UpdateMinor {
+		t.Errorf("versioned tag: %+v, %v", u, err)
+	}
+
+	u, err = c.Check(ctx, host+"/acme/web:latest", []string{"nginx@sha256:x", host + "/acme/web@sha256:old"})
+	if err != nil || u.Kind != UpdateDigest || u.Digest != "sha256:new" {
+		t.Errorf("moved latest: %+v, %v", u, err)
+	}
+	u, err = c.Check(ctx, host+"/acme/web:latest", []string{host + "/acme/web@sha256:new"})
+	if err != nil || u.Kind != "" {
+		t.Errorf("current latest: %+v, %v", u, err)
+	}
+
+	if u, err := c.Check(ctx, host+"/acme/web@sha256:old", nil); err != nil || u.Kind != "" || u.Note == "" {
+		t.Errorf("pinned: %+v, %v", u, err)
+	}
+	if u, err := c.Check(ctx, host+"/acme/web:latest", nil); err != nil || u.Kind != "" || u.Note == "" {
+		t.Errorf("latest, not deployed: %+v, %v", u, err)
+	}
+	if _, err := c.Check(ctx, host+"/acme/gone:1.0", nil); err == nil {
+		t.Error("a missing repository checked out")
+	}
+}
@@ -0,0 +1,92 @@
+// Package registry: reading image tags as versions, to find newer ones.
+package registry
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Version is an image tag read as a version: 1.25.3, v2.1, 1.25-alpine.
+// Tags only compare with tags of the same form — the same "v" prefix, as
+// many numbers, and the same suffix — so 1.25-alpine is followed by
+// 1.27-alpine, not by 1.27 or 1.27.1-alpine.
+type Version struct {
+	Prefix string // "v" or ""
+	Nums   []int
+	Suffix string // from the first "-", e.g. -alpine
+}
+
+// ParseVersion reads tag as a Version.
+func ParseVersion(tag string) (Version, bool) {
+	var v Version
+	rest := tag
+	if strings.HasPrefix(rest, "v") {
+		v.Prefix, rest = "v", rest[1:]
+	}
+	if i := strings.Index(rest, "-"); i >= 0 {
+		rest, v.Suffix = rest[:i], rest[i:]
+	}
+	parts := strings.Split(rest, ".")
+	if len(parts) > 4 {
+		return Version{}, false
+	}
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || p != strconv.Itoa(n) {
+			return Version{}, false
+		}
+		v.Nums = append(v.Nums, n)
+	}
+	return v, true
+}
+
+// sameForm reports whether v and o compare.
+func (v Version) sameForm(o Version) bool {
+	return v.Prefix == o.Prefix && len(v.Nums) == len(o.Nums) && v.Suffix == o.Suffix
+}
+
+// Less reports whether v is an older version than o of the same form.
+func (v Version) Less(o Version) bool {
+	for i := range v.Nums {
+		if v.Nums[i] != o.Nums[i] {
+			return v.Nums[i] < o.Nums[i]
+		}
+	}
+	return false
+}
+
+// Update kinds.
+const (
+	UpdateMajor  = "major"
+	UpdateMinor  = "minor"
+	UpdatePatch  = "patch"
+	UpdateDigest = "digest" // the same tag points at a new image
+)
+
+// Newest returns the newest of tags that is a newer version of tag in its
+// form, and the kind of update it is, or "" when there is none.
+func Newest(tag string, tags []string) (newest, kind string) {
+	cur, ok := ParseVersion(tag)
+	if !ok {
+		return "", ""
+	}
+	best := cur
+	for _, t := range tags {
+		v, ok := ParseVersion(t)
+		if ok && cur.sameForm(v) && best.Less(v) {
+			best, newest = v, t
+		}
+	}
+	if newest == "" {
+		return "", ""
+	}
+	switch {
+	case best.Nums[0] != cur.Nums[0]:
+		kind = UpdateMajor
+	case len(cur.Nums) > 1 && best.Nums[1] != cur.Nums[1]:
+		kind = UpdateMinor
+	default:
+		kind = UpdatePatch
+	}
+	return newest, kind
+}
@@ -0,0 +1,80 @@
+// Package remote: parallel command execution across nodes.
+package remote
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/sshutil"
+)
+
+// DefaultParallelism bounds concurrent sessions in RunAll when none is given.
+const DefaultParallelism = 10
+
+// ExecResult is the outcome of running a command on one node.
+type ExecResult struct {
+	Node     string        `json:"node"`
+	ExitCode int           `json:"exit_code"` // -1 when the command never ran
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"` // connection or session failure
+}
+
+// OK reports whether the command ran and exited zero.
+func (r ExecResult) OK() bool {
+	return r.Error == "" && r.ExitCode == 0
+}
+
+// Exec runs cmd on node with stdout and stderr attached to the given writers
+// and returns its exit status. A non-zero exit is not an error.
+func (p *Pool) Exec(ctx context.Context, node v1.NodeInfo, cmd string, stdout, stderr io.Writer) (int, error) {
+	client, release, err := p.acquire(ctx, node)
+	if err != nil {
+		return -1, err
+	}
+	defer release()
+	return sshutil.RunStreams(client, cmd, stdout, stderr)
+}
+
+// RunAll runs cmd on every node with at most parallel sessions at once.
+// streams supplies the stdout/stderr writers for each node; it is called
+// once per node before the command starts. Results keep the order of nodes.
+func (p *Pool) RunAll(ctx context.Context, nodes []v1.NodeInfo, cmd string, parallel int,
+	streams func(node string) (stdout, stderr io.Writer)) []ExecResult {
+	if parallel <= 0 {
+		parallel = DefaultParallelism
+	}
+	results := make([]ExecResult, len(nodes))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node v1.NodeInfo) {
+			defer wg.Done()
+			res := ExecResult{Node: node.Spec.Name, ExitCode: -1}
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				res.Error = ctx.Err().Error()
+				results[i] = res
+				return
+			}
+
+			stdout, stderr := streams(node.Spec.Name)
+			start := time.Now()
+			code, err := p.Exec(ctx, node, cmd, stdout, stderr)
+			res.ExitCode, res.Duration = code, time.Since(start)
+			if err != nil {
+				res.Error = err.Error()
+				p.log.Warn("exec failed", "node", node.Spec.Name, "err", err)
+			}
+			results[i] = res
+		}(i, node)
+	}
+	wg.Wait()
+	return results
+}
@@ -1,4 +1,5 @@
-// Package remote: heartbeat engine — per-node goroutines maintaining live connectivity state.
+// Package remote: heartbeat engine — per-node goroutines maintaining live
+// connectivity state and host metrics.
 package remote
 
 import (
@@ -8,9 +9,11 @@ import (
 
 	v1 "github.com/f9-o/orbit/api/v1"
 	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/internal/metrics"
 )
 
-// HeartbeatInterval is how often each node is probed.
+// HeartbeatInterval is how often each node is probed unless its spec sets
+// heartbeat_interval.
 const HeartbeatInterval = 30 * time.Second
 
 // HeartbeatTimeout is the max time allowed for a single probe.
@@ -20,6 +23,7 @@ const HeartbeatTimeout = 10 * time.Second
 type NodeEvent struct {
 	Node   string
 	Status v1.NodeStatus
+	Time   time.Time
 }
 
 // Engine runs one goroutine per node to maintain heartbeat state.
@@ -86,52 +90,86 @@ func (e *Engine) StopAll() {
 	}
 }
 
-// watchLoop is the per-node heartbeat goroutine.
+// watchLoop is the per-node heartbeat goroutine. The first probe runs
+// immediately so status is current as soon as watching starts.
 func (e *Engine) watchLoop(ctx context.Context, node v1.NodeInfo) {
-	ticker := time.NewTicker(HeartbeatInterval)
+	ticker := time.NewTicker(heartbeatInterval(node))
 	defer ticker.Stop()
 
 	failCount := 0
+	last := node.Status
 
 	for {
+		probeCtx, cancel := context.WithTimeout(ctx, HeartbeatTimeout)
+		out, _, err := e.pool.Run(probeCtx, node, metrics.HostScript)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			failCount++
+			e.log.Debug("heartbeat miss", "node", node.Spec.Name, "fail_count", failCount)
+
+			status := v1.NodeDegraded
+			if failCount >= 3 {
+				status = v1.NodeOffline
+			}
+
+			if uerr := e.registry.MarkOffline(node.Spec.Name, failCount); uerr != nil {
+				e.log.Warn("heartbeat: state update failed", "err", uerr)
+			}
+
+			// Emit event on status transition
+			if status != last {
+				last = status
+				e.emit(NodeEvent{Node: node.Spec.Name, Status: status, Time: time.Now()})
+			}
+		} else {
+			if failCount > 0 {
+				// Recovery from degraded state
+				e.log.Info("node recovered", "node", node.Spec.Name)
+			}
+			if last != v1.NodeOnline {
+				last = v1.NodeOnline
+				e.emit(NodeEvent{Node: node.Spec.Name, Status: v1.NodeOnline, Time: time.Now()})
+			}
+			failCount = 0
+			if uerr := e.registry.MarkOnline(node.Spec.Name); uerr != nil {
+				e.log.Warn("heartbeat: state update failed", "err", uerr)
+			}
+			e.recordHost(node.Spec.Name, out)
+		}
+
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			probeCtx, cancel := context.WithTimeout(ctx, HeartbeatTimeout)
-			_, _, err := e.pool.Run(probeCtx, node, "echo __orbit_hb__")
-			cancel()
-
-			if err != nil {
-				failCount++
-				e.log.Debug("heartbeat miss", "node", node.Spec.Name, "fail_count", failCount)
-
-				status := v1.NodeDegraded
-				if failCount >= 3 {
-					status = v1.NodeOffline
-				}
-
-				if uerr := e.registry.MarkOffline(node.Spec.Name, failCount); uerr != nil {
-					e.log.Warn("heartbeat: state update failed", "err", uerr)
-				}
-
-				// Emit event on status transition
-				e.emit(NodeEvent{Node: node.Spec.Name, Status: status})
-			} else {
-				if failCount > 0 {
-					// Recovery from degraded state
-					e.log.Info("node recovered", "node", node.Spec.Name)
-					e.emit(NodeEvent{Node: node.Spec.Name, Status: v1.NodeOnline})
-				}
-				failCount = 0
-				if uerr := e.registry.MarkOnline(node.Spec.Name); uerr != nil {
-					e.log.Warn("heartbeat: state update failed", "err", uerr)
-				}
-			}
 		}
 	}
 }
 
+// recordHost stores the host metrics reported by a successful probe. A node
+// whose report is unreadable (no /proc, not Linux) keeps heartbeating without them.
+func (e *Engine) recordHost(name, out string) {
+	h, err := metrics.ParseHostReport(out)
+	if err != nil {
+		e.log.Debug("heartbeat: host metrics", "node", name, "err", err)
+		return
+	}
+	if err := e.registry.SetHostMetrics(name, h); err != nil {
+		e.log.Warn("heartbeat: state update failed", "err", err)
+	}
+}
+
+// heartbeatInterval returns the node's probe interval.
+func heartbeatInterval(node v1.NodeInfo) time.Duration {
+	if node.Spec.HeartbeatInterval > 0 {
+		return node.Spec.HeartbeatInterval
+	}
+	return HeartbeatInterval
+}
+
 // emit sends a NodeEvent without blocking (drops if channel full).
 func (e *Engine) emit(ev NodeEvent) {
 	select {
@@ -0,0 +1,144 @@
+// Package remote: connection pool limits, idle reaping, and stats.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// PoolLimits bounds the resources a Pool holds. Zero values mean unlimited.
+type PoolLimits struct {
+	MaxConns    int           `json:"max_connections"` // open connections across all nodes
+	IdleTimeout time.Duration `json:"idle_timeout"`    // close connections unused for this long
+	MaxSessions int           `json:"max_sessions"`    // concurrent sessions per connection
+}
+
+// PoolStats is a point-in-time view of a Pool, for `orbit doctor` and metrics.
+type PoolStats struct {
+	Connections int        `json:"connections"`
+	Sessions    int        `json:"sessions"` // sessions in use or waiting for a slot
+	Idle        int        `json:"idle"`     // connections with no active session
+	Reaped      int        `json:"reaped"`   // connections closed for idleness since start
+	Limits      PoolLimits `json:"limits"`
+}
+
+// WithLimits applies resource limits and, when IdleTimeout is set, starts a
+// background reaper that runs until Close.
+func (p *Pool) WithLimits(l PoolLimits) *Pool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.limits = l
+	if l.IdleTimeout > 0 && p.stop == nil {
+		p.stop = make(chan struct{})
+		go p.reapLoop(p.stop, max(l.IdleTimeout/2, time.Second))
+	}
+	return p
+}
+
+// Stats returns current pool usage.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := PoolStats{Connections: len(p.conns), Reaped: p.reaped, Limits: p.limits}
+	for _, c := range p.conns {
+		s.Sessions += c.active
+		if c.active == 0 {
+			s.Idle++
+		}
+	}
+	return s
+}
+
+// acquire returns the node's connection with one session slot reserved.
+// The release func must be called when the session ends.
+func (p *Pool) acquire(ctx context.Context, node v1.NodeInfo) (*ssh.Client, func(), error) {
+	c, err := p.connect(ctx, node, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.sessions != nil {
+		select {
+		case c.sessions <- struct{}{}:
+		case <-ctx.Done():
+			p.mu.Lock()
+			c.active--
+			p.mu.Unlock()
+			return nil, nil, fmt.Errorf("waiting for ssh session on %q: %w", node.Spec.Name, ctx.Err())
+		}
+	}
+
+	p.mu.Lock()
+	c.lastUsed = time.Now()
+	p.mu.Unlock()
+
+	release := func() {
+		p.mu.Lock()
+		c.active--
+		c.lastUsed = time.Now()
+		p.mu.Unlock()
+		if c.sessions != nil {
+			<-c.sessions
+		}
+	}
+	return c.client, release, nil
+}
+
+// evictIdle closes the least recently used connection without active
+// sessions to make room under MaxConns. Caller holds p.mu.
+func (p *Pool) evictIdle() bool {
+	var lru *connection
+	for _, c := range p.conns {
+		if c.active == 0 && (lru == nil || c.lastUsed.Before(lru.lastUsed)) {
+			lru = c
+		}
+	}
+	if lru == nil {
+		return false
+	}
+	p.closeConn(lru)
+	p.log.Info("ssh connection evicted", "node", lru.node, "max_connections", p.limits.MaxConns)
+	return true
+}
+
+// reapLoop periodically closes connections idle for longer than IdleTimeout.
+func (p *Pool) reapLoop(stop <-chan struct{}, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			p.reapIdle(now)
+		}
+	}
+}
+
+// reapIdle closes connections with no active session unused since before
+// now-IdleTimeout and returns how many it closed.
+func (p *Pool) reapIdle(now time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, c := range p.conns {
+		if c.active == 0 && now.Sub(c.lastUsed) > p.limits.IdleTimeout {
+			p.closeConn(c)
+			p.log.Info("ssh connection reaped", "node", c.node, "idle", now.Sub(c.lastUsed).Round(time.Second))
+			n++
+		}
+	}
+	p.reaped += n
+	return n
+}
+
+// closeConn closes c and removes it from the pool. Caller holds p.mu.
+func (p *Pool) closeConn(c *connection) {
+	c.cancel()
+	c.client.Close()
+	delete(p.conns, c.node)
+}
@@ -0,0 +1,186 @@
+package remote
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/logger"
+)
+
+// loopbackClient returns an SSH client connected over loopback to a server that
+// accepts any client and rejects all channels.
+func loopbackClient(t *testing.T) *ssh.Client {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvCfg := &ssh.ServerConfig{NoClientAuth: true}
+	srvCfg.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		sc, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		_, chans, reqs, err := ssh.NewServerConn(sc, srvCfg)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		for ch := range chans {
+			ch.Reject(ssh.Prohibited, "test server")
+		}
+	}()
+
+	cc, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, chans, reqs, err := ssh.NewClientConn(cc, ln.Addr().String(), &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint:gosec
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ssh.NewClient(conn, chans, reqs)
+}
+
+func testPool(t *testing.T, limits PoolLimits, nodes ...string) *Pool {
+	t.Helper()
+	log, err := logger.Init("error", "text", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPool(log)
+	p.limits = limits // no reaper goroutine; tests call reapIdle directly
+	for _, n := range nodes {
+		c := &connection{client: loopbackClient(t), node: n, lastUsed: time.Now(), cancel: func() {}}
+		if limits.MaxSessions > 0 {
+			c.sessions = make(chan struct{}, limits.MaxSessions)
+		}
+		p.conns[n] = c
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+func nodeInfo(name string) v1.NodeInfo {
+	return v1.NodeInfo{Spec: v1.NodeSpec{Name: name}}
+}
+
+func TestPoolReapIdle(t *testing.T) {
+	p := testPool(t, PoolLimits{IdleTimeout: time.Minute}, "a", "b", "c")
+	now := time.Now()
+	p.conns["a"].lastUsed = now.Add(-2 * time.Minute)
+	p.conns["b"].lastUsed = now.Add(-2 * time.Minute)
+	p.conns["b"].active = 1 // busy connections are never reaped
+
+	if n := p.reapIdle(now); n != 1 {
+		t.Fatalf("reaped %d, want 1", n)
+	}
+	if _, ok := p.conns["a"]; ok {
+		t.Error("idle connection a was not reaped")
+	}
+	s := p.Stats()
+	if s.Connections != 2 || s.Sessions != 1 || s.Idle != 1 || s.Reaped != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestPoolEvictsLeastRecentlyUsed(t *testing.T) {
+	p := testPool(t, PoolLimits{MaxConns: 2}, "a", "b")
+	p.conns["a"].lastUsed = time.Now().Add(-time.Hour)
+
+	if !p.evictIdle() {
+		t.Fatal("expected an idle connection to be evicted")
+	}
+	if _, ok := p.conns["a"]; ok {
+		t.Error("least recently used connection a was kept")
+	}
+
+	p.conns["b"].active = 1
+	if p.evictIdle() {
+		t.Error("evicted a connection with an active session")
+	}
+}
+
+func TestPoolSessionLimit(t *testing.T) {
+	p := testPool(t, PoolLimits{MaxSessions: 1}, "a")
+
+	_, release, err := p.acquire(context.Background(), nodeInfo("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Stats(); s.Sessions != 1 || s.Idle != 0 {
+		t.Errorf("stats while busy = %+v", s)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := p.acquire(ctx, nodeInfo("a")); err == nil {
+		t.Fatal("second session acquired beyond max_sessions")
+	}
+
+	if s := p.Stats(); s.Sessions != 1 {
+		t.Errorf("stats after a timed-out wait = %+v", s)
+	}
+
+	release()
+	_, release, err = p.acquire(context.Background(), nodeInfo("a"))
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	release()
+}
+
+func TestPoolKeepsConnectionForWaiter(t *testing.T) {
+	p := testPool(t, PoolLimits{MaxSessions: 1, IdleTimeout: time.Minute}, "a")
+	c := p.conns["a"]
+	c.sessions <- struct{}{} // the only slot, taken
+
+	acquired := make(chan error, 1)
+	go func() {
+		_, release, err := p.acquire(context.Background(), nodeInfo("a"))
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+	for i := 0; p.Stats().Sessions == 0; i++ {
+		if i == 1000 {
+			t.Fatal("a caller waiting for a slot is not counted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The caller waits for a slot on a connection long unused: the reaper
+	// must leave it open.
+	p.mu.Lock()
+	c.lastUsed = time.Now().Add(-time.Hour)
+	p.mu.Unlock()
+	if n := p.reapIdle(time.Now()); n != 0 {
+		t.Fatal("reaped the connection a caller is waiting on")
+	}
+	<-c.sessions
+	if err := <-acquired; err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+}
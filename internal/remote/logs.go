@@ -0,0 +1,118 @@
+// Package remote: fetching the orbit agent's and Docker daemon's logs, and
+// system details, from a node.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// Log sources a node's logs are fetched from.
+const (
+	LogAgent  = "agent"  // the orbit agent: its systemd unit, container, or log file
+	LogDocker = "docker" // the Docker daemon's journal
+)
+
+// LogSources lists every source, in the order they are fetched.
+var LogSources = []string{LogAgent, LogDocker}
+
+// LogOptions bound what is fetched of each log.
+type LogOptions struct {
+	Since time.Duration // only entries this recent; 0 for all
+	Lines int           // at most this many last lines of each log; 0 for all
+}
+
+// LogCommand is the shell command that prints source's logs on a node. Each
+// log it finds is printed under a `==> name <==` heading, as tail does for
+// several files; logs that are missing or unreadable are skipped, so a node
+// without systemd, or an agent that never ran, prints nothing.
+func LogCommand(source string, opts LogOptions) (string, error) {
+	var journal, tail, docker string
+	if opts.Since > 0 {
+		journal += fmt.Sprintf(" --since -%ds", int(opts.Since.Seconds()))
+		docker += fmt.Sprintf(" --since %ds", int(opts.Since.Seconds()))
+	}
+	tail = " -n +1"
+	if opts.Lines > 0 {
+		journal += fmt.Sprintf(" -n %d", opts.Lines)
+		docker += fmt.Sprintf(" --tail %d", opts.Lines)
+		tail = fmt.Sprintf(" -n %d", opts.Lines)
+	}
+
+	var logs []string
+	switch source {
+	case LogAgent:
+		logs = []string{
+			"journalctl -u orbit-agent.service --no-pager -q" + journal,
+			"journalctl --user -u orbit-agent.service --no-pager -q" + journal,
+			"docker logs -t" + docker + " orbit-agent",
+			"tail" + tail + " $HOME/.orbit/logs/orbit.log",
+			"tail" + tail + " $HOME/.orbit/logs/agent.log",
+			"tail" + tail + " /var/log/orbit-agent.log",
+		}
+	case LogDocker:
+		logs = []string{
+			"journalctl -u docker.service --no-pager -q" + journal,
+			"tail" + tail + " /var/log/docker.log",
+		}
+	default:
+		return "", fmt.Errorf("unknown log source %q (want %s)", source, strings.Join(LogSources, " or "))
+	}
+
+	return headed(logs), nil
+}
+
+// SystemCommands print what a bug report needs to know about a node: its
+// kernel, Docker daemon and orbit binary.
+var SystemCommands = []string{
+	"uname -srm",
+	"docker version",
+	"docker info",
+	"orbit version -o yaml",
+}
+
+// headed is a shell script running each of cmds and printing its output
+// under a `==> cmd <==` heading, which names the command as a reader would
+// rerun it. Commands that fail or print nothing are skipped.
+func headed(cmds []string) string {
+	var sb strings.Builder
+	for _, cmd := range cmds {
+		fmt.Fprintf(&sb, "out=$(%s 2>/dev/null) && [ -n \"$out\" ] && printf '==> %%s <==\\n%%s\\n\\n' '%s' \"$out\"; ", cmd, cmd)
+	}
+	sb.WriteString("true")
+	return sb.String()
+}
+
+// Logs fetches source's logs from node. The SSH user needs to read them:
+// journals are readable by the systemd-journal or adm groups.
+func (p *Pool) Logs(ctx context.Context, node v1.NodeInfo, source string, opts LogOptions) ([]byte, error) {
+	cmd, err := LogCommand(source, opts)
+	if err != nil {
+		return nil, err
+	}
+	return p.output(ctx, node, source+" logs", cmd)
+}
+
+// System runs SystemCommands on node.
+func (p *Pool) System(ctx context.Context, node v1.NodeInfo) ([]byte, error) {
+	return p.output(ctx, node, "system info", headed(SystemCommands))
+}
+
+// output runs cmd on node and returns what it printed; what names it in
+// errors.
+func (p *Pool) output(ctx context.Context, node v1.NodeInfo, what, cmd string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	code, err := p.Exec(ctx, node, cmd, &stdout, &stderr)
+	if err != nil {
+		return nil, err
+	}
+	if code != 0 {
+		return stdout.Bytes(), fmt.Errorf("%s on %s: exit %d: %s", what, node.Spec.Name, code, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,47 @@
+package remote
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLogCommand runs the agent log command in a shell with only tail on
+// $PATH, as on a node without systemd or Docker.
+func TestLogCommand(t *testing.T) {
+	tailPath, err := exec.LookPath("tail")
+	if err != nil {
+		t.Skip("no tail")
+	}
+	bin, home := t.TempDir(), t.TempDir()
+	if err := os.Symlink(tailPath, filepath.Join(bin, "tail")); err != nil {
+		t.Fatal(err)
+	}
+	logs := filepath.Join(home, ".orbit", "logs")
+	os.MkdirAll(logs, 0o755)
+	os.WriteFile(filepath.Join(logs, "orbit.log"), []byte("one\ntwo\nthree\n"), 0o644)
+
+	cmd, err := LogCommand(LogAgent, LogOptions{Since: time.Hour, Lines: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(cmd, "journalctl -u orbit-agent.service --no-pager -q --since -3600s -n 2") {
+		t.Errorf("journal command: %s", cmd)
+	}
+	sh := exec.Command("/bin/sh", "-c", cmd)
+	sh.Env = []string{"PATH=" + bin, "HOME=" + home}
+	out, err := sh.CombinedOutput()
+	if err != nil {
+		t.Fatalf("run: %v\n%s", err, out)
+	}
+	if want := "==> tail -n 2 $HOME/.orbit/logs/orbit.log <==\ntwo\nthree\n\n"; string(out) != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	if _, err := LogCommand("kernel", LogOptions{}); err == nil {
+		t.Error("an unknown source gave a command")
+	}
+}
@@ -30,9 +30,31 @@ func (r *Registry) Add(node v1.NodeInfo) error {
 	}
 	node.Status = v1.NodeOffline
 	node.LastSeen = time.Now().UTC()
+	node.Modified = node.LastSeen
 	return r.db.PutNode(node)
 }
 
+// Update replaces a registered node's spec, keeping its status. Moving the
+// node to another address or port forgets its trusted host key.
+func (r *Registry) Update(spec v1.NodeSpec) error {
+	info, err := r.Get(spec.Name)
+	if err != nil {
+		return err
+	}
+	if info.Spec.Host != spec.Host || info.Spec.Port != spec.Port {
+		info.KeyFingerprint, info.HostKey, info.HostKeyKnown = "", "", false
+	}
+	if spec.HeartbeatInterval == 0 {
+		spec.HeartbeatInterval = info.Spec.HeartbeatInterval
+	}
+	if spec.ProxyJump == "" {
+		spec.ProxyJump = info.Spec.ProxyJump
+	}
+	info.Spec = spec
+	info.Modified = time.Now().UTC()
+	return r.db.PutNode(info)
+}
+
 // Remove deletes a node from the registry.
 func (r *Registry) Remove(name string) error {
 	existing, err := r.db.GetNode(name)
@@ -71,6 +93,7 @@ func (r *Registry) Trust(name, fingerprint, encodedHostKey string) error {
 	info.KeyFingerprint = fingerprint
 	info.HostKey = encodedHostKey
 	info.HostKeyKnown = true
+	info.Modified = time.Now().UTC()
 	return r.db.PutNode(info)
 }
 
@@ -79,6 +102,16 @@ func (r *Registry) MarkOnline(name string) error {
 	return r.db.UpdateNodeStatus(name, v1.NodeOnline, 0)
 }
 
+// SetHostMetrics stores the latest host metrics reading for a node.
+func (r *Registry) SetHostMetrics(name string, h v1.HostMetrics) error {
+	info, err := r.Get(name)
+	if err != nil {
+		return err
+	}
+	info.Host = &h
+	return r.db.PutNode(info)
+}
+
 // MarkOffline increments the fail count and marks the node Offline if threshold is reached.
 func (r *Registry) MarkOffline(name string, failCount int) error {
 	status := v1.NodeDegraded
@@ -4,15 +4,22 @@ package remote
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
 
 	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
 	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/pkg/errs"
+	"github.com/f9-o/orbit/pkg/retry"
 	"github.com/f9-o/orbit/pkg/sshutil"
 )
 
@@ -25,42 +32,101 @@ type connection struct {
 	node     string
 	lastUsed time.Time
 	cancel   context.CancelFunc
+	active   int           // sessions in use
+	sessions chan struct{} // session slots; nil when unlimited
 }
 
 // Pool manages persistent SSH connections to remote nodes.
 type Pool struct {
-	mu    sync.Mutex
-	conns map[string]*connection // node name → connection
-	log   *logger.Logger
+	mu         sync.Mutex
+	conns      map[string]*connection // node name → connection
+	log        *logger.Logger
+	knownHosts string // known_hosts file consulted for host keys
+	strict     bool   // refuse hosts without a trusted key
+	limits     PoolLimits
+	reaped     int
+	stop       chan struct{}     // stops the idle reaper
+	hooks      v1.HookDispatcher // receives OnNodeConnect
 }
 
-// NewPool creates an empty connection pool.
+// NewPool creates an empty connection pool that verifies host keys against
+// Orbit's managed known_hosts file.
 func NewPool(log *logger.Logger) *Pool {
 	return &Pool{
-		conns: make(map[string]*connection),
-		log:   log,
+		conns:      make(map[string]*connection),
+		log:        log,
+		knownHosts: config.KnownHostsFile(),
 	}
 }
 
+// WithKnownHosts sets the known_hosts file used for host key verification.
+func (p *Pool) WithKnownHosts(file string) *Pool {
+	p.knownHosts = file
+	return p
+}
+
+// WithStrictHostKeys makes the pool refuse hosts whose key is not in
+// known_hosts (or trusted in the registry) instead of accepting them.
+func (p *Pool) WithStrictHostKeys(strict bool) *Pool {
+	p.strict = strict
+	return p
+}
+
+// WithHooks fires OnNodeConnect at hooks each time the pool opens a new
+// connection to a node.
+func (p *Pool) WithHooks(hooks v1.HookDispatcher) *Pool {
+	p.hooks = hooks
+	return p
+}
+
 // Connect establishes (or returns an existing) SSH connection for a node.
 func (p *Pool) Connect(ctx context.Context, node v1.NodeInfo) (*ssh.Client, error) {
+	c, err := p.connect(ctx, node, false)
+	if err != nil {
+		return nil, err
+	}
+	return c.client, nil
+}
+
+// connect returns node's live connection. With reserve it also counts one
+// more active session on it before releasing p.mu, so that the reaper and
+// eviction leave it open while the caller waits for a session slot.
+func (p *Pool) connect(ctx context.Context, node v1.NodeInfo, reserve bool) (*connection, error) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
+	c, fresh, err := p.connectLocked(ctx, node)
+	if err == nil && reserve {
+		c.active++
+	}
+	p.mu.Unlock()
+	if fresh && p.hooks != nil {
+		spec := node.Spec
+		p.hooks.Fire(ctx, v1.HookNodeConnect, v1.HookContext{Node: &spec})
+	}
+	return c, err
+}
+
+// connectLocked returns node's live connection, dialling one if needed;
+// fresh reports whether it did. Caller holds p.mu.
+func (p *Pool) connectLocked(ctx context.Context, node v1.NodeInfo) (c *connection, fresh bool, err error) {
 
 	if c, ok := p.conns[node.Spec.Name]; ok {
 		// Verify connection is still alive with a lightweight keepalive
 		if _, _, err := c.client.Conn.SendRequest("keepalive@orbit", true, nil); err == nil {
 			c.lastUsed = time.Now()
-			return c.client, nil
+			return c, false, nil
 		}
 		// Connection dead — remove it and reconnect
 		c.cancel()
 		delete(p.conns, node.Spec.Name)
 	}
 
-	client, err := p.dial(node)
+	if limit := p.limits.MaxConns; limit > 0 && len(p.conns) >= limit && !p.evictIdle() {
+		return nil, false, fmt.Errorf("ssh pool full: %d connections in use (ssh.max_connections)", limit)
+	}
+
+	client, err := p.dial(ctx, node)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	connCtx, cancel := context.WithCancel(context.Background())
@@ -70,17 +136,21 @@ func (p *Pool) Connect(ctx context.Context, node v1.NodeInfo) (*ssh.Client, erro
 		lastUsed: time.Now(),
 		cancel:   cancel,
 	}
+	if p.limits.MaxSessions > 0 {
+		conn.sessions = make(chan struct{}, p.limits.MaxSessions)
+	}
 	p.conns[node.Spec.Name] = conn
 
 	// Background keepalive goroutine
 	go p.keepalive(connCtx, node.Spec.Name, client)
 
 	p.log.Info("ssh connected", "node", node.Spec.Name, "host", node.Spec.Host)
-	return client, nil
+	return conn, true, nil
 }
 
-// dial opens a new SSH connection to node based on its spec.
-func (p *Pool) dial(node v1.NodeInfo) (*ssh.Client, error) {
+// dial opens a new SSH connection to node based on its spec, retrying
+// while the node refuses or drops the connection.
+func (p *Pool) dial(ctx context.Context, node v1.NodeInfo) (*ssh.Client, error) {
 	keyPath := node.Spec.Key
 	if keyPath == "" {
 		return nil, fmt.Errorf("no SSH key configured for node %q", node.Spec.Name)
@@ -92,44 +162,191 @@ func (p *Pool) dial(node v1.NodeInfo) (*ssh.Client, error) {
 	}
 	addr := net.JoinHostPort(node.Spec.Host, fmt.Sprintf("%d", port))
 
-	// Use InsecureIgnoreHostKey for initial connections; proper known_hosts for subsequent.
 	cfg, err := sshutil.ClientConfig(node.Spec.User, keyPath, "")
 	if err != nil {
 		return nil, fmt.Errorf("ssh config for node %q: %w", node.Spec.Name, err)
 	}
+	fingerprint := ""
+	if node.HostKeyKnown {
+		fingerprint = node.KeyFingerprint
+	}
+	if cfg.HostKeyCallback, err = p.hostKeyCallback(node.Spec.Name, fingerprint); err != nil {
+		return nil, err
+	}
+
+	policy := retry.Policy{
+		Attempts:  3,
+		Retryable: dialRetryable,
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			p.log.Warn("ssh dial failed, retrying", "node", node.Spec.Name, "attempt", attempt, "wait", wait, "err", err)
+		},
+	}
+	return retry.Value(ctx, policy, func(context.Context) (*ssh.Client, error) {
+		if node.Spec.ProxyJump == "" {
+			return sshutil.Dial(addr, cfg)
+		}
+		return p.dialJump(node, addr, cfg)
+	})
+}
+
+// dialRetryable reports whether a failed dial is worth repeating: the
+// connection was refused or reset, or sshd dropped it during the handshake
+// (as it does past MaxStartups). A timeout is not: the dial already waited
+// sshutil.ConnectTimeout.
+func dialRetryable(err error) bool {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return false
+	}
+	return errs.Retryable(err) || errors.Is(err, io.EOF)
+}
+
+// dialJump reaches addr through the node's ProxyJump chain. Bastions
+// authenticate with the node's key; their connections are closed when the
+// target connection ends.
+func (p *Pool) dialJump(node v1.NodeInfo, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+	hops, err := parseProxyJump(node.Spec.ProxyJump, node.Spec.User)
+	if err != nil {
+		return nil, fmt.Errorf("node %q: %w", node.Spec.Name, err)
+	}
+
+	var chain []*ssh.Client
+	closeChain := func() {
+		for i := len(chain) - 1; i >= 0; i-- {
+			chain[i].Close()
+		}
+	}
+	for _, hop := range hops {
+		hopCfg, err := sshutil.ClientConfig(hop.user, node.Spec.Key, "")
+		if err == nil {
+			hopCfg.HostKeyCallback, err = p.hostKeyCallback(node.Spec.Name, "")
+		}
+		if err != nil {
+			closeChain()
+			return nil, fmt.Errorf("ssh config for jump host %q: %w", hop.addr, err)
+		}
+		var c *ssh.Client
+		if len(chain) == 0 {
+			c, err = sshutil.Dial(hop.addr, hopCfg)
+		} else {
+			c, err = sshutil.DialVia(chain[len(chain)-1], hop.addr, hopCfg)
+		}
+		if err != nil {
+			closeChain()
+			return nil, fmt.Errorf("jump host: %w", err)
+		}
+		p.log.Debug("ssh jump host connected", "node", node.Spec.Name, "via", hop.addr)
+		chain = append(chain, c)
+	}
+
+	client, err := sshutil.DialVia(chain[len(chain)-1], addr, cfg)
+	if err != nil {
+		closeChain()
+		return nil, err
+	}
+	go func() {
+		client.Wait() //nolint:errcheck
+		closeChain()
+	}()
+	return client, nil
+}
 
-	// Override host key callback if node has a recorded host key
-	if node.HostKeyKnown && node.HostKey != "" {
-		cfg.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			got := sshutil.FingerprintMD5(key)
-			expect := node.KeyFingerprint
-			if got != expect {
-				return fmt.Errorf("host key mismatch for %s: got %s, expected %s", hostname, got, expect)
+// hostKeyCallback verifies host keys against known_hosts. Hosts missing from
+// the file fall back to the fingerprint recorded by an older `orbit nodes
+// trust` (when given); otherwise they are refused in strict mode and accepted
+// with a warning if not.
+func (p *Pool) hostKeyCallback(node, fingerprint string) (ssh.HostKeyCallback, error) {
+	known, err := sshutil.KnownHosts(p.knownHosts)
+	if err != nil {
+		return nil, err
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := known(hostname, remote, key)
+		if !errors.Is(err, sshutil.ErrUnknownHost) {
+			if err != nil {
+				return fmt.Errorf("host key verification failed for %s: %w", hostname, err)
 			}
 			return nil
 		}
-	}
+		if fingerprint != "" {
+			if got := sshutil.FingerprintMD5(key); got != fingerprint {
+				return fmt.Errorf("host key mismatch for %s: got %s, expected %s", hostname, got, fingerprint)
+			}
+			return nil
+		}
+		if p.strict {
+			return fmt.Errorf("host key for %s is not trusted — run `orbit nodes trust %s`", hostname, node)
+		}
+		p.log.Warn("ssh host key not verified", "node", node, "host", hostname,
+			"fingerprint", sshutil.FingerprintMD5(key))
+		return nil
+	}, nil
+}
+
+// jumpHop is one bastion in a ProxyJump chain.
+type jumpHop struct {
+	user string
+	addr string // host:port
+}
 
-	return sshutil.Dial(addr, cfg)
+// parseProxyJump parses OpenSSH ProxyJump syntax, "[user@]host[:port]" hops
+// separated by commas. Hops without a user inherit defaultUser.
+func parseProxyJump(spec, defaultUser string) ([]jumpHop, error) {
+	var hops []jumpHop
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid proxy_jump %q: empty hop", spec)
+		}
+		user := defaultUser
+		if at := strings.LastIndex(part, "@"); at >= 0 {
+			user, part = part[:at], part[at+1:]
+		}
+		host, port := part, DefaultSSHPort
+		if h, ps, err := net.SplitHostPort(part); err == nil {
+			n, err := strconv.Atoi(ps)
+			if err != nil || n <= 0 || n > 65535 {
+				return nil, fmt.Errorf("invalid proxy_jump %q: bad port %q", spec, ps)
+			}
+			host, port = h, n
+		}
+		host = strings.Trim(host, "[]")
+		if host == "" || user == "" {
+			return nil, fmt.Errorf("invalid proxy_jump %q: hop needs a host and user", spec)
+		}
+		hops = append(hops, jumpHop{user: user, addr: net.JoinHostPort(host, strconv.Itoa(port))})
+	}
+	return hops, nil
 }
 
 // Run executes a command on the named node and returns its combined output.
 func (p *Pool) Run(ctx context.Context, node v1.NodeInfo, cmd string) (string, int, error) {
-	client, err := p.Connect(ctx, node)
+	client, release, err := p.acquire(ctx, node)
 	if err != nil {
 		return "", -1, err
 	}
+	defer release()
 	return sshutil.RunCommand(client, cmd)
 }
 
+// Stream executes a command on the named node with stdin/stdout attached,
+// e.g. to pipe a tar archive through `docker cp`.
+func (p *Pool) Stream(ctx context.Context, node v1.NodeInfo, cmd string, stdin io.Reader, stdout io.Writer) error {
+	client, release, err := p.acquire(ctx, node)
+	if err != nil {
+		return err
+	}
+	defer release()
+	_, err = sshutil.StreamCommand(client, cmd, stdin, stdout)
+	return err
+}
+
 // Disconnect closes the connection for a named node.
 func (p *Pool) Disconnect(name string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if c, ok := p.conns[name]; ok {
-		c.cancel()
-		c.client.Close()
-		delete(p.conns, name)
+		p.closeConn(c)
 		p.log.Info("ssh disconnected", "node", name)
 	}
 }
@@ -138,10 +355,12 @@ func (p *Pool) Disconnect(name string) {
 func (p *Pool) Close() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
 	for name, c := range p.conns {
-		c.cancel()
-		c.client.Close()
-		delete(p.conns, name)
+		p.closeConn(c)
 		p.log.Info("ssh connection closed", "node", name)
 	}
 }
@@ -0,0 +1,104 @@
+package remote
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/pkg/sshutil"
+)
+
+func TestParseProxyJump(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []jumpHop
+	}{
+		{"bastion", []jumpHop{{"deploy", "bastion:22"}}},
+		{"ops@bastion:2222", []jumpHop{{"ops", "bastion:2222"}}},
+		{"a@edge, inner:2200", []jumpHop{{"a", "edge:22"}, {"deploy", "inner:2200"}}},
+		{"[fd00::1]:22", []jumpHop{{"deploy", "[fd00::1]:22"}}},
+	}
+	for _, tt := range tests {
+		got, err := parseProxyJump(tt.spec, "deploy")
+		if err != nil {
+			t.Errorf("parseProxyJump(%q): %v", tt.spec, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseProxyJump(%q) = %v, want %v", tt.spec, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseProxyJump(%q)[%d] = %v, want %v", tt.spec, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	for _, bad := range []string{"", "a,,b", "host:0", "host:ssh", "@host"} {
+		if _, err := parseProxyJump(bad, ""); err == nil {
+			t.Errorf("parseProxyJump(%q): expected error", bad)
+		}
+	}
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	log, err := logger.Init("error", "text", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	trusted, other := newHostKey(t), newHostKey(t)
+	if err := sshutil.AddKnownHost(file, "prod-01:22", other); err != nil {
+		t.Fatal(err)
+	}
+	// Re-trusting replaces the old entry
+	if err := sshutil.AddKnownHost(file, "prod-01:22", trusted); err != nil {
+		t.Fatal(err)
+	}
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 22}
+
+	tests := []struct {
+		name   string
+		strict bool
+		host   string
+		key    ssh.PublicKey
+		fp     string
+		ok     bool
+	}{
+		{"known host", true, "prod-01:22", trusted, "", true},
+		{"key mismatch", false, "prod-01:22", other, "", false},
+		{"unknown lenient", false, "new:22", trusted, "", true},
+		{"unknown strict", true, "new:22", trusted, "", false},
+		{"legacy fingerprint", true, "new:22", trusted, sshutil.FingerprintMD5(trusted), true},
+		{"legacy mismatch", false, "new:22", other, sshutil.FingerprintMD5(trusted), false},
+	}
+	for _, tt := range tests {
+		pool := NewPool(log).WithKnownHosts(file).WithStrictHostKeys(tt.strict)
+		cb, err := pool.hostKeyCallback("node", tt.fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cb(tt.host, addr, tt.key); (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
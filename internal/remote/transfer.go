@@ -0,0 +1,362 @@
+// Package remote: file transfer and directory sync over SFTP.
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/sftp"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/sshutil"
+)
+
+// TransferProgress reports progress copying one file: done of total bytes.
+type TransferProgress func(file string, done, total int64)
+
+// SyncOptions tunes SyncDir.
+type SyncOptions struct {
+	Delete   bool // remove remote files that no longer exist locally
+	Progress TransferProgress
+}
+
+// SyncResult lists what SyncDir did, as slash-separated paths relative to
+// the synced directory.
+type SyncResult struct {
+	Uploaded []string
+	Skipped  []string
+	Deleted  []string
+}
+
+// sftpClient is an SFTP session; closing it gives its connection back to
+// the pool.
+type sftpClient struct {
+	*sftp.Client
+	release func() // nil when the session does not hold a pooled connection
+}
+
+// Close ends the session.
+func (c *sftpClient) Close() error {
+	err := c.Client.Close()
+	if c.release != nil {
+		c.release()
+	}
+	return err
+}
+
+// sftp opens an SFTP session on the node's pooled connection.
+func (p *Pool) sftp(ctx context.Context, node v1.NodeInfo) (*sftpClient, error) {
+	client, release, err := p.acquire(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	c, err := sftp.NewClient(client)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("sftp to %q: %w", node.Spec.Name, err)
+	}
+	return &sftpClient{Client: c, release: release}, nil
+}
+
+// Upload copies a local file to remotePath on node, creating parent
+// directories. It returns skipped=true without transferring anything when the
+// remote file already has the same content.
+func (p *Pool) Upload(ctx context.Context, node v1.NodeInfo, localPath, remotePath string, progress TransferProgress) (skipped bool, err error) {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return false, err
+	}
+	c, err := p.sftp(ctx, node)
+	if err != nil {
+		return false, err
+	}
+	defer c.Close()
+
+	if remote, err := c.Stat(remotePath); err == nil {
+		sums := p.remoteChecksums(ctx, node, []string{remotePath})
+		if same, err := sameContent(localPath, info, remote, sums[remotePath]); err != nil {
+			return false, err
+		} else if same {
+			return true, nil
+		}
+	}
+	return false, c.upload(localPath, info, remotePath, progress)
+}
+
+// Download copies remotePath on node to localPath, replacing it atomically.
+func (p *Pool) Download(ctx context.Context, node v1.NodeInfo, remotePath, localPath string, progress TransferProgress) error {
+	c, err := p.sftp(ctx, node)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	f, err := c.Open(remotePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(localPath), "."+filepath.Base(localPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, &progressReader{r: f, file: remotePath, total: info.Size(), progress: progress}); err != nil {
+		tmp.Close()
+		return fmt.Errorf("read %s: %w", remotePath, err)
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), localPath)
+}
+
+// SyncDir makes remoteDir on node mirror the regular files under localDir,
+// uploading only files whose content differs.
+func (p *Pool) SyncDir(ctx context.Context, node v1.NodeInfo, localDir, remoteDir string, opts SyncOptions) (*SyncResult, error) {
+	c, err := p.sftp(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	res, err := c.syncDir(ctx, localDir, remoteDir, opts, func(paths []string) map[string]string {
+		return p.remoteChecksums(ctx, node, paths)
+	})
+	if err != nil {
+		return res, err
+	}
+	p.log.Info("sftp.sync", "node", node.Spec.Name, "dir", remoteDir,
+		"uploaded", len(res.Uploaded), "skipped", len(res.Skipped), "deleted", len(res.Deleted))
+	return res, nil
+}
+
+// syncDir uploads the files under localDir that differ from remoteDir. checksums hashes remote paths; it may return a partial map.
+func (c *sftpClient) syncDir(ctx context.Context, localDir, remoteDir string, opts SyncOptions,
+	checksums func([]string) map[string]string) (*SyncResult, error) {
+	local, err := localFiles(localDir)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := c.walk(remoteDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hash only files whose size already matches
+	var candidates []string
+	for rel, info := range local {
+		if r, ok := remote[rel]; ok && r.Size() == info.Size() {
+			candidates = append(candidates, path.Join(remoteDir, rel))
+		}
+	}
+	sums := checksums(candidates)
+
+	rels := make([]string, 0, len(local))
+	for rel := range local {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+
+	res := &SyncResult{}
+	for _, rel := range rels {
+		info, lp, rp := local[rel], filepath.Join(localDir, filepath.FromSlash(rel)), path.Join(remoteDir, rel)
+		if r, ok := remote[rel]; ok {
+			same, err := sameContent(lp, info, r, sums[rp])
+			if err != nil {
+				return res, err
+			}
+			if same {
+				res.Skipped = append(res.Skipped, rel)
+				continue
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if err := c.upload(lp, info, rp, opts.Progress); err != nil {
+			return res, err
+		}
+		res.Uploaded = append(res.Uploaded, rel)
+	}
+
+	if opts.Delete {
+		var extra []string
+		for rel := range remote {
+			if _, ok := local[rel]; !ok {
+				extra = append(extra, rel)
+			}
+		}
+		sort.Strings(extra)
+		for _, rel := range extra {
+			if err := c.Remove(path.Join(remoteDir, rel)); err != nil {
+				return res, err
+			}
+			res.Deleted = append(res.Deleted, rel)
+		}
+	}
+	return res, nil
+}
+
+// upload writes a local file to remotePath, preserving its mode and mtime.
+func (c *sftpClient) upload(localPath string, info fs.FileInfo, remotePath string, progress TransferProgress) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := c.MkdirAll(path.Dir(remotePath)); err != nil {
+		return err
+	}
+	w, err := c.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, &progressReader{r: f, file: remotePath, total: info.Size(), progress: progress}); err != nil {
+		w.Close()
+		return fmt.Errorf("write %s: %w", remotePath, err)
+	}
+	if err := w.Chmod(info.Mode().Perm()); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Chtimes(remotePath, info.ModTime(), info.ModTime())
+}
+
+// progressReader reports to progress as a file's bytes are read.
+type progressReader struct {
+	r           io.Reader
+	file        string
+	done, total int64
+	progress    TransferProgress
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 && p.progress != nil {
+		p.done += int64(n)
+		p.progress(p.file, p.done, p.total)
+	}
+	return n, err
+}
+
+// localFiles lists the regular files under dir keyed by slash-separated
+// relative path.
+func localFiles(dir string) (map[string]fs.FileInfo, error) {
+	local := map[string]fs.FileInfo{}
+	err := filepath.WalkDir(dir, func(fp string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, fp)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		local[filepath.ToSlash(rel)] = info
+		return nil
+	})
+	return local, err
+}
+
+// walk lists the regular files under dir keyed by slash-separated relative
+// path. A missing dir yields an empty map.
+func (c *sftpClient) walk(dir string) (map[string]fs.FileInfo, error) {
+	out := map[string]fs.FileInfo{}
+	for w := c.Walk(dir); w.Step(); {
+		if err := w.Err(); err != nil {
+			if w.Path() == dir && errors.Is(err, fs.ErrNotExist) {
+				return out, nil
+			}
+			return nil, err
+		}
+		if w.Stat().Mode().IsRegular() {
+			rel := strings.TrimPrefix(strings.TrimPrefix(w.Path(), dir), "/")
+			out[rel] = w.Stat()
+		}
+	}
+	return out, nil
+}
+
+// remoteChecksums returns SHA-256 sums for the given remote paths, computed
+// on the node with sha256sum. Paths that could not be hashed are absent.
+func (p *Pool) remoteChecksums(ctx context.Context, node v1.NodeInfo, paths []string) map[string]string {
+	sums := map[string]string{}
+	if len(paths) == 0 {
+		return sums
+	}
+	quoted := make([]string, len(paths))
+	for i, rp := range paths {
+		quoted[i] = sshutil.Quote(rp)
+	}
+	out, _, err := p.Run(ctx, node, "sha256sum -- "+strings.Join(quoted, " ")+" 2>/dev/null")
+	if err != nil && out == "" {
+		p.log.Debug("sftp.checksum unavailable", "node", node.Spec.Name, "err", err)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		sum, file, ok := strings.Cut(line, "  ")
+		if ok && len(sum) == sha256.Size*2 {
+			sums[file] = sum
+		}
+	}
+	return sums
+}
+
+// sameContent decides whether a remote file matches a local one: by SHA-256
+// when the remote sum is known, otherwise by size and modification time.
+func sameContent(localPath string, info, remote fs.FileInfo, remoteSum string) (bool, error) {
+	if remote.Size() != info.Size() {
+		return false, nil
+	}
+	if remoteSum == "" {
+		return remote.ModTime().Unix() == info.ModTime().Unix(), nil
+	}
+	sum, err := fileSHA256(localPath)
+	if err != nil {
+		return false, err
+	}
+	return sum == remoteSum, nil
+}
+
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,173 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// startFakeSFTP serves root over SFTP in-process. Relative paths are
+// resolved under root.
+func startFakeSFTP(t *testing.T, root string) *sftpClient {
+	t.Helper()
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	srv, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{sr, sw}, sftp.WithServerWorkingDirectory(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve() //nolint:errcheck
+
+	client, err := sftp.NewClientPipe(cr, cw)
+	if err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	c := &sftpClient{Client: client}
+	t.Cleanup(func() { srv.Close(); c.Close() })
+	return c
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSFTPUploadRoundTrip(t *testing.T) {
+	local, remote := t.TempDir(), t.TempDir()
+	c := startFakeSFTP(t, remote)
+
+	// Larger than one packet to exercise offsets
+	content := string(make([]byte, 32*1024*2+17))
+	writeFile(t, filepath.Join(local, "big.bin"), content)
+	info, _ := os.Stat(filepath.Join(local, "big.bin"))
+
+	var last int64
+	progress := func(_ string, done, total int64) {
+		if total != info.Size() {
+			t.Errorf("total = %d, want %d", total, info.Size())
+		}
+		last = done
+	}
+	if err := c.upload(filepath.Join(local, "big.bin"), info, "nested/dir/big.bin", progress); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if last != info.Size() {
+		t.Errorf("final progress = %d, want %d", last, info.Size())
+	}
+
+	got, err := os.Stat(filepath.Join(remote, "nested/dir/big.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Size() != info.Size() || got.Mode().Perm() != 0640 {
+		t.Errorf("remote file size=%d mode=%v", got.Size(), got.Mode().Perm())
+	}
+	if !got.ModTime().Truncate(time.Second).Equal(info.ModTime().Truncate(time.Second)) {
+		t.Errorf("mtime not preserved: %v vs %v", got.ModTime(), info.ModTime())
+	}
+
+	a, err := c.Stat("nested/dir/big.bin")
+	if err != nil || a.Size() != info.Size() || !a.Mode().IsRegular() {
+		t.Fatalf("stat = %+v, %v", a, err)
+	}
+	if _, err := c.Stat("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("stat missing err = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestSFTPSyncDir(t *testing.T) {
+	local, remote := t.TempDir(), t.TempDir()
+	c := startFakeSFTP(t, remote)
+
+	writeFile(t, filepath.Join(local, "same.txt"), "unchanged")
+	writeFile(t, filepath.Join(local, "conf/app.yaml"), "new contents")
+	writeFile(t, filepath.Join(local, "added.txt"), "added")
+	writeFile(t, filepath.Join(remote, "app/same.txt"), "unchanged")
+	writeFile(t, filepath.Join(remote, "app/conf/app.yaml"), "old contents")
+	writeFile(t, filepath.Join(remote, "app/stale.txt"), "stale")
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(remote, "app/same.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// Checksums come from the local copy of the remote tree
+	checksums := func(paths []string) map[string]string {
+		sums := map[string]string{}
+		for _, p := range paths {
+			sum, err := fileSHA256(filepath.Join(remote, p))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sums[p] = sum
+		}
+		return sums
+	}
+
+	res, err := c.syncDir(context.Background(), local, "app", SyncOptions{Delete: true}, checksums)
+	if err != nil {
+		t.Fatalf("syncDir: %v", err)
+	}
+	assertList(t, "uploaded", res.Uploaded, "added.txt", "conf/app.yaml")
+	assertList(t, "skipped", res.Skipped, "same.txt")
+	assertList(t, "deleted", res.Deleted, "stale.txt")
+
+	data, _ := os.ReadFile(filepath.Join(remote, "app/conf/app.yaml"))
+	if string(data) != "new contents" {
+		t.Errorf("app.yaml = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "app/stale.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("stale.txt not deleted: %v", err)
+	}
+
+	// Without checksums, files fall back to size+mtime: uploads carried the
+	// local mtime over, the pre-existing same.txt did not
+	res, err = c.syncDir(context.Background(), local, "app", SyncOptions{},
+		func([]string) map[string]string { return nil })
+	if err != nil {
+		t.Fatalf("second syncDir: %v", err)
+	}
+	assertList(t, "uploaded", res.Uploaded, "same.txt")
+	assertList(t, "skipped", res.Skipped, "added.txt", "conf/app.yaml")
+}
+
+func TestSFTPSyncDirMissingRemote(t *testing.T) {
+	local, remote := t.TempDir(), t.TempDir()
+	c := startFakeSFTP(t, remote)
+	writeFile(t, filepath.Join(local, "a.txt"), "a")
+
+	res, err := c.syncDir(context.Background(), local, "fresh", SyncOptions{},
+		func([]string) map[string]string { return nil })
+	if err != nil {
+		t.Fatalf("syncDir: %v", err)
+	}
+	assertList(t, "uploaded", res.Uploaded, "a.txt")
+}
+
+func assertList(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Package scan: failing CI runs and deploys on vulnerable images.
+package scan
+
+import (
+	"context"
+	"strings"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// Check fails when r has findings of severity failOn or more, naming the
+// worst of them.
+func Check(r Report, failOn Severity) error {
+	found := r.AtLeast(failOn)
+	if len(found) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, 3)
+	for _, f := range found {
+		if len(ids) == cap(ids) {
+			ids = append(ids, "…")
+			break
+		}
+		ids = append(ids, f.ID)
+	}
+	return errs.Newf(errs.ErrImageVulnerable, "scan.check",
+		"%s: %d vulnerabilities rated %s or worse (%s)", r.Image, len(found), strings.ToLower(string(failOn)), strings.Join(ids, ", ")).
+		WithAdvice("Run `orbit scan` for the full report, and move to an image with the fixes")
+}
+
+// Gate returns a pre-deploy policy that scans each image before it is
+// deployed and refuses it when Check fails. A scan that cannot run refuses
+// the deploy too: an image that was not scanned is not let through.
+func (s *Scanner) Gate(failOn Severity, opts Options) func(ctx context.Context, spec v1.ServiceSpec, image string) error {
+	return func(ctx context.Context, spec v1.ServiceSpec, image string) error {
+		r, err := s.Scan(ctx, image, opts)
+		if err != nil {
+			return errs.New(errs.ErrImageScan, "deploy.scan", err).WithNode(spec.Name)
+		}
+		return Check(r, failOn)
+	}
+}
@@ -0,0 +1,107 @@
+// Package scan: reading the JSON reports of Trivy and Grype.
+package scan
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// parseTrivy reads `trivy image --format json`. A vulnerability reported
+// for several targets of the image — the OS and a language lockfile, say —
+// is listed once.
+func parseTrivy(data []byte) ([]Finding, error) {
+	var report struct {
+		Results []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID  string
+				PkgName          string
+				InstalledVersion string
+				FixedVersion     string
+				Severity         string
+				Title            string
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	seen := map[string]bool{}
+	for _, r := range report.Results {
+		for _, v := range r.Vulnerabilities {
+			key := v.VulnerabilityID + " " + v.PkgName + " " + v.InstalledVersion
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			findings = append(findings, Finding{
+				ID:        v.VulnerabilityID,
+				Package:   v.PkgName,
+				Installed: v.InstalledVersion,
+				Fixed:     v.FixedVersion,
+				Severity:  severityOf(v.Severity),
+				Title:     v.Title,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// parseGrype reads `grype -o json`.
+func parseGrype(data []byte) ([]Finding, error) {
+	var report struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID          string `json:"id"`
+				Severity    string `json:"severity"`
+				Description string `json:"description"`
+				Fix         struct {
+					Versions []string `json:"versions"`
+					State    string   `json:"state"`
+				} `json:"fix"`
+			} `json:"vulnerability"`
+			Artifact struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"artifact"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, m := range report.Matches {
+		v := m.Vulnerability
+		f := Finding{
+			ID:        v.ID,
+			Package:   m.Artifact.Name,
+			Installed: m.Artifact.Version,
+			Severity:  severityOf(v.Severity),
+			Title:     firstSentence(v.Description),
+		}
+		if v.Fix.State == "fixed" {
+			f.Fixed = strings.Join(v.Fix.Versions, ", ")
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// severityOf reads a scanner's severity, as Unknown when it is not one.
+func severityOf(s string) Severity {
+	sev, err := ParseSeverity(s)
+	if err != nil {
+		return Unknown
+	}
+	return sev
+}
+
+// firstSentence shortens a Grype description to a title.
+func firstSentence(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, ". "); i >= 0 {
+		s = s[:i]
+	}
+	s, _, _ = strings.Cut(s, "\n")
+	return strings.TrimSuffix(s, ".")
+}
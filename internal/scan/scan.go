@@ -0,0 +1,202 @@
+// Package scan checks container images for known vulnerabilities by running
+// Trivy or Grype, whichever is installed, and reading its JSON report.
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Scanners orbit knows how to run, in the order they are looked for.
+const (
+	Trivy = "trivy"
+	Grype = "grype"
+)
+
+// Severity is a vulnerability's severity, as the scanners rate it.
+type Severity string
+
+const (
+	Critical Severity = "CRITICAL"
+	High     Severity = "HIGH"
+	Medium   Severity = "MEDIUM"
+	Low      Severity = "LOW"
+	Unknown  Severity = "UNKNOWN"
+)
+
+// Severities lists every severity, the most severe first.
+var Severities = []Severity{Critical, High, Medium, Low, Unknown}
+
+// ParseSeverity reads a severity in any case. Grype's Negligible counts as
+// Low.
+func ParseSeverity(s string) (Severity, error) {
+	sev := Severity(strings.ToUpper(strings.TrimSpace(s)))
+	if sev == "NEGLIGIBLE" {
+		return Low, nil
+	}
+	for _, known := range Severities {
+		if sev == known {
+			return sev, nil
+		}
+	}
+	return "", fmt.Errorf("unknown severity %q (want critical, high, medium, low or unknown)", s)
+}
+
+// rank orders severities: Critical is 4, Unknown 0.
+func (s Severity) rank() int {
+	for i, known := range Severities {
+		if s == known {
+			return len(Severities) - 1 - i
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether s is as severe as min or more.
+func (s Severity) AtLeast(min Severity) bool {
+	return s.rank() >= min.rank()
+}
+
+// Finding is one vulnerability in one package of an image.
+type Finding struct {
+	ID        string   `json:"id"`
+	Package   string   `json:"package"`
+	Installed string   `json:"installed"`
+	Fixed     string   `json:"fixed,omitempty"` // the version that fixes it; "" when there is no fix yet
+	Severity  Severity `json:"severity"`
+	Title     string   `json:"title,omitempty"`
+}
+
+// Report is the result of scanning one image.
+type Report struct {
+	Image    string    `json:"image"`
+	Scanner  string    `json:"scanner"`
+	Findings []Finding `json:"findings"`
+}
+
+// Counts returns how many findings there are of each severity.
+func (r Report) Counts() map[Severity]int {
+	counts := map[Severity]int{}
+	for _, f := range r.Findings {
+		counts[f.Severity]++
+	}
+	return counts
+}
+
+// AtLeast returns the findings of severity min or more.
+func (r Report) AtLeast(min Severity) []Finding {
+	var found []Finding
+	for _, f := range r.Findings {
+		if f.Severity.AtLeast(min) {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+// Options tune a scan.
+type Options struct {
+	IgnoreUnfixed bool // leave out vulnerabilities with no fixed version
+}
+
+// Scanner runs one vulnerability scanner.
+type Scanner struct {
+	name string
+	path string
+	run  func(ctx context.Context, path string, args ...string) ([]byte, error)
+}
+
+// Detect returns the scanner called name, or the first of Trivy and Grype
+// installed when name is "". The scanner must be on $PATH.
+func Detect(name string) (*Scanner, error) {
+	names := []string{Trivy, Grype}
+	if name != "" {
+		if name != Trivy && name != Grype {
+			return nil, fmt.Errorf("unknown scanner %q (want trivy or grype)", name)
+		}
+		names = []string{name}
+	}
+	for _, n := range names {
+		if path, err := exec.LookPath(n); err == nil {
+			return &Scanner{name: n, path: path, run: runCommand}, nil
+		}
+	}
+	if name != "" {
+		return nil, fmt.Errorf("%s is not installed (not found on $PATH)", name)
+	}
+	return nil, fmt.Errorf("no vulnerability scanner found; install trivy or grype")
+}
+
+// Name is the scanner's name: trivy or grype.
+func (s *Scanner) Name() string { return s.name }
+
+// Scan scans image, which the scanner pulls from its registry unless the
+// local Docker daemon has it.
+func (s *Scanner) Scan(ctx context.Context, image string, opts Options) (Report, error) {
+	var args []string
+	var parse func([]byte) ([]Finding, error)
+	switch s.name {
+	case Trivy:
+		args, parse = []string{"image", "--format", "json", "--quiet", image}, parseTrivy
+	case Grype:
+		args, parse = []string{image, "-o", "json", "-q"}, parseGrype
+	}
+	out, err := s.run(ctx, s.path, args...)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s %s: %w", s.name, image, err)
+	}
+	findings, err := parse(out)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s %s: reading report: %w", s.name, image, err)
+	}
+	if opts.IgnoreUnfixed {
+		findings = fixable(findings)
+	}
+	sortFindings(findings)
+	return Report{Image: image, Scanner: s.name, Findings: findings}, nil
+}
+
+// runCommand runs path with args, returning its stdout. A failure carries
+// the last line the command wrote to stderr.
+func runCommand(ctx context.Context, path string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+			return nil, fmt.Errorf("%w: %s", err, last)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// fixable drops the findings with no fixed version.
+func fixable(findings []Finding) []Finding {
+	var kept []Finding
+	for _, f := range findings {
+		if f.Fixed != "" {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// sortFindings orders findings most severe first, then by package and ID.
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity != b.Severity {
+			return a.Severity.rank() > b.Severity.rank()
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.ID < b.ID
+	})
+}
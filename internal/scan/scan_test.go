@@ -0,0 +1,110 @@
+package scan
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+const trivyReport = `{
+  "ArtifactName": "acme/web:1.0",
+  "Results": [
+    {"Target": "acme/web:1.0 (debian 12.4)", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2024-0002", "PkgName": "zlib1g", "InstalledVersion": "1.2.13", "Severity": "LOW", "Title": "zlib: overflow"},
+      {"VulnerabilityID": "CVE-2024-0001", "PkgName": "openssl", "InstalledVersion": "3.0.11", "FixedVersion": "3.0.13", "Severity": "CRITICAL", "Title": "openssl: bad"}
+    ]},
+    {"Target": "app/package-lock.json", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2024-0001", "PkgName": "openssl", "InstalledVersion": "3.0.11", "FixedVersion": "3.0.13", "Severity": "CRITICAL"},
+      {"VulnerabilityID": "GHSA-xxxx", "PkgName": "lodash", "InstalledVersion": "4.17.20", "FixedVersion": "4.17.21", "Severity": "HIGH"}
+    ]},
+    {"Target": "app/go.sum"}
+  ]
+}`
+
+const grypeReport = `{
+  "matches": [
+    {"vulnerability": {"id": "CVE-2024-0003", "severity": "Negligible", "description": "Meh.", "fix": {"versions": [], "state": "not-fixed"}},
+     "artifact": {"name": "bash", "version": "5.2"}},
+    {"vulnerability": {"id": "CVE-2024-0004", "severity": "Medium", "description": "A flaw was found in curl. It leaks.", "fix": {"versions": ["8.5.0"], "state": "fixed"}},
+     "artifact": {"name": "curl", "version": "8.4.0"}}
+  ]
+}`
+
+func fakeScanner(name, report string) *Scanner {
+	return &Scanner{name: name, path: name, run: func(context.Context, string, ...string) ([]byte, error) {
+		return []byte(report), nil
+	}}
+}
+
+func TestScanTrivy(t *testing.T) {
+	r, err := fakeScanner(Trivy, trivyReport).Scan(context.Background(), "acme/web:1.0", Options{})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	var ids []string
+	for _, f := range r.Findings {
+		ids = append(ids, f.ID)
+	}
+	if got := strings.Join(ids, " "); got != "CVE-2024-0001 GHSA-xxxx CVE-2024-0002" {
+		t.Errorf("findings = %s", got)
+	}
+	if c := r.Counts(); c[Critical] != 1 || c[High] != 1 || c[Low] != 1 {
+		t.Errorf("counts = %v", c)
+	}
+	if f := r.Findings[0]; f.Package != "openssl" || f.Fixed != "3.0.13" || f.Title != "openssl: bad" {
+		t.Errorf("first finding = %+v", f)
+	}
+
+	r, _ = fakeScanner(Trivy, trivyReport).Scan(context.Background(), "acme/web:1.0", Options{IgnoreUnfixed: true})
+	if len(r.Findings) != 2 {
+		t.Errorf("unfixed kept: %+v", r.Findings)
+	}
+}
+
+func TestScanGrype(t *testing.T) {
+	r, err := fakeScanner(Grype, grypeReport).Scan(context.Background(), "acme/web:1.0", Options{})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if len(r.Findings) != 2 {
+		t.Fatalf("findings = %+v", r.Findings)
+	}
+	if f := r.Findings[0]; f.ID != "CVE-2024-0004" || f.Severity != Medium || f.Fixed != "8.5.0" || f.Title != "A flaw was found in curl" {
+		t.Errorf("first finding = %+v", f)
+	}
+	if f := r.Findings[1]; f.Severity != Low || f.Fixed != "" {
+		t.Errorf("negligible finding = %+v", f)
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	if s, err := ParseSeverity("high"); err != nil || s != High {
+		t.Errorf("ParseSeverity(high) = %s, %v", s, err)
+	}
+	if _, err := ParseSeverity("severe"); err == nil {
+		t.Error("an unknown severity parsed")
+	}
+	if !Critical.AtLeast(High) || !High.AtLeast(High) || Medium.AtLeast(High) || !Unknown.AtLeast(Unknown) {
+		t.Error("AtLeast misorders severities")
+	}
+}
+
+func TestGate(t *testing.T) {
+	spec := v1.ServiceSpec{Name: "web"}
+	gate := fakeScanner(Trivy, trivyReport).Gate(Critical, Options{})
+	err := gate(context.Background(), spec, "acme/web:1.0")
+	if !errs.IsCode(err, errs.ErrImageVulnerable) || !strings.Contains(err.Error(), "CVE-2024-0001") {
+		t.Errorf("gate = %v", err)
+	}
+	if err := fakeScanner(Grype, grypeReport).Gate(High, Options{})(context.Background(), spec, "acme/web:1.0"); err != nil {
+		t.Errorf("gate passed nothing above medium: %v", err)
+	}
+
+	broken := &Scanner{name: Trivy, run: func(context.Context, string, ...string) ([]byte, error) { return []byte("not json"), nil }}
+	if err := broken.Gate(Critical, Options{})(context.Background(), spec, "acme/web:1.0"); !errs.IsCode(err, errs.ErrImageScan) {
+		t.Errorf("an unreadable scan let the deploy through: %v", err)
+	}
+}
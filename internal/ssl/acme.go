@@ -0,0 +1,315 @@
+// Package ssl issues certificates from an ACME CA such as Let's Encrypt.
+package ssl
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+
+	"github.com/f9-o/orbit/internal/core/logger"
+	"github.com/f9-o/orbit/pkg/retry"
+)
+
+// DefaultTimeout bounds one issuance when ssl.timeout is unset: DNS
+// propagation plus validation by the CA.
+const DefaultTimeout = 5 * time.Minute
+
+// propagationInterval is how often a challenge record is looked up while
+// waiting for it to become visible, for at most propagationTimeout.
+var (
+	propagationInterval = 5 * time.Second
+	propagationTimeout  = 2 * time.Minute
+)
+
+// Certificate is the result of a successful issuance.
+type Certificate struct {
+	Domains  []string
+	CertFile string // leaf followed by the issuer chain
+	KeyFile  string
+	NotAfter time.Time
+}
+
+// Issuer obtains certificates from an ACME directory and writes them under
+// the cert dir as laid out by CertPaths.
+type Issuer struct {
+	directory  string
+	email      string
+	certDir    string
+	accountKey string
+	dns        Provider
+	timeout    time.Duration
+	log        *logger.Logger
+
+	// lookupTXT resolves challenge records while waiting for propagation.
+	lookupTXT func(ctx context.Context, name string) ([]string, error)
+}
+
+// NewIssuer returns an Issuer for the ACME directory URL. The account key is
+// kept in accountKeyFile and created on first use.
+func NewIssuer(directory, email, certDir, accountKeyFile string, log *logger.Logger) *Issuer {
+	return &Issuer{
+		directory:  directory,
+		email:      email,
+		certDir:    certDir,
+		accountKey: accountKeyFile,
+		timeout:    DefaultTimeout,
+		log:        log,
+		lookupTXT:  net.DefaultResolver.LookupTXT,
+	}
+}
+
+// WithDNS answers challenges with DNS-01 records published through p.
+func (is *Issuer) WithDNS(p Provider) *Issuer {
+	is.dns = p
+	return is
+}
+
+// WithTimeout bounds each issuance; zero keeps DefaultTimeout.
+func (is *Issuer) WithTimeout(d time.Duration) *Issuer {
+	if d > 0 {
+		is.timeout = d
+	}
+	return is
+}
+
+// Issue obtains one certificate covering domains; the first names the
+// files. Wildcards ("*.example.com") need a DNS provider.
+func (is *Issuer) Issue(ctx context.Context, domains []string) (*Certificate, error) {
+	if len(domains) == 0 {
+		return nil, errors.New("no domains to issue a certificate for")
+	}
+	if is.dns == nil {
+		return nil, errors.New("no challenge solver: set ssl.dns_provider for DNS-01")
+	}
+	ctx, cancel := context.WithTimeout(ctx, is.timeout)
+	defer cancel()
+
+	key, err := loadOrCreateKey(is.accountKey)
+	if err != nil {
+		return nil, fmt.Errorf("ACME account key: %w", err)
+	}
+	client := &acme.Client{Key: key, DirectoryURL: is.directory, UserAgent: "orbit"}
+	acct := &acme.Account{}
+	if is.email != "" {
+		acct.Contact = []string{"mailto:" + is.email}
+	}
+	err = retry.Do(ctx, is.retryPolicy("register"), func(ctx context.Context) error {
+		_, err := client.Register(ctx, acct, acme.AcceptTOS)
+		return err
+	})
+	if err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("ACME account: %w", err)
+	}
+
+	order, err := retry.Value(ctx, is.retryPolicy("order"), func(ctx context.Context) (*acme.Order, error) {
+		return client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ACME order: %w", err)
+	}
+	// Authorizations are solved one at a time: a name and its wildcard share
+	// the _acme-challenge record, and some providers replace rather than add.
+	for _, u := range order.AuthzURLs {
+		if err := is.authorize(ctx, client, u); err != nil {
+			return nil, err
+		}
+	}
+	err = retry.Do(ctx, is.retryPolicy("wait order"), func(ctx context.Context) error {
+		_, err := client.WaitOrder(ctx, order.URI)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ACME order: %w", err)
+	}
+
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}, certKey)
+	if err != nil {
+		return nil, err
+	}
+	der, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, fmt.Errorf("ACME finalize: %w", err)
+	}
+	return writeCert(is.certDir, domains, der, certKey)
+}
+
+// retryPolicy retries an ACME request, op, that failed on the network. The
+// acme client itself already retries the CA's 5xx and rate-limit replies.
+func (is *Issuer) retryPolicy(op string) retry.Policy {
+	return retry.Policy{
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			is.log.Warn("ACME request failed, retrying", "op", op, "attempt", attempt, "wait", wait, "err", err)
+		},
+	}
+}
+
+// authorize answers the DNS-01 challenge of one authorization and waits
+// for the CA to validate it.
+func (is *Issuer) authorize(ctx context.Context, client *acme.Client, url string) error {
+	z, err := retry.Value(ctx, is.retryPolicy("authorization"), func(ctx context.Context) (*acme.Authorization, error) {
+		return client.GetAuthorization(ctx, url)
+	})
+	if err != nil {
+		return fmt.Errorf("ACME authorization: %w", err)
+	}
+	if z.Status == acme.StatusValid {
+		return nil
+	}
+	var chal *acme.Challenge
+	for _, c := range z.Challenges {
+		if c.Type == "dns-01" {
+			chal = c
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("%s: CA offered no dns-01 challenge", z.Identifier.Value)
+	}
+	value, err := client.DNS01ChallengeRecord(chal.Token)
+	if err != nil {
+		return err
+	}
+
+	fqdn := "_acme-challenge." + z.Identifier.Value
+	is.log.Debug("dns-01 present", "record", fqdn)
+	if err := is.dns.Present(ctx, fqdn, value); err != nil {
+		return fmt.Errorf("%s: publish challenge record: %w", z.Identifier.Value, err)
+	}
+	defer func() {
+		// The order may have timed out; clean up regardless.
+		cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+		if err := is.dns.CleanUp(cctx, fqdn, value); err != nil {
+			is.log.Warn("dns-01 challenge record not removed", "record", fqdn, "err", err)
+		}
+	}()
+
+	is.waitPropagation(ctx, fqdn, value)
+	if _, err := client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("%s: accept challenge: %w", z.Identifier.Value, err)
+	}
+	if _, err := client.WaitAuthorization(ctx, z.URI); err != nil {
+		return fmt.Errorf("%s: validation failed: %w", z.Identifier.Value, err)
+	}
+	return nil
+}
+
+// waitPropagation polls until fqdn resolves to value. Resolvers may cache a
+// negative answer, so a record that is never seen locally is not an error:
+// the CA queries the authoritative servers and gets the final word.
+func (is *Issuer) waitPropagation(ctx context.Context, fqdn, value string) {
+	ctx, cancel := context.WithTimeout(ctx, propagationTimeout)
+	defer cancel()
+	for {
+		records, _ := is.lookupTXT(ctx, fqdn)
+		for _, r := range records {
+			if r == value {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			is.log.Debug("dns-01 record not visible yet, asking the CA anyway", "record", fqdn)
+			return
+		case <-time.After(propagationInterval):
+		}
+	}
+}
+
+// writeCert stores the chain and key of a certificate for domains under
+// certDir and returns the issued Certificate.
+func writeCert(certDir string, domains []string, der [][]byte, key *ecdsa.PrivateKey) (*Certificate, error) {
+	leaf, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		return nil, fmt.Errorf("issued certificate: %w", err)
+	}
+	certFile, keyFile := CertPaths(certDir, domains[0])
+	if err := os.MkdirAll(filepath.Dir(certFile), 0o700); err != nil {
+		return nil, err
+	}
+
+	var chain []byte
+	for _, b := range der {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: b})...)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	cert := &Certificate{
+		Domains:  domains,
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		NotAfter: leaf.NotAfter,
+	}
+	if err := os.WriteFile(cert.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(cert.CertFile, chain, 0o644); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// CertPaths returns where the certificate chain and key for domain live
+// under certDir. Wildcards use a "_wildcard.<domain>" directory, which no
+// real host name can clash with.
+func CertPaths(certDir, domain string) (certFile, keyFile string) {
+	if rest, ok := strings.CutPrefix(domain, "*."); ok {
+		domain = "_wildcard." + rest
+	}
+	dir := filepath.Join(certDir, domain)
+	return filepath.Join(dir, "fullchain.pem"), filepath.Join(dir, "privkey.pem")
+}
+
+// loadOrCreateKey reads a PEM EC private key from path, generating and
+// saving one when the file does not exist.
+func loadOrCreateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: not a PEM file", path)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
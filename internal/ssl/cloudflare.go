@@ -0,0 +1,100 @@
+// Package ssl: Cloudflare DNS provider.
+package ssl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// cloudflare manages records through the v4 API with a scoped API token
+// (Zone:Read and DNS:Edit).
+type cloudflare struct {
+	api apiClient
+}
+
+func newCloudflare(creds map[string]string) (Provider, error) {
+	token, err := credential("cloudflare", creds, "api_token", "CLOUDFLARE_API_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	return &cloudflare{api: newAPIClient(cloudflareAPI, http.Header{"Authorization": {"Bearer " + token}})}, nil
+}
+
+// cfResponse is the envelope of every v4 API response. Result holds a
+// pointer to decode the payload into.
+type cfResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result any `json:"result"`
+}
+
+type cfRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl,omitempty"`
+}
+
+func (c *cloudflare) call(ctx context.Context, method, path string, in any, result any) error {
+	resp := cfResponse{Result: result}
+	if err := c.api.do(ctx, method, path, in, &resp); err != nil {
+		return fmt.Errorf("cloudflare: %w", err)
+	}
+	if !resp.Success {
+		msg := "request failed"
+		if len(resp.Errors) > 0 {
+			msg = resp.Errors[0].Message
+		}
+		return fmt.Errorf("cloudflare: %s %s: %s", method, path, msg)
+	}
+	return nil
+}
+
+func (c *cloudflare) zoneID(ctx context.Context, fqdn string) (string, error) {
+	for _, name := range zoneCandidates(fqdn) {
+		var zones []struct {
+			ID string `json:"id"`
+		}
+		if err := c.call(ctx, http.MethodGet, "/zones?name="+url.QueryEscape(name), nil, &zones); err != nil {
+			return "", err
+		}
+		if len(zones) > 0 {
+			return zones[0].ID, nil
+		}
+	}
+	return "", fmt.Errorf("cloudflare: no zone found for %s", fqdn)
+}
+
+func (c *cloudflare) Present(ctx context.Context, fqdn, value string) error {
+	zone, err := c.zoneID(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	rec := cfRecord{Type: "TXT", Name: fqdn, Content: value, TTL: challengeTTL}
+	return c.call(ctx, http.MethodPost, "/zones/"+zone+"/dns_records", rec, nil)
+}
+
+func (c *cloudflare) CleanUp(ctx context.Context, fqdn, value string) error {
+	zone, err := c.zoneID(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	q := url.Values{"type": {"TXT"}, "name": {fqdn}, "content": {value}}
+	var recs []cfRecord
+	if err := c.call(ctx, http.MethodGet, "/zones/"+zone+"/dns_records?"+q.Encode(), nil, &recs); err != nil {
+		return err
+	}
+	for _, r := range recs {
+		if err := c.call(ctx, http.MethodDelete, "/zones/"+zone+"/dns_records/"+r.ID, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package ssl: deSEC DNS provider.
+package ssl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const deSECAPI = "https://desec.io/api/v1"
+
+// deSECMinTTL is the lowest TTL deSEC accepts for an RRset.
+const deSECMinTTL = 3600
+
+// deSEC manages records through the deSEC API. Records are edited as whole
+// RRsets, so a challenge value is added to or removed from the TXT values
+// already published under the name.
+type deSEC struct {
+	api apiClient
+}
+
+func newDeSEC(creds map[string]string) (Provider, error) {
+	token, err := credential("desec", creds, "token", "DESEC_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	return &deSEC{api: newAPIClient(deSECAPI, http.Header{"Authorization": {"Token " + token}})}, nil
+}
+
+type deSECRRset struct {
+	Subname string   `json:"subname"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl"`
+	Records []string `json:"records"`
+}
+
+func (d *deSEC) domain(ctx context.Context, fqdn string) (string, error) {
+	var domains []struct {
+		Name string `json:"name"`
+	}
+	if err := d.api.do(ctx, http.MethodGet, "/domains/?owns_qname="+url.QueryEscape(fqdn), nil, &domains); err != nil {
+		return "", fmt.Errorf("desec: %w", err)
+	}
+	if len(domains) == 0 {
+		return "", fmt.Errorf("desec: no domain found for %s", fqdn)
+	}
+	return domains[0].Name, nil
+}
+
+// update applies edit to the TXT values of fqdn and writes the RRset back;
+// an empty result deletes it.
+func (d *deSEC) update(ctx context.Context, fqdn string, edit func([]string) []string) error {
+	domain, err := d.domain(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	sub := relativeName(fqdn, domain)
+	rrset := deSECRRset{Subname: sub, Type: "TXT", TTL: deSECMinTTL}
+	err = d.api.do(ctx, http.MethodGet, "/domains/"+domain+"/rrsets/"+sub+"/TXT/", nil, &rrset)
+	if err != nil && !errors.Is(err, errNotFound) {
+		return fmt.Errorf("desec: %w", err)
+	}
+	rrset.Records = edit(rrset.Records)
+	if err := d.api.do(ctx, http.MethodPut, "/domains/"+domain+"/rrsets/", []deSECRRset{rrset}, nil); err != nil {
+		return fmt.Errorf("desec: %w", err)
+	}
+	return nil
+}
+
+func (d *deSEC) Present(ctx context.Context, fqdn, value string) error {
+	quoted := strconv.Quote(value)
+	return d.update(ctx, fqdn, func(records []string) []string {
+		for _, r := range records {
+			if r == quoted {
+				return records
+			}
+		}
+		return append(records, quoted)
+	})
+}
+
+func (d *deSEC) CleanUp(ctx context.Context, fqdn, value string) error {
+	quoted := strconv.Quote(value)
+	return d.update(ctx, fqdn, func(records []string) []string {
+		kept := []string{}
+		for _, r := range records {
+			if r != quoted {
+				kept = append(kept, r)
+			}
+		}
+		return kept
+	})
+}
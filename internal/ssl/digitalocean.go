@@ -0,0 +1,83 @@
+// Package ssl: DigitalOcean DNS provider.
+package ssl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const digitalOceanAPI = "https://api.digitalocean.com/v2"
+
+// digitalOcean manages records of domains hosted on DigitalOcean DNS with a
+// personal access token.
+type digitalOcean struct {
+	api apiClient
+}
+
+func newDigitalOcean(creds map[string]string) (Provider, error) {
+	token, err := credential("digitalocean", creds, "token", "DO_AUTH_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	return &digitalOcean{api: newAPIClient(digitalOceanAPI, http.Header{"Authorization": {"Bearer " + token}})}, nil
+}
+
+type doRecord struct {
+	ID   int    `json:"id,omitempty"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Data string `json:"data"`
+	TTL  int    `json:"ttl,omitempty"`
+}
+
+func (d *digitalOcean) domain(ctx context.Context, fqdn string) (string, error) {
+	for _, name := range zoneCandidates(fqdn) {
+		err := d.api.do(ctx, http.MethodGet, "/domains/"+name, nil, nil)
+		switch {
+		case err == nil:
+			return name, nil
+		case !errors.Is(err, errNotFound):
+			return "", fmt.Errorf("digitalocean: %w", err)
+		}
+	}
+	return "", fmt.Errorf("digitalocean: no domain found for %s", fqdn)
+}
+
+func (d *digitalOcean) Present(ctx context.Context, fqdn, value string) error {
+	domain, err := d.domain(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	rec := doRecord{Type: "TXT", Name: relativeName(fqdn, domain), Data: value, TTL: challengeTTL}
+	if err := d.api.do(ctx, http.MethodPost, "/domains/"+domain+"/records", rec, nil); err != nil {
+		return fmt.Errorf("digitalocean: %w", err)
+	}
+	return nil
+}
+
+func (d *digitalOcean) CleanUp(ctx context.Context, fqdn, value string) error {
+	domain, err := d.domain(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	q := url.Values{"type": {"TXT"}, "name": {fqdn}}
+	var list struct {
+		Records []doRecord `json:"domain_records"`
+	}
+	if err := d.api.do(ctx, http.MethodGet, "/domains/"+domain+"/records?"+q.Encode(), nil, &list); err != nil {
+		return fmt.Errorf("digitalocean: %w", err)
+	}
+	for _, r := range list.Records {
+		if r.Data != value {
+			continue
+		}
+		if err := d.api.do(ctx, http.MethodDelete, "/domains/"+domain+"/records/"+strconv.Itoa(r.ID), nil, nil); err != nil {
+			return fmt.Errorf("digitalocean: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Package ssl: DNS-01 challenge providers.
+package ssl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Provider publishes and removes the TXT records that answer DNS-01
+// challenges. fqdn is the full record name without a trailing dot, e.g.
+// _acme-challenge.example.com, and value the record's content.
+type Provider interface {
+	Present(ctx context.Context, fqdn, value string) error
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// challengeTTL is the TTL of challenge records where the API lets us choose.
+const challengeTTL = 120
+
+// providers maps ssl.dns_provider names to their constructors.
+var providers = map[string]func(creds map[string]string) (Provider, error){
+	"cloudflare":   newCloudflare,
+	"route53":      newRoute53,
+	"digitalocean": newDigitalOcean,
+	"desec":        newDeSEC,
+}
+
+// ProviderNames lists the accepted ssl.dns_provider values, sorted.
+func ProviderNames() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider returns the named DNS provider. Credentials are read from
+// creds (ssl.dns_credentials), falling back to the environment variables
+// each provider's own tooling uses.
+func NewProvider(name string, creds map[string]string) (Provider, error) {
+	newFn, ok := providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown DNS provider %q (want %s)", name, strings.Join(ProviderNames(), ", "))
+	}
+	return newFn(creds)
+}
+
+// credential returns creds[key], or the value of env when that is empty.
+func credential(provider string, creds map[string]string, key, env string) (string, error) {
+	if v := creds[key]; v != "" {
+		return v, nil
+	}
+	if v := os.Getenv(env); v != "" {
+		return v, nil
+	}
+	return "", fmt.Errorf("%s: ssl.dns_credentials.%s (or %s) is required", provider, key, env)
+}
+
+// zoneCandidates returns the names a zone for fqdn could have, longest
+// first, skipping the record's own first label and the top-level domain:
+// _acme-challenge.a.example.com → a.example.com, example.com.
+func zoneCandidates(fqdn string) []string {
+	labels := strings.Split(strings.TrimSuffix(fqdn, "."), ".")
+	var out []string
+	for i := 1; i < len(labels)-1; i++ {
+		out = append(out, strings.Join(labels[i:], "."))
+	}
+	return out
+}
+
+// relativeName returns fqdn relative to zone ("_acme-challenge" for
+// _acme-challenge.example.com in example.com).
+func relativeName(fqdn, zone string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(fqdn, zone), ".")
+}
+
+// apiClient is the HTTP plumbing shared by the JSON-speaking providers.
+type apiClient struct {
+	base   string
+	header http.Header
+	client *http.Client
+}
+
+func newAPIClient(base string, header http.Header) apiClient {
+	return apiClient{base: base, header: header, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// errNotFound is returned by do for 404 responses.
+var errNotFound = fmt.Errorf("not found")
+
+// do sends a JSON request to base+path and decodes the response into out
+// when out is non-nil.
+func (c apiClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,256 @@
+package ssl
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testFQDN  = "_acme-challenge.www.example.com"
+	testValue = "LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0"
+)
+
+func TestZoneCandidates(t *testing.T) {
+	got := zoneCandidates(testFQDN)
+	want := []string{"www.example.com", "example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("zoneCandidates = %v, want %v", got, want)
+	}
+	if got := relativeName(testFQDN, "example.com"); got != "_acme-challenge.www" {
+		t.Errorf("relativeName = %q", got)
+	}
+}
+
+func TestNewProviderCredentials(t *testing.T) {
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	if _, err := NewProvider("cloudflare", nil); err == nil || !strings.Contains(err.Error(), "api_token") {
+		t.Errorf("missing token: err = %v", err)
+	}
+	t.Setenv("CLOUDFLARE_API_TOKEN", "from-env")
+	if _, err := NewProvider("Cloudflare", nil); err != nil {
+		t.Errorf("token from environment: %v", err)
+	}
+	if _, err := NewProvider("bind", nil); err == nil {
+		t.Error("unknown provider accepted")
+	}
+}
+
+func TestCloudflare(t *testing.T) {
+	records := map[string]cfRecord{}
+	var posted cfRecord
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer cf" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var result any
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/zones":
+			zones := []map[string]string{}
+			if r.URL.Query().Get("name") == "example.com" {
+				zones = append(zones, map[string]string{"id": "z1"})
+			}
+			result = zones
+		case r.Method == http.MethodPost && r.URL.Path == "/zones/z1/dns_records":
+			var rec cfRecord
+			json.NewDecoder(r.Body).Decode(&rec)
+			rec.ID = "r1"
+			records[rec.ID], posted = rec, rec
+			result = rec
+		case r.Method == http.MethodGet && r.URL.Path == "/zones/z1/dns_records":
+			list := []cfRecord{}
+			for _, rec := range records {
+				if rec.Name == r.URL.Query().Get("name") && rec.Content == r.URL.Query().Get("content") {
+					list = append(list, rec)
+				}
+			}
+			result = list
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/zones/z1/dns_records/"):
+			delete(records, strings.TrimPrefix(r.URL.Path, "/zones/z1/dns_records/"))
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "result": result})
+	}))
+	defer srv.Close()
+
+	p, err := newCloudflare(map[string]string{"api_token": "cf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.(*cloudflare).api.base = srv.URL
+	checkPresentCleanUp(t, p, func() int { return len(records) })
+	if posted.Name != testFQDN || posted.Content != testValue || posted.Type != "TXT" {
+		t.Errorf("record = %+v", posted)
+	}
+}
+
+// checkPresentCleanUp presents and removes the test record, using count to
+// see how many records the fake API holds.
+func checkPresentCleanUp(t *testing.T, p Provider, count func() int) {
+	t.Helper()
+	ctx := context.Background()
+	if err := p.Present(ctx, testFQDN, testValue); err != nil {
+		t.Fatalf("Present: %v", err)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("%d records after Present, want 1", n)
+	}
+	if err := p.CleanUp(ctx, testFQDN, testValue); err != nil {
+		t.Fatalf("CleanUp: %v", err)
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("%d records after CleanUp, want 0", n)
+	}
+}
+
+func TestDigitalOcean(t *testing.T) {
+	records := map[int]doRecord{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != "Bearer do":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Method == http.MethodGet && r.URL.Path == "/domains/example.com":
+			w.Write([]byte(`{"domain":{"name":"example.com"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/domains/example.com/records":
+			var rec doRecord
+			json.NewDecoder(r.Body).Decode(&rec)
+			if rec.Name != "_acme-challenge.www" {
+				http.Error(w, "name must be relative", http.StatusUnprocessableEntity)
+				return
+			}
+			rec.ID = 7
+			records[rec.ID] = rec
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && r.URL.Path == "/domains/example.com/records":
+			list := []doRecord{}
+			if r.URL.Query().Get("name") == testFQDN {
+				for _, rec := range records {
+					list = append(list, rec)
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]any{"domain_records": list})
+		case r.Method == http.MethodDelete && r.URL.Path == "/domains/example.com/records/7":
+			delete(records, 7)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := newDigitalOcean(map[string]string{"token": "do"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.(*digitalOcean).api.base = srv.URL
+	checkPresentCleanUp(t, p, func() int { return len(records) })
+}
+
+func TestDeSECKeepsOtherValues(t *testing.T) {
+	rrset := deSECRRset{Subname: "_acme-challenge.www", Type: "TXT", TTL: 3600, Records: []string{`"other"`}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != "Token ds":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Method == http.MethodGet && r.URL.Path == "/domains/":
+			json.NewEncoder(w).Encode([]map[string]string{{"name": "example.com"}})
+		case r.Method == http.MethodGet && r.URL.Path == "/domains/example.com/rrsets/_acme-challenge.www/TXT/":
+			json.NewEncoder(w).Encode(rrset)
+		case r.Method == http.MethodPut && r.URL.Path == "/domains/example.com/rrsets/":
+			var sets []deSECRRset
+			json.NewDecoder(r.Body).Decode(&sets)
+			rrset = sets[0]
+			w.Write([]byte("[]"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := newDeSEC(map[string]string{"token": "ds"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.(*deSEC).api.base = srv.URL
+	ctx := context.Background()
+	if err := p.Present(ctx, testFQDN, testValue); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`"other"`, `"` + testValue + `"`}; !reflect.DeepEqual(rrset.Records, want) {
+		t.Errorf("after Present: %v, want %v", rrset.Records, want)
+	}
+	if err := p.CleanUp(ctx, testFQDN, testValue); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`"other"`}; !reflect.DeepEqual(rrset.Records, want) {
+		t.Errorf("after CleanUp: %v, want %v", rrset.Records, want)
+	}
+}
+
+func TestRoute53(t *testing.T) {
+	route53PollInterval = time.Millisecond
+	var changes []r53Change
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.URL.Path == "/hostedzonesbyname":
+			name := r.URL.Query().Get("dnsname")
+			if name == "www.example.com" {
+				name = "example.com" // next zone in DNS name order
+			}
+			io.WriteString(w, `<ListHostedZonesByNameResponse><HostedZones><HostedZone>
+				<Id>/hostedzone/Z1</Id><Name>`+name+`.</Name></HostedZone></HostedZones></ListHostedZonesByNameResponse>`)
+		case r.Method == http.MethodPost && r.URL.Path == "/hostedzone/Z1/rrset":
+			var batch r53ChangeBatch
+			if err := xml.NewDecoder(r.Body).Decode(&batch); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			changes = append(changes, batch.Changes...)
+			io.WriteString(w, `<ChangeResourceRecordSetsResponse><ChangeInfo><Id>/change/C1</Id><Status>PENDING</Status></ChangeInfo></ChangeResourceRecordSetsResponse>`)
+		case r.URL.Path == "/change/C1":
+			polls++
+			io.WriteString(w, `<GetChangeResponse><ChangeInfo><Id>/change/C1</Id><Status>INSYNC</Status></ChangeInfo></GetChangeResponse>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := newRoute53(map[string]string{"access_key_id": "AKID", "secret_access_key": "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.(*route53).base = srv.URL
+	ctx := context.Background()
+	if err := p.Present(ctx, testFQDN, testValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CleanUp(ctx, testFQDN, testValue); err != nil {
+		t.Fatal(err)
+	}
+	want := []r53Change{
+		{Action: "UPSERT", Name: testFQDN + ".", Type: "TXT", TTL: challengeTTL, Values: []string{`"` + testValue + `"`}},
+		{Action: "DELETE", Name: testFQDN + ".", Type: "TXT", TTL: challengeTTL, Values: []string{`"` + testValue + `"`}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+	if polls != 2 {
+		t.Errorf("polled %d times, want once per change", polls)
+	}
+}
@@ -0,0 +1,254 @@
+// Package ssl: reading issued certificates back for status reports.
+package ssl
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/f9-o/orbit/pkg/sshutil"
+)
+
+// DefaultRenewDays is the renewal window when ssl.renew_days is unset.
+const DefaultRenewDays = 30
+
+// Certificate statuses reported by Inspect.
+const (
+	CertValid   = "valid"
+	CertRenew   = "renew"   // inside the renewal window
+	CertExpired = "expired" // past NotAfter
+	CertInvalid = "invalid" // the chain does not verify for another reason
+)
+
+// certPatterns are the files under the cert dir that may hold certificates:
+// issued ones live in per-domain directories, hand-placed ones at the top.
+var certPatterns = []string{"*.pem", "*.crt", "*/*.pem", "*/*.crt"}
+
+// CertInfo describes one certificate file.
+type CertInfo struct {
+	Node       string    `json:"node"`
+	Domain     string    `json:"domain"`
+	File       string    `json:"file"`
+	Issuer     string    `json:"issuer"`
+	SANs       []string  `json:"sans"`
+	NotBefore  time.Time `json:"not_before"`
+	NotAfter   time.Time `json:"not_after"`
+	DaysLeft   int       `json:"days_left"`
+	Status     string    `json:"status"`
+	ChainValid bool      `json:"chain_valid"`
+	ChainError string    `json:"chain_error,omitempty"`
+}
+
+// NeedsAttention reports whether the certificate is due for renewal, expired
+// or otherwise unusable.
+func (c CertInfo) NeedsAttention() bool {
+	return c.Status != CertValid
+}
+
+// Inspector parses certificate files as of Now, flagging those that expire
+// within RenewWindow.
+type Inspector struct {
+	Now         time.Time
+	RenewWindow time.Duration
+	Roots       *x509.CertPool // nil verifies against the system roots
+}
+
+// NewInspector returns an Inspector for the current time with a renewal
+// window of renewDays (DefaultRenewDays when zero).
+func NewInspector(renewDays int) Inspector {
+	if renewDays <= 0 {
+		renewDays = DefaultRenewDays
+	}
+	return Inspector{Now: time.Now(), RenewWindow: time.Duration(renewDays) * 24 * time.Hour}
+}
+
+// WithLocalCA also trusts the local CA kept in dir, when there is one, so
+// development certificates verify.
+func (in Inspector) WithLocalCA(dir string) Inspector {
+	data, err := os.ReadFile(filepath.Join(dir, caCertFile))
+	if err != nil {
+		return in
+	}
+	roots := in.Roots
+	if roots == nil {
+		if roots, err = x509.SystemCertPool(); err != nil {
+			roots = x509.NewCertPool()
+		}
+	} else {
+		roots = roots.Clone()
+	}
+	if roots.AppendCertsFromPEM(data) {
+		in.Roots = roots
+	}
+	return in
+}
+
+// errNoCertificate marks files, such as private keys, without a certificate.
+var errNoCertificate = errors.New("no certificate")
+
+// Inspect parses the PEM data of file: the first certificate is the leaf and
+// any that follow are its chain.
+func (in Inspector) Inspect(file string, data []byte) (CertInfo, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return CertInfo{}, err
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return CertInfo{}, errNoCertificate
+	}
+
+	leaf := certs[0]
+	info := CertInfo{
+		Domain:    certDomain(leaf, file),
+		File:      file,
+		Issuer:    issuerName(leaf),
+		SANs:      leaf.DNSNames,
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+		DaysLeft:  int(leaf.NotAfter.Sub(in.Now).Hours() / 24),
+	}
+	if info.SANs == nil {
+		info.SANs = []string{}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         in.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   in.Now,
+	})
+	info.ChainValid = err == nil
+	if err != nil {
+		info.ChainError = err.Error()
+	}
+
+	left := leaf.NotAfter.Sub(in.Now)
+	switch {
+	case left <= 0:
+		info.Status = CertExpired
+	case err != nil:
+		info.Status = CertInvalid
+	case left < in.RenewWindow:
+		info.Status = CertRenew
+	default:
+		info.Status = CertValid
+	}
+	return info, nil
+}
+
+// InspectFiles inspects each file's contents, skipping files that hold no
+// certificate, and returns them ordered by domain.
+func (in Inspector) InspectFiles(files map[string][]byte) []CertInfo {
+	var out []CertInfo
+	for name, data := range files {
+		info, err := in.Inspect(name, data)
+		if err != nil {
+			continue // keys, and files that are not PEM certificates
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Domain != out[j].Domain {
+			return out[i].Domain < out[j].Domain
+		}
+		return out[i].File < out[j].File
+	})
+	return out
+}
+
+// InspectDir inspects the certificates under dir. A missing dir yields none.
+func (in Inspector) InspectDir(dir string) ([]CertInfo, error) {
+	files := map[string][]byte{}
+	for _, pattern := range certPatterns {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			data, err := os.ReadFile(m)
+			if err != nil {
+				return nil, err
+			}
+			files[m] = data
+		}
+	}
+	return in.InspectFiles(files), nil
+}
+
+// ListCommand is a shell command that prints every candidate certificate
+// file under dir on a remote node, each preceded by a "==> path" line, for
+// ParseListing. A leading ~/ refers to the remote user's home.
+func ListCommand(dir string) string {
+	if rest, ok := strings.CutPrefix(dir, "~/"); ok {
+		dir = `"$HOME"/` + sshutil.Quote(rest)
+	} else {
+		dir = sshutil.Quote(dir)
+	}
+	return "cd " + dir + " 2>/dev/null || exit 0; for f in " + strings.Join(certPatterns, " ") +
+		`; do [ -f "$f" ] && printf '==> %s\n' "$PWD/$f" && cat "$f"; done; exit 0`
+}
+
+// ParseListing splits the output of ListCommand into file contents.
+func ParseListing(out string) map[string][]byte {
+	files := map[string][]byte{}
+	var name string
+	var body strings.Builder
+	flush := func() {
+		if name != "" {
+			files[name] = []byte(body.String())
+		}
+		body.Reset()
+	}
+	for _, line := range strings.SplitAfter(out, "\n") {
+		if rest, ok := strings.CutPrefix(line, "==> "); ok {
+			flush()
+			name = strings.TrimSpace(rest)
+			continue
+		}
+		body.WriteString(line)
+	}
+	flush()
+	return files
+}
+
+// certDomain names a certificate after its first SAN, its subject, or the
+// directory it was issued into.
+func certDomain(c *x509.Certificate, file string) string {
+	if len(c.DNSNames) > 0 {
+		return c.DNSNames[0]
+	}
+	if c.Subject.CommonName != "" {
+		return c.Subject.CommonName
+	}
+	return filepath.Base(filepath.Dir(file))
+}
+
+// issuerName is the issuing CA's organisation and common name, e.g.
+// "Let's Encrypt R11".
+func issuerName(c *x509.Certificate) string {
+	parts := append([]string{}, c.Issuer.Organization...)
+	if cn := c.Issuer.CommonName; cn != "" && (len(parts) == 0 || parts[len(parts)-1] != cn) {
+		parts = append(parts, cn)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,128 @@
+package ssl
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testCA returns a self-signed CA and a function that issues leaves from it.
+func testCA(t *testing.T) (*x509.Certificate, func(domain string, notAfter time.Time) []byte) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"Orbit Test"}, CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(der)
+
+	issue := func(domain string, notAfter time.Time) []byte {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		leaf := &x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      pkix.Name{CommonName: domain},
+			DNSNames:     []string{domain, "www." + domain},
+			NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+			NotAfter:     notAfter,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, leaf, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+	return ca, issue
+}
+
+func TestInspectStatuses(t *testing.T) {
+	ca, issue := testCA(t)
+	now := time.Now()
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	in := Inspector{Now: now, RenewWindow: 30 * 24 * time.Hour, Roots: roots}
+
+	for _, tc := range []struct {
+		name     string
+		notAfter time.Time
+		roots    *x509.CertPool
+		want     string
+	}{
+		{"valid", now.Add(60 * 24 * time.Hour), roots, CertValid},
+		{"renew", now.Add(10 * 24 * time.Hour), roots, CertRenew},
+		{"expired", now.Add(-24 * time.Hour), roots, CertExpired},
+		{"untrusted", now.Add(60 * 24 * time.Hour), x509.NewCertPool(), CertInvalid},
+	} {
+		in.Roots = tc.roots
+		info, err := in.Inspect("example.com/fullchain.pem", issue("example.com", tc.notAfter))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if info.Status != tc.want {
+			t.Errorf("%s: status = %s, want %s (chain: %s)", tc.name, info.Status, tc.want, info.ChainError)
+		}
+		if info.ChainValid != (tc.want == CertValid || tc.want == CertRenew) {
+			t.Errorf("%s: chain valid = %v", tc.name, info.ChainValid)
+		}
+	}
+
+	in.Roots = roots
+	info, _ := in.Inspect("f.pem", issue("example.com", now.Add(45*24*time.Hour+time.Hour)))
+	if info.Issuer != "Orbit Test Test CA" || info.DaysLeft != 45 || info.Domain != "example.com" ||
+		!reflect.DeepEqual(info.SANs, []string{"example.com", "www.example.com"}) {
+		t.Errorf("info = %+v", info)
+	}
+}
+
+func TestInspectDirSkipsKeys(t *testing.T) {
+	_, issue := testCA(t)
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "b.example.com"), 0o700)
+	os.WriteFile(filepath.Join(dir, "b.example.com", "fullchain.pem"), issue("b.example.com", time.Now().Add(time.Hour)), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.example.com", "privkey.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte{1}}), 0o600)
+	os.WriteFile(filepath.Join(dir, "a.example.com.crt"), issue("a.example.com", time.Now().Add(time.Hour)), 0o644)
+
+	certs, err := NewInspector(0).InspectDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 2 || certs[0].Domain != "a.example.com" || certs[1].Domain != "b.example.com" {
+		t.Fatalf("certs = %+v", certs)
+	}
+
+	if certs, err := NewInspector(0).InspectDir(filepath.Join(dir, "missing")); err != nil || len(certs) != 0 {
+		t.Errorf("missing dir: %v, %v", certs, err)
+	}
+}
+
+func TestParseListing(t *testing.T) {
+	out := "==> /home/deploy/.orbit/certs/a/fullchain.pem\nline1\nline2\n==> /home/deploy/.orbit/certs/a/privkey.pem\nkey\n"
+	want := map[string][]byte{
+		"/home/deploy/.orbit/certs/a/fullchain.pem": []byte("line1\nline2\n"),
+		"/home/deploy/.orbit/certs/a/privkey.pem":   []byte("key\n"),
+	}
+	if got := ParseListing(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseListing = %q", got)
+	}
+}
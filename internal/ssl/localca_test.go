@@ -0,0 +1,55 @@
+package ssl
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalCAIssue(t *testing.T) {
+	caDir, certDir := t.TempDir(), t.TempDir()
+	ca, err := LoadOrCreateCA(caDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ca.Issue([]string{"app.test", "127.0.0.1"}, certDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := CertPaths(certDir, "app.test"); cert.CertFile != want {
+		t.Errorf("cert written to %s, want %s", cert.CertFile, want)
+	}
+	if fi, err := os.Stat(cert.KeyFile); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("key file: %v, %v", fi, err)
+	}
+
+	// Reopening returns the same CA rather than minting a new one.
+	again, err := LoadOrCreateCA(caDir)
+	if err != nil || !again.cert.Equal(ca.cert) {
+		t.Fatalf("reloaded CA differs: %v", err)
+	}
+
+	data, _ := os.ReadFile(cert.CertFile)
+	info, err := NewInspector(0).WithLocalCA(caDir).Inspect(cert.CertFile, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Status != CertValid || !info.ChainValid {
+		t.Errorf("status = %s, chain error = %s", info.Status, info.ChainError)
+	}
+	block, _ := pem.Decode(data)
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaf.IPAddresses) != 1 || !leaf.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")) || leaf.DNSNames[0] != "app.test" {
+		t.Errorf("SANs = %v %v", leaf.DNSNames, leaf.IPAddresses)
+	}
+
+	if info, _ := NewInspector(0).WithLocalCA(filepath.Join(caDir, "none")).Inspect(cert.CertFile, data); info.ChainValid {
+		t.Error("chain verified without trusting the local CA")
+	}
+}
@@ -0,0 +1,166 @@
+// Package ssl: AWS Route 53 DNS provider.
+package ssl
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/f9-o/orbit/pkg/sigv4"
+)
+
+const (
+	route53API    = "https://route53.amazonaws.com/2013-04-01"
+	route53XMLNS  = "https://route53.amazonaws.com/doc/2013-04-01/"
+	route53Region = "us-east-1" // Route 53 is global; requests are signed for us-east-1
+)
+
+// route53PollInterval is how often a record change is checked for INSYNC.
+var route53PollInterval = 2 * time.Second
+
+// route53 manages records in a public hosted zone. Requests are signed with
+// AWS Signature Version 4 using a static access key.
+type route53 struct {
+	base   string
+	zoneID string // optional: skips the hosted-zone lookup
+	signer sigv4.Signer
+	client *http.Client
+}
+
+func newRoute53(creds map[string]string) (Provider, error) {
+	keyID, err := credential("route53", creds, "access_key_id", "AWS_ACCESS_KEY_ID")
+	if err != nil {
+		return nil, err
+	}
+	secret, err := credential("route53", creds, "secret_access_key", "AWS_SECRET_ACCESS_KEY")
+	if err != nil {
+		return nil, err
+	}
+	token, _ := credential("route53", creds, "session_token", "AWS_SESSION_TOKEN")
+	zoneID, _ := credential("route53", creds, "hosted_zone_id", "AWS_HOSTED_ZONE_ID")
+	return &route53{
+		base:   route53API,
+		zoneID: zoneID,
+		signer: sigv4.Signer{KeyID: keyID, Secret: secret, Token: token, Region: route53Region, Service: "route53"},
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+type r53HostedZones struct {
+	HostedZones []struct {
+		ID   string `xml:"Id"`
+		Name string `xml:"Name"`
+	} `xml:"HostedZones>HostedZone"`
+}
+
+type r53ChangeInfo struct {
+	ID     string `xml:"ChangeInfo>Id"`
+	Status string `xml:"ChangeInfo>Status"`
+}
+
+type r53ChangeBatch struct {
+	XMLName xml.Name    `xml:"ChangeResourceRecordSetsRequest"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Changes []r53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+type r53Change struct {
+	Action string   `xml:"Action"`
+	Name   string   `xml:"ResourceRecordSet>Name"`
+	Type   string   `xml:"ResourceRecordSet>Type"`
+	TTL    int      `xml:"ResourceRecordSet>TTL"`
+	Values []string `xml:"ResourceRecordSet>ResourceRecords>ResourceRecord>Value"`
+}
+
+// do sends a signed request and decodes the XML response into out.
+func (r *route53) do(ctx context.Context, method, path string, in, out any) error {
+	var body []byte
+	if in != nil {
+		data, err := xml.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = append([]byte(xml.Header), data...)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.base+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	r.signer.Sign(req, body, time.Now())
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("route53: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("route53: %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("route53: %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func (r *route53) hostedZone(ctx context.Context, fqdn string) (string, error) {
+	if r.zoneID != "" {
+		return r.zoneID, nil
+	}
+	for _, name := range zoneCandidates(fqdn) {
+		var zones r53HostedZones
+		q := url.Values{"dnsname": {name}, "maxitems": {"1"}}
+		if err := r.do(ctx, http.MethodGet, "/hostedzonesbyname?"+q.Encode(), nil, &zones); err != nil {
+			return "", err
+		}
+		if len(zones.HostedZones) > 0 && zones.HostedZones[0].Name == name+"." {
+			return strings.TrimPrefix(zones.HostedZones[0].ID, "/hostedzone/"), nil
+		}
+	}
+	return "", fmt.Errorf("route53: no hosted zone found for %s", fqdn)
+}
+
+// change applies one record change and waits until Route 53 reports it
+// INSYNC on all of its name servers.
+func (r *route53) change(ctx context.Context, action, fqdn, value string) error {
+	zone, err := r.hostedZone(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	batch := r53ChangeBatch{XMLNS: route53XMLNS, Changes: []r53Change{{
+		Action: action, Name: fqdn + ".", Type: "TXT", TTL: challengeTTL,
+		Values: []string{strconv.Quote(value)},
+	}}}
+	var info r53ChangeInfo
+	if err := r.do(ctx, http.MethodPost, "/hostedzone/"+zone+"/rrset", batch, &info); err != nil {
+		return err
+	}
+	for info.Status != "INSYNC" {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("route53: waiting for change %s: %w", info.ID, ctx.Err())
+		case <-time.After(route53PollInterval):
+		}
+		if err := r.do(ctx, http.MethodGet, "/change/"+strings.TrimPrefix(info.ID, "/change/"), nil, &info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *route53) Present(ctx context.Context, fqdn, value string) error {
+	return r.change(ctx, "UPSERT", fqdn, value)
+}
+
+func (r *route53) CleanUp(ctx context.Context, fqdn, value string) error {
+	return r.change(ctx, "DELETE", fqdn, value)
+}
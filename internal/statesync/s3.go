@@ -0,0 +1,134 @@
+// Package statesync: the shared state as an object in an S3-compatible
+// bucket.
+package statesync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/f9-o/orbit/pkg/sigv4"
+)
+
+// S3 keeps the document as one object. It is only replaced if its ETag is
+// still the one it was read at, which AWS S3, MinIO and most compatible
+// stores check.
+type S3 struct {
+	bucket, key string
+	base        string // the object's URL
+	signer      sigv4.Signer
+	client      *http.Client
+}
+
+// NewS3 returns the object at key in bucket. With an endpoint, such as
+// http://minio:9000, it is addressed path-style there; without one, on AWS
+// in region. Credentials come from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// and AWS_SESSION_TOKEN.
+func NewS3(bucket, key, region, endpoint string) (*S3, error) {
+	keyID, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if keyID == "" || secret == "" {
+		return nil, fmt.Errorf("s3: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	key = strings.TrimPrefix(key, "/")
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("s3: want s3://<bucket>/<key>")
+	}
+	base := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, escapeKey(key))
+	if endpoint != "" {
+		base = strings.TrimRight(endpoint, "/") + "/" + bucket + "/" + escapeKey(key)
+	}
+	return &S3{
+		bucket: bucket,
+		key:    key,
+		base:   base,
+		signer: sigv4.Signer{KeyID: keyID, Secret: secret, Token: os.Getenv("AWS_SESSION_TOKEN"), Region: region, Service: "s3"},
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+func (s *S3) String() string { return "s3://" + s.bucket + "/" + s.key }
+
+func (s *S3) do(ctx context.Context, method string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.base, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	s.signer.Sign(req, body, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s, err)
+	}
+	return resp, nil
+}
+
+func (s *S3) Fetch(ctx context.Context) ([]byte, string, error) {
+	resp, err := s.do(ctx, http.MethodGet, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", s.statusErr(http.MethodGet, resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", s, err)
+	}
+	return data, resp.Header.Get("ETag"), nil
+}
+
+func (s *S3) Store(ctx context.Context, data []byte, version string) error {
+	header := http.Header{"Content-Type": {"application/json"}}
+	if version == "" {
+		header.Set("If-None-Match", "*")
+	} else {
+		header.Set("If-Match", version)
+	}
+	resp, err := s.do(ctx, http.MethodPut, data, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return ErrChanged
+	}
+	return s.statusErr(http.MethodPut, resp)
+}
+
+func (s *S3) statusErr(method string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s: %s: %s: %s", s, method, resp.Status, strings.TrimSpace(string(msg)))
+}
@@ -0,0 +1,91 @@
+// Package statesync: the shared state as a file on a node.
+package statesync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	pathpkg "path"
+
+	"golang.org/x/crypto/ssh"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/pkg/sshutil"
+)
+
+// streamer runs a command on a node with stdin and stdout attached, as
+// remote.Pool does.
+type streamer interface {
+	Stream(ctx context.Context, node v1.NodeInfo, cmd string, stdin io.Reader, stdout io.Writer) error
+}
+
+// SSH keeps the document in a file on a node. It is replaced by renaming a
+// new file over it, and only if its SHA-256 is still the one it was read
+// at.
+type SSH struct {
+	pool streamer
+	node v1.NodeInfo
+	path string
+}
+
+// exitChanged is the exit status of the store script when the file changed.
+const exitChanged = 75
+
+// NewSSH returns the file at path, an absolute path on node.
+func NewSSH(pool streamer, node v1.NodeInfo, path string) (*SSH, error) {
+	if !pathpkg.IsAbs(path) || pathpkg.Clean(path) == "/" {
+		return nil, fmt.Errorf("ssh: want ssh://<node>/<absolute path>, got path %q", path)
+	}
+	return &SSH{pool: pool, node: node, path: path}, nil
+}
+
+func (s *SSH) String() string { return "ssh://" + s.node.Spec.Name + s.path }
+
+// Fetch reads the file; a missing one reads as empty, which no document is.
+func (s *SSH) Fetch(ctx context.Context) ([]byte, string, error) {
+	p := sshutil.Quote(s.path)
+	var out bytes.Buffer
+	if err := s.pool.Stream(ctx, s.node, "if [ -e "+p+" ]; then cat -- "+p+"; fi", nil, &out); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", s, err)
+	}
+	if out.Len() == 0 {
+		return nil, "", nil
+	}
+	return out.Bytes(), checksum(out.Bytes()), nil
+}
+
+func (s *SSH) Store(ctx context.Context, data []byte, version string) error {
+	p := sshutil.Quote(s.path)
+	script := fmt.Sprintf(`set -e
+t=%[1]s.tmp.$$
+mkdir -p "$(dirname %[1]s)"
+cat > "$t"
+cur=
+if [ -e %[1]s ]; then
+	cur=$(sha256sum %[1]s 2>/dev/null || shasum -a 256 %[1]s)
+	cur=${cur%%%% *}
+fi
+if [ "$cur" != %[2]s ]; then
+	rm -f "$t"
+	exit %[3]d
+fi
+mv -f "$t" %[1]s`, p, sshutil.Quote(version), exitChanged)
+	err := s.pool.Stream(ctx, s.node, script, bytes.NewReader(data), io.Discard)
+	var exit *ssh.ExitError
+	if errors.As(err, &exit) && exit.ExitStatus() == exitChanged {
+		return ErrChanged
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", s, err)
+	}
+	return nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,386 @@
+// Package statesync shares the node registry and deployment history between
+// the machines a team operates one fleet from. Each keeps its own state DB;
+// Sync merges it with a shared document kept in an S3-compatible bucket or a
+// file on a node. A record changed on one side since the last sync is copied
+// to the other. A record changed on both is a conflict: the side that
+// changed it last wins, and the conflict is reported.
+package statesync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// DocVersion is the format version of the shared document.
+const DocVersion = 1
+
+// Doc is the shared state, as kept at the remote.
+type Doc struct {
+	Version int               `json:"version"`
+	Records map[string]Record `json:"records"` // by key, e.g. nodes/edge-1
+}
+
+// Record is one shared record, or the tombstone of a deleted one.
+type Record struct {
+	Value   json.RawMessage `json:"value,omitempty"` // a v1.NodeInfo or v1.DeploymentRecord; nil when deleted
+	Hash    string          `json:"hash,omitempty"`  // of the shared content of Value
+	Updated time.Time       `json:"updated"`         // when it was last changed, by the writer's clock
+	By      string          `json:"by"`              // user@host that wrote it
+}
+
+// Remote is where the shared document is kept.
+type Remote interface {
+	// Fetch returns the document and a version to pass to Store, or nil data
+	// when there is no document yet.
+	Fetch(ctx context.Context) (data []byte, version string, err error)
+	// Store replaces the document if it is still at version, and fails with
+	// ErrChanged otherwise.
+	Store(ctx context.Context, data []byte, version string) error
+	String() string
+}
+
+// ErrChanged is returned by Store when another machine stored the document
+// since it was fetched.
+var ErrChanged = errors.New("the shared state changed while syncing")
+
+// attempts is how many times Sync starts over when the document changes
+// under it.
+const attempts = 3
+
+// Options tune Sync.
+type Options struct {
+	DryRun bool   // report what would change, changing nothing
+	By     string // recorded as the writer of pushed records; default user@host
+	Now    func() time.Time
+}
+
+// Change is one record copied or deleted by a sync.
+type Change struct {
+	Key     string `json:"key"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// Conflict is a record both sides changed since they last synced.
+type Conflict struct {
+	Key        string    `json:"key"`
+	Kept       string    `json:"kept"` // local | remote
+	LocalTime  time.Time `json:"local_time"`
+	RemoteTime time.Time `json:"remote_time"`
+	RemoteBy   string    `json:"remote_by"`
+}
+
+// Report is what a sync did, or would do.
+type Report struct {
+	Remote    string     `json:"remote"`
+	Pulled    []Change   `json:"pulled"` // remote changes applied to the local DB
+	Pushed    []Change   `json:"pushed"` // local changes stored at the remote
+	Conflicts []Conflict `json:"conflicts"`
+	DryRun    bool       `json:"dry_run,omitempty"`
+}
+
+// NewRemote returns the remote cfg names. An ssh:// remote's node is looked
+// up with node and reached through pool.
+func NewRemote(cfg config.StateSyncConfig, node func(name string) (v1.NodeInfo, error), pool streamer) (Remote, error) {
+	if cfg.URL == "" {
+		return nil, errs.Newf(errs.ErrValidation, "statesync.NewRemote", "no shared state is configured").
+			WithAdvice("Set state.sync.url in orbit.yaml to s3://<bucket>/<key> or ssh://<node>/<absolute path>")
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, errs.New(errs.ErrValidation, "statesync.NewRemote", err)
+	}
+	switch u.Scheme {
+	case "s3":
+		s3, err := NewS3(u.Host, u.Path, cfg.Region, cfg.Endpoint)
+		if err != nil {
+			return nil, errs.New(errs.ErrValidation, "statesync.NewRemote", err)
+		}
+		return s3, nil
+	case "ssh":
+		info, err := node(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewSSH(pool, info, u.Path)
+		if err != nil {
+			return nil, errs.New(errs.ErrValidation, "statesync.NewRemote", err)
+		}
+		return s, nil
+	}
+	return nil, errs.Newf(errs.ErrValidation, "statesync.NewRemote", "state.sync.url %q: want s3:// or ssh://", cfg.URL)
+}
+
+// local is one local record, with its shared hash and the time it changed.
+type local struct {
+	value json.RawMessage
+	hash  string
+	time  time.Time
+}
+
+// Sync merges db's node registry and deployment history, of every project,
+// with the document at r.
+func Sync(ctx context.Context, db *state.DB, r Remote, opts Options) (*Report, error) {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.By == "" {
+		opts.By = whoami()
+	}
+	db = db.Project(state.AllProjects)
+	for i := 1; ; i++ {
+		report, err := syncOnce(ctx, db, r, opts)
+		if errors.Is(err, ErrChanged) && i < attempts {
+			continue
+		}
+		if err != nil {
+			return nil, errs.Wrap(err, errs.ErrStateWrite, "statesync.Sync").
+				WithAdvice("Check the state.sync settings in orbit.yaml, and that " + r.String() + " is reachable")
+		}
+		return report, nil
+	}
+}
+
+func syncOnce(ctx context.Context, db *state.DB, r Remote, opts Options) (*Report, error) {
+	data, version, err := r.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc := Doc{Version: DocVersion, Records: map[string]Record{}}
+	if data != nil {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", r, err)
+		}
+		if doc.Version > DocVersion {
+			return nil, fmt.Errorf("%s is version %d of the shared state; this orbit reads up to %d", r, doc.Version, DocVersion)
+		}
+		if doc.Records == nil {
+			doc.Records = map[string]Record{}
+		}
+	}
+	locals, err := readLocal(db)
+	if err != nil {
+		return nil, err
+	}
+	bases, err := db.SyncBases()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	for k := range locals {
+		keys[k] = true
+	}
+	for k := range doc.Records {
+		// Kinds of record written by a newer orbit are left to it.
+		if strings.HasPrefix(k, prefixNode) || strings.HasPrefix(k, prefixDeployment) {
+			keys[k] = true
+		}
+	}
+	for k := range bases {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	now := opts.Now().UTC()
+	report := &Report{Remote: r.String(), DryRun: opts.DryRun}
+	pull := map[string]Record{}
+	newBases := map[string]state.SyncBase{}
+	for _, key := range sorted {
+		loc, haveLocal := locals[key]
+		rem := doc.Records[key]
+		base := bases[key].Hash
+
+		push := false
+		switch localChanged, remoteChanged := loc.hash != base, rem.Hash != base; {
+		case loc.hash == rem.Hash:
+		case localChanged && !remoteChanged:
+			push = true
+		case remoteChanged && !localChanged:
+			pull[key] = rem
+		default:
+			// Both changed: the later change wins. A local deletion leaves
+			// no time behind, so it counts as made now.
+			localTime := loc.time
+			if !haveLocal {
+				localTime = now
+			}
+			c := Conflict{Key: key, Kept: "remote", LocalTime: localTime, RemoteTime: rem.Updated, RemoteBy: rem.By}
+			if localTime.After(rem.Updated) {
+				c.Kept = "local"
+				push = true
+			} else {
+				pull[key] = rem
+			}
+			report.Conflicts = append(report.Conflicts, c)
+		}
+
+		hash := rem.Hash
+		if push {
+			hash = loc.hash
+			if haveLocal {
+				doc.Records[key] = Record{Value: loc.value, Hash: loc.hash, Updated: loc.time, By: opts.By}
+			} else {
+				doc.Records[key] = Record{Updated: now, By: opts.By}
+			}
+			report.Pushed = append(report.Pushed, Change{Key: key, Deleted: !haveLocal})
+		}
+		if rec, ok := pull[key]; ok {
+			report.Pulled = append(report.Pulled, Change{Key: key, Deleted: rec.Value == nil})
+		}
+		if hash != "" {
+			newBases[key] = state.SyncBase{Hash: hash, Synced: now}
+		}
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	// Store first: should applying the pulled records fail, they are still
+	// changed at the remote since the last sync, and pulled next time.
+	if len(report.Pushed) > 0 || data == nil {
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Store(ctx, out, version); err != nil {
+			return nil, err
+		}
+	}
+	for key, rec := range pull {
+		if err := apply(db, key, rec); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if err := db.SetSyncBases(newBases); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Record key prefixes.
+const (
+	prefixNode       = "nodes/"
+	prefixDeployment = "deployments/"
+)
+
+// readLocal returns the records db shares, by key.
+func readLocal(db *state.DB) (map[string]local, error) {
+	locals := map[string]local{}
+	nodes, err := db.ListNodes()
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		// A node changes when its spec or host key is written, not when
+		// a heartbeat last reached it.
+		l, err := newLocal(n, sharedNode(n), n.Modified)
+		if err != nil {
+			return nil, err
+		}
+		locals[prefixNode+n.Spec.Name] = l
+	}
+	recs, err := db.ListDeployments("")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range recs {
+		changed := r.CompletedAt
+		if changed.IsZero() {
+			changed = r.StartedAt
+		}
+		l, err := newLocal(r, r, changed)
+		if err != nil {
+			return nil, err
+		}
+		locals[prefixDeployment+r.ID] = l
+	}
+	return locals, nil
+}
+
+func newLocal(value, shared any, changed time.Time) (local, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return local{}, err
+	}
+	h, err := hash(shared)
+	if err != nil {
+		return local{}, err
+	}
+	return local{value: data, hash: h, time: changed.UTC()}, nil
+}
+
+// sharedNode is what of a node is shared: how to reach it and its host key,
+// not what this machine last saw of it.
+func sharedNode(n v1.NodeInfo) v1.NodeInfo {
+	return v1.NodeInfo{Spec: n.Spec, KeyFingerprint: n.KeyFingerprint, HostKey: n.HostKey, HostKeyKnown: n.HostKeyKnown}
+}
+
+func hash(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// apply writes a pulled record to db, or deletes it for a tombstone.
+func apply(db *state.DB, key string, rec Record) error {
+	switch {
+	case strings.HasPrefix(key, prefixNode):
+		name := strings.TrimPrefix(key, prefixNode)
+		if rec.Value == nil {
+			return db.DeleteNode(name)
+		}
+		var n v1.NodeInfo
+		if err := json.Unmarshal(rec.Value, &n); err != nil {
+			return err
+		}
+		// Keep what this machine has seen of the node.
+		if cur, err := db.GetNode(name); err == nil && cur != nil {
+			n.Status, n.LastSeen, n.FailCount, n.Host = cur.Status, cur.LastSeen, cur.FailCount, cur.Host
+		}
+		return db.PutNode(n)
+	case strings.HasPrefix(key, prefixDeployment):
+		if rec.Value == nil {
+			return nil // history is not deleted
+		}
+		var r v1.DeploymentRecord
+		if err := json.Unmarshal(rec.Value, &r); err != nil {
+			return err
+		}
+		return db.PutDeployment(r)
+	}
+	return nil
+}
+
+// whoami is user@host, naming this machine in the records it pushes.
+func whoami() string {
+	host, _ := os.Hostname()
+	user := os.Getenv("USER")
+	if user == "" {
+		user = os.Getenv("USERNAME")
+	}
+	if user == "" {
+		return host
+	}
+	return user + "@" + host
+}
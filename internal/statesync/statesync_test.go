@@ -0,0 +1,226 @@
+package statesync
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/pkg/encryption"
+)
+
+// memRemote keeps the document in memory. changes makes that many Stores
+// fail as if another machine had stored first.
+type memRemote struct {
+	mu      sync.Mutex
+	data    []byte
+	version int
+	changes int
+}
+
+func (m *memRemote) Fetch(context.Context) ([]byte, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return nil, "", nil
+	}
+	return m.data, strconv.Itoa(m.version), nil
+}
+
+func (m *memRemote) Store(_ context.Context, data []byte, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.changes > 0 {
+		m.changes--
+		m.version++
+		return ErrChanged
+	}
+	if (m.data == nil) != (version == "") || (m.data != nil && version != strconv.Itoa(m.version)) {
+		return ErrChanged
+	}
+	m.data, m.version = data, m.version+1
+	return nil
+}
+
+func (m *memRemote) String() string { return "mem://" }
+
+func openDB(t *testing.T) *state.DB {
+	t.Helper()
+	t.Setenv(encryption.EnvSecretKey, "12345678901234567890123456789012")
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func sync1(t *testing.T, db *state.DB, r Remote, by string) *Report {
+	t.Helper()
+	report, err := Sync(context.Background(), db, r, Options{By: by})
+	if err != nil {
+		t.Fatalf("%s: sync: %v", by, err)
+	}
+	return report
+}
+
+func TestSync(t *testing.T) {
+	r := &memRemote{}
+	alice, bob := openDB(t), openDB(t)
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	node := v1.NodeInfo{Spec: v1.NodeSpec{Name: "edge-1", Host: "10.0.0.1", User: "deploy"}, LastSeen: t0, Status: v1.NodeOnline}
+	if err := alice.PutNode(node); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Project("shop").PutDeployment(v1.DeploymentRecord{ID: "1-web", Service: "web", Node: "edge-1", StartedAt: t0}); err != nil {
+		t.Fatal(err)
+	}
+	if rep := sync1(t, alice, r, "alice"); len(rep.Pushed) != 2 || len(rep.Pulled) != 0 {
+		t.Fatalf("alice's first sync: %+v", rep)
+	}
+	if rep := sync1(t, bob, r, "bob"); len(rep.Pulled) != 2 || len(rep.Pushed) != 0 {
+		t.Fatalf("bob's first sync: %+v", rep)
+	}
+	got, err := bob.GetNode("edge-1")
+	if err != nil || got == nil || got.Spec.Host != "10.0.0.1" {
+		t.Fatalf("bob's edge-1 = %+v, %v", got, err)
+	}
+	if recs, _ := bob.Project("shop").ListDeployments("web"); len(recs) != 1 {
+		t.Errorf("bob's shop deployments = %+v", recs)
+	}
+
+	// What each machine has seen of a node is not a change to share.
+	if err := bob.UpdateNodeStatus("edge-1", v1.NodeOffline, 3); err != nil {
+		t.Fatal(err)
+	}
+	if rep := sync1(t, bob, r, "bob"); len(rep.Pushed)+len(rep.Pulled)+len(rep.Conflicts) != 0 {
+		t.Errorf("status change synced: %+v", rep)
+	}
+
+	// Both move the node: bob's later change wins on both machines, though
+	// alice heard from the node after it.
+	node.Spec.Host, node.Modified, node.LastSeen = "10.0.0.2", t0.Add(time.Minute), t0.Add(5*time.Minute)
+	if err := alice.PutNode(node); err != nil {
+		t.Fatal(err)
+	}
+	node.Spec.Host, node.Modified, node.LastSeen = "10.0.0.3", t0.Add(2*time.Minute), t0.Add(2*time.Minute)
+	if err := bob.PutNode(node); err != nil {
+		t.Fatal(err)
+	}
+	sync1(t, alice, r, "alice")
+	rep := sync1(t, bob, r, "bob")
+	if len(rep.Conflicts) != 1 || rep.Conflicts[0].Kept != "local" || rep.Conflicts[0].RemoteBy != "alice" {
+		t.Fatalf("bob's conflicting sync: %+v", rep)
+	}
+	sync1(t, alice, r, "alice")
+	for name, db := range map[string]*state.DB{"alice": alice, "bob": bob} {
+		if got, _ := db.GetNode("edge-1"); got == nil || got.Spec.Host != "10.0.0.3" {
+			t.Errorf("%s's edge-1 = %+v", name, got)
+		}
+	}
+	if got, _ := alice.GetNode("edge-1"); !got.LastSeen.Equal(t0.Add(5 * time.Minute)) {
+		t.Errorf("alice's view of edge-1 was overwritten: %+v", got)
+	}
+
+	// A deletion is shared too.
+	if err := alice.DeleteNode("edge-1"); err != nil {
+		t.Fatal(err)
+	}
+	if rep := sync1(t, alice, r, "alice"); len(rep.Pushed) != 1 || !rep.Pushed[0].Deleted {
+		t.Fatalf("alice's deleting sync: %+v", rep)
+	}
+	sync1(t, bob, r, "bob")
+	if got, _ := bob.GetNode("edge-1"); got != nil {
+		t.Errorf("bob still has edge-1: %+v", got)
+	}
+	if rep := sync1(t, bob, r, "bob"); len(rep.Pushed)+len(rep.Pulled) != 0 {
+		t.Errorf("sync after settling: %+v", rep)
+	}
+}
+
+func TestSyncRetriesAChangedRemote(t *testing.T) {
+	db := openDB(t)
+	if err := db.PutNode(v1.NodeInfo{Spec: v1.NodeSpec{Name: "edge-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	r := &memRemote{changes: attempts - 1}
+	if rep := sync1(t, db, r, "alice"); len(rep.Pushed) != 1 {
+		t.Errorf("report = %+v", rep)
+	}
+
+	r = &memRemote{changes: attempts}
+	if _, err := Sync(context.Background(), db, r, Options{}); err == nil {
+		t.Error("sync succeeded though every store found the remote changed")
+	}
+}
+
+func TestS3(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	var (
+		mu     sync.Mutex
+		object []byte
+		etag   int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if req.URL.Path != "/team/orbit/state.json" || req.Header.Get("Authorization") == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		current := strconv.Quote(strconv.Itoa(etag))
+		switch req.Method {
+		case http.MethodGet:
+			if object == nil {
+				http.NotFound(w, req)
+				return
+			}
+			w.Header().Set("ETag", current)
+			w.Write(object)
+		case http.MethodPut:
+			if (req.Header.Get("If-None-Match") == "*" && object != nil) ||
+				(req.Header.Get("If-Match") != "" && req.Header.Get("If-Match") != current) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			object, _ = io.ReadAll(req.Body)
+			etag++
+		}
+	}))
+	defer srv.Close()
+
+	s3, err := NewS3("team", "/orbit/state.json", "", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	data, version, err := s3.Fetch(ctx)
+	if err != nil || data != nil {
+		t.Fatalf("fetch before any store = %q, %v", data, err)
+	}
+	if err := s3.Store(ctx, []byte(`{"version":1}`), version); err != nil {
+		t.Fatal(err)
+	}
+	if err := s3.Store(ctx, []byte(`{"version":1}`), ""); err != ErrChanged {
+		t.Errorf("creating it again: want ErrChanged, got %v", err)
+	}
+	data, version, err = s3.Fetch(ctx)
+	if err != nil || string(data) != `{"version":1}` {
+		t.Fatalf("fetch = %q, %v", data, err)
+	}
+	if err := s3.Store(ctx, []byte(`{"version":1,"records":{}}`), version); err != nil {
+		t.Fatal(err)
+	}
+	if err := s3.Store(ctx, []byte(`{}`), version); err != ErrChanged {
+		t.Errorf("storing over a newer version: want ErrChanged, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+// Package support writes the archive `orbit support-bundle` collects for a
+// bug report: a gzipped tar of text files under one directory, with secrets
+// masked.
+package support
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+// Bundle is a support archive being written.
+type Bundle struct {
+	gz    *gzip.Writer
+	tw    *tar.Writer
+	root  string
+	now   time.Time
+	files []string
+}
+
+// NewBundle starts an archive on w whose files are all under root/.
+func NewBundle(w io.Writer, root string) *Bundle {
+	gz := gzip.NewWriter(w)
+	return &Bundle{gz: gz, tw: tar.NewWriter(gz), root: root, now: time.Now()}
+}
+
+// Add writes data to the archive as name, a slash-separated path under the
+// bundle's root, with the secrets RedactText finds masked.
+func (b *Bundle) Add(name string, data []byte) error {
+	data = RedactText(data)
+	hdr := &tar.Header{
+		Name:    path.Join(b.root, name),
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: b.now,
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return err
+	}
+	b.files = append(b.files, name)
+	return nil
+}
+
+// AddTail writes the last max bytes of the file at p as name, from the
+// start of a line. A file that does not exist is skipped.
+func (b *Bundle) AddTail(name, p string, max int64) error {
+	data, err := Tail(p, max)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return b.Add(name, data)
+}
+
+// Files lists the names added, in order.
+func (b *Bundle) Files() []string { return b.files }
+
+// Close finishes the archive. It does not close the underlying writer.
+func (b *Bundle) Close() error {
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return b.gz.Close()
+}
+
+// Tail reads the last max bytes of the file at p, dropping the partial line
+// they start in.
+func Tail(p string, max int64) ([]byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() <= max {
+		return io.ReadAll(f)
+	}
+	if _, err := f.Seek(info.Size()-max, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return data, nil
+}
@@ -0,0 +1,64 @@
+package support
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readBundle returns the files of a bundle by name.
+func readBundle(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(tr)
+		files[hdr.Name] = string(body)
+	}
+}
+
+func TestBundle(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "orbit.log")
+	os.WriteFile(log, []byte("first line\nsecond line\nthird\n"), 0o644)
+
+	var buf bytes.Buffer
+	b := NewBundle(&buf, "orbit-support")
+	if err := b.Add("doctor.json", []byte("[]\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddTail("logs/orbit.log", log, 15); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddTail("logs/missing.log", filepath.Join(t.TempDir(), "nope"), 15); err != nil {
+		t.Errorf("a missing file: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readBundle(t, buf.Bytes())
+	if len(files) != 2 || files["orbit-support/doctor.json"] != "[]\n" {
+		t.Errorf("files = %q", files)
+	}
+	if got := files["orbit-support/logs/orbit.log"]; got != "third\n" {
+		t.Errorf("tail = %q", got)
+	}
+	if len(b.Files()) != 2 {
+		t.Errorf("Files() = %v", b.Files())
+	}
+}
@@ -0,0 +1,187 @@
+// Package telemetry: OTLP/HTTP push of collector readings.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// ExportInterval is how often `orbit agent` pushes metrics.
+const ExportInterval = 15 * time.Second
+
+// MetricsExporter pushes service and host readings to an OTLP/HTTP endpoint.
+type MetricsExporter struct {
+	url     string
+	version string
+	client  *http.Client
+	start   time.Time // start of the cumulative counters' reporting window
+}
+
+// NewMetricsExporter creates an exporter for the collector base URL endpoint.
+func NewMetricsExporter(endpoint, version string) (*MetricsExporter, error) {
+	u, err := signalURL(endpoint, "v1/metrics")
+	if err != nil {
+		return nil, err
+	}
+	return &MetricsExporter{
+		url:     u,
+		version: version,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		start:   time.Now(),
+	}, nil
+}
+
+// Export sends m and the host readings of nodes in one request.
+func (e *MetricsExporter) Export(ctx context.Context, m v1.Metrics, nodes []v1.NodeInfo) error {
+	body, err := proto.Marshal(e.request(m, nodes, time.Now()))
+	if err != nil {
+		return fmt.Errorf("otlp metrics: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("otlp metrics: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp metrics: %s returned %s", e.url, resp.Status)
+	}
+	return nil
+}
+
+// request builds the export request for one push.
+func (e *MetricsExporter) request(m v1.Metrics, nodes []v1.NodeInfo, now time.Time) *colmetricpb.ExportMetricsServiceRequest {
+	b := &batch{now: uint64(now.UnixNano()), start: uint64(e.start.UnixNano()), index: map[string]*metricpb.Metric{}}
+
+	services := make([]string, 0, len(m.Services))
+	for name := range m.Services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	for _, name := range services {
+		s := m.Services[name]
+		node, svc := kv("orbit.node", m.Node), kv("orbit.service", name)
+		b.gauge("orbit.service.cpu.percent", "%", "CPU usage of the service's containers, in percent of one CPU.", float64Point(s.CPUPercent, node, svc))
+		b.gauge("orbit.service.memory.usage", "By", "Memory used by the service's containers.", intPoint(s.MemBytes, node, svc))
+		b.gauge("orbit.service.memory.limit", "By", "Memory limit of the service's containers.", intPoint(s.MemLimit, node, svc))
+		b.gauge("orbit.service.pids", "{process}", "Processes running in the service's containers.", intPoint(int64(s.PIDs), node, svc))
+		b.gauge("orbit.service.disk.usage", "By", "Size of the containers' writable layers.", intPoint(s.DiskBytes, node, svc))
+		b.counter("orbit.service.network.io", "By", "Bytes sent and received on eth0.",
+			intPoint(s.NetRxBytes, node, svc, kv("direction", "receive")),
+			intPoint(s.NetTxBytes, node, svc, kv("direction", "transmit")))
+		b.counter("orbit.service.disk.io", "By", "Bytes read from and written to block devices.",
+			intPoint(s.BlockReadBytes, node, svc, kv("direction", "read")),
+			intPoint(s.BlockWriteBytes, node, svc, kv("direction", "write")))
+	}
+
+	b.host(m.Node, m.Host)
+	for _, n := range nodes {
+		if n.Spec.Name != m.Node {
+			b.host(n.Spec.Name, n.Host)
+		}
+	}
+
+	return &colmetricpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricpb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				kv("service.name", "orbit"), kv("service.version", e.version),
+			}},
+			ScopeMetrics: []*metricpb.ScopeMetrics{{
+				Scope:   &commonpb.InstrumentationScope{Name: instrumentation, Version: e.version},
+				Metrics: b.metrics,
+			}},
+		}},
+	}
+}
+
+// batch accumulates data points, one Metric per name in first-seen order.
+type batch struct {
+	now, start uint64
+	metrics    []*metricpb.Metric
+	index      map[string]*metricpb.Metric
+}
+
+// gauge adds points to the named gauge.
+func (b *batch) gauge(name, unit, desc string, pts ...*metricpb.NumberDataPoint) {
+	m := b.metric(name, unit, desc, func() *metricpb.Metric {
+		return &metricpb.Metric{Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{}}}
+	})
+	for _, p := range pts {
+		p.TimeUnixNano = b.now
+	}
+	m.GetGauge().DataPoints = append(m.GetGauge().DataPoints, pts...)
+}
+
+// counter adds points to the named cumulative, monotonic sum.
+func (b *batch) counter(name, unit, desc string, pts ...*metricpb.NumberDataPoint) {
+	m := b.metric(name, unit, desc, func() *metricpb.Metric {
+		return &metricpb.Metric{Data: &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+			AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}}
+	})
+	for _, p := range pts {
+		p.StartTimeUnixNano, p.TimeUnixNano = b.start, b.now
+	}
+	m.GetSum().DataPoints = append(m.GetSum().DataPoints, pts...)
+}
+
+func (b *batch) metric(name, unit, desc string, create func() *metricpb.Metric) *metricpb.Metric {
+	if m, ok := b.index[name]; ok {
+		return m
+	}
+	m := create()
+	m.Name, m.Unit, m.Description = name, unit, desc
+	b.index[name] = m
+	b.metrics = append(b.metrics, m)
+	return m
+}
+
+// host adds a node's host reading; nil readings are skipped.
+func (b *batch) host(node string, h *v1.HostMetrics) {
+	if h == nil {
+		return
+	}
+	n := kv("orbit.node", node)
+	b.gauge("orbit.host.load", "1", "Load average.",
+		float64Point(h.Load1, n, kv("period", "1m")),
+		float64Point(h.Load5, n, kv("period", "5m")),
+		float64Point(h.Load15, n, kv("period", "15m")))
+	b.gauge("orbit.host.memory.total", "By", "Total host memory.", intPoint(h.MemTotal, n))
+	b.gauge("orbit.host.memory.available", "By", "Host memory available for new work.", intPoint(h.MemAvailable, n))
+	p := kv("path", h.DiskPath)
+	b.gauge("orbit.host.filesystem.total", "By", "Size of the filesystem holding the runtime data root.", intPoint(h.DiskTotal, n, p))
+	b.gauge("orbit.host.filesystem.free", "By", "Free space on the filesystem holding the runtime data root.", intPoint(h.DiskFree, n, p))
+	b.gauge("orbit.host.inodes.total", "{inode}", "Inodes on the filesystem holding the runtime data root.", intPoint(h.InodesTotal, n, p))
+	b.gauge("orbit.host.inodes.free", "{inode}", "Free inodes on the filesystem holding the runtime data root.", intPoint(h.InodesFree, n, p))
+}
+
+func intPoint(v int64, attrs ...*commonpb.KeyValue) *metricpb.NumberDataPoint {
+	return &metricpb.NumberDataPoint{Value: &metricpb.NumberDataPoint_AsInt{AsInt: v}, Attributes: attrs}
+}
+
+func float64Point(v float64, attrs ...*commonpb.KeyValue) *metricpb.NumberDataPoint {
+	return &metricpb.NumberDataPoint{Value: &metricpb.NumberDataPoint_AsDouble{AsDouble: v}, Attributes: attrs}
+}
+
+func kv(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
@@ -0,0 +1,70 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+func TestMetricsExporterPushesOTLP(t *testing.T) {
+	var got colmetricpb.ExportMetricsServiceRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("request %s %s (%s)", r.Method, r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := proto.Unmarshal(body, &got); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	exp, err := NewMetricsExporter(srv.URL+"/", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v1.Metrics{
+		Node:     "local",
+		Services: map[string]v1.ServiceMetrics{"api": {MemBytes: 42, NetRxBytes: 1, NetTxBytes: 2}},
+		Host:     &v1.HostMetrics{Load1: 0.5, MemTotal: 100},
+	}
+	if err := exp.Export(context.Background(), m, nil); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	metrics := map[string]int{}
+	for _, m := range got.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		metrics[m.Name] = len(m.GetGauge().GetDataPoints()) + len(m.GetSum().GetDataPoints())
+		if m.Name == "orbit.service.memory.usage" && m.GetGauge().DataPoints[0].GetAsInt() != 42 {
+			t.Errorf("memory point = %v", m.GetGauge().DataPoints[0])
+		}
+		if m.Name == "orbit.service.network.io" && !m.GetSum().IsMonotonic {
+			t.Error("network.io is not a monotonic sum")
+		}
+	}
+	if metrics["orbit.service.network.io"] != 2 || metrics["orbit.host.load"] != 3 || metrics["orbit.service.memory.usage"] != 1 {
+		t.Fatalf("metrics = %v", metrics)
+	}
+}
+
+func TestMetricsExporterReportsRejection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	exp, _ := NewMetricsExporter(srv.URL, "test")
+	if err := exp.Export(context.Background(), v1.Metrics{}, nil); err == nil {
+		t.Fatal("expected an error for a 400 response")
+	}
+	if _, err := NewMetricsExporter("otel:4318", "test"); err == nil {
+		t.Fatal("expected an error for an endpoint without scheme")
+	}
+}
@@ -0,0 +1,79 @@
+// Package telemetry exports orbit's traces and metrics to an OpenTelemetry
+// collector over OTLP/HTTP. Orchestration code records spans through Start
+// and End; they are dropped unless Setup has installed an exporter.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation names orbit's tracer and metric scope.
+const instrumentation = "github.com/f9-o/orbit"
+
+// Setup installs a global tracer provider that batches spans to endpoint, the
+// collector's OTLP/HTTP base URL (e.g. http://otel-collector:4318). The
+// returned func flushes pending spans and must be called before exit.
+func Setup(ctx context.Context, endpoint, version string) (func(context.Context) error, error) {
+	traceURL, err := signalURL(endpoint, "v1/traces")
+	if err != nil {
+		return nil, err
+	}
+	exp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(traceURL))
+	if err != nil {
+		return nil, fmt.Errorf("otlp trace exporter: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(resourceAttrs(version)...)),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Start opens a span named name under any span already in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err, if any, on span and ends it. Use it as
+// `defer func() { telemetry.End(span, err) }()` with a named error result.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// resourceAttrs identifies this orbit process to the collector.
+func resourceAttrs(version string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("service.name", "orbit"),
+		attribute.String("service.version", version),
+	}
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, attribute.String("host.name", host))
+	}
+	return attrs
+}
+
+// signalURL joins the collector base URL and a signal path such as v1/traces.
+func signalURL(endpoint, path string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("otlp endpoint %q: want an http:// or https:// URL", endpoint)
+	}
+	return strings.TrimSuffix(endpoint, "/") + "/" + path, nil
+}
@@ -0,0 +1,174 @@
+// Package transfer implements `orbit cp`: tar streaming between the local
+// filesystem and service containers, locally or on remote nodes.
+package transfer
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Tar writes src (a file or directory tree) to w as a tar archive whose root
+// entry is named root. Symlinks are stored as links, not followed.
+func Tar(w io.Writer, src, root string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		name := root
+		if rel != "." {
+			name = path.Join(root, filepath.ToSlash(rel))
+		}
+
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// Size returns the total size of the regular files under src.
+func Size(src string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// Untar extracts an archive whose entries share a single root (as produced by
+// Tar or the Docker copy API) following `cp` semantics: if dst is an existing
+// directory the root is created inside it, otherwise the root is renamed to dst.
+// Entries that would escape the destination are rejected.
+func Untar(r io.Reader, dst string) error {
+	target := dst
+	if info, err := os.Stat(dst); err == nil && info.IsDir() {
+		target = "" // resolved from the root entry's name
+	}
+
+	tr := tar.NewReader(r)
+	root := ""
+	var links []string // symlinks created so far; nothing may be written through them
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) {
+			return fmt.Errorf("archive entry %q is absolute", hdr.Name)
+		}
+		first, rest, _ := strings.Cut(name, "/")
+		if root == "" {
+			root = first
+			if target == "" {
+				target = filepath.Join(dst, root)
+			}
+		}
+		if first != root || first == ".." || strings.HasPrefix(rest, "../") || rest == ".." {
+			return fmt.Errorf("archive entry %q is outside %q", hdr.Name, root)
+		}
+		out := target
+		if rest != "" {
+			out = filepath.Join(target, filepath.FromSlash(rest))
+		}
+		for _, l := range links {
+			if strings.HasPrefix(out, l+string(filepath.Separator)) {
+				return fmt.Errorf("archive entry %q is written through symlink %q", hdr.Name, l)
+			}
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(out, hdr.FileInfo().Mode().Perm()|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(out, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+				return err
+			}
+			_ = os.Remove(out)
+			if err := os.Symlink(hdr.Linkname, out); err != nil {
+				return err
+			}
+			links = append(links, out)
+		default:
+			// devices, fifos, and hard links are skipped
+		}
+	}
+}
+
+func writeFile(p string, r io.Reader, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,133 @@
+package transfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, p string) string {
+	t.Helper()
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestTarUntarDirectory(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "html")
+	writeTree(t, src, map[string]string{"index.html": "hi", "css/site.css": "body{}"})
+
+	var buf bytes.Buffer
+	if err := Tar(&buf, src, "html"); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.Bytes()
+
+	// Existing directory: the tree lands inside it.
+	into := t.TempDir()
+	if err := Untar(bytes.NewReader(archive), into); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(into, "html", "css", "site.css")); got != "body{}" {
+		t.Errorf("site.css = %q", got)
+	}
+
+	// New path: the root is renamed.
+	renamed := filepath.Join(t.TempDir(), "public")
+	if err := Untar(bytes.NewReader(archive), renamed); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(renamed, "index.html")); got != "hi" {
+		t.Errorf("index.html = %q", got)
+	}
+
+	if n, err := Size(src); err != nil || n != int64(len("hi")+len("body{}")) {
+		t.Errorf("Size = %d, %v", n, err)
+	}
+}
+
+func TestUntarSingleFileRename(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "nginx.conf")
+	writeTree(t, filepath.Dir(src), map[string]string{"nginx.conf": "worker_processes 1;"})
+
+	var buf bytes.Buffer
+	if err := Tar(&buf, src, "nginx.conf"); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(t.TempDir(), "copy.conf")
+	if err := Untar(&buf, dst); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, dst); got != "worker_processes 1;" {
+		t.Errorf("copy.conf = %q", got)
+	}
+}
+
+func TestUntarRejectsEscapes(t *testing.T) {
+	cases := map[string][]*tar.Header{
+		"dotdot": {
+			{Name: "root/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "root/../../evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"absolute": {
+			{Name: "/etc/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"through symlink": {
+			{Name: "root/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "root/link", Typeflag: tar.TypeSymlink, Linkname: "/tmp"},
+			{Name: "root/link/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+	}
+	for name, headers := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			for _, h := range headers {
+				if err := tw.WriteHeader(h); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tw.Close()
+			if err := Untar(&buf, t.TempDir()); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	cases := map[string]Location{
+		"web:/etc/nginx":  {Service: "web", Path: "/etc/nginx"},
+		"./web:/etc":      {Path: "./web:/etc"},
+		"/abs/path":       {Path: "/abs/path"},
+		"notes.txt":       {Path: "notes.txt"},
+		"dir/file:colon":  {Path: "dir/file:colon"},
+		"api:relative/ok": {Service: "api", Path: "relative/ok"},
+	}
+	for arg, want := range cases {
+		if got := ParseLocation(arg); got != want {
+			t.Errorf("ParseLocation(%q) = %+v, want %+v", arg, got, want)
+		}
+	}
+	if !strings.Contains(ParseLocation("web:/x").String(), "web:") {
+		t.Error("String() should include the service")
+	}
+}
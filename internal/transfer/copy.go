@@ -0,0 +1,164 @@
+// Package transfer: copying between the local filesystem and containers.
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/pkg/sshutil"
+)
+
+// Location is one side of a copy: a local path, or a path inside the
+// container of Service when Service is set.
+type Location struct {
+	Service string
+	Path    string
+}
+
+// ParseLocation parses a cp argument. "web:/etc/nginx" names a path in the
+// web service's container; anything else is a local path. Arguments that
+// start with "." or "/" or contain a path separator before the colon are
+// always local, so "./a:b" is a file named "a:b".
+func ParseLocation(arg string) Location {
+	if strings.HasPrefix(arg, ".") || strings.HasPrefix(arg, "/") || filepath.VolumeName(arg) != "" {
+		return Location{Path: arg}
+	}
+	svc, p, ok := strings.Cut(arg, ":")
+	if !ok || svc == "" || strings.ContainsAny(svc, `/\`) {
+		return Location{Path: arg}
+	}
+	return Location{Service: svc, Path: p}
+}
+
+// Local reports whether l is on the local filesystem.
+func (l Location) Local() bool { return l.Service == "" }
+
+func (l Location) String() string {
+	if l.Local() {
+		return l.Path
+	}
+	return l.Service + ":" + l.Path
+}
+
+// Copier moves files in and out of a service container, through the Docker
+// API for the local node or over SSH (`docker cp` on the host) for remote ones.
+type Copier struct {
+	docker *orchestrator.Client
+	pool   *remote.Pool
+	node   *v1.NodeInfo
+
+	progress func(done int64)
+}
+
+// NewLocalCopier copies via the local Docker daemon.
+func NewLocalCopier(docker *orchestrator.Client) *Copier {
+	return &Copier{docker: docker}
+}
+
+// NewRemoteCopier copies via SSH to node.
+func NewRemoteCopier(pool *remote.Pool, node v1.NodeInfo) *Copier {
+	return &Copier{pool: pool, node: &node}
+}
+
+// WithProgress registers a callback receiving the running count of archive
+// bytes transferred.
+func (c *Copier) WithProgress(fn func(done int64)) *Copier {
+	c.progress = fn
+	return c
+}
+
+// Download copies src from the container to the local path dst.
+func (c *Copier) Download(ctx context.Context, containerID, src, dst string) error {
+	var archive io.Reader
+	if c.node == nil {
+		rc, _, err := c.docker.CopyFromContainer(ctx, containerID, src)
+		if err != nil {
+			return fmt.Errorf("copy from %s: %w", shortID(containerID), err)
+		}
+		defer rc.Close()
+		archive = rc
+	} else {
+		pr, pw := io.Pipe()
+		go func() {
+			cmd := fmt.Sprintf("docker cp %s -", sshutil.Quote(containerID+":"+src))
+			pw.CloseWithError(c.pool.Stream(ctx, *c.node, cmd, nil, pw))
+		}()
+		defer pr.Close()
+		archive = pr
+	}
+	return Untar(c.count(archive), dst)
+}
+
+// Upload copies the local path src into the container at dst. As with
+// `docker cp`, an existing directory at dst receives src inside it; otherwise
+// src is created as dst.
+func (c *Copier) Upload(ctx context.Context, src, containerID, dst string) error {
+	if _, err := os.Lstat(src); err != nil {
+		return err
+	}
+	if c.node != nil {
+		return c.uploadRemote(ctx, src, containerID, dst)
+	}
+
+	dir, root := dst, filepath.Base(src)
+	if st, err := c.docker.StatPath(ctx, containerID, dst); err != nil || !st.Mode.IsDir() {
+		dir, root = path.Dir(dst), path.Base(dst)
+	}
+	return c.docker.CopyToContainer(ctx, containerID, dir, c.count(tarStream(src, root)))
+}
+
+// uploadRemote stages src in a temporary directory on the node, then lets
+// `docker cp` place it, so dst follows the same rules as a local copy.
+func (c *Copier) uploadRemote(ctx context.Context, src, containerID, dst string) error {
+	root := filepath.Base(src)
+	cmd := fmt.Sprintf(`d=$(mktemp -d) && tar -xf - -C "$d" && docker cp "$d"/%s %s; rc=$?; rm -rf "$d"; exit $rc`,
+		sshutil.Quote(root), sshutil.Quote(containerID+":"+dst))
+	return c.pool.Stream(ctx, *c.node, cmd, c.count(tarStream(src, root)), nil)
+}
+
+// tarStream returns a reader producing Tar(src, root).
+func tarStream(src, root string) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(Tar(pw, src, root))
+	}()
+	return pr
+}
+
+func (c *Copier) count(r io.Reader) io.Reader {
+	if c.progress == nil {
+		return r
+	}
+	return &countingReader{r: r, fn: c.progress}
+}
+
+// countingReader reports the running byte count after every read.
+type countingReader struct {
+	r  io.Reader
+	n  int64
+	fn func(int64)
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	if n > 0 {
+		cr.n += int64(n)
+		cr.fn(cr.n)
+	}
+	return n, err
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
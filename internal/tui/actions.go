@@ -0,0 +1,187 @@
+// Package tui: service actions (stop, deploy, scale) run as async commands.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/tui/components"
+)
+
+// actionDoneMsg reports the outcome of a service action.
+type actionDoneMsg struct {
+	verb    string // past tense, e.g. "stopped"
+	service string
+	err     error
+}
+
+// selected returns the service under the cursor, if any.
+func (m *Model) selected() (v1.ServiceState, bool) {
+	visible := m.visibleServices()
+	if m.selectedService < 0 || m.selectedService >= len(visible) {
+		return v1.ServiceState{}, false
+	}
+	return visible[m.selectedService], true
+}
+
+// selectedLocal returns the selected service if it runs on the local node;
+// actions against remote nodes are not available from the dashboard.
+func (m *Model) selectedLocal() (v1.ServiceState, bool) {
+	svc, ok := m.selected()
+	if !ok {
+		return svc, false
+	}
+	if !m.isLocal(svc) {
+		m.footer.SetError(fmt.Errorf("%s runs on node %q; actions are only available for %q", svc.Name, svc.Node, m.cfg.Node))
+		return svc, false
+	}
+	return svc, true
+}
+
+// specFor looks up the orbit.yaml spec for a running service.
+func (m *Model) specFor(name string) (v1.ServiceSpec, error) {
+	if m.cfg.OrbitConfig != nil {
+		if spec := m.cfg.OrbitConfig.ServiceByName(name); spec != nil {
+			return *spec, nil
+		}
+	}
+	return v1.ServiceSpec{}, fmt.Errorf("service %q not found in orbit.yaml", name)
+}
+
+// beginAction marks an action as running and shows progress in the footer.
+// It returns false if another action is still in flight.
+func (m *Model) beginAction(progress string) bool {
+	if m.busy {
+		m.footer.SetError(fmt.Errorf("another action is still running"))
+		return false
+	}
+	m.busy = true
+	m.footer.SetError(nil)
+	m.footer.SetStatus("◌ " + progress)
+	return true
+}
+
+// confirmStop opens the stop confirmation modal for the selected service.
+func (m *Model) confirmStop() {
+	svc, ok := m.selectedLocal()
+	if !ok {
+		return
+	}
+	m.modal = components.NewConfirmModal(
+		fmt.Sprintf("Stop %s?", svc.Name),
+		fmt.Sprintf("This will stop and remove container %s", shortID(svc.ContainerID)),
+		m.styles.Modal,
+		func() tea.Cmd {
+			if !m.beginAction("Stopping " + svc.Name + "…") {
+				return nil
+			}
+			return m.stopCmd(svc.Name)
+		},
+	)
+}
+
+// promptScale opens the replica-count input modal for the selected service,
+// pre-filled with the current count.
+func (m *Model) promptScale() {
+	svc, ok := m.selectedLocal()
+	if !ok {
+		return
+	}
+	spec, err := m.specFor(svc.Name)
+	if err != nil {
+		m.footer.SetError(err)
+		return
+	}
+	current := max(svc.Replicas, 1)
+	m.modal = components.NewInputModal(
+		fmt.Sprintf("Scale %s", svc.Name),
+		fmt.Sprintf("Target number of replicas (currently %d):", current),
+		m.styles.Modal,
+		func(input string) tea.Cmd {
+			n, err := strconv.Atoi(input)
+			if err != nil || n < 0 {
+				m.footer.SetError(fmt.Errorf("invalid replica count %q", input))
+				return nil
+			}
+			if n == current {
+				m.footer.SetStatus(fmt.Sprintf("%s already has %d replica(s)", svc.Name, n))
+				return nil
+			}
+			if !m.beginAction(fmt.Sprintf("Scaling %s %d → %d…", svc.Name, current, n)) {
+				return nil
+			}
+			return m.scaleCmd(spec, n)
+		},
+	).WithValue(strconv.Itoa(current)).Numeric()
+}
+
+func (m *Model) stopCmd(name string) tea.Cmd {
+	docker, db, log, node, commands := m.cfg.DockerClient, m.cfg.State, m.cfg.Log, m.cfg.Node, m.cfg.CommandHooks
+	var specs []v1.ServiceSpec
+	if m.cfg.OrbitConfig != nil {
+		specs = m.cfg.OrbitConfig.Services
+	}
+	return func() tea.Msg {
+		lm := orchestrator.NewLifecycleManager(docker, db, log).WithServices(specs).WithCommandHooks(commands)
+		err := lm.Down(context.Background(), node, []string{name}, false)
+		return actionDoneMsg{verb: "stopped", service: name, err: err}
+	}
+}
+
+// scaleProgressMsg reports replica progress during a scale action.
+type scaleProgressMsg struct {
+	service         string
+	current, target int
+	updates         <-chan tea.Msg
+}
+
+func (m *Model) scaleCmd(spec v1.ServiceSpec, replicas int) tea.Cmd {
+	docker, db, log, node, hooks := m.cfg.DockerClient, m.cfg.State, m.cfg.Log, m.cfg.Node, m.cfg.Hooks
+	updates := make(chan tea.Msg, replicas+16)
+	go func() {
+		scaler := orchestrator.NewScaler(docker, db, log).WithHooks(hooks).WithProgress(func(current, target int) {
+			updates <- scaleProgressMsg{service: spec.Name, current: current, target: target, updates: updates}
+		})
+		err := scaler.Scale(context.Background(), spec, node, replicas)
+		updates <- actionDoneMsg{verb: fmt.Sprintf("scaled to %d", replicas), service: spec.Name, err: err}
+		close(updates)
+	}()
+	return waitUpdateCmd(updates)
+}
+
+// waitUpdateCmd delivers the next message from a long-running action.
+func waitUpdateCmd(updates <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-updates
+	}
+}
+
+// handleScaleProgress shows replica progress and refreshes the services table.
+func (m *Model) handleScaleProgress(msg scaleProgressMsg) tea.Cmd {
+	m.footer.SetStatus(fmt.Sprintf("◌ Scaling %s: %d/%d replicas", msg.service, msg.current, msg.target))
+	return tea.Batch(m.loadServicesCmd(), waitUpdateCmd(msg.updates))
+}
+
+// handleActionDone updates the footer and refreshes state after an action.
+func (m *Model) handleActionDone(msg actionDoneMsg) tea.Cmd {
+	m.busy = false
+	if msg.err != nil {
+		m.footer.SetStatus("")
+		m.footer.SetError(fmt.Errorf("%s: %w", msg.service, msg.err))
+	} else {
+		m.footer.SetStatus(fmt.Sprintf("✓ %s %s", msg.service, msg.verb))
+	}
+	return m.loadServicesCmd()
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,45 @@
+// Package tui: active alerts raised by orbit agent, shown in the header and timeline.
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/tui/components"
+)
+
+// alertListMsg carries the active alerts read from state.
+type alertListMsg []v1.Alert
+
+// loadAlertsCmd reads the active alerts. The agent owns evaluation; the
+// dashboard only reflects what it has recorded.
+func (m *Model) loadAlertsCmd() tea.Cmd {
+	return func() tea.Msg {
+		alerts, err := m.cfg.State.ListAlerts()
+		if err != nil {
+			return errMsg(err)
+		}
+		return alertListMsg(alerts)
+	}
+}
+
+// handleAlerts records alerts that fired or resolved since the last load.
+func (m *Model) handleAlerts(alerts []v1.Alert) {
+	current := make(map[string]v1.Alert, len(alerts))
+	for _, a := range alerts {
+		key := state.AlertKey(a)
+		current[key] = a
+		if _, seen := m.alerts[key]; !seen {
+			m.recordEvent(components.EventError, "alert", "%s: %s", a.Rule, a.Message)
+			m.timeline[len(m.timeline)-1].Time = a.FiredAt
+		}
+	}
+	for key, a := range m.alerts {
+		if _, ok := current[key]; !ok {
+			m.recordEvent(components.EventOK, "alert", "%s resolved: %s", a.Rule, a.Subject)
+		}
+	}
+	m.alerts = current
+	m.header.SetAlertCount(len(current))
+}
@@ -2,6 +2,8 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -13,8 +15,10 @@ import (
 	"github.com/f9-o/orbit/internal/core/config"
 	"github.com/f9-o/orbit/internal/core/logger"
 	"github.com/f9-o/orbit/internal/core/state"
+	"github.com/f9-o/orbit/internal/health"
 	"github.com/f9-o/orbit/internal/metrics"
 	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/remote"
 	"github.com/f9-o/orbit/internal/tui/components"
 )
 
@@ -25,6 +29,14 @@ type Config struct {
 	State        *state.DB
 	Log          *logger.Logger
 	OrbitConfig  *config.Config
+	Health       *health.Monitor           // optional — when set, health transitions refresh the view
+	Heartbeat    *remote.Engine            // optional — node status changes feed the event timeline
+	Palette      *components.Palette       // optional — defaults to orbit-dark
+	Keymap       *Keymap                   // optional — defaults to defaultKeymap()
+	Hooks        v1.HookDispatcher         // optional — plugin hooks fired by deploys and scales started here
+	Selector     config.Selector           // optional — only the services it matches are listed
+	Policies     []orchestrator.Policy     // optional — check each image before a deploy started here
+	CommandHooks orchestrator.CommandHooks // optional — runs services' hook commands for actions started here
 }
 
 // ActivePanel identifies which main panel has focus.
@@ -34,11 +46,19 @@ const (
 	PanelServices ActivePanel = iota
 	PanelLogs
 	PanelMetrics
+	PanelHistory
+	PanelEvents
+
+	panelCount = 5
 )
 
 // Model is the root Bubble Tea model (Elm architecture).
 type Model struct {
-	cfg Config
+	cfg  Config
+	keys Keymap
+
+	// node is the scope for services, logs, and metrics; allNodes aggregates.
+	node string
 
 	// Dimensions
 	width  int
@@ -61,12 +81,37 @@ type Model struct {
 	// Selected service for log/metrics view
 	selectedService int
 
+	// Deployment history for the selected service
+	history         []v1.DeploymentRecord
+	historyService  string
+	selectedHistory int
+
 	// Collector
-	collector *metrics.Collector
+	collector     *metrics.Collector
+	stopCollector context.CancelFunc
+
+	// Container event subscription that drives service reloads
+	watch containerWatch
 
 	// Error state
 	lastError error
 
+	// Container log stream for the logs panel
+	stream logStream
+
+	// Active alerts by state.AlertKey, as last read from state
+	alerts map[string]v1.Alert
+
+	// Event timeline; timelineOffset scrolls back from the newest entry
+	timeline       []components.TimelineEvent
+	timelineOffset int
+
+	// Incremental search across services and logs
+	search search
+
+	// busy is set while a stop/deploy/scale action is in flight
+	busy bool
+
 	// Theme
 	styles Styles
 }
@@ -86,24 +131,39 @@ type serviceListMsg []v1.ServiceState
 // nodeListMsg carries an updated nodes list.
 type nodeListMsg []v1.NodeInfo
 
+// healthEventMsg carries a service health transition from the monitor.
+type healthEventMsg health.ServiceEvent
+
 // errMsg carries an error to display in the status bar.
 type errMsg error
 
 // New constructs a new TUI Model.
 func New(cfg Config) *Model {
-	styles := newStyles()
+	if cfg.Palette != nil {
+		components.SetPalette(*cfg.Palette)
+	}
+	styles := newStyles(components.CurrentPalette())
 	lv := viewport.New(0, 0)
 	lv.Style = styles.LogViewport
 
 	collector := metrics.NewCollector(cfg.DockerClient, cfg.Node, cfg.Log)
 
+	keys := defaultKeymap()
+	if cfg.Keymap != nil {
+		keys = *cfg.Keymap
+	}
+	footer := components.NewFooter()
+	footer.SetHints(keys.Hints())
+
 	return &Model{
 		cfg:         cfg,
+		keys:        keys,
+		node:        cfg.Node,
 		logViewport: lv,
 		styles:      styles,
 		header:      components.NewHeader(cfg.Node),
 		sidebar:     components.NewSidebar(),
-		footer:      components.NewFooter(),
+		footer:      footer,
 		collector:   collector,
 	}
 }
@@ -117,7 +177,12 @@ func (m *Model) Init() tea.Cmd {
 		m.tickCmd(),
 		m.loadServicesCmd(),
 		m.loadNodesCmd(),
+		m.loadAlertsCmd(),
+		m.loadCertsCmd(),
 		m.startCollectorCmd(),
+		m.startWatchCmd(),
+		m.waitHealthEventCmd(),
+		m.waitNodeEventCmd(),
 	)
 }
 
@@ -144,35 +209,97 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, cmd
 		}
+		if m.search.active {
+			m.handleSearchKey(msg)
+			return m, nil
+		}
+		if !m.busy {
+			m.footer.SetStatus("") // dismiss the previous action's result
+		}
 		cmds = append(cmds, m.handleKey(msg))
+		if !m.busy {
+			m.updateSearchStatus()
+		}
 
 	case tickMsg:
-		cmds = append(cmds, m.tickCmd(), m.loadServicesCmd())
+		cmds = append(cmds, m.tickCmd(), m.loadAlertsCmd())
+		if m.servicesStale(time.Time(msg)) {
+			cmds = append(cmds, m.loadServicesCmd())
+		}
+		if m.panel == PanelHistory {
+			cmds = append(cmds, m.loadHistoryCmd())
+		}
 		m.metrics = m.collector.AllMetrics()
+		m.header.SetHost(m.scopedHost())
 
 	case serviceListMsg:
 		m.services = msg
+		m.watch.lastLoad = time.Now()
 		m.header.SetServiceCount(len(msg))
+		cmds = append(cmds, m.followReplacement(msg))
 
 	case nodeListMsg:
 		m.nodes = msg
-		nodeNames := make([]string, len(msg))
-		for i, n := range msg {
-			nodeNames[i] = n.Spec.Name
-		}
-		m.sidebar.SetNodes(nodeNames)
-		m.header.SetNodeCount(len(msg))
+		m.syncNodeViews()
+
+	case alertListMsg:
+		m.handleAlerts(msg)
+
+	case certListMsg:
+		m.handleCerts(msg)
 
 	case metricsMsg:
 		m.metrics = v1.Metrics(msg)
 
 	case logLineMsg:
-		m.logLines = append(m.logLines, string(msg))
-		if len(m.logLines) > 500 {
-			m.logLines = m.logLines[len(m.logLines)-500:]
+		m.appendLog(string(msg))
+
+	case containerLogMsg:
+		if msg.gen == m.stream.gen {
+			m.appendLog(msg.line)
+			cmds = append(cmds, waitLogLineCmd(msg.gen, m.stream.lines, m.stream.done))
 		}
-		m.logViewport.SetContent(joinLines(m.logLines))
-		m.logViewport.GotoBottom()
+
+	case logStreamEndedMsg:
+		if msg.gen == m.stream.gen && m.stream.service != "" {
+			end := "── log stream ended ──"
+			if msg.err != nil && !errors.Is(msg.err, context.Canceled) {
+				end = fmt.Sprintf("── log stream ended: %v ──", msg.err)
+			}
+			m.appendLog(end)
+		}
+
+	case actionDoneMsg:
+		m.recordActionDone(msg)
+		cmds = append(cmds, m.handleActionDone(msg))
+
+	case execDoneMsg:
+		cmds = append(cmds, m.handleExecDone(msg))
+
+	case historyMsg:
+		m.handleHistory(msg)
+
+	case deployPlanMsg:
+		m.handleDeployPlan(msg)
+
+	case deployProgressMsg:
+		m.recordDeployStep(msg.service, msg.step)
+		cmds = append(cmds, m.handleDeployProgress(msg))
+
+	case scaleProgressMsg:
+		cmds = append(cmds, m.handleScaleProgress(msg))
+
+	case healthEventMsg:
+		m.appendLog(formatHealthEvent(health.ServiceEvent(msg)))
+		m.recordHealthEvent(health.ServiceEvent(msg))
+		cmds = append(cmds, m.loadServicesCmd(), m.waitHealthEventCmd())
+
+	case watchStartedMsg, containerEventMsg, reloadServicesMsg, watchEndedMsg, watchRetryMsg:
+		cmds = append(cmds, m.handleWatch(msg))
+
+	case nodeEventMsg:
+		m.recordNodeEvent(remote.NodeEvent(msg))
+		cmds = append(cmds, m.loadNodesCmd(), m.waitNodeEventCmd())
 
 	case errMsg:
 		m.lastError = msg
@@ -189,44 +316,104 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // handleKey processes keyboard input when no modal is open.
 func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
-	kb := defaultKeymap()
+	kb := m.keys
 
 	switch msg.String() {
 	case kb.Quit:
+		m.stopLogStream()
+		m.stopWatch()
+		if m.stopCollector != nil {
+			m.stopCollector()
+		}
 		return tea.Quit
 
 	case kb.TabNext:
-		m.panel = (m.panel + 1) % 3
+		m.panel = (m.panel + 1) % panelCount
+		if m.panel == PanelHistory {
+			return m.loadHistoryCmd()
+		}
 
 	case kb.TabPrev:
-		m.panel = (m.panel + 2) % 3 // wrap backwards
+		m.panel = (m.panel + panelCount - 1) % panelCount // wrap backwards
+		if m.panel == PanelHistory {
+			return m.loadHistoryCmd()
+		}
 
-	case kb.NavDown, "j":
-		if m.panel == PanelServices && m.selectedService < len(m.services)-1 {
+	case kb.NavDown, kb.NavDownAlt:
+		if m.panel == PanelServices && m.selectedService < len(m.visibleServices())-1 {
 			m.selectedService++
 		}
+		if m.panel == PanelHistory && m.selectedHistory < len(m.history)-1 {
+			m.selectedHistory++
+		}
+		if m.panel == PanelEvents {
+			m.scrollTimeline(-1)
+		}
 
-	case kb.NavUp, "k":
+	case kb.NavUp, kb.NavUpAlt:
 		if m.panel == PanelServices && m.selectedService > 0 {
 			m.selectedService--
 		}
+		if m.panel == PanelHistory && m.selectedHistory > 0 {
+			m.selectedHistory--
+		}
+		if m.panel == PanelEvents {
+			m.scrollTimeline(1)
+		}
 
-	case "l":
+	case kb.Logs:
 		m.panel = PanelLogs
+		if svc, ok := m.selected(); ok && m.isLocal(svc) && svc.ContainerID != m.stream.containerID {
+			return m.startLogStream(svc, "following")
+		}
+
+	case kb.Events:
+		m.panel = PanelEvents
+		m.timelineOffset = 0
+
+	case kb.Help:
+		m.modal = components.NewHelpModal(kb.HelpText(), m.styles.Modal)
+
+	case kb.Search:
+		m.search.active = true
+
+	case kb.Clear:
+		m.search = search{}
+		m.refreshLogView()
+
+	case kb.Nodes, kb.NodesPrev:
+		delta := 1
+		if msg.String() == kb.NodesPrev {
+			delta = -1
+		}
+		if m.panel == PanelLogs && m.search.query != "" {
+			m.jumpToMatch(delta)
+			return nil
+		}
+		return m.switchNode(delta)
 
-	case "?":
-		m.modal = components.NewHelpModal(m.styles.Modal)
-
-	case "x":
-		if len(m.services) > 0 && m.selectedService < len(m.services) {
-			svc := m.services[m.selectedService]
-			m.modal = components.NewConfirmModal(
-				fmt.Sprintf("Stop %s?", svc.Name),
-				fmt.Sprintf("This will stop and remove container %s", svc.ContainerID[:12]),
-				m.styles.Modal,
-				nil,
-			)
+	case kb.NavRight:
+		return m.switchNode(1)
+
+	case kb.NavLeft:
+		return m.switchNode(-1)
+
+	case kb.Stop:
+		m.confirmStop()
+
+	case kb.Exec:
+		return m.execIntoSelected()
+
+	case kb.Rollback:
+		if m.panel == PanelHistory {
+			m.confirmRollback()
 		}
+
+	case kb.Deploy:
+		m.startDeployWizard()
+
+	case kb.Scale:
+		m.promptScale()
 	}
 	return nil
 }
@@ -261,12 +448,16 @@ func (m *Model) renderMain() string {
 
 	switch m.panel {
 	case PanelServices:
-		return components.RenderServicesTable(m.services, m.metrics, m.selectedService, m.styles, mainWidth, m.height-6)
+		return components.RenderServicesTable(m.visibleServices(), m.scopedMetrics(), m.selectedService, m.node == allNodes, m.styles, mainWidth, m.height-6)
 	case PanelLogs:
 		title := m.styles.PanelTitle.Render("LOGS")
 		return lipgloss.JoinVertical(lipgloss.Left, title, m.logViewport.View())
 	case PanelMetrics:
-		return components.RenderMetrics(m.metrics, m.styles, mainWidth, m.height-6)
+		return components.RenderMetrics(m.scopedMetrics(), m.styles, mainWidth, m.height-6)
+	case PanelHistory:
+		return components.RenderHistory(m.historyService, m.history, m.selectedHistory, mainWidth, m.height-6)
+	case PanelEvents:
+		return components.RenderEvents(m.timeline, m.timelineOffset, mainWidth, m.height-6)
 	}
 	return ""
 }
@@ -282,13 +473,34 @@ func (m *Model) tickCmd() tea.Cmd {
 }
 
 func (m *Model) loadServicesCmd() tea.Cmd {
+	node := m.node
 	return func() tea.Msg {
-		states, err := m.cfg.State.ListServiceStates(m.cfg.Node)
+		states, err := m.cfg.State.ListServiceStates(node)
 		if err != nil {
 			return errMsg(err)
 		}
-		return serviceListMsg(states)
+		return serviceListMsg(m.selectServices(states))
+	}
+}
+
+// selectServices returns the states of states that cfg.Selector matches,
+// by the labels of their spec in orbit.yaml and orbit's own.
+func (m *Model) selectServices(states []v1.ServiceState) []v1.ServiceState {
+	sel := m.cfg.Selector
+	if len(sel) == 0 {
+		return states
+	}
+	var out []v1.ServiceState
+	for _, s := range states {
+		var spec *v1.ServiceSpec
+		if m.cfg.OrbitConfig != nil && (s.Project == "" || s.Project == m.cfg.State.ProjectName()) {
+			spec = m.cfg.OrbitConfig.ServiceByName(s.Name)
+		}
+		if sel.Matches(config.ServiceLabels(spec, s.Name, s.Node, s.Project)) {
+			out = append(out, s)
+		}
 	}
+	return out
 }
 
 func (m *Model) loadNodesCmd() tea.Cmd {
@@ -301,13 +513,54 @@ func (m *Model) loadNodesCmd() tea.Cmd {
 	}
 }
 
+// startCollectorCmd runs the metrics collector for the dashboard's lifetime.
 func (m *Model) startCollectorCmd() tea.Cmd {
+	if m.cfg.DockerClient == nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.stopCollector = cancel
 	return func() tea.Msg {
-		// Collector is started in a separate goroutine — no msg returned
+		m.collector.Run(ctx) // returns only on quit — no msg
 		return nil
 	}
 }
 
+// waitHealthEventCmd blocks until the monitor publishes the next health event.
+func (m *Model) waitHealthEventCmd() tea.Cmd {
+	if m.cfg.Health == nil {
+		return nil
+	}
+	events := m.cfg.Health.Events()
+	return func() tea.Msg {
+		return healthEventMsg(<-events)
+	}
+}
+
+// appendLog adds a line to the log panel, keeping the last 500 lines.
+func (m *Model) appendLog(line string) {
+	m.logLines = append(m.logLines, line)
+	if len(m.logLines) > 500 {
+		m.logLines = m.logLines[len(m.logLines)-500:]
+	}
+	m.refreshLogView()
+	if m.search.query == "" {
+		m.logViewport.GotoBottom()
+	}
+}
+
+// formatHealthEvent renders a health transition as a log-panel line.
+func formatHealthEvent(ev health.ServiceEvent) string {
+	ts := ev.Time.Local().Format("15:04:05")
+	if ev.CrashLoop {
+		return fmt.Sprintf("%s  ✖ %s crash-looping — container stopped", ts, ev.Service)
+	}
+	if ev.Restarted {
+		return fmt.Sprintf("%s  ⟳ %s restarted (liveness failing)", ts, ev.Service)
+	}
+	return fmt.Sprintf("%s  ◉ %s %s → %s", ts, ev.Service, ev.From, ev.To)
+}
+
 // joinLines concatenates log lines with newlines.
 func joinLines(lines []string) string {
 	out := ""
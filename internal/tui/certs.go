@@ -0,0 +1,47 @@
+// Package tui: local certificates close to expiry, shown in the header and timeline.
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/ssl"
+	"github.com/f9-o/orbit/internal/tui/components"
+)
+
+// certListMsg carries the certificates read from ssl.cert_dir.
+type certListMsg []ssl.CertInfo
+
+// loadCertsCmd inspects the local certificates once; expiry moves in days,
+// so the dashboard does not poll for it.
+func (m *Model) loadCertsCmd() tea.Cmd {
+	if m.cfg.OrbitConfig == nil {
+		return nil
+	}
+	sslCfg := m.cfg.OrbitConfig.SSL
+	return func() tea.Msg {
+		certs, err := ssl.NewInspector(sslCfg.RenewDays).WithLocalCA(config.LocalCADir()).InspectDir(sslCfg.ResolvedCertDir())
+		if err != nil {
+			return errMsg(err)
+		}
+		return certListMsg(certs)
+	}
+}
+
+// handleCerts records a timeline event for each certificate that is due for
+// renewal, expired or invalid, and counts them in the header.
+func (m *Model) handleCerts(certs []ssl.CertInfo) {
+	n := 0
+	for _, c := range certs {
+		if !c.NeedsAttention() {
+			continue
+		}
+		n++
+		level := components.EventWarn
+		if c.Status != ssl.CertRenew {
+			level = components.EventError
+		}
+		m.recordEvent(level, "ssl", "%s certificate %s: %d days left", c.Domain, c.Status, c.DaysLeft)
+	}
+	m.header.SetCertWarnings(n)
+}
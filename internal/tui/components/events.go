@@ -0,0 +1,77 @@
+// Package components: event timeline panel.
+package components
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// EventLevel classifies a timeline entry for coloring.
+type EventLevel int
+
+const (
+	EventInfo EventLevel = iota
+	EventOK
+	EventWarn
+	EventError
+)
+
+// TimelineEvent is one line of the event timeline.
+type TimelineEvent struct {
+	Time   time.Time
+	Level  EventLevel
+	Source string // "node", "deploy", "health", ...
+	Text   string
+}
+
+// RenderEvents renders the newest events that fit in height, oldest at the top,
+// starting offset entries back from the end so the panel can be scrolled.
+func RenderEvents(events []TimelineEvent, offset int, width, height int) string {
+	title := lipgloss.NewStyle().
+		Foreground(pal.Primary).Bold(true).
+		Padding(0, 1).
+		Render("EVENTS")
+
+	if len(events) == 0 {
+		empty := lipgloss.NewStyle().
+			Foreground(pal.Muted).
+			Padding(2, 2).
+			Render("No events yet. Node heartbeats, deploys, and health transitions appear here.")
+		return lipgloss.NewStyle().Width(width).Height(height).
+			Render(lipgloss.JoinVertical(lipgloss.Left, title, empty))
+	}
+
+	rows := max(height-2, 1)
+	end := max(len(events)-offset, 0)
+	start := max(end-rows, 0)
+
+	timeStyle := lipgloss.NewStyle().Foreground(pal.Muted)
+	srcStyle := lipgloss.NewStyle().Foreground(pal.Accent)
+	lines := ""
+	for _, ev := range events[start:end] {
+		lines += fmt.Sprintf(" %s  %s %s  %s\n",
+			timeStyle.Render(ev.Time.Local().Format("15:04:05")),
+			levelMarker(ev.Level),
+			srcStyle.Render(fmt.Sprintf("%-7s", ev.Source)),
+			truncate(ev.Text, max(width-24, 10)),
+		)
+	}
+
+	return lipgloss.NewStyle().Width(width).Height(height).
+		Render(lipgloss.JoinVertical(lipgloss.Left, title, lines))
+}
+
+func levelMarker(l EventLevel) string {
+	switch l {
+	case EventOK:
+		return lipgloss.NewStyle().Foreground(pal.Success).Render("●")
+	case EventWarn:
+		return lipgloss.NewStyle().Foreground(pal.Warning).Render("▲")
+	case EventError:
+		return lipgloss.NewStyle().Foreground(pal.Danger).Render("✖")
+	default:
+		return lipgloss.NewStyle().Foreground(pal.Muted).Render("·")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/charmbracelet/lipgloss"
+
+	v1 "github.com/f9-o/orbit/api/v1"
 )
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -16,6 +18,9 @@ type Header struct {
 	node         string
 	serviceCount int
 	nodeCount    int
+	host         *v1.HostMetrics // scoped node's host reading; nil hides it
+	alertCount   int
+	certWarnings int
 }
 
 // NewHeader creates a Header for the named node.
@@ -25,24 +30,54 @@ func NewHeader(node string) Header {
 
 func (h *Header) SetServiceCount(n int) { h.serviceCount = n }
 func (h *Header) SetNodeCount(n int)    { h.nodeCount = n }
+func (h *Header) SetNode(node string)   { h.node = node }
+
+// SetAlertCount sets the number of active alerts; zero hides the badge.
+func (h *Header) SetAlertCount(n int) { h.alertCount = n }
+
+// SetCertWarnings sets the number of certificates due for renewal or
+// unusable; zero hides the badge.
+func (h *Header) SetCertWarnings(n int) { h.certWarnings = n }
+
+// SetHost sets the host metrics shown for the scoped node.
+func (h *Header) SetHost(host *v1.HostMetrics) { h.host = host }
 
 // View renders the header bar. Accepts total terminal width.
 func (h *Header) View(width int) string {
 	left := fmt.Sprintf(" ◉ ORBIT  %s ", h.node)
 	right := fmt.Sprintf(" %d nodes · %d services ",
 		h.nodeCount, h.serviceCount)
+	if hm := h.host; hm != nil {
+		right = fmt.Sprintf(" load %.2f · mem %s · disk %s · inodes %s ·",
+			hm.Load1, usedPercent(hm.MemTotal, hm.MemAvailable),
+			usedPercent(hm.DiskTotal, hm.DiskFree), usedPercent(hm.InodesTotal, hm.InodesFree)) + right
+	}
+	if h.certWarnings > 0 {
+		right = fmt.Sprintf(" ⚠ %d certs ·", h.certWarnings) + right
+	}
+	if h.alertCount > 0 {
+		right = fmt.Sprintf(" ▲ %d alerts ·", h.alertCount) + right
+	}
 	gap := width - len(left) - len(right)
 	if gap < 0 {
 		gap = 0
 	}
 	return lipgloss.NewStyle().
-		Background(lipgloss.Color("#7B8CDE")).
-		Foreground(lipgloss.Color("#0D0F18")).
+		Background(pal.Primary).
+		Foreground(pal.Background).
 		Bold(true).
 		Width(width).
 		Render(left + spaces(gap) + right)
 }
 
+// usedPercent formats the used share of total, or "-" when total is unknown.
+func usedPercent(total, free int64) string {
+	if total <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", float64(total-free)/float64(total)*100)
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Sidebar component
 // ─────────────────────────────────────────────────────────────────────────────
@@ -55,7 +90,7 @@ type Sidebar struct {
 
 type nodeEntry struct {
 	Name   string
-	Status string
+	Status v1.NodeStatus // empty when not heartbeated (local node, aggregate view)
 }
 
 // NewSidebar creates an empty Sidebar.
@@ -69,47 +104,82 @@ func (s *Sidebar) SetNodes(names []string) {
 	}
 }
 
+// SetStatuses attaches heartbeat status to entries by name; entries without
+// a status render without an indicator.
+func (s *Sidebar) SetStatuses(statuses map[string]v1.NodeStatus) {
+	for i := range s.items {
+		s.items[i].Status = statuses[s.items[i].Name]
+	}
+}
+
+// Select highlights the entry at index i.
+func (s *Sidebar) Select(i int) { s.selected = i }
+
 // View renders the sidebar.
 func (s *Sidebar) View(width, height int) string {
 	title := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#7B8CDE")).Bold(true).
+		Foreground(pal.Primary).Bold(true).
 		Render("NODES")
 
 	content := title + "\n"
 
 	if len(s.items) == 0 {
 		content += lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#4A5568")).
+			Foreground(pal.Muted).
 			Render("  (no nodes)")
 	}
 
 	for i, item := range s.items {
 		icon := "○ "
-		style := lipgloss.NewStyle().Foreground(lipgloss.Color("#E2E8F0")).PaddingLeft(2)
+		style := lipgloss.NewStyle().Foreground(pal.Text).PaddingLeft(2)
 		if i == s.selected {
 			icon = "▶ "
-			style = style.Foreground(lipgloss.Color("#56E0C8")).Bold(true)
+			style = style.Foreground(pal.Accent).Bold(true)
 		}
-		content += style.Render(icon+item.Name) + "\n"
+		content += style.Render(icon) + statusDot(item.Status) + style.UnsetPaddingLeft().Render(item.Name) + "\n"
 	}
 
 	return lipgloss.NewStyle().
-		Background(lipgloss.Color("#171A2B")).
+		Background(pal.Surface).
 		Width(width).Height(height).
 		BorderStyle(lipgloss.NormalBorder()).
 		BorderRight(true).
-		BorderForeground(lipgloss.Color("#4A5568")).
+		BorderForeground(pal.Muted).
 		Padding(1, 1).
 		Render(content)
 }
 
+// statusDot renders a colored heartbeat indicator followed by a space.
+func statusDot(status v1.NodeStatus) string {
+	var color lipgloss.Color
+	switch status {
+	case v1.NodeOnline:
+		color = pal.Success
+	case v1.NodeDegraded:
+		color = pal.Warning
+	case v1.NodeOffline:
+		color = pal.Danger
+	default:
+		return ""
+	}
+	return lipgloss.NewStyle().Foreground(color).Render("● ")
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Footer component
 // ─────────────────────────────────────────────────────────────────────────────
 
+// Hint is a key/description pair shown in the footer.
+type Hint struct {
+	Key  string
+	Desc string
+}
+
 // Footer renders the bottom hint bar.
 type Footer struct {
-	err error
+	err    error
+	status string
+	hints  []Hint
 }
 
 // NewFooter creates a Footer.
@@ -118,26 +188,39 @@ func NewFooter() Footer { return Footer{} }
 // SetError sets an error message to display.
 func (f *Footer) SetError(err error) { f.err = err }
 
+// SetHints replaces the key hints shown when there is no status or error.
+func (f *Footer) SetHints(h []Hint) { f.hints = h }
+
+// SetStatus sets a progress or result message shown in place of the key hints.
+func (f *Footer) SetStatus(msg string) { f.status = msg }
+
 // View renders the footer.
 func (f *Footer) View(width int) string {
-	hints := []struct{ key, desc string }{
-		{"↑↓", "navigate"}, {"l", "logs"}, {"s", "scale"},
-		{"d", "deploy"}, {"x", "stop"}, {"/", "search"}, {"?", "help"}, {"q", "quit"},
+	hints := f.hints
+	if hints == nil {
+		hints = []Hint{
+			{"↑↓", "navigate"}, {"l", "logs"}, {"s", "scale"},
+			{"d", "deploy"}, {"x", "stop"}, {"/", "search"}, {"?", "help"}, {"q", "quit"},
+		}
 	}
 
 	content := ""
 	for _, h := range hints {
-		content += lipgloss.NewStyle().Foreground(lipgloss.Color("#7B8CDE")).Bold(true).Render(h.key)
-		content += lipgloss.NewStyle().Foreground(lipgloss.Color("#4A5568")).Render(" " + h.desc + "  ")
+		content += lipgloss.NewStyle().Foreground(pal.Primary).Bold(true).Render(h.Key)
+		content += lipgloss.NewStyle().Foreground(pal.Muted).Render(" " + h.Desc + "  ")
+	}
+
+	if f.status != "" {
+		content = lipgloss.NewStyle().Foreground(pal.Accent).Render(f.status)
 	}
 
 	if f.err != nil {
-		content = lipgloss.NewStyle().Foreground(lipgloss.Color("#F56565")).
+		content = lipgloss.NewStyle().Foreground(pal.Danger).
 			Render("Error: " + f.err.Error())
 	}
 
 	return lipgloss.NewStyle().
-		Background(lipgloss.Color("#171A2B")).
+		Background(pal.Surface).
 		Width(width).Padding(0, 1).
 		Render(content)
 }
@@ -0,0 +1,92 @@
+// Package components: deployment history panel.
+package components
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// RenderHistory renders the deployment records for service, newest first.
+func RenderHistory(service string, records []v1.DeploymentRecord, selected int, width, height int) string {
+	headerStyle := lipgloss.NewStyle().
+		Foreground(pal.Muted).Bold(true).Padding(0, 1)
+	rowStyle := lipgloss.NewStyle().Foreground(pal.Text).Padding(0, 1)
+	selStyle := lipgloss.NewStyle().
+		Background(pal.Surface).
+		Foreground(pal.Accent).Bold(true).Padding(0, 1)
+
+	heading := "HISTORY"
+	if service != "" {
+		heading += " — " + service
+	}
+	title := lipgloss.NewStyle().
+		Foreground(pal.Primary).Bold(true).
+		Padding(0, 1).
+		Render(heading)
+
+	hdr := headerStyle.Render(
+		fmt.Sprintf("%-16s %-50s %-9s %s", "TIME", "IMAGE", "DURATION", "RESULT"),
+	)
+
+	rows := ""
+	for i, rec := range records {
+		images := truncate(rec.ToImage, 48)
+		if rec.FromImage != "" && rec.FromImage != rec.ToImage {
+			images = truncate(shortImage(rec.FromImage)+" → "+shortImage(rec.ToImage), 48)
+		}
+		line := fmt.Sprintf("%-16s %-50s %-9s %s",
+			rec.StartedAt.Local().Format("01-02 15:04:05"),
+			images,
+			(time.Duration(rec.DurationMS) * time.Millisecond).Round(100*time.Millisecond),
+			resultBadge(rec.Result),
+		)
+		if rec.Commit != "" {
+			line += " @" + rec.Commit[:min(7, len(rec.Commit))]
+		}
+		if i == selected {
+			rows += selStyle.Render("▶ "+line) + "\n"
+		} else {
+			rows += rowStyle.Render("  "+line) + "\n"
+		}
+	}
+
+	if len(records) == 0 {
+		rows = lipgloss.NewStyle().
+			Foreground(pal.Muted).
+			Padding(2, 2).
+			Render("No deployments recorded for this service.")
+	} else {
+		rows += "\n" + lipgloss.NewStyle().Foreground(pal.Muted).Padding(0, 1).
+			Render("r  roll back to the highlighted deployment's image")
+	}
+
+	return lipgloss.NewStyle().Width(width).Height(height).
+		Render(lipgloss.JoinVertical(lipgloss.Left, title, hdr, rows))
+}
+
+func resultBadge(result string) string {
+	switch result {
+	case "success":
+		return lipgloss.NewStyle().Foreground(pal.Success).Render("● success")
+	case "rolledback":
+		return lipgloss.NewStyle().Foreground(pal.Warning).Render("↺ rolled back")
+	case "failure":
+		return lipgloss.NewStyle().Foreground(pal.Danger).Render("○ failure")
+	default:
+		return lipgloss.NewStyle().Foreground(pal.Muted).Render("? " + result)
+	}
+}
+
+// shortImage drops the registry host from an image reference.
+func shortImage(ref string) string {
+	for i := len(ref) - 1; i >= 0; i-- {
+		if ref[i] == '/' {
+			return ref[i+1:]
+		}
+	}
+	return ref
+}
@@ -0,0 +1,36 @@
+// Package components: theme palette shared by all components.
+package components
+
+import "github.com/charmbracelet/lipgloss"
+
+// Palette is the set of theme colors every component renders with.
+type Palette struct {
+	Background lipgloss.Color
+	Surface    lipgloss.Color
+	Primary    lipgloss.Color
+	Accent     lipgloss.Color
+	Danger     lipgloss.Color
+	Warning    lipgloss.Color
+	Success    lipgloss.Color
+	Muted      lipgloss.Color
+	Text       lipgloss.Color
+}
+
+// pal is the active palette, defaulting to orbit-dark.
+var pal = Palette{
+	Background: "#0D0F18",
+	Surface:    "#171A2B",
+	Primary:    "#7B8CDE",
+	Accent:     "#56E0C8",
+	Danger:     "#F56565",
+	Warning:    "#ECC94B",
+	Success:    "#68D391",
+	Muted:      "#4A5568",
+	Text:       "#E2E8F0",
+}
+
+// SetPalette switches the colors used by all components.
+func SetPalette(p Palette) { pal = p }
+
+// CurrentPalette returns the active palette.
+func CurrentPalette() Palette { return pal }
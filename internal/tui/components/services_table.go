@@ -15,32 +15,43 @@ import (
 // Services Table
 // ─────────────────────────────────────────────────────────────────────────────
 
-// RenderServicesTable renders the service list table.
-func RenderServicesTable(services []v1.ServiceState, metrics v1.Metrics, selected int, styles interface{}, width, height int) string {
+// RenderServicesTable renders the service list table. showNode adds a NODE
+// column for the multi-node aggregate view.
+func RenderServicesTable(services []v1.ServiceState, metrics v1.Metrics, selected int, showNode bool, styles interface{}, width, height int) string {
 	headerStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#4A5568")).Bold(true).Padding(0, 1)
-	rowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#E2E8F0")).Padding(0, 1)
+		Foreground(pal.Muted).Bold(true).Padding(0, 1)
+	rowStyle := lipgloss.NewStyle().Foreground(pal.Text).Padding(0, 1)
 	selStyle := lipgloss.NewStyle().
-		Background(lipgloss.Color("#171A2B")).
-		Foreground(lipgloss.Color("#56E0C8")).Bold(true).Padding(0, 1)
+		Background(pal.Surface).
+		Foreground(pal.Accent).Bold(true).Padding(0, 1)
 
 	title := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#7B8CDE")).Bold(true).
+		Foreground(pal.Primary).Bold(true).
 		Padding(0, 1).
 		Render("SERVICES")
 
+	nodeCol := func(node string) string {
+		if !showNode {
+			return ""
+		}
+		return fmt.Sprintf("%-14s ", truncate(node, 13))
+	}
+
 	hdr := headerStyle.Render(
-		fmt.Sprintf("%-20s %-30s %-10s %-8s %s",
-			"NAME", "IMAGE", "HEALTH", "CPU%", "MEM"),
+		nodeCol("NODE") + fmt.Sprintf("%-20s %-30s %-10s %-9s %-8s %s",
+			"NAME", "IMAGE", "HEALTH", "RESTARTS", "CPU%", "MEM"),
 	)
 
 	rows := ""
 	for i, svc := range services {
 		health := healthBadge(svc.Status)
+		if svc.CrashLoop {
+			health = lipgloss.NewStyle().Foreground(pal.Danger).Render("↻ LOOP")
+		}
 
 		cpuStr := "-"
 		memStr := "-"
-		if m, ok := metrics.Services[svc.Name]; ok {
+		if m, ok := metrics.Services[svc.Name]; ok && (metrics.Node == "" || metrics.Node == svc.Node) {
 			cpuStr = fmt.Sprintf("%.1f%%", m.CPUPercent)
 			memStr = fmtBytes(m.MemBytes)
 		}
@@ -50,9 +61,19 @@ func RenderServicesTable(services []v1.ServiceState, metrics v1.Metrics, selecte
 			image = "..." + image[len(image)-25:]
 		}
 
-		line := fmt.Sprintf("%-20s %-30s %-10s %-8s %s",
-			truncate(svc.Name, 18), truncate(image, 28),
-			health, cpuStr, memStr,
+		name := svc.Name
+		if svc.Replicas > 1 {
+			name = fmt.Sprintf("%s ×%d", truncate(svc.Name, 14), svc.Replicas)
+		}
+
+		restarts := fmt.Sprint(svc.RestartCount)
+		if svc.OOMKilled {
+			restarts += " OOM"
+		}
+
+		line := nodeCol(svc.Node) + fmt.Sprintf("%-20s %-30s %-10s %-9s %-8s %s",
+			truncate(name, 18), truncate(image, 28),
+			health, restarts, cpuStr, memStr,
 		)
 
 		if i == selected {
@@ -64,7 +85,7 @@ func RenderServicesTable(services []v1.ServiceState, metrics v1.Metrics, selecte
 
 	if len(services) == 0 {
 		rows = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#4A5568")).
+			Foreground(pal.Muted).
 			Padding(2, 2).
 			Render("No services running. Run 'orbit up' to start.")
 	}
@@ -80,21 +101,22 @@ func RenderServicesTable(services []v1.ServiceState, metrics v1.Metrics, selecte
 // RenderMetrics renders the metrics sparkline panel.
 func RenderMetrics(metrics v1.Metrics, styles interface{}, width, height int) string {
 	title := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#7B8CDE")).Bold(true).
+		Foreground(pal.Primary).Bold(true).
 		Padding(0, 1).Render("METRICS")
 
 	content := title + "\n\n"
 
 	if len(metrics.Services) == 0 {
 		return content + lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#4A5568")).Padding(1, 2).
+			Foreground(pal.Muted).Padding(1, 2).
 			Render("No metrics available. Ensure services are running.")
 	}
 
 	for name, m := range metrics.Services {
 		bar := cpuBar(m.CPUPercent, 20)
-		content += fmt.Sprintf("  %-18s CPU: %s %5.1f%%   MEM: %s/%s\n",
-			name, bar, m.CPUPercent, fmtBytes(m.MemBytes), fmtBytes(m.MemLimit))
+		content += fmt.Sprintf("  %-18s CPU: %s %5.1f%%   MEM: %s/%s   IO: %s r / %s w   DISK: %s\n",
+			name, bar, m.CPUPercent, fmtBytes(m.MemBytes), fmtBytes(m.MemLimit),
+			fmtBytes(m.BlockReadBytes), fmtBytes(m.BlockWriteBytes), fmtBytes(m.DiskBytes))
 	}
 
 	return lipgloss.NewStyle().Width(width).Height(height).Render(content)
@@ -110,7 +132,11 @@ type Modal struct {
 	body      string
 	style     lipgloss.Style
 	onConfirm func() tea.Cmd
+	onSubmit  func(input string) tea.Cmd
 	input     string
+	numeric   bool
+	suggest   []string
+	suggestAt int
 	typ       modalType
 }
 
@@ -119,6 +145,7 @@ type modalType int
 const (
 	modalConfirm modalType = iota
 	modalHelp
+	modalInput
 )
 
 // NewConfirmModal creates a destructive-action confirmation modal.
@@ -132,17 +159,42 @@ func NewConfirmModal(title, body string, style lipgloss.Style, onConfirm func()
 	}
 }
 
-// NewHelpModal creates the keyboard help modal.
-func NewHelpModal(style lipgloss.Style) *Modal {
+// NewInputModal creates a modal that collects a single line of text and
+// passes it to onSubmit when Enter is pressed.
+func NewInputModal(title, body string, style lipgloss.Style, onSubmit func(input string) tea.Cmd) *Modal {
+	return &Modal{
+		title:    title,
+		body:     body,
+		style:    style,
+		onSubmit: onSubmit,
+		typ:      modalInput,
+	}
+}
+
+// WithValue pre-fills the modal's input.
+func (m *Modal) WithValue(v string) *Modal {
+	m.input = v
+	return m
+}
+
+// WithSuggestions lets Tab cycle the input through values.
+func (m *Modal) WithSuggestions(values []string) *Modal {
+	m.suggest = values
+	m.suggestAt = -1
+	return m
+}
+
+// Numeric restricts the modal's input to digits.
+func (m *Modal) Numeric() *Modal {
+	m.numeric = true
+	return m
+}
+
+// NewHelpModal creates the keyboard help modal showing body.
+func NewHelpModal(body string, style lipgloss.Style) *Modal {
 	return &Modal{
 		title: "Keyboard Shortcuts",
-		body: `
-  Tab / Shift+Tab    Cycle panels        l    Logs
-  ↑↓  /  j k        Navigate            s    Scale
-  ←→  /  h l        Switch tabs         d    Deploy
-  Enter              Select              x    Stop
-  /                  Search              q    Quit
-`,
+		body:  body,
 		style: style,
 		typ:   modalHelp,
 	}
@@ -150,17 +202,36 @@ func NewHelpModal(style lipgloss.Style) *Modal {
 
 // HandleKey processes a key for the modal. Returns (cmd, done).
 func (m *Modal) HandleKey(msg tea.KeyMsg) (tea.Cmd, bool) {
-	switch msg.String() {
+	key := msg.String()
+	if key == "q" && m.typ == modalInput {
+		key = "" // typed into the input, e.g. as part of an image tag
+	}
+	switch key {
 	case "esc", "q":
 		return nil, true
 	case "enter":
 		if m.typ == modalConfirm && m.onConfirm != nil {
 			return m.onConfirm(), true
 		}
+		if m.typ == modalInput && m.onSubmit != nil {
+			return m.onSubmit(strings.TrimSpace(m.input)), true
+		}
 		return nil, true
+	case "tab":
+		if len(m.suggest) > 0 {
+			m.suggestAt = (m.suggestAt + 1) % len(m.suggest)
+			m.input = m.suggest[m.suggestAt]
+		}
+	case "backspace":
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
 	default:
-		if m.typ == modalConfirm {
-			m.input += msg.String()
+		if (m.typ == modalConfirm || m.typ == modalInput) && msg.Type == tea.KeyRunes {
+			if m.numeric && strings.Trim(string(msg.Runes), "0123456789") != "" {
+				break
+			}
+			m.input += string(msg.Runes)
 		}
 	}
 	return nil, false
@@ -169,14 +240,23 @@ func (m *Modal) HandleKey(msg tea.KeyMsg) (tea.Cmd, bool) {
 // Overlay renders the modal centred over the background content.
 func (m *Modal) Overlay(bg string, width, height int) string {
 	content := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#ECC94B")).Bold(true).
+		Foreground(pal.Warning).Bold(true).
 		Render("⚠  "+m.title) + "\n\n"
 	content += m.body
 
-	if m.typ == modalConfirm {
+	switch m.typ {
+	case modalConfirm:
 		content += "\n\n  > " + m.input + "█"
 		content += "\n\n  [Enter] Confirm   [Esc] Cancel"
-	} else {
+	case modalInput:
+		content += "\n\n  > " + m.input + "█"
+		if len(m.suggest) > 0 {
+			content += "\n\n  Recent: " + strings.Join(m.suggest, ", ")
+			content += "\n\n  [Tab] Cycle   [Enter] Submit   [Esc] Cancel"
+		} else {
+			content += "\n\n  [Enter] Submit   [Esc] Cancel"
+		}
+	default:
 		content += "\n\n  [Esc] Close"
 	}
 
@@ -217,13 +297,13 @@ func (m *Modal) Overlay(bg string, width, height int) string {
 func healthBadge(status v1.ServiceStatus) string {
 	switch status {
 	case v1.StatusHealthy:
-		return lipgloss.NewStyle().Foreground(lipgloss.Color("#68D391")).Render("● OK")
+		return lipgloss.NewStyle().Foreground(pal.Success).Render("● OK")
 	case v1.StatusDegraded:
-		return lipgloss.NewStyle().Foreground(lipgloss.Color("#ECC94B")).Render("◐ DEG")
+		return lipgloss.NewStyle().Foreground(pal.Warning).Render("◐ DEG")
 	case v1.StatusUnhealthy:
-		return lipgloss.NewStyle().Foreground(lipgloss.Color("#F56565")).Render("○ ERR")
+		return lipgloss.NewStyle().Foreground(pal.Danger).Render("○ ERR")
 	default:
-		return lipgloss.NewStyle().Foreground(lipgloss.Color("#4A5568")).Render("? UNK")
+		return lipgloss.NewStyle().Foreground(pal.Muted).Render("? UNK")
 	}
 }
 
@@ -233,11 +313,11 @@ func cpuBar(pct float64, width int) string {
 		filled = width
 	}
 	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
-	color := lipgloss.Color("#68D391")
+	color := pal.Success
 	if pct > 80 {
-		color = lipgloss.Color("#F56565")
+		color = pal.Danger
 	} else if pct > 50 {
-		color = lipgloss.Color("#ECC94B")
+		color = pal.Warning
 	}
 	return lipgloss.NewStyle().Foreground(color).Render("[" + bar + "]")
 }
@@ -0,0 +1,202 @@
+// Package tui: deployment wizard — pick a tag, review the plan, deploy with progress.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/tui/components"
+)
+
+// maxSuggestedTags caps the recent tags offered by the wizard.
+const maxSuggestedTags = 5
+
+// deployPlanMsg carries the plan computed for a wizard deploy.
+type deployPlanMsg struct {
+	spec v1.ServiceSpec
+	plan *orchestrator.Plan
+	err  error
+}
+
+// deployProgressMsg reports the step a running deploy has reached.
+type deployProgressMsg struct {
+	service string
+	step    orchestrator.DeployStep
+	updates <-chan tea.Msg
+}
+
+// startDeployWizard opens the tag prompt for the selected service.
+func (m *Model) startDeployWizard() {
+	svc, ok := m.selectedLocal()
+	if !ok {
+		return
+	}
+	spec, err := m.specFor(svc.Name)
+	if err != nil {
+		m.footer.SetError(err)
+		return
+	}
+
+	current := imageTag(spec.Image)
+	m.modal = components.NewInputModal(
+		fmt.Sprintf("Deploy %s", svc.Name),
+		fmt.Sprintf("Image: %s\nTarget tag:", spec.Image),
+		m.styles.Modal,
+		func(tag string) tea.Cmd {
+			if tag == "" {
+				tag = current
+			}
+			planned := spec
+			planned.Image = orchestrator.ResolveImage(spec.Image, tag)
+			return m.planDeployCmd(planned)
+		},
+	).WithValue(current).WithSuggestions(m.recentTags(svc.Name))
+}
+
+// recentTags returns distinct image tags from the service's deployment
+// history, newest first.
+func (m *Model) recentTags(service string) []string {
+	recs, err := m.cfg.State.ListDeployments(service)
+	if err != nil {
+		return nil
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].StartedAt.After(recs[j].StartedAt) })
+
+	seen := map[string]bool{}
+	var tags []string
+	for _, r := range recs {
+		tag := imageTag(r.ToImage)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+		if len(tags) == maxSuggestedTags {
+			break
+		}
+	}
+	return tags
+}
+
+// planDeployCmd computes the plan for deploying spec.
+func (m *Model) planDeployCmd(spec v1.ServiceSpec) tea.Cmd {
+	docker, db, log, node := m.cfg.DockerClient, m.cfg.State, m.cfg.Log, m.cfg.Node
+	return func() tea.Msg {
+		planner := orchestrator.NewPlanner(docker, db, log)
+		plan, err := planner.Plan(context.Background(), []v1.ServiceSpec{spec}, node, false)
+		return deployPlanMsg{spec: spec, plan: plan, err: err}
+	}
+}
+
+// handleDeployPlan shows the plan summary and asks for confirmation.
+func (m *Model) handleDeployPlan(msg deployPlanMsg) {
+	if msg.err != nil {
+		m.footer.SetError(fmt.Errorf("plan %s: %w", msg.spec.Name, msg.err))
+		return
+	}
+	spec := msg.spec
+	m.modal = components.NewConfirmModal(
+		fmt.Sprintf("Deploy %s?", spec.Name),
+		planSummary(msg.plan),
+		m.styles.Modal,
+		func() tea.Cmd {
+			if !m.beginAction("Deploying " + spec.Name + "…") {
+				return nil
+			}
+			return m.deployCmd(spec)
+		},
+	)
+}
+
+// deployCmd runs a rolling deploy, streaming step progress back to the model.
+func (m *Model) deployCmd(spec v1.ServiceSpec) tea.Cmd {
+	docker, db, log, node, hooks, policies := m.cfg.DockerClient, m.cfg.State, m.cfg.Log, m.cfg.Node, m.cfg.Hooks, m.cfg.Policies
+	commands := m.cfg.CommandHooks
+	updates := make(chan tea.Msg, len(orchestrator.DeploySteps)+2)
+	go func() {
+		checker := health.NewChecker(log).WithExecer(docker).WithInspector(docker)
+		deployer := orchestrator.NewDeployer(docker, db, checker, log).WithHooks(hooks).WithCommandHooks(commands).WithProgress(func(step orchestrator.DeployStep) {
+			updates <- deployProgressMsg{service: spec.Name, step: step, updates: updates}
+		})
+		for _, policy := range policies {
+			deployer.WithPolicy(policy)
+		}
+		err := deployer.Deploy(context.Background(), spec, node, orchestrator.DeployOptions{})
+		updates <- actionDoneMsg{verb: "deployed " + imageTag(spec.Image), service: spec.Name, err: err}
+		close(updates)
+	}()
+	return waitUpdateCmd(updates)
+}
+
+// handleDeployProgress renders a step indicator such as "[2/4] starting container".
+func (m *Model) handleDeployProgress(msg deployProgressMsg) tea.Cmd {
+	label := map[orchestrator.DeployStep]string{
+		orchestrator.StepPreDeploy:  "running pre_deploy hooks",
+		orchestrator.StepPull:       "pulling image",
+		orchestrator.StepMigrate:    "running migrations",
+		orchestrator.StepStart:      "starting new container",
+		orchestrator.StepHealth:     "waiting for health checks",
+		orchestrator.StepCutover:    "cutting over",
+		orchestrator.StepVerify:     "verifying after cut-over",
+		orchestrator.StepPostDeploy: "running post_deploy hooks",
+		orchestrator.StepRollback:   "deploy failed — rolling back",
+	}[msg.step]
+
+	n := len(orchestrator.DeploySteps)
+	pos := n
+	if msg.step == orchestrator.StepPreDeploy {
+		pos = 0
+	}
+	for i, s := range orchestrator.DeploySteps {
+		if s == msg.step {
+			pos = i + 1
+		}
+	}
+	bar := strings.Repeat("●", pos) + strings.Repeat("○", n-pos)
+	m.footer.SetStatus(fmt.Sprintf("◌ Deploying %s  %s [%d/%d] %s", msg.service, bar, pos, n, label))
+	return tea.Batch(m.loadServicesCmd(), waitUpdateCmd(msg.updates))
+}
+
+// planSummary renders a plan as modal body text.
+func planSummary(plan *orchestrator.Plan) string {
+	var sb strings.Builder
+	for _, s := range plan.Services {
+		switch s.Action {
+		case orchestrator.ActionCreate:
+			fmt.Fprintf(&sb, "+ %s (create)\n", s.Service)
+		case orchestrator.ActionUpdate:
+			fmt.Fprintf(&sb, "~ %s (update)\n", s.Service)
+		case orchestrator.ActionDestroy:
+			fmt.Fprintf(&sb, "- %s (destroy)\n", s.Service)
+		default:
+			fmt.Fprintf(&sb, "  %s (unchanged — will redeploy)\n", s.Service)
+		}
+		for _, c := range s.Changes {
+			fmt.Fprintf(&sb, "    %s: %s → %s\n", c.Field, orNone(c.From), orNone(c.To))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// imageTag returns the tag of an image reference, or "" if it has none.
+func imageTag(ref string) string {
+	i := strings.LastIndex(ref, ":")
+	if i < 0 || strings.Contains(ref[i:], "/") {
+		return ""
+	}
+	return ref[i+1:]
+}
@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/f9-o/orbit/internal/orchestrator"
+)
+
+func TestImageTag(t *testing.T) {
+	cases := map[string]string{
+		"nginx:1.25":                 "1.25",
+		"nginx":                      "",
+		"registry:5000/app":          "",
+		"registry:5000/app:v2":       "v2",
+		"ghcr.io/acme/api:2024.10.1": "2024.10.1",
+	}
+	for ref, want := range cases {
+		if got := imageTag(ref); got != want {
+			t.Errorf("imageTag(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+func TestPlanSummary(t *testing.T) {
+	plan := &orchestrator.Plan{Services: []orchestrator.ServiceChange{{
+		Service: "web",
+		Action:  orchestrator.ActionUpdate,
+		Changes: []orchestrator.FieldChange{{Field: "image", From: "web:1", To: "web:2"}},
+	}}}
+	got := planSummary(plan)
+	for _, want := range []string{"~ web (update)", "image: web:1 → web:2"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package tui: event timeline fed by node heartbeats, deploys, and health transitions.
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/internal/tui/components"
+)
+
+// maxTimelineEvents bounds the in-memory timeline.
+const maxTimelineEvents = 500
+
+// nodeEventMsg carries a node status change from the heartbeat engine.
+type nodeEventMsg remote.NodeEvent
+
+// waitNodeEventCmd blocks until the heartbeat engine publishes the next event.
+func (m *Model) waitNodeEventCmd() tea.Cmd {
+	if m.cfg.Heartbeat == nil {
+		return nil
+	}
+	events := m.cfg.Heartbeat.Events()
+	return func() tea.Msg {
+		return nodeEventMsg(<-events)
+	}
+}
+
+// recordEvent appends an entry to the timeline, keeping the newest entries.
+// A scrolled-back view stays anchored on the same entries.
+func (m *Model) recordEvent(level components.EventLevel, source, format string, args ...any) {
+	m.timeline = append(m.timeline, components.TimelineEvent{
+		Time:   time.Now(),
+		Level:  level,
+		Source: source,
+		Text:   fmt.Sprintf(format, args...),
+	})
+	if len(m.timeline) > maxTimelineEvents {
+		m.timeline = m.timeline[len(m.timeline)-maxTimelineEvents:]
+	}
+	if m.timelineOffset > 0 {
+		m.timelineOffset = min(m.timelineOffset+1, len(m.timeline)-1)
+	}
+}
+
+// recordNodeEvent adds a heartbeat transition to the timeline.
+func (m *Model) recordNodeEvent(ev remote.NodeEvent) {
+	level := components.EventOK
+	switch ev.Status {
+	case v1.NodeDegraded:
+		level = components.EventWarn
+	case v1.NodeOffline:
+		level = components.EventError
+	}
+	m.recordEvent(level, "node", "%s is %s", ev.Node, ev.Status)
+	if !ev.Time.IsZero() {
+		m.timeline[len(m.timeline)-1].Time = ev.Time
+	}
+}
+
+// recordHealthEvent adds a service health transition to the timeline.
+func (m *Model) recordHealthEvent(ev health.ServiceEvent) {
+	if ev.CrashLoop {
+		m.recordEvent(components.EventError, "health", "%s crash-looping: %v", ev.Service, ev.Err)
+	} else if ev.Restarted {
+		m.recordEvent(components.EventWarn, "health", "%s restarted after failing liveness", ev.Service)
+	} else {
+		level := components.EventInfo
+		switch ev.To {
+		case v1.StatusHealthy:
+			level = components.EventOK
+		case v1.StatusDegraded:
+			level = components.EventWarn
+		case v1.StatusUnhealthy:
+			level = components.EventError
+		}
+		m.recordEvent(level, "health", "%s %s → %s", ev.Service, ev.From, ev.To)
+	}
+	if !ev.Time.IsZero() {
+		m.timeline[len(m.timeline)-1].Time = ev.Time
+	}
+}
+
+// recordDeployStep adds deploy lifecycle milestones to the timeline.
+func (m *Model) recordDeployStep(service string, step orchestrator.DeployStep) {
+	switch step {
+	case orchestrator.StepPull:
+		m.recordEvent(components.EventInfo, "deploy", "%s deploy started", service)
+	case orchestrator.StepCutover:
+		m.recordEvent(components.EventInfo, "deploy", "%s healthy, cutting over", service)
+	case orchestrator.StepVerify:
+		m.recordEvent(components.EventInfo, "deploy", "%s cut over, verifying", service)
+	case orchestrator.StepRollback:
+		m.recordEvent(components.EventWarn, "deploy", "%s deploy failed, rollback triggered", service)
+	}
+}
+
+// recordActionDone adds the outcome of a dashboard action to the timeline.
+func (m *Model) recordActionDone(msg actionDoneMsg) {
+	source, action := "action", msg.verb
+	switch {
+	case strings.HasPrefix(msg.verb, "deployed"):
+		source, action = "deploy", "deploy"
+	case strings.HasPrefix(msg.verb, "rolled back"):
+		source, action = "deploy", "rollback"
+	case strings.HasPrefix(msg.verb, "scaled"):
+		action = "scale"
+	case msg.verb == "stopped":
+		action = "stop"
+	}
+	if msg.err != nil {
+		m.recordEvent(components.EventError, source, "%s %s failed: %v", msg.service, action, msg.err)
+		return
+	}
+	m.recordEvent(components.EventOK, source, "%s %s", msg.service, msg.verb)
+}
+
+// scrollTimeline moves the events panel view by delta entries (positive = older).
+func (m *Model) scrollTimeline(delta int) {
+	m.timelineOffset = max(0, min(m.timelineOffset+delta, len(m.timeline)-1))
+}
@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"errors"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/remote"
+	"github.com/f9-o/orbit/internal/tui/components"
+)
+
+func TestTimelineRecordsCauseAndEffect(t *testing.T) {
+	m := &Model{}
+	m.recordNodeEvent(remote.NodeEvent{Node: "edge-1", Status: v1.NodeOffline})
+	m.recordDeployStep("web", orchestrator.StepPull)
+	m.recordDeployStep("web", orchestrator.StepStart) // not a timeline milestone
+	m.recordDeployStep("web", orchestrator.StepRollback)
+	m.recordActionDone(actionDoneMsg{verb: "deployed v2", service: "web", err: errors.New("unhealthy")})
+
+	want := []struct {
+		level components.EventLevel
+		text  string
+	}{
+		{components.EventError, "edge-1 is offline"},
+		{components.EventInfo, "web deploy started"},
+		{components.EventWarn, "web deploy failed, rollback triggered"},
+		{components.EventError, "web deploy failed: unhealthy"},
+	}
+	if len(m.timeline) != len(want) {
+		t.Fatalf("timeline has %d entries, want %d: %+v", len(m.timeline), len(want), m.timeline)
+	}
+	for i, w := range want {
+		if got := m.timeline[i]; got.Level != w.level || got.Text != w.text {
+			t.Errorf("entry %d = {%v %q}, want {%v %q}", i, got.Level, got.Text, w.level, w.text)
+		}
+	}
+}
+
+func TestTimelineBoundedAndScrollAnchored(t *testing.T) {
+	m := &Model{}
+	for i := 0; i < maxTimelineEvents+10; i++ {
+		m.recordEvent(components.EventInfo, "test", "event %d", i)
+	}
+	if len(m.timeline) != maxTimelineEvents {
+		t.Fatalf("timeline len = %d, want %d", len(m.timeline), maxTimelineEvents)
+	}
+
+	m.scrollTimeline(3)
+	m.recordEvent(components.EventInfo, "test", "newer")
+	if m.timelineOffset != 4 {
+		t.Errorf("offset = %d, want 4 (view stays on the same entries)", m.timelineOffset)
+	}
+	m.scrollTimeline(-10)
+	if m.timelineOffset != 0 {
+		t.Errorf("offset = %d, want 0", m.timelineOffset)
+	}
+}
+
+func TestTimelineRecordsAlertChanges(t *testing.T) {
+	m := &Model{}
+	hot := v1.Alert{Rule: "hot", Node: "local", Subject: "api", Message: "api cpu 95.0% above 90%"}
+	m.handleAlerts([]v1.Alert{hot})
+	m.handleAlerts([]v1.Alert{hot}) // unchanged: no new entry
+	m.handleAlerts(nil)
+
+	if len(m.timeline) != 2 {
+		t.Fatalf("timeline = %+v", m.timeline)
+	}
+	if got := m.timeline[0]; got.Level != components.EventError || got.Text != "hot: api cpu 95.0% above 90%" {
+		t.Errorf("fired entry = %+v", got)
+	}
+	if got := m.timeline[1]; got.Level != components.EventOK || got.Text != "hot resolved: api" {
+		t.Errorf("resolved entry = %+v", got)
+	}
+}
@@ -0,0 +1,52 @@
+// Package tui: interactive shell into a service container.
+package tui
+
+import (
+	"fmt"
+	"os/exec"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// execShell is run inside the container: prefer bash, fall back to sh.
+const execShell = "command -v bash >/dev/null 2>&1 && exec bash || exec sh"
+
+// execDoneMsg is sent when the exec session exits and the TUI resumes.
+type execDoneMsg struct {
+	service string
+	err     error
+}
+
+// execIntoSelected suspends the TUI and attaches an interactive shell to the
+// selected service's container via `docker exec -it`, resuming on exit.
+func (m *Model) execIntoSelected() tea.Cmd {
+	svc, ok := m.selectedLocal()
+	if !ok {
+		return nil
+	}
+	if svc.ContainerID == "" {
+		m.footer.SetError(fmt.Errorf("%s has no running container", svc.Name))
+		return nil
+	}
+	docker, err := exec.LookPath("docker")
+	if err != nil {
+		m.footer.SetError(fmt.Errorf("exec requires the docker CLI on PATH"))
+		return nil
+	}
+
+	m.stopLogStream()
+	c := exec.Command(docker, "exec", "-it", svc.ContainerID, "/bin/sh", "-c", execShell) //nolint:gosec // container id from state
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return execDoneMsg{service: svc.Name, err: err}
+	})
+}
+
+// handleExecDone reports how the exec session ended.
+func (m *Model) handleExecDone(msg execDoneMsg) tea.Cmd {
+	if msg.err != nil {
+		m.footer.SetError(fmt.Errorf("exec %s: %w", msg.service, msg.err))
+	} else {
+		m.footer.SetStatus(fmt.Sprintf("✓ shell session in %s closed", msg.service))
+	}
+	return m.loadServicesCmd()
+}
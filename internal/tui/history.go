@@ -0,0 +1,94 @@
+// Package tui: deployment history panel and rollback action.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/health"
+	"github.com/f9-o/orbit/internal/orchestrator"
+	"github.com/f9-o/orbit/internal/tui/components"
+)
+
+// historyMsg carries the deployment records for a service, newest first.
+type historyMsg struct {
+	service string
+	records []v1.DeploymentRecord
+}
+
+// loadHistoryCmd fetches deployment records for the selected service.
+func (m *Model) loadHistoryCmd() tea.Cmd {
+	svc, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	db, node := m.cfg.State, svc.Node
+	return func() tea.Msg {
+		all, err := db.ListDeployments(svc.Name)
+		if err != nil {
+			return errMsg(err)
+		}
+		var recs []v1.DeploymentRecord
+		for _, r := range all {
+			if r.Node == "" || r.Node == node {
+				recs = append(recs, r)
+			}
+		}
+		sort.Slice(recs, func(i, j int) bool { return recs[i].StartedAt.After(recs[j].StartedAt) })
+		return historyMsg{service: svc.Name, records: recs}
+	}
+}
+
+// handleHistory stores loaded records, keeping the cursor in range.
+func (m *Model) handleHistory(msg historyMsg) {
+	if msg.service != m.historyService {
+		m.selectedHistory = 0
+	}
+	m.historyService = msg.service
+	m.history = msg.records
+	if m.selectedHistory >= len(m.history) {
+		m.selectedHistory = max(len(m.history)-1, 0)
+	}
+}
+
+// confirmRollback opens the rollback confirmation modal for the highlighted record.
+func (m *Model) confirmRollback() {
+	if m.selectedHistory >= len(m.history) {
+		return
+	}
+	svc, ok := m.selectedLocal()
+	if !ok {
+		return
+	}
+	spec, err := m.specFor(svc.Name)
+	if err != nil {
+		m.footer.SetError(err)
+		return
+	}
+	rec := m.history[m.selectedHistory]
+	m.modal = components.NewConfirmModal(
+		fmt.Sprintf("Roll back %s?", svc.Name),
+		fmt.Sprintf("Rolling deploy back to %s\n(deployed %s)", rec.ToImage, rec.StartedAt.Local().Format("2006-01-02 15:04")),
+		m.styles.Modal,
+		func() tea.Cmd {
+			if !m.beginAction("Rolling back " + svc.Name + "…") {
+				return nil
+			}
+			return m.rollbackCmd(spec, rec)
+		},
+	)
+}
+
+func (m *Model) rollbackCmd(spec v1.ServiceSpec, rec v1.DeploymentRecord) tea.Cmd {
+	docker, db, log, node, hooks, commands := m.cfg.DockerClient, m.cfg.State, m.cfg.Log, m.cfg.Node, m.cfg.Hooks, m.cfg.CommandHooks
+	return func() tea.Msg {
+		checker := health.NewChecker(log).WithExecer(docker).WithInspector(docker)
+		deployer := orchestrator.NewDeployer(docker, db, checker, log).WithHooks(hooks).WithCommandHooks(commands)
+		err := deployer.Rollback(context.Background(), spec, node, rec)
+		return actionDoneMsg{verb: "rolled back to " + rec.ToImage, service: spec.Name, err: err}
+	}
+}
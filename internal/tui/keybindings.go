@@ -1,69 +1,188 @@
 // Package tui: keyboard binding configuration.
 package tui
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/f9-o/orbit/internal/tui/components"
+)
+
 // Keymap defines all keyboard shortcuts for the TUI.
 type Keymap struct {
-	Quit     string
-	TabNext  string
-	TabPrev  string
-	NavUp    string
-	NavDown  string
-	NavLeft  string
-	NavRight string
-	Select   string
-	Logs     string
-	Scale    string
-	Deploy   string
-	Stop     string
-	Nodes    string
-	Search   string
-	Help     string
+	Quit       string
+	TabNext    string
+	TabPrev    string
+	NavUp      string
+	NavDown    string
+	NavUpAlt   string
+	NavDownAlt string
+	NavLeft    string
+	NavRight   string
+	Select     string
+	Logs       string
+	Events     string
+	Scale      string
+	Deploy     string
+	Stop       string
+	Rollback   string
+	Exec       string
+	Nodes      string
+	NodesPrev  string
+	Search     string
+	Clear      string
+	Help       string
 }
 
 // defaultKeymap returns the default Orbit TUI key bindings.
 func defaultKeymap() Keymap {
 	return Keymap{
-		Quit:     "q",
-		TabNext:  "tab",
-		TabPrev:  "shift+tab",
-		NavUp:    "up",
-		NavDown:  "down",
-		NavLeft:  "left",
-		NavRight: "right",
-		Select:   "enter",
-		Logs:     "l",
-		Scale:    "s",
-		Deploy:   "d",
-		Stop:     "x",
-		Nodes:    "n",
-		Search:   "/",
-		Help:     "?",
+		Quit:       "q",
+		TabNext:    "tab",
+		TabPrev:    "shift+tab",
+		NavUp:      "up",
+		NavDown:    "down",
+		NavUpAlt:   "k",
+		NavDownAlt: "j",
+		NavLeft:    "left",
+		NavRight:   "right",
+		Select:     "enter",
+		Logs:       "l",
+		Events:     "t",
+		Scale:      "s",
+		Deploy:     "d",
+		Stop:       "x",
+		Rollback:   "r",
+		Exec:       "e",
+		Nodes:      "n",
+		NodesPrev:  "N",
+		Search:     "/",
+		Clear:      "esc",
+		Help:       "?",
 	}
 }
 
-// HelpText returns the keyboard shortcut reference displayed in the help modal.
-func HelpText() string {
-	return `
-  NAVIGATION
-  ──────────────────────────────────────
-  Tab / Shift+Tab    Cycle panels
-  ↑↓  /  j k        Navigate list
-  ←→  /  h l        Switch tabs
+// binding describes one configurable action for validation and help rendering.
+type binding struct {
+	name    string // config key under tui.keys
+	section string
+	desc    string
+	hint    string // short footer label; "" keeps it out of the footer
+	key     func(*Keymap) *string
+}
 
-  ACTIONS
-  ──────────────────────────────────────
-  Enter              Select / Expand
-  l                  Open service logs
-  s                  Scale service
-  d                  Deploy (rolling)
-  x                  Stop service
-  n                  Switch node
+var bindings = []binding{
+	{"tab_next", "NAVIGATION", "Next panel", "", func(k *Keymap) *string { return &k.TabNext }},
+	{"tab_prev", "NAVIGATION", "Previous panel", "", func(k *Keymap) *string { return &k.TabPrev }},
+	{"nav_up", "NAVIGATION", "Move up", "", func(k *Keymap) *string { return &k.NavUp }},
+	{"nav_down", "NAVIGATION", "Move down", "navigate", func(k *Keymap) *string { return &k.NavDown }},
+	{"nav_up_alt", "NAVIGATION", "Move up (vi)", "", func(k *Keymap) *string { return &k.NavUpAlt }},
+	{"nav_down_alt", "NAVIGATION", "Move down (vi)", "", func(k *Keymap) *string { return &k.NavDownAlt }},
+	{"nav_left", "NAVIGATION", "Previous node", "", func(k *Keymap) *string { return &k.NavLeft }},
+	{"nav_right", "NAVIGATION", "Next node", "", func(k *Keymap) *string { return &k.NavRight }},
+	{"nodes", "NAVIGATION", "Next node / next log match", "", func(k *Keymap) *string { return &k.Nodes }},
+	{"nodes_prev", "NAVIGATION", "Previous node / previous log match", "", func(k *Keymap) *string { return &k.NodesPrev }},
+	{"select", "ACTIONS", "Select / expand", "", func(k *Keymap) *string { return &k.Select }},
+	{"logs", "ACTIONS", "Open service logs", "logs", func(k *Keymap) *string { return &k.Logs }},
+	{"events", "ACTIONS", "Open event timeline", "", func(k *Keymap) *string { return &k.Events }},
+	{"scale", "ACTIONS", "Scale service", "scale", func(k *Keymap) *string { return &k.Scale }},
+	{"deploy", "ACTIONS", "Deploy (rolling)", "deploy", func(k *Keymap) *string { return &k.Deploy }},
+	{"stop", "ACTIONS", "Stop service", "stop", func(k *Keymap) *string { return &k.Stop }},
+	{"rollback", "ACTIONS", "Roll back (history panel)", "", func(k *Keymap) *string { return &k.Rollback }},
+	{"exec", "ACTIONS", "Open a shell in the service container", "", func(k *Keymap) *string { return &k.Exec }},
+	{"search", "SEARCH & MISC", "Incremental search (Enter keeps, Esc clears)", "search", func(k *Keymap) *string { return &k.Search }},
+	{"clear", "SEARCH & MISC", "Clear a kept search", "", func(k *Keymap) *string { return &k.Clear }},
+	{"help", "SEARCH & MISC", "Toggle this help", "help", func(k *Keymap) *string { return &k.Help }},
+	{"quit", "SEARCH & MISC", "Quit", "quit", func(k *Keymap) *string { return &k.Quit }},
+}
 
-  SEARCH & MISC
-  ──────────────────────────────────────
-  /                  Incremental search
-  ?                  Toggle this help
-  q                  Quit
-  Ctrl+C             Force quit
-`
+// NewKeymap applies overrides (action name → key, as in `tui.keys.quit: "ctrl+q"`)
+// to the defaults and rejects unknown actions and keys bound twice.
+func NewKeymap(overrides map[string]string) (Keymap, error) {
+	km := defaultKeymap()
+	byName := make(map[string]binding, len(bindings))
+	for _, b := range bindings {
+		byName[b.name] = b
+	}
+
+	for name, key := range overrides {
+		b, ok := byName[strings.ToLower(name)]
+		if !ok {
+			valid := make([]string, 0, len(bindings))
+			for _, b := range bindings {
+				valid = append(valid, b.name)
+			}
+			sort.Strings(valid)
+			return Keymap{}, fmt.Errorf("unknown key action %q (valid: %s)", name, strings.Join(valid, ", "))
+		}
+		if key == "" {
+			return Keymap{}, fmt.Errorf("key for %q must not be empty", name)
+		}
+		*b.key(&km) = key
+	}
+
+	owner := map[string]string{}
+	var conflicts []string
+	for _, b := range bindings {
+		key := *b.key(&km)
+		if other, ok := owner[key]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("%q is bound to both %s and %s", key, other, b.name))
+			continue
+		}
+		owner[key] = b.name
+	}
+	if len(conflicts) > 0 {
+		return Keymap{}, fmt.Errorf("key binding conflicts: %s", strings.Join(conflicts, "; "))
+	}
+	return km, nil
+}
+
+// Hints returns the footer hints for the effective keymap.
+func (k Keymap) Hints() []components.Hint {
+	var hints []components.Hint
+	for _, b := range bindings {
+		if b.hint != "" {
+			hints = append(hints, components.Hint{Key: displayKey(*b.key(&k)), Desc: b.hint})
+		}
+	}
+	return hints
+}
+
+// HelpText returns the keyboard shortcut reference displayed in the help modal.
+func (k Keymap) HelpText() string {
+	var sb strings.Builder
+	section := ""
+	for _, b := range bindings {
+		if b.section != section {
+			section = b.section
+			fmt.Fprintf(&sb, "\n  %s\n  ──────────────────────────────────────\n", section)
+		}
+		fmt.Fprintf(&sb, "  %-18s %s\n", displayKey(*b.key(&k)), b.desc)
+	}
+	fmt.Fprintf(&sb, "  %-18s %s\n", "Ctrl+C", "Force quit")
+	return sb.String()
+}
+
+// displayKey renders a bubbletea key name for humans.
+func displayKey(key string) string {
+	switch key {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	case "tab":
+		return "Tab"
+	case "shift+tab":
+		return "Shift+Tab"
+	case "enter":
+		return "Enter"
+	case "esc":
+		return "Esc"
+	}
+	return key
 }
@@ -0,0 +1,36 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewKeymap(t *testing.T) {
+	km, err := NewKeymap(map[string]string{"quit": "ctrl+q", "search": "f"})
+	if err != nil {
+		t.Fatalf("NewKeymap: %v", err)
+	}
+	if km.Quit != "ctrl+q" || km.Search != "f" || km.Logs != "l" {
+		t.Errorf("keymap = %+v", km)
+	}
+	if !strings.Contains(km.HelpText(), "ctrl+q") {
+		t.Error("help text should reflect overrides")
+	}
+
+	if _, err := NewKeymap(map[string]string{"stop": "d"}); err == nil || !strings.Contains(err.Error(), "deploy and stop") {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+	// The vi keys and esc are bindings too, so overrides cannot shadow them.
+	if _, err := NewKeymap(map[string]string{"logs": "j"}); err == nil || !strings.Contains(err.Error(), "nav_down_alt and logs") {
+		t.Errorf("expected conflict with j, got %v", err)
+	}
+	if _, err := NewKeymap(map[string]string{"quit": "esc"}); err == nil || !strings.Contains(err.Error(), "clear and quit") {
+		t.Errorf("expected conflict with esc, got %v", err)
+	}
+	if km, err := NewKeymap(map[string]string{"nav_down_alt": "J", "logs": "j"}); err != nil || km.Logs != "j" {
+		t.Errorf("rebinding j: %+v, %v", km, err)
+	}
+	if _, err := NewKeymap(map[string]string{"explode": "e"}); err == nil {
+		t.Error("expected unknown action error")
+	}
+}
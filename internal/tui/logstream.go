@@ -0,0 +1,137 @@
+// Package tui: follow-mode container log streaming for the logs panel.
+package tui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+	"github.com/f9-o/orbit/internal/orchestrator"
+)
+
+// logStream tracks the container whose logs are being followed.
+type logStream struct {
+	gen         int // incremented per stream so stale messages are ignored
+	service     string
+	containerID string
+	lines       chan string
+	done        chan error
+	cancel      context.CancelFunc
+}
+
+// containerLogMsg carries one line from the active container log stream.
+type containerLogMsg struct {
+	gen  int
+	line string
+}
+
+// logStreamEndedMsg is sent when a container log stream closes.
+type logStreamEndedMsg struct {
+	gen int
+	err error
+}
+
+// startLogStream begins following svc's container logs, replacing any
+// existing stream, and returns the command that delivers the first line.
+func (m *Model) startLogStream(svc v1.ServiceState, banner string) tea.Cmd {
+	m.stopLogStream()
+	if svc.ContainerID == "" || m.cfg.DockerClient == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.stream.gen++
+	m.stream.service = svc.Name
+	m.stream.containerID = svc.ContainerID
+	m.stream.lines = make(chan string, 256)
+	m.stream.cancel = cancel
+
+	m.appendLog(fmt.Sprintf("── %s %s (%s) ──", banner, svc.Name, shortID(svc.ContainerID)))
+
+	gen, lines, docker, id := m.stream.gen, m.stream.lines, m.cfg.DockerClient, svc.ContainerID
+	done := make(chan error, 1)
+	go func() {
+		w := &lineWriter{ctx: ctx, out: lines}
+		err := docker.StreamLogs(ctx, id, orchestrator.LogStreamOptions{Follow: true, Timestamps: true}, w)
+		w.flush()
+		done <- err
+		close(lines)
+	}()
+	m.stream.done = done
+	return waitLogLineCmd(gen, lines, done)
+}
+
+// stopLogStream cancels the active stream, if any.
+func (m *Model) stopLogStream() {
+	if m.stream.cancel != nil {
+		m.stream.cancel()
+	}
+	m.stream.cancel = nil
+	m.stream.service = ""
+	m.stream.containerID = ""
+}
+
+// followReplacement restarts the stream when the followed service's container
+// has been replaced (e.g. by a rolling deploy).
+func (m *Model) followReplacement(services []v1.ServiceState) tea.Cmd {
+	if m.stream.service == "" {
+		return nil
+	}
+	for _, svc := range services {
+		if svc.Name == m.stream.service && m.isLocal(svc) && svc.ContainerID != "" && svc.ContainerID != m.stream.containerID {
+			return m.startLogStream(svc, "container replaced — following")
+		}
+	}
+	return nil
+}
+
+// waitLogLineCmd blocks until the next line arrives or the stream closes.
+func waitLogLineCmd(gen int, lines <-chan string, done <-chan error) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-lines
+		if !ok {
+			return logStreamEndedMsg{gen: gen, err: <-done}
+		}
+		return containerLogMsg{gen: gen, line: line}
+	}
+}
+
+// lineWriter splits written bytes into lines and forwards them on out.
+// ANSI escape sequences are passed through untouched.
+type lineWriter struct {
+	ctx context.Context
+	out chan<- string
+	buf bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := strings.TrimRight(string(w.buf.Next(i+1)), "\r\n")
+		select {
+		case w.out <- line:
+		case <-w.ctx.Done():
+			return 0, w.ctx.Err()
+		}
+	}
+}
+
+// flush forwards any trailing partial line.
+func (w *lineWriter) flush() {
+	if w.buf.Len() == 0 {
+		return
+	}
+	select {
+	case w.out <- w.buf.String():
+	case <-w.ctx.Done():
+	}
+	w.buf.Reset()
+}
@@ -0,0 +1,107 @@
+// Package tui: node switching and the multi-node aggregate view.
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// allNodes is the scope value for the aggregate view across every node.
+const allNodes = ""
+
+// allNodesLabel is how the aggregate scope appears in the sidebar and header.
+const allNodesLabel = "All nodes"
+
+// nodeScopes returns the selectable scopes: the aggregate view, the local
+// node, then every registered node.
+func (m *Model) nodeScopes() []string {
+	scopes := []string{allNodes, m.cfg.Node}
+	for _, n := range m.nodes {
+		if n.Spec.Name != m.cfg.Node {
+			scopes = append(scopes, n.Spec.Name)
+		}
+	}
+	return scopes
+}
+
+// scopeLabel renders a scope for display.
+func scopeLabel(scope string) string {
+	if scope == allNodes {
+		return allNodesLabel
+	}
+	return scope
+}
+
+// switchNode moves the node scope by delta (wrapping) and reloads the view.
+func (m *Model) switchNode(delta int) tea.Cmd {
+	scopes := m.nodeScopes()
+	cur := 0
+	for i, s := range scopes {
+		if s == m.node {
+			cur = i
+			break
+		}
+	}
+	next := ((cur+delta)%len(scopes) + len(scopes)) % len(scopes)
+	m.node = scopes[next]
+	m.selectedService = 0
+	m.stopLogStream()
+	m.syncNodeViews()
+	return m.loadServicesCmd()
+}
+
+// syncNodeViews updates the sidebar and header to reflect the current scope.
+func (m *Model) syncNodeViews() {
+	scopes := m.nodeScopes()
+	labels := make([]string, len(scopes))
+	for i, s := range scopes {
+		labels[i] = scopeLabel(s)
+		if s == m.node {
+			m.sidebar.Select(i)
+		}
+	}
+	m.sidebar.SetNodes(labels)
+	statuses := make(map[string]v1.NodeStatus, len(m.nodes))
+	for _, n := range m.nodes {
+		if n.Spec.Name != m.cfg.Node {
+			statuses[n.Spec.Name] = n.Status
+		}
+	}
+	m.sidebar.SetStatuses(statuses)
+	m.header.SetNode(scopeLabel(m.node))
+	m.header.SetNodeCount(len(scopes) - 1)
+	m.header.SetHost(m.scopedHost())
+}
+
+// scopedHost returns the host metrics for the current scope: the collector's
+// reading for this machine, the last heartbeat's for a remote node, and none
+// for the aggregate view.
+func (m *Model) scopedHost() *v1.HostMetrics {
+	switch m.node {
+	case allNodes:
+		return nil
+	case m.cfg.Node:
+		return m.metrics.Host
+	}
+	for _, n := range m.nodes {
+		if n.Spec.Name == m.node {
+			return n.Host
+		}
+	}
+	return nil
+}
+
+// isLocal reports whether svc runs on the node this dashboard's Docker client manages.
+func (m *Model) isLocal(svc v1.ServiceState) bool {
+	return svc.Node == m.cfg.Node
+}
+
+// scopedMetrics returns the collector's metrics, which only cover the local node,
+// or nothing when a remote node is in scope.
+func (m *Model) scopedMetrics() v1.Metrics {
+	if m.node != allNodes && m.node != m.cfg.Node {
+		return v1.Metrics{Node: m.node}
+	}
+	return m.metrics
+}
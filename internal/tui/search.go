@@ -0,0 +1,138 @@
+// Package tui: incremental search across the services table and logs panel.
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// search holds the incremental search state.
+type search struct {
+	active  bool   // input is open and capturing keys
+	query   string // current filter; kept after Enter, cleared by Esc
+	matches []int  // log line indexes matching query
+	current int    // index into matches of the focused match
+}
+
+// visibleServices returns the services matching the search query by name, image, or status.
+func (m *Model) visibleServices() []v1.ServiceState {
+	q := strings.ToLower(m.search.query)
+	if q == "" {
+		return m.services
+	}
+	var out []v1.ServiceState
+	for _, svc := range m.services {
+		if strings.Contains(strings.ToLower(svc.Name), q) ||
+			strings.Contains(strings.ToLower(svc.Image), q) ||
+			strings.Contains(strings.ToLower(string(svc.Status)), q) {
+			out = append(out, svc)
+		}
+	}
+	return out
+}
+
+// handleSearchKey processes a key while the search input is open.
+func (m *Model) handleSearchKey(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.search = search{}
+	case tea.KeyEnter:
+		m.search.active = false
+	case tea.KeyBackspace:
+		if _, size := utf8.DecodeLastRuneInString(m.search.query); size > 0 {
+			m.search.query = m.search.query[:len(m.search.query)-size]
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.search.query += string(msg.Runes)
+	default:
+		return
+	}
+	m.selectedService = 0
+	m.refreshLogView()
+	m.jumpToMatch(0)
+	m.updateSearchStatus()
+}
+
+// jumpToMatch moves the focused log match by delta (wrapping) and scrolls to it.
+func (m *Model) jumpToMatch(delta int) {
+	n := len(m.search.matches)
+	if n == 0 {
+		return
+	}
+	m.search.current = ((m.search.current+delta)%n + n) % n
+	line := m.search.matches[m.search.current]
+	m.logViewport.SetYOffset(max(line-m.logViewport.Height/2, 0))
+	m.updateSearchStatus()
+}
+
+// updateSearchStatus shows the search prompt and match count in the footer.
+func (m *Model) updateSearchStatus() {
+	if !m.search.active && m.search.query == "" {
+		m.footer.SetStatus("")
+		return
+	}
+	status := "/" + m.search.query
+	if m.search.active {
+		status += "█"
+	}
+	switch m.panel {
+	case PanelLogs:
+		if n := len(m.search.matches); n > 0 {
+			status += fmt.Sprintf("  (%d/%d — n/N to jump)", m.search.current+1, n)
+		} else if m.search.query != "" {
+			status += "  (no matches)"
+		}
+	case PanelServices:
+		status += fmt.Sprintf("  (%d of %d services)", len(m.visibleServices()), len(m.services))
+	}
+	m.footer.SetStatus(status)
+}
+
+// refreshLogView re-renders the log viewport, highlighting query matches.
+func (m *Model) refreshLogView() {
+	q := m.search.query
+	m.search.matches = m.search.matches[:0]
+	if q == "" {
+		m.logViewport.SetContent(joinLines(m.logLines))
+		return
+	}
+
+	re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(q))
+	lines := make([]string, len(m.logLines))
+	for i, l := range m.logLines {
+		hl, ok := highlight(l, re, m.styles.SearchMatch.Render)
+		if ok {
+			m.search.matches = append(m.search.matches, i)
+		}
+		lines[i] = hl
+	}
+	if m.search.current >= len(m.search.matches) {
+		m.search.current = 0
+	}
+	m.logViewport.SetContent(joinLines(lines))
+}
+
+// highlight wraps the matches of re in line with render. Matching with re
+// rather than on a lowercased copy keeps offsets valid in line, whose case
+// mapping may change byte lengths.
+func highlight(line string, re *regexp.Regexp, render func(...string) string) (string, bool) {
+	locs := re.FindAllStringIndex(line, -1)
+	if len(locs) == 0 {
+		return line, false
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		b.WriteString(line[last:loc[0]])
+		b.WriteString(render(line[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(line[last:])
+	return b.String(), true
+}
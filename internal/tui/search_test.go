@@ -0,0 +1,33 @@
+package tui
+
+import (
+	"regexp"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHighlight(t *testing.T) {
+	mark := func(s ...string) string { return "[" + s[0] + "]" }
+	for _, tc := range []struct{ line, q, want string }{
+		{"Error: disk ERROR", "error", "[Error]: disk [ERROR]"},
+		{"İstanbul error İ", "error", "İstanbul [error] İ"},
+		{"straße STRASSE", "straße", "[straße] STRASSE"},
+		{"no match", "x", "no match"},
+	} {
+		re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(tc.q))
+		if got, _ := highlight(tc.line, re, mark); got != tc.want {
+			t.Errorf("highlight(%q, %q) = %q, want %q", tc.line, tc.q, got, tc.want)
+		}
+	}
+}
+
+func TestSearchBackspaceTrimsRune(t *testing.T) {
+	m := New(Config{})
+	m.search.active = true
+	m.handleSearchKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("añé")})
+	m.handleSearchKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.search.query != "añ" {
+		t.Errorf("query = %q, want %q", m.search.query, "añ")
+	}
+}
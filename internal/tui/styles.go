@@ -1,7 +1,11 @@
-// Package tui: Lipgloss style constants for the "Orbit Dark" theme.
+// Package tui: Lipgloss styles derived from the active theme palette.
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/f9-o/orbit/internal/tui/components"
+)
 
 // Styles holds all theme-aware Lipgloss styles.
 type Styles struct {
@@ -35,20 +39,21 @@ type Styles struct {
 	StatusOK     lipgloss.Style
 	StatusWarn   lipgloss.Style
 	StatusErr    lipgloss.Style
+	SearchMatch  lipgloss.Style
 	Border       lipgloss.Style
 }
 
-// newStyles returns the "Orbit Dark" theme styles.
-func newStyles() Styles {
-	bg := lipgloss.Color("#0D0F18")
-	surface := lipgloss.Color("#171A2B")
-	primary := lipgloss.Color("#7B8CDE")
-	accent := lipgloss.Color("#56E0C8")
-	danger := lipgloss.Color("#F56565")
-	warning := lipgloss.Color("#ECC94B")
-	success := lipgloss.Color("#68D391")
-	muted := lipgloss.Color("#4A5568")
-	text := lipgloss.Color("#E2E8F0")
+// newStyles returns the styles for palette p.
+func newStyles(p components.Palette) Styles {
+	bg := p.Background
+	surface := p.Surface
+	primary := p.Primary
+	accent := p.Accent
+	danger := p.Danger
+	warning := p.Warning
+	success := p.Success
+	muted := p.Muted
+	text := p.Text
 
 	border := lipgloss.Border{
 		Top: "─", Bottom: "─", Left: "│", Right: "│",
@@ -121,6 +126,8 @@ func newStyles() Styles {
 		StatusWarn: lipgloss.NewStyle().Foreground(warning),
 		StatusErr:  lipgloss.NewStyle().Foreground(danger),
 
+		SearchMatch: lipgloss.NewStyle().Background(warning).Foreground(bg),
+
 		Border: lipgloss.NewStyle().BorderStyle(border).BorderForeground(muted),
 	}
 }
@@ -0,0 +1,131 @@
+// Package tui: theme registry with built-in palettes and user themes.
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+
+	"github.com/f9-o/orbit/internal/core/config"
+	"github.com/f9-o/orbit/internal/tui/components"
+)
+
+// DefaultTheme is used when no theme is configured.
+const DefaultTheme = "orbit-dark"
+
+// builtinThemes are the palettes shipped with Orbit.
+var builtinThemes = map[string]components.Palette{
+	"orbit-dark": components.CurrentPalette(),
+	"orbit-light": {
+		Background: "#F7F8FC",
+		Surface:    "#E6E9F4",
+		Primary:    "#4453A6",
+		Accent:     "#0F8A76",
+		Danger:     "#C53030",
+		Warning:    "#B7791F",
+		Success:    "#2F855A",
+		Muted:      "#718096",
+		Text:       "#1A202C",
+	},
+	"high-contrast": {
+		Background: "#000000",
+		Surface:    "#1C1C1C",
+		Primary:    "#FFFFFF",
+		Accent:     "#00FFFF",
+		Danger:     "#FF0000",
+		Warning:    "#FFFF00",
+		Success:    "#00FF00",
+		Muted:      "#BFBFBF",
+		Text:       "#FFFFFF",
+	},
+}
+
+// themeFile is the on-disk format of ~/.orbit/themes/<name>.yaml.
+// Colors left empty are inherited from Base (orbit-dark by default).
+type themeFile struct {
+	Base       string `yaml:"base"`
+	Background string `yaml:"background"`
+	Surface    string `yaml:"surface"`
+	Primary    string `yaml:"primary"`
+	Accent     string `yaml:"accent"`
+	Danger     string `yaml:"danger"`
+	Warning    string `yaml:"warning"`
+	Success    string `yaml:"success"`
+	Muted      string `yaml:"muted"`
+	Text       string `yaml:"text"`
+}
+
+// ThemeNames lists the built-in themes followed by user themes found on disk.
+func ThemeNames() []string {
+	var names []string
+	for n := range builtinThemes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	files, _ := filepath.Glob(filepath.Join(themeDir(), "*.yaml"))
+	for _, f := range files {
+		n := filepath.Base(f)
+		n = n[:len(n)-len(".yaml")]
+		if _, ok := builtinThemes[n]; !ok {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// LoadTheme resolves a theme name to a palette. User themes in
+// ~/.orbit/themes take precedence over built-ins of the same name.
+func LoadTheme(name string) (components.Palette, error) {
+	if name == "" {
+		name = DefaultTheme
+	}
+
+	path := filepath.Join(themeDir(), name+".yaml")
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if p, ok := builtinThemes[name]; ok {
+			return p, nil
+		}
+		return components.Palette{}, fmt.Errorf("unknown theme %q (available: %v)", name, ThemeNames())
+	}
+	if err != nil {
+		return components.Palette{}, fmt.Errorf("read theme %q: %w", path, err)
+	}
+
+	var tf themeFile
+	if err := yaml.Unmarshal(raw, &tf); err != nil {
+		return components.Palette{}, fmt.Errorf("parse theme %q: %w", path, err)
+	}
+	if tf.Base == "" || tf.Base == name {
+		tf.Base = DefaultTheme
+	}
+	base, ok := builtinThemes[tf.Base]
+	if !ok {
+		return components.Palette{}, fmt.Errorf("theme %q: unknown base %q", name, tf.Base)
+	}
+
+	override := func(dst *lipgloss.Color, v string) {
+		if v != "" {
+			*dst = lipgloss.Color(v)
+		}
+	}
+	override(&base.Background, tf.Background)
+	override(&base.Surface, tf.Surface)
+	override(&base.Primary, tf.Primary)
+	override(&base.Accent, tf.Accent)
+	override(&base.Danger, tf.Danger)
+	override(&base.Warning, tf.Warning)
+	override(&base.Success, tf.Success)
+	override(&base.Muted, tf.Muted)
+	override(&base.Text, tf.Text)
+	return base, nil
+}
+
+func themeDir() string {
+	return filepath.Join(config.OrbitHome(), "themes")
+}
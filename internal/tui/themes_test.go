@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestLoadThemeUserOverride(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".orbit", "themes")
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	theme := "base: orbit-light\naccent: \"#FF00FF\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "mine.yaml"), []byte(theme), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadTheme("mine")
+	if err != nil {
+		t.Fatalf("LoadTheme: %v", err)
+	}
+	light := builtinThemes["orbit-light"]
+	if p.Accent != lipgloss.Color("#FF00FF") || p.Background != light.Background {
+		t.Errorf("palette = %+v", p)
+	}
+
+	if _, err := LoadTheme("no-such-theme"); err == nil {
+		t.Error("expected error for unknown theme")
+	}
+	if p, err := LoadTheme(""); err != nil || p != builtinThemes[DefaultTheme] {
+		t.Errorf("default theme = %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,122 @@
+// Package tui: container event subscription that keeps the services view current.
+package tui
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// resyncInterval is how often services are reloaded from state while the
+// container event stream is up. Events trigger reloads in between; without
+// a stream the view falls back to reloading on every tick.
+const resyncInterval = 30 * time.Second
+
+// reloadDebounce coalesces a burst of container events (a deploy emits
+// create, start, rename, kill, die, and destroy within moments) into one reload.
+const reloadDebounce = 300 * time.Millisecond
+
+// watchRetry is how long to wait before resubscribing after the stream drops.
+const watchRetry = 5 * time.Second
+
+// containerWatch tracks the runtime event subscription.
+type containerWatch struct {
+	events   <-chan v1.ContainerEvent // nil while unsubscribed
+	cancel   context.CancelFunc
+	pending  bool      // a debounced reload is scheduled
+	lastLoad time.Time // last services reload
+}
+
+// watchStartedMsg carries a freshly opened event subscription.
+type watchStartedMsg struct {
+	events <-chan v1.ContainerEvent
+	cancel context.CancelFunc
+}
+
+// containerEventMsg carries one container event.
+type containerEventMsg v1.ContainerEvent
+
+// watchEndedMsg is sent when the event stream closes.
+type watchEndedMsg struct{}
+
+// watchRetryMsg asks for a new subscription after watchRetry.
+type watchRetryMsg struct{}
+
+// reloadServicesMsg fires once a debounce window has passed.
+type reloadServicesMsg struct{}
+
+// startWatchCmd subscribes to container events off the UI goroutine, since
+// opening the stream waits for the daemon to respond.
+func (m *Model) startWatchCmd() tea.Cmd {
+	docker := m.cfg.DockerClient
+	if docker == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithCancel(context.Background())
+		evs, _ := docker.ContainerEvents(ctx)
+		return watchStartedMsg{events: evs, cancel: cancel}
+	}
+}
+
+// waitContainerEventCmd blocks until the next event or the end of the stream.
+func waitContainerEventCmd(evs <-chan v1.ContainerEvent) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-evs
+		if !ok {
+			return watchEndedMsg{}
+		}
+		return containerEventMsg(ev)
+	}
+}
+
+// handleWatch processes subscription lifecycle and event messages.
+func (m *Model) handleWatch(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case watchStartedMsg:
+		m.stopWatch()
+		m.watch.events, m.watch.cancel = msg.events, msg.cancel
+		// Reload once: anything that changed before the stream opened was missed.
+		return tea.Batch(m.loadServicesCmd(), waitContainerEventCmd(msg.events))
+
+	case containerEventMsg:
+		if m.watch.events == nil {
+			return nil
+		}
+		cmd := waitContainerEventCmd(m.watch.events)
+		if m.watch.pending {
+			return cmd
+		}
+		m.watch.pending = true
+		return tea.Batch(cmd, tea.Tick(reloadDebounce, func(time.Time) tea.Msg { return reloadServicesMsg{} }))
+
+	case reloadServicesMsg:
+		m.watch.pending = false
+		return m.loadServicesCmd()
+
+	case watchEndedMsg:
+		m.stopWatch()
+		return tea.Tick(watchRetry, func(time.Time) tea.Msg { return watchRetryMsg{} })
+
+	case watchRetryMsg:
+		return m.startWatchCmd()
+	}
+	return nil
+}
+
+// stopWatch closes the current subscription, if any.
+func (m *Model) stopWatch() {
+	if m.watch.cancel != nil {
+		m.watch.cancel()
+	}
+	m.watch.events, m.watch.cancel = nil, nil
+}
+
+// servicesStale reports whether the tick should reload services: always
+// without an event stream, otherwise once per resyncInterval.
+func (m *Model) servicesStale(now time.Time) bool {
+	return m.watch.events == nil || now.Sub(m.watch.lastLoad) >= resyncInterval
+}
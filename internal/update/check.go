@@ -0,0 +1,73 @@
+// Package update: the opt-in background check for a newer release.
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// CheckFile is the file, in the Orbit home, that remembers the last check.
+const CheckFile = "update-check.json"
+
+// DefaultCheckInterval is how often the background check asks GitHub.
+const DefaultCheckInterval = 24 * time.Hour
+
+// backgroundTimeout bounds the background request, so an unreachable API
+// never holds up a command for long.
+const backgroundTimeout = 3 * time.Second
+
+// CheckState is the outcome of the last check.
+type CheckState struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest,omitempty"`
+	URL       string    `json:"url,omitempty"`
+}
+
+// LoadCheck reads the remembered check at path; the zero CheckState when
+// there is none.
+func LoadCheck(path string) CheckState {
+	var s CheckState
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &s)
+	}
+	return s
+}
+
+// SaveCheck remembers s at path.
+func SaveCheck(path string, s CheckState) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Background delivers the newest release known: the one remembered at path
+// when it was checked within interval, otherwise whatever GitHub reports,
+// which is then remembered. A failed request is remembered too, so an
+// offline machine does not retry on every command. The channel yields one
+// value and is never closed.
+func (c *Client) Background(ctx context.Context, path string, interval time.Duration) <-chan CheckState {
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+	ch := make(chan CheckState, 1)
+	s := LoadCheck(path)
+	if time.Since(s.CheckedAt) < interval {
+		ch <- s
+		return ch
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, backgroundTimeout)
+		defer cancel()
+		s.CheckedAt = time.Now().UTC()
+		if rel, err := c.Latest(ctx); err == nil {
+			s.Latest, s.URL = rel.Version, rel.URL
+		}
+		_ = SaveCheck(path, s)
+		ch <- s
+	}()
+	return ch
+}
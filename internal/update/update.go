@@ -0,0 +1,277 @@
+// Package update finds newer Orbit releases on GitHub and installs them over
+// the running binary, verifying each download against the release's
+// checksums.txt.
+package update
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAPI is the GitHub API URL of Orbit's repository.
+const DefaultAPI = "https://api.github.com/repos/f9-o/orbit"
+
+// ChecksumsAsset is the release asset listing the SHA-256 of every binary,
+// in sha256sum format.
+const ChecksumsAsset = "checksums.txt"
+
+// maxBinarySize caps a downloaded binary so a bad release cannot fill the disk.
+const maxBinarySize = 512 << 20
+
+// Release is a published GitHub release.
+type Release struct {
+	Version   string    `json:"tag_name"`
+	URL       string    `json:"html_url"`
+	Published time.Time `json:"published_at"`
+	Assets    []Asset   `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// Client talks to the GitHub releases API.
+type Client struct {
+	api  string
+	http *http.Client
+}
+
+// New returns a Client for Orbit's GitHub repository.
+func New() *Client {
+	return &Client{api: DefaultAPI, http: &http.Client{Timeout: 5 * time.Minute}}
+}
+
+// WithAPI points the client at another repository API URL, e.g. a mirror.
+func (c *Client) WithAPI(url string) *Client {
+	c.api = strings.TrimSuffix(url, "/")
+	return c
+}
+
+// Latest returns the newest published release.
+func (c *Client) Latest(ctx context.Context) (*Release, error) {
+	return c.release(ctx, c.api+"/releases/latest")
+}
+
+// Tag returns the release of the given version tag, e.g. "v1.4.0".
+func (c *Client) Tag(ctx context.Context, tag string) (*Release, error) {
+	if !strings.HasPrefix(tag, "v") {
+		tag = "v" + tag
+	}
+	return c.release(ctx, c.api+"/releases/tags/"+tag)
+}
+
+func (c *Client) release(ctx context.Context, url string) (*Release, error) {
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var rel Release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("decode release: %w", err)
+	}
+	return &rel, nil
+}
+
+// get issues a GET and fails on any non-2xx status.
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "orbit")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch %s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// BinaryName is the release asset name of the binary for a platform, as
+// built by the release workflow: orbit-linux-amd64, orbit-windows-amd64.exe.
+func BinaryName(goos, goarch string) string {
+	name := "orbit-" + goos + "-" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Asset returns the release asset named name.
+func (r *Release) Asset(name string) (*Asset, error) {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("release %s has no %s", r.Version, name)
+}
+
+// Install downloads the release's binary for this platform, checks it
+// against the release's checksums and moves it over exe.
+func (c *Client) Install(ctx context.Context, rel *Release, exe string) error {
+	name := BinaryName(runtime.GOOS, runtime.GOARCH)
+	bin, err := rel.Asset(name)
+	if err != nil {
+		return err
+	}
+	sums, err := rel.Asset(ChecksumsAsset)
+	if err != nil {
+		return fmt.Errorf("%w; refusing to install an unverified binary", err)
+	}
+	want, err := c.checksum(ctx, sums.URL, name)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".orbit-update-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	resp, err := c.get(ctx, bin.URL)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	defer resp.Body.Close()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(resp.Body, maxBinarySize+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("download %s: %w", name, err)
+	}
+	if n > maxBinarySize {
+		return fmt.Errorf("download %s: larger than %d MiB", name, maxBinarySize>>20)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("download %s: sha256 %s does not match checksums.txt (%s)", name, got, want)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+	return replace(tmp.Name(), exe)
+}
+
+// checksum looks up name's SHA-256 in the checksums file at url.
+func (c *Client) checksum(ctx context.Context, url, name string) (string, error) {
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	sc := bufio.NewScanner(io.LimitReader(resp.Body, 1<<20))
+	for sc.Scan() {
+		// sha256sum lines: "<hex>  <name>", or "<hex> *<name>" in binary mode
+		sum, file, ok := strings.Cut(sc.Text(), " ")
+		if ok && strings.TrimLeft(file, " *") == name {
+			return strings.ToLower(sum), nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", fmt.Errorf("read %s: %w", ChecksumsAsset, err)
+	}
+	return "", fmt.Errorf("%s lists no checksum for %s", ChecksumsAsset, name)
+}
+
+// replace moves the new binary over exe. Windows will not overwrite a
+// running executable, but lets it be renamed aside first.
+func replace(src, exe string) error {
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("move %s aside: %w", exe, err)
+		}
+		if err := os.Rename(src, exe); err != nil {
+			_ = os.Rename(old, exe)
+			return fmt.Errorf("install %s: %w", exe, err)
+		}
+		return nil
+	}
+	if err := os.Rename(src, exe); err != nil {
+		return fmt.Errorf("install %s: %w", exe, err)
+	}
+	return nil
+}
+
+// Newer reports whether version latest is newer than current. Both are
+// semantic versions with an optional leading "v"; a pre-release sorts
+// before its release. A current version that does not parse, such as a
+// "dev" build, is never considered outdated.
+func Newer(latest, current string) bool {
+	l, ok1 := parseVersion(latest)
+	c, ok2 := parseVersion(current)
+	if !ok1 || !ok2 {
+		return false
+	}
+	for i := range l.num {
+		if l.num[i] != c.num[i] {
+			return l.num[i] > c.num[i]
+		}
+	}
+	switch {
+	case l.pre == c.pre:
+		return false
+	case l.pre == "":
+		return true
+	case c.pre == "":
+		return false
+	}
+	return l.pre > c.pre
+}
+
+// IsRelease reports whether version is a released version rather than a
+// development build.
+func IsRelease(version string) bool {
+	_, ok := parseVersion(version)
+	return ok
+}
+
+type semver struct {
+	num [3]int
+	pre string
+}
+
+func parseVersion(v string) (semver, bool) {
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "+") // build metadata does not order
+	v, pre, _ := strings.Cut(v, "-")
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return semver{}, false
+	}
+	var s semver
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return semver{}, false
+		}
+		s.num[i] = n
+	}
+	s.pre = pre
+	return s, true
+}
@@ -0,0 +1,134 @@
+package update
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewer(t *testing.T) {
+	cases := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.4.0", "v1.3.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"v1.3.0", "v1.3.0", false},
+		{"v1.3.0", "v1.4.0", false},
+		{"v1.4.0", "v1.4.0-rc.1", true},
+		{"v1.4.0-rc.2", "v1.4.0-rc.1", true},
+		{"v1.4.0-rc.1", "v1.4.0", false},
+		{"2.0.0", "v1.0.0", true},
+		{"v1.4.0", "dev", false},
+		{"nightly", "v1.0.0", false},
+	}
+	for _, c := range cases {
+		if got := Newer(c.latest, c.current); got != c.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", c.latest, c.current, got, c.want)
+		}
+	}
+}
+
+// fakeGitHub serves a release v9.9.9 whose binary for this platform is bin,
+// with checksums.txt listing sum for it.
+func fakeGitHub(t *testing.T, bin []byte, sum string) *httptest.Server {
+	t.Helper()
+	name := BinaryName(runtime.GOOS, runtime.GOARCH)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases/latest", "/releases/tags/v9.9.9":
+			json.NewEncoder(w).Encode(Release{
+				Version: "v9.9.9",
+				URL:     srv.URL + "/releases/v9.9.9",
+				Assets: []Asset{
+					{Name: name, URL: srv.URL + "/download/" + name},
+					{Name: ChecksumsAsset, URL: srv.URL + "/download/" + ChecksumsAsset},
+				},
+			})
+		case "/download/" + name:
+			w.Write(bin)
+		case "/download/" + ChecksumsAsset:
+			fmt.Fprintf(w, "%s  orbit-plan9-mips\n%s  %s\n", strings.Repeat("0", 64), sum, name)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestInstall(t *testing.T) {
+	bin := []byte("#!/bin/sh\necho new orbit\n")
+	h := sha256.Sum256(bin)
+	srv := fakeGitHub(t, bin, hex.EncodeToString(h[:]))
+	c := New().WithAPI(srv.URL)
+
+	rel, err := c.Tag(context.Background(), "9.9.9")
+	if err != nil || rel.Version != "v9.9.9" {
+		t.Fatalf("Tag = %+v, %v", rel, err)
+	}
+	exe := filepath.Join(t.TempDir(), "orbit")
+	os.WriteFile(exe, []byte("old"), 0o755)
+	if err := c.Install(context.Background(), rel, exe); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(exe)
+	info, _ := os.Stat(exe)
+	if string(got) != string(bin) || info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("installed %q with mode %v", got, info.Mode())
+	}
+}
+
+func TestInstallRejectsChecksumMismatch(t *testing.T) {
+	srv := fakeGitHub(t, []byte("tampered"), strings.Repeat("ab", 32))
+	c := New().WithAPI(srv.URL)
+	rel, err := c.Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "orbit")
+	os.WriteFile(exe, []byte("old"), 0o755)
+	if err := c.Install(context.Background(), rel, exe); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("err = %v", err)
+	}
+	if got, _ := os.ReadFile(exe); string(got) != "old" {
+		t.Errorf("binary replaced despite the mismatch: %q", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temp file left behind: %v", entries)
+	}
+}
+
+func TestBackground(t *testing.T) {
+	srv := fakeGitHub(t, nil, "")
+	path := filepath.Join(t.TempDir(), CheckFile)
+	c := New().WithAPI(srv.URL)
+
+	s := <-c.Background(context.Background(), path, time.Hour)
+	if s.Latest != "v9.9.9" || LoadCheck(path).Latest != "v9.9.9" {
+		t.Fatalf("first check = %+v, saved %+v", s, LoadCheck(path))
+	}
+
+	// Within the interval the remembered result is used, without a request.
+	srv.Close()
+	if s := <-c.Background(context.Background(), path, time.Hour); s.Latest != "v9.9.9" {
+		t.Errorf("remembered check = %+v", s)
+	}
+	// Past it, a failed request keeps the last known release.
+	SaveCheck(path, CheckState{CheckedAt: time.Now().Add(-2 * time.Hour), Latest: "v9.9.8"})
+	if s := <-c.Background(context.Background(), path, time.Hour); s.Latest != "v9.9.8" || time.Since(s.CheckedAt) > time.Minute {
+		t.Errorf("failed check = %+v", s)
+	}
+}
@@ -29,13 +29,19 @@ type Engine struct {
 
 // NewEngine initializes the secure encryption engine.
 // It loads a 32-byte master key from ORBIT_SECRET_KEY environment variable,
-// or reads/generates a safe key in ~/.orbit/.master.key.
+// derives it from a passphrase or reads it from the OS keychain when
+// `orbit state rekey` chose either, or reads/generates a safe key in
+// ~/.orbit/.master.key.
 func NewEngine() (*Engine, error) {
 	key, err := loadOrGenerateKey()
 	if err != nil {
 		return nil, err
 	}
+	return newEngine(key)
+}
 
+// newEngine initializes an engine for a 32-byte key.
+func newEngine(key []byte) (*Engine, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, errs.New(ErrEncryption, "encryption.InitCipher", err).
@@ -65,18 +71,20 @@ func loadOrGenerateKey() ([]byte, error) {
 			WithAdvice("ORBIT_SECRET_KEY must be a 32-byte raw string or a 64-character hex string.")
 	}
 
-	// 2. Check ~/.orbit/.master.key
-	homeDir, err := os.UserHomeDir()
+	orbitDir, err := keyDir()
 	if err != nil {
-		return nil, errs.New(ErrEncryption, "encryption.UserHomeDir", err).
-			WithAdvice("Unable to determine user home directory to store master key.")
+		return nil, err
 	}
 
-	orbitDir := filepath.Join(homeDir, ".orbit")
-	if err := os.MkdirAll(orbitDir, 0700); err != nil {
-		return nil, errs.New(ErrEncryption, "encryption.Mkdir", err)
+	// 2. A passphrase or keychain key chosen with `orbit state rekey`
+	switch storedSource(orbitDir) {
+	case SourcePassphrase:
+		return loadPassphraseKey(orbitDir)
+	case SourceKeychain:
+		return loadKeychainKey()
 	}
 
+	// 3. Check ~/.orbit/.master.key
 	keyPath := filepath.Join(orbitDir, KeyFilename)
 	data, err := os.ReadFile(keyPath)
 	if err == nil {
@@ -96,7 +104,7 @@ func loadOrGenerateKey() ([]byte, error) {
 		return nil, errs.New(ErrEncryption, "encryption.ReadFile", err)
 	}
 
-	// 3. Generate new key securely
+	// 4. Generate new key securely
 	key := make([]byte, 32)
 	if _, err := io.ReadFull(rand.Reader, key); err != nil {
 		return nil, errs.New(ErrEncryption, "encryption.Generate", err).
@@ -111,6 +119,21 @@ func loadOrGenerateKey() ([]byte, error) {
 	return key, nil
 }
 
+// keyDir is ~/.orbit, where key material is kept; created if missing.
+func keyDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errs.New(ErrEncryption, "encryption.UserHomeDir", err).
+			WithAdvice("Unable to determine user home directory to store master key.")
+	}
+
+	orbitDir := filepath.Join(homeDir, ".orbit")
+	if err := os.MkdirAll(orbitDir, 0700); err != nil {
+		return "", errs.New(ErrEncryption, "encryption.Mkdir", err)
+	}
+	return orbitDir, nil
+}
+
 // Encrypt encrypts the given plaintext using AES-256-GCM.
 func (e *Engine) Encrypt(plaintext []byte) ([]byte, error) {
 	nonce := make([]byte, e.aead.NonceSize())
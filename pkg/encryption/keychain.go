@@ -0,0 +1,100 @@
+// Package encryption: keeping the master key in the OS keychain, through the
+// macOS security tool or the Secret Service's secret-tool on Linux.
+package encryption
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// The keychain item holding the master key.
+const (
+	keychainService = "orbit"
+	keychainAccount = "master-key"
+)
+
+// keychainRun runs a keychain command with stdin and returns its output.
+// Tests replace it.
+var keychainRun = func(stdin string, argv ...string) (string, error) {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", argv[0], err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", argv[0], err)
+	}
+	return string(out), nil
+}
+
+// keychainArgs is the command, and its input, that does op — get, set or
+// delete — on the keychain item. set's input ends with the secret, so it
+// never appears in a process listing.
+func keychainArgs(op string) (argv []string, stdin string, err error) {
+	switch runtime.GOOS {
+	case "darwin":
+		switch op {
+		case "get":
+			return []string{"security", "find-generic-password", "-s", keychainService, "-a", keychainAccount, "-w"}, "", nil
+		case "set":
+			return []string{"security", "-i"}, fmt.Sprintf("add-generic-password -U -s %s -a %s -w ", keychainService, keychainAccount), nil
+		default:
+			return []string{"security", "delete-generic-password", "-s", keychainService, "-a", keychainAccount}, "", nil
+		}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		switch op {
+		case "get":
+			return []string{"secret-tool", "lookup", "service", keychainService, "account", keychainAccount}, "", nil
+		case "set":
+			return []string{"secret-tool", "store", "--label=orbit master key", "service", keychainService, "account", keychainAccount}, "", nil
+		default:
+			return []string{"secret-tool", "clear", "service", keychainService, "account", keychainAccount}, "", nil
+		}
+	}
+	return nil, "", errs.Newf(ErrEncryption, "encryption.Keychain", "no supported OS keychain on %s", runtime.GOOS).
+		WithAdvice("Use a passphrase instead: orbit state rekey --to passphrase")
+}
+
+func loadKeychainKey() ([]byte, error) {
+	argv, stdin, err := keychainArgs("get")
+	if err != nil {
+		return nil, err
+	}
+	out, err := keychainRun(stdin, argv...)
+	if err != nil {
+		return nil, errs.New(ErrEncryption, "encryption.LoadKeychainKey", err).
+			WithAdvice("Unlock the keychain; the master key is the item " + keychainService + "/" + keychainAccount)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(out))
+	if err != nil || len(key) != 32 {
+		return nil, errs.Newf(ErrEncryption, "encryption.LoadKeychainKey", "keychain item %s/%s is not a master key", keychainService, keychainAccount)
+	}
+	return key, nil
+}
+
+func keychainSet(secret string) error {
+	argv, stdin, err := keychainArgs("set")
+	if err != nil {
+		return err
+	}
+	_, err = keychainRun(stdin+secret+"\n", argv...)
+	return err
+}
+
+func keychainDelete() error {
+	argv, stdin, err := keychainArgs("delete")
+	if err != nil {
+		return err
+	}
+	_, err = keychainRun(stdin, argv...)
+	return err
+}
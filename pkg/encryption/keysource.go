@@ -0,0 +1,229 @@
+// Package encryption: where the master key comes from, and moving it to a
+// passphrase or the OS keychain so it is not kept beside the state it
+// protects.
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// KeySource is where the master key comes from.
+type KeySource string
+
+const (
+	SourceEnv        KeySource = "env"        // ORBIT_SECRET_KEY
+	SourceFile       KeySource = "file"       // ~/.orbit/.master.key, beside state.db
+	SourcePassphrase KeySource = "passphrase" // derived from ORBIT_PASSPHRASE, or asked for
+	SourceKeychain   KeySource = "keychain"   // macOS Keychain, or the Secret Service on Linux
+)
+
+// KeySources lists the sources a key can be moved to, in help order.
+var KeySources = []KeySource{SourceFile, SourcePassphrase, SourceKeychain}
+
+const (
+	// EnvPassphrase is the environment variable holding the passphrase the
+	// master key is derived from, for when it cannot be asked for.
+	EnvPassphrase = "ORBIT_PASSPHRASE"
+	// KDFFilename holds the salt and parameters of a passphrase key.
+	KDFFilename = ".master.kdf"
+	// KeychainFilename marks a master key kept in the OS keychain.
+	KeychainFilename = ".master.keychain"
+)
+
+// MinPassphrase is the shortest passphrase NewKey accepts.
+const MinPassphrase = 8
+
+// askPassphrase asks for the passphrase when ORBIT_PASSPHRASE is unset.
+var askPassphrase func(question string) (string, error)
+
+// SetPassphrasePrompt sets how the passphrase is asked for when
+// ORBIT_PASSPHRASE is unset. Without a prompt it must be set.
+func SetPassphrasePrompt(fn func(question string) (string, error)) {
+	askPassphrase = fn
+}
+
+// CurrentSource reports where NewEngine takes the master key from.
+func CurrentSource() KeySource {
+	if os.Getenv(EnvSecretKey) != "" {
+		return SourceEnv
+	}
+	if dir, err := keyDir(); err == nil {
+		if src := storedSource(dir); src != "" {
+			return src
+		}
+	}
+	return SourceFile
+}
+
+// storedSource is the source `orbit state rekey` moved the key to, or "".
+func storedSource(dir string) KeySource {
+	if _, err := os.Stat(filepath.Join(dir, KDFFilename)); err == nil {
+		return SourcePassphrase
+	}
+	if _, err := os.Stat(filepath.Join(dir, KeychainFilename)); err == nil {
+		return SourceKeychain
+	}
+	return ""
+}
+
+// kdf is the content of KDFFilename: how the key is derived with scrypt,
+// and a value encrypted with it to tell a wrong passphrase from a corrupt DB.
+type kdf struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	Salt  []byte `json:"salt"`
+	Check []byte `json:"check"`
+}
+
+const kdfCheck = "orbit"
+
+func (k kdf) derive(passphrase string) ([]byte, error) {
+	key, err := scrypt.Key([]byte(passphrase), k.Salt, k.N, k.R, k.P, 32)
+	if err != nil {
+		return nil, errs.New(ErrEncryption, "encryption.DeriveKey", err)
+	}
+	return key, nil
+}
+
+func loadPassphraseKey(dir string) ([]byte, error) {
+	path := filepath.Join(dir, KDFFilename)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.New(ErrEncryption, "encryption.LoadPassphraseKey", err)
+	}
+	var k kdf
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, errs.Newf(ErrEncryption, "encryption.LoadPassphraseKey", "%s: %v", path, err)
+	}
+	passphrase := os.Getenv(EnvPassphrase)
+	if passphrase == "" {
+		if askPassphrase == nil {
+			return nil, errs.Newf(ErrEncryption, "encryption.LoadPassphraseKey", "the state DB is encrypted with a passphrase and %s is not set", EnvPassphrase).
+				WithAdvice("Set " + EnvPassphrase + " to the passphrase given to `orbit state rekey`")
+		}
+		if passphrase, err = askPassphrase("State passphrase"); err != nil {
+			return nil, errs.New(ErrEncryption, "encryption.LoadPassphraseKey", err).
+				WithAdvice("Set " + EnvPassphrase + " when orbit runs unattended")
+		}
+	}
+	key, err := k.derive(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	e, err := newEngine(key)
+	if err != nil {
+		return nil, err
+	}
+	if check, err := e.Decrypt(k.Check); err != nil || string(check) != kdfCheck {
+		return nil, errs.Newf(ErrEncryption, "encryption.LoadPassphraseKey", "wrong state passphrase").
+			WithAdvice("Check " + EnvPassphrase + ", or the passphrase typed")
+	}
+	return key, nil
+}
+
+// Key is a new master key that is not stored yet. State is re-encrypted
+// with its Engine before Save makes it the key NewEngine loads.
+type Key struct {
+	Source KeySource
+	key    []byte
+	kdf    *kdf
+}
+
+// NewKey generates a master key to be kept in src. A passphrase key is
+// derived from passphrase.
+func NewKey(src KeySource, passphrase string) (*Key, error) {
+	k := &Key{Source: src}
+	switch src {
+	case SourceFile, SourceKeychain:
+		if src == SourceKeychain {
+			if _, _, err := keychainArgs("get"); err != nil {
+				return nil, err
+			}
+		}
+		k.key = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, k.key); err != nil {
+			return nil, errs.New(ErrEncryption, "encryption.Generate", err)
+		}
+	case SourcePassphrase:
+		if len(passphrase) < MinPassphrase {
+			return nil, errs.Newf(ErrEncryption, "encryption.NewKey", "the passphrase must be at least %d characters", MinPassphrase)
+		}
+		k.kdf = &kdf{N: 1 << 15, R: 8, P: 1, Salt: make([]byte, 16)}
+		if _, err := io.ReadFull(rand.Reader, k.kdf.Salt); err != nil {
+			return nil, errs.New(ErrEncryption, "encryption.Generate", err)
+		}
+		key, err := k.kdf.derive(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		e, err := newEngine(key)
+		if err != nil {
+			return nil, err
+		}
+		if k.kdf.Check, err = e.Encrypt([]byte(kdfCheck)); err != nil {
+			return nil, err
+		}
+		k.key = key
+	default:
+		return nil, errs.Newf(ErrEncryption, "encryption.NewKey", "unknown key source %q", src)
+	}
+	return k, nil
+}
+
+// Engine encrypts with k.
+func (k *Key) Engine() (*Engine, error) {
+	return newEngine(k.key)
+}
+
+// Save makes k the master key NewEngine loads, and deletes the key material
+// of the other sources.
+func (k *Key) Save() error {
+	dir, err := keyDir()
+	if err != nil {
+		return err
+	}
+	previous := storedSource(dir)
+	switch k.Source {
+	case SourceFile:
+		err = os.WriteFile(filepath.Join(dir, KeyFilename), []byte(hex.EncodeToString(k.key)), 0600)
+	case SourcePassphrase:
+		var data []byte
+		if data, err = json.Marshal(k.kdf); err == nil {
+			err = os.WriteFile(filepath.Join(dir, KDFFilename), data, 0600)
+		}
+	case SourceKeychain:
+		if err = keychainSet(hex.EncodeToString(k.key)); err == nil {
+			err = os.WriteFile(filepath.Join(dir, KeychainFilename), []byte(keychainService+"/"+keychainAccount+"\n"), 0600)
+		}
+	}
+	if err != nil {
+		return errs.New(ErrEncryption, "encryption.SaveKey", err)
+	}
+
+	var stale []string
+	for src, file := range map[KeySource]string{SourceFile: KeyFilename, SourcePassphrase: KDFFilename, SourceKeychain: KeychainFilename} {
+		if src != k.Source {
+			stale = append(stale, filepath.Join(dir, file))
+		}
+	}
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errs.New(ErrEncryption, "encryption.SaveKey", err)
+		}
+	}
+	if previous == SourceKeychain && k.Source != SourceKeychain {
+		_ = keychainDelete()
+	}
+	return nil
+}
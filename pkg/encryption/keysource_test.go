@@ -0,0 +1,128 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolate points ~/.orbit at a temporary directory with no key in the
+// environment, and returns it.
+func isolate(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvSecretKey, "")
+	t.Setenv(EnvPassphrase, "")
+	SetPassphrasePrompt(nil)
+	return filepath.Join(home, ".orbit")
+}
+
+func TestPassphraseKey(t *testing.T) {
+	dir := isolate(t)
+	old, err := NewEngine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src := CurrentSource(); src != SourceFile {
+		t.Fatalf("source = %s, want file", src)
+	}
+
+	if _, err := NewKey(SourcePassphrase, "short"); err == nil {
+		t.Error("short passphrase accepted")
+	}
+	key, err := NewKey(SourcePassphrase, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, _ := key.Engine()
+	sealed, _ := engine.Encrypt([]byte("state"))
+	if err := key.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, KeyFilename)); !os.IsNotExist(err) {
+		t.Errorf("%s kept after moving the key: %v", KeyFilename, err)
+	}
+	if src := CurrentSource(); src != SourcePassphrase {
+		t.Errorf("source = %s, want passphrase", src)
+	}
+
+	if _, err := NewEngine(); err == nil || !strings.Contains(err.Error(), EnvPassphrase) {
+		t.Errorf("no passphrase: err = %v", err)
+	}
+	t.Setenv(EnvPassphrase, "wrong horse")
+	if _, err := NewEngine(); err == nil || !strings.Contains(err.Error(), "wrong state passphrase") {
+		t.Errorf("wrong passphrase: err = %v", err)
+	}
+	SetPassphrasePrompt(func(string) (string, error) { return "correct horse", nil })
+	t.Setenv(EnvPassphrase, "")
+	e, err := NewEngine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := e.Decrypt(sealed); err != nil || string(plain) != "state" {
+		t.Errorf("decrypt = %q, %v", plain, err)
+	}
+	if _, err := e.Decrypt(must(old.Encrypt([]byte("state")))); err == nil {
+		t.Error("the old key still decrypts")
+	}
+}
+
+func TestKeychainKey(t *testing.T) {
+	dir := isolate(t)
+	if _, _, err := keychainArgs("get"); err != nil {
+		t.Skip(err)
+	}
+	store := map[string]string{}
+	run := keychainRun
+	defer func() { keychainRun = run }()
+	keychainRun = func(stdin string, argv ...string) (string, error) {
+		op := strings.Join(argv, " ")
+		switch {
+		case strings.Contains(op, "find-generic-password") || strings.Contains(op, "lookup"):
+			return store["key"] + "\n", nil
+		case strings.Contains(op, "delete-generic-password") || strings.Contains(op, "clear"):
+			delete(store, "key")
+		default:
+			fields := strings.Fields(stdin)
+			store["key"] = fields[len(fields)-1]
+		}
+		return "", nil
+	}
+
+	key, err := NewKey(SourceKeychain, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := key.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if store["key"] != hex.EncodeToString(key.key) || CurrentSource() != SourceKeychain {
+		t.Fatalf("keychain = %v, source = %s", store, CurrentSource())
+	}
+	if got, err := loadOrGenerateKey(); err != nil || !bytes.Equal(got, key.key) {
+		t.Fatalf("loaded %x, %v", got, err)
+	}
+
+	// Moving the key back to a file removes it from the keychain.
+	key, _ = NewKey(SourceFile, "")
+	if err := key.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store["key"]; ok {
+		t.Error("keychain item kept")
+	}
+	if _, err := os.Stat(filepath.Join(dir, KeychainFilename)); !os.IsNotExist(err) {
+		t.Errorf("%s kept: %v", KeychainFilename, err)
+	}
+}
+
+func must(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -29,6 +29,9 @@ const (
 	ErrServiceStop       ErrorCode = "ERR-SVC-003"
 	ErrServiceHealthFail ErrorCode = "ERR-SVC-004"
 	ErrServiceRollback   ErrorCode = "ERR-SVC-005"
+	ErrServiceVerify     ErrorCode = "ERR-SVC-006"
+	ErrServiceHook       ErrorCode = "ERR-SVC-007"
+	ErrServiceMigration  ErrorCode = "ERR-SVC-008"
 
 	// Docker errors
 	ErrDockerConnect ErrorCode = "ERR-DOCKER-001"
@@ -37,14 +40,29 @@ const (
 	ErrDockerRemove  ErrorCode = "ERR-DOCKER-004"
 	ErrDockerInspect ErrorCode = "ERR-DOCKER-005"
 
+	// Image errors
+	ErrImageScan       ErrorCode = "ERR-IMAGE-001"
+	ErrImageVulnerable ErrorCode = "ERR-IMAGE-002"
+
+	// Deploy policy errors
+	ErrPolicyWindow   ErrorCode = "ERR-POLICY-001"
+	ErrPolicyConfirm  ErrorCode = "ERR-POLICY-002"
+	ErrPolicyApproval ErrorCode = "ERR-POLICY-003"
+
 	// SSL errors
 	ErrSSLIssueFail    ErrorCode = "ERR-SSL-001"
 	ErrSSLRenewFail    ErrorCode = "ERR-SSL-002"
 	ErrSSLCertNotFound ErrorCode = "ERR-SSL-003"
+	ErrSSLExpired      ErrorCode = "ERR-SSL-004"
+
+	// Host errors
+	ErrHostDiskSpace    ErrorCode = "ERR-HOST-001"
+	ErrHostPortConflict ErrorCode = "ERR-HOST-002"
 
 	// State errors
-	ErrStateRead  ErrorCode = "ERR-STATE-001"
-	ErrStateWrite ErrorCode = "ERR-STATE-002"
+	ErrStateRead   ErrorCode = "ERR-STATE-001"
+	ErrStateWrite  ErrorCode = "ERR-STATE-002"
+	ErrStateLocked ErrorCode = "ERR-STATE-003"
 )
 
 // OrbitError is the standard structured error type used across all Orbit packages.
@@ -54,6 +72,15 @@ type OrbitError struct {
 	Node   string    // Resource identifier (node name, service name, etc.)
 	Cause  error     // Wrapped upstream error
 	Advice string    // Human-readable remediation hint
+	RunID  string    // Invocation that raised it; quoted to users as the support ID
+}
+
+// runID is stamped on every OrbitError created in this process.
+var runID string
+
+// SetRunID sets the run ID stamped on OrbitErrors created from now on.
+func SetRunID(id string) {
+	runID = id
 }
 
 func (e *OrbitError) Error() string {
@@ -76,17 +103,20 @@ func (e *OrbitError) UserMessage() string {
 	if e.Advice != "" {
 		msg += fmt.Sprintf("\n  → %s", e.Advice)
 	}
+	if e.RunID != "" {
+		msg += fmt.Sprintf("\n  Support ID: %s", e.RunID)
+	}
 	return msg
 }
 
 // New creates a new OrbitError.
 func New(code ErrorCode, op string, cause error) *OrbitError {
-	return &OrbitError{Code: code, Op: op, Cause: cause}
+	return &OrbitError{Code: code, Op: op, Cause: cause, RunID: runID}
 }
 
 // Newf creates a new OrbitError with a formatted message as the cause.
 func Newf(code ErrorCode, op, format string, args ...any) *OrbitError {
-	return &OrbitError{Code: code, Op: op, Cause: fmt.Errorf(format, args...)}
+	return &OrbitError{Code: code, Op: op, Cause: fmt.Errorf(format, args...), RunID: runID}
 }
 
 // WithNode sets the node/resource identifier on an OrbitError.
@@ -106,11 +136,21 @@ func Wrap(err error, code ErrorCode, op string) *OrbitError {
 	if err == nil {
 		return nil
 	}
-	return &OrbitError{Code: code, Op: op, Cause: err}
+	return &OrbitError{Code: code, Op: op, Cause: err, RunID: runID}
 }
 
-// IsCode reports whether err is an OrbitError with the given code.
+// IsCode reports whether err is an OrbitError with the given code, or a
+// MultiError with such an error for any of its resources.
 func IsCode(err error, code ErrorCode) bool {
+	var m *MultiError
+	if errors.As(err, &m) {
+		for _, e := range m.Errors {
+			if IsCode(e, code) {
+				return true
+			}
+		}
+		return false
+	}
 	var oe *OrbitError
 	if errors.As(err, &oe) {
 		return oe.Code == code
@@ -0,0 +1,123 @@
+// Package errs: collecting the errors of an operation over many resources.
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Group collects the errors of one operation applied to many resources
+// (services, nodes, containers), so the operation can carry on past the
+// first failure and report them all at the end. It is safe for concurrent use.
+type Group struct {
+	code ErrorCode
+	op   string
+
+	mu     sync.Mutex
+	total  int
+	failed []*OrbitError
+}
+
+// NewGroup creates a Group for op. Errors added that are not already
+// OrbitErrors are wrapped with code.
+func NewGroup(code ErrorCode, op string) *Group {
+	return &Group{code: code, op: op}
+}
+
+// Add records the outcome of the operation on resource; a nil err counts it
+// as done. An OrbitError without a resource gets this one.
+func (g *Group) Add(resource string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.total++
+	if err == nil {
+		return
+	}
+	oe := AsOrbit(err)
+	switch {
+	case oe == nil:
+		oe = Wrap(err, g.code, g.op).WithNode(resource)
+	case oe.Node == "":
+		c := *oe
+		oe = c.WithNode(resource)
+	}
+	g.failed = append(g.failed, oe)
+}
+
+// Len returns the number of failures recorded.
+func (g *Group) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.failed)
+}
+
+// Err returns nil when nothing failed, the error itself when one resource
+// failed, and a *MultiError when several did.
+func (g *Group) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch len(g.failed) {
+	case 0:
+		return nil
+	case 1:
+		return g.failed[0]
+	}
+	return &MultiError{Op: g.op, Total: g.total, Errors: append([]*OrbitError(nil), g.failed...)}
+}
+
+// MultiError is an operation that failed for several resources.
+type MultiError struct {
+	Op     string
+	Total  int           // resources the operation was applied to
+	Errors []*OrbitError // one per failed resource, in the order they failed
+}
+
+func (m *MultiError) Error() string {
+	parts := make([]string, len(m.Errors))
+	for i, e := range m.Errors {
+		parts[i] = fmt.Sprintf("%s: %v", e.Node, e.Cause)
+	}
+	return fmt.Sprintf("%s failed for %d of %d: %s", m.Op, len(m.Errors), m.Total, strings.Join(parts, "; "))
+}
+
+// Unwrap exposes each resource's error to errors.Is and errors.As.
+func (m *MultiError) Unwrap() []error {
+	out := make([]error, len(m.Errors))
+	for i, e := range m.Errors {
+		out[i] = e
+	}
+	return out
+}
+
+// Summary renders the failures one resource per line, with their codes and
+// any remediation advice, for printing to the user.
+func (m *MultiError) Summary() string {
+	width := 0
+	for _, e := range m.Errors {
+		width = max(width, len(e.Node))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s failed for %d of %d:", m.Op, len(m.Errors), m.Total)
+	for _, e := range m.Errors {
+		fmt.Fprintf(&b, "\n  %-*s  %s  %v", width, e.Node, e.Code, e.Cause)
+		if e.Advice != "" {
+			fmt.Fprintf(&b, "\n  %-*s  → %s", width, "", e.Advice)
+		}
+	}
+	return b.String()
+}
+
+// Collect returns every OrbitError in err: the resources of a MultiError,
+// err itself when it is an OrbitError, or nothing.
+func Collect(err error) []*OrbitError {
+	var m *MultiError
+	if errors.As(err, &m) {
+		return m.Errors
+	}
+	if oe := AsOrbit(err); oe != nil {
+		return []*OrbitError{oe}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	g := NewGroup(ErrServiceStart, "up")
+	g.Add("db", nil)
+	if g.Err() != nil {
+		t.Fatalf("Err with no failures = %v", g.Err())
+	}
+
+	g.Add("web", context.DeadlineExceeded)
+	oe := AsOrbit(g.Err())
+	if oe == nil || oe.Node != "web" || oe.Code != ErrServiceStart || !errors.Is(g.Err(), context.DeadlineExceeded) {
+		t.Fatalf("single failure = %#v", g.Err())
+	}
+
+	locked := Newf(ErrStateLocked, "lock", "held by deploy").WithAdvice("Wait, or run `orbit locks unlock`")
+	g.Add("api", locked)
+	err := g.Err()
+	var m *MultiError
+	if !errors.As(err, &m) || m.Total != 3 || len(m.Errors) != 2 {
+		t.Fatalf("err = %#v", err)
+	}
+	if locked.Node != "" {
+		t.Error("Add changed the caller's error")
+	}
+	if !IsCode(err, ErrStateLocked) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("errors.Is/As do not reach the resources' errors")
+	}
+	if got := len(Collect(err)); got != 2 {
+		t.Errorf("Collect = %d errors", got)
+	}
+
+	sum := m.Summary()
+	for _, want := range []string{"up failed for 2 of 3:", "web  ERR-SVC-002", "api  ERR-STATE-003  held by deploy", "→ Wait"} {
+		if !strings.Contains(sum, want) {
+			t.Errorf("summary lacks %q:\n%s", want, sum)
+		}
+	}
+	if !strings.Contains(err.Error(), "web: context deadline exceeded; api: held by deploy") {
+		t.Errorf("Error() = %s", err)
+	}
+}
@@ -0,0 +1,67 @@
+// Package errs: telling transient failures from terminal ones.
+package errs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// Retryable reports whether an operation that failed with code may succeed
+// when simply tried again: the node, daemon or registry was unreachable or
+// slow. Every other code is terminal.
+func (c ErrorCode) Retryable() bool {
+	switch c {
+	case ErrNodeConnect, ErrNodeTimeout, ErrDockerConnect, ErrDockerPull:
+		return true
+	}
+	return false
+}
+
+// Permanent marks err as terminal, so Retryable reports false for it
+// whatever it wraps.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// transientErrnos are socket errors that usually clear up on their own.
+var transientErrnos = []syscall.Errno{
+	syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.ECONNABORTED,
+	syscall.EPIPE, syscall.EHOSTUNREACH, syscall.ENETUNREACH,
+}
+
+// Retryable reports whether err is transient and the operation that
+// returned it worth trying again. An OrbitError is classified by its code,
+// unless the code is ErrUnknown; otherwise timeouts, refused or reset
+// connections and truncated responses are transient. Cancellation, errors
+// marked Permanent and anything unrecognised are terminal.
+func Retryable(err error) bool {
+	var perm *permanentError
+	if err == nil || errors.As(err, &perm) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if oe := AsOrbit(err); oe != nil && oe.Code != ErrUnknown {
+		return oe.Code.Retryable()
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	for _, errno := range transientErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
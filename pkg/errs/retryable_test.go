@@ -0,0 +1,35 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestRetryable(t *testing.T) {
+	timeout := &net.OpError{Op: "dial", Err: &net.DNSError{IsTimeout: true}}
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("invalid reference format"), false},
+		{fmt.Errorf("ssh dial: %w", syscall.ECONNREFUSED), true},
+		{timeout, true},
+		{io.ErrUnexpectedEOF, true},
+		{context.DeadlineExceeded, false},
+		{Permanent(syscall.ECONNRESET), false},
+		{Newf(ErrDockerPull, "pull", "registry went away"), true},
+		{Wrap(syscall.ECONNRESET, ErrConfig, "load"), false},
+		{Wrap(syscall.ECONNRESET, ErrUnknown, "x"), true},
+	}
+	for _, c := range cases {
+		if got := Retryable(c.err); got != c.want {
+			t.Errorf("Retryable(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
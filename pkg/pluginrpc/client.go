@@ -0,0 +1,228 @@
+// Package pluginrpc: host side — starting a plugin binary and calling it.
+package pluginrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// StartTimeout bounds how long a plugin may take to print its handshake.
+const StartTimeout = 10 * time.Second
+
+// CallTimeout bounds each call into a plugin, hooks included.
+const CallTimeout = 30 * time.Second
+
+// exitTimeout is how long Shutdown waits for the process before killing it.
+const exitTimeout = 5 * time.Second
+
+// Client is a running plugin binary. It implements v1.PluginV1,
+// v1.PluginConfigRequirer and v1.PluginVersioner, so the host registers it
+// like an in-process plugin.
+type Client struct {
+	path  string
+	desc  description
+	conn  *grpc.ClientConn
+	cmd   *exec.Cmd
+	stdin io.Closer
+	done  chan struct{} // closed when cmd has exited
+}
+
+// Launch starts the plugin binary at path, completes the handshake, and asks
+// the plugin to describe itself. The plugin's log output goes to logw.
+func Launch(path string, logw io.Writer) (*Client, error) {
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), MagicCookieKey+"="+MagicCookieValue)
+	cmd.Stderr = logw
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+
+	kill := func() {
+		_ = stdin.Close()
+		_ = cmd.Process.Kill()
+		<-done
+	}
+	line, err := readHandshake(stdout, logw, StartTimeout, done)
+	if err != nil {
+		kill()
+		return nil, err
+	}
+	c, err := dial(line)
+	if err != nil {
+		kill()
+		return nil, err
+	}
+	c.path, c.cmd, c.stdin, c.done = path, cmd, stdin, done
+	return c, nil
+}
+
+// readHandshake returns the first line of stdout, then copies the rest to
+// logw. It fails if the line does not arrive within timeout or the process
+// exits first.
+func readHandshake(stdout io.Reader, logw io.Writer, timeout time.Duration, exited <-chan struct{}) (string, error) {
+	lines := make(chan string, 1)
+	go func() {
+		r := bufio.NewReader(stdout)
+		line, err := r.ReadString('\n')
+		if err == nil {
+			lines <- strings.TrimSpace(line)
+		}
+		_, _ = io.Copy(logw, r)
+	}()
+	select {
+	case line := <-lines:
+		return line, nil
+	case <-exited:
+		return "", errors.New("plugin exited before completing the handshake")
+	case <-time.After(timeout):
+		return "", fmt.Errorf("no handshake within %s", timeout)
+	}
+}
+
+// dial parses a handshake line, connects to the socket it names, and fetches
+// the plugin's description.
+func dial(line string) (*Client, error) {
+	parts := strings.Split(line, "|")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("malformed handshake %q", line)
+	}
+	core, api, network, addr, proto := parts[0], parts[1], parts[2], parts[3], parts[4]
+	if core != CoreProtocolVersion {
+		return nil, fmt.Errorf("protocol version mismatch: plugin=%q, host=%q", core, CoreProtocolVersion)
+	}
+	if api != v1.PluginAPIVersion {
+		return nil, fmt.Errorf("API version mismatch: plugin=%q, host=%q", api, v1.PluginAPIVersion)
+	}
+	if proto != protocolGRPC {
+		return nil, fmt.Errorf("unsupported plugin protocol %q", proto)
+	}
+
+	var target string
+	switch network {
+	case "unix":
+		target = "unix://" + addr
+	case "tcp":
+		target = "passthrough:///" + addr
+	default:
+		return nil, fmt.Errorf("unsupported plugin network %q", network)
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("connect %s: %w", addr, err)
+	}
+
+	c := &Client{conn: conn}
+	if err := c.call(methodDescribe, nil, &c.desc); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("describe: %w", err)
+	}
+	return c, nil
+}
+
+// call invokes method with arg encoded as JSON and decodes the reply into
+// reply when it is non-nil.
+func (c *Client) call(method string, arg, reply any) error {
+	in, err := json.Marshal(arg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), CallTimeout)
+	defer cancel()
+	out := new(wrapperspb.BytesValue)
+	if err := c.conn.Invoke(ctx, "/"+serviceName+"/"+method, wrapperspb.Bytes(in), out); err != nil {
+		return errors.New(status.Convert(err).Message())
+	}
+	if reply == nil {
+		return nil
+	}
+	return json.Unmarshal(out.Value, reply)
+}
+
+// Path returns the plugin binary's path.
+func (c *Client) Path() string { return c.path }
+
+// Name returns the name the plugin reported at launch.
+func (c *Client) Name() string { return c.desc.Name }
+
+// APIVersion returns the API version the plugin reported at launch.
+func (c *Client) APIVersion() string { return c.desc.APIVersion }
+
+// Version returns the release version the plugin reported at launch.
+func (c *Client) Version() string { return c.desc.Version }
+
+// RequiredConfig returns the config keys the plugin reported at launch.
+func (c *Client) RequiredConfig() []string { return c.desc.RequiredConfig }
+
+// Init passes cfg to the plugin's Init.
+func (c *Client) Init(cfg map[string]string) error {
+	return c.call(methodInit, cfg, nil)
+}
+
+// Hooks asks the plugin which hooks it subscribes to and returns a HookFunc
+// per hook that fires it remotely. An unreachable plugin subscribes to none.
+func (c *Client) Hooks() map[string]v1.HookFunc {
+	var names []string
+	if err := c.call(methodHooks, nil, &names); err != nil {
+		return nil
+	}
+	hooks := make(map[string]v1.HookFunc, len(names))
+	for _, name := range names {
+		hook := name
+		hooks[hook] = func(hctx v1.HookContext) error {
+			return c.call(methodFire, fireRequest{Hook: hook, Context: hctx}, nil)
+		}
+	}
+	return hooks
+}
+
+// Shutdown calls the plugin's Shutdown and waits for the process to exit,
+// killing it if it does not.
+func (c *Client) Shutdown() error {
+	err := c.call(methodShutdown, nil, nil)
+	c.Kill()
+	return err
+}
+
+// Kill closes the connection and stops the process without calling the
+// plugin's Shutdown.
+func (c *Client) Kill() {
+	c.conn.Close()
+	if c.cmd == nil {
+		return
+	}
+	_ = c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(exitTimeout):
+		_ = c.cmd.Process.Kill()
+		<-c.done
+	}
+}
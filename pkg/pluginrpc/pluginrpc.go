@@ -0,0 +1,214 @@
+// Package pluginrpc is the out-of-process plugin transport. A plugin binary
+// passes its api/v1.PluginV1 to Serve; the host starts the binary with Launch
+// and gets back a PluginV1 whose calls travel over gRPC.
+//
+// The handshake follows hashicorp/go-plugin's: the host sets MagicCookieKey
+// in the child's environment, and the child answers with a single stdout line
+//
+//	CORE-VERSION|API-VERSION|NETWORK|ADDRESS|grpc
+//
+// naming the socket it serves on. Anything after that line on stdout, and all
+// of stderr, is treated as plugin log output. The child exits when its stdin
+// closes, so a plugin never outlives the host that started it.
+package pluginrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+// Handshake constants shared by host and plugin.
+const (
+	MagicCookieKey      = "ORBIT_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue    = "4d0c6e8a-orbit-plugin"
+	CoreProtocolVersion = "1"
+	protocolGRPC        = "grpc"
+)
+
+// serviceName is the gRPC service every plugin registers. Each method takes
+// and returns a BytesValue holding JSON, which keeps the wire contract the
+// Go types of api/v1 without a generated stub.
+const serviceName = "orbit.plugin.v1.Plugin"
+
+const (
+	methodDescribe = "Describe"
+	methodInit     = "Init"
+	methodHooks    = "Hooks"
+	methodFire     = "Fire"
+	methodShutdown = "Shutdown"
+)
+
+// description is the reply to Describe.
+type description struct {
+	Name           string   `json:"name"`
+	APIVersion     string   `json:"api_version"`
+	Version        string   `json:"version,omitempty"`
+	RequiredConfig []string `json:"required_config,omitempty"`
+}
+
+// fireRequest is the argument of Fire.
+type fireRequest struct {
+	Hook    string         `json:"hook"`
+	Context v1.HookContext `json:"context"`
+}
+
+// Serve runs impl as an out-of-process plugin and does not return until the
+// host shuts it down. Call it from the plugin binary's main. Started outside
+// orbit, it prints a hint and exits 1.
+func Serve(impl v1.PluginV1) {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		fmt.Fprintln(os.Stderr, "This binary is an Orbit plugin. Copy it to ~/.orbit/plugins; orbit starts it itself.")
+		os.Exit(1)
+	}
+	if err := serve(impl, os.Stdout, os.Stdin); err != nil {
+		fmt.Fprintf(os.Stderr, "orbit plugin: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serve listens on a fresh socket, announces it on out, and serves until
+// Shutdown or until parent (when non-nil) reaches EOF.
+func serve(impl v1.PluginV1, out io.Writer, parent io.Reader) error {
+	network, addr := "unix", ""
+	if runtime.GOOS == "windows" {
+		network, addr = "tcp", "127.0.0.1:0"
+	} else {
+		dir, err := os.MkdirTemp("", "orbit-plugin-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		addr = filepath.Join(dir, "plugin.sock")
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	srv := grpc.NewServer()
+	srv.RegisterService(&serviceDesc, &server{impl: impl, stop: func() { go srv.GracefulStop() }})
+	if parent != nil {
+		go func() {
+			_, _ = io.Copy(io.Discard, parent)
+			srv.Stop()
+		}()
+	}
+
+	if _, err := fmt.Fprintf(out, "%s|%s|%s|%s|%s\n",
+		CoreProtocolVersion, v1.PluginAPIVersion, network, ln.Addr().String(), protocolGRPC); err != nil {
+		ln.Close()
+		return fmt.Errorf("handshake: %w", err)
+	}
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// server adapts a PluginV1 to the plugin service.
+type server struct {
+	impl  v1.PluginV1
+	hooks map[string]v1.HookFunc
+	stop  func()
+}
+
+func (s *server) describe([]byte) (any, error) {
+	d := description{Name: s.impl.Name(), APIVersion: s.impl.APIVersion()}
+	if req, ok := s.impl.(v1.PluginConfigRequirer); ok {
+		d.RequiredConfig = req.RequiredConfig()
+	}
+	if ver, ok := s.impl.(v1.PluginVersioner); ok {
+		d.Version = ver.Version()
+	}
+	return d, nil
+}
+
+func (s *server) init(in []byte) (any, error) {
+	var cfg map[string]string
+	if err := json.Unmarshal(in, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		cfg = map[string]string{}
+	}
+	return nil, s.impl.Init(cfg)
+}
+
+func (s *server) listHooks([]byte) (any, error) {
+	s.hooks = s.impl.Hooks()
+	names := make([]string, 0, len(s.hooks))
+	for name := range s.hooks {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (s *server) fire(in []byte) (any, error) {
+	var req fireRequest
+	if err := json.Unmarshal(in, &req); err != nil {
+		return nil, err
+	}
+	fn, ok := s.hooks[req.Hook]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "hook %s not registered", req.Hook)
+	}
+	return nil, fn(req.Context)
+}
+
+func (s *server) shutdown([]byte) (any, error) {
+	defer s.stop()
+	return nil, s.impl.Shutdown()
+}
+
+// serviceDesc registers the plugin service by hand, as generated code would.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		unary(methodDescribe, (*server).describe),
+		unary(methodInit, (*server).init),
+		unary(methodHooks, (*server).listHooks),
+		unary(methodFire, (*server).fire),
+		unary(methodShutdown, (*server).shutdown),
+	},
+}
+
+// unary wraps a JSON-in, JSON-out server method as a gRPC method. Plugin
+// errors become status errors carrying their message.
+func unary(name string, call func(*server, []byte) (any, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+			in := new(wrapperspb.BytesValue)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			reply, err := call(srv.(*server), in.Value)
+			if err != nil {
+				if _, ok := status.FromError(err); ok {
+					return nil, err
+				}
+				return nil, status.Error(codes.Unknown, err.Error())
+			}
+			out, err := json.Marshal(reply)
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			return wrapperspb.Bytes(out), nil
+		},
+	}
+}
@@ -0,0 +1,91 @@
+package pluginrpc
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	v1 "github.com/f9-o/orbit/api/v1"
+)
+
+type echoPlugin struct{ cfg map[string]string }
+
+func (p *echoPlugin) Name() string                     { return "echo" }
+func (p *echoPlugin) APIVersion() string               { return v1.PluginAPIVersion }
+func (p *echoPlugin) Init(cfg map[string]string) error { p.cfg = cfg; return nil }
+func (p *echoPlugin) Shutdown() error                  { return nil }
+func (p *echoPlugin) RequiredConfig() []string         { return []string{"prefix"} }
+func (p *echoPlugin) Hooks() map[string]v1.HookFunc {
+	return map[string]v1.HookFunc{
+		"OnPreDeploy": func(hctx v1.HookContext) error {
+			if hctx.Service == nil || hctx.Service.Name != "api" || hctx.ImageTo != "api:2" {
+				return errors.New("unexpected context")
+			}
+			if hctx.Metadata["reject"] != "" {
+				return errors.New(p.cfg["prefix"] + hctx.Metadata["reject"])
+			}
+			return nil
+		},
+	}
+}
+
+// TestMain lets the test binary double as a plugin: Launch re-executes it
+// with the magic cookie set.
+func TestMain(m *testing.M) {
+	if os.Getenv(MagicCookieKey) == MagicCookieValue {
+		Serve(&echoPlugin{})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestLaunchRoundTrip(t *testing.T) {
+	c, err := Launch(os.Args[0], io.Discard)
+	if err != nil {
+		t.Fatalf("launch: %v", err)
+	}
+	if c.Name() != "echo" || c.APIVersion() != v1.PluginAPIVersion || len(c.RequiredConfig()) != 1 {
+		t.Fatalf("described as %+v", c.desc)
+	}
+	if err := c.Init(map[string]string{"prefix": "denied: "}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	hooks := c.Hooks()
+	fire := hooks["OnPreDeploy"]
+	if len(hooks) != 1 || fire == nil {
+		t.Fatalf("hooks = %v", hooks)
+	}
+
+	hctx := v1.HookContext{Service: &v1.ServiceSpec{Name: "api"}, ImageTo: "api:2"}
+	if err := fire(hctx); err != nil {
+		t.Errorf("fire: %v", err)
+	}
+	hctx.Metadata = map[string]string{"reject": "freeze window"}
+	if err := fire(hctx); err == nil || err.Error() != "denied: freeze window" {
+		t.Errorf("fire err = %v, want the plugin's message", err)
+	}
+
+	if err := c.Shutdown(); err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+	select {
+	case <-c.done:
+	default:
+		t.Error("process still running after Shutdown")
+	}
+}
+
+func TestDialRejectsBadHandshake(t *testing.T) {
+	for _, line := range []string{
+		"garbage",
+		"2|v1|unix|/tmp/x.sock|grpc",
+		"1|v9|unix|/tmp/x.sock|grpc",
+		"1|v1|unix|/tmp/x.sock|netrpc",
+		"1|v1|udp|127.0.0.1:1|grpc",
+	} {
+		if _, err := dial(line); err == nil {
+			t.Errorf("dial(%q) succeeded", line)
+		}
+	}
+}
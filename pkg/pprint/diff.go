@@ -0,0 +1,251 @@
+// Package pprint: coloured unified and field-by-field diffs.
+package pprint
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultDiffContext is how many unchanged lines surround each hunk.
+const DefaultDiffContext = 3
+
+// maxDiffCells bounds the line-matching table; larger inputs are shown as
+// wholly replaced.
+const maxDiffCells = 4 << 20
+
+// DiffOp says what happened to a line.
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffRemove
+	DiffAdd
+)
+
+// DiffLine is one line of a line diff.
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// Change is a field whose value changes, for Diff.Fields.
+type Change struct {
+	Field    string
+	From, To string // "" is shown as (none)
+}
+
+// Diff renders differences: unified diffs of text, such as generated
+// configs, and field-by-field changes, such as a deploy plan.
+type Diff struct {
+	out     io.Writer
+	context int
+}
+
+// NewDiff returns a Diff writing to stdout with DefaultDiffContext lines of
+// context.
+func NewDiff() *Diff {
+	return &Diff{out: os.Stdout, context: DefaultDiffContext}
+}
+
+// WithWriter sets where the diff is rendered.
+func (d *Diff) WithWriter(w io.Writer) *Diff {
+	d.out = w
+	return d
+}
+
+// WithContext sets how many unchanged lines surround each hunk.
+func (d *Diff) WithContext(n int) *Diff {
+	d.context = max(n, 0)
+	return d
+}
+
+// Unified prints a unified diff from a to b, headed by their names, and
+// reports whether they differ. Nothing is printed when they are equal.
+func (d *Diff) Unified(fromName, toName, a, b string) bool {
+	lines := DiffLines(a, b)
+	hunks := d.hunks(lines)
+	if len(hunks) == 0 {
+		return false
+	}
+	fmt.Fprintln(d.out, StyleError.Render("--- "+fromName))
+	fmt.Fprintln(d.out, StyleSuccess.Render("+++ "+toName))
+	for _, h := range hunks {
+		fmt.Fprintln(d.out, StyleAccent.Render(h.header()))
+		for _, l := range lines[h.start:h.end] {
+			switch l.Op {
+			case DiffAdd:
+				fmt.Fprintln(d.out, StyleSuccess.Render("+"+l.Text))
+			case DiffRemove:
+				fmt.Fprintln(d.out, StyleError.Render("-"+l.Text))
+			default:
+				fmt.Fprintln(d.out, StyleMuted.Render(" "+l.Text))
+			}
+		}
+	}
+	return true
+}
+
+// Fields prints one "field: from → to" line per change, old values in red
+// and new ones in green, each line prefixed with indent.
+func (d *Diff) Fields(indent string, changes []Change) {
+	for _, c := range changes {
+		from, to := c.From, c.To
+		if from == "" {
+			from = "(none)"
+		}
+		if to == "" {
+			to = "(none)"
+		}
+		fmt.Fprintf(d.out, "%s%s %s %s %s\n", indent, StyleText.Render(c.Field+":"),
+			StyleError.Render(from), StyleMuted.Render("→"), StyleSuccess.Render(to))
+	}
+}
+
+// DiffLines matches the lines of a and b, returning them in order as kept,
+// removed from a or added from b.
+func DiffLines(a, b string) []DiffLine {
+	x, y := splitLines(a), splitLines(b)
+	n, m := len(x), len(y)
+	if n*m > maxDiffCells {
+		out := make([]DiffLine, 0, n+m)
+		for _, l := range x {
+			out = append(out, DiffLine{DiffRemove, l})
+		}
+		for _, l := range y {
+			out = append(out, DiffLine{DiffAdd, l})
+		}
+		return out
+	}
+
+	// lcs[i][j] is the longest common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Walk the table, replacing a line by its counterpart where that costs
+	// no common line, so edits pair up rather than drift apart. Each run of
+	// changes lists its removals before its additions.
+	out := make([]DiffLine, 0, max(n, m))
+	var removed, added []DiffLine
+	flush := func() {
+		out = append(append(out, removed...), added...)
+		removed, added = removed[:0], added[:0]
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && x[i] == y[j]:
+			flush()
+			out = append(out, DiffLine{DiffEqual, x[i]})
+			i, j = i+1, j+1
+		case i < n && j < m && lcs[i+1][j+1] == lcs[i][j]:
+			removed = append(removed, DiffLine{DiffRemove, x[i]})
+			added = append(added, DiffLine{DiffAdd, y[j]})
+			i, j = i+1, j+1
+		case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = append(removed, DiffLine{DiffRemove, x[i]})
+			i++
+		default:
+			added = append(added, DiffLine{DiffAdd, y[j]})
+			j++
+		}
+	}
+	flush()
+	return out
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// hunk is a run of lines[start:end] holding changes and their context.
+type hunk struct {
+	start, end        int
+	fromLine, fromLen int
+	toLine, toLen     int
+}
+
+func (h hunk) header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.fromLine, h.fromLen, h.toLine, h.toLen)
+}
+
+// hunks groups changed lines, with d.context lines around them, merging
+// groups whose context overlaps.
+func (d *Diff) hunks(lines []DiffLine) []hunk {
+	var out []hunk
+	for i := 0; i < len(lines); {
+		if lines[i].Op == DiffEqual {
+			i++
+			continue
+		}
+		start := max(i-d.context, 0)
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != DiffEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Op == DiffEqual {
+				run++
+			}
+			if run == len(lines) || run-end > 2*d.context {
+				end = min(end+d.context, len(lines))
+				break
+			}
+			end = run
+		}
+		if len(out) > 0 && start <= out[len(out)-1].end {
+			start = out[len(out)-1].start
+			out = out[:len(out)-1]
+		}
+		out = append(out, hunk{start: start, end: end})
+		i = end
+	}
+
+	// Line numbers: count a's and b's lines before and inside each hunk.
+	from, to, pos := 1, 1, 0
+	for k := range out {
+		h := &out[k]
+		for ; pos < h.start; pos++ {
+			from, to = advance(lines[pos].Op, from, to)
+		}
+		h.fromLine, h.toLine = from, to
+		for ; pos < h.end; pos++ {
+			from, to = advance(lines[pos].Op, from, to)
+		}
+		h.fromLen, h.toLen = from-h.fromLine, to-h.toLine
+		if h.fromLen == 0 {
+			h.fromLine-- // unified diff convention for an empty range
+		}
+		if h.toLen == 0 {
+			h.toLine--
+		}
+	}
+	return out
+}
+
+func advance(op DiffOp, from, to int) (int, int) {
+	switch op {
+	case DiffRemove:
+		return from + 1, to
+	case DiffAdd:
+		return from, to + 1
+	}
+	return from + 1, to + 1
+}
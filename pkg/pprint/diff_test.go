@@ -0,0 +1,59 @@
+package pprint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiffUnified(t *testing.T) {
+	a := "server {\n  listen 80;\n  server_name a.example.com;\n  location / {\n    proxy_pass http://127.0.0.1:3000;\n  }\n}\n"
+	b := "server {\n  listen 80;\n  server_name a.example.com;\n  location / {\n    proxy_pass http://127.0.0.1:3001;\n  }\n}\n# extra\n"
+
+	var buf bytes.Buffer
+	if !NewDiff().WithWriter(&buf).WithContext(1).Unified("old/app.conf", "new/app.conf", a, b) {
+		t.Fatal("no difference reported")
+	}
+	want := `--- old/app.conf
++++ new/app.conf
+@@ -4,4 +4,5 @@
+   location / {
+-    proxy_pass http://127.0.0.1:3000;
++    proxy_pass http://127.0.0.1:3001;
+   }
+ }
++# extra
+`
+	if got := ansiSeq.ReplaceAllString(buf.String(), ""); got != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	if NewDiff().WithWriter(&buf).Unified("a", "b", a, a) || buf.Len() != 0 {
+		t.Errorf("equal inputs printed %q", buf.String())
+	}
+}
+
+func TestDiffSeparateHunks(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20; i++ {
+		a = append(a, "line")
+		b = append(b, "line")
+	}
+	b[2], b[17] = "changed", "changed"
+	var buf bytes.Buffer
+	NewDiff().WithWriter(&buf).Unified("a", "b", strings.Join(a, "\n"), strings.Join(b, "\n"))
+	out := ansiSeq.ReplaceAllString(buf.String(), "")
+	if !strings.Contains(out, "@@ -1,6 +1,6 @@") || !strings.Contains(out, "@@ -15,6 +15,6 @@") {
+		t.Errorf("hunks:\n%s", out)
+	}
+}
+
+func TestDiffFields(t *testing.T) {
+	var buf bytes.Buffer
+	NewDiff().WithWriter(&buf).Fields("  ", []Change{{Field: "image", From: "web:1", To: "web:2"}, {Field: "ports", To: "80:80"}})
+	want := "  image: web:1 → web:2\n  ports: (none) → 80:80\n"
+	if got := ansiSeq.ReplaceAllString(buf.String(), ""); got != want {
+		t.Errorf("fields = %q", got)
+	}
+}
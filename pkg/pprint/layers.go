@@ -0,0 +1,117 @@
+// Package pprint: per-layer progress of an image pull, drawn like docker pull.
+package pprint
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// layerRedraw is the least time between redraws of layer progress.
+const layerRedraw = 100 * time.Millisecond
+
+// LayerProgress shows one line per image layer with its status and, while
+// it downloads or extracts, a progress bar. Off a terminal it prints each
+// status change as a plain line instead.
+type LayerProgress struct {
+	title string
+
+	mu     sync.Mutex
+	layers []*layerLine // in order of first appearance
+	byID   map[string]*layerLine
+	drawn  int // lines drawn by the last redraw
+	last   time.Time
+}
+
+type layerLine struct {
+	id, status     string
+	current, total int64
+}
+
+// NewLayerProgress prints title and returns a LayerProgress under it.
+func NewLayerProgress(title string) *LayerProgress {
+	fmt.Println(StyleText.Render(title))
+	return &LayerProgress{title: title, byID: map[string]*layerLine{}}
+}
+
+// Update records the status of layer id. Messages without a layer, such as
+// the digest, are ignored.
+func (p *LayerProgress) Update(id, status string, current, total int64) {
+	if id == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	l, ok := p.byID[id]
+	if !ok {
+		l = &layerLine{id: id}
+		p.byID[id] = l
+		p.layers = append(p.layers, l)
+	}
+	changed := l.status != status
+	l.status, l.current, l.total = status, current, total
+
+	if !interactive {
+		if changed {
+			fmt.Println(StyleMuted.Render("  " + id + ": " + status))
+		}
+		return
+	}
+	if changed || time.Since(p.last) >= layerRedraw {
+		p.redraw()
+	}
+}
+
+// Done draws the final state of every layer and a ✓ or ✗ title line.
+func (p *LayerProgress) Done(ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if interactive {
+		p.redraw()
+	}
+	if ok {
+		fmt.Printf("%s %s\n", StyleSuccess.Render("✓"), StyleText.Render(p.title))
+	} else {
+		fmt.Printf("%s %s\n", StyleError.Render("✗"), StyleText.Render(p.title))
+	}
+}
+
+// redraw moves the cursor back over the previous drawing and draws every
+// layer again.
+func (p *LayerProgress) redraw() {
+	if p.drawn > 0 {
+		fmt.Printf("\x1b[%dA", p.drawn)
+	}
+	for _, l := range p.layers {
+		fmt.Printf("\r\x1b[2K%s\n", l.render())
+	}
+	p.drawn, p.last = len(p.layers), time.Now()
+}
+
+func (l *layerLine) render() string {
+	line := StyleMuted.Render("  "+l.id+": ") + StyleText.Render(fmt.Sprintf("%-16s", l.status))
+	if l.total <= 0 || l.current <= 0 {
+		return line
+	}
+	const width = 30
+	filled := int(min(float64(l.current)/float64(l.total), 1) * width)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+	return line + " " + StyleAccent.Render(bar) + " " +
+		StyleMuted.Render(FormatBytes(l.current)+"/"+FormatBytes(l.total))
+}
+
+// FormatBytes renders n with a decimal unit, as docker does: 12.3MB.
+func FormatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 4; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "kMGTP"[exp])
+}
@@ -0,0 +1,17 @@
+package pprint
+
+import "testing"
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:             "0B",
+		999:           "999B",
+		1000:          "1.0kB",
+		12_345_678:    "12.3MB",
+		2_500_000_000: "2.5GB",
+	} {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -5,7 +5,6 @@ package pprint
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"strings"
 	"sync"
@@ -120,71 +119,6 @@ func Panel(title, body string) {
 	fmt.Println(StylePanel.Render(content))
 }
 
-// ─────────────────────────────────────────────────────────────────────────────
-// Table
-// ─────────────────────────────────────────────────────────────────────────────
-
-// Table renders a simple terminal table with coloured headers.
-type Table struct {
-	headers []string
-	rows    [][]string
-	out     io.Writer
-}
-
-// NewTable creates a new Table writing to stdout.
-func NewTable(headers ...string) *Table {
-	return &Table{headers: headers, out: os.Stdout}
-}
-
-// AddRow appends a data row to the table.
-func (t *Table) AddRow(cells ...string) {
-	t.rows = append(t.rows, cells)
-}
-
-// Render prints the table.
-func (t *Table) Render() {
-	// Calculate column widths
-	widths := make([]int, len(t.headers))
-	for i, h := range t.headers {
-		widths[i] = len(h)
-	}
-	for _, row := range t.rows {
-		for i, cell := range row {
-			if i < len(widths) && len(cell) > widths[i] {
-				widths[i] = len(cell)
-			}
-		}
-	}
-
-	// Header
-	fmt.Fprintln(t.out)
-	header := ""
-	for i, h := range t.headers {
-		header += fmt.Sprintf("%-*s", widths[i]+2, h)
-	}
-	fmt.Fprintln(t.out, StylePrimary.Render(header))
-
-	sep := ""
-	for _, w := range widths {
-		sep += strings.Repeat("─", w+2)
-	}
-	fmt.Fprintln(t.out, StyleMuted.Render(sep))
-
-	// Rows
-	for _, row := range t.rows {
-		line := ""
-		for i, cell := range row {
-			w := 0
-			if i < len(widths) {
-				w = widths[i]
-			}
-			line += fmt.Sprintf("%-*s", w+2, cell)
-		}
-		fmt.Fprintln(t.out, StyleText.Render(line))
-	}
-	fmt.Fprintln(t.out)
-}
-
 // ─────────────────────────────────────────────────────────────────────────────
 // Spinner
 // ─────────────────────────────────────────────────────────────────────────────
@@ -204,12 +138,18 @@ func NewSpinner(label string) *Spinner {
 	return &Spinner{label: label, done: make(chan struct{})}
 }
 
-// Start begins the spinner animation in a goroutine.
+// Start begins the spinner animation in a goroutine. Off a terminal it
+// prints the label once instead.
 func (s *Spinner) Start() {
 	s.mu.Lock()
 	s.active = true
 	s.mu.Unlock()
 
+	if !interactive {
+		fmt.Println(StyleText.Render(s.label + " …"))
+		return
+	}
+
 	go func() {
 		i := 0
 		for {
@@ -237,10 +177,14 @@ func (s *Spinner) Stop(success bool) {
 	close(s.done)
 	s.active = false
 
+	cr := "\r"
+	if !interactive {
+		cr = ""
+	}
 	if success {
-		fmt.Printf("\r%s %s\n", StyleSuccess.Render("✓"), StyleText.Render(s.label))
+		fmt.Printf("%s%s %s\n", cr, StyleSuccess.Render("✓"), StyleText.Render(s.label))
 	} else {
-		fmt.Printf("\r%s %s\n", StyleError.Render("✗"), StyleText.Render(s.label))
+		fmt.Printf("%s%s %s\n", cr, StyleError.Render("✗"), StyleText.Render(s.label))
 	}
 }
 
@@ -248,11 +192,13 @@ func (s *Spinner) Stop(success bool) {
 // Progress bar
 // ─────────────────────────────────────────────────────────────────────────────
 
-// Progress renders a simple inline progress bar.
+// Progress renders a simple inline progress bar. Off a terminal it prints
+// a line at every quarter instead.
 type Progress struct {
-	label string
-	total int
-	width int
+	label   string
+	total   int
+	width   int
+	printed int // quarters printed off a terminal
 }
 
 // NewProgress creates a Progress bar.
@@ -266,6 +212,13 @@ func (p *Progress) Set(current int) {
 		return
 	}
 	pct := float64(current) / float64(p.total)
+	if !interactive {
+		if q := int(min(pct, 1) * 4); q > p.printed {
+			p.printed = q
+			fmt.Printf("%s %3.0f%%\n", StyleText.Render(p.label), pct*100)
+		}
+		return
+	}
 	filled := int(pct * float64(p.width))
 	bar := strings.Repeat("█", filled) + strings.Repeat("░", p.width-filled)
 	fmt.Printf("\r%s [%s] %3.0f%%",
@@ -0,0 +1,329 @@
+// Package prompt asks the user questions on the terminal: yes/no
+// confirmations, a choice from a list, several choices from a list, free
+// text, and passwords read without echo.
+//
+// Questions are written to stderr so they never mix with structured output
+// on stdout. When stdin is not a terminal answers are still read from it, one
+// per line, so `echo y | orbit ...` works. With SetAssumeYes (the global --yes
+// flag) nothing is asked: confirmations are answered yes and every other
+// prompt takes its default, failing with ErrNonInteractive when it has none.
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/moby/term"
+
+	"github.com/f9-o/orbit/pkg/pprint"
+)
+
+// ErrNonInteractive is returned when an answer is needed but none can be
+// read: --yes was given for a prompt with no default, or stdin ran out.
+var ErrNonInteractive = errors.New("an answer is required but orbit is not running interactively; pass the value as a flag, or --yes to accept defaults")
+
+// Prompter reads answers from in and writes questions to out.
+type Prompter struct {
+	in        *bufio.Reader
+	out       io.Writer
+	fd        uintptr // descriptor of in, when it is a terminal
+	terminal  bool
+	assumeYes bool
+}
+
+// New creates a Prompter reading from in and writing to out. Password input
+// is masked only when in is a terminal.
+func New(in io.Reader, out io.Writer) *Prompter {
+	fd, isTerm := term.GetFdInfo(in)
+	return &Prompter{in: bufio.NewReader(in), out: out, fd: fd, terminal: isTerm}
+}
+
+// WithAssumeYes makes every prompt answer itself without reading input.
+func (p *Prompter) WithAssumeYes(yes bool) *Prompter {
+	p.assumeYes = yes
+	return p
+}
+
+// std is the Prompter behind the package-level functions.
+var std = New(os.Stdin, os.Stderr)
+
+// SetAssumeYes makes the package-level prompts answer themselves, for --yes.
+func SetAssumeYes(yes bool) {
+	std.assumeYes = yes
+}
+
+// AssumeYes reports whether the package-level prompts answer themselves.
+func AssumeYes() bool {
+	return std.assumeYes
+}
+
+// Confirm asks a yes/no question on the terminal. See Prompter.Confirm.
+func Confirm(question string, def bool) (bool, error) {
+	return std.Confirm(question, def)
+}
+
+// Select asks for one of options on the terminal. See Prompter.Select.
+func Select(question string, options []string, def int) (int, error) {
+	return std.Select(question, options, def)
+}
+
+// MultiSelect asks for any of options on the terminal. See Prompter.MultiSelect.
+func MultiSelect(question string, options []string, defs []int) ([]int, error) {
+	return std.MultiSelect(question, options, defs)
+}
+
+// Input asks for a line of text on the terminal. See Prompter.Input.
+func Input(question, def string, validate func(string) error) (string, error) {
+	return std.Input(question, def, validate)
+}
+
+// Password asks for a secret on the terminal. See Prompter.Password.
+func Password(question string) (string, error) {
+	return std.Password(question)
+}
+
+// Confirm asks a yes/no question. An empty answer takes def.
+func (p *Prompter) Confirm(question string, def bool) (bool, error) {
+	if p.assumeYes {
+		return true, nil
+	}
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	for {
+		answer, err := p.ask(question + " " + hint)
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		p.retry("Please answer y or n")
+	}
+}
+
+// Select lists options, numbered from 1, and returns the index of the one
+// chosen. def is the index taken on an empty answer; -1 for none.
+func (p *Prompter) Select(question string, options []string, def int) (int, error) {
+	if len(options) == 0 {
+		return 0, errors.New("prompt: nothing to select from")
+	}
+	if def >= len(options) {
+		def = -1
+	}
+	if p.assumeYes {
+		if def < 0 {
+			return 0, ErrNonInteractive
+		}
+		return def, nil
+	}
+	p.list(question, options, func(i int) bool { return i == def })
+	hint := fmt.Sprintf("Choose 1-%d", len(options))
+	if def >= 0 {
+		hint += fmt.Sprintf(" [%d]", def+1)
+	}
+	for {
+		answer, err := p.ask(hint)
+		if err != nil {
+			return 0, err
+		}
+		if answer == "" && def >= 0 {
+			return def, nil
+		}
+		if i, ok := pick(answer, options); ok {
+			return i, nil
+		}
+		p.retry("Enter a number from 1 to %d", len(options))
+	}
+}
+
+// MultiSelect lists options, numbered from 1, and returns the indexes of
+// those chosen, in order. Answers are numbers and ranges separated by commas
+// or spaces ("1,3", "2-4"), "all" or "none"; an empty answer takes defs.
+func (p *Prompter) MultiSelect(question string, options []string, defs []int) ([]int, error) {
+	if len(options) == 0 {
+		return nil, errors.New("prompt: nothing to select from")
+	}
+	if p.assumeYes {
+		return defs, nil
+	}
+	chosen := map[int]bool{}
+	for _, i := range defs {
+		chosen[i] = true
+	}
+	p.list(question, options, func(i int) bool { return chosen[i] })
+	hint := fmt.Sprintf("Choose any of 1-%d (e.g. 1,3 or 2-4, all, none)", len(options))
+	for {
+		answer, err := p.ask(hint)
+		if err != nil {
+			return nil, err
+		}
+		if answer == "" {
+			return defs, nil
+		}
+		sel, err := parseSelection(answer, len(options))
+		if err == nil {
+			return sel, nil
+		}
+		p.retry("%v", err)
+	}
+}
+
+// Input asks for a line of text. An empty answer takes def. validate, when
+// not nil, is run on the answer and the question asked again while it fails.
+func (p *Prompter) Input(question, def string, validate func(string) error) (string, error) {
+	if p.assumeYes {
+		if def == "" {
+			return "", ErrNonInteractive
+		}
+		return def, nil
+	}
+	if def != "" {
+		question += " [" + def + "]"
+	}
+	for {
+		answer, err := p.ask(question)
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = def
+		}
+		if validate == nil {
+			return answer, nil
+		}
+		if err := validate(answer); err != nil {
+			p.retry("%v", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// Password asks for a secret, taken as typed. On a terminal the answer is
+// not echoed. It has no default, so it fails with ErrNonInteractive under --yes.
+func (p *Prompter) Password(question string) (string, error) {
+	if p.assumeYes {
+		return "", ErrNonInteractive
+	}
+	p.question(question)
+	if !p.terminal {
+		return p.readLine()
+	}
+	state, err := term.SaveState(p.fd)
+	if err != nil {
+		return "", err
+	}
+	if err := term.DisableEcho(p.fd, state); err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = term.RestoreTerminal(p.fd, state)
+		fmt.Fprintln(p.out) // the user's Enter was not echoed
+	}()
+	return p.readLine()
+}
+
+// ask prints question and reads the trimmed answer.
+func (p *Prompter) ask(question string) (string, error) {
+	p.question(question)
+	line, err := p.readLine()
+	return strings.TrimSpace(line), err
+}
+
+// question prints question, leaving the cursor after it for the answer.
+func (p *Prompter) question(question string) {
+	fmt.Fprint(p.out, pprint.StylePrimary.Render("?")+" "+question+": ")
+}
+
+// readLine reads one line without its line ending. Input that ends without
+// an answer is ErrNonInteractive.
+func (p *Prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err == io.EOF && line == "" {
+		fmt.Fprintln(p.out)
+		return "", ErrNonInteractive
+	}
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// list prints question and the numbered options, marking the selected ones.
+func (p *Prompter) list(question string, options []string, selected func(int) bool) {
+	fmt.Fprintln(p.out, pprint.StylePrimary.Render("?")+" "+question)
+	for i, o := range options {
+		mark := " "
+		if selected(i) {
+			mark = pprint.StyleAccent.Render("•")
+		}
+		fmt.Fprintf(p.out, "  %s %2d) %s\n", mark, i+1, o)
+	}
+}
+
+// retry explains why an answer was not accepted.
+func (p *Prompter) retry(format string, args ...any) {
+	fmt.Fprintln(p.out, "  "+pprint.StyleWarning.Render(fmt.Sprintf(format, args...)))
+}
+
+// pick resolves a Select answer: a number from 1, or an option's exact text.
+func pick(answer string, options []string) (int, bool) {
+	if n, err := strconv.Atoi(answer); err == nil {
+		return n - 1, n >= 1 && n <= len(options)
+	}
+	for i, o := range options {
+		if strings.EqualFold(o, answer) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// parseSelection parses a MultiSelect answer for n options into sorted,
+// distinct, zero-based indexes.
+func parseSelection(answer string, n int) ([]int, error) {
+	switch strings.ToLower(answer) {
+	case "all", "*":
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	case "none", "-":
+		return []int{}, nil
+	}
+	seen := map[int]bool{}
+	for _, f := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+		lo, hi, isRange := strings.Cut(f, "-")
+		a, err1 := strconv.Atoi(lo)
+		b, err2 := a, error(nil)
+		if isRange {
+			b, err2 = strconv.Atoi(hi)
+		}
+		if err1 != nil || err2 != nil || a < 1 || b > n || a > b {
+			return nil, fmt.Errorf("%q is not a number or range within 1-%d", f, n)
+		}
+		for i := a; i <= b; i++ {
+			seen[i-1] = true
+		}
+	}
+	out := make([]int, 0, len(seen))
+	for i := range seen {
+		out = append(out, i)
+	}
+	sort.Ints(out)
+	return out, nil
+}
@@ -0,0 +1,135 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTest(input string) (*Prompter, *strings.Builder) {
+	var out strings.Builder
+	return New(strings.NewReader(input), &out), &out
+}
+
+func TestConfirm(t *testing.T) {
+	cases := []struct {
+		input string
+		def   bool
+		want  bool
+	}{
+		{"y\n", false, true},
+		{"YES\n", false, true},
+		{"n\n", true, false},
+		{"\n", true, true},
+		{"\n", false, false},
+		{"maybe\ny\n", false, true}, // asked again
+		{"y", false, true},          // no trailing newline
+	}
+	for _, c := range cases {
+		p, out := newTest(c.input)
+		got, err := p.Confirm("Proceed?", c.def)
+		if err != nil || got != c.want {
+			t.Errorf("Confirm(%q, %v) = %v, %v; want %v", c.input, c.def, got, err, c.want)
+		}
+		if !strings.Contains(out.String(), "Proceed?") {
+			t.Errorf("question not written: %q", out.String())
+		}
+	}
+}
+
+func TestConfirmWithoutInput(t *testing.T) {
+	p, _ := newTest("")
+	if _, err := p.Confirm("Proceed?", false); !errors.Is(err, ErrNonInteractive) {
+		t.Errorf("err = %v, want ErrNonInteractive", err)
+	}
+	p, _ = newTest("")
+	if ok, err := p.WithAssumeYes(true).Confirm("Proceed?", false); err != nil || !ok {
+		t.Errorf("with --yes = %v, %v", ok, err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	opts := []string{"nginx", "caddy", "traefik"}
+	for input, want := range map[string]int{"2\n": 1, "\n": 0, "Traefik\n": 2, "9\n3\n": 2} {
+		p, _ := newTest(input)
+		if got, err := p.Select("Backend", opts, 0); err != nil || got != want {
+			t.Errorf("Select(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+
+	p, out := newTest("\n\n1\n")
+	if got, err := p.Select("Backend", opts, -1); err != nil || got != 0 {
+		t.Errorf("Select without default = %d, %v", got, err)
+	}
+	if n := strings.Count(out.String(), "Enter a number"); n != 2 {
+		t.Errorf("empty answers without a default re-asked %d times:\n%s", n, out)
+	}
+
+	p, _ = newTest("")
+	if got, err := p.WithAssumeYes(true).Select("Backend", opts, 1); err != nil || got != 1 {
+		t.Errorf("with --yes = %d, %v", got, err)
+	}
+	if _, err := p.Select("Backend", opts, -1); !errors.Is(err, ErrNonInteractive) {
+		t.Errorf("with --yes and no default: err = %v", err)
+	}
+}
+
+func TestMultiSelect(t *testing.T) {
+	opts := []string{"a", "b", "c", "d", "e"}
+	cases := map[string][]int{
+		"1,3\n":     {0, 2},
+		"4 2-3\n":   {1, 2, 3},
+		"2-4,3\n":   {1, 2, 3},
+		"all\n":     {0, 1, 2, 3, 4},
+		"none\n":    {},
+		"\n":        {4},
+		"0\n7\n1\n": {0},
+		"3-1\n5\n":  {4},
+	}
+	for input, want := range cases {
+		p, _ := newTest(input)
+		got, err := p.MultiSelect("Services", opts, []int{4})
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("MultiSelect(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+}
+
+func TestInput(t *testing.T) {
+	p, _ := newTest("\n")
+	if got, _ := p.Input("Name", "web", nil); got != "web" {
+		t.Errorf("default = %q", got)
+	}
+
+	notEmpty := func(s string) error {
+		if s == "" {
+			return fmt.Errorf("a name is required")
+		}
+		return nil
+	}
+	p, out := newTest("\n  api  \n")
+	if got, err := p.Input("Name", "", notEmpty); err != nil || got != "api" {
+		t.Errorf("Input = %q, %v", got, err)
+	}
+	if !strings.Contains(out.String(), "a name is required") {
+		t.Errorf("validation error not shown:\n%s", out)
+	}
+
+	p, _ = newTest("")
+	if _, err := p.WithAssumeYes(true).Input("Name", "", nil); !errors.Is(err, ErrNonInteractive) {
+		t.Errorf("with --yes and no default: err = %v", err)
+	}
+}
+
+func TestPasswordFromPipe(t *testing.T) {
+	p, _ := newTest(" s3cret\r\n")
+	if got, err := p.Password("Passphrase"); err != nil || got != " s3cret" {
+		t.Errorf("Password = %q, %v", got, err)
+	}
+	p, _ = newTest("s3cret\n")
+	if _, err := p.WithAssumeYes(true).Password("Passphrase"); !errors.Is(err, ErrNonInteractive) {
+		t.Errorf("with --yes: err = %v", err)
+	}
+}
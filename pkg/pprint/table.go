@@ -0,0 +1,317 @@
+// Package pprint: tables measured by display width, fitted to the terminal.
+package pprint
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/moby/term"
+)
+
+// Align is the horizontal alignment of a table column.
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+	AlignCenter
+)
+
+// minColumnWidth is how narrow a column may be squeezed to fit the table in
+// its max width. Columns narrower than this by nature keep their width.
+const minColumnWidth = 4
+
+// ansiSeq matches ANSI escape sequences, which take no space on screen.
+var ansiSeq = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)`)
+
+// Table renders a terminal table with coloured headers. Cells are measured
+// by display width, so wide runes and ANSI styling do not break alignment,
+// and the table is fitted to a max width by truncating (or wrapping) cells.
+type Table struct {
+	headers  []string
+	rows     [][]string
+	out      io.Writer
+	maxWidth int // 0 = unlimited
+	align    []Align
+	wrap     bool
+	border   bool
+}
+
+// NewTable creates a new Table writing to stdout, as wide as the terminal.
+func NewTable(headers ...string) *Table {
+	return &Table{headers: headers, out: os.Stdout, maxWidth: TerminalWidth(), align: make([]Align, len(headers))}
+}
+
+// WithWriter sets where the table is rendered.
+func (t *Table) WithWriter(w io.Writer) *Table {
+	t.out = w
+	return t
+}
+
+// WithMaxWidth limits the table to n columns of screen; 0 lifts the limit.
+func (t *Table) WithMaxWidth(n int) *Table {
+	t.maxWidth = n
+	return t
+}
+
+// WithAlign sets the alignment of column col.
+func (t *Table) WithAlign(col int, a Align) *Table {
+	if col >= 0 && col < len(t.align) {
+		t.align[col] = a
+	}
+	return t
+}
+
+// WithWrap wraps cells too long for their column onto more lines instead of
+// truncating them with an ellipsis.
+func (t *Table) WithWrap(wrap bool) *Table {
+	t.wrap = wrap
+	return t
+}
+
+// WithBorder draws box borders around the table and between columns.
+func (t *Table) WithBorder(border bool) *Table {
+	t.border = border
+	return t
+}
+
+// AddRow appends a data row to the table. Missing cells are blank; extra
+// cells are dropped.
+func (t *Table) AddRow(cells ...string) {
+	row := make([]string, len(t.headers))
+	copy(row, cells)
+	t.rows = append(t.rows, row)
+}
+
+// Render prints the table.
+func (t *Table) Render() {
+	widths := t.columnWidths()
+
+	fmt.Fprintln(t.out)
+	if t.border {
+		fmt.Fprintln(t.out, StyleMuted.Render(t.rule(widths, "┌", "┬", "┐")))
+	}
+	for _, line := range t.layout(t.headers, widths) {
+		fmt.Fprintln(t.out, StylePrimary.Render(line))
+	}
+	if t.border {
+		fmt.Fprintln(t.out, StyleMuted.Render(t.rule(widths, "├", "┼", "┤")))
+	} else {
+		fmt.Fprintln(t.out, StyleMuted.Render(t.rule(widths, "", "──", "")))
+	}
+	for _, row := range t.rows {
+		for _, line := range t.layout(row, widths) {
+			fmt.Fprintln(t.out, StyleText.Render(line))
+		}
+	}
+	if t.border {
+		fmt.Fprintln(t.out, StyleMuted.Render(t.rule(widths, "└", "┴", "┘")))
+	}
+	fmt.Fprintln(t.out)
+}
+
+// columnWidths returns the width of each column: its widest cell, with the
+// widest columns narrowed in turn until the table fits maxWidth.
+func (t *Table) columnWidths() []int {
+	widths := make([]int, len(t.headers))
+	for i, h := range t.headers {
+		widths[i] = cellWidth(h)
+	}
+	for _, row := range t.rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], cellWidth(cell))
+		}
+	}
+	if t.maxWidth <= 0 {
+		return widths
+	}
+	for total := t.frameWidth(widths); total > t.maxWidth; total-- {
+		widest := -1
+		for i, w := range widths {
+			if w > minColumnWidth && (widest < 0 || w > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break // as narrow as it goes
+		}
+		widths[widest]--
+	}
+	return widths
+}
+
+// frameWidth is the screen width of the table with the given column widths.
+func (t *Table) frameWidth(widths []int) int {
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	if t.border {
+		return total + 3*len(widths) + 1 // "│ " before each cell, " │" after the last
+	}
+	return total + 2*(len(widths)-1)
+}
+
+// layout turns a row into the screen lines it takes.
+func (t *Table) layout(cells []string, widths []int) []string {
+	cols := make([][]string, len(widths))
+	height := 1
+	for i, w := range widths {
+		for _, l := range strings.Split(cells[i], "\n") {
+			switch {
+			case cellWidth(l) <= w:
+				cols[i] = append(cols[i], l)
+			case t.wrap:
+				cols[i] = append(cols[i], wrapText(l, w)...)
+			default:
+				cols[i] = append(cols[i], truncate(l, w))
+			}
+		}
+		height = max(height, len(cols[i]))
+	}
+
+	lines := make([]string, height)
+	for n := range lines {
+		var b strings.Builder
+		if t.border {
+			b.WriteString("│ ")
+		}
+		for i, w := range widths {
+			cell := ""
+			if n < len(cols[i]) {
+				cell = cols[i][n]
+			}
+			if i > 0 {
+				if t.border {
+					b.WriteString(" │ ")
+				} else {
+					b.WriteString("  ")
+				}
+			}
+			b.WriteString(pad(cell, w, t.align[i]))
+		}
+		if t.border {
+			b.WriteString(" │")
+			lines[n] = b.String()
+		} else {
+			lines[n] = strings.TrimRight(b.String(), " ")
+		}
+	}
+	return lines
+}
+
+// rule draws a horizontal line across the columns.
+func (t *Table) rule(widths []int, left, mid, right string) string {
+	var b strings.Builder
+	b.WriteString(left)
+	for i, w := range widths {
+		if i > 0 {
+			b.WriteString(mid)
+		}
+		if t.border {
+			w += 2
+		}
+		b.WriteString(strings.Repeat("─", w))
+	}
+	b.WriteString(right)
+	return b.String()
+}
+
+// cellWidth is the widest line of s on screen.
+func cellWidth(s string) int {
+	w := 0
+	for _, l := range strings.Split(s, "\n") {
+		w = max(w, runewidth.StringWidth(ansiSeq.ReplaceAllString(l, "")))
+	}
+	return w
+}
+
+// pad fills s with spaces to width w.
+func pad(s string, w int, a Align) string {
+	gap := w - cellWidth(s)
+	if gap <= 0 {
+		return s
+	}
+	switch a {
+	case AlignRight:
+		return strings.Repeat(" ", gap) + s
+	case AlignCenter:
+		return strings.Repeat(" ", gap/2) + s + strings.Repeat(" ", gap-gap/2)
+	}
+	return s + strings.Repeat(" ", gap)
+}
+
+// truncate shortens s to w columns, ending in an ellipsis. ANSI sequences
+// are kept, and styling is reset after the cut.
+func truncate(s string, w int) string {
+	if w <= 0 {
+		return ""
+	}
+	var b strings.Builder
+	width, styled := 0, false
+	for len(s) > 0 {
+		if loc := ansiSeq.FindStringIndex(s); loc != nil && loc[0] == 0 {
+			b.WriteString(s[:loc[1]])
+			s, styled = s[loc[1]:], true
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		rw := runewidth.RuneWidth(r)
+		if width+rw > w-1 {
+			break
+		}
+		b.WriteString(s[:size])
+		s, width = s[size:], width+rw
+	}
+	b.WriteString("…")
+	if styled {
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
+
+// wrapText breaks s into lines of at most w columns, at spaces where it can.
+// Styling is dropped from wrapped cells.
+func wrapText(s string, w int) []string {
+	var lines []string
+	line, lineW := "", 0
+	for _, word := range strings.Fields(ansiSeq.ReplaceAllString(s, "")) {
+		ww := runewidth.StringWidth(word)
+		if lineW > 0 && lineW+1+ww <= w {
+			line, lineW = line+" "+word, lineW+1+ww
+			continue
+		}
+		if lineW > 0 {
+			lines = append(lines, line)
+			line, lineW = "", 0
+		}
+		for ww > w { // a word longer than the column is split
+			cut := runewidth.Truncate(word, w, "")
+			lines = append(lines, cut)
+			word = word[len(cut):]
+			ww = runewidth.StringWidth(word)
+		}
+		line, lineW = word, ww
+	}
+	return append(lines, line)
+}
+
+// TerminalWidth returns the width of the terminal on stdout, or $COLUMNS,
+// or 0 when neither is known.
+func TerminalWidth() int {
+	if interactive {
+		if ws, err := term.GetWinsize(os.Stdout.Fd()); err == nil && ws.Width > 0 {
+			return int(ws.Width)
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 0
+}
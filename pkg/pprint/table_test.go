@@ -0,0 +1,83 @@
+package pprint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// render returns the table's non-blank lines.
+func render(t *Table) []string {
+	var buf bytes.Buffer
+	t.WithWriter(&buf).Render()
+	return strings.Split(strings.TrimSpace(ansiSeq.ReplaceAllString(buf.String(), "")), "\n")
+}
+
+func TestTableAlignsWideRunesAndANSI(t *testing.T) {
+	tbl := NewTable("NAME", "STATUS", "COUNT").WithMaxWidth(0).WithAlign(2, AlignRight)
+	tbl.AddRow("日本語", "\x1b[32mrunning\x1b[0m", "3")
+	tbl.AddRow("api", "stopped", "12")
+	lines := render(tbl)
+
+	// Every column starts at the same screen offset on every line.
+	want := []string{
+		"NAME    STATUS   COUNT",
+		"──────────────────────",
+		"日本語  running      3",
+		"api     stopped     12",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("table:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTableFitsMaxWidth(t *testing.T) {
+	long := strings.Repeat("abcdefghij ", 6)
+	tbl := NewTable("ID", "DESCRIPTION").WithMaxWidth(30)
+	tbl.AddRow("1", long)
+	lines := render(tbl)
+	for _, l := range lines {
+		if w := runewidth.StringWidth(l); w > 30 {
+			t.Errorf("%q is %d wide", l, w)
+		}
+	}
+	if !strings.HasSuffix(lines[2], "…") {
+		t.Errorf("truncated cell = %q", lines[2])
+	}
+
+	wrapped := render(NewTable("ID", "DESCRIPTION").WithMaxWidth(30).WithWrap(true).withRow("1", long))
+	if len(wrapped) != 5 || strings.TrimSpace(wrapped[2]) != "1   abcdefghij abcdefghij" {
+		t.Errorf("wrapped:\n%s", strings.Join(wrapped, "\n"))
+	}
+}
+
+func TestTableBorder(t *testing.T) {
+	lines := render(NewTable("A", "B").WithMaxWidth(0).WithBorder(true).withRow("x", "yy"))
+	want := []string{
+		"┌───┬────┐",
+		"│ A │ B  │",
+		"├───┼────┤",
+		"│ x │ yy │",
+		"└───┴────┘",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("table:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestTruncateKeepsStyling(t *testing.T) {
+	got := truncate("\x1b[31mabcdef\x1b[0m", 4)
+	if got != "\x1b[31mabc…\x1b[0m" {
+		t.Errorf("truncate = %q", got)
+	}
+	if w := cellWidth(truncate("日本語テキスト", 5)); w > 5 {
+		t.Errorf("truncated wide text is %d wide", w)
+	}
+}
+
+func (t *Table) withRow(cells ...string) *Table {
+	t.AddRow(cells...)
+	return t
+}
@@ -0,0 +1,64 @@
+// Package pprint: colour and terminal detection.
+package pprint
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+var (
+	colorOn     = ColorSupported()
+	interactive = isTerminal(os.Stdout) && os.Getenv("TERM") != "dumb"
+)
+
+// ColorSupported reports whether output should be coloured by default:
+// stdout is a terminal and NO_COLOR (https://no-color.org) is unset.
+func ColorSupported() bool {
+	return os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+}
+
+// SetColor turns ANSI colour and emphasis on or off for everything pprint
+// and lipgloss render.
+func SetColor(on bool) {
+	colorOn = on
+	if on {
+		lipgloss.SetColorProfile(termenv.NewOutput(os.Stdout).EnvColorProfile())
+		return
+	}
+	lipgloss.SetColorProfile(termenv.Ascii)
+}
+
+// Color reports whether output is coloured.
+func Color() bool {
+	return colorOn
+}
+
+// Interactive reports whether stdout is a terminal that can redraw a line.
+// When it is not, spinners and progress bars print plain lines instead.
+func Interactive() bool {
+	return interactive
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ansiRe matches ANSI escape sequences: CSI sequences such as colours and
+// cursor moves, OSC sequences such as hyperlinks and titles, and two-byte
+// escapes.
+var ansiRe = regexp.MustCompile(`\x1b(\[[0-9:;<=>?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)|[0-~])`)
+
+// StripANSI removes ANSI escape sequences from s, such as the colours of
+// a container's log lines.
+func StripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return ansiRe.ReplaceAllString(s, "")
+}
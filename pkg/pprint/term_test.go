@@ -0,0 +1,19 @@
+package pprint
+
+import "testing"
+
+func TestStripANSI(t *testing.T) {
+	for in, want := range map[string]string{
+		"plain":                                    "plain",
+		"\x1b[31mERROR\x1b[0m db down":             "ERROR db down",
+		"\x1b[1;38;5;208mwarn\x1b[m":               "warn",
+		"\x1b[2K\x1b[1Gprogress 50%":               "progress 50%",
+		"\x1b]8;;https://x.io\x07link\x1b]8;;\x07": "link",
+		"\x1b]0;title\x1b\\text":                   "text",
+		"\x1b7saved\x1b8":                          "saved",
+	} {
+		if got := StripANSI(in); got != want {
+			t.Errorf("StripANSI(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
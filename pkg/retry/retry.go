@@ -0,0 +1,126 @@
+// Package retry runs an operation again after transient failures, waiting
+// exponentially longer between attempts, with jitter so that many clients
+// retrying at once do not stay in step.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// Policy defaults, used for zero Policy fields.
+const (
+	DefaultAttempts   = 4
+	DefaultInitial    = 500 * time.Millisecond
+	DefaultMax        = 10 * time.Second
+	DefaultMultiplier = 2
+	DefaultJitter     = 0.2
+)
+
+// Policy controls how often and how long apart an operation is retried.
+type Policy struct {
+	Attempts   int           // tries in all, including the first; 0 = DefaultAttempts
+	Initial    time.Duration // wait after the first failure; 0 = DefaultInitial
+	Max        time.Duration // longest wait between tries; 0 = DefaultMax
+	Multiplier float64       // growth of the wait per try; 0 = DefaultMultiplier
+	Jitter     float64       // each wait is varied by up to ± this fraction; 0 = DefaultJitter, <0 = none
+
+	// Retryable decides whether an error is worth another try; nil uses
+	// errs.Retryable.
+	Retryable func(error) bool
+	// OnRetry, when set, is called before each wait, e.g. to log it.
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// Default is the policy for most network calls: four tries over about
+// three and a half seconds.
+var Default = Policy{}
+
+func (p Policy) withDefaults() Policy {
+	if p.Attempts <= 0 {
+		p.Attempts = DefaultAttempts
+	}
+	if p.Initial <= 0 {
+		p.Initial = DefaultInitial
+	}
+	if p.Max <= 0 {
+		p.Max = DefaultMax
+	}
+	if p.Multiplier <= 0 {
+		p.Multiplier = DefaultMultiplier
+	}
+	if p.Jitter == 0 {
+		p.Jitter = DefaultJitter
+	}
+	if p.Retryable == nil {
+		p.Retryable = errs.Retryable
+	}
+	return p
+}
+
+// Backoff returns the wait after the given failed attempt (from 1), before
+// jitter.
+func (p Policy) Backoff(attempt int) time.Duration {
+	p = p.withDefaults()
+	d := float64(p.Initial)
+	for i := 1; i < attempt && d < float64(p.Max); i++ {
+		d *= p.Multiplier
+	}
+	return min(time.Duration(d), p.Max)
+}
+
+// wait is Backoff with jitter applied.
+func (p Policy) wait(attempt int) time.Duration {
+	d := p.Backoff(attempt)
+	if p.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+	return d
+}
+
+// Do runs op until it succeeds, fails with an error p does not consider
+// retryable, runs out of attempts, or ctx is done. The error returned is
+// op's last one, noting the number of attempts when there were several.
+func Do(ctx context.Context, p Policy, op func(context.Context) error) error {
+	_, err := Value(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, op(ctx)
+	})
+	return err
+}
+
+// Value is Do for operations that return a result.
+func Value[T any](ctx context.Context, p Policy, op func(context.Context) (T, error)) (T, error) {
+	p = p.withDefaults()
+	for attempt := 1; ; attempt++ {
+		v, err := op(ctx)
+		if err == nil {
+			return v, nil
+		}
+		if attempt >= p.Attempts || !p.Retryable(err) || ctx.Err() != nil {
+			return v, attempted(err, attempt)
+		}
+		wait := p.wait(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, wait)
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return v, attempted(err, attempt)
+		case <-t.C:
+		}
+	}
+}
+
+// attempted notes how many tries it took to give up with err.
+func attempted(err error, attempts int) error {
+	if attempts == 1 {
+		return err
+	}
+	return fmt.Errorf("%w (after %d attempts)", err, attempts)
+}
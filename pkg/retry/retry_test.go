@@ -0,0 +1,74 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/f9-o/orbit/pkg/errs"
+)
+
+// fast retries without noticeable waits.
+var fast = Policy{Initial: time.Millisecond, Max: 2 * time.Millisecond, Jitter: -1}
+
+func TestDoRetriesTransientErrors(t *testing.T) {
+	calls := 0
+	var waits []time.Duration
+	p := fast
+	p.OnRetry = func(_ int, _ error, wait time.Duration) { waits = append(waits, wait) }
+	err := Do(context.Background(), p, func(context.Context) error {
+		if calls++; calls < 3 {
+			return fmt.Errorf("dial: %w", syscall.ECONNREFUSED)
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("err = %v after %d calls", err, calls)
+	}
+	if len(waits) != 2 || waits[0] != time.Millisecond || waits[1] != 2*time.Millisecond {
+		t.Errorf("waits = %v", waits)
+	}
+}
+
+func TestDoStops(t *testing.T) {
+	calls := 0
+	terminal := errs.Newf(errs.ErrConfig, "load", "bad yaml")
+	err := Do(context.Background(), fast, func(context.Context) error { calls++; return terminal })
+	if calls != 1 || err != terminal {
+		t.Errorf("terminal error: %d calls, err = %v", calls, err)
+	}
+
+	calls = 0
+	err = Do(context.Background(), fast, func(context.Context) error { calls++; return syscall.ECONNRESET })
+	if calls != DefaultAttempts || !errors.Is(err, syscall.ECONNRESET) || !strings.Contains(err.Error(), "after 4 attempts") {
+		t.Errorf("exhausted: %d calls, err = %v", calls, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	p := Policy{Initial: time.Hour}
+	p.OnRetry = func(int, error, time.Duration) { cancel() }
+	err = Do(ctx, p, func(context.Context) error { calls++; return syscall.ECONNRESET })
+	if calls != 1 || !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("cancelled: %d calls, err = %v", calls, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{Initial: time.Second, Max: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if d := p.withDefaults().wait(1); d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("jittered wait %v outside ±20%%", d)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Package sshutil: managed known_hosts file for host key verification.
+package sshutil
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ErrUnknownHost is returned by a KnownHosts callback for hosts that have no
+// entry in the file. Key mismatches are reported as *knownhosts.KeyError.
+var ErrUnknownHost = errors.New("host key not in known_hosts")
+
+// KnownHosts returns a host key callback backed by file. A missing file
+// behaves as an empty one.
+func KnownHosts(file string) (ssh.HostKeyCallback, error) {
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		return func(hostname string, _ net.Addr, _ ssh.PublicKey) error {
+			return fmt.Errorf("%s: %w", hostname, ErrUnknownHost)
+		}, nil
+	}
+	cb, err := knownhosts.New(file)
+	if err != nil {
+		return nil, fmt.Errorf("load known_hosts %q: %w", file, err)
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := cb(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			return fmt.Errorf("%s: %w", hostname, ErrUnknownHost)
+		}
+		return err
+	}, nil
+}
+
+// AddKnownHost records key for addr (host:port) in file, replacing any
+// existing entries for that address. The file and its directory are created
+// with owner-only permissions.
+func AddKnownHost(file, addr string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	host := knownhosts.Normalize(addr)
+
+	var kept bytes.Buffer
+	if data, err := os.ReadFile(file); err == nil {
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			line := sc.Text()
+			if fields := strings.Fields(line); len(fields) > 0 && !strings.HasPrefix(fields[0], "#") &&
+				containsHost(fields[0], host) {
+				continue
+			}
+			kept.WriteString(line + "\n")
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	kept.WriteString(knownhosts.Line([]string{host}, key) + "\n")
+
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, kept.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// containsHost reports whether a comma-separated known_hosts host field lists
+// host verbatim. Hashed and wildcard entries are left alone.
+func containsHost(field, host string) bool {
+	for _, h := range strings.Split(field, ",") {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/md5"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"strings"
@@ -65,6 +66,21 @@ func Dial(addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
 	return client, nil
 }
 
+// DialVia establishes an SSH connection to addr tunnelled through an existing
+// connection, e.g. a bastion host.
+func DialVia(via *ssh.Client, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh tunnel to %q: %w", addr, err)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh dial %q via %q: %w", addr, via.RemoteAddr(), err)
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
 // RunCommand executes a shell command on the remote host and returns its combined output.
 func RunCommand(client *ssh.Client, cmd string) (string, int, error) {
 	session, err := client.NewSession()
@@ -83,6 +99,59 @@ func RunCommand(client *ssh.Client, cmd string) (string, int, error) {
 	return string(out), 0, nil
 }
 
+// StreamCommand executes cmd on the remote host with stdin and stdout attached
+// to the given streams (either may be nil). On a non-zero exit the returned
+// error includes the command's stderr.
+func StreamCommand(client *ssh.Client, cmd string, stdin io.Reader, stdout io.Writer) (int, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return -1, fmt.Errorf("new session: %w", err)
+	}
+	defer session.Close()
+
+	var stderr strings.Builder
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = &stderr
+
+	if err := session.Run(cmd); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			if msg == "" {
+				return exitErr.ExitStatus(), err
+			}
+			return exitErr.ExitStatus(), fmt.Errorf("%w: %s", err, msg)
+		}
+		return -1, err
+	}
+	return 0, nil
+}
+
+// RunStreams executes cmd on the remote host, writing its stdout and stderr to
+// the given writers, and returns the exit status.
+func RunStreams(client *ssh.Client, cmd string, stdout, stderr io.Writer) (int, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return -1, fmt.Errorf("new session: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+	if err := session.Run(cmd); err != nil {
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			return exitErr.ExitStatus(), nil
+		}
+		return -1, err
+	}
+	return 0, nil
+}
+
+// Quote returns s single-quoted for safe use as one POSIX shell word.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // FingerprintMD5 computes the legacy MD5 fingerprint of an SSH public key.
 func FingerprintMD5(key ssh.PublicKey) string {
 	sum := md5.Sum(key.Marshal()) //nolint:gosec